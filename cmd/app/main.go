@@ -23,9 +23,18 @@ func main() {
 			panic(err)
 		}
 	}
-	cfg := config.NewConfig()
+	startupLog := logger.NewNamedLogger("startup")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		startupLog.Error(err.Error())
+		os.Exit(1)
+	}
 
-	initialization := initialization.NewInitialization(cfg)
+	initialization, err := initialization.NewInitialization(cfg)
+	if err != nil {
+		startupLog.Error(err.Error())
+		os.Exit(1)
+	}
 
 	logger.InitializeLogger()
 	log := logger.NewNamedLogger("server")
@@ -37,14 +46,42 @@ func main() {
 		os.Exit(1)
 	}
 
+	cancelBackfill := initialization.BackfillWorker.Start()
+	cancelPartitions := initialization.PartitionWorker.Start()
+	cancelTrustList := initialization.TrustListWorker.Start()
+	cancelDraftCleanup := initialization.DraftCleanupWorker.Start()
+	cancelArchive := initialization.ArchiveWorker.Start()
+	cancelAnalytics := func() {}
+	if initialization.AnalyticsExportWorker != nil {
+		cancelAnalytics = initialization.AnalyticsExportWorker.Start()
+	}
+	cancelJudgeAudit := func() {}
+	if initialization.JudgeAuditWorker != nil {
+		cancelJudgeAudit = initialization.JudgeAuditWorker.Start()
+	}
+
 	server := server.NewServer(initialization, log)
 	err = server.Start()
 	if err != nil {
 		cancel() // Stop the queue listener
+		cancelBackfill()
+		cancelPartitions()
+		cancelTrustList()
+		cancelDraftCleanup()
+		cancelAnalytics()
+		cancelJudgeAudit()
+		cancelArchive()
 		log.Errorf("failed to start server: %v", err.Error())
 		os.Exit(1)
 
 	}
 
 	cancel() // Stop the queue listener on graceful shutdown
+	cancelBackfill()
+	cancelPartitions()
+	cancelTrustList()
+	cancelDraftCleanup()
+	cancelAnalytics()
+	cancelJudgeAudit()
+	cancelArchive()
 }
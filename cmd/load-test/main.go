@@ -0,0 +1,246 @@
+// Command load-test submits solutions through the HTTP API at a fixed rate, so the whole pipeline including
+// the queue and the judge is exercised, e.g. before exams. Each submission picks one of the solution files at
+// random by its weight, so a realistic mix of accepted and failing solutions can be sent.
+//
+// Usage:
+//
+//	load-test -email=student@example.com -password=... -task=1 -language=1 [-url=http://localhost:8080/api/v1]
+//		[-rate=5] [-duration=1m] [-concurrency=20] solution.c[:weight] ...
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/schemas"
+)
+
+// solution is a solution file sent with probability proportional to its weight
+type solution struct {
+	name    string
+	content []byte
+	weight  int
+}
+
+// result is the outcome of a single submission, status is 0 when the request failed
+type result struct {
+	status  int
+	latency time.Duration
+}
+
+type client struct {
+	url     string
+	session schemas.Session
+	http    *http.Client
+}
+
+func main() {
+	url := flag.String("url", "http://localhost:8080/api/v1", "base URL of the API")
+	email := flag.String("email", "", "email of the user submitting the solutions")
+	password := flag.String("password", "", "password of the user")
+	taskId := flag.Int64("task", 0, "ID of the task the solutions are submitted to")
+	languageId := flag.Int64("language", 0, "ID of the language of the solutions")
+	rate := flag.Float64("rate", 5, "submissions per second")
+	duration := flag.Duration("duration", time.Minute, "how long submissions are sent")
+	concurrency := flag.Int("concurrency", 20, "maximum number of submissions in flight, ticks above it are skipped")
+	flag.Parse()
+	if *email == "" || *taskId == 0 || *languageId == 0 || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "email, task, language and at least one solution file are required")
+		os.Exit(2)
+	}
+	if *rate <= 0 || *concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "rate and concurrency must be positive")
+		os.Exit(2)
+	}
+
+	solutions, err := readSolutions(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	c := &client{url: strings.TrimSuffix(*url, "/"), http: &http.Client{Timeout: 30 * time.Second}}
+	if err := c.login(*email, *password); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to log in: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	var (
+		mu      sync.Mutex
+		results []result
+		skipped int
+		wg      sync.WaitGroup
+	)
+	inFlight := make(chan struct{}, *concurrency)
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	defer ticker.Stop()
+	deadline := time.After(*duration)
+	fmt.Printf("submitting %.1f solutions per second to task %d for %s\n", *rate, *taskId, duration.String())
+
+loop:
+	for {
+		select {
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			skipped++
+			continue
+		}
+		wg.Add(1)
+		go func(s solution) {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			r := c.submit(*taskId, *languageId, s)
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}(pick(solutions))
+	}
+	wg.Wait()
+	report(results, skipped)
+}
+
+// readSolutions reads solution files given as path or path:weight, the weight defaults to 1
+func readSolutions(args []string) ([]solution, error) {
+	solutions := make([]solution, 0, len(args))
+	for _, arg := range args {
+		path, weight := arg, 1
+		if i := strings.LastIndex(arg, ":"); i > 0 {
+			w, err := strconv.Atoi(arg[i+1:])
+			if err == nil {
+				path, weight = arg[:i], w
+			}
+		}
+		if weight < 1 {
+			return nil, fmt.Errorf("weight of %s must be positive", path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		solutions = append(solutions, solution{name: filepath.Base(path), content: content, weight: weight})
+	}
+	return solutions, nil
+}
+
+func pick(solutions []solution) solution {
+	total := 0
+	for _, s := range solutions {
+		total += s.weight
+	}
+	n := rand.Intn(total)
+	for _, s := range solutions {
+		if n < s.weight {
+			return s
+		}
+		n -= s.weight
+	}
+	return solutions[len(solutions)-1]
+}
+
+func (c *client) login(email, password string) error {
+	body, err := json.Marshal(schemas.UserLoginRequest{Email: email, Password: password})
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(c.url+"/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	envelope := struct {
+		Ok   bool            `json:"ok"`
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return err
+	}
+	if !envelope.Ok {
+		return errors.New(string(envelope.Data))
+	}
+	return json.Unmarshal(envelope.Data, &c.session)
+}
+
+func (c *client) submit(taskId, languageId int64, s solution) result {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	form.WriteField("taskID", strconv.FormatInt(taskId, 10))
+	form.WriteField("userID", strconv.FormatInt(c.session.UserId, 10))
+	form.WriteField("languageID", strconv.FormatInt(languageId, 10))
+	file, err := form.CreateFormFile("solution", s.name)
+	if err == nil {
+		_, err = file.Write(s.content)
+	}
+	if err == nil {
+		err = form.Close()
+	}
+	if err != nil {
+		return result{}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url+"/task/submit", body)
+	if err != nil {
+		return result{}
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Session", c.session.Id)
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return result{latency: time.Since(start)}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return result{status: resp.StatusCode, latency: time.Since(start)}
+}
+
+// report prints the number of submissions by status and latency percentiles of accepted ones
+func report(results []result, skipped int) {
+	statuses := make(map[int]int)
+	var latencies []time.Duration
+	for _, r := range results {
+		statuses[r.status]++
+		if r.status == http.StatusOK {
+			latencies = append(latencies, r.latency)
+		}
+	}
+	fmt.Printf("sent %d submissions, skipped %d ticks at the concurrency limit\n", len(results), skipped)
+	codes := make([]int, 0, len(statuses))
+	for status := range statuses {
+		codes = append(codes, status)
+	}
+	slices.Sort(codes)
+	for _, status := range codes {
+		if status == 0 {
+			fmt.Printf("  request failed: %d\n", statuses[status])
+		} else {
+			fmt.Printf("  %d %s: %d\n", status, http.StatusText(status), statuses[status])
+		}
+	}
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	fmt.Printf("latency of accepted submissions: p50 %s, p95 %s, max %s\n", percentile(0.5), percentile(0.95), latencies[len(latencies)-1])
+}
@@ -0,0 +1,143 @@
+// Command recompute recomputes aggregate scores of submission results from stored test results,
+// e.g. after the scoring logic changed. Every batch runs in its own transaction.
+//
+// Usage:
+//
+//	recompute [-dry-run=false] [-batch-size=1000]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/service"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "only report how many results would change")
+	batchSize := flag.Int("batch-size", service.DefaultScoreRecomputeBatchSize, "number of results processed per transaction")
+	flag.Parse()
+	if *batchSize < 1 {
+		fmt.Fprintln(os.Stderr, "batch-size must be positive")
+		os.Exit(2)
+	}
+
+	if _, ok := os.LookupEnv("DEBUG"); ok {
+		err := godotenv.Load("././.env")
+		if err != nil {
+			panic(err)
+		}
+	}
+	cfg := config.NewConfig()
+	logger.InitializeLogger()
+	log := logger.NewNamedLogger("recompute")
+
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %s", err.Error())
+	}
+	submissionService, err := newSubmissionService(cfg, db.Db)
+	if err != nil {
+		log.Fatalf("Failed to create submission service: %s", err.Error())
+	}
+
+	total := schemas.ScoreRecomputeReport{DryRun: *dryRun}
+	afterId := int64(0)
+	for {
+		var batch *schemas.ScoreRecomputeReport
+		err := db.Db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			afterId, batch, err = submissionService.RecomputeScoresBatch(tx, afterId, *batchSize, *dryRun)
+			return err
+		})
+		if err != nil {
+			log.Fatalf("Failed to recompute scores after submission result %d: %s", afterId, err.Error())
+		}
+		if batch.Processed == 0 {
+			break
+		}
+		total.Processed += batch.Processed
+		total.Changed += batch.Changed
+		fmt.Printf("processed %d results, %d changed (last id %d)\n", total.Processed, total.Changed, afterId)
+	}
+
+	if *dryRun {
+		fmt.Printf("dry run: %d of %d results would change\n", total.Changed, total.Processed)
+	} else {
+		fmt.Printf("updated %d of %d results\n", total.Changed, total.Processed)
+	}
+}
+
+func newSubmissionService(cfg *config.Config, db *gorm.DB) (service.SubmissionService, error) {
+	submissionRepository, err := repository.NewSubmissionRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	submissionResultRepository, err := repository.NewSubmissionResultRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	taskRepository, err := repository.NewTaskRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	taskCoAuthorRepository, err := repository.NewTaskCoAuthorRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	inputOutputRepository, err := repository.NewInputOutputRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	testResultRepository, err := repository.NewTestResultRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	manualGradeRepository, err := repository.NewManualGradeRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	testCaseGroupRepository, err := repository.NewTestCaseGroupRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	userTaskSummaryRepository, err := repository.NewUserTaskSummaryRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	taskVerdictSummaryRepository, err := repository.NewTaskVerdictSummaryRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	timelineEventRepository, err := repository.NewTimelineEventRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	plagiarismRepository, err := repository.NewPlagiarismRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	roleRepository, err := repository.NewRoleRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	auditLogRepository, err := repository.NewAuditLogRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	accessControlService := service.NewAccessControlService(roleRepository, userRepository, auditLogRepository)
+	fileStorageService := service.NewFileStorageService(cfg.FileStorageUrl)
+	return service.NewSubmissionService(submissionRepository, submissionResultRepository, taskRepository, taskCoAuthorRepository, inputOutputRepository, testResultRepository, manualGradeRepository, testCaseGroupRepository, userTaskSummaryRepository, taskVerdictSummaryRepository, timelineEventRepository, plagiarismRepository, fileStorageService, service.NewArchiveService(submissionRepository, fileStorageService), accessControlService, false), nil
+}
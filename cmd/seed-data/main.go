@@ -0,0 +1,424 @@
+// Command seed-data exports an anonymized subset of the database and imports it into another one, e.g. to
+// reproduce bugs or test performance on staging with realistic data volumes. Ids are kept, so the relations
+// between the exported rows hold. Users are renamed and their emails and passwords replaced, free-form messages
+// of the worker are cleared. Files are not exported, so imported submissions are marked as redacted and tasks
+// have no description or tests in the file storage.
+//
+// The database is taken from the same environment variables as the app. Import expects a database without users.
+//
+// Usage:
+//
+//	seed-data export [-out=seed.json.gz] [-tasks=100] [-submissions=500]
+//	seed-data import [-in=seed.json.gz] [-password=password]
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// chunkSize bounds the number of ids in a single IN clause
+	chunkSize = 10000
+	// batchSize is the number of rows inserted at once on import
+	batchSize = 500
+)
+
+// dump is the exported data. Rows are listed in an order in which they can be inserted
+type dump struct {
+	ExportedAt        time.Time
+	Languages         []models.LanguageConfig
+	Terms             []models.Term
+	Users             []models.User
+	Groups            []models.Group
+	UserGroups        []models.UserGroup
+	Tasks             []models.Task
+	TaskUsers         []models.TaskUser
+	TaskGroups        []models.TaskGroup
+	InputOutputs      []models.InputOutput
+	Submissions       []models.Submission
+	SubmissionResults []models.SubmissionResult
+	TestResults       []models.TestResult
+}
+
+// sequences are the tables with generated ids, their sequences are moved past the imported ids
+var sequences = []string{
+	"language_configs", "terms", "users", "groups", "tasks", "input_outputs", "submissions", "submission_results", "test_results",
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: seed-data export|import [flags]")
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	exportFlags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := exportFlags.String("out", "seed.json.gz", "file the data is written to")
+	tasks := exportFlags.Int("tasks", 100, "number of the most recent tasks exported, 0 for all")
+	submissions := exportFlags.Int("submissions", 500, "number of the most recent submissions exported per task, 0 for all")
+	importFlags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := importFlags.String("in", "seed.json.gz", "file the data is read from")
+	password := importFlags.String("password", "password", "password set for every imported user")
+
+	switch command {
+	case "export":
+		exportFlags.Parse(args)
+		if *tasks < 0 || *submissions < 0 {
+			fmt.Fprintln(os.Stderr, "tasks and submissions must not be negative")
+			os.Exit(2)
+		}
+	case "import":
+		importFlags.Parse(args)
+		if *password == "" {
+			fmt.Fprintln(os.Stderr, "password must not be empty")
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s, expected export or import\n", command)
+		os.Exit(2)
+	}
+
+	if _, ok := os.LookupEnv("DEBUG"); ok {
+		err := godotenv.Load("././.env")
+		if err != nil {
+			panic(err)
+		}
+	}
+	cfg := config.NewConfig()
+	logger.InitializeLogger()
+	log := logger.NewNamedLogger("seed-data")
+
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %s", err.Error())
+	}
+
+	if command == "export" {
+		data, err := exportData(db.Db, *tasks, *submissions)
+		if err != nil {
+			log.Fatalf("Failed to export data: %s", err.Error())
+		}
+		if err := writeDump(*out, data); err != nil {
+			log.Fatalf("Failed to write %s: %s", *out, err.Error())
+		}
+		fmt.Printf("exported %d users, %d tasks, %d submissions and %d test results to %s\n",
+			len(data.Users), len(data.Tasks), len(data.Submissions), len(data.TestResults), *out)
+		return
+	}
+
+	data, err := readDump(*in)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %s", *in, err.Error())
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatalf("Failed to hash password: %s", err.Error())
+	}
+	if err := migrate(db.Db); err != nil {
+		log.Fatalf("Failed to create tables: %s", err.Error())
+	}
+	err = db.Db.Transaction(func(tx *gorm.DB) error {
+		return importData(tx, data, string(hash))
+	})
+	if err != nil {
+		log.Fatalf("Failed to import data: %s", err.Error())
+	}
+	fmt.Printf("imported %d users, %d tasks, %d submissions and %d test results exported at %s\n",
+		len(data.Users), len(data.Tasks), len(data.Submissions), len(data.TestResults), data.ExportedAt.Format(time.RFC3339))
+}
+
+// exportData reads the most recent tasks with their most recent submissions and everything they refer to
+func exportData(db *gorm.DB, tasks, submissions int) (*dump, error) {
+	data := &dump{ExportedAt: time.Now()}
+	if err := db.Order("id").Find(&data.Languages).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&data.Terms).Error; err != nil {
+		return nil, err
+	}
+
+	query := db.Order("id DESC")
+	if tasks > 0 {
+		query = query.Limit(tasks)
+	}
+	if err := query.Find(&data.Tasks).Error; err != nil {
+		return nil, err
+	}
+	taskIds := make([]int64, 0, len(data.Tasks))
+	userIds := make(map[int64]bool)
+	for _, task := range data.Tasks {
+		taskIds = append(taskIds, task.Id)
+		userIds[task.CreatedBy] = true
+	}
+
+	err := inChunks(taskIds, func(ids []int64) error {
+		var inputOutputs []models.InputOutput
+		if err := db.Where("task_id IN ?", ids).Order("id").Find(&inputOutputs).Error; err != nil {
+			return err
+		}
+		data.InputOutputs = append(data.InputOutputs, inputOutputs...)
+		var taskUsers []models.TaskUser
+		if err := db.Where("task_id IN ?", ids).Find(&taskUsers).Error; err != nil {
+			return err
+		}
+		data.TaskUsers = append(data.TaskUsers, taskUsers...)
+		var taskGroups []models.TaskGroup
+		if err := db.Where("task_id IN ?", ids).Find(&taskGroups).Error; err != nil {
+			return err
+		}
+		data.TaskGroups = append(data.TaskGroups, taskGroups...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, taskUser := range data.TaskUsers {
+		userIds[taskUser.UserId] = true
+	}
+
+	for _, taskId := range taskIds {
+		var taskSubmissions []models.Submission
+		query := db.Where("task_id = ?", taskId).Order("submitted_at DESC")
+		if submissions > 0 {
+			query = query.Limit(submissions)
+		}
+		if err := query.Find(&taskSubmissions).Error; err != nil {
+			return nil, err
+		}
+		data.Submissions = append(data.Submissions, taskSubmissions...)
+	}
+	submissionIds := make([]int64, 0, len(data.Submissions))
+	for _, submission := range data.Submissions {
+		submissionIds = append(submissionIds, submission.Id)
+		userIds[submission.UserId] = true
+	}
+	err = inChunks(submissionIds, func(ids []int64) error {
+		var results []models.SubmissionResult
+		err := db.Where("submission_id IN ?", ids).Order("id").Find(&results).Error
+		data.SubmissionResults = append(data.SubmissionResults, results...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resultIds := make([]int64, 0, len(data.SubmissionResults))
+	for _, result := range data.SubmissionResults {
+		resultIds = append(resultIds, result.Id)
+	}
+	err = inChunks(resultIds, func(ids []int64) error {
+		var testResults []models.TestResult
+		err := db.Where("submission_result_id IN ?", ids).Order("id").Find(&testResults).Error
+		data.TestResults = append(data.TestResults, testResults...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(userIds))
+	for id := range userIds {
+		ids = append(ids, id)
+	}
+	err = inChunks(ids, func(ids []int64) error {
+		var users []models.User
+		if err := db.Where("id IN ?", ids).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+		data.Users = append(data.Users, users...)
+		// Only memberships of exported users are kept, groups may therefore look smaller than they are
+		var userGroups []models.UserGroup
+		if err := db.Where("user_id IN ?", ids).Find(&userGroups).Error; err != nil {
+			return err
+		}
+		data.UserGroups = append(data.UserGroups, userGroups...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	groupIds := make(map[int64]bool)
+	for _, userGroup := range data.UserGroups {
+		groupIds[userGroup.GroupId] = true
+	}
+	for _, taskGroup := range data.TaskGroups {
+		groupIds[taskGroup.GroupId] = true
+	}
+	ids = make([]int64, 0, len(groupIds))
+	for id := range groupIds {
+		ids = append(ids, id)
+	}
+	err = inChunks(ids, func(ids []int64) error {
+		var groups []models.Group
+		err := db.Where("id IN ?", ids).Order("id").Find(&groups).Error
+		data.Groups = append(data.Groups, groups...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	anonymize(data)
+	return data, nil
+}
+
+// anonymize replaces personal data of users and messages which may contain parts of sources or their output
+func anonymize(data *dump) {
+	for i := range data.Users {
+		user := &data.Users[i]
+		user.Name = "User"
+		user.Surname = fmt.Sprint(user.Id)
+		user.Email = fmt.Sprintf("user%d@example.com", user.Id)
+		user.Username = fmt.Sprintf("user%d", user.Id)
+		user.PasswordHash = ""
+	}
+	for i := range data.Groups {
+		data.Groups[i].Name = fmt.Sprintf("Group %d", data.Groups[i].Id)
+	}
+	redactedAt := data.ExportedAt
+	for i := range data.Submissions {
+		submission := &data.Submissions[i]
+		submission.StatusMessage = ""
+		submission.RedactedAt = &redactedAt
+		submission.ArchivedAt = nil
+	}
+	for i := range data.TestResults {
+		data.TestResults[i].ErrorMessage = ""
+	}
+}
+
+// migrate creates the tables of the exported rows, unless they exist
+func migrate(db *gorm.DB) error {
+	migrations := []func(*gorm.DB) error{
+		func(db *gorm.DB) error { _, err := repository.NewLanguageRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewTermRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewUserRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewGroupRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewTaskRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewInputOutputRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewSubmissionRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewSubmissionResultRepository(db); return err },
+		func(db *gorm.DB) error { _, err := repository.NewTestResultRepository(db); return err },
+	}
+	for _, migration := range migrations {
+		if err := migration(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importData(tx *gorm.DB, data *dump, passwordHash string) error {
+	var users int64
+	if err := tx.Model(&models.User{}).Count(&users).Error; err != nil {
+		return err
+	}
+	if users > 0 {
+		return errors.New("the database already has users, import expects an empty database")
+	}
+	for i := range data.Users {
+		data.Users[i].PasswordHash = passwordHash
+	}
+
+	// Default languages are created with the tables, so languages are updated in place
+	if len(data.Languages) > 0 {
+		err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(&data.Languages, batchSize).Error
+		if err != nil {
+			return err
+		}
+	}
+	tables := []struct {
+		name string
+		rows interface{}
+		len  int
+	}{
+		{"terms", &data.Terms, len(data.Terms)},
+		{"users", &data.Users, len(data.Users)},
+		{"groups", &data.Groups, len(data.Groups)},
+		{"user_groups", &data.UserGroups, len(data.UserGroups)},
+		{"tasks", &data.Tasks, len(data.Tasks)},
+		{"task_users", &data.TaskUsers, len(data.TaskUsers)},
+		{"task_groups", &data.TaskGroups, len(data.TaskGroups)},
+		{"input_outputs", &data.InputOutputs, len(data.InputOutputs)},
+		{"submissions", &data.Submissions, len(data.Submissions)},
+		{"submission_results", &data.SubmissionResults, len(data.SubmissionResults)},
+		{"test_results", &data.TestResults, len(data.TestResults)},
+	}
+	for _, table := range tables {
+		if table.len == 0 {
+			continue
+		}
+		err := tx.Omit(clause.Associations).CreateInBatches(table.rows, batchSize).Error
+		if err != nil {
+			return fmt.Errorf("%s: %w", table.name, err)
+		}
+	}
+
+	for _, table := range sequences {
+		err := tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s", table, table)).Error
+		if err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func inChunks(ids []int64, f func(ids []int64) error) error {
+	for start := 0; start < len(ids); start += chunkSize {
+		if err := f(ids[start:min(start+chunkSize, len(ids))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDump writes the data as gzipped JSON. Empty associations of the models repeat in every row, which
+// compresses well
+func writeDump(path string, data *dump) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := gzip.NewWriter(file)
+	if err := json.NewEncoder(writer).Encode(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func readDump(path string) (*dump, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data := &dump{}
+	if err := json.NewDecoder(reader).Decode(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
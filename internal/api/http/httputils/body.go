@@ -0,0 +1,36 @@
+package httputils
+
+import (
+	"errors"
+	"net/http"
+)
+
+// MultipartMemoryLimit is the number of bytes of a multipart form kept in memory.
+// Larger file parts are streamed to temporary files on disk.
+const MultipartMemoryLimit = 1 << 20 // 1 MB
+
+// IsRequestBodyTooLarge reports whether err was caused by reading past the request body limit.
+func IsRequestBodyTooLarge(err error) bool {
+	var maxBytesError *http.MaxBytesError
+	return errors.As(err, &maxBytesError)
+}
+
+// ReturnRequestBodyError responds with 413 if the request body exceeded its size limit,
+// and with 400 otherwise.
+func ReturnRequestBodyError(w http.ResponseWriter, err error) {
+	if IsRequestBodyTooLarge(err) {
+		ReturnError(w, http.StatusRequestEntityTooLarge, "Request body too large. "+err.Error())
+		return
+	}
+	ReturnError(w, http.StatusBadRequest, "Invalid request body. "+err.Error())
+}
+
+// ParseMultipartForm parses a multipart request keeping at most MultipartMemoryLimit bytes in memory.
+// Temporary files created for the form are removed if parsing fails.
+func ParseMultipartForm(r *http.Request) error {
+	err := r.ParseMultipartForm(MultipartMemoryLimit)
+	if err != nil && r.MultipartForm != nil {
+		r.MultipartForm.RemoveAll()
+	}
+	return err
+}
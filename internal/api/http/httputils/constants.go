@@ -1,4 +1,3 @@
 package httputils
 
-const DefaultPaginationLimitStr = "10"
-const DefaultPaginationOffsetStr = "0"
+const DefaultPaginationOffset = 0
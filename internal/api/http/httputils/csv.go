@@ -0,0 +1,50 @@
+package httputils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/mini-maxit/backend/package/utils"
+)
+
+var csvDelimiters = map[string]rune{
+	"comma":     ',',
+	"semicolon": ';',
+	"tab":       '\t',
+}
+
+// GetCSVOptions parses the delimiter (comma, semicolon or tab) and bom query parameters of a CSV export
+func GetCSVOptions(query url.Values) (utils.CSVOptions, error) {
+	options := utils.CSVOptions{}
+	if delimiterStr := query.Get("delimiter"); delimiterStr != "" {
+		delimiter, ok := csvDelimiters[delimiterStr]
+		if !ok {
+			return options, errors.New("delimiter must be comma, semicolon or tab")
+		}
+		options.Delimiter = delimiter
+	}
+	if bomStr := query.Get("bom"); bomStr != "" {
+		bom, err := strconv.ParseBool(bomStr)
+		if err != nil {
+			return options, errors.New("bom must be true or false")
+		}
+		options.BOM = bom
+	}
+	return options, nil
+}
+
+// StreamCSV starts a CSV attachment response. Errors after this can no longer change the status, so
+// handlers must load everything that can fail before streaming
+func StreamCSV(w http.ResponseWriter, filename string, options utils.CSVOptions) (*utils.CSVWriter, error) {
+	writer, err := utils.NewCSVWriter(w, options)
+	if err != nil {
+		return nil, err
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8; header=present")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	return writer, nil
+}
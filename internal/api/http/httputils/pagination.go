@@ -0,0 +1,93 @@
+package httputils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// PaginationLimits are the default and the maximum page size of a class of list endpoints
+type PaginationLimits struct {
+	DefaultLimit int64
+	MaxLimit     int64
+}
+
+// GetPagination parses the limit and offset query parameters. A missing limit is the default of the
+// endpoint class, a missing offset is DefaultPaginationOffset. Limits above the maximum are rejected
+// instead of being truncated, so clients do not mistake a short page for the last one.
+func GetPagination(query url.Values, limits PaginationLimits) (int64, int64, error) {
+	limit := limits.DefaultLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || limit <= 0 {
+			return 0, 0, errors.New("limit must be a positive number")
+		}
+		if limit > limits.MaxLimit {
+			return 0, 0, fmt.Errorf("limit must be at most %d", limits.MaxLimit)
+		}
+	}
+
+	offset := int64(DefaultPaginationOffset)
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative number")
+		}
+	}
+	return limit, offset, nil
+}
+
+// GetCursorPagination parses the pagination of endpoints which can also be paginated with the opaque cursor
+// query parameter, the next_cursor of the previous page. A cursor can not be combined with an offset
+func GetCursorPagination(query url.Values, limits PaginationLimits) (int64, int64, string, error) {
+	limit, offset, err := GetPagination(query, limits)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	cursor := query.Get("cursor")
+	if cursor != "" && query.Get("offset") != "" {
+		return 0, 0, "", errors.New("cursor can not be combined with offset")
+	}
+	return limit, offset, cursor, nil
+}
+
+// PageInfo is the pagination of a list response. NextCursor is only set by cursor paginated endpoints,
+// it is empty on the last page
+type PageInfo struct {
+	Limit      int64  `json:"limit"`
+	Offset     int64  `json:"offset"`
+	Count      int    `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ApiPageResponse is the envelope of list endpoints, data is the page and pagination describes it
+type ApiPageResponse[T any] struct {
+	Ok         bool     `json:"ok"`
+	Data       T        `json:"data"`
+	Pagination PageInfo `json:"pagination"`
+}
+
+// ReturnPage returns a page of a list endpoint. Count is set from the items, so it can be left unset
+func ReturnPage[T any](w http.ResponseWriter, items []T, page PageInfo) {
+	if items == nil {
+		items = []T{}
+	}
+	page.Count = len(items)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := ApiPageResponse[[]T]{
+		Ok:         true,
+		Data:       items,
+		Pagination: page,
+	}
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		ReturnError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
@@ -2,7 +2,12 @@ package httputils
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
 )
 
 type ApiResponse[T any] struct {
@@ -46,3 +51,126 @@ func ReturnSuccess(w http.ResponseWriter, statusCode int, data any) {
 		return
 	}
 }
+
+type conflictStruct[T any] struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Current T      `json:"current"`
+}
+
+// ApiConflictError is returned with 409 when an edit is based on an outdated version. Current is the current state
+type ApiConflictError[T any] ApiResponse[conflictStruct[T]]
+
+func ReturnConflict[T any](w http.ResponseWriter, message string, current T) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	response := ApiConflictError[T]{
+		Ok:   false,
+		Data: conflictStruct[T]{Code: http.StatusText(http.StatusConflict), Message: message, Current: current},
+	}
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		ReturnError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// FieldError is a rule a request field failed. Field is the path of the field in the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type validationErrorStruct struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields"`
+}
+
+// ApiValidationError is returned with 400 when a request fails validation, with an entry for each failed rule
+type ApiValidationError ApiResponse[validationErrorStruct]
+
+func ReturnValidationError(w http.ResponseWriter, message string, errs validator.ValidationErrors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	response := ApiValidationError{
+		Ok:   false,
+		Data: validationErrorStruct{Code: http.StatusText(http.StatusBadRequest), Message: message, Fields: FieldErrors(errs)},
+	}
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		ReturnError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+}
+
+// FieldErrors converts validation errors to field errors named as in the request
+func FieldErrors(errs validator.ValidationErrors) []FieldError {
+	fieldErrors := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		// The namespace starts with the name of the validated struct, which is not part of the request
+		field := fe.Namespace()
+		if i := strings.IndexByte(field, '.'); i >= 0 {
+			field = field[i+1:]
+		}
+		param := fe.Param()
+		if strings.HasSuffix(fe.ActualTag(), "field") {
+			param = snakeCase(param)
+		}
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   field,
+			Rule:    fe.ActualTag(),
+			Param:   param,
+			Message: fieldErrorMessage(fe.ActualTag(), param),
+		})
+	}
+	return fieldErrors
+}
+
+func fieldErrorMessage(rule string, param string) string {
+	switch rule {
+	case "required":
+		return "is required"
+	case "required_without_all":
+		return fmt.Sprintf("is required unless one of %s is set", strings.ReplaceAll(param, " ", ", "))
+	case "gte", "min":
+		return fmt.Sprintf("must be at least %s", param)
+	case "lte", "max":
+		return fmt.Sprintf("must be at most %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "lt":
+		return fmt.Sprintf("must be less than %s", param)
+	case "gtfield":
+		return fmt.Sprintf("must be after %s", param)
+	case "gtefield":
+		return fmt.Sprintf("must not be before %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(param, " ", ", "))
+	case "email":
+		return "must be a valid email"
+	case "username":
+		return "must start with a letter and contain only letters, digits and underscores"
+	case "unique":
+		return "must not contain duplicates"
+	default:
+		return fmt.Sprintf("failed the %s rule", rule)
+	}
+}
+
+// snakeCase converts a Go field name of a cross-field rule to its request name, e.g. StartsAt to starts_at
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
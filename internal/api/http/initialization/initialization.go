@@ -1,69 +1,120 @@
 package initialization
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
 	"github.com/mini-maxit/backend/internal/api/http/routes"
 	"github.com/mini-maxit/backend/internal/api/queue"
+	"github.com/mini-maxit/backend/internal/broker"
 	"github.com/mini-maxit/backend/internal/config"
 	"github.com/mini-maxit/backend/internal/database"
 	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/internal/worker"
 	"github.com/mini-maxit/backend/package/repository"
 	"github.com/mini-maxit/backend/package/service"
 	"github.com/mini-maxit/backend/package/utils"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
 )
 
+const componentCheckTimeout = 2 * time.Second
+
 type Initialization struct {
 	Cfg *config.Config
 	Db  database.Database
 
 	TaskService    service.TaskService
 	SessionService service.SessionService
+	UserService    service.UserService
 
-	AuthRoute    routes.AuthRoute
-	TaskRoute    routes.TaskRoute
-	SessionRoute routes.SessionRoute
-	UserRoute    routes.UserRoute
-
-	QueueListener queue.QueueListener
-}
+	// IsTrustedRequest matches requests exempt from rate limiting and load shedding
+	IsTrustedRequest middleware.TrustedRequestFunc
+	// SessionUsers remembers the users of validated sessions for IsTrustedRequest
+	SessionUsers *middleware.SessionUserCache
+	// SandboxLimiter limits requests to the sandbox per client IP, shared by replicas when Redis is enabled
+	SandboxLimiter middleware.Limiter
 
-func connectToBroker(cfg *config.Config) (*amqp.Connection, *amqp.Channel) {
-	log := logger.NewNamedLogger("connect_to_broker")
+	AuthRoute         routes.AuthRoute
+	TaskRoute         routes.TaskRoute
+	SessionRoute      routes.SessionRoute
+	UserRoute         routes.UserRoute
+	AdminRoute        routes.AdminRoute
+	TermRoute         routes.TermRoute
+	TagRoute          routes.TagRoute
+	StatusRoute       routes.StatusRoute
+	SandboxRoute      routes.SandboxRoute
+	SubmissionRoute   routes.SubmissionRoute
+	LimitsRoute       routes.LimitsRoute
+	ActivityRoute     routes.ActivityRoute
+	NotificationRoute routes.NotificationRoute
+	PlagiarismRoute   routes.PlagiarismRoute
+	StatsRoute        routes.StatsRoute
+	AnnouncementRoute routes.AnnouncementRoute
 
-	var err error
-	var conn *amqp.Connection
-	for v := range 5 {
-		conn, err = amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.BrokerConfig.User, cfg.BrokerConfig.Password, cfg.BrokerConfig.Host, cfg.BrokerConfig.Port))
-		if err != nil {
-			log.Warnf("Failed to connect to RabbitMQ: %s", err.Error())
-			time.Sleep(2*time.Second + time.Duration(v))
-			continue
-		}
-	}
-	log.Info("Connected to RabbitMQ")
+	QueueListener      queue.QueueListener
+	BackfillWorker     worker.BackfillWorker
+	PartitionWorker    worker.PartitionWorker
+	TrustListWorker    worker.TrustListWorker
+	DraftCleanupWorker worker.DraftCleanupWorker
+	// AnalyticsExportWorker is nil when the analytics export is disabled
+	AnalyticsExportWorker worker.AnalyticsExportWorker
+	// JudgeAuditWorker is nil when the judge audit is disabled
+	JudgeAuditWorker worker.JudgeAuditWorker
+	// ArchiveWorker archives sources of old submissions when archiving is enabled and prunes superseded attempts
+	ArchiveWorker worker.ArchiveWorker
+}
 
-	if err != nil {
-		log.Panicf("Failed to connect to RabbitMQ: %s", err.Error())
+// statusComponents returns health checks of the dependencies shown on the status page
+func statusComponents(cfg *config.Config, db *database.PostgresDB, connection *broker.Connection, redisClient *redis.Client) []service.Component {
+	components := []service.Component{
+		{Name: "database", Check: func() error {
+			sqlDb, err := db.Db.DB()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), componentCheckTimeout)
+			defer cancel()
+			return sqlDb.PingContext(ctx)
+		}},
+		{Name: "broker", Check: func() error {
+			state, failedAttempts := connection.State()
+			if state == broker.StateReconnecting {
+				return fmt.Errorf("reconnecting to broker, %d attempts failed", failedAttempts)
+			}
+			if state != broker.StateConnected {
+				return fmt.Errorf("connection to broker is %s", state)
+			}
+			return nil
+		}},
+		{Name: "file_storage", Check: func() error {
+			return checkFileStorage(cfg)
+		}},
 	}
-	channel, err := conn.Channel()
-	if err != nil {
-		log.Panicf("Failed to create channel: %s", err.Error())
+	if redisClient != nil {
+		components = append(components, service.Component{Name: "redis", Check: func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), componentCheckTimeout)
+			defer cancel()
+			return redisClient.Ping(ctx).Err()
+		}})
 	}
-
-	return conn, channel
+	return components
 }
 
-func NewInitialization(cfg *config.Config) *Initialization {
+// NewInitialization runs the startup check and wires the application. A failed startup check is returned
+// as a *StartupCheckError
+func NewInitialization(cfg *config.Config) (*Initialization, error) {
 	log := logger.NewNamedLogger("initialization")
-	conn, channel := connectToBroker(cfg)
-	db, err := database.NewPostgresDB(cfg)
+	deps, err := checkStartup(cfg)
 	if err != nil {
-		log.Panicf("Failed to connect to database: %s", err.Error())
+		return nil, err
 	}
+	db := deps.db
+	connection := deps.connection
+	redisClient := deps.redisClient
 	tx, err := db.Connect()
 
 	defer utils.TransactionPanicRecover(tx)
@@ -72,7 +123,7 @@ func NewInitialization(cfg *config.Config) *Initialization {
 		log.Panicf("Failed to connect to database: %s", err.Error())
 	}
 	// Repositories
-	_, err = repository.NewLanguageRepository(tx)
+	languageRepository, err := repository.NewLanguageRepository(tx)
 	if err != nil {
 		log.Panicf("Failed to create language repository: %s", err.Error())
 	}
@@ -84,7 +135,7 @@ func NewInitialization(cfg *config.Config) *Initialization {
 	if err != nil {
 		log.Panicf("Failed to create task repository: %s", err.Error())
 	}
-	_, err = repository.NewGroupRepository(tx)
+	groupRepository, err := repository.NewGroupRepository(tx)
 	if err != nil {
 		log.Panicf("Failed to create group repository: %s", err.Error())
 	}
@@ -92,17 +143,151 @@ func NewInitialization(cfg *config.Config) *Initialization {
 	if err != nil {
 		log.Panicf("Failed to create submission repository: %s", err.Error())
 	}
-	_, err = repository.NewSubmissionResultRepository(tx)
+	submissionResultRepository, err := repository.NewSubmissionResultRepository(tx)
 	if err != nil {
 		log.Panicf("Failed to create submission result repository: %s", err.Error())
 	}
+	inputOutputRepository, err := repository.NewInputOutputRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create input output repository: %s", err.Error())
+	}
+	testResultRepository, err := repository.NewTestResultRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create test result repository: %s", err.Error())
+	}
 	queueRepository, err := repository.NewQueueMessageRepository(tx)
 	if err != nil {
 		log.Panicf("Failed to create queue repository: %s", err.Error())
 	}
-	sessionRepository, err := repository.NewSessionRepository(tx)
+	queueFailureRepository, err := repository.NewQueueFailureRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create queue failure repository: %s", err.Error())
+	}
+	timelineEventRepository, err := repository.NewTimelineEventRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create timeline event repository: %s", err.Error())
+	}
+	var sessionRepository repository.SessionRepository
+	if redisClient != nil {
+		log.Info("Connected to Redis, sessions and the sandbox rate limit are stored in Redis")
+		sessionRepository = repository.NewRedisSessionRepository(redisClient)
+	} else {
+		sessionRepository, err = repository.NewSessionRepository(tx)
+		if err != nil {
+			log.Panicf("Failed to create session repository: %s", err.Error())
+		}
+	}
+	taskBookmarkRepository, err := repository.NewTaskBookmarkRepository(tx)
 	if err != nil {
-		log.Panicf("Failed to create session repository: %s", err.Error())
+		log.Panicf("Failed to create task bookmark repository: %s", err.Error())
+	}
+	taskNoteRepository, err := repository.NewTaskNoteRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create task note repository: %s", err.Error())
+	}
+	taskDraftRepository, err := repository.NewTaskDraftRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create task draft repository: %s", err.Error())
+	}
+	taskCoAuthorRepository, err := repository.NewTaskCoAuthorRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create task co-author repository: %s", err.Error())
+	}
+	testCaseGroupRepository, err := repository.NewTestCaseGroupRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create test case group repository: %s", err.Error())
+	}
+	taskPoolRepository, err := repository.NewTaskPoolRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create task pool repository: %s", err.Error())
+	}
+	tagRepository, err := repository.NewTagRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create tag repository: %s", err.Error())
+	}
+	onlineMigrationRepository, err := repository.NewOnlineMigrationRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create online migration repository: %s", err.Error())
+	}
+	incidentRepository, err := repository.NewIncidentRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create incident repository: %s", err.Error())
+	}
+	termRepository, err := repository.NewTermRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create term repository: %s", err.Error())
+	}
+	trustListRepository, err := repository.NewTrustListRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create trust list repository: %s", err.Error())
+	}
+	judgeAuditRepository, err := repository.NewJudgeAuditRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create judge audit repository: %s", err.Error())
+	}
+	rejudgeRepository, err := repository.NewRejudgeRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create rejudge repository: %s", err.Error())
+	}
+	refreshTokenRepository, err := repository.NewRefreshTokenRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create refresh token repository: %s", err.Error())
+	}
+	auditLogRepository, err := repository.NewAuditLogRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create audit log repository: %s", err.Error())
+	}
+	roleRepository, err := repository.NewRoleRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create role repository: %s", err.Error())
+	}
+	passwordResetRepository, err := repository.NewPasswordResetRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create password reset repository: %s", err.Error())
+	}
+	oauthRepository, err := repository.NewOAuthRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create oauth repository: %s", err.Error())
+	}
+	manualGradeRepository, err := repository.NewManualGradeRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create manual grade repository: %s", err.Error())
+	}
+	notificationRepository, err := repository.NewNotificationRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create notification repository: %s", err.Error())
+	}
+	plagiarismRepository, err := repository.NewPlagiarismRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create plagiarism repository: %s", err.Error())
+	}
+	announcementRepository, err := repository.NewAnnouncementRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create announcement repository: %s", err.Error())
+	}
+	quarantineRepository, err := repository.NewQuarantineRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create quarantine repository: %s", err.Error())
+	}
+	userTaskSummaryRepository, err := repository.NewUserTaskSummaryRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create user task summary repository: %s", err.Error())
+	}
+	taskVerdictSummaryRepository, err := repository.NewTaskVerdictSummaryRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create task verdict summary repository: %s", err.Error())
+	}
+	taskChangeRepository, err := repository.NewTaskChangeRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create task change repository: %s", err.Error())
+	}
+	// The startup check made sure the recorded version is not newer, every table is migrated by now
+	schemaVersionRepository, err := repository.NewSchemaVersionRepository(tx)
+	if err != nil {
+		log.Panicf("Failed to create schema version repository: %s", err.Error())
+	}
+	if err := schemaVersionRepository.SetVersion(tx, repository.SchemaVersion); err != nil {
+		log.Panicf("Failed to record schema version: %s", err.Error())
 	}
 
 	if err := db.Commit(); err != nil {
@@ -110,35 +295,133 @@ func NewInitialization(cfg *config.Config) *Initialization {
 	}
 
 	// Services
-	userService := service.NewUserService(userRepository)
-	taskService := service.NewTaskService(cfg, taskRepository, submissionRepository)
-	queueService, err := service.NewQueueService(taskRepository, submissionRepository, queueRepository, conn, channel, cfg.BrokerConfig.QueueName, cfg.BrokerConfig.ResponseQueueName)
+	fileStorageService := service.NewFileStorageService(cfg.FileStorageUrl)
+	sessionService := service.NewSessionService(sessionRepository, userRepository)
+	accessControlService := service.NewAccessControlService(roleRepository, userRepository, auditLogRepository)
+	userService := service.NewUserService(userRepository, taskRepository, refreshTokenRepository, auditLogRepository, sessionService, accessControlService)
+	var mailService service.MailService
+	if cfg.Mail.Enabled {
+		// Sent in the background, so password reset requests take as long for unknown emails as for users
+		mailService = service.NewAsyncMailService(service.NewSMTPMailService(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From))
+	}
+	notificationService := service.NewNotificationService(notificationRepository, userRepository, mailService)
+	// Backfills of online migrations are registered here and run by the backfill worker
+	onlineMigrationService := service.NewOnlineMigrationService(onlineMigrationRepository, accessControlService, service.DefaultBackfillBatchSize)
+	onlineMigrationService.Register(service.NewUserTaskSummaryBackfill(submissionRepository, userTaskSummaryRepository, cfg.App.EstimatedCounts))
+	onlineMigrationService.Register(service.NewTaskVerdictSummaryBackfill(submissionRepository, taskVerdictSummaryRepository, cfg.App.EstimatedCounts))
+	taskService := service.NewTaskService(cfg, taskRepository, submissionRepository, taskBookmarkRepository, taskNoteRepository, taskDraftRepository, taskCoAuthorRepository, testCaseGroupRepository, taskPoolRepository, userRepository, termRepository, userTaskSummaryRepository, taskVerdictSummaryRepository, taskChangeRepository, tagRepository, notificationService, onlineMigrationService, accessControlService)
+	archiveService := service.NewArchiveService(submissionRepository, fileStorageService)
+	queueService, err := service.NewQueueService(taskRepository, submissionRepository, queueRepository, queueFailureRepository, timelineEventRepository, archiveService, accessControlService, cfg.Evaluation.Defaults, connection, cfg.BrokerConfig)
 	if err != nil {
 		log.Panicf("Failed to create queue service: %s", err.Error())
 	}
-	sessionService := service.NewSessionService(sessionRepository, userRepository)
-	authService := service.NewAuthService(userRepository, sessionService)
+	submissionService := service.NewSubmissionService(submissionRepository, submissionResultRepository, taskRepository, taskCoAuthorRepository, inputOutputRepository, testResultRepository, manualGradeRepository, testCaseGroupRepository, userTaskSummaryRepository, taskVerdictSummaryRepository, timelineEventRepository, plagiarismRepository, fileStorageService, archiveService, accessControlService, cfg.App.ReuseIdenticalSubmissions)
+	var ldapAuthenticator service.LDAPAuthenticator
+	if cfg.LDAP.Enabled {
+		ldapAuthenticator = service.NewLDAPAuthenticator(cfg.LDAP)
+	}
+	authService := service.NewAuthService(userRepository, refreshTokenRepository, passwordResetRepository, oauthRepository, sessionService, mailService, cfg.Mail.PasswordResetUrl, service.NewOAuthProviders(cfg.OAuth), ldapAuthenticator)
+	integrityService := service.NewIntegrityService(repository.NewIntegrityRepository(), quarantineRepository, cfg.Scan.QuarantineDir, accessControlService)
+	partitionService := service.NewPartitionService(repository.NewPartitionRepository())
+	termService := service.NewTermService(termRepository, accessControlService)
+	tagService := service.NewTagService(tagRepository, taskRepository, accessControlService)
+	trustListService := service.NewTrustListService(trustListRepository, accessControlService)
+	judgeAuditService := service.NewJudgeAuditService(submissionRepository, submissionResultRepository, judgeAuditRepository, testCaseGroupRepository, queueService, accessControlService)
+	rejudgeService := service.NewRejudgeService(submissionRepository, taskRepository, rejudgeRepository, queueService, accessControlService)
+	plagiarismService := service.NewPlagiarismService(submissionRepository, taskRepository, plagiarismRepository, fileStorageService, archiveService, accessControlService)
+	statsService := service.NewStatsService(groupRepository, taskRepository, taskCoAuthorRepository, submissionRepository, accessControlService)
+	announcementService := service.NewAnnouncementService(announcementRepository, groupRepository, notificationService, accessControlService)
+	taskExportService := service.NewTaskExportService(taskService, taskRepository, fileStorageService, accessControlService, cfg.App.MaxMultipartBodySize)
+	uploadScanService := service.NewUploadScanService(service.NewFileScanner(cfg.Scan), cfg.Scan.QuarantineDir, quarantineRepository, accessControlService)
+	sandboxRateLimit := 0
+	if cfg.Sandbox.Enabled {
+		sandboxRateLimit = cfg.Sandbox.RateLimit
+	}
+	limitsService := service.NewLimitsService(languageRepository, accessControlService, cfg.App.MaxJSONBodySize, cfg.App.MaxMultipartBodySize, routes.MaxSubmissionSize, sandboxRateLimit)
+	languageService := service.NewLanguageService(languageRepository, accessControlService)
+	gradingService := service.NewGradingService(submissionRepository, manualGradeRepository, accessControlService)
+	activityService := service.NewActivityService(repository.NewActivityRepository(), accessControlService)
+	statusService := service.NewStatusService(submissionRepository, incidentRepository, accessControlService, statusComponents(cfg, db, connection, redisClient))
+
+	var sandboxLimiter middleware.Limiter = middleware.NewRateLimiter(sandboxRateLimit, time.Minute)
+	if redisClient != nil {
+		sandboxLimiter = middleware.NewRedisRateLimiter(redisClient, "sandbox", sandboxRateLimit, time.Minute)
+	}
+	sessionUsers := middleware.NewSessionUserCache(middleware.SessionUserTTL)
+	isTrustedRequest := middleware.NewTrustedRequestFunc(trustListService, sessionUsers)
 
 	// Routes
-	taskRoute := routes.NewTaskRoute(cfg.FileStorageUrl, taskService, queueService)
+	taskRoute := routes.NewTaskRoute(cfg.FileStorageUrl, taskService, queueService, submissionService, languageService, uploadScanService, taskExportService, httputils.PaginationLimits(cfg.Pagination.List))
 	sessionRoute := routes.NewSessionRoute(sessionService)
 	authRoute := routes.NewAuthRoute(userService, authService)
-	userRoute := routes.NewUserRoute(userService)
+	userRoute := routes.NewUserRoute(userService, httputils.PaginationLimits(cfg.Pagination.List))
+	adminRoute := routes.NewAdminRoute(integrityService, onlineMigrationService, statusService, submissionService, trustListService, userService, judgeAuditService, languageService, queueService, uploadScanService, accessControlService, httputils.PaginationLimits(cfg.Pagination.Admin))
+	statusRoute := routes.NewStatusRoute(statusService)
+	termRoute := routes.NewTermRoute(termService)
+	tagRoute := routes.NewTagRoute(tagService)
+	sandboxRoute := routes.NewSandboxRoute(taskService, httputils.PaginationLimits(cfg.Pagination.List))
+	submissionRoute := routes.NewSubmissionRoute(submissionService, rejudgeService, gradingService, httputils.PaginationLimits(cfg.Pagination.Submission))
+	limitsRoute := routes.NewLimitsRoute(limitsService, isTrustedRequest)
+	activityRoute := routes.NewActivityRoute(activityService, httputils.PaginationLimits(cfg.Pagination.Submission))
+	notificationRoute := routes.NewNotificationRoute(notificationService, httputils.PaginationLimits(cfg.Pagination.List))
+	plagiarismRoute := routes.NewPlagiarismRoute(plagiarismService)
+	statsRoute := routes.NewStatsRoute(statsService)
+	announcementRoute := routes.NewAnnouncementRoute(announcementService, httputils.PaginationLimits(cfg.Pagination.List))
 
 	// Queue listener
-	queueListener, err := queue.NewQueueListener(conn, channel, taskService, cfg.BrokerConfig.ResponseQueueName)
+	queueListener, err := queue.NewQueueListener(connection, db, taskService, queueService, submissionService, judgeAuditService, rejudgeService, cfg.BrokerConfig.ResponseQueueName)
 	if err != nil {
 		log.Panicf("Failed to create queue listener: %s", err.Error())
 	}
 
+	// Background workers
+	backfillWorker := worker.NewBackfillWorker(db.Db, onlineMigrationService)
+	partitionWorker := worker.NewPartitionWorker(db.Db, partitionService)
+	trustListWorker := worker.NewTrustListWorker(db.Db, trustListService)
+	draftCleanupWorker := worker.NewDraftCleanupWorker(db.Db, taskService)
+	var analyticsExportWorker worker.AnalyticsExportWorker
+	if cfg.Analytics.Enabled {
+		analyticsService := service.NewAnalyticsService(submissionRepository, cfg.Analytics.ExportDir, cfg.Analytics.Salt)
+		analyticsExportWorker = worker.NewAnalyticsExportWorker(db.Db, analyticsService)
+	}
+	var judgeAuditWorker worker.JudgeAuditWorker
+	if cfg.JudgeAudit.SampleSize > 0 {
+		judgeAuditWorker = worker.NewJudgeAuditWorker(db.Db, judgeAuditService, cfg.JudgeAudit.SampleSize)
+	}
+	// Always started, tasks can opt in to pruning even when archiving and the platform policy are disabled
+	archiveWorker := worker.NewArchiveWorker(db.Db, archiveService, cfg.Archive)
+
 	return &Initialization{
-		Cfg:            cfg,
-		Db:             db,
-		QueueListener:  queueListener,
-		TaskService:    taskService,
-		SessionService: sessionService,
-		AuthRoute:      authRoute,
-		SessionRoute:   sessionRoute,
-		TaskRoute:      taskRoute,
-		UserRoute:      userRoute}
+		Cfg:                   cfg,
+		Db:                    db,
+		QueueListener:         queueListener,
+		BackfillWorker:        backfillWorker,
+		PartitionWorker:       partitionWorker,
+		TrustListWorker:       trustListWorker,
+		DraftCleanupWorker:    draftCleanupWorker,
+		AnalyticsExportWorker: analyticsExportWorker,
+		JudgeAuditWorker:      judgeAuditWorker,
+		ArchiveWorker:         archiveWorker,
+		TaskService:           taskService,
+		SessionService:        sessionService,
+		UserService:           userService,
+		IsTrustedRequest:      isTrustedRequest,
+		SessionUsers:          sessionUsers,
+		SandboxLimiter:        sandboxLimiter,
+		AuthRoute:             authRoute,
+		SessionRoute:          sessionRoute,
+		TaskRoute:             taskRoute,
+		UserRoute:             userRoute,
+		AdminRoute:            adminRoute,
+		TermRoute:             termRoute,
+		TagRoute:              tagRoute,
+		StatusRoute:           statusRoute,
+		SandboxRoute:          sandboxRoute,
+		SubmissionRoute:       submissionRoute,
+		LimitsRoute:           limitsRoute,
+		ActivityRoute:         activityRoute,
+		NotificationRoute:     notificationRoute,
+		PlagiarismRoute:       plagiarismRoute,
+		StatsRoute:            statsRoute,
+		AnnouncementRoute:     announcementRoute}, nil
 }
@@ -0,0 +1,118 @@
+package initialization
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mini-maxit/backend/internal/broker"
+	"github.com/mini-maxit/backend/internal/cache"
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// StartupCheckError lists every check which failed at startup
+type StartupCheckError struct {
+	Failures []string
+}
+
+func (e *StartupCheckError) Error() string {
+	return fmt.Sprintf("startup check failed, %d problems:\n  - %s", len(e.Failures), strings.Join(e.Failures, "\n  - "))
+}
+
+// dependencies are the connections opened by the startup check, used by the rest of the initialization
+type dependencies struct {
+	db          *database.PostgresDB
+	connection  *broker.Connection
+	redisClient *redis.Client // Nil when Redis is disabled
+}
+
+// checkStartup connects to the database, the broker and Redis, checks that the file storage is reachable
+// and that the database schema is not newer than this build. Every check runs, so a misconfigured instance
+// fails with all of its problems before any repository is created. Connections are closed on failure
+func checkStartup(cfg *config.Config) (*dependencies, error) {
+	log := logger.NewNamedLogger("startup_check")
+	deps := &dependencies{}
+	var failures []string
+
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("database %s:%d is not reachable: %s", cfg.DB.Host, cfg.DB.Port, err.Error()))
+	} else {
+		deps.db = db
+		if err := checkSchemaVersion(db); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	connection, err := broker.Dial(fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.BrokerConfig.User, cfg.BrokerConfig.Password, cfg.BrokerConfig.Host, cfg.BrokerConfig.Port))
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("broker %s:%d is not reachable: %s", cfg.BrokerConfig.Host, cfg.BrokerConfig.Port, err.Error()))
+	} else {
+		deps.connection = connection
+	}
+
+	if err := checkFileStorage(cfg); err != nil {
+		failures = append(failures, fmt.Sprintf("file storage %s is not reachable: %s", cfg.FileStorageUrl, err.Error()))
+	}
+
+	if cfg.Redis.Enabled {
+		redisClient, err := cache.NewRedisClient(cfg)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("redis %s:%d is not reachable: %s", cfg.Redis.Host, cfg.Redis.Port, err.Error()))
+		} else {
+			deps.redisClient = redisClient
+		}
+	}
+
+	if len(failures) > 0 {
+		deps.close()
+		return nil, &StartupCheckError{Failures: failures}
+	}
+	log.Info("Startup check passed")
+	return deps, nil
+}
+
+// checkSchemaVersion fails when the database was initialized by a newer build, whose schema this build may
+// not be able to use. Older schemas are migrated by the repositories
+func checkSchemaVersion(db *database.PostgresDB) error {
+	schemaVersionRepository, err := repository.NewSchemaVersionRepository(db.Db)
+	if err != nil {
+		return fmt.Errorf("schema version could not be read: %s", err.Error())
+	}
+	version, err := schemaVersionRepository.GetVersion(db.Db)
+	if err != nil {
+		return fmt.Errorf("schema version could not be read: %s", err.Error())
+	}
+	if version > repository.SchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than version %d of this build, deploy a newer release", version, repository.SchemaVersion)
+	}
+	return nil
+}
+
+func checkFileStorage(cfg *config.Config) error {
+	client := &http.Client{Timeout: componentCheckTimeout}
+	resp, err := client.Get(cfg.FileStorageUrl)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (d *dependencies) close() {
+	if d.db != nil {
+		if sqlDb, err := d.db.Db.DB(); err == nil {
+			sqlDb.Close()
+		}
+	}
+	if d.connection != nil {
+		d.connection.Close()
+	}
+	if d.redisClient != nil {
+		d.redisClient.Close()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+)
+
+// BodyLimits maps routes to the maximum size of their request bodies. Routes are registered with
+// http.ServeMux patterns matching paths below the API prefix, bodies of other routes are limited
+// to the default limit whatever their content type.
+type BodyLimits struct {
+	defaultLimit int64
+	routes       *http.ServeMux
+}
+
+// bodyLimit is registered for routes in BodyLimits, it is never served
+type bodyLimit int64
+
+func (bodyLimit) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func NewBodyLimits(defaultLimit int64) *BodyLimits {
+	return &BodyLimits{defaultLimit: defaultLimit, routes: http.NewServeMux()}
+}
+
+// Limit limits bodies of requests matching the pattern to limit bytes
+func (bl *BodyLimits) Limit(pattern string, limit int64) {
+	bl.routes.Handle(pattern, bodyLimit(limit))
+}
+
+// limit returns the limit of the route of the request. Requests redirected to another path or
+// sent with a method the route does not accept get the default limit
+func (bl *BodyLimits) limit(r *http.Request) int64 {
+	handler, _ := bl.routes.Handler(r)
+	if limit, ok := handler.(bodyLimit); ok {
+		return int64(limit)
+	}
+	return bl.defaultLimit
+}
+
+// BodyLimitMiddleware limits the size of request bodies to the limit of their route. Requests
+// which declare a larger body are rejected with 413 before any handler runs.
+func BodyLimitMiddleware(next http.Handler, limits *BodyLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := limits.limit(r)
+		if r.ContentLength > limit {
+			httputils.ReturnError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large. Maximum allowed size is %d bytes.", limit))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	const defaultLimit, uploadLimit = 8, 64
+	limits := NewBodyLimits(defaultLimit)
+	limits.Limit("POST /upload/{$}", uploadLimit)
+	limits.Limit("/upload/small", defaultLimit/2)
+	handler := BodyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), limits)
+
+	requests := []struct {
+		name   string
+		method string
+		path   string
+		size   int
+		status int
+	}{
+		{"Upload route", http.MethodPost, "/upload/", uploadLimit, http.StatusOK},
+		{"Upload route over its limit", http.MethodPost, "/upload/", uploadLimit + 1, http.StatusRequestEntityTooLarge},
+		{"Other method of an upload route", http.MethodPut, "/upload/", defaultLimit + 1, http.StatusRequestEntityTooLarge},
+		{"Route with a smaller limit", http.MethodPost, "/upload/small", defaultLimit, http.StatusRequestEntityTooLarge},
+		{"Other route", http.MethodPost, "/other", defaultLimit, http.StatusOK},
+		{"Other route over the default limit", http.MethodPost, "/other", defaultLimit + 1, http.StatusRequestEntityTooLarge},
+	}
+	for _, request := range requests {
+		t.Run(request.name, func(t *testing.T) {
+			r := httptest.NewRequest(request.method, request.path, strings.NewReader(strings.Repeat("a", request.size)))
+			r.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+			w := serve(handler, r)
+			assert.Equal(t, request.status, w.Code)
+		})
+	}
+}
@@ -7,6 +7,8 @@ const (
 	SessionKey ContextKey = "session"
 	// UserIDKey is the key used to store the user ID in the context.
 	UserIDKey ContextKey = "userId"
+	// UserKey is the key used to store the authenticated user (schemas.User) in the context.
+	UserKey ContextKey = "user"
 	// DatabaseKey is the key used to store the database connection in the context.
 	DatabaseKey ContextKey = "database"
 )
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+)
+
+// ReadCapacityPercent is the share of request capacity available to reads. The rest is
+// kept for submissions and other writes, so they are still served when listings spike.
+const ReadCapacityPercent = 80
+
+// LoadSheddingRetryAfter is the number of seconds clients are asked to wait when load is shed
+const LoadSheddingRetryAfter = 2
+
+// LoadSheddingMiddleware rejects requests with 503 and a Retry-After header once too many
+// requests are being served. Writes may use up to maxInFlight slots, reads only
+// ReadCapacityPercent of them. Trusted requests are neither counted nor shed.
+// A maxInFlight of 0 disables load shedding.
+//
+// Responses are neither cached nor coalesced. Task lists and task details carry the verdicts and the
+// pool variant of the current user, so identical requests of different users do not share a response.
+// Coalescing would need those per-user parts split from the shared task data first.
+func LoadSheddingMiddleware(next http.Handler, maxInFlight int64, isTrusted TrustedRequestFunc) http.Handler {
+	if maxInFlight <= 0 {
+		return next
+	}
+	readLimit := max(maxInFlight*ReadCapacityPercent/100, 1)
+	var inFlight atomic.Int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isTrusted(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit := maxInFlight
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			limit = readLimit
+		}
+		if inFlight.Add(1) > limit {
+			inFlight.Add(-1)
+			w.Header().Set("Retry-After", strconv.Itoa(LoadSheddingRetryAfter))
+			httputils.ReturnError(w, http.StatusServiceUnavailable, "Server is overloaded. Try again later.")
+			return
+		}
+		defer inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingHandler holds requests with the block query parameter until release is closed
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (bh *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("block") {
+		bh.started <- struct{}{}
+		<-bh.release
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// hold starts count blocked requests and waits until all of them are being served
+func (bh *blockingHandler) hold(handler http.Handler, method string, count int) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/?block", nil))
+		}()
+		<-bh.started
+	}
+	return &wg
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func notTrusted(r *http.Request) bool {
+	return false
+}
+
+func TestLoadSheddingMiddleware(t *testing.T) {
+	const maxInFlight = 5
+
+	t.Run("In-flight limit", func(t *testing.T) {
+		next := newBlockingHandler()
+		handler := LoadSheddingMiddleware(next, maxInFlight, notTrusted)
+		wg := next.hold(handler, http.MethodPost, maxInFlight)
+
+		w := serve(handler, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, strconv.Itoa(LoadSheddingRetryAfter), w.Header().Get("Retry-After"))
+
+		close(next.release)
+		wg.Wait()
+		w = serve(handler, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Reads keep capacity for writes", func(t *testing.T) {
+		next := newBlockingHandler()
+		handler := LoadSheddingMiddleware(next, maxInFlight, notTrusted)
+		readLimit := maxInFlight * ReadCapacityPercent / 100
+		wg := next.hold(handler, http.MethodGet, readLimit)
+
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			w := serve(handler, httptest.NewRequest(method, "/", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code, method)
+			assert.Equal(t, strconv.Itoa(LoadSheddingRetryAfter), w.Header().Get("Retry-After"), method)
+		}
+		w := serve(handler, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		close(next.release)
+		wg.Wait()
+		w = serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Trusted requests are not shed", func(t *testing.T) {
+		next := newBlockingHandler()
+		trusted := func(r *http.Request) bool {
+			return r.Header.Get("X-Trusted") != ""
+		}
+		handler := LoadSheddingMiddleware(next, maxInFlight, trusted)
+		wg := next.hold(handler, http.MethodPost, maxInFlight)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Trusted", "yes")
+		w := serve(handler, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		close(next.release)
+		wg.Wait()
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		next := newBlockingHandler()
+		handler := LoadSheddingMiddleware(next, 0, notTrusted)
+		assert.Equal(t, http.Handler(next), handler)
+	})
+}
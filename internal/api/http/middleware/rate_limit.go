@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	redisRateLimitKeyPrefix = "rate_limit:"
+	redisRateLimitTimeout   = time.Second
+)
+
+// Limiter allows each client a fixed number of requests per window
+type Limiter interface {
+	// Allow counts a request of the client and reports whether it is within the limit.
+	// When it is not, the returned duration is the time left until the next window.
+	Allow(client string) (bool, time.Duration)
+}
+
+// RateLimiter is a Limiter keeping its counters in memory, so with several replicas the limit applies per replica.
+type RateLimiter struct {
+	limit       int
+	window      time.Duration
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func (rl *RateLimiter) Allow(client string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.windowStart) >= rl.window {
+		rl.windowStart = now
+		rl.counts = make(map[string]int)
+	}
+	if rl.counts[client] >= rl.limit {
+		return false, rl.window - now.Sub(rl.windowStart)
+	}
+	rl.counts[client]++
+	return true, 0
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:       limit,
+		window:      window,
+		windowStart: time.Now(),
+		counts:      make(map[string]int),
+	}
+}
+
+// RedisRateLimiter is a Limiter keeping its counters in Redis, so the limit is shared by all replicas.
+// Windows are aligned to multiples of the window length and every window has its own key expiring with it.
+// While Redis cannot be reached requests are counted in memory, so the limit applies per replica
+type RedisRateLimiter struct {
+	client    *redis.Client
+	keyPrefix string
+	limit     int
+	window    time.Duration
+	fallback  *RateLimiter
+	logger    *zap.SugaredLogger
+}
+
+func (rl *RedisRateLimiter) Allow(client string) (bool, time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+
+	now := time.Now()
+	windowIndex := now.UnixNano() / int64(rl.window)
+	retryAfter := time.Duration(int64(rl.window) - now.UnixNano()%int64(rl.window))
+	key := rl.keyPrefix + client + ":" + strconv.FormatInt(windowIndex, 10)
+	var count *redis.IntCmd
+	_, err := rl.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.PExpire(ctx, key, retryAfter)
+		return nil
+	})
+	if err != nil {
+		rl.logger.Warnf("Error counting request of %s in Redis, counting it in memory: %v", client, err.Error())
+		return rl.fallback.Allow(client)
+	}
+	if count.Val() > int64(rl.limit) {
+		return false, retryAfter
+	}
+	return true, 0
+}
+
+// NewRedisRateLimiter creates a limiter shared by all replicas. The name separates the counters of limiters
+func NewRedisRateLimiter(client *redis.Client, name string, limit int, window time.Duration) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:    client,
+		keyPrefix: redisRateLimitKeyPrefix + name + ":",
+		limit:     limit,
+		window:    window,
+		fallback:  NewRateLimiter(limit, window),
+		logger:    logger.NewNamedLogger("rate_limiter"),
+	}
+}
+
+// RateLimitMiddleware rejects requests over the limit of the client IP with 429 and a Retry-After header.
+// Trusted requests are not limited
+func RateLimitMiddleware(next http.Handler, limiter Limiter, isTrusted TrustedRequestFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isTrusted(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowed, retryAfter := limiter.Allow(clientIP(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			httputils.ReturnError(w, http.StatusTooManyRequests, "Too many requests. Try again later.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
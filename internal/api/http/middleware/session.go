@@ -9,7 +9,9 @@ import (
 	"github.com/mini-maxit/backend/package/service"
 )
 
-func SessionValidationMiddleware(next http.Handler, db database.Database, sessionService service.SessionService) http.Handler {
+// SessionValidationMiddleware authorizes requests by the Session header. Validated sessions are remembered
+// in sessionUsers, so trusted users are recognized by later requests
+func SessionValidationMiddleware(next http.Handler, db database.Database, sessionService service.SessionService, userService service.UserService, sessionUsers *SessionUserCache) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sessionHeader := r.Header.Get("Session")
 		if sessionHeader == "" {
@@ -31,13 +33,26 @@ func SessionValidationMiddleware(next http.Handler, db database.Database, sessio
 				httputils.ReturnError(w, http.StatusUnauthorized, "Session expired")
 				return
 			}
+			if err == service.ErrUserInactive {
+				httputils.ReturnError(w, http.StatusForbidden, "User is deactivated or banned")
+				return
+			}
 			httputils.ReturnError(w, http.StatusInternalServerError, "Failed to validate session. "+err.Error())
 			return
 		}
 
+		user, err := userService.GetUserById(tx, sessionResponse.UserId)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusInternalServerError, "Failed to get session user. "+err.Error())
+			return
+		}
+
+		sessionUsers.Set(sessionHeader, sessionResponse.UserId)
+
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, SessionKey, sessionHeader)
 		ctx = context.WithValue(ctx, UserIDKey, sessionResponse.UserId)
+		ctx = context.WithValue(ctx, UserKey, *user)
 		rWithSession := r.WithContext(ctx)
 
 		next.ServeHTTP(w, rWithSession)
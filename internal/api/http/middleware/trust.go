@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mini-maxit/backend/package/service"
+)
+
+// ApiKeyHeader carries API keys of trusted clients
+const ApiKeyHeader = "X-API-Key"
+
+// SessionUserTTL is how long the user of a validated session is remembered for the trust list
+const SessionUserTTL = 30 * time.Second
+
+// maxSessionUsers bounds the remembered sessions. When it is reached expired sessions are dropped,
+// and all of them if none expired
+const maxSessionUsers = 100_000
+
+// TrustedRequestFunc reports whether a request is exempt from rate limiting and load shedding
+type TrustedRequestFunc func(r *http.Request) bool
+
+type sessionUser struct {
+	userId    int64
+	expiresAt time.Time
+}
+
+// SessionUserCache remembers the users of sessions validated by the session middleware, so trusted users
+// are recognized before load shedding without reading the database
+type SessionUserCache struct {
+	ttl      time.Duration
+	mu       sync.Mutex
+	sessions map[string]sessionUser
+}
+
+func NewSessionUserCache(ttl time.Duration) *SessionUserCache {
+	return &SessionUserCache{ttl: ttl, sessions: make(map[string]sessionUser)}
+}
+
+// Set remembers the user of a validated session
+func (c *SessionUserCache) Set(session string, userId int64) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.sessions[session]; !ok && len(c.sessions) >= maxSessionUsers {
+		for key, cached := range c.sessions {
+			if !cached.expiresAt.After(now) {
+				delete(c.sessions, key)
+			}
+		}
+		if len(c.sessions) >= maxSessionUsers {
+			c.sessions = make(map[string]sessionUser)
+		}
+	}
+	c.sessions[session] = sessionUser{userId: userId, expiresAt: now.Add(c.ttl)}
+}
+
+// Get returns the user of a session validated within the TTL, or 0
+func (c *SessionUserCache) Get(session string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.sessions[session]
+	if !ok || !cached.expiresAt.After(time.Now()) {
+		return 0
+	}
+	return cached.userId
+}
+
+// NewTrustedRequestFunc matches requests against the trust list by client IP, API key and session user.
+// It runs before load shedding, so the session user is only taken from sessions recently validated by the
+// session middleware. The first request of a trusted user after the TTL is treated as untrusted
+func NewTrustedRequestFunc(trustListService service.TrustListService, sessionUsers *SessionUserCache) TrustedRequestFunc {
+	return func(r *http.Request) bool {
+		userId := int64(0)
+		sessionHeader := r.Header.Get("Session")
+		if sessionHeader != "" && trustListService.HasTrustedUsers() {
+			userId = sessionUsers.Get(sessionHeader)
+		}
+		return trustListService.IsTrusted(net.ParseIP(clientIP(r)), r.Header.Get(ApiKeyHeader), userId)
+	}
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type ActivityRoute interface {
+	GetActivity(w http.ResponseWriter, r *http.Request)
+}
+
+type ActivityRouteImpl struct {
+	activityService service.ActivityService
+	pagination      httputils.PaginationLimits
+}
+
+// GetActivity godoc
+//
+//	@Tags			activity
+//	@Summary		Get activity feed
+//	@Description	Returns recent submissions, rejudges and co-author changes on tasks the current user manages, newest first. Teachers see their own tasks, admins all tasks
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.ActivityEvent]
+//	@Router			/activity [get]
+func (ar *ActivityRouteImpl) GetActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, offset, err := httputils.GetPagination(query, ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	events, err := ar.activityService.GetActivity(tx, currentUser, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins have an activity feed.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting activity. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, events, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+func NewActivityRoute(activityService service.ActivityService, pagination httputils.PaginationLimits) ActivityRoute {
+	return &ActivityRouteImpl{activityService: activityService, pagination: pagination}
+}
@@ -0,0 +1,1655 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type AdminRoute interface {
+	GetOrphans(w http.ResponseWriter, r *http.Request)
+	CleanupOrphans(w http.ResponseWriter, r *http.Request)
+	GetOnlineMigrations(w http.ResponseWriter, r *http.Request)
+	SetOnlineMigrationCutover(w http.ResponseWriter, r *http.Request)
+	ResumeOnlineMigration(w http.ResponseWriter, r *http.Request)
+	CreateIncident(w http.ResponseWriter, r *http.Request)
+	AddIncidentUpdate(w http.ResponseWriter, r *http.Request)
+	SimulateCapacity(w http.ResponseWriter, r *http.Request)
+	RecomputeScores(w http.ResponseWriter, r *http.Request)
+	GetTrustList(w http.ResponseWriter, r *http.Request)
+	CreateTrustListEntry(w http.ResponseWriter, r *http.Request)
+	DeleteTrustListEntry(w http.ResponseWriter, r *http.Request)
+	ImportUsers(w http.ResponseWriter, r *http.Request)
+	SetUserStatus(w http.ResponseWriter, r *http.Request)
+	ImpersonateUser(w http.ResponseWriter, r *http.Request)
+	GetAuditLog(w http.ResponseWriter, r *http.Request)
+	GetJudgeAudits(w http.ResponseWriter, r *http.Request)
+	GetLanguages(w http.ResponseWriter, r *http.Request)
+	CreateLanguage(w http.ResponseWriter, r *http.Request)
+	UpdateLanguage(w http.ResponseWriter, r *http.Request)
+	GetQueueFailures(w http.ResponseWriter, r *http.Request)
+	RequeueQueueFailure(w http.ResponseWriter, r *http.Request)
+	GetQuarantinedFiles(w http.ResponseWriter, r *http.Request)
+	GetRoles(w http.ResponseWriter, r *http.Request)
+	CreateRole(w http.ResponseWriter, r *http.Request)
+	UpdateRole(w http.ResponseWriter, r *http.Request)
+	DeleteRole(w http.ResponseWriter, r *http.Request)
+	AssignUserRole(w http.ResponseWriter, r *http.Request)
+}
+
+type AdminRouteImpl struct {
+	integrityService       service.IntegrityService
+	onlineMigrationService service.OnlineMigrationService
+	statusService          service.StatusService
+	submissionService      service.SubmissionService
+	trustListService       service.TrustListService
+	userService            service.UserService
+	judgeAuditService      service.JudgeAuditService
+	languageService        service.LanguageService
+	queueService           service.QueueService
+	uploadScanService      service.UploadScanService
+	accessControlService   service.AccessControlService
+	pagination             httputils.PaginationLimits
+}
+
+// GetOrphans godoc
+//
+//	@Tags			admin
+//	@Summary		Report orphaned records
+//	@Description	Returns the number of rows referencing missing records for every checked reference, and of files in the quarantine
+//	@Description	directory without a quarantined file row. Files in FileStorage are not checked
+//	@Produce		json
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.IntegrityReport]
+//	@Router			/admin/integrity/orphans [get]
+func (ar *AdminRouteImpl) GetOrphans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	dryRun := true
+	ar.checkOrphans(w, r, schemas.OrphanCleanupRequest{DryRun: &dryRun})
+}
+
+// CleanupOrphans godoc
+//
+//	@Tags			admin
+//	@Summary		Clean up orphaned records
+//	@Description	Deletes rows referencing missing records in batches, then removes files in the quarantine directory without a row.
+//	@Description	Runs as a dry run unless dry_run is explicitly set to false
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.OrphanCleanupRequest	true	"Cleanup options"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.IntegrityReport]
+//	@Router			/admin/integrity/orphans [post]
+func (ar *AdminRouteImpl) CleanupOrphans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request schemas.OrphanCleanupRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	ar.checkOrphans(w, r, request)
+}
+
+func (ar *AdminRouteImpl) checkOrphans(w http.ResponseWriter, r *http.Request, request schemas.OrphanCleanupRequest) {
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	report, err := ar.integrityService.CheckOrphans(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can check orphaned records.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid cleanup options.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error checking orphaned records. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, report)
+}
+
+// GetOnlineMigrations godoc
+//
+//	@Tags			admin
+//	@Summary		List online migrations
+//	@Description	Returns the phase and backfill progress of every online schema migration
+//	@Produce		json
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.OnlineMigration]
+//	@Router			/admin/migrations [get]
+func (ar *AdminRouteImpl) GetOnlineMigrations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	migrations, err := ar.onlineMigrationService.GetMigrations(tx, currentUser)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can view online migrations.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting online migrations. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, migrations)
+}
+
+// SetOnlineMigrationCutover godoc
+//
+//	@Tags			admin
+//	@Summary		Switch reads of an online migration
+//	@Description	Switches reads of a backfilled online migration to the new schema, or back to the old one
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string							true	"Migration name"
+//	@Param			request	body		schemas.OnlineMigrationCutover	true	"Cutover switch"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		409		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.OnlineMigration]
+//	@Router			/admin/migrations/{name}/cutover [put]
+func (ar *AdminRouteImpl) SetOnlineMigrationCutover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := r.PathValue("name")
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.OnlineMigrationCutover
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	migration, err := ar.onlineMigrationService.SetCutover(tx, currentUser, name, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can switch online migrations.")
+			return
+		}
+		if err == service.ErrOnlineMigrationNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Online migration not found.")
+			return
+		}
+		if err == service.ErrOnlineMigrationNotBackfilled {
+			httputils.ReturnError(w, http.StatusConflict, "Online migration is still being backfilled.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid cutover request.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error switching online migration. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, migration)
+}
+
+// ResumeOnlineMigration godoc
+//
+//	@Tags			admin
+//	@Summary		Resume the backfill of an online migration
+//	@Description	Resumes a backfill paused after too many batches failed in a row. Resuming a backfill which is not paused does nothing
+//	@Produce		json
+//	@Param			name	path		string	true	"Migration name"
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.OnlineMigration]
+//	@Router			/admin/migrations/{name}/resume [put]
+func (ar *AdminRouteImpl) ResumeOnlineMigration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := r.PathValue("name")
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	migration, err := ar.onlineMigrationService.ResumeBackfill(tx, currentUser, name)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can resume online migrations.")
+			return
+		}
+		if err == service.ErrOnlineMigrationNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Online migration not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error resuming online migration. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, migration)
+}
+
+// CreateIncident godoc
+//
+//	@Tags			admin
+//	@Summary		Report an incident
+//	@Description	Creates an incident shown on the status page together with its first update
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.IncidentCreate	true	"Incident"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.Incident]
+//	@Router			/admin/incidents [post]
+func (ar *AdminRouteImpl) CreateIncident(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.IncidentCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	incident, err := ar.statusService.CreateIncident(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can report incidents.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid incident.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating incident. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, incident)
+}
+
+// AddIncidentUpdate godoc
+//
+//	@Tags			admin
+//	@Summary		Post an incident update
+//	@Description	Adds an update to an incident and changes its status. Status resolved resolves the incident
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Incident ID"
+//	@Param			request	body		schemas.IncidentUpdateCreate	true	"Update"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.Incident]
+//	@Router			/admin/incidents/{id}/updates [post]
+func (ar *AdminRouteImpl) AddIncidentUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	incidentId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid incident ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.IncidentUpdateCreate
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	incident, err := ar.statusService.AddIncidentUpdate(tx, currentUser, incidentId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can post incident updates.")
+			return
+		}
+		if err == service.ErrIncidentNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Incident not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid incident update.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error posting incident update. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, incident)
+}
+
+// SimulateCapacity godoc
+//
+//	@Tags			admin
+//	@Summary		Simulate judge capacity
+//	@Description	Simulates queue depth and judging latency of the expected exam load on the given number of workers, using judging times per test of recent submissions. With a target latency the fewest workers meeting it are recommended
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.CapacitySimulationRequest	true	"Expected load"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.CapacitySimulation]
+//	@Router			/admin/capacity/simulate [post]
+func (ar *AdminRouteImpl) SimulateCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.CapacitySimulationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	simulation, err := ar.statusService.SimulateCapacity(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can simulate capacity.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid simulation request.", validationErrors)
+			return
+		}
+		if err == service.ErrSimulationTooLarge {
+			httputils.ReturnError(w, http.StatusBadRequest, fmt.Sprintf("Expected load is too large, at most %d submissions can be simulated.", service.MaxSimulatedSubmissions))
+			return
+		}
+		if err == service.ErrNoJudgeTimings {
+			httputils.ReturnError(w, http.StatusNotFound, "No submissions were judged recently, there are no judging times to simulate with.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error simulating capacity. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, simulation)
+}
+
+// RecomputeScores godoc
+//
+//	@Tags			admin
+//	@Summary		Recompute submission scores
+//	@Description	Recomputes aggregate scores of all submission results from stored test results in batches. Runs as a dry run reporting how many results would change unless dry_run is explicitly set to false
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.ScoreRecomputeRequest	true	"Recompute options"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.ScoreRecomputeReport]
+//	@Router			/admin/submission-results/recompute [post]
+func (ar *AdminRouteImpl) RecomputeScores(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.ScoreRecomputeRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	report, err := ar.submissionService.RecomputeScores(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can recompute scores.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid recompute options.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error recomputing scores. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, report)
+}
+
+// GetTrustList godoc
+//
+//	@Tags			admin
+//	@Summary		Get the trust list
+//	@Description	Returns IP ranges, API keys and users exempt from rate limiting and load shedding. API keys themselves are not returned
+//	@Produce		json
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.TrustListEntry]
+//	@Router			/admin/trust-list [get]
+func (ar *AdminRouteImpl) GetTrustList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	entries, err := ar.trustListService.GetEntries(tx, currentUser)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can manage the trust list.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting trust list. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, entries)
+}
+
+// CreateTrustListEntry godoc
+//
+//	@Tags			admin
+//	@Summary		Add a trust list entry
+//	@Description	Exempts an IP range, a user or a generated API key from rate limiting and load shedding. The API key is only returned in this response and is sent in the X-API-Key header. Changes apply to all replicas within seconds
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.TrustListEntryCreate	true	"Trust list entry"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.TrustListEntry]
+//	@Router			/admin/trust-list [post]
+func (ar *AdminRouteImpl) CreateTrustListEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TrustListEntryCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	entry, err := ar.trustListService.CreateEntry(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can manage the trust list.")
+			return
+		}
+		if err == service.ErrInvalidTrustListEntry {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid trust list entry. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid trust list entry.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating trust list entry. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, entry)
+}
+
+// DeleteTrustListEntry godoc
+//
+//	@Tags			admin
+//	@Summary		Delete a trust list entry
+//	@Description	Removes an entry from the trust list. Changes apply to all replicas within seconds
+//	@Produce		json
+//	@Param			id	path		int	true	"Trust list entry ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/admin/trust-list/{id} [delete]
+func (ar *AdminRouteImpl) DeleteTrustListEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	entryId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid trust list entry ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = ar.trustListService.DeleteEntry(tx, currentUser, entryId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can manage the trust list.")
+			return
+		}
+		if err == service.ErrTrustListEntryNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Trust list entry not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error deleting trust list entry. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Trust list entry deleted")
+}
+
+// GetLanguages godoc
+//
+//	@Tags			admin
+//	@Summary		Get languages
+//	@Description	Returns languages with the compiler flags, run arguments and limit multipliers passed to the worker
+//	@Produce		json
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.LanguageConfigDetailed]
+//	@Router			/admin/languages [get]
+func (ar *AdminRouteImpl) GetLanguages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	languages, err := ar.languageService.GetLanguages(tx, currentUser)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can manage languages.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting languages. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, languages)
+}
+
+// CreateLanguage godoc
+//
+//	@Tags			admin
+//	@Summary		Add a language
+//	@Description	Adds a language version submissions can be made in. Time and memory multipliers default to 1
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.LanguageConfigCreate	true	"Language"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		409		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.LanguageConfigDetailed]
+//	@Router			/admin/languages [post]
+func (ar *AdminRouteImpl) CreateLanguage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.LanguageConfigCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	language, err := ar.languageService.CreateLanguage(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can manage languages.")
+			return
+		}
+		if err == service.ErrLanguageExists {
+			httputils.ReturnError(w, http.StatusConflict, "Language with this version already exists.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid language.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating language. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, language)
+}
+
+// UpdateLanguage godoc
+//
+//	@Tags			admin
+//	@Summary		Configure a language
+//	@Description	Changes the fields of the language which are set. Submissions judged afterwards, including rejudges, use the new configuration
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Language ID"
+//	@Param			request	body		schemas.LanguageConfigUpdate	true	"Configuration"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.LanguageConfigDetailed]
+//	@Router			/admin/languages/{id} [patch]
+func (ar *AdminRouteImpl) UpdateLanguage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	languageId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid language ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.LanguageConfigUpdate
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	language, err := ar.languageService.UpdateLanguage(tx, currentUser, languageId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can manage languages.")
+			return
+		}
+		if err == service.ErrLanguageNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Language not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid language configuration.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating language. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, language)
+}
+
+// ImportUsers godoc
+//
+//	@Tags			admin
+//	@Summary		Import users
+//	@Description	Creates users from a CSV file with a header naming the name, surname, email, username and role columns, and optionally
+//	@Description	password. Users without a password get a generated one, returned once in the report. Invalid rows are reported and
+//	@Description	skipped, the others are created. At most 1000 users can be imported at once
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file	formData	file	true	"CSV file of users"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		413		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.UserImportReport]
+//	@Router			/admin/users/import [post]
+func (ar *AdminRouteImpl) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	// The body size is limited by BodyLimitMiddleware
+	if err := httputils.ParseMultipartForm(r); err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Error retrieving the file. No users file found.")
+		return
+	}
+	defer file.Close()
+	if !strings.HasSuffix(strings.ToLower(handler.Filename), ".csv") {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid file format. Only .csv files are allowed, export spreadsheets as CSV. Received: "+handler.Filename)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	report, err := ar.userService.ImportUsers(tx, currentUser, file)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can import users.")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidUserImport) {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid users file. "+err.Error())
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error importing users. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, report)
+}
+
+// SetUserStatus godoc
+//
+//	@Tags			admin
+//	@Summary		Set the status of a user
+//	@Description	Deactivates, bans or reactivates a user. Deactivated and banned users cannot log in, their sessions are rejected
+//	@Description	with 403 and their refresh tokens are revoked. The change is recorded in the audit log
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"User ID"
+//	@Param			request	body		schemas.UserStatusEdit	true	"Status"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.User]
+//	@Router			/admin/users/{id}/status [patch]
+func (ar *AdminRouteImpl) SetUserStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.UserStatusEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	user, err := ar.userService.SetUserStatus(tx, currentUser, userId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can change the status of users.")
+			return
+		}
+		if err == service.ErrOwnUserStatus {
+			httputils.ReturnError(w, http.StatusBadRequest, "You cannot change the status of your own account.")
+			return
+		}
+		if err == service.ErrUserNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "User not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid user status.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error changing user status. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, user)
+}
+
+// ImpersonateUser godoc
+//
+//	@Tags			admin
+//	@Summary		Impersonate a user
+//	@Description	Returns a session of the user valid for 15 minutes, without a refresh token, so an admin can see what the user sees.
+//	@Description	Admins, users with permissions the current user lacks and deactivated or banned users cannot be impersonated.
+//	@Description	Every impersonation is recorded in the audit log
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		409	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		201	{object}	httputils.ApiResponse[schemas.Session]
+//	@Router			/admin/users/{id}/impersonate [post]
+func (ar *AdminRouteImpl) ImpersonateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	session, err := ar.userService.ImpersonateUser(tx, currentUser, userId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can impersonate users.")
+			return
+		}
+		if err == service.ErrCannotImpersonate {
+			httputils.ReturnError(w, http.StatusForbidden, "You cannot impersonate yourself, admins or users with permissions you lack.")
+			return
+		}
+		if err == service.ErrUserNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "User not found.")
+			return
+		}
+		if err == service.ErrUserInactive {
+			httputils.ReturnError(w, http.StatusConflict, "The user is deactivated or banned.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error impersonating user. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, session)
+}
+
+// GetAuditLog godoc
+//
+//	@Tags			admin
+//	@Summary		Get the audit log
+//	@Description	Returns status changes and impersonations of user accounts by admins, newest first
+//	@Produce		json
+//	@Param			user_id	query		int	false	"Only entries about this user"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.AuditLogEntry]
+//	@Router			/admin/audit-log [get]
+func (ar *AdminRouteImpl) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	query := r.URL.Query()
+	var targetUserId *int64
+	if userIdStr := query.Get("user_id"); userIdStr != "" {
+		userId, err := strconv.ParseInt(userIdStr, 10, 64)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid user ID.")
+			return
+		}
+		targetUserId = &userId
+	}
+	limit, offset, err := httputils.GetPagination(query, ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	entries, err := ar.userService.GetAuditLog(tx, currentUser, targetUserId, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can see the audit log.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting audit log. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, entries, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// GetJudgeAudits godoc
+//
+//	@Tags			admin
+//	@Summary		Get judge audits
+//	@Description	Returns re-runs of randomly sampled judged submissions, newest first. A discrepancy means the re-run gave another verdict than the recorded one, which hints at judge environment drift
+//	@Produce		json
+//	@Param			discrepancies	query		bool	false	"Only audits with a discrepancy"
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			offset			query		int		false	"Offset"
+//	@Failure		400				{object}	httputils.ApiError
+//	@Failure		403				{object}	httputils.ApiError
+//	@Failure		405				{object}	httputils.ApiError
+//	@Failure		500				{object}	httputils.ApiError
+//	@Success		200				{object}	httputils.ApiPageResponse[[]schemas.JudgeAudit]
+//	@Router			/admin/judge-audits [get]
+func (ar *AdminRouteImpl) GetJudgeAudits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	query := r.URL.Query()
+	discrepanciesOnly := false
+	discrepanciesStr := query.Get("discrepancies")
+	if discrepanciesStr != "" {
+		var err error
+		discrepanciesOnly, err = strconv.ParseBool(discrepanciesStr)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid discrepancies flag.")
+			return
+		}
+	}
+	limit, offset, err := httputils.GetPagination(query, ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	audits, err := ar.judgeAuditService.GetAudits(tx, currentUser, discrepanciesOnly, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can see judge audits.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting judge audits. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, audits, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// GetQueueFailures godoc
+//
+//	@Tags			admin
+//	@Summary		Get queue failures
+//	@Description	Returns submissions the worker failed to evaluate on every attempt, newest first
+//	@Produce		json
+//	@Param			pending	query		bool	false	"Only failures which were not requeued"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.QueueFailure]
+//	@Router			/admin/queue-failures [get]
+func (ar *AdminRouteImpl) GetQueueFailures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	query := r.URL.Query()
+	pendingOnly := false
+	pendingStr := query.Get("pending")
+	if pendingStr != "" {
+		var err error
+		pendingOnly, err = strconv.ParseBool(pendingStr)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid pending flag.")
+			return
+		}
+	}
+	limit, offset, err := httputils.GetPagination(query, ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	failures, err := ar.queueService.GetQueueFailures(tx, currentUser, pendingOnly, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can see queue failures.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting queue failures. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, failures, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// RequeueQueueFailure godoc
+//
+//	@Tags			admin
+//	@Summary		Requeue a queue failure
+//	@Description	Publishes the submission of the failure for evaluation again with a fresh set of attempts. A failure can be requeued once
+//	@Produce		json
+//	@Param			id	path		int	true	"Queue failure ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		409	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.QueueFailure]
+//	@Router			/admin/queue-failures/{id}/requeue [post]
+func (ar *AdminRouteImpl) RequeueQueueFailure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	failureId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid queue failure ID.")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	failure, err := ar.queueService.RequeueFailure(tx, currentUser, failureId)
+	if err != nil {
+		db.Rollback()
+		switch err {
+		case service.ErrNotAuthorized:
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can requeue queue failures.")
+		case service.ErrQueueFailureNotFound:
+			httputils.ReturnError(w, http.StatusNotFound, "Queue failure not found.")
+		case service.ErrQueueFailureRequeued:
+			httputils.ReturnError(w, http.StatusConflict, "Queue failure was already requeued.")
+		default:
+			httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error requeueing queue failure. %s", err.Error()))
+		}
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, failure)
+}
+
+// GetQuarantinedFiles godoc
+//
+//	@Tags			admin
+//	@Summary		Get quarantined files
+//	@Description	Returns uploaded task archives and solutions rejected by the virus scanner, newest first. The files are kept in the
+//	@Description	quarantine directory of the backend, named by their sha256
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.QuarantinedFile]
+//	@Router			/admin/quarantine [get]
+func (ar *AdminRouteImpl) GetQuarantinedFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	limit, offset, err := httputils.GetPagination(r.URL.Query(), ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	files, err := ar.uploadScanService.GetQuarantinedFiles(tx, currentUser, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can see quarantined files.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting quarantined files. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, files, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// GetRoles godoc
+//
+//	@Tags			admin
+//	@Summary		Get roles
+//	@Description	Returns the built-in student, teacher and admin roles followed by custom roles, with their permissions.
+//	@Description	A permission allows an action on a resource, on resources of the user with the own scope or on every resource with the all scope
+//	@Produce		json
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.Role]
+//	@Router			/admin/roles [get]
+func (ar *AdminRouteImpl) GetRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	roles, err := ar.accessControlService.GetRoles(tx, currentUser)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to manage roles.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting roles. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, roles)
+}
+
+// CreateRole godoc
+//
+//	@Tags			admin
+//	@Summary		Create a custom role
+//	@Description	Defines a role, such as a teaching assistant, out of permissions listed by the built-in roles. Users assigned the role
+//	@Description	may perform exactly these actions
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.RoleCreate	true	"Role"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		409		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.Role]
+//	@Router			/admin/roles [post]
+func (ar *AdminRouteImpl) CreateRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.RoleCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	role, err := ar.accessControlService.CreateRole(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to manage roles.")
+			return
+		}
+		if err == service.ErrRoleAlreadyExists {
+			httputils.ReturnError(w, http.StatusConflict, "Role already exists.")
+			return
+		}
+		if errors.Is(err, service.ErrUnknownPermission) {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid role. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid role.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating role. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, role)
+}
+
+// UpdateRole godoc
+//
+//	@Tags			admin
+//	@Summary		Update a custom role
+//	@Description	Replaces the description and permissions of a custom role. Users of the role are affected by their next request
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Role ID"
+//	@Param			request	body		schemas.RoleUpdate	true	"Role"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.Role]
+//	@Router			/admin/roles/{id} [put]
+func (ar *AdminRouteImpl) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	roleId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid role ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.RoleUpdate
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	role, err := ar.accessControlService.UpdateRole(tx, currentUser, roleId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to manage roles.")
+			return
+		}
+		if err == service.ErrRoleNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Role not found.")
+			return
+		}
+		if errors.Is(err, service.ErrUnknownPermission) {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid role. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid role.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating role. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, role)
+}
+
+// DeleteRole godoc
+//
+//	@Tags			admin
+//	@Summary		Delete a custom role
+//	@Description	Deletes a custom role. Roles assigned to users cannot be deleted, assign the users another role first
+//	@Produce		json
+//	@Param			id	path		int	true	"Role ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		409	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/admin/roles/{id} [delete]
+func (ar *AdminRouteImpl) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	roleId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid role ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = ar.accessControlService.DeleteRole(tx, currentUser, roleId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to manage roles.")
+			return
+		}
+		if err == service.ErrRoleNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Role not found.")
+			return
+		}
+		if err == service.ErrRoleInUse {
+			httputils.ReturnError(w, http.StatusConflict, "Role is assigned to users.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error deleting role. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Role deleted")
+}
+
+// AssignUserRole godoc
+//
+//	@Tags			admin
+//	@Summary		Assign a role to a user
+//	@Description	Assigns a built-in or custom role to another user. The change applies to the next request of the user and is recorded in the audit log
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"User ID"
+//	@Param			request	body		schemas.UserRoleEdit	true	"Role"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.User]
+//	@Router			/admin/users/{id}/role [put]
+func (ar *AdminRouteImpl) AssignUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.UserRoleEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	user, err := ar.accessControlService.AssignRole(tx, currentUser, userId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to manage roles.")
+			return
+		}
+		if err == service.ErrOwnRole {
+			httputils.ReturnError(w, http.StatusBadRequest, "You cannot change the role of your own account.")
+			return
+		}
+		if err == service.ErrRoleNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Role not found.")
+			return
+		}
+		if err == service.ErrUserNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "User not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid role.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error assigning role. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, user)
+}
+
+func NewAdminRoute(integrityService service.IntegrityService, onlineMigrationService service.OnlineMigrationService, statusService service.StatusService, submissionService service.SubmissionService, trustListService service.TrustListService, userService service.UserService, judgeAuditService service.JudgeAuditService, languageService service.LanguageService, queueService service.QueueService, uploadScanService service.UploadScanService, accessControlService service.AccessControlService, pagination httputils.PaginationLimits) AdminRoute {
+	return &AdminRouteImpl{
+		integrityService:       integrityService,
+		onlineMigrationService: onlineMigrationService,
+		statusService:          statusService,
+		submissionService:      submissionService,
+		trustListService:       trustListService,
+		userService:            userService,
+		judgeAuditService:      judgeAuditService,
+		languageService:        languageService,
+		queueService:           queueService,
+		uploadScanService:      uploadScanService,
+		accessControlService:   accessControlService,
+		pagination:             pagination,
+	}
+}
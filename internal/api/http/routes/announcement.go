@@ -0,0 +1,337 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type AnnouncementRoute interface {
+	GetAnnouncements(w http.ResponseWriter, r *http.Request)
+	CreateAnnouncement(w http.ResponseWriter, r *http.Request)
+	GetGroupAnnouncements(w http.ResponseWriter, r *http.Request)
+	CreateGroupAnnouncement(w http.ResponseWriter, r *http.Request)
+	CountUnreadAnnouncements(w http.ResponseWriter, r *http.Request)
+	MarkAnnouncementRead(w http.ResponseWriter, r *http.Request)
+}
+
+type AnnouncementRouteImpl struct {
+	announcementService service.AnnouncementService
+	pagination          httputils.PaginationLimits
+}
+
+// GetAnnouncements godoc
+//
+//	@Tags			announcement
+//	@Summary		Get announcements
+//	@Description	Returns announcements to every user and to groups of the current user which are published and did not expire, newest first
+//	@Produce		json
+//	@Param			unread	query		bool	false	"Only announcements the current user did not read"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.Announcement]
+//	@Router			/announcement/ [get]
+func (ar *AnnouncementRouteImpl) GetAnnouncements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	unreadOnly := false
+	unreadStr := query.Get("unread")
+	if unreadStr != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(unreadStr)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid unread flag.")
+			return
+		}
+	}
+	limit, offset, err := httputils.GetPagination(query, ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	announcements, err := ar.announcementService.GetAnnouncements(tx, currentUser, unreadOnly, limit, offset)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting announcements. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, announcements, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// CreateAnnouncement godoc
+//
+//	@Tags			announcement
+//	@Summary		Publish an announcement
+//	@Description	Publishes an announcement to every user or to members of a group, right away or at published_at, until expires_at.
+//	@Description	Only admins can announce to every user, teachers and admins can announce to a group.
+//	@Description	Members of the group are notified about announcements published right away
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.AnnouncementCreate	true	"Announcement"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.Announcement]
+//	@Router			/announcement/ [post]
+func (ar *AnnouncementRouteImpl) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.AnnouncementCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	ar.createAnnouncement(w, r, currentUser, request)
+}
+
+// GetGroupAnnouncements godoc
+//
+//	@Tags			announcement
+//	@Summary		Get announcements to a group
+//	@Description	Returns published announcements to the group, expired ones included, newest first.
+//	@Description	Available to members of the group, teachers and admins
+//	@Produce		json
+//	@Param			id		path		int	true	"Group ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.Announcement]
+//	@Router			/group/{id}/announcements [get]
+func (ar *AnnouncementRouteImpl) GetGroupAnnouncements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid group ID.")
+		return
+	}
+	limit, offset, err := httputils.GetPagination(r.URL.Query(), ar.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	announcements, err := ar.announcementService.GetGroupAnnouncements(tx, currentUser, groupId, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only members of the group, teachers and admins can see its announcements.")
+			return
+		}
+		if err == service.ErrGroupNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Group not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting announcements. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, announcements, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// CreateGroupAnnouncement godoc
+//
+//	@Tags			announcement
+//	@Summary		Publish an announcement to a group
+//	@Description	Publishes an announcement to members of the group, right away or at published_at, until expires_at.
+//	@Description	Members are notified in the app and by mail when mail is enabled. group_id of the body is ignored.
+//	@Description	Only teachers and admins can announce to a group
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Group ID"
+//	@Param			request	body		schemas.AnnouncementCreate	true	"Announcement"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.Announcement]
+//	@Router			/group/{id}/announcements [post]
+func (ar *AnnouncementRouteImpl) CreateGroupAnnouncement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid group ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.AnnouncementCreate
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+	request.GroupId = &groupId
+
+	ar.createAnnouncement(w, r, currentUser, request)
+}
+
+func (ar *AnnouncementRouteImpl) createAnnouncement(w http.ResponseWriter, r *http.Request, currentUser schemas.User, request schemas.AnnouncementCreate) {
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	announcement, err := ar.announcementService.CreateAnnouncement(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can announce to every user, and only teachers and admins to a group.")
+			return
+		}
+		if err == service.ErrGroupNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Group not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid announcement.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error publishing announcement. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, announcement)
+}
+
+// CountUnreadAnnouncements godoc
+//
+//	@Tags			announcement
+//	@Summary		Count unread announcements
+//	@Description	Returns the number of announcements currently visible to the current user which the user did not read
+//	@Produce		json
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.UnreadAnnouncements]
+//	@Router			/announcement/unread [get]
+func (ar *AnnouncementRouteImpl) CountUnreadAnnouncements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	unread, err := ar.announcementService.CountUnreadAnnouncements(tx, currentUser)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error counting unread announcements. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, unread)
+}
+
+// MarkAnnouncementRead godoc
+//
+//	@Tags			announcement
+//	@Summary		Mark an announcement read
+//	@Description	Marks an announcement visible to the current user read. Marking it again keeps the time it was first read
+//	@Produce		json
+//	@Param			id	path		int	true	"Announcement ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/announcement/{id}/read [put]
+func (ar *AnnouncementRouteImpl) MarkAnnouncementRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	announcementId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid announcement ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = ar.announcementService.MarkAnnouncementRead(tx, currentUser, announcementId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrAnnouncementNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Announcement not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error marking announcement read. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Announcement marked read")
+}
+
+func NewAnnouncementRoute(announcementService service.AnnouncementService, pagination httputils.PaginationLimits) AnnouncementRoute {
+	return &AnnouncementRouteImpl{announcementService: announcementService, pagination: pagination}
+}
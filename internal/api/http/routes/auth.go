@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/mini-maxit/backend/internal/api/http/httputils"
 	"github.com/mini-maxit/backend/internal/api/http/middleware"
 	"github.com/mini-maxit/backend/internal/database"
@@ -11,9 +12,18 @@ import (
 	"github.com/mini-maxit/backend/package/service"
 )
 
+// oauthClientCookie binds an OAuth login to the client that started it
+const oauthClientCookie = "oauth_client"
+
 type AuthRoute interface {
 	Login(w http.ResponseWriter, r *http.Request)
 	Register(w http.ResponseWriter, r *http.Request)
+	Refresh(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+	OAuthLogin(w http.ResponseWriter, r *http.Request)
+	OAuthCallback(w http.ResponseWriter, r *http.Request)
 }
 
 type AuthRouteImpl struct {
@@ -25,15 +35,18 @@ type AuthRouteImpl struct {
 //
 //	@Tags			auth
 //	@Summary		Login a user
-//	@Description	Logs in a user with email and password
+//	@Description	Logs in a user with email and password. When LDAP login is enabled, emails found in the directory are checked against it, others against their local password
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		schemas.UserLoginRequest	true	"User Login Request"
 //	@Failure		400		{object}	httputils.ApiError
 //	@Failure		401		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
 //	@Failure		500		{object}	httputils.ApiError
+//	@Failure		503		{object}	httputils.ApiError
 //	@Success		200		{object}	httputils.ApiResponse[schemas.Session]
-//	@Router			/login [post]
+//	@Router			/auth/login [post]
 func (ar *AuthRouteImpl) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -44,7 +57,7 @@ func (ar *AuthRouteImpl) Login(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid request body. "+err.Error())
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
 
@@ -65,6 +78,18 @@ func (ar *AuthRouteImpl) Login(w http.ResponseWriter, r *http.Request) {
 			httputils.ReturnError(w, http.StatusUnauthorized, "Invalid credentials. Verify your email and password and try again.")
 			return
 		}
+		if err == service.ErrUserInactive {
+			httputils.ReturnError(w, http.StatusForbidden, "Your account is deactivated or banned.")
+			return
+		}
+		if err == service.ErrLDAPUnavailable {
+			httputils.ReturnError(w, http.StatusServiceUnavailable, "The directory server is not available. Try again later.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid login request.", validationErrors)
+			return
+		}
 		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to login. "+err.Error())
 		return
 	}
@@ -83,7 +108,7 @@ func (ar *AuthRouteImpl) Login(w http.ResponseWriter, r *http.Request) {
 //	@Failure		405		{object}	httputils.ApiError
 //	@Failure		500		{object}	httputils.ApiError
 //	@Success		201		{object}	httputils.ApiResponse[schemas.Session]
-//	@Router			/register [post]
+//	@Router			/auth/register [post]
 func (ar *AuthRouteImpl) Register(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -94,7 +119,7 @@ func (ar *AuthRouteImpl) Register(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid request body. "+err.Error())
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
 	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
@@ -113,6 +138,10 @@ func (ar *AuthRouteImpl) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	default:
 		db.Rollback()
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid register request.", validationErrors)
+			return
+		}
 		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to register. "+err.Error())
 		return
 	}
@@ -120,6 +149,377 @@ func (ar *AuthRouteImpl) Register(w http.ResponseWriter, r *http.Request) {
 	httputils.ReturnSuccess(w, http.StatusCreated, session)
 }
 
+// Refresh godoc
+//
+//	@Tags			auth
+//	@Summary		Refresh a session
+//	@Description	Exchanges a refresh token for an extended session and a new refresh token. The used refresh token is revoked
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.RefreshTokenRequest	true	"Refresh Token Request"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		401		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.Session]
+//	@Router			/auth/refresh [post]
+func (ar *AuthRouteImpl) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request schemas.RefreshTokenRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	session, err := ar.authService.Refresh(tx, request)
+	if err != nil {
+		if err == service.ErrInvalidRefreshToken {
+			// Revocation of reused tokens must be kept, so the transaction is committed
+			httputils.ReturnError(w, http.StatusUnauthorized, "Invalid refresh token. Log in again.")
+			return
+		}
+		db.Rollback()
+		if err == service.ErrUserInactive {
+			httputils.ReturnError(w, http.StatusForbidden, "Your account is deactivated or banned.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid refresh request.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to refresh session. "+err.Error())
+		return
+	}
+	httputils.ReturnSuccess(w, http.StatusOK, session)
+}
+
+// Logout godoc
+//
+//	@Tags			auth
+//	@Summary		Logout a user
+//	@Description	Revokes the refresh token and invalidates the session issued with it
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.RefreshTokenRequest	true	"Refresh Token Request"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		401		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/auth/logout [post]
+func (ar *AuthRouteImpl) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request schemas.RefreshTokenRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = ar.authService.Logout(tx, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrInvalidRefreshToken {
+			httputils.ReturnError(w, http.StatusUnauthorized, "Invalid refresh token.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid logout request.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to logout. "+err.Error())
+		return
+	}
+	httputils.ReturnSuccess(w, http.StatusOK, "Logged out")
+}
+
+// ForgotPassword godoc
+//
+//	@Tags			auth
+//	@Summary		Request a password reset
+//	@Description	Mails a password reset link to the user. The response is the same whether the email is registered or not
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.ForgotPasswordRequest	true	"Forgot Password Request"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Failure		503		{object}	httputils.ApiError
+//	@Success		202		{object}	httputils.ApiResponse[string]
+//	@Router			/auth/forgot-password [post]
+func (ar *AuthRouteImpl) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request schemas.ForgotPasswordRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = ar.authService.ForgotPassword(tx, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrPasswordResetDisabled {
+			httputils.ReturnError(w, http.StatusServiceUnavailable, "Password reset is not available.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid forgot password request.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to request password reset. "+err.Error())
+		return
+	}
+	httputils.ReturnSuccess(w, http.StatusAccepted, "If the email is registered, a password reset link was sent to it")
+}
+
+// ResetPassword godoc
+//
+//	@Tags			auth
+//	@Summary		Reset password
+//	@Description	Sets a new password with a token from a password reset mail. The token can be used once, refresh tokens of the user are revoked
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.ResetPasswordRequest	true	"Reset Password Request"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		401		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/auth/reset-password [post]
+func (ar *AuthRouteImpl) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request schemas.ResetPasswordRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = ar.authService.ResetPassword(tx, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrInvalidPasswordResetToken {
+			httputils.ReturnError(w, http.StatusUnauthorized, "Invalid password reset token. Request a new password reset.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid reset password request.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to reset password. "+err.Error())
+		return
+	}
+	httputils.ReturnSuccess(w, http.StatusOK, "Password reset")
+}
+
+// OAuthLogin godoc
+//
+//	@Tags			auth
+//	@Summary		Start a login with an OAuth provider
+//	@Description	Returns the login page of an OpenID Connect provider configured with OAUTH_PROVIDERS, such as Google or the SSO of
+//	@Description	a university. The provider redirects back to its redirect url with a code and a state valid for 10 minutes.
+//	@Description	The state is bound to the client by the HttpOnly oauth_client cookie, which has to be sent with the callback
+//	@Produce		json
+//	@Param			provider	path		string	true	"Provider name"
+//	@Failure		401			{object}	httputils.ApiError
+//	@Failure		404			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		500			{object}	httputils.ApiError
+//	@Success		200			{object}	httputils.ApiResponse[schemas.OAuthLogin]
+//	@Router			/auth/oauth/{provider}/login [get]
+func (ar *AuthRouteImpl) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	provider := r.PathValue("provider")
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	login, err := ar.authService.OAuthLogin(tx, provider)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrOAuthProviderNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "OAuth provider not found.")
+			return
+		}
+		if err == service.ErrOAuthLoginFailed {
+			httputils.ReturnError(w, http.StatusUnauthorized, "The OAuth provider is not available. Try again later.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to start OAuth login. "+err.Error())
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthClientCookie,
+		Value:    login.ClientNonce,
+		Path:     "/",
+		MaxAge:   int(service.OAuthStateLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	httputils.ReturnSuccess(w, http.StatusOK, login)
+}
+
+// OAuthCallback godoc
+//
+//	@Tags			auth
+//	@Summary		Finish a login with an OAuth provider
+//	@Description	Logs in with the code and state the provider redirected back with. The account linked to the identity at the
+//	@Description	provider is logged in. On the first login the account with the same email is linked if the provider verified the
+//	@Description	email, otherwise an account without a password is created with the role given by the role rules of the provider.
+//	@Description	The state is only accepted together with the oauth_client cookie set when the login was started
+//	@Produce		json
+//	@Param			provider	path		string	true	"Provider name"
+//	@Param			code		query		string	true	"Authorization code"
+//	@Param			state		query		string	true	"State"
+//	@Param			error		query		string	false	"Error returned by the provider instead of a code"
+//	@Failure		400			{object}	httputils.ApiError
+//	@Failure		401			{object}	httputils.ApiError
+//	@Failure		403			{object}	httputils.ApiError
+//	@Failure		404			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		500			{object}	httputils.ApiError
+//	@Success		200			{object}	httputils.ApiResponse[schemas.Session]
+//	@Router			/auth/oauth/{provider}/callback [get]
+func (ar *AuthRouteImpl) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	provider := r.PathValue("provider")
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		httputils.ReturnError(w, http.StatusUnauthorized, "Login with the OAuth provider failed. "+providerError)
+		return
+	}
+	request := schemas.OAuthCallbackRequest{Code: query.Get("code"), State: query.Get("state")}
+	if cookie, err := r.Cookie(oauthClientCookie); err == nil {
+		request.ClientNonce = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthClientCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	codeVerifier, err := ar.authService.UseOAuthState(tx, provider, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrOAuthProviderNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "OAuth provider not found.")
+			return
+		}
+		if err == service.ErrInvalidOAuthState {
+			httputils.ReturnError(w, http.StatusUnauthorized, "Invalid OAuth state. Start the login again.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid OAuth callback.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to login. "+err.Error())
+		return
+	}
+	// The state stays used even if the login below fails and is rolled back
+	err = db.Commit()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to use OAuth state. "+err.Error())
+		return
+	}
+	tx, err = db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to start transaction. "+err.Error())
+		return
+	}
+
+	session, err := ar.authService.OAuthCallback(tx, provider, request.Code, codeVerifier)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrOAuthProviderNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "OAuth provider not found.")
+			return
+		}
+		if err == service.ErrOAuthLoginFailed {
+			httputils.ReturnError(w, http.StatusUnauthorized, "Login with the OAuth provider failed. Start the login again.")
+			return
+		}
+		if err == service.ErrUserInactive {
+			httputils.ReturnError(w, http.StatusForbidden, "Your account is deactivated or banned.")
+			return
+		}
+		if err == service.ErrOAuthEmailNotVerified {
+			httputils.ReturnError(w, http.StatusForbidden, "The OAuth provider did not verify your email, so it cannot be used to log in.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, "Failed to login. "+err.Error())
+		return
+	}
+	httputils.ReturnSuccess(w, http.StatusOK, session)
+}
+
 func NewAuthRoute(userService service.UserService, authService service.AuthService) AuthRoute {
 	return &AuthRouteImpl{
 		userService: userService,
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type LimitsRoute interface {
+	GetLimits(w http.ResponseWriter, r *http.Request)
+}
+
+type LimitsRouteImpl struct {
+	limitsService service.LimitsService
+	isTrusted     middleware.TrustedRequestFunc
+}
+
+// GetLimits godoc
+//
+//	@Tags			limits
+//	@Summary		Get effective limits
+//	@Description	Returns upload sizes, rate limits and languages applying to the current user, so clients can disable actions instead of running into errors
+//	@Produce		json
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.Limits]
+//	@Router			/limits [get]
+func (lr *LimitsRouteImpl) GetLimits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	limits, err := lr.limitsService.GetLimits(tx, currentUser, lr.isTrusted(r))
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting limits. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, limits)
+}
+
+func NewLimitsRoute(limitsService service.LimitsService, isTrusted middleware.TrustedRequestFunc) LimitsRoute {
+	return &LimitsRouteImpl{limitsService: limitsService, isTrusted: isTrusted}
+}
@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type NotificationRoute interface {
+	GetNotifications(w http.ResponseWriter, r *http.Request)
+	MarkNotificationRead(w http.ResponseWriter, r *http.Request)
+}
+
+type NotificationRouteImpl struct {
+	notificationService service.NotificationService
+	pagination          httputils.PaginationLimits
+}
+
+// GetNotifications godoc
+//
+//	@Tags			notification
+//	@Summary		Get notifications
+//	@Description	Returns notifications of the current user about changes made by other users, such as being added as a task co-author, newest first
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.Notification]
+//	@Router			/notification/ [get]
+func (nr *NotificationRouteImpl) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, offset, err := httputils.GetPagination(query, nr.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	notifications, err := nr.notificationService.GetNotifications(tx, currentUser, limit, offset)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting notifications. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, notifications, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// MarkNotificationRead godoc
+//
+//	@Tags			notification
+//	@Summary		Mark a notification read
+//	@Description	Marks a notification of the current user read. Marking it again keeps the time it was first read
+//	@Produce		json
+//	@Param			id	path		int	true	"Notification ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/notification/{id}/read [put]
+func (nr *NotificationRouteImpl) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	notificationId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid notification ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = nr.notificationService.MarkNotificationRead(tx, currentUser, notificationId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotificationNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Notification not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error marking notification read. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Notification marked read")
+}
+
+func NewNotificationRoute(notificationService service.NotificationService, pagination httputils.PaginationLimits) NotificationRoute {
+	return &NotificationRouteImpl{notificationService: notificationService, pagination: pagination}
+}
@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type PlagiarismRoute interface {
+	CheckPlagiarism(w http.ResponseWriter, r *http.Request)
+	GetPlagiarismReport(w http.ResponseWriter, r *http.Request)
+}
+
+type PlagiarismRouteImpl struct {
+	plagiarismService service.PlagiarismService
+}
+
+// CheckPlagiarism godoc
+//
+//	@Tags			task
+//	@Summary		Check submissions of a task for plagiarism
+//	@Description	Compares the latest accepted submission of each user with submissions of other users in the same language and stores their similarity,
+//	@Description	replacing the previous check. Returns pairs with similarity of at least 0.8. Only admins and the task author can check
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		413	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.PlagiarismReport]
+//	@Router			/task/{id}/plagiarism [post]
+func (pr *PlagiarismRouteImpl) CheckPlagiarism(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	report, err := pr.plagiarismService.CheckTask(tx, currentUser, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and the task author can check for plagiarism.")
+			return
+		}
+		if err == service.ErrPlagiarismTooLarge {
+			httputils.ReturnError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Task has more than %d accepted submissions to check.", service.MaxPlagiarismSubmissions))
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error checking for plagiarism. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, report)
+}
+
+// GetPlagiarismReport godoc
+//
+//	@Tags			task
+//	@Summary		Get the plagiarism report of a task
+//	@Description	Returns pairs of submissions found by the last plagiarism check of the task with similarity of at least the threshold, most similar first.
+//	@Description	Only admins and the task author can see the report
+//	@Produce		json
+//	@Param			id			path		int		true	"Task ID"
+//	@Param			threshold	query		number	false	"Lowest similarity reported, from 0 to 1. Defaults to 0.8"
+//	@Failure		400			{object}	httputils.ApiError
+//	@Failure		403			{object}	httputils.ApiError
+//	@Failure		404			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		500			{object}	httputils.ApiError
+//	@Success		200			{object}	httputils.ApiResponse[schemas.PlagiarismReport]
+//	@Router			/task/{id}/plagiarism [get]
+func (pr *PlagiarismRouteImpl) GetPlagiarismReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	threshold := service.DefaultPlagiarismThreshold
+	if thresholdStr := r.URL.Query().Get("threshold"); thresholdStr != "" {
+		threshold, err = strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || threshold < 0 || threshold > 1 {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid threshold, it has to be a number from 0 to 1.")
+			return
+		}
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	report, err := pr.plagiarismService.GetReport(tx, currentUser, taskId, threshold)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrPlagiarismNotChecked {
+			httputils.ReturnError(w, http.StatusNotFound, "Submissions of the task were not checked for plagiarism yet.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and the task author can see the plagiarism report.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting plagiarism report. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, report)
+}
+
+func NewPlagiarismRoute(plagiarismService service.PlagiarismService) PlagiarismRoute {
+	return &PlagiarismRouteImpl{plagiarismService: plagiarismService}
+}
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/service"
+
+	// Types of the swag annotations
+	_ "github.com/mini-maxit/backend/package/domain/schemas"
+)
+
+type SandboxRoute interface {
+	GetSandboxTasks(w http.ResponseWriter, r *http.Request)
+	GetSandboxTask(w http.ResponseWriter, r *http.Request)
+}
+
+type SandboxRouteImpl struct {
+	taskService service.TaskService
+	pagination  httputils.PaginationLimits
+}
+
+// GetSandboxTasks godoc
+//
+//	@Tags			sandbox
+//	@Summary		Get sandbox tasks
+//	@Description	Returns tasks of the public practice sandbox. Does not require a session, requests are rate limited per IP
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		429		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.Task]
+//	@Router			/sandbox/tasks [get]
+func (sr *SandboxRouteImpl) GetSandboxTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, offset, err := httputils.GetPagination(query, sr.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	tasks, err := sr.taskService.GetSandboxTasks(tx, limit, offset)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting sandbox tasks. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, tasks, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// GetSandboxTask godoc
+//
+//	@Tags			sandbox
+//	@Summary		Get a sandbox task
+//	@Description	Returns a sandbox task with the URL of its statement. Does not require a session, requests are rate limited per IP
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		429	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.TaskDetailed]
+//	@Router			/sandbox/tasks/{id} [get]
+func (sr *SandboxRouteImpl) GetSandboxTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	task, err := sr.taskService.GetSandboxTask(tx, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting sandbox task. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, task)
+}
+
+func NewSandboxRoute(taskService service.TaskService, pagination httputils.PaginationLimits) SandboxRoute {
+	return &SandboxRouteImpl{taskService: taskService, pagination: pagination}
+}
@@ -21,6 +21,20 @@ type SessionRouteImpl struct {
 	sessionService service.SessionService
 }
 
+// CreateSession godoc
+//
+//	@Tags			session
+//	@Summary		Create a session
+//	@Description	Creates a session for a user. Deprecated, sessions are created by logging in
+//	@Accept			json
+//	@Produce		json
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		413	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.Session]
+//	@Deprecated
+//	@Router			/session/ [post]
 func (sr *SessionRouteImpl) CreateSession(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -33,7 +47,7 @@ func (sr *SessionRouteImpl) CreateSession(w http.ResponseWriter, r *http.Request
 
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid request body. "+err.Error())
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
 	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
@@ -61,10 +75,15 @@ func (sr *SessionRouteImpl) CreateSession(w http.ResponseWriter, r *http.Request
 //	@Param			Session	header		string	true	"Session Token"
 //	@Failure		400		{object}	httputils.ApiError
 //	@Failure		401		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
 //	@Failure		500		{object}	httputils.ApiError
-//	@Success		200		{object}	httputils.ApiResponse[[]schemas.Task]
+//	@Success		200		{object}	httputils.ApiResponse[schemas.ValidateSessionResponse]
 //	@Router			/session/validate [get]
 func (sr *SessionRouteImpl) ValidateSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
 	sessionToken := r.Header.Get("Session")
 	if sessionToken == "" {
 		httputils.ReturnError(w, http.StatusUnauthorized, "Session token is empty")
@@ -0,0 +1,320 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type StatsRoute interface {
+	GetGroupStats(w http.ResponseWriter, r *http.Request)
+	GetGroupProgress(w http.ResponseWriter, r *http.Request)
+	ExportGroupStats(w http.ResponseWriter, r *http.Request)
+	SetTaskStatsVisibility(w http.ResponseWriter, r *http.Request)
+}
+
+type StatsRouteImpl struct {
+	statsService service.StatsService
+}
+
+// GetGroupStats godoc
+//
+//	@Tags			group
+//	@Summary		Get group statistics
+//	@Description	Returns solved tasks and best scores of each member for tasks assigned to the group, average scores per task and
+//	@Description	submissions of the members per day up to today. Only teachers and admins can see statistics of groups
+//	@Produce		json
+//	@Param			id		path		int	true	"Group ID"
+//	@Param			days	query		int	false	"Days of activity, 30 by default and at most 365"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.GroupStats]
+//	@Router			/group/{id}/stats [get]
+func (sr *StatsRouteImpl) GetGroupStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid group ID.")
+		return
+	}
+	days := service.DefaultGroupActivityDays
+	daysStr := r.URL.Query().Get("days")
+	if daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > service.MaxGroupActivityDays {
+			httputils.ReturnError(w, http.StatusBadRequest, fmt.Sprintf("Days must be a number from 1 to %d.", service.MaxGroupActivityDays))
+			return
+		}
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	stats, err := sr.statsService.GetGroupStats(tx, currentUser, groupId, days)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrGroupNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Group not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can see statistics of groups.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting group statistics. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, stats)
+}
+
+// GetGroupProgress godoc
+//
+//	@Tags			group
+//	@Summary		Get group progress
+//	@Description	Returns for each task assigned to the group how many members submitted to it and solved it, and the median best
+//	@Description	score of the members who submitted. Only teachers and admins can see progress of groups
+//	@Produce		json
+//	@Param			id	path		int	true	"Group ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.GroupProgress]
+//	@Router			/group/{id}/progress [get]
+func (sr *StatsRouteImpl) GetGroupProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid group ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	progress, err := sr.statsService.GetGroupProgress(tx, currentUser, groupId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrGroupNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Group not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can see progress of groups.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting group progress. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, progress)
+}
+
+// ExportGroupStats godoc
+//
+//	@Tags			group
+//	@Summary		Export group statistics
+//	@Description	Returns a CSV file with a row for each member of the group, with the number of solved tasks, attempts, the average
+//	@Description	score and the best score for each task assigned to the group. Only teachers and admins can export statistics of groups
+//	@Produce		text/csv
+//	@Param			id			path		int		true	"Group ID"
+//	@Param			delimiter	query		string	false	"Field delimiter, comma by default. Excel in European locales expects semicolon"	Enums(comma, semicolon, tab)
+//	@Param			bom			query		bool	false	"Start the file with a UTF-8 byte order mark, so Excel shows non-ASCII characters correctly"
+//	@Failure		400			{object}	httputils.ApiError
+//	@Failure		403			{object}	httputils.ApiError
+//	@Failure		404			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		500			{object}	httputils.ApiError
+//	@Success		200			{file}		binary
+//	@Router			/group/{id}/stats/export [get]
+func (sr *StatsRouteImpl) ExportGroupStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid group ID.")
+		return
+	}
+	options, err := httputils.GetCSVOptions(r.URL.Query())
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid CSV options. "+err.Error())
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	// The export has no activity, so only the current day is counted
+	stats, err := sr.statsService.GetGroupStats(tx, currentUser, groupId, 1)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrGroupNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Group not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can export statistics of groups.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting group statistics. %s", err.Error()))
+		return
+	}
+
+	writer, err := httputils.StreamCSV(w, fmt.Sprintf("group_%d_stats.csv", groupId), options)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error exporting group statistics. %s", err.Error()))
+		return
+	}
+	header := []string{"user_id", "name", "surname", "solved", "attempts", "average_score"}
+	for _, task := range stats.Tasks {
+		header = append(header, task.Title)
+	}
+	if writer.Write(header) != nil {
+		return
+	}
+	for _, member := range stats.Members {
+		record := []string{
+			strconv.FormatInt(member.UserId, 10),
+			member.Name,
+			member.Surname,
+			strconv.FormatInt(member.Solved, 10),
+			strconv.FormatInt(member.Attempts, 10),
+			strconv.FormatFloat(member.AverageScore, 'f', -1, 64),
+		}
+		for _, score := range member.Scores {
+			if score == nil {
+				record = append(record, "")
+			} else {
+				record = append(record, strconv.FormatFloat(*score, 'f', -1, 64))
+			}
+		}
+		// The status is already sent, a client which went away only stops the stream
+		if writer.Write(record) != nil {
+			return
+		}
+	}
+	writer.Flush()
+}
+
+// SetTaskStatsVisibility godoc
+//
+//	@Tags			group
+//	@Summary		Hide or show task statistics to a group
+//	@Description	Sets whether members of the group see statistics of other users for a task assigned to the group, such as how many
+//	@Description	users solved it, or only their own results. Only teachers authoring or co-authoring the task and admins can change it
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"Group ID"
+//	@Param			task_id	path		int									true	"Task ID"
+//	@Param			request	body		schemas.GroupTaskStatsVisibility	true	"Visibility"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/group/{id}/task/{task_id}/stats-visibility [put]
+func (sr *StatsRouteImpl) SetTaskStatsVisibility(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid group ID.")
+		return
+	}
+	taskId, err := strconv.ParseInt(r.PathValue("task_id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.GroupTaskStatsVisibility
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = sr.statsService.SetTaskStatsVisibility(tx, currentUser, groupId, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrGroupNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Group not found.")
+			return
+		}
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrTaskNotInGroup {
+			httputils.ReturnError(w, http.StatusNotFound, "Task is not assigned to the group.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only authors of the task and admins can change visibility of task statistics.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid visibility.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating visibility of task statistics. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Visibility of task statistics updated")
+}
+
+func NewStatsRoute(statsService service.StatsService) StatsRoute {
+	return &StatsRouteImpl{statsService: statsService}
+}
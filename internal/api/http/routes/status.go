@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/service"
+
+	// Types of the swag annotations
+	_ "github.com/mini-maxit/backend/package/domain/schemas"
+)
+
+type StatusRoute interface {
+	GetStatus(w http.ResponseWriter, r *http.Request)
+}
+
+type StatusRouteImpl struct {
+	statusService service.StatusService
+}
+
+// GetStatus godoc
+//
+//	@Tags			status
+//	@Summary		Get service status
+//	@Description	Returns component health, the judge latency percentile and recent incidents for the status page. Does not require a session
+//	@Produce		json
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.Status]
+//	@Router			/status [get]
+func (sr *StatusRouteImpl) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	status, err := sr.statusService.GetStatus(tx)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting status. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, status)
+}
+
+func NewStatusRoute(statusService service.StatusService) StatusRoute {
+	return &StatusRouteImpl{statusService: statusService}
+}
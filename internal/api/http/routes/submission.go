@@ -0,0 +1,569 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type SubmissionRoute interface {
+	GetSubmission(w http.ResponseWriter, r *http.Request)
+	GetSubmissionSource(w http.ResponseWriter, r *http.Request)
+	GetSubmissionTimeline(w http.ResponseWriter, r *http.Request)
+	GetSubmissionsByEnvironment(w http.ResponseWriter, r *http.Request)
+	RedactSubmission(w http.ResponseWriter, r *http.Request)
+	RejudgeSubmission(w http.ResponseWriter, r *http.Request)
+	RejudgeTask(w http.ResponseWriter, r *http.Request)
+	GetRejudgeBatch(w http.ResponseWriter, r *http.Request)
+	ImportGrades(w http.ResponseWriter, r *http.Request)
+}
+
+type SubmissionRouteImpl struct {
+	submissionService service.SubmissionService
+	rejudgeService    service.RejudgeService
+	gradingService    service.GradingService
+	pagination        httputils.PaginationLimits
+}
+
+// GetSubmissionsByEnvironment godoc
+//
+//	@Tags			submission
+//	@Summary		Get submissions judged in an environment
+//	@Description	Returns submissions with the judge environment of their result, to find submissions to rejudge after a toolchain bug.
+//	@Description	At least one filter is required, unset filters match any value. Only teachers and admins can list submissions.
+//	@Description	Large listings should be paginated with the next_cursor of the previous page instead of an offset
+//	@Produce		json
+//	@Param			worker_version			query		string	false	"Judge worker version"
+//	@Param			compiler_version		query		string	false	"Compiler version"
+//	@Param			sandbox_image_digest	query		string	false	"Sandbox image digest"
+//	@Param			limit					query		int		false	"Limit"
+//	@Param			offset					query		int		false	"Offset"
+//	@Param			cursor					query		string	false	"Cursor of the next page, can not be combined with offset"
+//	@Failure		400						{object}	httputils.ApiError
+//	@Failure		403						{object}	httputils.ApiError
+//	@Failure		405						{object}	httputils.ApiError
+//	@Failure		500						{object}	httputils.ApiError
+//	@Success		200						{object}	httputils.ApiPageResponse[[]schemas.Submission]
+//	@Router			/submission/ [get]
+func (sr *SubmissionRouteImpl) GetSubmissionsByEnvironment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, offset, cursor, err := httputils.GetCursorPagination(query, sr.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	filter := schemas.JudgeEnvironmentFilter{
+		WorkerVersion:      query.Get("worker_version"),
+		CompilerVersion:    query.Get("compiler_version"),
+		SandboxImageDigest: query.Get("sandbox_image_digest"),
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	submissions, nextCursor, err := sr.submissionService.GetSubmissionsByEnvironment(tx, currentUser, filter, limit, offset, cursor)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can list submissions.")
+			return
+		}
+		if err == service.ErrInvalidCursor {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. Invalid cursor.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid submission filter.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting submissions. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, submissions, httputils.PageInfo{Limit: limit, Offset: offset, NextCursor: nextCursor})
+}
+
+// GetSubmission godoc
+//
+//	@Tags			submission
+//	@Summary		Get a submission
+//	@Description	Returns a submission with its result. While it is processing, progress lists the tests finished so far, so it can be polled to show tests completing one by one
+//	@Produce		json
+//	@Param			id	path		int	true	"Submission ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.Submission]
+//	@Router			/submission/{id} [get]
+func (sr *SubmissionRouteImpl) GetSubmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	submissionId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid submission ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	submission, err := sr.submissionService.GetSubmission(tx, currentUser, submissionId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrSubmissionNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Submission not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to see this submission.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting submission. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, submission)
+}
+
+// GetSubmissionTimeline godoc
+//
+//	@Tags			submission
+//	@Summary		Get the timeline of a submission
+//	@Description	Returns lifecycle events of a submission in the order they happened with the milliseconds since it was created: enqueued for
+//	@Description	every attempt, picked_up with the first progress report of the worker, each test, worker errors and the final result.
+//	@Description	Only admins and teachers of the task can see it
+//	@Produce		json
+//	@Param			id	path		int	true	"Submission ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.SubmissionTimeline]
+//	@Router			/submission/{id}/timeline [get]
+func (sr *SubmissionRouteImpl) GetSubmissionTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	submissionId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid submission ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	timeline, err := sr.submissionService.GetSubmissionTimeline(tx, currentUser, submissionId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrSubmissionNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Submission not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and teachers of the task can see the timeline of a submission.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting submission timeline. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, timeline)
+}
+
+// GetSubmissionSource godoc
+//
+//	@Tags			submission
+//	@Summary		Get the source of a submission
+//	@Description	Returns the submitted source file with the media type of its language, so it can be shown with syntax highlighting.
+//	@Description	It can be read by the same users as the submission. Redacted and pruned sources are gone
+//	@Produce		plain
+//	@Param			id	path		int	true	"Submission ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		410	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{file}		binary
+//	@Header			200	{string}	X-Language			"Language of the source"
+//	@Header			200	{string}	X-Language-Version	"Version of the language"
+//	@Router			/submission/{id}/source [get]
+func (sr *SubmissionRouteImpl) GetSubmissionSource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	submissionId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid submission ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	source, err := sr.submissionService.GetSubmissionSource(tx, currentUser, submissionId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrSubmissionNotFound || err == service.ErrFileNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Submission not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to see this submission.")
+			return
+		}
+		if err == service.ErrSubmissionRedacted {
+			httputils.ReturnError(w, http.StatusGone, "Source of the submission was redacted.")
+			return
+		}
+		if err == service.ErrSubmissionPruned {
+			httputils.ReturnError(w, http.StatusGone, "Source of the submission was pruned as a superseded failing attempt.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting submission source. %s", err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", source.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", source.FileName))
+	w.Header().Set("X-Language", source.Language)
+	w.Header().Set("X-Language-Version", source.LanguageVersion)
+	w.WriteHeader(http.StatusOK)
+	w.Write(source.Content)
+}
+
+// RedactSubmission godoc
+//
+//	@Tags			submission
+//	@Summary		Redact a submission
+//	@Description	Removes the source of a submission from file storage, for when personal data was submitted by accident. Result
+//	@Description	messages are cleared as they may quote the source, and plagiarism matches of the submission are deleted. Verdicts and
+//	@Description	scores are kept. Only admins and the task author can redact
+//	@Produce		json
+//	@Param			id	path		int	true	"Submission ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/submission/{id}/redact [post]
+func (sr *SubmissionRouteImpl) RedactSubmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	submissionId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid submission ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = sr.submissionService.RedactSubmission(tx, currentUser, submissionId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrSubmissionNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Submission not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and the task author can redact submissions.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error redacting submission. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Submission redacted")
+}
+
+// RejudgeSubmission godoc
+//
+//	@Tags			submission
+//	@Summary		Rejudge a submission
+//	@Description	Publishes a judged submission to be evaluated again as a rejudge batch of one. Its new result supersedes the previous one. Only admins and the task author can rejudge
+//	@Produce		json
+//	@Param			id	path		int	true	"Submission ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		409	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		202	{object}	httputils.ApiResponse[schemas.RejudgeBatch]
+//	@Router			/submission/{id}/rejudge [post]
+func (sr *SubmissionRouteImpl) RejudgeSubmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	submissionId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid submission ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	batch, err := sr.rejudgeService.RejudgeSubmission(tx, currentUser, submissionId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrSubmissionNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Submission not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and the task author can rejudge submissions.")
+			return
+		}
+		if err == service.ErrSubmissionNotJudged || err == service.ErrSubmissionRedacted || err == service.ErrSubmissionPruned {
+			httputils.ReturnError(w, http.StatusConflict, fmt.Sprintf("Submission cannot be rejudged, %s.", err.Error()))
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error rejudging submission. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusAccepted, batch)
+}
+
+// RejudgeTask godoc
+//
+//	@Tags			submission
+//	@Summary		Rejudge submissions of a task
+//	@Description	Publishes all judged submissions of a task to be evaluated again, e.g. after its tests or limits changed. Redacted and pruned submissions are skipped. Progress is reported by the returned rejudge batch. Only admins and the task author can rejudge
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		413	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		202	{object}	httputils.ApiResponse[schemas.RejudgeBatch]
+//	@Router			/task/{id}/rejudge [post]
+func (sr *SubmissionRouteImpl) RejudgeTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	batch, err := sr.rejudgeService.RejudgeTask(tx, currentUser, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and the task author can rejudge submissions.")
+			return
+		}
+		if err == service.ErrRejudgeTooLarge {
+			httputils.ReturnError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Task has more than %d submissions to rejudge.", service.MaxRejudgeSubmissions))
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error rejudging task. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusAccepted, batch)
+}
+
+// GetRejudgeBatch godoc
+//
+//	@Tags			submission
+//	@Summary		Get a rejudge batch
+//	@Description	Returns the progress of a rejudge batch. Only admins and the task author can see rejudge batches
+//	@Produce		json
+//	@Param			id	path		int	true	"Rejudge batch ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.RejudgeBatch]
+//	@Router			/rejudge/{id} [get]
+func (sr *SubmissionRouteImpl) GetRejudgeBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	batchId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid rejudge batch ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	batch, err := sr.rejudgeService.GetBatch(tx, currentUser, batchId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrRejudgeBatchNotFound || err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Rejudge batch not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins and the task author can see rejudge batches.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting rejudge batch. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, batch)
+}
+
+// ImportGrades godoc
+//
+//	@Tags			submission
+//	@Summary		Import manual grades
+//	@Description	Applies manual grades from a CSV file with submission_id, manual_score (0-100) and optional comment columns, e.g. exported from a grading spreadsheet. Grades are applied only if every row is valid, otherwise the report lists the failed rows and nothing is changed. Teachers can grade submissions of their tasks, admins all submissions
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file	formData	file	true	"CSV file with grades"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		413		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.GradeImportReport]
+//	@Router			/submission/grades/import [post]
+func (sr *SubmissionRouteImpl) ImportGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	// The body size is limited by BodyLimitMiddleware
+	if err := httputils.ParseMultipartForm(r); err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Error retrieving the file. No grades file found.")
+		return
+	}
+	defer file.Close()
+	if !strings.HasSuffix(strings.ToLower(handler.Filename), ".csv") {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid file format. Only .csv files are allowed, export spreadsheets as CSV. Received: "+handler.Filename)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	report, err := sr.gradingService.ImportGrades(tx, currentUser, file)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can import grades.")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidGradeImport) {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid grades file. "+err.Error())
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error importing grades. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, report)
+}
+
+func NewSubmissionRoute(submissionService service.SubmissionService, rejudgeService service.RejudgeService, gradingService service.GradingService, pagination httputils.PaginationLimits) SubmissionRoute {
+	return &SubmissionRouteImpl{submissionService: submissionService, rejudgeService: rejudgeService, gradingService: gradingService, pagination: pagination}
+}
@@ -0,0 +1,243 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type TagRoute interface {
+	GetTags(w http.ResponseWriter, r *http.Request)
+	CreateTag(w http.ResponseWriter, r *http.Request)
+	DeleteTag(w http.ResponseWriter, r *http.Request)
+	SetTaskTags(w http.ResponseWriter, r *http.Request)
+}
+
+type TagRouteImpl struct {
+	tagService service.TagService
+}
+
+// GetTags godoc
+//
+//	@Tags			tag
+//	@Summary		Get all tags
+//	@Description	Returns tags tasks can be classified with, in name order
+//	@Produce		json
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.Tag]
+//	@Router			/tag/ [get]
+func (tr *TagRouteImpl) GetTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	tags, err := tr.tagService.GetTags(tx)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting tags. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, tags)
+}
+
+// CreateTag godoc
+//
+//	@Tags			tag
+//	@Summary		Create a tag
+//	@Description	Creates a tag with the trimmed and lowercased name. Only teachers and admins can create tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.TagCreate	true	"Tag"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		409		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.Tag]
+//	@Router			/tag/ [post]
+func (tr *TagRouteImpl) CreateTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TagCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	tag, err := tr.tagService.CreateTag(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can create tags.")
+			return
+		}
+		if err == service.ErrTagAlreadyExists {
+			httputils.ReturnError(w, http.StatusConflict, "Tag already exists.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid tag.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating tag. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, tag)
+}
+
+// DeleteTag godoc
+//
+//	@Tags			tag
+//	@Summary		Delete a tag
+//	@Description	Deletes a tag and removes it from its tasks. Teachers can delete tags they created, admins any tags
+//	@Produce		json
+//	@Param			id	path		int	true	"Tag ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/tag/{id} [delete]
+func (tr *TagRouteImpl) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tagId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid tag ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.tagService.DeleteTag(tx, currentUser, tagId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTagNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Tag not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the creator of the tag and admins can delete it.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error deleting tag. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Tag deleted")
+}
+
+// SetTaskTags godoc
+//
+//	@Tags			task
+//	@Summary		Set tags of a task
+//	@Description	Replaces the tags of a task with the tags of the names, which have to exist. Only the task author and admins can set them
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Task ID"
+//	@Param			request	body		schemas.TaskTagsEdit	true	"Tag names"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[[]string]
+//	@Router			/task/{id}/tags [put]
+func (tr *TagRouteImpl) SetTaskTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskTagsEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	tags, err := tr.tagService.SetTaskTags(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrTagNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Tag not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author can set its tags.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid tags.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error setting task tags. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, tags)
+}
+
+func NewTagRoute(tagService service.TagService) TagRoute {
+	return &TagRouteImpl{tagService: tagService}
+}
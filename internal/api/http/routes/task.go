@@ -1,8 +1,10 @@
 package routes
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -10,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/mini-maxit/backend/internal/api/http/httputils"
 	"github.com/mini-maxit/backend/internal/api/http/middleware"
 	"github.com/mini-maxit/backend/internal/database"
@@ -17,31 +20,69 @@ import (
 	"github.com/mini-maxit/backend/package/service"
 )
 
+// MaxSubmissionSize is the maximum size in bytes of a solution upload request
+const MaxSubmissionSize = 10 << 20 // 10 MB
+
 type TaskRoute interface {
 	GetAllTasks(w http.ResponseWriter, r *http.Request)
 	GetTask(w http.ResponseWriter, r *http.Request)
+	UpdateTask(w http.ResponseWriter, r *http.Request)
 	GetAllForUser(w http.ResponseWriter, r *http.Request)
 	GetAllForGroup(w http.ResponseWriter, r *http.Request)
 	UploadTask(w http.ResponseWriter, r *http.Request)
 	SubmitSolution(w http.ResponseWriter, r *http.Request)
+	BookmarkTask(w http.ResponseWriter, r *http.Request)
+	UnbookmarkTask(w http.ResponseWriter, r *http.Request)
+	GetTaskNote(w http.ResponseWriter, r *http.Request)
+	PutTaskNote(w http.ResponseWriter, r *http.Request)
+	DeleteTaskNote(w http.ResponseWriter, r *http.Request)
+	GetTaskDrafts(w http.ResponseWriter, r *http.Request)
+	PutTaskDraft(w http.ResponseWriter, r *http.Request)
+	ExportMySubmissions(w http.ResponseWriter, r *http.Request)
+	ExportTask(w http.ResponseWriter, r *http.Request)
+	ImportTask(w http.ResponseWriter, r *http.Request)
+	UpdateTaskCoAuthors(w http.ResponseWriter, r *http.Request)
+	GetTestCaseGroups(w http.ResponseWriter, r *http.Request)
+	UpdateTestCaseGroups(w http.ResponseWriter, r *http.Request)
+	GetTaskEvaluationPolicy(w http.ResponseWriter, r *http.Request)
+	UpdateTaskEvaluationPolicy(w http.ResponseWriter, r *http.Request)
+	GetTaskChanges(w http.ResponseWriter, r *http.Request)
+	SetTaskSandbox(w http.ResponseWriter, r *http.Request)
+	SetTaskTestVisibility(w http.ResponseWriter, r *http.Request)
+	SetTaskSubmissionRetention(w http.ResponseWriter, r *http.Request)
+	GetTaskStats(w http.ResponseWriter, r *http.Request)
+	CreateTaskPool(w http.ResponseWriter, r *http.Request)
+	GetTaskPool(w http.ResponseWriter, r *http.Request)
+	DeleteTaskPool(w http.ResponseWriter, r *http.Request)
 }
 
 type TaskRouteImpl struct {
 	fileStorageUrl string
 
 	// Service that handles task-related operations
-	taskService  service.TaskService
-	queueService service.QueueService
+	taskService       service.TaskService
+	queueService      service.QueueService
+	submissionService service.SubmissionService
+	languageService   service.LanguageService
+	uploadScanService service.UploadScanService
+	taskExportService service.TaskExportService
+	pagination        httputils.PaginationLimits
 }
 
 // GetAllTasks godoc
 //
 //	@Tags			task
 //	@Summary		Get all tasks
-//	@Description	Returns all tasks
+//	@Description	Returns all tasks, marking ones bookmarked or noted by the requesting user
 //	@Produce		json
-//	@Failure		500	{object}	httputils.ApiError
-//	@Success		200	{object}	httputils.ApiResponse[[]schemas.Task]
+//	@Param			bookmarked	query		bool		false	"Return only bookmarked tasks"
+//	@Param			tag			query		[]string	false	"Return only tasks having every one of the tags"	collectionFormat(multi)
+//	@Param			difficulty	query		string		false	"Return only tasks of the difficulty"				Enums(easy, medium, hard)
+//	@Param			search		query		string		false	"Return only tasks with the text in their title or a tag name, case insensitive"
+//	@Failure		400			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		500			{object}	httputils.ApiError
+//	@Success		200			{object}	httputils.ApiPageResponse[[]schemas.Task]
 //	@Router			/task/ [get]
 func (tr *TaskRouteImpl) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -50,27 +91,26 @@ func (tr *TaskRouteImpl) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := r.URL.Query()
-	limitStr := query.Get("limit")
-	if limitStr == "" {
-		limitStr = httputils.DefaultPaginationLimitStr
-	}
-
-	offsetStr := query.Get("offset")
-	if offsetStr == "" {
-		offsetStr = httputils.DefaultPaginationOffsetStr
-	}
-
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
+	limit, offset, err := httputils.GetPagination(query, tr.pagination)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid limit.")
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
 		return
 	}
 
-	offset, err := strconv.ParseInt(offsetStr, 10, 64)
-	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid offset.")
-		return
+	filter := schemas.TaskFilter{}
+	bookmarkedStr := query.Get("bookmarked")
+	if bookmarkedStr != "" {
+		filter.Bookmarked, err = strconv.ParseBool(bookmarkedStr)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid bookmarked flag.")
+			return
+		}
 	}
+	filter.Tags = query["tag"]
+	filter.Difficulty = query.Get("difficulty")
+	filter.Search = query.Get("search")
+
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
 
 	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
 	tx, err := db.Connect()
@@ -79,9 +119,13 @@ func (tr *TaskRouteImpl) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, err := tr.taskService.GetAll(tx, limit, offset)
+	tasks, err := tr.taskService.GetAll(tx, userId, filter, limit, offset)
 	if err != nil {
 		db.Rollback()
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid task filter.", validationErrors)
+			return
+		}
 		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting tasks. %s", err.Error()))
 		return
 	}
@@ -90,20 +134,21 @@ func (tr *TaskRouteImpl) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 		tasks = []schemas.Task{}
 	}
 
-	httputils.ReturnSuccess(w, http.StatusOK, tasks)
+	httputils.ReturnPage(w, tasks, httputils.PageInfo{Limit: limit, Offset: offset})
 }
 
 // GetTask godoc
 //
 //	@Tags			task
 //	@Summary		Get a task
-//	@Description	Returns a task by ID
+//	@Description	Returns a task by ID. The ETag header holds the task version, to be sent back in If-Match when editing
 //	@Produce		json
 //	@Param			id	path		int	true	"Task ID"
 //	@Failure		400	{object}	httputils.ApiError
 //	@Failure		405	{object}	httputils.ApiError
 //	@Failure		500	{object}	httputils.ApiError
 //	@Success		200	{object}	httputils.ApiResponse[schemas.TaskDetailed]
+//	@Header			200	{string}	ETag	"Task version"
 //	@Router			/task/{id} [get]
 func (tr *TaskRouteImpl) GetTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -136,43 +181,135 @@ func (tr *TaskRouteImpl) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(task.Version))
 	httputils.ReturnSuccess(w, http.StatusOK, task)
 }
 
-func (tr *TaskRouteImpl) GetAllForUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// UpdateTask godoc
+//
+//	@Tags			task
+//	@Summary		Edit a task
+//	@Description	Edits a task based on the version it was read at, given in the If-Match header or in the body. If the task was
+//	@Description	edited since, nothing is saved and 409 is returned with the current task, so the client can merge and retry.
+//	@Description	Only the task author and admins can edit a task
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Task ID"
+//	@Param			If-Match	header		string				false	"ETag of the task version the edit is based on"
+//	@Param			request		body		schemas.UpdateTask	true	"Task edit"
+//	@Failure		400			{object}	httputils.ApiError
+//	@Failure		403			{object}	httputils.ApiError
+//	@Failure		404			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		409			{object}	httputils.ApiConflictError[schemas.TaskDetailed]
+//	@Failure		500			{object}	httputils.ApiError
+//	@Success		200			{object}	httputils.ApiResponse[schemas.TaskDetailed]
+//	@Header			200			{string}	ETag	"Task version"
+//	@Router			/task/{id} [put]
+func (tr *TaskRouteImpl) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	userIdStr := r.PathValue("id")
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
 
-	if userIdStr == "" {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user id")
+	var request schemas.UpdateTask
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid If-Match header.")
+			return
+		}
+		request.Version = version
+	}
 
-	query := r.URL.Query()
-	limitStr := query.Get("limit")
-	offsetStr := query.Get("offset")
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
 
-	if limitStr == "" {
-		limitStr = httputils.DefaultPaginationLimitStr
+	task, err := tr.taskService.UpdateTask(tx, currentUser, taskId, request)
+	if err != nil {
+		if err == service.ErrTaskVersionConflict {
+			current, getErr := tr.taskService.GetTask(tx, taskId)
+			db.Rollback()
+			if getErr != nil {
+				httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting task. %s", getErr.Error()))
+				return
+			}
+			w.Header().Set("ETag", taskETag(current.Version))
+			httputils.ReturnConflict(w, "Task was edited by someone else. Merge your changes with the current task and retry.", current)
+			return
+		}
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author and admins can edit the task.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid task edit.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating task. %s", err.Error()))
+		return
 	}
 
-	if offsetStr == "" {
-		offsetStr = httputils.DefaultPaginationOffsetStr
+	w.Header().Set("ETag", taskETag(task.Version))
+	httputils.ReturnSuccess(w, http.StatusOK, task)
+}
+
+func taskETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// GetAllForUser godoc
+//
+//	@Tags			task
+//	@Summary		Get tasks of a user
+//	@Description	Returns tasks created by the user
+//	@Produce		json
+//	@Param			id		path		int	true	"User ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.Task]
+//	@Router			/user/{id}/task [get]
+func (tr *TaskRouteImpl) GetAllForUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid offset")
+	userIdStr := r.PathValue("id")
+
+	if userIdStr == "" {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user id")
 		return
 	}
 
-	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	query := r.URL.Query()
+	limit, offset, err := httputils.GetPagination(query, tr.pagination)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid offset")
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
 		return
 	}
 
@@ -186,9 +323,11 @@ func (tr *TaskRouteImpl) GetAllForUser(w http.ResponseWriter, r *http.Request) {
 	tx, err := db.Connect()
 	if err != nil {
 		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
 	}
 
-	tasks, err := tr.taskService.GetAllForUser(tx, userId, limit, offset)
+	currentUserId := r.Context().Value(middleware.UserIDKey).(int64)
+	tasks, err := tr.taskService.GetAllForUser(tx, currentUserId, userId, limit, offset)
 	if err != nil {
 		db.Rollback()
 		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting tasks. %s", err.Error()))
@@ -199,9 +338,23 @@ func (tr *TaskRouteImpl) GetAllForUser(w http.ResponseWriter, r *http.Request) {
 		tasks = []schemas.Task{}
 	}
 
-	httputils.ReturnSuccess(w, http.StatusOK, tasks)
+	httputils.ReturnPage(w, tasks, httputils.PageInfo{Limit: limit, Offset: offset})
 }
 
+// GetAllForGroup godoc
+//
+//	@Tags			task
+//	@Summary		Get tasks of a group
+//	@Description	Returns tasks assigned to the group
+//	@Produce		json
+//	@Param			id		path		int	true	"Group ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.Task]
+//	@Router			/group/{id}/task [get]
 func (tr *TaskRouteImpl) GetAllForGroup(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -216,26 +369,9 @@ func (tr *TaskRouteImpl) GetAllForGroup(w http.ResponseWriter, r *http.Request)
 	}
 
 	query := r.URL.Query()
-	limitStr := query.Get("limit")
-	offsetStr := query.Get("offset")
-
-	if limitStr == "" {
-		limitStr = httputils.DefaultPaginationLimitStr
-	}
-
-	if offsetStr == "" {
-		offsetStr = httputils.DefaultPaginationOffsetStr
-	}
-
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid limit")
-		return
-	}
-
-	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	limit, offset, err := httputils.GetPagination(query, tr.pagination)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid offset")
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
 		return
 	}
 
@@ -252,7 +388,8 @@ func (tr *TaskRouteImpl) GetAllForGroup(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	tasks, err := tr.taskService.GetAllForGroup(tx, groupId, limit, offset)
+	currentUserId := r.Context().Value(middleware.UserIDKey).(int64)
+	tasks, err := tr.taskService.GetAllForGroup(tx, currentUserId, groupId, limit, offset)
 	if err != nil {
 		db.Rollback()
 		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting tasks. %s", err.Error()))
@@ -263,23 +400,26 @@ func (tr *TaskRouteImpl) GetAllForGroup(w http.ResponseWriter, r *http.Request)
 		tasks = []schemas.Task{}
 	}
 
-	httputils.ReturnSuccess(w, http.StatusOK, tasks)
+	httputils.ReturnPage(w, tasks, httputils.PageInfo{Limit: limit, Offset: offset})
 }
 
 // UploadTask godoc
 //
 //	@Tags			task
 //	@Summary		Upload a task
-//	@Description	Uploads a task to the FileStorage service
+//	@Description	Uploads a task to the FileStorage service. The current user is the author of the task
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Param			taskName	formData	string	true	"Name of the task"
-//	@Param			userId		formData	int		true	"ID of the author"
 //	@Param			overwrite	formData	bool	false	"Overwrite flag"
 //	@Param			archive		formData	file	true	"Task archive"
 //	@Failure		405			{object}	httputils.ApiError
 //	@Failure		400			{object}	httputils.ApiError
+//	@Failure		403			{object}	httputils.ApiError
+//	@Failure		413			{object}	httputils.ApiError
+//	@Failure		422			{object}	httputils.ApiError
 //	@Failure		500			{object}	httputils.ApiError
+//	@Failure		503			{object}	httputils.ApiError
 //	@Success		200			{object}	httputils.ApiResponse[schemas.TaskCreateResponse]
 //	@Router			/task/ [post]
 func (tr *TaskRouteImpl) UploadTask(w http.ResponseWriter, r *http.Request) {
@@ -288,14 +428,12 @@ func (tr *TaskRouteImpl) UploadTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Limit the size of the incoming request
-	r.Body = http.MaxBytesReader(w, r.Body, 50*1024*1024) // 50 MB limit
-
-	// Parse the multipart form data
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "The uploaded files are too large.")
+	// Parse the multipart form data. The body size is limited by BodyLimitMiddleware
+	if err := httputils.ParseMultipartForm(r); err != nil {
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
 	overwriteStr := r.FormValue("overwrite")
 	overwrite := false
@@ -312,17 +450,6 @@ func (tr *TaskRouteImpl) UploadTask(w http.ResponseWriter, r *http.Request) {
 		httputils.ReturnError(w, http.StatusBadRequest, "Task name is required.")
 		return
 	}
-	userIdStr := r.FormValue("userId")
-	if userIdStr == "" {
-		httputils.ReturnError(w, http.StatusBadRequest, "User ID of author is required.")
-		return
-	}
-	userId, err := strconv.ParseInt(userIdStr, 10, 64)
-	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user ID.")
-		return
-	}
-
 	// Extract the uploaded file
 	file, handler, err := r.FormFile("archive")
 	if err != nil {
@@ -334,15 +461,11 @@ func (tr *TaskRouteImpl) UploadTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer file.Close()
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
 
-	// Create a multipart writer for the HTTP request to FileStorage service
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Create empty task to get the task ID
+	// Create empty task to get the task ID. The current user is its author
 	task := schemas.Task{
-		Title:     taskName,
-		CreatedBy: userId,
+		Title: taskName,
 	}
 	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
 	tx, err := db.Connect()
@@ -350,34 +473,30 @@ func (tr *TaskRouteImpl) UploadTask(w http.ResponseWriter, r *http.Request) {
 		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
 		return
 	}
-	taskId, err := tr.taskService.Create(tx, &task)
+	err = tr.uploadScanService.ScanTaskArchive(tx, currentUser.Id, handler.Filename, file)
 	if err != nil {
-		db.Rollback()
-		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating empty task. %s", err.Error()))
+		returnScanError(w, db, err)
 		return
 	}
-
-	// Add form fields
-	writer.WriteField("taskID", fmt.Sprintf("%d", taskId))
-	writer.WriteField("overwrite", strconv.FormatBool(overwrite))
-
-	// Create a form file field and copy the uploaded file to it
-	part, err := writer.CreateFormFile("archive", handler.Filename)
+	taskId, err := tr.taskService.Create(tx, currentUser, &task)
 	if err != nil {
 		db.Rollback()
-		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating form file for FileStorage. %s", err.Error()))
-		return
-	}
-	if _, err := io.Copy(part, file); err != nil {
-		db.Rollback()
-		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error copying file to FileStorage request. %s", err.Error()))
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to create tasks.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating empty task. %s", err.Error()))
 		return
 	}
-	writer.Close()
 
-	// Send the request to FileStorage service
+	// Stream the archive to FileStorage service
+	fields := map[string]string{
+		"taskID":    fmt.Sprintf("%d", taskId),
+		"overwrite": strconv.FormatBool(overwrite),
+	}
+	body, contentType := streamMultipart(fields, "archive", handler.Filename, file)
 	client := &http.Client{}
-	resp, err := client.Post(tr.fileStorageUrl+"/createTask", writer.FormDataContentType(), body)
+	resp, err := client.Post(tr.fileStorageUrl+"/createTask", contentType, body)
 	if err != nil {
 		db.Rollback()
 		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error sending file to FileStorage service. %s", err.Error()))
@@ -411,20 +530,39 @@ func (tr *TaskRouteImpl) UploadTask(w http.ResponseWriter, r *http.Request) {
 	httputils.ReturnSuccess(w, http.StatusOK, schemas.TaskCreateResponse{Id: taskId})
 }
 
+// SubmitSolution godoc
+//
+//	@Tags			task
+//	@Summary		Submit a solution
+//	@Description	Uploads a solution of a task by the current user and queues it for judging. The file extension has to match the language
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			taskID		formData	int		true	"Task ID"
+//	@Param			userID		formData	int		false	"User ID, has to be the current user when given"
+//	@Param			languageID	formData	int		true	"Language ID"
+//	@Param			solution	formData	file	true	"Solution file"
+//	@Failure		400			{object}	httputils.ApiError
+//	@Failure		403			{object}	httputils.ApiError
+//	@Failure		404			{object}	httputils.ApiError
+//	@Failure		405			{object}	httputils.ApiError
+//	@Failure		413			{object}	httputils.ApiError
+//	@Failure		422			{object}	httputils.ApiError
+//	@Failure		500			{object}	httputils.ApiError
+//	@Failure		503			{object}	httputils.ApiError
+//	@Success		200			{object}	httputils.ApiResponse[string]
+//	@Router			/task/submit [post]
 func (tr *TaskRouteImpl) SubmitSolution(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Limit the size of the incoming request to 10 MB
-	r.Body = http.MaxBytesReader(w, r.Body, 10*1024*1024)
-
-	// Parse the multipart form data
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "The uploaded files are too large.")
+	// Parse the multipart form data. The body size is limited to MaxSubmissionSize by BodyLimitMiddleware
+	if err := httputils.ParseMultipartForm(r); err != nil {
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
 	// Extract the task ID
 	taskIdStr := r.FormValue("taskID")
@@ -446,15 +584,11 @@ func (tr *TaskRouteImpl) SubmitSolution(w http.ResponseWriter, r *http.Request)
 	}
 	defer file.Close()
 
-	// Extract user ID
-	userIDStr := r.FormValue("userID")
-	if userIDStr == "" {
-		httputils.ReturnError(w, http.StatusBadRequest, "User ID is required.")
-		return
-	}
-	userId, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid user ID.")
+	// Solutions are always submitted by the current user. The user ID field is only checked for older clients
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+	userIDStr := strconv.FormatInt(userId, 10)
+	if formUserId := r.FormValue("userID"); formUserId != "" && formUserId != userIDStr {
+		httputils.ReturnError(w, http.StatusForbidden, "Solutions can only be submitted by the current user.")
 		return
 	}
 
@@ -470,30 +604,62 @@ func (tr *TaskRouteImpl) SubmitSolution(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Create a multipart writer for the HTTP request to FileStorage service
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
 
-	// Add form fields
-	writer.WriteField("taskID", taskIdStr)
-	writer.WriteField("userID", userIDStr)
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+	err = tr.taskService.AuthorizeSubmission(tx, currentUser, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "You are not allowed to submit solutions.")
+			return
+		}
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrTaskNotAssigned {
+			httputils.ReturnError(w, http.StatusForbidden, "Another variant of this task is assigned to you.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error checking submission. %s", err.Error()))
+		return
+	}
 
-	// Create a form file field and copy the uploaded file to it
-	part, err := writer.CreateFormFile("submissionFile", handler.Filename)
+	err = tr.languageService.ValidateSourceFile(tx, languageId, handler.Filename)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusInternalServerError, "Error creating form file for FileStorage. "+err.Error())
+		db.Rollback()
+		if err == service.ErrLanguageNotFound {
+			httputils.ReturnError(w, http.StatusBadRequest, "Language not found.")
+			return
+		}
+		if err == service.ErrInvalidSourceExtension {
+			httputils.ReturnError(w, http.StatusBadRequest, "The solution file extension does not match the language.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error checking solution file. %s", err.Error()))
 		return
 	}
-	if _, err := io.Copy(part, file); err != nil {
-		httputils.ReturnError(w, http.StatusInternalServerError, "Error copying file to FileStorage request. "+err.Error())
+	err = tr.uploadScanService.ScanSubmission(tx, currentUser.Id, handler.Filename, file)
+	if err != nil {
+		returnScanError(w, db, err)
 		return
 	}
 
-	writer.Close()
-
-	// Send the request to FileStorage service
+	// Stream the solution to FileStorage service, hashing it on the way
+	fields := map[string]string{
+		"taskID": taskIdStr,
+		"userID": userIDStr,
+	}
+	sourceHash := sha256.New()
+	body, contentType := streamMultipart(fields, "submissionFile", handler.Filename, io.TeeReader(file, sourceHash))
 	client := &http.Client{}
-	resp, err := client.Post(tr.fileStorageUrl+"/submit", writer.FormDataContentType(), body)
+	resp, err := client.Post(tr.fileStorageUrl+"/submit", contentType, body)
 	if err != nil {
 		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error sending file to FileStorage service. %s", err.Error()))
 		return
@@ -518,18 +684,22 @@ func (tr *TaskRouteImpl) SubmitSolution(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
-	tx, err := db.Connect()
+	// Create the submission with the correct order
+	submissionId, err := tr.taskService.CreateSubmission(tx, taskId, userId, languageId, respJson.SubmissionNumber, hex.EncodeToString(sourceHash.Sum(nil)))
 	if err != nil {
-		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating submission. %s", err.Error()))
 		return
 	}
 
-	// Create the submission with the correct order
-	submissionId, err := tr.taskService.CreateSubmission(tx, taskId, userId, languageId, respJson.SubmissionNumber)
+	reused, err := tr.submissionService.ReuseIdenticalResult(tx, submissionId)
 	if err != nil {
 		db.Rollback()
-		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating submission. %s", err.Error()))
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error reusing result of identical submission. %s", err.Error()))
+		return
+	}
+	if reused {
+		httputils.ReturnSuccess(w, http.StatusOK, "Solution submitted successfully")
 		return
 	}
 
@@ -543,6 +713,1367 @@ func (tr *TaskRouteImpl) SubmitSolution(w http.ResponseWriter, r *http.Request)
 	httputils.ReturnSuccess(w, http.StatusOK, "Solution submitted successfully")
 }
 
-func NewTaskRoute(fileStorageUrl string, taskService service.TaskService, queueService service.QueueService) TaskRoute {
-	return &TaskRouteImpl{fileStorageUrl: fileStorageUrl, taskService: taskService, queueService: queueService}
+// BookmarkTask godoc
+//
+//	@Tags			task
+//	@Summary		Bookmark a task
+//	@Description	Bookmarks a task for the requesting user
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/task/{id}/bookmark [put]
+func (tr *TaskRouteImpl) BookmarkTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.BookmarkTask(tx, taskId, userId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error bookmarking task. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task bookmarked")
+}
+
+// UnbookmarkTask godoc
+//
+//	@Tags			task
+//	@Summary		Remove a task bookmark
+//	@Description	Removes the bookmark of the requesting user from a task
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/task/{id}/bookmark [delete]
+func (tr *TaskRouteImpl) UnbookmarkTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.UnbookmarkTask(tx, taskId, userId)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error removing task bookmark. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task bookmark removed")
+}
+
+// GetTaskNote godoc
+//
+//	@Tags			task
+//	@Summary		Get a task note
+//	@Description	Returns the private note of the requesting user for a task
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.TaskNote]
+//	@Router			/task/{id}/note [get]
+func (tr *TaskRouteImpl) GetTaskNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	note, err := tr.taskService.GetTaskNote(tx, taskId, userId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNoteNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task note not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting task note. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, note)
+}
+
+// PutTaskNote godoc
+//
+//	@Tags			task
+//	@Summary		Save a task note
+//	@Description	Creates or replaces the private markdown note of the requesting user for a task
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Task ID"
+//	@Param			request	body		schemas.TaskNoteEdit	true	"Note content"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.TaskNote]
+//	@Router			/task/{id}/note [put]
+func (tr *TaskRouteImpl) PutTaskNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	var request schemas.TaskNoteEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	note, err := tr.taskService.PutTaskNote(tx, taskId, userId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid task note.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error saving task note. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, note)
+}
+
+// GetTaskDrafts godoc
+//
+//	@Tags			task
+//	@Summary		Get task drafts
+//	@Description	Returns the autosaved in-progress sources of the requesting user for a task, one per language. Drafts expire 30 days after they were last saved
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.TaskDraft]
+//	@Router			/task/{id}/draft [get]
+func (tr *TaskRouteImpl) GetTaskDrafts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	drafts, err := tr.taskService.GetTaskDrafts(tx, taskId, userId)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting task drafts. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, drafts)
+}
+
+// PutTaskDraft godoc
+//
+//	@Tags			task
+//	@Summary		Save a task draft
+//	@Description	Creates or replaces the in-progress source of the requesting user for a task in a language, for the editor to autosave
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Task ID"
+//	@Param			request	body		schemas.TaskDraftEdit	true	"Draft"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.TaskDraft]
+//	@Router			/task/{id}/draft [put]
+func (tr *TaskRouteImpl) PutTaskDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	var request schemas.TaskDraftEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	draft, err := tr.taskService.PutTaskDraft(tx, taskId, userId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid task draft.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error saving task draft. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, draft)
+}
+
+// DeleteTaskNote godoc
+//
+//	@Tags			task
+//	@Summary		Delete a task note
+//	@Description	Deletes the private note of the requesting user for a task
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/task/{id}/note [delete]
+func (tr *TaskRouteImpl) DeleteTaskNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.DeleteTaskNote(tx, taskId, userId)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error deleting task note. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task note deleted")
+}
+
+// UpdateTaskCoAuthors godoc
+//
+//	@Tags			task
+//	@Summary		Set task co-authors
+//	@Description	Replaces the co-authors displayed on a task. Co-authors get no access to the task. Only the task author and admins can edit co-authors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Task ID"
+//	@Param			request	body		schemas.TaskCoAuthorsEdit	true	"Co-authors in display order"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[[]schemas.TaskCoAuthor]
+//	@Router			/task/{id}/co-authors [put]
+func (tr *TaskRouteImpl) UpdateTaskCoAuthors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskCoAuthorsEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	coAuthors, err := tr.taskService.UpdateTaskCoAuthors(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author can edit co-authors.")
+			return
+		}
+		if err == service.ErrInvalidCoAuthor {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid co-author. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid co-authors.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating task co-authors. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, coAuthors)
+}
+
+// GetTestCaseGroups godoc
+//
+//	@Tags			task
+//	@Summary		Get test groups of a task
+//	@Description	Returns the test groups of a task in order. Tasks without groups are scored by the share of passed tests
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.TestCaseGroup]
+//	@Router			/task/{id}/test-groups [get]
+func (tr *TaskRouteImpl) GetTestCaseGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	groups, err := tr.taskService.GetTestCaseGroups(tx, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting test groups. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, groups)
+}
+
+// UpdateTestCaseGroups godoc
+//
+//	@Tags			task
+//	@Summary		Set test groups of a task
+//	@Description	Replaces the test groups of a task. A group scores its points only when all of its tests pass, tests outside of groups are not scored. Existing scores change only when recomputed. Only the task author and admins can edit test groups
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Task ID"
+//	@Param			request	body		schemas.TestCaseGroupsEdit	true	"Test groups in order, an empty list removes the groups"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[[]schemas.TestCaseGroup]
+//	@Router			/task/{id}/test-groups [put]
+func (tr *TaskRouteImpl) UpdateTestCaseGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TestCaseGroupsEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	groups, err := tr.taskService.UpdateTestCaseGroups(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author can edit test groups.")
+			return
+		}
+		if err == service.ErrInvalidTestCaseGroups {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid test groups. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid test groups.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating test groups. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, groups)
+}
+
+// GetTaskEvaluationPolicy godoc
+//
+//	@Tags			task
+//	@Summary		Get the evaluation policy of a task
+//	@Description	Returns the output, standard error and process limits tests of the task are evaluated with, and the highest limits the platform accepts
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.TaskEvaluationPolicy]
+//	@Router			/task/{id}/evaluation-policy [get]
+func (tr *TaskRouteImpl) GetTaskEvaluationPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	policy, err := tr.taskService.GetTaskEvaluationPolicy(tx, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting evaluation policy. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, policy)
+}
+
+// UpdateTaskEvaluationPolicy godoc
+//
+//	@Tags			task
+//	@Summary		Set the evaluation policy of a task
+//	@Description	Replaces the output, standard error and process limits of the task, null limits are reset to the defaults of the platform. Limits above the maximum of the platform are rejected. The policy applies to submissions published afterwards. Only the task author and admins can edit the policy
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"Task ID"
+//	@Param			request	body		schemas.TaskEvaluationPolicyEdit	true	"Evaluation policy"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.TaskEvaluationPolicy]
+//	@Router			/task/{id}/evaluation-policy [put]
+func (tr *TaskRouteImpl) UpdateTaskEvaluationPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskEvaluationPolicyEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	policy, err := tr.taskService.UpdateTaskEvaluationPolicy(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author can edit the evaluation policy.")
+			return
+		}
+		if err == service.ErrEvaluationLimitExceeded {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid evaluation policy. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid evaluation policy.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating evaluation policy. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, policy)
+}
+
+// GetTaskChanges godoc
+//
+//	@Tags			task
+//	@Summary		Get changes of a task
+//	@Description	Returns recorded changes of the title and limits of the task, newest first. Limits reset to the defaults of the platform have the value "default"
+//	@Produce		json
+//	@Param			id		path		int	true	"Task ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.TaskChange]
+//	@Router			/task/{id}/changes [get]
+func (tr *TaskRouteImpl) GetTaskChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+
+	limit, offset, err := httputils.GetPagination(r.URL.Query(), tr.pagination)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	changes, err := tr.taskService.GetTaskChanges(tx, taskId, limit, offset)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting task changes. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnPage(w, changes, httputils.PageInfo{Limit: limit, Offset: offset})
+}
+
+// SetTaskSandbox godoc
+//
+//	@Tags			task
+//	@Summary		Add or remove a task from the sandbox
+//	@Description	Sandbox tasks are listed in the public practice mode and can be read without an account. Only admins can curate the sandbox
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Task ID"
+//	@Param			request	body		schemas.TaskSandboxEdit	true	"Sandbox flag"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/task/{id}/sandbox [put]
+func (tr *TaskRouteImpl) SetTaskSandbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskSandboxEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.SetTaskSandbox(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can curate the sandbox.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid sandbox flag.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating task sandbox flag. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task sandbox flag updated")
+}
+
+// SetTaskTestVisibility godoc
+//
+//	@Tags			task
+//	@Summary		Set how much of the test results students see
+//	@Description	Sets the test visibility of the task: all tests, tests up to and including the first failed one (first_failed), or only the verdict, the number of passed tests and the score (hidden).
+//	@Description	It applies to results and progress of submissions students read. Teachers of the task and admins see every test. Only the task author and admins can set it
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Task ID"
+//	@Param			request	body		schemas.TaskTestVisibilityEdit	true	"Test visibility"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/task/{id}/test-visibility [put]
+func (tr *TaskRouteImpl) SetTaskTestVisibility(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskTestVisibilityEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.SetTaskTestVisibility(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author can set the test visibility.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid test visibility.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating task test visibility. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task test visibility updated")
+}
+
+// SetTaskSubmissionRetention godoc
+//
+//	@Tags			task
+//	@Summary		Set which sources of old submissions are kept
+//	@Description	Sets the submission retention of the task: the platform policy (default), every source (all), or only the best and the latest submission of every user (best_and_latest).
+//	@Description	With best_and_latest, sources of failing attempts superseded by a later submission are removed once they reach the prune age. Verdicts and scores are kept. Only the task author and admins can set it
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"Task ID"
+//	@Param			request	body		schemas.TaskSubmissionRetentionEdit	true	"Submission retention"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/task/{id}/submission-retention [put]
+func (tr *TaskRouteImpl) SetTaskSubmissionRetention(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskSubmissionRetentionEdit
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.SetTaskSubmissionRetention(tx, currentUser, taskId, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the task author can set the submission retention.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid submission retention.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating task submission retention. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task submission retention updated")
+}
+
+// GetTaskStats godoc
+//
+//	@Tags			task
+//	@Summary		Get task statistics
+//	@Description	Returns attempts and acceptance of a task within a term. Defaults to the term in progress, or to all submissions if no term is in progress.
+//	@Description	Members of a group hiding statistics of the task get statistics of their own submissions, marked own_only
+//	@Produce		json
+//	@Param			id		path		int		true	"Task ID"
+//	@Param			term	query		string	false	"Term ID, or all for submissions of all terms"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		404		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.TaskStats]
+//	@Router			/task/{id}/stats [get]
+func (tr *TaskRouteImpl) GetTaskStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+
+	var termId *int64
+	allTime := false
+	termStr := r.URL.Query().Get("term")
+	if termStr == "all" {
+		allTime = true
+	} else if termStr != "" {
+		id, err := strconv.ParseInt(termStr, 10, 64)
+		if err != nil {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid term.")
+			return
+		}
+		termId = &id
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	stats, err := tr.taskService.GetTaskStats(tx, userId, taskId, termId, allTime)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrTermNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Term not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting task stats. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, stats)
+}
+
+// ExportMySubmissions godoc
+//
+//	@Tags			task
+//	@Summary		Export own submissions for a task
+//	@Description	Returns a zip archive with sources and a verdict summary (summary.json) of all submissions of the requesting user for a task
+//	@Produce		application/zip
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{file}		binary
+//	@Router			/task/{id}/my-submissions/export [get]
+func (tr *TaskRouteImpl) ExportMySubmissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	userId := r.Context().Value(middleware.UserIDKey).(int64)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	archive, err := tr.submissionService.ExportUserSubmissions(tx, taskId, userId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNoSubmissions {
+			httputils.ReturnError(w, http.StatusNotFound, "No submissions found for this task.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error exporting submissions. %s", err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"task_%d_submissions.zip\"", taskId))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// ExportTask godoc
+//
+//	@Tags			task
+//	@Summary		Export a task
+//	@Description	Returns a portable archive of a task, so it can be imported into another Mini-Maxit instance. The zip contains a
+//	@Description	manifest.json with the title, evaluation policy, limits of the tests and test groups, and task.tar.gz with the
+//	@Description	description and tests. Only the author of the task and admins can export it
+//	@Produce		application/zip
+//	@Param			id	path		int	true	"Task ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{file}		binary
+//	@Router			/task/{id}/export [get]
+func (tr *TaskRouteImpl) ExportTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	taskId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	archive, err := tr.taskExportService.ExportTask(tx, currentUser, taskId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrTaskNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task not found.")
+			return
+		}
+		if err == service.ErrFileNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Files of the task not found.")
+			return
+		}
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the author of the task and admins can export it.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error exporting task. %s", err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"task_%d.zip\"", taskId))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// ImportTask godoc
+//
+//	@Tags			task
+//	@Summary		Import a task
+//	@Description	Creates a task of the current user from a portable archive exported by GET /task/{id}/export, also from another
+//	@Description	Mini-Maxit instance, or from a full Polygon package with problem.xml, tests and a PDF statement. Only teachers and
+//	@Description	admins can import tasks
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			archive	formData	file	true	"Task archive"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		409		{object}	httputils.ApiError
+//	@Failure		413		{object}	httputils.ApiError
+//	@Failure		422		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Failure		503		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.TaskCreateResponse]
+//	@Router			/task/import [post]
+func (tr *TaskRouteImpl) ImportTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	// The body size is limited by BodyLimitMiddleware
+	if err := httputils.ParseMultipartForm(r); err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, handler, err := r.FormFile("archive")
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Error retrieving the file. No task archive found.")
+		return
+	}
+	defer file.Close()
+	if !strings.HasSuffix(strings.ToLower(handler.Filename), ".zip") {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid file format. Only .zip archives exported from a task are allowed. Received: "+handler.Filename)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+	err = tr.uploadScanService.ScanTaskArchive(tx, currentUser.Id, handler.Filename, file)
+	if err != nil {
+		returnScanError(w, db, err)
+		return
+	}
+
+	task, err := tr.taskExportService.ImportTask(tx, currentUser, file, handler.Size)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can import tasks.")
+			return
+		}
+		if err == service.ErrTaskExists {
+			httputils.ReturnError(w, http.StatusConflict, "A task with this title already exists.")
+			return
+		}
+		if err == service.ErrEvaluationLimitExceeded {
+			httputils.ReturnError(w, http.StatusBadRequest, "The evaluation policy of the task exceeds the limits of this instance.")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTaskArchive) {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid task archive. "+err.Error())
+			return
+		}
+		if err == service.ErrInvalidTestCaseGroups {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid test groups in the task archive.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error importing task. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, task)
+}
+
+// CreateTaskPool godoc
+//
+//	@Tags			task-pool
+//	@Summary		Create a task pool
+//	@Description	Groups equivalent tasks of a homework. Every student is assigned one of them and only sees and submits that one. Teachers can pool their own tasks, admins any tasks
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.TaskPoolCreate	true	"Task pool"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.TaskPool]
+//	@Router			/task-pool/ [post]
+func (tr *TaskRouteImpl) CreateTaskPool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TaskPoolCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	pool, err := tr.taskService.CreateTaskPool(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can create task pools.")
+			return
+		}
+		if err == service.ErrInvalidTaskPool {
+			httputils.ReturnError(w, http.StatusBadRequest, "Invalid task pool. "+err.Error())
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid task pool.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating task pool. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, pool)
+}
+
+// GetTaskPool godoc
+//
+//	@Tags			task-pool
+//	@Summary		Get a task pool
+//	@Description	Returns a task pool with all of its tasks. Students cannot see task pools
+//	@Produce		json
+//	@Param			id	path		int	true	"Task pool ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.TaskPool]
+//	@Router			/task-pool/{id} [get]
+func (tr *TaskRouteImpl) GetTaskPool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	poolId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task pool ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	pool, err := tr.taskService.GetTaskPool(tx, currentUser, poolId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only teachers and admins can see task pools.")
+			return
+		}
+		if err == service.ErrTaskPoolNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task pool not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting task pool. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, pool)
+}
+
+// DeleteTaskPool godoc
+//
+//	@Tags			task-pool
+//	@Summary		Delete a task pool
+//	@Description	Deletes a task pool, its tasks become available to every student again. Only the pool author and admins can delete a pool
+//	@Produce		json
+//	@Param			id	path		int	true	"Task pool ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		403	{object}	httputils.ApiError
+//	@Failure		404	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[string]
+//	@Router			/task-pool/{id} [delete]
+func (tr *TaskRouteImpl) DeleteTaskPool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	poolId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid task pool ID.")
+		return
+	}
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	err = tr.taskService.DeleteTaskPool(tx, currentUser, poolId)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only the pool author can delete the task pool.")
+			return
+		}
+		if err == service.ErrTaskPoolNotFound {
+			httputils.ReturnError(w, http.StatusNotFound, "Task pool not found.")
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error deleting task pool. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, "Task pool deleted")
+}
+
+func NewTaskRoute(fileStorageUrl string, taskService service.TaskService, queueService service.QueueService, submissionService service.SubmissionService, languageService service.LanguageService, uploadScanService service.UploadScanService, taskExportService service.TaskExportService, pagination httputils.PaginationLimits) TaskRoute {
+	return &TaskRouteImpl{fileStorageUrl: fileStorageUrl, taskService: taskService, queueService: queueService, submissionService: submissionService, languageService: languageService, uploadScanService: uploadScanService, taskExportService: taskExportService, pagination: pagination}
+}
+
+// returnScanError responds to an upload rejected by the file scanner. The transaction is kept when the file is
+// infected, so its quarantine record is committed
+func returnScanError(w http.ResponseWriter, db database.Database, err error) {
+	if err == service.ErrFileInfected {
+		httputils.ReturnError(w, http.StatusUnprocessableEntity, "The file was rejected by the virus scanner.")
+		return
+	}
+	db.Rollback()
+	if errors.Is(err, service.ErrScannerUnavailable) {
+		httputils.ReturnError(w, http.StatusServiceUnavailable, "The virus scanner is unavailable, try again later.")
+		return
+	}
+	httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error scanning the file. %s", err.Error()))
+}
+
+// streamMultipart encodes fields and a single file as multipart/form-data into a pipe,
+// so uploads are forwarded to FileStorage without buffering them in memory.
+// It returns the body reader and its content type.
+func streamMultipart(fields map[string]string, fileField string, fileName string, file io.Reader) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		for key, value := range fields {
+			if err := writer.WriteField(key, value); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		part, err := writer.CreateFormFile(fileField, fileName)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(part, file); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(writer.Close())
+	}()
+	return pr, writer.FormDataContentType()
 }
@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/database"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+)
+
+type TermRoute interface {
+	GetAllTerms(w http.ResponseWriter, r *http.Request)
+	CreateTerm(w http.ResponseWriter, r *http.Request)
+}
+
+type TermRouteImpl struct {
+	termService service.TermService
+}
+
+// GetAllTerms godoc
+//
+//	@Tags			term
+//	@Summary		Get all terms
+//	@Description	Returns academic terms, newest first
+//	@Produce		json
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[[]schemas.Term]
+//	@Router			/term/ [get]
+func (tr *TermRouteImpl) GetAllTerms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	terms, err := tr.termService.GetAllTerms(tx)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting terms. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, terms)
+}
+
+// CreateTerm godoc
+//
+//	@Tags			term
+//	@Summary		Create a term
+//	@Description	Creates an academic term. Submissions are tagged with the term in progress. Terms cannot overlap. Only admins can create terms
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schemas.TermCreate	true	"Term"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		403		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		409		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		201		{object}	httputils.ApiResponse[schemas.Term]
+//	@Router			/term/ [post]
+func (tr *TermRouteImpl) CreateTerm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+
+	var request schemas.TermCreate
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		httputils.ReturnRequestBodyError(w, err)
+		return
+	}
+
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, "Transaction was not started by middleware. "+err.Error())
+		return
+	}
+
+	term, err := tr.termService.CreateTerm(tx, currentUser, request)
+	if err != nil {
+		db.Rollback()
+		if err == service.ErrNotAuthorized {
+			httputils.ReturnError(w, http.StatusForbidden, "Only admins can create terms.")
+			return
+		}
+		if err == service.ErrTermOverlaps {
+			httputils.ReturnError(w, http.StatusConflict, "Term overlaps an existing term.")
+			return
+		}
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			httputils.ReturnValidationError(w, "Invalid term.", validationErrors)
+			return
+		}
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating term. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusCreated, term)
+}
+
+func NewTermRoute(termService service.TermService) TermRoute {
+	return &TermRouteImpl{termService: termService}
+}
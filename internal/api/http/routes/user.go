@@ -19,12 +19,27 @@ type UserRoute interface {
 	GetUserByEmail(w http.ResponseWriter, r *http.Request)
 	EditUser(w http.ResponseWriter, r *http.Request)
 	CreateUsers(w http.ResponseWriter, r *http.Request)
+	GetMyCapabilities(w http.ResponseWriter, r *http.Request)
 }
 
 type UserRouteImpl struct {
 	userService service.UserService
+	pagination  httputils.PaginationLimits
 }
 
+// GetAllUsers godoc
+//
+//	@Tags			user
+//	@Summary		Get all users
+//	@Description	Returns all users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiPageResponse[[]schemas.User]
+//	@Router			/user/ [get]
 func (u *UserRouteImpl) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -33,26 +48,9 @@ func (u *UserRouteImpl) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 
 	query := r.URL.Query()
 
-	limitStr := query.Get("limit")
-	offsetStr := query.Get("offset")
-
-	if limitStr == "" {
-		limitStr = httputils.DefaultPaginationLimitStr
-	}
-
-	if offsetStr == "" {
-		offsetStr = httputils.DefaultPaginationOffsetStr
-	}
-
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid limit")
-		return
-	}
-
-	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	limit, offset, err := httputils.GetPagination(query, u.pagination)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid offset")
+		httputils.ReturnError(w, http.StatusBadRequest, "Invalid pagination. "+err.Error())
 		return
 	}
 
@@ -74,9 +72,21 @@ func (u *UserRouteImpl) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		users = []schemas.User{}
 	}
 
-	httputils.ReturnSuccess(w, http.StatusOK, users)
+	httputils.ReturnPage(w, users, httputils.PageInfo{Limit: limit, Offset: offset})
 }
 
+// GetUserById godoc
+//
+//	@Tags			user
+//	@Summary		Get a user
+//	@Description	Returns a user by ID
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Failure		400	{object}	httputils.ApiError
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.User]
+//	@Router			/user/{id} [get]
 func (u *UserRouteImpl) GetUserById(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -112,6 +122,52 @@ func (u *UserRouteImpl) GetUserById(w http.ResponseWriter, r *http.Request) {
 	httputils.ReturnSuccess(w, http.StatusOK, user)
 }
 
+// GetMyCapabilities godoc
+//
+//	@Tags			user
+//	@Summary		Get capabilities of the current user
+//	@Description	Returns the actions the current user may perform based on their role and the number of tasks they created, so menus can be rendered without repeating the role checks
+//	@Produce		json
+//	@Failure		405	{object}	httputils.ApiError
+//	@Failure		500	{object}	httputils.ApiError
+//	@Success		200	{object}	httputils.ApiResponse[schemas.UserCapabilities]
+//	@Router			/user/me/capabilities [get]
+func (u *UserRouteImpl) GetMyCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	currentUser := r.Context().Value(middleware.UserKey).(schemas.User)
+	db := r.Context().Value(middleware.DatabaseKey).(database.Database)
+	tx, err := db.Connect()
+	if err != nil {
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error connecting to database. %s", err.Error()))
+		return
+	}
+
+	capabilities, err := u.userService.GetCapabilities(tx, currentUser)
+	if err != nil {
+		db.Rollback()
+		httputils.ReturnError(w, http.StatusInternalServerError, fmt.Sprintf("Error getting capabilities. %s", err.Error()))
+		return
+	}
+
+	httputils.ReturnSuccess(w, http.StatusOK, capabilities)
+}
+
+// GetUserByEmail godoc
+//
+//	@Tags			user
+//	@Summary		Get a user by email
+//	@Description	Returns a user by email
+//	@Produce		json
+//	@Param			email	query		string	true	"User email"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[schemas.User]
+//	@Router			/user/email [get]
 func (u *UserRouteImpl) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -141,6 +197,21 @@ func (u *UserRouteImpl) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 	httputils.ReturnSuccess(w, http.StatusOK, user)
 }
 
+// EditUser godoc
+//
+//	@Tags			user
+//	@Summary		Edit a user
+//	@Description	Updates fields of a user
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"User ID"
+//	@Param			request	body		schemas.UserEdit	true	"Changed fields"
+//	@Failure		400		{object}	httputils.ApiError
+//	@Failure		405		{object}	httputils.ApiError
+//	@Failure		413		{object}	httputils.ApiError
+//	@Failure		500		{object}	httputils.ApiError
+//	@Success		200		{object}	httputils.ApiResponse[string]
+//	@Router			/user/{id} [put]
 func (u *UserRouteImpl) EditUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		httputils.ReturnError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -159,7 +230,7 @@ func (u *UserRouteImpl) EditUser(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		httputils.ReturnError(w, http.StatusBadRequest, "Invalid request body. "+err.Error())
+		httputils.ReturnRequestBodyError(w, err)
 		return
 	}
 
@@ -186,6 +257,6 @@ func (u *UserRouteImpl) CreateUsers(w http.ResponseWriter, r *http.Request) {
 	httputils.ReturnError(w, http.StatusNotImplemented, "Not implemented")
 }
 
-func NewUserRoute(userService service.UserService) UserRoute {
-	return &UserRouteImpl{userService: userService}
+func NewUserRoute(userService service.UserService, pagination httputils.PaginationLimits) UserRoute {
+	return &UserRouteImpl{userService: userService, pagination: pagination}
 }
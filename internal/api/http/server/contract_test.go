@@ -0,0 +1,309 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/api/http/httputils"
+	"github.com/mini-maxit/backend/internal/api/http/initialization"
+	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/api/http/routes"
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/swaggo/swag"
+)
+
+// repoRoot is the root of the repository relative to this package
+const repoRoot = "../../../.."
+
+// apiSpec is the part of the generated OpenAPI spec checked against the handlers
+type apiSpec struct {
+	BasePath string                             `json:"basePath"`
+	Paths    map[string]map[string]apiOperation `json:"paths"`
+}
+
+type apiOperation struct {
+	Consumes  []string               `json:"consumes"`
+	Produces  []string               `json:"produces"`
+	Responses map[string]interface{} `json:"responses"`
+}
+
+// apiEnvelope is the shape of every JSON response, see httputils.ApiResponse and httputils.ApiError
+type apiEnvelope struct {
+	Ok   *bool           `json:"ok"`
+	Data json.RawMessage `json:"data"`
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{[^}]+\}`)
+
+// undocumentedHandlers are route handlers which are not registered, so they have no @Router annotation
+var undocumentedHandlers = []string{
+	"UserRouteImpl.CreateUsers",
+}
+
+type discardDebugger struct{}
+
+func (d discardDebugger) Printf(format string, v ...interface{}) {}
+
+// loadApiSpec generates the spec from the swag annotations with the same options as the docs workflow,
+// so the contract is checked against the current annotations and not the last published docs
+func loadApiSpec(t *testing.T) *apiSpec {
+	p := swag.New(swag.SetDebugger(discardDebugger{}))
+	p.PropNamingStrategy = swag.SnakeCase
+	dirs := []string{
+		filepath.Join(repoRoot, "cmd/app"),
+		filepath.Join(repoRoot, "internal/api/http/httputils"),
+		filepath.Join(repoRoot, "package/domain/schemas"),
+		repoRoot,
+	}
+	if err := p.ParseAPIMultiSearchDir(dirs, "main.go", 100); err != nil {
+		t.Fatalf("failed to generate the API spec: %v", err)
+	}
+	raw, err := json.Marshal(p.GetSwagger())
+	if err != nil {
+		t.Fatalf("failed to encode the API spec: %v", err)
+	}
+	result := &apiSpec{}
+	if err := json.Unmarshal(raw, result); err != nil {
+		t.Fatalf("failed to decode the API spec: %v", err)
+	}
+	return result
+}
+
+// newContractServer serves every route, including the optional ones, with stubbed services
+func newContractServer() *Server {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			MaxJSONBodySize:      1 << 20,
+			MaxMultipartBodySize: 16 << 20,
+		},
+		Sandbox: config.SandboxConfig{Enabled: true, RateLimit: 1 << 20},
+	}
+	isTrusted := func(r *http.Request) bool { return false }
+	pagination := httputils.PaginationLimits{DefaultLimit: 10, MaxLimit: 100}
+	taskService := &taskServiceStub{}
+	submissionService := &submissionServiceStub{}
+	languageService := &languageServiceStub{}
+	userService := &userServiceStub{}
+	statusService := &statusServiceStub{}
+	uploadScanService := &uploadScanServiceStub{}
+	app := &initialization.Initialization{
+		Cfg:              cfg,
+		Db:               &databaseStub{},
+		TaskService:      taskService,
+		SessionService:   &sessionServiceStub{},
+		UserService:      userService,
+		IsTrustedRequest: isTrusted,
+		SessionUsers:     middleware.NewSessionUserCache(middleware.SessionUserTTL),
+		SandboxLimiter:   middleware.NewRateLimiter(cfg.Sandbox.RateLimit, time.Minute),
+		AuthRoute:        routes.NewAuthRoute(userService, &authServiceStub{}),
+		TaskRoute:        routes.NewTaskRoute("", taskService, &queueServiceStub{}, submissionService, languageService, uploadScanService, &taskExportServiceStub{}, pagination),
+		SessionRoute:     routes.NewSessionRoute(&sessionServiceStub{}),
+		UserRoute:        routes.NewUserRoute(userService, pagination),
+		AdminRoute: routes.NewAdminRoute(&integrityServiceStub{}, &onlineMigrationServiceStub{}, statusService, submissionService,
+			&trustListServiceStub{}, userService, &judgeAuditServiceStub{}, languageService, &queueServiceStub{}, uploadScanService, &accessControlServiceStub{}, pagination),
+		TermRoute:         routes.NewTermRoute(&termServiceStub{}),
+		TagRoute:          routes.NewTagRoute(&tagServiceStub{}),
+		StatusRoute:       routes.NewStatusRoute(statusService),
+		SandboxRoute:      routes.NewSandboxRoute(taskService, pagination),
+		SubmissionRoute:   routes.NewSubmissionRoute(submissionService, &rejudgeServiceStub{}, &gradingServiceStub{}, pagination),
+		LimitsRoute:       routes.NewLimitsRoute(&limitsServiceStub{}, isTrusted),
+		ActivityRoute:     routes.NewActivityRoute(&activityServiceStub{}, pagination),
+		NotificationRoute: routes.NewNotificationRoute(&notificationServiceStub{}, pagination),
+		PlagiarismRoute:   routes.NewPlagiarismRoute(&plagiarismServiceStub{}),
+		StatsRoute:        routes.NewStatsRoute(&statsServiceStub{}),
+		AnnouncementRoute: routes.NewAnnouncementRoute(&announcementServiceStub{}, pagination),
+	}
+	return NewServer(app, logger.NewNamedLogger("contract_test"))
+}
+
+// TestApiContract checks that every documented operation is served and responds only with documented
+// status codes in the response envelope, and that every route handler is documented
+func TestApiContract(t *testing.T) {
+	logger.InitializeLoggerInDir(t.TempDir())
+	spec := loadApiSpec(t)
+	server := newContractServer()
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		operations := spec.Paths[path]
+		url := spec.BasePath + pathParamRegexp.ReplaceAllString(path, "1")
+		for method, operation := range operations {
+			method = strings.ToUpper(method)
+			t.Run(method+" "+path, func(t *testing.T) {
+				r := httptest.NewRequest(method, url, nil)
+				if slices.Contains(operation.Consumes, "application/json") {
+					r = httptest.NewRequest(method, url, strings.NewReader("{}"))
+					r.Header.Set("Content-Type", "application/json")
+				}
+				assertDocumentedResponse(t, server, r, operation)
+			})
+		}
+
+		// A method not used by any operation of the path has to be rejected by the handlers
+		t.Run("Not allowed method "+path, func(t *testing.T) {
+			for _, method := range []string{http.MethodPatch, http.MethodDelete, http.MethodPut, http.MethodPost} {
+				if _, ok := operations[strings.ToLower(method)]; ok {
+					continue
+				}
+				w := serve(server, httptest.NewRequest(method, url, nil))
+				assert.Equal(t, http.StatusMethodNotAllowed, w.Code, "%s %s", method, path)
+				assertEnvelope(t, w)
+				for _, operation := range operations {
+					_, documented := operation.Responses[strconv.Itoa(http.StatusMethodNotAllowed)]
+					assert.True(t, documented, "status 405 is not documented")
+				}
+				return
+			}
+		})
+	}
+
+	t.Run("Route handlers are documented", func(t *testing.T) {
+		for handler, documented := range routeHandlers(t) {
+			if slices.Contains(undocumentedHandlers, handler) {
+				continue
+			}
+			assert.True(t, documented, "%s has no @Router annotation", handler)
+		}
+	})
+}
+
+// TestBodyLimits checks that bodies are limited by the route they are sent to, not by their content type
+func TestBodyLimits(t *testing.T) {
+	logger.InitializeLoggerInDir(t.TempDir())
+	server := newContractServer()
+	multipartRequest := func(method, path string, size int) *http.Request {
+		r := httptest.NewRequest(method, "/api/"+ApiVersion+path, bytes.NewReader(make([]byte, size)))
+		r.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+		return r
+	}
+
+	t.Run("Submission larger than the submission limit", func(t *testing.T) {
+		w := serve(server, multipartRequest(http.MethodPost, "/task/submit", routes.MaxSubmissionSize+1))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Multipart body to a JSON route", func(t *testing.T) {
+		w := serve(server, multipartRequest(http.MethodPost, "/announcement/", 2<<20))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Upload within the upload limit", func(t *testing.T) {
+		w := serve(server, multipartRequest(http.MethodPost, "/task/import", 2<<20))
+		assert.NotEqual(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
+func serve(server *Server, r *http.Request) *httptest.ResponseRecorder {
+	r.Header.Set("Session", "session")
+	w := httptest.NewRecorder()
+	server.mux.ServeHTTP(w, r)
+	return w
+}
+
+func assertDocumentedResponse(t *testing.T, server *Server, r *http.Request, operation apiOperation) {
+	w := serve(server, r)
+	if w.Code == http.StatusNotFound && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("route is not registered")
+	}
+	_, documented := operation.Responses[strconv.Itoa(w.Code)]
+	assert.True(t, documented, "responded with undocumented status %d: %s", w.Code, w.Body.String())
+	// Downloads are the only responses outside of the envelope
+	if len(operation.Produces) > 0 && !slices.Contains(operation.Produces, "application/json") && w.Code < 400 {
+		return
+	}
+	assertEnvelope(t, w)
+}
+
+func assertEnvelope(t *testing.T, w *httptest.ResponseRecorder) {
+	envelope := apiEnvelope{}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil || envelope.Ok == nil || envelope.Data == nil {
+		t.Errorf("response is not an API envelope: %s", w.Body.String())
+		return
+	}
+	assert.Equal(t, w.Code < 400, *envelope.Ok, "ok does not match status %d", w.Code)
+	if !*envelope.Ok {
+		errorData := struct {
+			Code    *string `json:"code"`
+			Message *string `json:"message"`
+		}{}
+		err := json.Unmarshal(envelope.Data, &errorData)
+		assert.True(t, err == nil && errorData.Code != nil && errorData.Message != nil, "error without code and message: %s", w.Body.String())
+	}
+}
+
+// routeHandlers returns methods of the route interfaces and whether their doc comment has a @Router annotation
+func routeHandlers(t *testing.T) map[string]bool {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(repoRoot, "internal/api/http/routes/*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers := make(map[string]bool)
+	var decls []*ast.FuncDecl
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, s := range decl.Specs {
+					typeSpec, ok := s.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					iface, ok := typeSpec.Type.(*ast.InterfaceType)
+					if !ok || !strings.HasSuffix(typeSpec.Name.Name, "Route") {
+						continue
+					}
+					for _, method := range iface.Methods.List {
+						for _, name := range method.Names {
+							handlers[fmt.Sprintf("%sImpl.%s", typeSpec.Name.Name, name.Name)] = false
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				decls = append(decls, decl)
+			}
+		}
+	}
+	for _, decl := range decls {
+		if decl.Recv == nil || decl.Doc == nil || !strings.Contains(decl.Doc.Text(), "@Router") {
+			continue
+		}
+		receiver, ok := decl.Recv.List[0].Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		ident, ok := receiver.X.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		handler := ident.Name + "." + decl.Name.Name
+		if _, ok := handlers[handler]; ok {
+			handlers[handler] = true
+		}
+	}
+	return handlers
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/mini-maxit/backend/internal/api/http/initialization"
 	"github.com/mini-maxit/backend/internal/api/http/middleware"
+	"github.com/mini-maxit/backend/internal/api/http/routes"
 	"github.com/mini-maxit/backend/internal/logger"
 	"go.uber.org/zap"
 )
@@ -57,37 +58,198 @@ func NewServer(initialization *initialization.Initialization, log *zap.SugaredLo
 	authMux := http.NewServeMux()
 	authMux.HandleFunc("/login", initialization.AuthRoute.Login)
 	authMux.HandleFunc("/register", initialization.AuthRoute.Register)
+	authMux.HandleFunc("/refresh", initialization.AuthRoute.Refresh)
+	authMux.HandleFunc("/logout", initialization.AuthRoute.Logout)
+	authMux.HandleFunc("/forgot-password", initialization.AuthRoute.ForgotPassword)
+	authMux.HandleFunc("/reset-password", initialization.AuthRoute.ResetPassword)
+	authMux.HandleFunc("/oauth/{provider}/login", initialization.AuthRoute.OAuthLogin)
+	authMux.HandleFunc("/oauth/{provider}/callback", initialization.AuthRoute.OAuthCallback)
 
 	// Task routes
 	taskMux := http.NewServeMux()
 	taskMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
+		if r.Method == http.MethodPost {
 			initialization.TaskRoute.UploadTask(w, r)
-		} else if r.Method == "GET" {
+		} else {
 			initialization.TaskRoute.GetAllTasks(w, r)
 		}
 	},
 	)
-	taskMux.HandleFunc("/{id}", initialization.TaskRoute.GetTask)
+	taskMux.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			initialization.TaskRoute.UpdateTask(w, r)
+		} else {
+			initialization.TaskRoute.GetTask(w, r)
+		}
+	},
+	)
 	taskMux.HandleFunc("/submit", initialization.TaskRoute.SubmitSolution)
+	taskMux.HandleFunc("/{id}/bookmark", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			initialization.TaskRoute.BookmarkTask(w, r)
+		} else {
+			initialization.TaskRoute.UnbookmarkTask(w, r)
+		}
+	},
+	)
+	taskMux.HandleFunc("/{id}/my-submissions/export", initialization.TaskRoute.ExportMySubmissions)
+	taskMux.HandleFunc("/{id}/export", initialization.TaskRoute.ExportTask)
+	taskMux.HandleFunc("/import", initialization.TaskRoute.ImportTask)
+	taskMux.HandleFunc("/{id}/co-authors", initialization.TaskRoute.UpdateTaskCoAuthors)
+	taskMux.HandleFunc("/{id}/test-groups", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			initialization.TaskRoute.UpdateTestCaseGroups(w, r)
+		} else {
+			initialization.TaskRoute.GetTestCaseGroups(w, r)
+		}
+	},
+	)
+	taskMux.HandleFunc("/{id}/evaluation-policy", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			initialization.TaskRoute.UpdateTaskEvaluationPolicy(w, r)
+		} else {
+			initialization.TaskRoute.GetTaskEvaluationPolicy(w, r)
+		}
+	},
+	)
+	taskMux.HandleFunc("/{id}/sandbox", initialization.TaskRoute.SetTaskSandbox)
+	taskMux.HandleFunc("/{id}/test-visibility", initialization.TaskRoute.SetTaskTestVisibility)
+	taskMux.HandleFunc("/{id}/submission-retention", initialization.TaskRoute.SetTaskSubmissionRetention)
+	taskMux.HandleFunc("/{id}/tags", initialization.TagRoute.SetTaskTags)
+	taskMux.HandleFunc("/{id}/stats", initialization.TaskRoute.GetTaskStats)
+	taskMux.HandleFunc("/{id}/changes", initialization.TaskRoute.GetTaskChanges)
+	taskMux.HandleFunc("/{id}/rejudge", initialization.SubmissionRoute.RejudgeTask)
+	taskMux.HandleFunc("/{id}/plagiarism", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.PlagiarismRoute.CheckPlagiarism(w, r)
+		} else {
+			initialization.PlagiarismRoute.GetPlagiarismReport(w, r)
+		}
+	},
+	)
+	taskMux.HandleFunc("/{id}/draft", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			initialization.TaskRoute.PutTaskDraft(w, r)
+		} else {
+			initialization.TaskRoute.GetTaskDrafts(w, r)
+		}
+	},
+	)
+	taskMux.HandleFunc("/{id}/note", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			initialization.TaskRoute.PutTaskNote(w, r)
+		case http.MethodDelete:
+			initialization.TaskRoute.DeleteTaskNote(w, r)
+		default:
+			initialization.TaskRoute.GetTaskNote(w, r)
+		}
+	},
+	)
+
+	// Task pool routes
+	taskPoolMux := http.NewServeMux()
+	taskPoolMux.HandleFunc("/", initialization.TaskRoute.CreateTaskPool)
+	taskPoolMux.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			initialization.TaskRoute.DeleteTaskPool(w, r)
+		} else {
+			initialization.TaskRoute.GetTaskPool(w, r)
+		}
+	},
+	)
 
 	// User routes
 	userMux := http.NewServeMux()
 	userMux.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			initialization.UserRoute.GetUserById(w, r)
-		} else if r.Method == http.MethodPut {
+		if r.Method == http.MethodPut {
 			initialization.UserRoute.EditUser(w, r)
+		} else {
+			initialization.UserRoute.GetUserById(w, r)
 		}
 	},
 	)
 	userMux.HandleFunc("/", initialization.UserRoute.GetAllUsers)
 	userMux.HandleFunc("/email", initialization.UserRoute.GetUserByEmail)
+	userMux.HandleFunc("/me/capabilities", initialization.UserRoute.GetMyCapabilities)
 	userMux.HandleFunc("/{id}/task", initialization.TaskRoute.GetAllForUser)
 
 	// Group routes
 	groupMux := http.NewServeMux()
 	groupMux.HandleFunc("/{id}/task", initialization.TaskRoute.GetAllForGroup)
+	groupMux.HandleFunc("/{id}/stats", initialization.StatsRoute.GetGroupStats)
+	groupMux.HandleFunc("/{id}/progress", initialization.StatsRoute.GetGroupProgress)
+	groupMux.HandleFunc("/{id}/stats/export", initialization.StatsRoute.ExportGroupStats)
+	groupMux.HandleFunc("/{id}/task/{task_id}/stats-visibility", initialization.StatsRoute.SetTaskStatsVisibility)
+	groupMux.HandleFunc("/{id}/announcements", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.AnnouncementRoute.CreateGroupAnnouncement(w, r)
+		} else {
+			initialization.AnnouncementRoute.GetGroupAnnouncements(w, r)
+		}
+	},
+	)
+
+	// Admin routes
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("/integrity/orphans", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.AdminRoute.CleanupOrphans(w, r)
+		} else {
+			initialization.AdminRoute.GetOrphans(w, r)
+		}
+	},
+	)
+	adminMux.HandleFunc("/migrations", initialization.AdminRoute.GetOnlineMigrations)
+	adminMux.HandleFunc("/migrations/{name}/cutover", initialization.AdminRoute.SetOnlineMigrationCutover)
+	adminMux.HandleFunc("/migrations/{name}/resume", initialization.AdminRoute.ResumeOnlineMigration)
+	adminMux.HandleFunc("/incidents", initialization.AdminRoute.CreateIncident)
+	adminMux.HandleFunc("/incidents/{id}/updates", initialization.AdminRoute.AddIncidentUpdate)
+	adminMux.HandleFunc("/capacity/simulate", initialization.AdminRoute.SimulateCapacity)
+	adminMux.HandleFunc("/submission-results/recompute", initialization.AdminRoute.RecomputeScores)
+	adminMux.HandleFunc("/users/import", initialization.AdminRoute.ImportUsers)
+	adminMux.HandleFunc("/users/{id}/status", initialization.AdminRoute.SetUserStatus)
+	adminMux.HandleFunc("/users/{id}/impersonate", initialization.AdminRoute.ImpersonateUser)
+	adminMux.HandleFunc("/users/{id}/role", initialization.AdminRoute.AssignUserRole)
+	adminMux.HandleFunc("/roles", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.AdminRoute.CreateRole(w, r)
+		} else {
+			initialization.AdminRoute.GetRoles(w, r)
+		}
+	},
+	)
+	adminMux.HandleFunc("/roles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			initialization.AdminRoute.DeleteRole(w, r)
+		} else {
+			initialization.AdminRoute.UpdateRole(w, r)
+		}
+	},
+	)
+	adminMux.HandleFunc("/audit-log", initialization.AdminRoute.GetAuditLog)
+	adminMux.HandleFunc("/judge-audits", initialization.AdminRoute.GetJudgeAudits)
+	adminMux.HandleFunc("/queue-failures", initialization.AdminRoute.GetQueueFailures)
+	adminMux.HandleFunc("/queue-failures/{id}/requeue", initialization.AdminRoute.RequeueQueueFailure)
+	adminMux.HandleFunc("/quarantine", initialization.AdminRoute.GetQuarantinedFiles)
+	adminMux.HandleFunc("/trust-list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.AdminRoute.CreateTrustListEntry(w, r)
+		} else {
+			initialization.AdminRoute.GetTrustList(w, r)
+		}
+	},
+	)
+	adminMux.HandleFunc("/trust-list/{id}", initialization.AdminRoute.DeleteTrustListEntry)
+	adminMux.HandleFunc("/languages", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.AdminRoute.CreateLanguage(w, r)
+		} else {
+			initialization.AdminRoute.GetLanguages(w, r)
+		}
+	},
+	)
+	adminMux.HandleFunc("/languages/{id}", initialization.AdminRoute.UpdateLanguage)
 
 	// Session routes
 	sessionMux := http.NewServeMux()
@@ -95,24 +257,105 @@ func NewServer(initialization *initialization.Initialization, log *zap.SugaredLo
 	sessionMux.HandleFunc("/validate", initialization.SessionRoute.ValidateSession)
 	sessionMux.HandleFunc("/invalidate", initialization.SessionRoute.InvalidateSession)
 
+	// Term routes
+	termMux := http.NewServeMux()
+	termMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.TermRoute.CreateTerm(w, r)
+		} else {
+			initialization.TermRoute.GetAllTerms(w, r)
+		}
+	},
+	)
+
+	// Tag routes
+	tagMux := http.NewServeMux()
+	tagMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.TagRoute.CreateTag(w, r)
+		} else {
+			initialization.TagRoute.GetTags(w, r)
+		}
+	},
+	)
+	tagMux.HandleFunc("/{id}", initialization.TagRoute.DeleteTag)
+
+	// Announcement routes
+	announcementMux := http.NewServeMux()
+	announcementMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			initialization.AnnouncementRoute.CreateAnnouncement(w, r)
+		} else {
+			initialization.AnnouncementRoute.GetAnnouncements(w, r)
+		}
+	},
+	)
+	announcementMux.HandleFunc("/unread", initialization.AnnouncementRoute.CountUnreadAnnouncements)
+	announcementMux.HandleFunc("/{id}/read", initialization.AnnouncementRoute.MarkAnnouncementRead)
+
+	// Submission routes
+	submissionMux := http.NewServeMux()
+	submissionMux.HandleFunc("/", initialization.SubmissionRoute.GetSubmissionsByEnvironment)
+	submissionMux.HandleFunc("/{id}", initialization.SubmissionRoute.GetSubmission)
+	submissionMux.HandleFunc("/grades/import", initialization.SubmissionRoute.ImportGrades)
+	submissionMux.HandleFunc("/{id}/source", initialization.SubmissionRoute.GetSubmissionSource)
+	submissionMux.HandleFunc("/{id}/timeline", initialization.SubmissionRoute.GetSubmissionTimeline)
+	submissionMux.HandleFunc("/{id}/redact", initialization.SubmissionRoute.RedactSubmission)
+	submissionMux.HandleFunc("/{id}/rejudge", initialization.SubmissionRoute.RejudgeSubmission)
+
+	// Rejudge routes
+	rejudgeMux := http.NewServeMux()
+	rejudgeMux.HandleFunc("/{id}", initialization.SubmissionRoute.GetRejudgeBatch)
+
 	// Secure routes (require authentication)
 	secureMux := http.NewServeMux()
 	secureMux.Handle("/task/", http.StripPrefix("/task", taskMux))
+	secureMux.Handle("/task-pool/", http.StripPrefix("/task-pool", taskPoolMux))
 	secureMux.Handle("/session/", http.StripPrefix("/session", sessionMux))
 	secureMux.Handle("/user/", http.StripPrefix("/user", userMux))
 	secureMux.Handle("/group/", http.StripPrefix("/group", groupMux))
+	secureMux.Handle("/admin/", http.StripPrefix("/admin", adminMux))
+	secureMux.Handle("/term/", http.StripPrefix("/term", termMux))
+	secureMux.Handle("/tag/", http.StripPrefix("/tag", tagMux))
+	secureMux.Handle("/announcement/", http.StripPrefix("/announcement", announcementMux))
+	secureMux.Handle("/submission/", http.StripPrefix("/submission", submissionMux))
+	secureMux.Handle("/rejudge/", http.StripPrefix("/rejudge", rejudgeMux))
+	secureMux.HandleFunc("/limits", initialization.LimitsRoute.GetLimits)
+	secureMux.HandleFunc("/activity", initialization.ActivityRoute.GetActivity)
+	secureMux.HandleFunc("/notification/", initialization.NotificationRoute.GetNotifications)
+	secureMux.HandleFunc("/notification/{id}/read", initialization.NotificationRoute.MarkNotificationRead)
 
 	// API routes
 	apiMux := http.NewServeMux()
 	apiMux.Handle("/auth/", http.StripPrefix("/auth", authMux))
-	apiMux.Handle("/", middleware.SessionValidationMiddleware(secureMux, initialization.Db, initialization.SessionService))
+	apiMux.HandleFunc("/status", initialization.StatusRoute.GetStatus)
+	if initialization.Cfg.Sandbox.Enabled {
+		// Sandbox routes are public, so they get their own per IP rate limit
+		sandboxMux := http.NewServeMux()
+		sandboxMux.HandleFunc("/tasks", initialization.SandboxRoute.GetSandboxTasks)
+		sandboxMux.HandleFunc("/tasks/{id}", initialization.SandboxRoute.GetSandboxTask)
+		apiMux.Handle("/sandbox/", middleware.RateLimitMiddleware(http.StripPrefix("/sandbox", sandboxMux), initialization.SandboxLimiter, initialization.IsTrustedRequest))
+	}
+	apiMux.Handle("/", middleware.SessionValidationMiddleware(secureMux, initialization.Db, initialization.SessionService, initialization.UserService, initialization.SessionUsers))
 	apiMux.Handle("/docs/", http.StripPrefix("/docs/", http.FileServer(http.Dir("docs"))))
 
+	// Upload routes accept larger bodies, every other route is limited to the JSON body size
+	bodyLimits := middleware.NewBodyLimits(initialization.Cfg.App.MaxJSONBodySize)
+	uploadLimit := initialization.Cfg.App.MaxMultipartBodySize
+	bodyLimits.Limit("POST /task/{$}", uploadLimit)
+	bodyLimits.Limit("/task/import", uploadLimit)
+	bodyLimits.Limit("/task/submit", min(routes.MaxSubmissionSize, uploadLimit))
+	bodyLimits.Limit("/admin/users/import", uploadLimit)
+	bodyLimits.Limit("/submission/grades/import", uploadLimit)
+
 	// Logging middleware
 	httpLoger := logger.NewHttpLogger()
 	loggingMux := http.NewServeMux()
 	loggingMux.Handle("/", middleware.LoggingMiddleware(apiMux, httpLoger))
 	// Add the API prefix to all routes
-	mux.Handle(apiPrefix+"/", http.StripPrefix(apiPrefix, middleware.RecoveryMiddleware(middleware.DatabaseMiddleware(loggingMux, initialization.Db), log)))
+	apiHandler := middleware.DatabaseMiddleware(loggingMux, initialization.Db)
+	apiHandler = middleware.BodyLimitMiddleware(apiHandler, bodyLimits)
+	apiHandler = middleware.LoadSheddingMiddleware(apiHandler, initialization.Cfg.App.MaxInFlightRequests, initialization.IsTrustedRequest)
+	mux.Handle(apiPrefix+"/", http.StripPrefix(apiPrefix, middleware.RecoveryMiddleware(apiHandler, log)))
 	return &Server{mux: mux, port: initialization.Cfg.App.Port, logger: log}
 }
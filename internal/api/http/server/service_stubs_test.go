@@ -0,0 +1,669 @@
+package server
+
+import (
+	"io"
+	"net"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/service"
+	"gorm.io/gorm"
+)
+
+// Service stubs used by the API contract test. Every method succeeds with an empty result,
+// so handlers can be exercised without a database or a broker
+
+type databaseStub struct{}
+
+func (d *databaseStub) Connect() (*gorm.DB, error) {
+	return nil, nil
+}
+
+func (d *databaseStub) ShouldRollback() bool {
+	return false
+}
+
+func (d *databaseStub) Rollback() {}
+
+func (d *databaseStub) Commit() error {
+	return nil
+}
+
+func (d *databaseStub) InvalidateTx() {}
+
+type taskServiceStub struct{}
+
+func (s *taskServiceStub) Create(tx *gorm.DB, currentUser schemas.User, task *schemas.Task) (int64, error) {
+	return 0, nil
+}
+
+func (s *taskServiceStub) GetAll(tx *gorm.DB, userId int64, filter schemas.TaskFilter, limit, offset int64) ([]schemas.Task, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) GetAllForUser(tx *gorm.DB, currentUserId, userId, limit, offset int64) ([]schemas.Task, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) GetAllForGroup(tx *gorm.DB, currentUserId, groupId, limit, offset int64) ([]schemas.Task, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) GetTask(tx *gorm.DB, taskId int64) (*schemas.TaskDetailed, error) {
+	return new(schemas.TaskDetailed), nil
+}
+
+func (s *taskServiceStub) GetTaskByTitle(tx *gorm.DB, title string) (*schemas.Task, error) {
+	return new(schemas.Task), nil
+}
+
+func (s *taskServiceStub) UpdateTask(tx *gorm.DB, currentUser schemas.User, taskId int64, updateInfo schemas.UpdateTask) (*schemas.TaskDetailed, error) {
+	return new(schemas.TaskDetailed), nil
+}
+
+func (s *taskServiceStub) AuthorizeSubmission(tx *gorm.DB, currentUser schemas.User, taskId int64) error {
+	return nil
+}
+
+func (s *taskServiceStub) CreateSubmission(tx *gorm.DB, taskId int64, userId int64, languageId int64, order int64, sourceHash string) (int64, error) {
+	return 0, nil
+}
+
+func (s *taskServiceStub) BookmarkTask(tx *gorm.DB, taskId int64, userId int64) error {
+	return nil
+}
+
+func (s *taskServiceStub) UnbookmarkTask(tx *gorm.DB, taskId int64, userId int64) error {
+	return nil
+}
+
+func (s *taskServiceStub) GetTaskNote(tx *gorm.DB, taskId int64, userId int64) (*schemas.TaskNote, error) {
+	return new(schemas.TaskNote), nil
+}
+
+func (s *taskServiceStub) PutTaskNote(tx *gorm.DB, taskId int64, userId int64, note schemas.TaskNoteEdit) (*schemas.TaskNote, error) {
+	return new(schemas.TaskNote), nil
+}
+
+func (s *taskServiceStub) DeleteTaskNote(tx *gorm.DB, taskId int64, userId int64) error {
+	return nil
+}
+
+func (s *taskServiceStub) GetTaskDrafts(tx *gorm.DB, taskId int64, userId int64) ([]schemas.TaskDraft, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) PutTaskDraft(tx *gorm.DB, taskId int64, userId int64, draft schemas.TaskDraftEdit) (*schemas.TaskDraft, error) {
+	return new(schemas.TaskDraft), nil
+}
+
+func (s *taskServiceStub) DeleteExpiredDrafts(tx *gorm.DB) (int64, error) {
+	return 0, nil
+}
+
+func (s *taskServiceStub) UpdateTaskCoAuthors(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskCoAuthorsEdit) ([]schemas.TaskCoAuthor, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) GetTestCaseGroups(tx *gorm.DB, taskId int64) ([]schemas.TestCaseGroup, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) UpdateTestCaseGroups(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TestCaseGroupsEdit) ([]schemas.TestCaseGroup, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) GetTaskEvaluationPolicy(tx *gorm.DB, taskId int64) (*schemas.TaskEvaluationPolicy, error) {
+	return new(schemas.TaskEvaluationPolicy), nil
+}
+
+func (s *taskServiceStub) GetTaskChanges(tx *gorm.DB, taskId int64, limit, offset int64) ([]schemas.TaskChange, error) {
+	return []schemas.TaskChange{}, nil
+}
+
+func (s *taskServiceStub) UpdateTaskEvaluationPolicy(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskEvaluationPolicyEdit) (*schemas.TaskEvaluationPolicy, error) {
+	return new(schemas.TaskEvaluationPolicy), nil
+}
+
+func (s *taskServiceStub) GetSandboxTasks(tx *gorm.DB, limit, offset int64) ([]schemas.Task, error) {
+	return nil, nil
+}
+
+func (s *taskServiceStub) GetSandboxTask(tx *gorm.DB, taskId int64) (*schemas.TaskDetailed, error) {
+	return new(schemas.TaskDetailed), nil
+}
+
+func (s *taskServiceStub) SetTaskSandbox(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskSandboxEdit) error {
+	return nil
+}
+
+func (s *taskServiceStub) SetTaskTestVisibility(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskTestVisibilityEdit) error {
+	return nil
+}
+
+func (s *taskServiceStub) SetTaskSubmissionRetention(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskSubmissionRetentionEdit) error {
+	return nil
+}
+
+func (s *taskServiceStub) GetTaskStats(tx *gorm.DB, currentUserId int64, taskId int64, termId *int64, allTime bool) (*schemas.TaskStats, error) {
+	return new(schemas.TaskStats), nil
+}
+
+func (s *taskServiceStub) CreateTaskPool(tx *gorm.DB, currentUser schemas.User, pool schemas.TaskPoolCreate) (*schemas.TaskPool, error) {
+	return new(schemas.TaskPool), nil
+}
+
+func (s *taskServiceStub) GetTaskPool(tx *gorm.DB, currentUser schemas.User, poolId int64) (*schemas.TaskPool, error) {
+	return new(schemas.TaskPool), nil
+}
+
+func (s *taskServiceStub) DeleteTaskPool(tx *gorm.DB, currentUser schemas.User, poolId int64) error {
+	return nil
+}
+
+type queueServiceStub struct{}
+
+func (s *queueServiceStub) PublishSubmission(tx *gorm.DB, submissionId int64) error {
+	return nil
+}
+
+func (s *queueServiceStub) GetSubmissionId(tx *gorm.DB, messageId string) (int64, error) {
+	return 0, nil
+}
+
+func (s *queueServiceStub) PublishAudit(tx *gorm.DB, submissionId int64, auditId int64) error {
+	return nil
+}
+
+func (s *queueServiceStub) GetAuditId(tx *gorm.DB, messageId string) (*int64, error) {
+	return new(int64), nil
+}
+
+func (s *queueServiceStub) PublishRejudge(tx *gorm.DB, submissionId int64, batchId int64) error {
+	return nil
+}
+
+func (s *queueServiceStub) GetRejudgeBatchId(tx *gorm.DB, messageId string) (*int64, error) {
+	return new(int64), nil
+}
+
+func (s *queueServiceStub) RetrySubmission(tx *gorm.DB, messageId string, reason string) (bool, error) {
+	return false, nil
+}
+
+func (s *queueServiceStub) GetQueueFailures(tx *gorm.DB, currentUser schemas.User, pendingOnly bool, limit, offset int64) ([]schemas.QueueFailure, error) {
+	return []schemas.QueueFailure{}, nil
+}
+
+func (s *queueServiceStub) RequeueFailure(tx *gorm.DB, currentUser schemas.User, failureId int64) (*schemas.QueueFailure, error) {
+	return &schemas.QueueFailure{}, nil
+}
+
+type submissionServiceStub struct{}
+
+func (s *submissionServiceStub) MarkSubmissionFailed(tx *gorm.DB, submissionId int64, errorMsg string) error {
+	return nil
+}
+
+func (s *submissionServiceStub) MarkSubmissionComplete(tx *gorm.DB, submissionId int64) error {
+	return nil
+}
+
+func (s *submissionServiceStub) MarkSubmissionProcessing(tx *gorm.DB, submissionId int64) error {
+	return nil
+}
+
+func (s *submissionServiceStub) CreateSubmissionResult(tx *gorm.DB, submissionId int64, responseMessage schemas.ResponseMessage) (int64, error) {
+	return 0, nil
+}
+
+func (s *submissionServiceStub) GetAllForTaskAndUser(tx *gorm.DB, taskId int64, userId int64) ([]schemas.Submission, error) {
+	return nil, nil
+}
+
+func (s *submissionServiceStub) GetSubmissionsByEnvironment(tx *gorm.DB, currentUser schemas.User, filter schemas.JudgeEnvironmentFilter, limit int64, offset int64, cursor string) ([]schemas.Submission, string, error) {
+	return nil, "", nil
+}
+
+func (s *submissionServiceStub) ExportUserSubmissions(tx *gorm.DB, taskId int64, userId int64) ([]byte, error) {
+	return nil, nil
+}
+
+func (s *submissionServiceStub) RecomputeScores(tx *gorm.DB, currentUser schemas.User, request schemas.ScoreRecomputeRequest) (*schemas.ScoreRecomputeReport, error) {
+	return new(schemas.ScoreRecomputeReport), nil
+}
+
+func (s *submissionServiceStub) RecomputeScoresBatch(tx *gorm.DB, afterId int64, batchSize int, dryRun bool) (int64, *schemas.ScoreRecomputeReport, error) {
+	return 0, new(schemas.ScoreRecomputeReport), nil
+}
+
+func (s *submissionServiceStub) ReuseIdenticalResult(tx *gorm.DB, submissionId int64) (bool, error) {
+	return false, nil
+}
+
+func (s *submissionServiceStub) RedactSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) error {
+	return nil
+}
+
+func (s *submissionServiceStub) GetSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.Submission, error) {
+	return new(schemas.Submission), nil
+}
+
+func (s *submissionServiceStub) GetSubmissionSource(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.SubmissionSource, error) {
+	return new(schemas.SubmissionSource), nil
+}
+
+func (s *submissionServiceStub) GetSubmissionTimeline(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.SubmissionTimeline, error) {
+	return new(schemas.SubmissionTimeline), nil
+}
+
+func (s *submissionServiceStub) RecordTestProgress(tx *gorm.DB, submissionId int64, responseMessage schemas.ResponseMessage) error {
+	return nil
+}
+
+type languageServiceStub struct{}
+
+func (s *languageServiceStub) GetLanguages(tx *gorm.DB, currentUser schemas.User) ([]schemas.LanguageConfigDetailed, error) {
+	return nil, nil
+}
+
+func (s *languageServiceStub) CreateLanguage(tx *gorm.DB, currentUser schemas.User, language schemas.LanguageConfigCreate) (*schemas.LanguageConfigDetailed, error) {
+	return new(schemas.LanguageConfigDetailed), nil
+}
+
+func (s *languageServiceStub) UpdateLanguage(tx *gorm.DB, currentUser schemas.User, languageId int64, update schemas.LanguageConfigUpdate) (*schemas.LanguageConfigDetailed, error) {
+	return new(schemas.LanguageConfigDetailed), nil
+}
+
+func (s *languageServiceStub) ValidateSourceFile(tx *gorm.DB, languageId int64, fileName string) error {
+	return nil
+}
+
+type sessionServiceStub struct{}
+
+func (s *sessionServiceStub) CreateSession(tx *gorm.DB, userId int64) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+func (s *sessionServiceStub) CreateImpersonationSession(tx *gorm.DB, userId int64, impersonatorId int64) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+func (s *sessionServiceStub) ValidateSession(tx *gorm.DB, sessionId string) (schemas.ValidateSessionResponse, error) {
+	return schemas.ValidateSessionResponse{}, nil
+}
+
+func (s *sessionServiceStub) InvalidateSession(tx *gorm.DB, sessionId string) error {
+	return nil
+}
+
+func (s *sessionServiceStub) InvalidateUserSession(tx *gorm.DB, userId int64) error {
+	return nil
+}
+
+func (s *sessionServiceStub) RefreshSession(tx *gorm.DB, sessionId string) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+type userServiceStub struct{}
+
+func (s *userServiceStub) GetUserByEmail(tx *gorm.DB, email string) (*schemas.User, error) {
+	return new(schemas.User), nil
+}
+
+func (s *userServiceStub) GetAllUsers(tx *gorm.DB, limit, offset int64) ([]schemas.User, error) {
+	return nil, nil
+}
+
+// GetUserById returns an admin, so session validation passes and handlers are not rejected by role checks
+func (s *userServiceStub) GetUserById(tx *gorm.DB, userId int64) (*schemas.User, error) {
+	return &schemas.User{Id: userId, Role: string(models.UserRoleAdmin)}, nil
+}
+
+func (s *userServiceStub) EditUser(tx *gorm.DB, userId int64, updateInfo *schemas.UserEdit) error {
+	return nil
+}
+
+func (s *userServiceStub) ImportUsers(tx *gorm.DB, currentUser schemas.User, file io.Reader) (*schemas.UserImportReport, error) {
+	return new(schemas.UserImportReport), nil
+}
+
+func (s *userServiceStub) GetCapabilities(tx *gorm.DB, currentUser schemas.User) (*schemas.UserCapabilities, error) {
+	return new(schemas.UserCapabilities), nil
+}
+
+func (s *userServiceStub) SetUserStatus(tx *gorm.DB, currentUser schemas.User, userId int64, edit schemas.UserStatusEdit) (*schemas.User, error) {
+	return new(schemas.User), nil
+}
+
+func (s *userServiceStub) ImpersonateUser(tx *gorm.DB, currentUser schemas.User, userId int64) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+func (s *userServiceStub) GetAuditLog(tx *gorm.DB, currentUser schemas.User, targetUserId *int64, limit, offset int64) ([]schemas.AuditLogEntry, error) {
+	return nil, nil
+}
+
+type authServiceStub struct{}
+
+func (s *authServiceStub) Login(tx *gorm.DB, userLogin schemas.UserLoginRequest) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+func (s *authServiceStub) Register(tx *gorm.DB, userRegister schemas.UserRegisterRequest) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+func (s *authServiceStub) Refresh(tx *gorm.DB, request schemas.RefreshTokenRequest) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+func (s *authServiceStub) Logout(tx *gorm.DB, request schemas.RefreshTokenRequest) error {
+	return nil
+}
+
+func (s *authServiceStub) ForgotPassword(tx *gorm.DB, request schemas.ForgotPasswordRequest) error {
+	return nil
+}
+
+func (s *authServiceStub) ResetPassword(tx *gorm.DB, request schemas.ResetPasswordRequest) error {
+	return nil
+}
+
+func (s *authServiceStub) OAuthLogin(tx *gorm.DB, provider string) (*schemas.OAuthLogin, error) {
+	return new(schemas.OAuthLogin), nil
+}
+
+func (s *authServiceStub) UseOAuthState(tx *gorm.DB, provider string, request schemas.OAuthCallbackRequest) (string, error) {
+	return "", nil
+}
+
+func (s *authServiceStub) OAuthCallback(tx *gorm.DB, provider string, code string, codeVerifier string) (*schemas.Session, error) {
+	return new(schemas.Session), nil
+}
+
+type integrityServiceStub struct{}
+
+func (s *integrityServiceStub) CheckOrphans(tx *gorm.DB, currentUser schemas.User, request schemas.OrphanCleanupRequest) (*schemas.IntegrityReport, error) {
+	return new(schemas.IntegrityReport), nil
+}
+
+type onlineMigrationServiceStub struct{}
+
+func (s *onlineMigrationServiceStub) Register(backfill service.Backfill) {}
+
+func (s *onlineMigrationServiceStub) RegisteredMigrations() []string {
+	return nil
+}
+
+func (s *onlineMigrationServiceStub) BackfillBatch(tx *gorm.DB, name string) (bool, error) {
+	return false, nil
+}
+
+func (s *onlineMigrationServiceStub) RecordBackfillError(tx *gorm.DB, name string, backfillErr error) error {
+	return nil
+}
+
+func (s *onlineMigrationServiceStub) ResumeBackfill(tx *gorm.DB, currentUser schemas.User, name string) (*schemas.OnlineMigration, error) {
+	return new(schemas.OnlineMigration), nil
+}
+
+func (s *onlineMigrationServiceStub) IsCutOver(tx *gorm.DB, name string) (bool, error) {
+	return false, nil
+}
+
+func (s *onlineMigrationServiceStub) GetMigrations(tx *gorm.DB, currentUser schemas.User) ([]schemas.OnlineMigration, error) {
+	return nil, nil
+}
+
+func (s *onlineMigrationServiceStub) SetCutover(tx *gorm.DB, currentUser schemas.User, name string, request schemas.OnlineMigrationCutover) (*schemas.OnlineMigration, error) {
+	return new(schemas.OnlineMigration), nil
+}
+
+type statusServiceStub struct{}
+
+func (s *statusServiceStub) GetStatus(tx *gorm.DB) (*schemas.Status, error) {
+	return new(schemas.Status), nil
+}
+
+func (s *statusServiceStub) CreateIncident(tx *gorm.DB, currentUser schemas.User, incident schemas.IncidentCreate) (*schemas.Incident, error) {
+	return new(schemas.Incident), nil
+}
+
+func (s *statusServiceStub) AddIncidentUpdate(tx *gorm.DB, currentUser schemas.User, incidentId int64, update schemas.IncidentUpdateCreate) (*schemas.Incident, error) {
+	return new(schemas.Incident), nil
+}
+
+func (s *statusServiceStub) SimulateCapacity(tx *gorm.DB, currentUser schemas.User, request schemas.CapacitySimulationRequest) (*schemas.CapacitySimulation, error) {
+	return new(schemas.CapacitySimulation), nil
+}
+
+type trustListServiceStub struct{}
+
+func (s *trustListServiceStub) GetEntries(tx *gorm.DB, currentUser schemas.User) ([]schemas.TrustListEntry, error) {
+	return nil, nil
+}
+
+func (s *trustListServiceStub) CreateEntry(tx *gorm.DB, currentUser schemas.User, entry schemas.TrustListEntryCreate) (*schemas.TrustListEntry, error) {
+	return new(schemas.TrustListEntry), nil
+}
+
+func (s *trustListServiceStub) DeleteEntry(tx *gorm.DB, currentUser schemas.User, entryId int64) error {
+	return nil
+}
+
+func (s *trustListServiceStub) Reload(tx *gorm.DB) error {
+	return nil
+}
+
+func (s *trustListServiceStub) IsTrusted(ip net.IP, apiKey string, userId int64) bool {
+	return false
+}
+
+func (s *trustListServiceStub) HasTrustedUsers() bool {
+	return false
+}
+
+type judgeAuditServiceStub struct{}
+
+func (s *judgeAuditServiceStub) StartAudit(tx *gorm.DB, sampleSize int) (int, error) {
+	return 0, nil
+}
+
+func (s *judgeAuditServiceStub) RecordAuditResult(tx *gorm.DB, auditId int64, responseMessage schemas.ResponseMessage) (*schemas.JudgeAudit, error) {
+	return new(schemas.JudgeAudit), nil
+}
+
+func (s *judgeAuditServiceStub) GetAudits(tx *gorm.DB, currentUser schemas.User, discrepanciesOnly bool, limit, offset int64) ([]schemas.JudgeAudit, error) {
+	return nil, nil
+}
+
+type termServiceStub struct{}
+
+func (s *termServiceStub) CreateTerm(tx *gorm.DB, currentUser schemas.User, term schemas.TermCreate) (*schemas.Term, error) {
+	return new(schemas.Term), nil
+}
+
+func (s *termServiceStub) GetAllTerms(tx *gorm.DB) ([]schemas.Term, error) {
+	return nil, nil
+}
+
+type tagServiceStub struct{}
+
+func (s *tagServiceStub) GetTags(tx *gorm.DB) ([]schemas.Tag, error) {
+	return nil, nil
+}
+
+func (s *tagServiceStub) CreateTag(tx *gorm.DB, currentUser schemas.User, tag schemas.TagCreate) (*schemas.Tag, error) {
+	return new(schemas.Tag), nil
+}
+
+func (s *tagServiceStub) DeleteTag(tx *gorm.DB, currentUser schemas.User, tagId int64) error {
+	return nil
+}
+
+func (s *tagServiceStub) SetTaskTags(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskTagsEdit) ([]string, error) {
+	return nil, nil
+}
+
+type rejudgeServiceStub struct{}
+
+func (s *rejudgeServiceStub) RejudgeTask(tx *gorm.DB, currentUser schemas.User, taskId int64) (*schemas.RejudgeBatch, error) {
+	return new(schemas.RejudgeBatch), nil
+}
+
+func (s *rejudgeServiceStub) RejudgeSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.RejudgeBatch, error) {
+	return new(schemas.RejudgeBatch), nil
+}
+
+func (s *rejudgeServiceStub) GetBatch(tx *gorm.DB, currentUser schemas.User, batchId int64) (*schemas.RejudgeBatch, error) {
+	return new(schemas.RejudgeBatch), nil
+}
+
+func (s *rejudgeServiceStub) RecordJudged(tx *gorm.DB, batchId int64, failed bool) error {
+	return nil
+}
+
+type gradingServiceStub struct{}
+
+func (s *gradingServiceStub) ImportGrades(tx *gorm.DB, currentUser schemas.User, file io.Reader) (*schemas.GradeImportReport, error) {
+	return new(schemas.GradeImportReport), nil
+}
+
+type limitsServiceStub struct{}
+
+func (s *limitsServiceStub) GetLimits(tx *gorm.DB, currentUser schemas.User, trusted bool) (*schemas.Limits, error) {
+	return new(schemas.Limits), nil
+}
+
+type notificationServiceStub struct{}
+
+func (s *notificationServiceStub) Notify(tx *gorm.DB, actor schemas.User, userIds []int64, notificationType models.NotificationType, taskId *int64, message string) error {
+	return nil
+}
+
+func (s *notificationServiceStub) GetNotifications(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.Notification, error) {
+	return nil, nil
+}
+
+func (s *notificationServiceStub) MarkNotificationRead(tx *gorm.DB, currentUser schemas.User, notificationId int64) error {
+	return nil
+}
+
+type plagiarismServiceStub struct{}
+
+func (s *plagiarismServiceStub) CheckTask(tx *gorm.DB, currentUser schemas.User, taskId int64) (*schemas.PlagiarismReport, error) {
+	return new(schemas.PlagiarismReport), nil
+}
+
+func (s *plagiarismServiceStub) GetReport(tx *gorm.DB, currentUser schemas.User, taskId int64, threshold float64) (*schemas.PlagiarismReport, error) {
+	return new(schemas.PlagiarismReport), nil
+}
+
+type statsServiceStub struct{}
+
+func (s *statsServiceStub) GetGroupStats(tx *gorm.DB, currentUser schemas.User, groupId int64, days int) (*schemas.GroupStats, error) {
+	return new(schemas.GroupStats), nil
+}
+
+func (s *statsServiceStub) GetGroupProgress(tx *gorm.DB, currentUser schemas.User, groupId int64) (*schemas.GroupProgress, error) {
+	return new(schemas.GroupProgress), nil
+}
+
+func (s *statsServiceStub) SetTaskStatsVisibility(tx *gorm.DB, currentUser schemas.User, groupId int64, taskId int64, edit schemas.GroupTaskStatsVisibility) error {
+	return nil
+}
+
+type announcementServiceStub struct{}
+
+func (s *announcementServiceStub) CreateAnnouncement(tx *gorm.DB, currentUser schemas.User, announcement schemas.AnnouncementCreate) (*schemas.Announcement, error) {
+	return new(schemas.Announcement), nil
+}
+
+func (s *announcementServiceStub) GetAnnouncements(tx *gorm.DB, currentUser schemas.User, unreadOnly bool, limit, offset int64) ([]schemas.Announcement, error) {
+	return []schemas.Announcement{}, nil
+}
+
+func (s *announcementServiceStub) GetGroupAnnouncements(tx *gorm.DB, currentUser schemas.User, groupId int64, limit, offset int64) ([]schemas.Announcement, error) {
+	return []schemas.Announcement{}, nil
+}
+
+func (s *announcementServiceStub) CountUnreadAnnouncements(tx *gorm.DB, currentUser schemas.User) (*schemas.UnreadAnnouncements, error) {
+	return new(schemas.UnreadAnnouncements), nil
+}
+
+func (s *announcementServiceStub) MarkAnnouncementRead(tx *gorm.DB, currentUser schemas.User, announcementId int64) error {
+	return nil
+}
+
+type activityServiceStub struct{}
+
+func (s *activityServiceStub) GetActivity(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.ActivityEvent, error) {
+	return nil, nil
+}
+
+type uploadScanServiceStub struct{}
+
+func (s *uploadScanServiceStub) ScanTaskArchive(tx *gorm.DB, uploadedBy int64, filename string, file io.ReadSeeker) error {
+	return nil
+}
+
+func (s *uploadScanServiceStub) ScanSubmission(tx *gorm.DB, uploadedBy int64, filename string, file io.ReadSeeker) error {
+	return nil
+}
+
+func (s *uploadScanServiceStub) GetQuarantinedFiles(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.QuarantinedFile, error) {
+	return []schemas.QuarantinedFile{}, nil
+}
+
+type accessControlServiceStub struct{}
+
+func (s *accessControlServiceStub) Can(user schemas.User, resource service.Resource, action service.Action) bool {
+	return true
+}
+
+func (s *accessControlServiceStub) CanAll(user schemas.User, resource service.Resource, action service.Action) bool {
+	return true
+}
+
+func (s *accessControlServiceStub) CanAccess(user schemas.User, resource service.Resource, action service.Action, ownerId int64) bool {
+	return true
+}
+
+func (s *accessControlServiceStub) Covers(user schemas.User, other schemas.User) bool {
+	return false
+}
+
+func (s *accessControlServiceStub) ResolvePermissions(tx *gorm.DB, user *schemas.User) error {
+	return nil
+}
+
+func (s *accessControlServiceStub) GetRoles(tx *gorm.DB, currentUser schemas.User) ([]schemas.Role, error) {
+	return []schemas.Role{}, nil
+}
+
+func (s *accessControlServiceStub) CreateRole(tx *gorm.DB, currentUser schemas.User, role schemas.RoleCreate) (*schemas.Role, error) {
+	return &schemas.Role{}, nil
+}
+
+func (s *accessControlServiceStub) UpdateRole(tx *gorm.DB, currentUser schemas.User, roleId int64, update schemas.RoleUpdate) (*schemas.Role, error) {
+	return &schemas.Role{}, nil
+}
+
+func (s *accessControlServiceStub) DeleteRole(tx *gorm.DB, currentUser schemas.User, roleId int64) error {
+	return nil
+}
+
+func (s *accessControlServiceStub) AssignRole(tx *gorm.DB, currentUser schemas.User, userId int64, edit schemas.UserRoleEdit) (*schemas.User, error) {
+	return &schemas.User{}, nil
+}
+
+type taskExportServiceStub struct{}
+
+func (s *taskExportServiceStub) ExportTask(tx *gorm.DB, currentUser schemas.User, taskId int64) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (s *taskExportServiceStub) ImportTask(tx *gorm.DB, currentUser schemas.User, archive io.ReaderAt, size int64) (*schemas.TaskCreateResponse, error) {
+	return new(schemas.TaskCreateResponse), nil
+}
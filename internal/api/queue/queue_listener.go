@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/mini-maxit/backend/internal/broker"
 	"github.com/mini-maxit/backend/internal/database"
 	"github.com/mini-maxit/backend/internal/logger"
 	"github.com/mini-maxit/backend/package/domain/schemas"
 	"github.com/mini-maxit/backend/package/service"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -23,6 +25,8 @@ const (
 	Success = iota + 1
 	Failed
 	InternalError
+	// InProgress messages report outcomes of tests finished so far, the final message follows
+	InProgress
 )
 
 type QueueListenerImpl struct {
@@ -31,25 +35,29 @@ type QueueListenerImpl struct {
 	taskService       service.TaskService
 	queueService      service.QueueService
 	submissionService service.SubmissionService
-	// RabbitMQ connection and channel
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	judgeAuditService service.JudgeAuditService
+	rejudgeService    service.RejudgeService
+	// RabbitMQ connection, reconnected when the broker restarts
+	connection *broker.Connection
 	// Queue name
 	queueName string
 	// Logger
 	logger *zap.SugaredLogger
 }
 
-func NewQueueListener(conn *amqp.Connection, channel *amqp.Channel, taskService service.TaskService, queueName string) (*QueueListenerImpl, error) {
-	// Declare the queue
-	_, err := channel.QueueDeclare(
-		queueName, // name of the queue
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
-	)
+func NewQueueListener(connection *broker.Connection, db database.Database, taskService service.TaskService, queueService service.QueueService, submissionService service.SubmissionService, judgeAuditService service.JudgeAuditService, rejudgeService service.RejudgeService, queueName string) (*QueueListenerImpl, error) {
+	// Declare the queue, again after every reconnect
+	err := connection.OnChannel(func(channel *amqp.Channel) error {
+		_, err := channel.QueueDeclare(
+			queueName, // name of the queue
+			true,      // durable
+			false,     // delete when unused
+			false,     // exclusive
+			false,     // no-wait
+			nil,       // arguments
+		)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -57,11 +65,15 @@ func NewQueueListener(conn *amqp.Connection, channel *amqp.Channel, taskService
 	log := logger.NewNamedLogger("queue_listener")
 
 	return &QueueListenerImpl{
-		taskService: taskService,
-		conn:        conn,
-		channel:     channel,
-		queueName:   queueName,
-		logger:      log,
+		database:          db,
+		taskService:       taskService,
+		queueService:      queueService,
+		submissionService: submissionService,
+		judgeAuditService: judgeAuditService,
+		rejudgeService:    rejudgeService,
+		connection:        connection,
+		queueName:         queueName,
+		logger:            log,
 	}, nil
 }
 
@@ -76,36 +88,48 @@ func (ql *QueueListenerImpl) Start() (context.CancelFunc, error) {
 }
 
 func (ql *QueueListenerImpl) listen(ctx context.Context) error {
-	// Start consuming messages from the queue
-	msgs, err := ql.channel.Consume(
-		ql.queueName, // queue name
-		"",           // consumer
-		true,         // auto-ack
-		false,        // exclusive
-		false,        // no-local
-		false,        // no-wait
-		nil,          // args
-	)
-	if err != nil {
-		return err
-	}
+	// The consumer is registered again on the new channel after every reconnect
+	return ql.connection.OnChannel(func(channel *amqp.Channel) error {
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Start consuming messages from the queue
+		msgs, err := channel.Consume(
+			ql.queueName, // queue name
+			"",           // consumer
+			true,         // auto-ack
+			false,        // exclusive
+			false,        // no-local
+			false,        // no-wait
+			nil,          // args
+		)
+		if err != nil {
+			return err
+		}
+
+		// Process messages in a goroutine
+		go ql.consume(ctx, msgs)
+		return nil
+	})
+}
 
-	// Process messages in a goroutine
-	go func() {
-		ql.logger.Info("Starting the message listener...")
-		for {
-			select {
-			case <-ctx.Done():
-				ql.logger.Info("Stopping the message listener...")
+// consume processes messages until the context is canceled or the channel is closed
+func (ql *QueueListenerImpl) consume(ctx context.Context, msgs <-chan amqp.Delivery) {
+	ql.logger.Info("Starting the message listener...")
+	for {
+		select {
+		case <-ctx.Done():
+			ql.logger.Info("Stopping the message listener...")
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				ql.logger.Warn("Channel closed, the message listener restarts once the broker is reconnected")
 				return
-			case msg := <-msgs:
-				// Call the processMessage function with each message
-				ql.processMessage(msg)
 			}
+			// Call the processMessage function with each message
+			ql.processMessage(msg)
 		}
-	}()
-
-	return nil
+	}
 }
 
 func (ql *QueueListenerImpl) processMessage(msg amqp.Delivery) {
@@ -126,13 +150,43 @@ func (ql *QueueListenerImpl) processMessage(msg amqp.Delivery) {
 		ql.logger.Errorf("Failed to connect to database: %s", err)
 		return
 	}
+	auditId, err := ql.queueService.GetAuditId(tx, queueMessage.MessageId)
+	if err != nil {
+		ql.logger.Errorf("Failed to get audit id: %s", err.Error())
+		return
+	}
+	if auditId != nil {
+		ql.processAuditMessage(tx, *auditId, queueMessage)
+		return
+	}
 	submissionId, err := ql.queueService.GetSubmissionId(tx, queueMessage.MessageId)
 	if err != nil {
 		ql.logger.Errorf("Failed to get submission id: %s", err.Error())
 		return
 	}
+	if queueMessage.Result.StatusCode == InProgress {
+		err = ql.submissionService.RecordTestProgress(tx, submissionId, queueMessage)
+		if err != nil {
+			tx.Rollback()
+			ql.logger.Errorf("Failed to record test progress: %s", err.Error())
+		}
+		return
+	}
+	rejudgeBatchId, err := ql.queueService.GetRejudgeBatchId(tx, queueMessage.MessageId)
+	if err != nil {
+		ql.logger.Errorf("Failed to get rejudge batch id: %s", err.Error())
+		return
+	}
 	if queueMessage.Result.StatusCode == InternalError {
+		retried, err := ql.queueService.RetrySubmission(tx, queueMessage.MessageId, queueMessage.Result.Message)
+		if err != nil {
+			ql.logger.Errorf("Failed to retry submission %d: %s", submissionId, err.Error())
+		}
+		if retried {
+			return
+		}
 		ql.submissionService.MarkSubmissionFailed(tx, submissionId, queueMessage.Result.Message)
+		ql.recordRejudged(tx, rejudgeBatchId, true)
 		return
 	}
 
@@ -148,5 +202,35 @@ func (ql *QueueListenerImpl) processMessage(msg amqp.Delivery) {
 		ql.logger.Errorf("Failed to create user solution result: %s", err.Error())
 		return
 	}
+	ql.recordRejudged(tx, rejudgeBatchId, false)
 	ql.logger.Infof("Succesfuly processed message: %s", queueMessage.MessageId)
 }
+
+// recordRejudged counts the judged submission in its rejudge batch, if the message was published for one
+func (ql *QueueListenerImpl) recordRejudged(tx *gorm.DB, rejudgeBatchId *int64, failed bool) {
+	if rejudgeBatchId == nil {
+		return
+	}
+	err := ql.rejudgeService.RecordJudged(tx, *rejudgeBatchId, failed)
+	if err != nil {
+		ql.logger.Errorf("Failed to record rejudge progress of batch %d: %s", *rejudgeBatchId, err.Error())
+	}
+}
+
+// processAuditMessage records the result of a judge audit re-run. The judged submission is left as it is
+func (ql *QueueListenerImpl) processAuditMessage(tx *gorm.DB, auditId int64, queueMessage schemas.ResponseMessage) {
+	if queueMessage.Result.StatusCode == InProgress {
+		return
+	}
+	if queueMessage.Result.StatusCode == InternalError {
+		ql.logger.Warnf("Judge audit %d could not be judged: %s", auditId, queueMessage.Result.Message)
+		return
+	}
+	_, err := ql.judgeAuditService.RecordAuditResult(tx, auditId, queueMessage)
+	if err != nil {
+		tx.Rollback()
+		ql.logger.Errorf("Failed to record judge audit result: %s", err.Error())
+		return
+	}
+	ql.logger.Infof("Successfully processed audit message: %s", queueMessage.MessageId)
+}
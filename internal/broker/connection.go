@@ -0,0 +1,214 @@
+package broker
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// ReconnectMinBackoff and ReconnectMaxBackoff bound the delay between connection attempts. The delay
+	// doubles with every failed attempt and is jittered, so replicas do not reconnect all at once
+	ReconnectMinBackoff = time.Second
+	ReconnectMaxBackoff = time.Minute
+	// DialAttempts is how many times the first connection is attempted before giving up
+	DialAttempts = 5
+)
+
+var ErrNotConnected = errors.New("not connected to the broker")
+
+type State string
+
+const (
+	StateConnected    State = "connected"
+	StateReconnecting State = "reconnecting"
+	StateClosed       State = "closed"
+)
+
+// ChannelSetup declares what its user needs on a channel, such as queues and consumers
+type ChannelSetup func(channel *amqp.Channel) error
+
+// Connection is a connection to RabbitMQ with a single channel. When the broker closes the connection or
+// the channel, for example on a restart, both are opened again and the setups are run on the new channel
+type Connection struct {
+	url            string
+	mu             sync.RWMutex
+	conn           *amqp.Connection
+	channel        *amqp.Channel
+	state          State
+	failedAttempts int
+	setups         []ChannelSetup
+	done           chan struct{}
+	closeOnce      sync.Once
+	logger         *zap.SugaredLogger
+}
+
+// Dial connects to the broker, retrying DialAttempts times, and keeps the connection open until Close is called
+func Dial(url string) (*Connection, error) {
+	c := &Connection{
+		url:    url,
+		state:  StateReconnecting,
+		done:   make(chan struct{}),
+		logger: logger.NewNamedLogger("broker"),
+	}
+	var err error
+	for attempt := range DialAttempts {
+		err = c.connect()
+		if err == nil {
+			break
+		}
+		c.logger.Warnf("Failed to connect to RabbitMQ: %s", err.Error())
+		time.Sleep(backoff(attempt))
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Info("Connected to RabbitMQ")
+	go c.watch()
+	return c, nil
+}
+
+// Channel returns the current channel. It must not be kept, it is replaced on reconnect
+func (c *Connection) Channel() (*amqp.Channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state != StateConnected {
+		return nil, ErrNotConnected
+	}
+	return c.channel, nil
+}
+
+// State returns the state of the connection and the number of failed attempts of the ongoing reconnect
+func (c *Connection) State() (State, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state, c.failedAttempts
+}
+
+// OnChannel runs the setup on the current channel and again on every channel opened after a reconnect.
+// Setups run in the order they were added
+func (c *Connection) OnChannel(setup ChannelSetup) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setups = append(c.setups, setup)
+	if c.state != StateConnected {
+		// The setup runs once the connection is back
+		return nil
+	}
+	return setup(c.channel)
+}
+
+// Close closes the connection, it is not reconnected afterwards
+func (c *Connection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.state = StateClosed
+		if c.conn != nil {
+			err = c.conn.Close()
+		}
+	})
+	return err
+}
+
+// connect dials the broker, opens the channel and runs the setups on it
+func (c *Connection) connect() error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return err
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == StateClosed {
+		conn.Close()
+		return ErrNotConnected
+	}
+	for _, setup := range c.setups {
+		err = setup(channel)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	c.conn = conn
+	c.channel = channel
+	c.state = StateConnected
+	c.failedAttempts = 0
+	return nil
+}
+
+// watch reconnects whenever the connection or the channel is closed by the broker
+func (c *Connection) watch() {
+	for {
+		c.mu.RLock()
+		conn := c.conn
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		channelClosed := c.channel.NotifyClose(make(chan *amqp.Error, 1))
+		c.mu.RUnlock()
+
+		var reason *amqp.Error
+		select {
+		case <-c.done:
+			return
+		case reason = <-connClosed:
+		case reason = <-channelClosed:
+		}
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		c.logger.Warnf("Connection to RabbitMQ lost: %v", reason)
+
+		c.mu.Lock()
+		c.state = StateReconnecting
+		c.mu.Unlock()
+		// The connection is still open when only the channel was closed
+		conn.Close()
+		if !c.reconnect() {
+			return
+		}
+	}
+}
+
+// reconnect connects again until it succeeds or the connection is closed. Returns false when it was closed
+func (c *Connection) reconnect() bool {
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(backoff(attempt)):
+		}
+		err := c.connect()
+		if err == nil {
+			c.logger.Infof("Reconnected to RabbitMQ after %d attempts", attempt+1)
+			return true
+		}
+		c.mu.Lock()
+		c.failedAttempts = attempt + 1
+		c.mu.Unlock()
+		c.logger.Warnf("Failed to reconnect to RabbitMQ: %s", err.Error())
+	}
+}
+
+// backoff returns the delay before the attempt, chosen at random from the upper half of the exponential delay
+func backoff(attempt int) time.Duration {
+	delay := ReconnectMaxBackoff
+	if attempt < 16 {
+		delay = min(ReconnectMinBackoff<<attempt, ReconnectMaxBackoff)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisClient connects to the Redis instance from the configuration and verifies the connection
+func NewRedisClient(cfg *config.Config) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
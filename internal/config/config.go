@@ -1,11 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mini-maxit/backend/internal/logger"
-	"go.uber.org/zap"
 	//"github.com/mini-maxit/backend/internal/config"
 )
 
@@ -16,6 +19,17 @@ type Config struct {
 	DB             DBConfig
 	App            AppConfig
 	BrokerConfig   BrokerConfig
+	Redis          RedisConfig
+	Sandbox        SandboxConfig
+	Analytics      AnalyticsConfig
+	JudgeAudit     JudgeAuditConfig
+	Mail           MailConfig
+	Pagination     PaginationConfig
+	Archive        ArchiveConfig
+	Evaluation     EvaluationConfig
+	OAuth          OAuthConfig
+	LDAP           LDAPConfig
+	Scan           ScanConfig
 }
 
 type DBConfig struct {
@@ -28,6 +42,18 @@ type DBConfig struct {
 
 type AppConfig struct {
 	Port uint16
+	// Maximum size in bytes of a request body of routes other than uploads
+	MaxJSONBodySize int64
+	// Maximum size in bytes of a request body of upload routes, such as task archives and imports
+	MaxMultipartBodySize int64
+	// Maximum number of requests served at once before shedding load. 0 disables load shedding
+	MaxInFlightRequests int64
+	// Complete submissions whose source is identical to an already checked submission of the same
+	// task and language with a copy of its result instead of evaluating them again
+	ReuseIdenticalSubmissions bool
+	// Count rows of the largest tables, such as submissions, from the planner statistics instead of with COUNT(*).
+	// Estimates are fast but lag behind recent writes
+	EstimatedCounts bool
 }
 
 type BrokerConfig struct {
@@ -35,6 +61,14 @@ type BrokerConfig struct {
 	QueueName string
 	// Queue name for receiving responses
 	ResponseQueueName string
+	// Queue for submissions of tasks with a test memory limit of at least HighMemoryThreshold, in the
+	// unit of memory limits sent to the worker. Empty when such tasks use the queues of their language
+	HighMemoryQueueName string
+	HighMemoryThreshold int64
+	// Submissions the worker fails to evaluate are published again until MaxAttempts evaluations failed.
+	// The delay before a retry starts at RetryBackoff and doubles with every attempt
+	MaxAttempts  int64
+	RetryBackoff time.Duration
 	// RabbitMQ host
 	Host string
 	// RabbitMQ port
@@ -45,27 +79,239 @@ type BrokerConfig struct {
 	Password string
 }
 
+// RedisConfig configures the optional Redis instance used to share state between replicas.
+// Sessions and the sandbox rate limit counters are kept in Redis. When Redis is disabled sessions are stored
+// in the database and the rate limit applies per replica. Revoked refresh and password reset tokens are
+// always stored in the database, which replicas share.
+type RedisConfig struct {
+	Enabled  bool
+	Host     string
+	Port     uint16
+	Password string
+	DB       int
+}
+
+// SandboxConfig configures the public practice mode. Sandbox tasks can be read without an account,
+// so the sandbox is disabled by default and its routes are rate limited per client IP.
+type SandboxConfig struct {
+	Enabled bool
+	// Maximum number of sandbox requests per minute from a single IP
+	RateLimit int
+}
+
+// AnalyticsConfig configures the nightly learning analytics export. The export is disabled
+// unless ExportDir is set. User ids are replaced with pseudonyms keyed by Salt.
+type AnalyticsConfig struct {
+	Enabled   bool
+	ExportDir string
+	Salt      string
+}
+
+// JudgeAuditConfig configures the periodic re-run of a random sample of judged submissions, whose
+// verdicts are compared with the recorded ones. The audit adds load on the judge, so it is disabled
+// unless SampleSize is positive.
+type JudgeAuditConfig struct {
+	// Number of submissions re-run every audit
+	SampleSize int
+}
+
+// MailConfig configures the SMTP server mails are sent with. Password reset needs mail, so it is
+// disabled unless Host is set. Reset links point to PasswordResetUrl with the token appended as a query parameter.
+type MailConfig struct {
+	Enabled          bool
+	Host             string
+	Port             uint16
+	Username         string
+	Password         string
+	From             string
+	PasswordResetUrl string
+}
+
+// OAuthConfig configures login with OpenID Connect providers, such as Google or the SSO of a university.
+// Providers are named by OAUTH_PROVIDERS and each of them is configured with OAUTH_<NAME>_* variables.
+type OAuthConfig struct {
+	Providers map[string]OAuthProviderConfig
+}
+
+type OAuthProviderConfig struct {
+	// Issuer is the base url of the provider, its endpoints are read from the discovery document of the issuer
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// RedirectUrl is registered at the provider. It is the callback endpoint, or a frontend page passing the
+	// code and state query parameters on to it
+	RedirectUrl string
+	// RoleRules give roles to accounts created on the first login. The first rule matching the email applies,
+	// accounts matching none are students
+	RoleRules []OAuthRoleRule
+}
+
+// OAuthRoleRule gives Role to emails matching EmailPattern, a path.Match pattern such as *@staff.example.edu
+type OAuthRoleRule struct {
+	Role         string
+	EmailPattern string
+}
+
+// LDAPConfig configures login against an LDAP or Active Directory server, disabled unless Url is set. Users are
+// searched by email with the service account and logged in by binding as them. Emails not found in the directory
+// log in with their local password, so local accounts such as the first admin keep working
+type LDAPConfig struct {
+	Enabled bool
+	// Url of the server, ldap:// or ldaps://
+	Url string
+	// StartTLS upgrades ldap:// connections to TLS before binding
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// Attributes of user entries. Defaults fit OpenLDAP, Active Directory uses sAMAccountName as the username
+	EmailAttribute    string
+	UsernameAttribute string
+	NameAttribute     string
+	SurnameAttribute  string
+	GroupAttribute    string
+	// Members of any of the groups, by DN, are admins or teachers, everyone else is a student.
+	// The role is updated on every login
+	AdminGroups   []string
+	TeacherGroups []string
+}
+
+// ScanConfig configures scanning of uploaded task archives and solutions before they are stored, disabled unless
+// ClamAVAddress or HttpUrl is set. Infected uploads are rejected and copied to QuarantineDir for review. Uploads
+// are rejected while the scanner is unavailable, so nothing unscanned is stored
+type ScanConfig struct {
+	Enabled bool
+	// ClamAVAddress is the host:port of a clamd daemon, files are sent with its INSTREAM command
+	ClamAVAddress string
+	// HttpUrl is an external scanner the file is posted to, which answers {"infected": bool, "signature": string}
+	HttpUrl       string
+	Timeout       time.Duration
+	QuarantineDir string
+}
+
+// ArchiveConfig configures moving sources of old submissions to the cheaper archive storage class of
+// FileStorage. Archived sources are restored when they are accessed, which is slow, so archiving is
+// disabled unless After is set.
+type ArchiveConfig struct {
+	Enabled bool
+	// Age of judged submissions after which their sources are archived
+	After time.Duration
+	// PruneSuperseded is the retention policy of tasks following the platform default. When set, sources of
+	// failing attempts superseded by a later submission are pruned, keeping the best and the latest
+	// submission of every user. Tasks can opt in or out regardless
+	PruneSuperseded bool
+	// Age of superseded attempts after which their sources are pruned
+	PruneAfter time.Duration
+}
+
+// PaginationConfig configures page sizes of list endpoints. Each class has a default used when
+// the client does not send a limit, and a maximum above which requests are rejected.
+type PaginationConfig struct {
+	// Tasks, sandbox tasks and users
+	List PaginationLimits
+	// Submissions and activity
+	Submission PaginationLimits
+	// Admin listings such as judge audits
+	Admin PaginationLimits
+}
+
+type PaginationLimits struct {
+	DefaultLimit int64
+	MaxLimit     int64
+}
+
+// EvaluationConfig bounds the evaluation policy task authors can set, so tasks cannot ask for more than
+// the workers are provisioned for. Tests of tasks without a policy are evaluated with the defaults.
+type EvaluationConfig struct {
+	Defaults EvaluationLimits
+	Max      EvaluationLimits
+}
+
+type EvaluationLimits struct {
+	// Kilobytes of standard output kept per test
+	OutputLimit int64
+	// Kilobytes of standard error captured per test
+	StderrLimit int64
+	// Number of processes a solution can run at once
+	ProcessLimit int64
+}
+
 const (
 	DEFAULT_PORT                = "8080"
 	DEFAULT_QUEUE_NAME          = "worker_queue"
 	DEFAULT_RESPONSE_QUEUE_NAME = "worker_response_queue"
+	DEFAULT_QUEUE_MAX_ATTEMPTS  = 3
+	DEFAULT_QUEUE_RETRY_BACKOFF = 30 // seconds
+	DEFAULT_REDIS_PORT          = "6379"
+	DEFAULT_MAX_JSON_BODY_SIZE  = 1 << 20  // 1 MB
+	DEFAULT_MAX_MULTIPART_SIZE  = 50 << 20 // 50 MB
+	DEFAULT_MAX_IN_FLIGHT       = 256
+	DEFAULT_SANDBOX_RATE_LIMIT  = 20
+	DEFAULT_SMTP_PORT           = "587"
+	DEFAULT_PAGE_SIZE           = 10
+	DEFAULT_MAX_PAGE_SIZE       = 100
+	DEFAULT_ADMIN_PAGE_SIZE     = 50
+	DEFAULT_ADMIN_MAX_PAGE_SIZE = 500
+	DEFAULT_OUTPUT_LIMIT        = 1 << 10 // 1 MB
+	DEFAULT_MAX_OUTPUT_LIMIT    = 64 << 10
+	DEFAULT_STDERR_LIMIT        = 64
+	DEFAULT_MAX_STDERR_LIMIT    = 1 << 10
+	DEFAULT_PROCESS_LIMIT       = 1
+	DEFAULT_MAX_PROCESS_LIMIT   = 64
+	DEFAULT_SCAN_TIMEOUT        = 30 // seconds
+	DEFAULT_PRUNE_AFTER_DAYS    = 30
+
+	DEFAULT_LDAP_EMAIL_ATTRIBUTE    = "mail"
+	DEFAULT_LDAP_USERNAME_ATTRIBUTE = "uid"
+	DEFAULT_LDAP_NAME_ATTRIBUTE     = "givenName"
+	DEFAULT_LDAP_SURNAME_ATTRIBUTE  = "sn"
+	DEFAULT_LDAP_GROUP_ATTRIBUTE    = "memberOf"
 )
 
+// ConfigError lists every missing or invalid variable found while reading the configuration
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration, %d problems:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// configProblems collects problems of the configuration, so all of them are reported at once instead of
+// stopping at the first
+type configProblems []string
+
+func (p *configProblems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// NewConfig reads the configuration from the environment and panics with the report of LoadConfig when it is invalid
 func NewConfig() *Config {
+	cfg, err := LoadConfig()
+	if err != nil {
+		logger.NewNamedLogger("config").Panic(err.Error())
+	}
+	return cfg
+}
+
+// LoadConfig reads the configuration from the environment. Every variable is checked, and all problems
+// are returned together in a *ConfigError
+func LoadConfig() (*Config, error) {
 	log := logger.NewNamedLogger("config")
+	var problems configProblems
 
 	dbHost := os.Getenv("DB_HOST")
 	if dbHost == "" {
-		log.Panic("DB_HOST is not set")
+		problems.add("DB_HOST is not set")
 	}
 	dbPortStr := os.Getenv("DB_PORT")
 	if dbPortStr == "" {
-		log.Panic("DB_PORT is not set")
+		problems.add("DB_PORT is not set")
 	}
-	dbPort := validatePort(dbPortStr, "database", log)
+	dbPort := validatePort(dbPortStr, "database", &problems)
 	dbUser := os.Getenv("DB_USER")
 	if dbUser == "" {
-		log.Panic("DB_USER is not set")
+		problems.add("DB_USER is not set")
 	}
 	dbPassword := os.Getenv("DB_PASSWORD")
 	if dbPassword == "" {
@@ -73,7 +319,7 @@ func NewConfig() *Config {
 	}
 	dbName := os.Getenv("DB_NAME")
 	if dbName == "" {
-		log.Panic("DB_NAME is not set")
+		problems.add("DB_NAME is not set")
 	}
 
 	appPortStr := os.Getenv("APP_PORT")
@@ -81,17 +327,48 @@ func NewConfig() *Config {
 		log.Warnf("APP_PORT is not set. Using default port %s", DEFAULT_PORT)
 		appPortStr = DEFAULT_PORT
 	}
-	appPort := validatePort(appPortStr, "application", log)
+	appPort := validatePort(appPortStr, "application", &problems)
+	maxJSONBodySize := parseSize(os.Getenv("MAX_JSON_BODY_SIZE"), DEFAULT_MAX_JSON_BODY_SIZE, "MAX_JSON_BODY_SIZE", &problems)
+	maxMultipartBodySize := parseSize(os.Getenv("MAX_MULTIPART_BODY_SIZE"), DEFAULT_MAX_MULTIPART_SIZE, "MAX_MULTIPART_BODY_SIZE", &problems)
+	maxInFlightRequests := int64(DEFAULT_MAX_IN_FLIGHT)
+	maxInFlightStr := os.Getenv("MAX_IN_FLIGHT_REQUESTS")
+	if maxInFlightStr != "" {
+		var err error
+		maxInFlightRequests, err = strconv.ParseInt(maxInFlightStr, 10, 64)
+		if err != nil || maxInFlightRequests < 0 {
+			problems.add("invalid MAX_IN_FLIGHT_REQUESTS %s", maxInFlightStr)
+		}
+	}
+
+	reuseIdenticalSubmissions := false
+	reuseIdenticalStr := os.Getenv("REUSE_IDENTICAL_SUBMISSIONS")
+	if reuseIdenticalStr != "" {
+		var err error
+		reuseIdenticalSubmissions, err = strconv.ParseBool(reuseIdenticalStr)
+		if err != nil {
+			problems.add("invalid REUSE_IDENTICAL_SUBMISSIONS %s", reuseIdenticalStr)
+		}
+	}
+
+	estimatedCounts := false
+	estimatedCountsStr := os.Getenv("ESTIMATED_COUNTS")
+	if estimatedCountsStr != "" {
+		var err error
+		estimatedCounts, err = strconv.ParseBool(estimatedCountsStr)
+		if err != nil {
+			problems.add("invalid ESTIMATED_COUNTS %s", estimatedCountsStr)
+		}
+	}
 
 	fileStorageHost := os.Getenv("FILE_STORAGE_HOST")
 	if fileStorageHost == "" {
-		log.Panic("FILE_STORAGE_HOST is not set")
+		problems.add("FILE_STORAGE_HOST is not set")
 	}
 	fileStoragePortStr := os.Getenv("FILE_STORAGE_PORT")
 	if fileStoragePortStr == "" {
-		log.Panic("FILE_STORAGE_PORT is not set")
+		problems.add("FILE_STORAGE_PORT is not set")
 	}
-	_ = validatePort(fileStoragePortStr, "file storage", log)
+	_ = validatePort(fileStoragePortStr, "file storage", &problems)
 
 	fileStorageUrl := "http://" + fileStorageHost + ":" + fileStoragePortStr
 
@@ -105,23 +382,268 @@ func NewConfig() *Config {
 		log.Warnf("RESPONSE_QUEUE_NAME is not set. Using default response queue name %s", DEFAULT_RESPONSE_QUEUE_NAME)
 		responseQueueName = DEFAULT_RESPONSE_QUEUE_NAME
 	}
+	highMemoryQueueName := os.Getenv("HIGH_MEMORY_QUEUE_NAME")
+	highMemoryThreshold := int64(0)
+	if highMemoryQueueName != "" {
+		highMemoryThreshold = parseSize(os.Getenv("HIGH_MEMORY_THRESHOLD"), 0, "HIGH_MEMORY_THRESHOLD", &problems)
+		if os.Getenv("HIGH_MEMORY_THRESHOLD") == "" {
+			problems.add("HIGH_MEMORY_THRESHOLD is required with HIGH_MEMORY_QUEUE_NAME")
+		}
+	}
+	queueMaxAttempts := parseSize(os.Getenv("QUEUE_MAX_ATTEMPTS"), DEFAULT_QUEUE_MAX_ATTEMPTS, "QUEUE_MAX_ATTEMPTS", &problems)
+	queueRetryBackoff := parseSize(os.Getenv("QUEUE_RETRY_BACKOFF_SECONDS"), DEFAULT_QUEUE_RETRY_BACKOFF, "QUEUE_RETRY_BACKOFF_SECONDS", &problems)
 	queueHost := os.Getenv("QUEUE_HOST")
 	if queueHost == "" {
-		log.Panic("QUEUE_HOST is not set")
+		problems.add("QUEUE_HOST is not set")
 	}
 	queuePortStr := os.Getenv("QUEUE_PORT")
 	if queuePortStr == "" {
-		log.Panic("QUEUE_PORT is not set")
+		problems.add("QUEUE_PORT is not set")
 	}
-	queuePort := validatePort(queuePortStr, "broker", log)
+	queuePort := validatePort(queuePortStr, "broker", &problems)
 
 	queueUser := os.Getenv("QUEUE_USER")
 	if queueUser == "" {
-		log.Panic("QUEUE_USER is not set")
+		problems.add("QUEUE_USER is not set")
 	}
 	queuePassword := os.Getenv("QUEUE_PASSWORD")
 	if queuePassword == "" {
-		log.Panic("QUEUE_PASSWORD is not set")
+		problems.add("QUEUE_PASSWORD is not set")
+	}
+
+	redisConfig := RedisConfig{}
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost != "" {
+		redisPortStr := os.Getenv("REDIS_PORT")
+		if redisPortStr == "" {
+			log.Warnf("REDIS_PORT is not set. Using default port %s", DEFAULT_REDIS_PORT)
+			redisPortStr = DEFAULT_REDIS_PORT
+		}
+		redisDB := 0
+		redisDBStr := os.Getenv("REDIS_DB")
+		if redisDBStr != "" {
+			var err error
+			redisDB, err = strconv.Atoi(redisDBStr)
+			if err != nil {
+				problems.add("invalid REDIS_DB %s", redisDBStr)
+			}
+		}
+		redisConfig = RedisConfig{
+			Enabled:  true,
+			Host:     redisHost,
+			Port:     validatePort(redisPortStr, "redis", &problems),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       redisDB,
+		}
+	} else {
+		log.Infof("REDIS_HOST is not set. Redis integration is disabled")
+	}
+
+	sandboxConfig := SandboxConfig{}
+	sandboxEnabledStr := os.Getenv("SANDBOX_ENABLED")
+	if sandboxEnabledStr != "" {
+		var err error
+		sandboxConfig.Enabled, err = strconv.ParseBool(sandboxEnabledStr)
+		if err != nil {
+			problems.add("invalid SANDBOX_ENABLED %s", sandboxEnabledStr)
+		}
+	}
+	sandboxConfig.RateLimit = DEFAULT_SANDBOX_RATE_LIMIT
+	sandboxRateLimitStr := os.Getenv("SANDBOX_RATE_LIMIT")
+	if sandboxRateLimitStr != "" {
+		var err error
+		sandboxConfig.RateLimit, err = strconv.Atoi(sandboxRateLimitStr)
+		if err != nil || sandboxConfig.RateLimit <= 0 {
+			problems.add("invalid SANDBOX_RATE_LIMIT %s", sandboxRateLimitStr)
+		}
+	}
+
+	analyticsConfig := AnalyticsConfig{}
+	analyticsExportDir := os.Getenv("ANALYTICS_EXPORT_DIR")
+	if analyticsExportDir != "" {
+		analyticsSalt := os.Getenv("ANALYTICS_SALT")
+		if analyticsSalt == "" {
+			problems.add("ANALYTICS_SALT is not set. It is required when ANALYTICS_EXPORT_DIR is set")
+		}
+		analyticsConfig = AnalyticsConfig{
+			Enabled:   true,
+			ExportDir: analyticsExportDir,
+			Salt:      analyticsSalt,
+		}
+	}
+
+	judgeAuditConfig := JudgeAuditConfig{}
+	judgeAuditSampleSizeStr := os.Getenv("JUDGE_AUDIT_SAMPLE_SIZE")
+	if judgeAuditSampleSizeStr != "" {
+		var err error
+		judgeAuditConfig.SampleSize, err = strconv.Atoi(judgeAuditSampleSizeStr)
+		if err != nil || judgeAuditConfig.SampleSize < 0 {
+			problems.add("invalid JUDGE_AUDIT_SAMPLE_SIZE %s", judgeAuditSampleSizeStr)
+		}
+	}
+
+	mailConfig := MailConfig{}
+	smtpHost := os.Getenv("SMTP_HOST")
+	if smtpHost != "" {
+		smtpPortStr := os.Getenv("SMTP_PORT")
+		if smtpPortStr == "" {
+			log.Warnf("SMTP_PORT is not set. Using default port %s", DEFAULT_SMTP_PORT)
+			smtpPortStr = DEFAULT_SMTP_PORT
+		}
+		mailFrom := os.Getenv("MAIL_FROM")
+		if mailFrom == "" {
+			problems.add("MAIL_FROM is not set. It is required when SMTP_HOST is set")
+		}
+		passwordResetUrl := os.Getenv("PASSWORD_RESET_URL")
+		if passwordResetUrl == "" {
+			problems.add("PASSWORD_RESET_URL is not set. It is required when SMTP_HOST is set")
+		}
+		mailConfig = MailConfig{
+			Enabled:          true,
+			Host:             smtpHost,
+			Port:             validatePort(smtpPortStr, "smtp", &problems),
+			Username:         os.Getenv("SMTP_USERNAME"),
+			Password:         os.Getenv("SMTP_PASSWORD"),
+			From:             mailFrom,
+			PasswordResetUrl: passwordResetUrl,
+		}
+	} else {
+		log.Infof("SMTP_HOST is not set. Password reset is disabled")
+	}
+
+	archiveConfig := ArchiveConfig{}
+	archiveAfterDaysStr := os.Getenv("ARCHIVE_SUBMISSIONS_AFTER_DAYS")
+	if archiveAfterDaysStr != "" {
+		archiveAfterDays, err := strconv.Atoi(archiveAfterDaysStr)
+		if err != nil || archiveAfterDays <= 0 {
+			problems.add("invalid ARCHIVE_SUBMISSIONS_AFTER_DAYS %s", archiveAfterDaysStr)
+		}
+		archiveConfig = ArchiveConfig{
+			Enabled: true,
+			After:   time.Duration(archiveAfterDays) * 24 * time.Hour,
+		}
+	}
+	archivePruneSupersededStr := os.Getenv("ARCHIVE_PRUNE_SUPERSEDED")
+	if archivePruneSupersededStr != "" {
+		var err error
+		archiveConfig.PruneSuperseded, err = strconv.ParseBool(archivePruneSupersededStr)
+		if err != nil {
+			problems.add("invalid ARCHIVE_PRUNE_SUPERSEDED %s", archivePruneSupersededStr)
+		}
+	}
+	archivePruneAfterDays := DEFAULT_PRUNE_AFTER_DAYS
+	archivePruneAfterDaysStr := os.Getenv("ARCHIVE_PRUNE_AFTER_DAYS")
+	if archivePruneAfterDaysStr != "" {
+		var err error
+		archivePruneAfterDays, err = strconv.Atoi(archivePruneAfterDaysStr)
+		if err != nil || archivePruneAfterDays <= 0 {
+			problems.add("invalid ARCHIVE_PRUNE_AFTER_DAYS %s", archivePruneAfterDaysStr)
+		}
+	}
+	archiveConfig.PruneAfter = time.Duration(archivePruneAfterDays) * 24 * time.Hour
+
+	oauthConfig := OAuthConfig{Providers: map[string]OAuthProviderConfig{}}
+	oauthProvidersStr := os.Getenv("OAUTH_PROVIDERS")
+	if oauthProvidersStr != "" {
+		for _, name := range strings.Split(oauthProvidersStr, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			oauthConfig.Providers[name] = parseOAuthProvider(name, &problems)
+		}
+	}
+
+	ldapConfig := LDAPConfig{}
+	ldapUrl := os.Getenv("LDAP_URL")
+	if ldapUrl != "" {
+		ldapBaseDN := os.Getenv("LDAP_BASE_DN")
+		if ldapBaseDN == "" {
+			problems.add("LDAP_BASE_DN is not set. It is required when LDAP_URL is set")
+		}
+		ldapStartTLS := false
+		ldapStartTLSStr := os.Getenv("LDAP_START_TLS")
+		if ldapStartTLSStr != "" {
+			var err error
+			ldapStartTLS, err = strconv.ParseBool(ldapStartTLSStr)
+			if err != nil {
+				problems.add("invalid LDAP_START_TLS %s", ldapStartTLSStr)
+			}
+		}
+		ldapConfig = LDAPConfig{
+			Enabled:           true,
+			Url:               ldapUrl,
+			StartTLS:          ldapStartTLS,
+			BindDN:            os.Getenv("LDAP_BIND_DN"),
+			BindPassword:      os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:            ldapBaseDN,
+			EmailAttribute:    getEnvOrDefault("LDAP_EMAIL_ATTRIBUTE", DEFAULT_LDAP_EMAIL_ATTRIBUTE),
+			UsernameAttribute: getEnvOrDefault("LDAP_USERNAME_ATTRIBUTE", DEFAULT_LDAP_USERNAME_ATTRIBUTE),
+			NameAttribute:     getEnvOrDefault("LDAP_NAME_ATTRIBUTE", DEFAULT_LDAP_NAME_ATTRIBUTE),
+			SurnameAttribute:  getEnvOrDefault("LDAP_SURNAME_ATTRIBUTE", DEFAULT_LDAP_SURNAME_ATTRIBUTE),
+			GroupAttribute:    getEnvOrDefault("LDAP_GROUP_ATTRIBUTE", DEFAULT_LDAP_GROUP_ATTRIBUTE),
+			// Group DNs contain commas, so the lists are separated by semicolons
+			AdminGroups:   splitNonEmpty(os.Getenv("LDAP_ADMIN_GROUPS"), ";"),
+			TeacherGroups: splitNonEmpty(os.Getenv("LDAP_TEACHER_GROUPS"), ";"),
+		}
+	} else {
+		log.Infof("LDAP_URL is not set. LDAP login is disabled")
+	}
+
+	scanConfig := ScanConfig{}
+	scanClamAVAddress := os.Getenv("SCAN_CLAMAV_ADDRESS")
+	scanHttpUrl := os.Getenv("SCAN_HTTP_URL")
+	if scanClamAVAddress != "" || scanHttpUrl != "" {
+		if scanClamAVAddress != "" && scanHttpUrl != "" {
+			problems.add("SCAN_CLAMAV_ADDRESS and SCAN_HTTP_URL are both set. Only one scanner can be used")
+		}
+		scanQuarantineDir := os.Getenv("SCAN_QUARANTINE_DIR")
+		if scanQuarantineDir == "" {
+			problems.add("SCAN_QUARANTINE_DIR is not set. It is required when a file scanner is set")
+		}
+		scanTimeout := DEFAULT_SCAN_TIMEOUT
+		scanTimeoutStr := os.Getenv("SCAN_TIMEOUT_SECONDS")
+		if scanTimeoutStr != "" {
+			var err error
+			scanTimeout, err = strconv.Atoi(scanTimeoutStr)
+			if err != nil || scanTimeout <= 0 {
+				problems.add("invalid SCAN_TIMEOUT_SECONDS %s", scanTimeoutStr)
+			}
+		}
+		scanConfig = ScanConfig{
+			Enabled:       true,
+			ClamAVAddress: scanClamAVAddress,
+			HttpUrl:       scanHttpUrl,
+			Timeout:       time.Duration(scanTimeout) * time.Second,
+			QuarantineDir: scanQuarantineDir,
+		}
+	} else {
+		log.Infof("SCAN_CLAMAV_ADDRESS and SCAN_HTTP_URL are not set. Uploads are not scanned")
+	}
+
+	paginationConfig := PaginationConfig{
+		List:       parsePaginationLimits("LIST", DEFAULT_PAGE_SIZE, DEFAULT_MAX_PAGE_SIZE, &problems),
+		Submission: parsePaginationLimits("SUBMISSION", DEFAULT_PAGE_SIZE, DEFAULT_MAX_PAGE_SIZE, &problems),
+		Admin:      parsePaginationLimits("ADMIN", DEFAULT_ADMIN_PAGE_SIZE, DEFAULT_ADMIN_MAX_PAGE_SIZE, &problems),
+	}
+
+	evaluationConfig := EvaluationConfig{
+		Defaults: EvaluationLimits{
+			OutputLimit:  parseSize(os.Getenv("DEFAULT_OUTPUT_LIMIT_KB"), DEFAULT_OUTPUT_LIMIT, "DEFAULT_OUTPUT_LIMIT_KB", &problems),
+			StderrLimit:  parseSize(os.Getenv("DEFAULT_STDERR_LIMIT_KB"), DEFAULT_STDERR_LIMIT, "DEFAULT_STDERR_LIMIT_KB", &problems),
+			ProcessLimit: parseSize(os.Getenv("DEFAULT_PROCESS_LIMIT"), DEFAULT_PROCESS_LIMIT, "DEFAULT_PROCESS_LIMIT", &problems),
+		},
+		Max: EvaluationLimits{
+			OutputLimit:  parseSize(os.Getenv("MAX_OUTPUT_LIMIT_KB"), DEFAULT_MAX_OUTPUT_LIMIT, "MAX_OUTPUT_LIMIT_KB", &problems),
+			StderrLimit:  parseSize(os.Getenv("MAX_STDERR_LIMIT_KB"), DEFAULT_MAX_STDERR_LIMIT, "MAX_STDERR_LIMIT_KB", &problems),
+			ProcessLimit: parseSize(os.Getenv("MAX_PROCESS_LIMIT"), DEFAULT_MAX_PROCESS_LIMIT, "MAX_PROCESS_LIMIT", &problems),
+		},
+	}
+	if evaluationConfig.Defaults.OutputLimit > evaluationConfig.Max.OutputLimit ||
+		evaluationConfig.Defaults.StderrLimit > evaluationConfig.Max.StderrLimit ||
+		evaluationConfig.Defaults.ProcessLimit > evaluationConfig.Max.ProcessLimit {
+		problems.add("default evaluation limits %+v are greater than the maximum %+v", evaluationConfig.Defaults, evaluationConfig.Max)
+	}
+
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
 	}
 
 	return &Config{
@@ -133,24 +655,123 @@ func NewConfig() *Config {
 			Name:     dbName,
 		},
 		App: AppConfig{
-			Port: appPort,
+			Port:                      appPort,
+			MaxJSONBodySize:           maxJSONBodySize,
+			MaxMultipartBodySize:      maxMultipartBodySize,
+			MaxInFlightRequests:       maxInFlightRequests,
+			ReuseIdenticalSubmissions: reuseIdenticalSubmissions,
+			EstimatedCounts:           estimatedCounts,
 		},
 		BrokerConfig: BrokerConfig{
-			QueueName:         queueName,
-			ResponseQueueName: responseQueueName,
-			Host:              queueHost,
-			Port:              queuePort,
-			User:              queueUser,
-			Password:          queuePassword,
+			QueueName:           queueName,
+			ResponseQueueName:   responseQueueName,
+			HighMemoryQueueName: highMemoryQueueName,
+			HighMemoryThreshold: highMemoryThreshold,
+			MaxAttempts:         queueMaxAttempts,
+			RetryBackoff:        time.Duration(queueRetryBackoff) * time.Second,
+			Host:                queueHost,
+			Port:                queuePort,
+			User:                queueUser,
+			Password:            queuePassword,
 		},
 		FileStorageUrl: fileStorageUrl,
-	}
+		Redis:          redisConfig,
+		Sandbox:        sandboxConfig,
+		Analytics:      analyticsConfig,
+		JudgeAudit:     judgeAuditConfig,
+		Mail:           mailConfig,
+		Pagination:     paginationConfig,
+		Archive:        archiveConfig,
+		Evaluation:     evaluationConfig,
+		OAuth:          oauthConfig,
+		LDAP:           ldapConfig,
+		Scan:           scanConfig,
+	}, nil
 }
 
-func validatePort(port string, which string, log *zap.SugaredLogger) uint16 {
+// validatePort parses a port number. Empty ports are left to the caller, which reports them as not set
+func validatePort(port string, which string, problems *configProblems) uint16 {
+	if port == "" {
+		return 0
+	}
 	p, err := strconv.ParseUint(port, 10, 16)
 	if err != nil {
-		log.Panicf("invalid %s port number %s", which, port)
+		problems.add("invalid %s port number %s", which, port)
 	}
 	return uint16(p)
 }
+
+// parseSize parses a positive size, falling back to defaultSize when size is empty
+func parseSize(size string, defaultSize int64, which string, problems *configProblems) int64 {
+	if size == "" {
+		return defaultSize
+	}
+	s, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || s <= 0 {
+		problems.add("invalid %s %s", which, size)
+		return defaultSize
+	}
+	return s
+}
+
+// parsePaginationLimits reads <class>_PAGE_SIZE and <class>_MAX_PAGE_SIZE, falling back to the defaults when unset
+func parsePaginationLimits(class string, defaultLimit int64, maxLimit int64, problems *configProblems) PaginationLimits {
+	limits := PaginationLimits{
+		DefaultLimit: parseSize(os.Getenv(class+"_PAGE_SIZE"), defaultLimit, class+"_PAGE_SIZE", problems),
+		MaxLimit:     parseSize(os.Getenv(class+"_MAX_PAGE_SIZE"), maxLimit, class+"_MAX_PAGE_SIZE", problems),
+	}
+	if limits.DefaultLimit > limits.MaxLimit {
+		problems.add("%s_PAGE_SIZE %d is greater than %s_MAX_PAGE_SIZE %d", class, limits.DefaultLimit, class, limits.MaxLimit)
+	}
+	return limits
+}
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// splitNonEmpty splits s by sep, dropping empty and blank parts
+func splitNonEmpty(s string, sep string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// parseOAuthProvider reads the OAUTH_<NAME>_* variables of a provider. Role rules are comma separated
+// role=pattern pairs, e.g. OAUTH_SSO_ROLE_RULES=teacher=*@staff.example.edu,admin=it@example.edu
+func parseOAuthProvider(name string, problems *configProblems) OAuthProviderConfig {
+	prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+	provider := OAuthProviderConfig{
+		Issuer:       strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+		ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectUrl:  os.Getenv(prefix + "REDIRECT_URL"),
+	}
+	if provider.Issuer == "" || provider.ClientId == "" || provider.ClientSecret == "" || provider.RedirectUrl == "" {
+		problems.add("%sISSUER, %sCLIENT_ID, %sCLIENT_SECRET and %sREDIRECT_URL are required for OAuth provider %s", prefix, prefix, prefix, prefix, name)
+	}
+	roleRulesStr := os.Getenv(prefix + "ROLE_RULES")
+	if roleRulesStr == "" {
+		return provider
+	}
+	for _, ruleStr := range strings.Split(roleRulesStr, ",") {
+		role, pattern, ok := strings.Cut(strings.TrimSpace(ruleStr), "=")
+		if !ok || (role != "student" && role != "teacher" && role != "admin") {
+			problems.add("invalid %sROLE_RULES rule %s", prefix, ruleStr)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			problems.add("invalid %sROLE_RULES pattern %s", prefix, pattern)
+			continue
+		}
+		provider.RoleRules = append(provider.RoleRules, OAuthRoleRule{Role: role, EmailPattern: strings.ToLower(pattern)})
+	}
+	return provider
+}
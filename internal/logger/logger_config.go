@@ -2,6 +2,7 @@ package logger
 
 import (
 	"os"
+	"path/filepath"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -23,6 +24,16 @@ var http_sugar_logger *zap.SugaredLogger
 
 // InitializeLogger sets up Zap with a custom configuration and initializes the SugaredLogger
 func InitializeLogger() {
+	initializeLogger(logPath, httpLogPath)
+}
+
+// InitializeLoggerInDir initializes the loggers like InitializeLogger, but writes log files under dir.
+// Used by tests to keep logs out of the source tree
+func InitializeLoggerInDir(dir string) {
+	initializeLogger(filepath.Join(dir, "services", "log.txt"), filepath.Join(dir, "http", "log.txt"))
+}
+
+func initializeLogger(logPath string, httpLogPath string) {
 	// Configure log rotation with lumberjack
 	w := zapcore.AddSync(&lumberjack.Logger{
 		Filename: logPath,
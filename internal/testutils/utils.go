@@ -1,13 +1,17 @@
 package testutils
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/mini-maxit/backend/internal/broker"
+	"github.com/mini-maxit/backend/internal/cache"
 	"github.com/mini-maxit/backend/internal/config"
 	"github.com/mini-maxit/backend/internal/database"
 	"github.com/mini-maxit/backend/package/repository"
-	"github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -25,14 +29,34 @@ func NewTestConfig() *config.Config {
 		App: config.AppConfig{
 			Port: 8080,
 		},
+		Redis: config.RedisConfig{
+			Enabled: true,
+			Host:    "localhost",
+			Port:    6379,
+			DB:      15,
+		},
 		BrokerConfig: config.BrokerConfig{
 			QueueName:         "test_worker_queue",
 			ResponseQueueName: "test_worker_response_queue",
+			MaxAttempts:       3,
+			RetryBackoff:      time.Second,
 			Host:              "localhost",
 			Port:              5672,
 			User:              "guest",
 			Password:          "guest",
 		},
+		Evaluation: config.EvaluationConfig{
+			Defaults: config.EvaluationLimits{
+				OutputLimit:  config.DEFAULT_OUTPUT_LIMIT,
+				StderrLimit:  config.DEFAULT_STDERR_LIMIT,
+				ProcessLimit: config.DEFAULT_PROCESS_LIMIT,
+			},
+			Max: config.EvaluationLimits{
+				OutputLimit:  config.DEFAULT_MAX_OUTPUT_LIMIT,
+				StderrLimit:  config.DEFAULT_MAX_STDERR_LIMIT,
+				ProcessLimit: config.DEFAULT_MAX_PROCESS_LIMIT,
+			},
+		},
 	}
 }
 
@@ -68,6 +92,18 @@ func NewTestPostgresDB(t *testing.T, cfg *config.Config) database.Database {
 	if err != nil {
 		t.Fatalf("failed to create submission result repository %v", err)
 	}
+	_, err = repository.NewInputOutputRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create input output repository %v", err)
+	}
+	_, err = repository.NewTestResultRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create test result repository %v", err)
+	}
+	_, err = repository.NewManualGradeRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create manual grade repository %v", err)
+	}
 	_, err = repository.NewQueueMessageRepository(db)
 	if err != nil {
 		t.Fatalf("failed to create queue message repository %f", err)
@@ -76,6 +112,118 @@ func NewTestPostgresDB(t *testing.T, cfg *config.Config) database.Database {
 	if err != nil {
 		t.Fatalf("failed to create session repository %f", err)
 	}
+	_, err = repository.NewTaskBookmarkRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task bookmark repository %v", err)
+	}
+	_, err = repository.NewTaskNoteRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task note repository %v", err)
+	}
+	_, err = repository.NewTaskDraftRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task draft repository %v", err)
+	}
+	_, err = repository.NewTaskCoAuthorRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task co-author repository %v", err)
+	}
+	_, err = repository.NewNotificationRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create notification repository %v", err)
+	}
+	_, err = repository.NewPlagiarismRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create plagiarism repository %v", err)
+	}
+	_, err = repository.NewAnnouncementRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create announcement repository %v", err)
+	}
+	_, err = repository.NewQuarantineRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create quarantine repository %v", err)
+	}
+	_, err = repository.NewUserTaskSummaryRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create user task summary repository %v", err)
+	}
+	_, err = repository.NewTaskVerdictSummaryRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task verdict summary repository %v", err)
+	}
+	_, err = repository.NewTaskChangeRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task change repository %v", err)
+	}
+	_, err = repository.NewQueueFailureRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create queue failure repository %v", err)
+	}
+	_, err = repository.NewTimelineEventRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create timeline event repository %v", err)
+	}
+	_, err = repository.NewAuditLogRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create audit log repository %v", err)
+	}
+	_, err = repository.NewRoleRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create role repository %v", err)
+	}
+	_, err = repository.NewSchemaVersionRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create schema version repository %v", err)
+	}
+	_, err = repository.NewTestCaseGroupRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create test case group repository %v", err)
+	}
+	_, err = repository.NewTaskPoolRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create task pool repository %v", err)
+	}
+	_, err = repository.NewTagRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create tag repository %v", err)
+	}
+	_, err = repository.NewOnlineMigrationRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create online migration repository %v", err)
+	}
+	_, err = repository.NewIncidentRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create incident repository %v", err)
+	}
+	_, err = repository.NewTermRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create term repository %v", err)
+	}
+	_, err = repository.NewTrustListRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create trust list repository %v", err)
+	}
+	_, err = repository.NewJudgeAuditRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create judge audit repository %v", err)
+	}
+	_, err = repository.NewRejudgeRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create rejudge repository %v", err)
+	}
+	_, err = repository.NewRefreshTokenRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create refresh token repository %v", err)
+	}
+	_, err = repository.NewPasswordResetRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create password reset repository %v", err)
+	}
+	_, err = repository.NewOAuthRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create oauth repository %v", err)
+	}
 
 	return &database.PostgresDB{Db: db}
 }
@@ -91,17 +239,34 @@ func NewTestTx(t *testing.T) *gorm.DB {
 	return tx
 }
 
-func NewTestChannel(t *testing.T) (*amqp091.Connection, *amqp091.Channel) {
+func NewTestConnection(t *testing.T) *broker.Connection {
 	cfg := NewTestConfig()
-	conn, err := amqp091.Dial(fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.BrokerConfig.User, cfg.BrokerConfig.Password, cfg.BrokerConfig.Host, cfg.BrokerConfig.Port))
+	connection, err := broker.Dial(fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.BrokerConfig.User, cfg.BrokerConfig.Password, cfg.BrokerConfig.Host, cfg.BrokerConfig.Port))
 	if err != nil {
 		t.Fatalf("failed to create a new amqp connection: %v", err)
 	}
+	t.Cleanup(func() { connection.Close() })
 
-	ch, err := conn.Channel()
+	return connection
+}
+
+// NewTestRedisClient connects to the test Redis database, which is flushed before and after the test
+func NewTestRedisClient(t *testing.T) *redis.Client {
+	cfg := NewTestConfig()
+	client, err := cache.NewRedisClient(cfg)
 	if err != nil {
-		t.Fatalf("failed to create a new amqp channel: %v", err)
+		t.Fatalf("failed to create a new redis client: %v", err)
+	}
+	flush := func() {
+		if err := client.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatalf("failed to flush the redis database: %v", err)
+		}
 	}
+	flush()
+	t.Cleanup(func() {
+		flush()
+		client.Close()
+	})
 
-	return conn, ch
+	return client
 }
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AnalyticsExportHour is the UTC hour at which the previous day is exported
+const AnalyticsExportHour = 2
+
+type AnalyticsExportWorker interface {
+	// Start exports the previous day immediately if it was not exported yet and then nightly
+	// until the returned function is called
+	Start() context.CancelFunc
+}
+
+type AnalyticsExportWorkerImpl struct {
+	db               *gorm.DB
+	analyticsService service.AnalyticsService
+	logger           *zap.SugaredLogger
+}
+
+func (aw *AnalyticsExportWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go aw.run(ctx)
+	return cancel
+}
+
+func (aw *AnalyticsExportWorkerImpl) run(ctx context.Context) {
+	for {
+		now := time.Now().UTC()
+		err := aw.db.Transaction(func(tx *gorm.DB) error {
+			_, err := aw.analyticsService.ExportDay(tx, now.AddDate(0, 0, -1))
+			return err
+		})
+		if err != nil {
+			aw.logger.Errorf("Analytics export failed: %s", err.Error())
+		}
+
+		next := time.Date(now.Year(), now.Month(), now.Day(), AnalyticsExportHour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			aw.logger.Info("Stopping analytics export...")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func NewAnalyticsExportWorker(db *gorm.DB, analyticsService service.AnalyticsService) AnalyticsExportWorker {
+	log := logger.NewNamedLogger("analytics_export_worker")
+	return &AnalyticsExportWorkerImpl{
+		db:               db,
+		analyticsService: analyticsService,
+		logger:           log,
+	}
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ArchiveInterval is how often sources of submissions past the archive age are archived and sources of
+// superseded attempts past the prune age are pruned
+const ArchiveInterval = 24 * time.Hour
+
+type ArchiveWorker interface {
+	// Start archives and prunes sources of old submissions immediately and then daily until the returned
+	// function is called
+	Start() context.CancelFunc
+}
+
+type ArchiveWorkerImpl struct {
+	db             *gorm.DB
+	archiveService service.ArchiveService
+	cfg            config.ArchiveConfig
+	logger         *zap.SugaredLogger
+}
+
+func (aw *ArchiveWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go aw.run(ctx)
+	return cancel
+}
+
+func (aw *ArchiveWorkerImpl) run(ctx context.Context) {
+	ticker := time.NewTicker(ArchiveInterval)
+	defer ticker.Stop()
+	for {
+		// Not run in a transaction, sources are recorded one by one as file storage moves or deletes them.
+		// Pruned first, so sources about to be deleted are not moved to the archive storage class
+		pruned, err := aw.archiveService.PruneSubmissions(aw.db, time.Now().Add(-aw.cfg.PruneAfter), aw.cfg.PruneSuperseded)
+		if err != nil {
+			aw.logger.Errorf("Pruning submissions failed after %d submissions: %s", pruned, err.Error())
+		} else if pruned > 0 {
+			aw.logger.Infof("Pruned sources of %d superseded submissions", pruned)
+		}
+		if aw.cfg.Enabled {
+			archived, err := aw.archiveService.ArchiveSubmissions(aw.db, time.Now().Add(-aw.cfg.After))
+			if err != nil {
+				aw.logger.Errorf("Archiving submissions failed after %d submissions: %s", archived, err.Error())
+			} else if archived > 0 {
+				aw.logger.Infof("Archived sources of %d submissions", archived)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			aw.logger.Info("Stopping archiving...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewArchiveWorker(db *gorm.DB, archiveService service.ArchiveService, cfg config.ArchiveConfig) ArchiveWorker {
+	log := logger.NewNamedLogger("archive_worker")
+	return &ArchiveWorkerImpl{
+		db:             db,
+		archiveService: archiveService,
+		cfg:            cfg,
+		logger:         log,
+	}
+}
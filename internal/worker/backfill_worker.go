@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// BackfillInterval is the pause between backfill batches, so backfills do not starve regular traffic
+const BackfillInterval = 200 * time.Millisecond
+
+// MaxBackfillBackoff caps the pause before retrying a migration whose batches keep failing. Paused
+// migrations are checked at this pace until an admin resumes them
+const MaxBackfillBackoff = 5 * time.Minute
+
+type BackfillWorker interface {
+	// Start backfills registered online migrations in the background until the returned function is called
+	Start() context.CancelFunc
+}
+
+type BackfillWorkerImpl struct {
+	// Connection pool used to run every batch in its own transaction
+	db                     *gorm.DB
+	onlineMigrationService service.OnlineMigrationService
+	logger                 *zap.SugaredLogger
+}
+
+func (bw *BackfillWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go bw.run(ctx)
+	return cancel
+}
+
+func (bw *BackfillWorkerImpl) run(ctx context.Context) {
+	pending := slices.Clone(bw.onlineMigrationService.RegisteredMigrations())
+	if len(pending) == 0 {
+		return
+	}
+	bw.logger.Infof("Starting backfills of online migrations %v", pending)
+
+	// Migrations whose last batches failed are retried after an exponentially growing pause
+	failures := make(map[string]int)
+	retryAt := make(map[string]time.Time)
+	ticker := time.NewTicker(BackfillInterval)
+	defer ticker.Stop()
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			bw.logger.Info("Stopping backfills of online migrations...")
+			return
+		case now := <-ticker.C:
+			pending = slices.DeleteFunc(pending, func(name string) bool {
+				if now.Before(retryAt[name]) {
+					return false
+				}
+				done, err := bw.backfillBatch(name)
+				switch {
+				case errors.Is(err, service.ErrOnlineMigrationPaused):
+					retryAt[name] = now.Add(MaxBackfillBackoff)
+				case err != nil:
+					failures[name]++
+					retryAt[name] = now.Add(backfillBackoff(failures[name]))
+				default:
+					delete(failures, name)
+					delete(retryAt, name)
+				}
+				return done
+			})
+		}
+	}
+	bw.logger.Info("All online migrations are backfilled")
+}
+
+// backfillBatch runs a single batch of the migration and reports whether the migration is backfilled.
+// Errors of failed batches are recorded on the migration
+func (bw *BackfillWorkerImpl) backfillBatch(name string) (bool, error) {
+	done := false
+	backfillErr := bw.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		done, err = bw.onlineMigrationService.BackfillBatch(tx, name)
+		return err
+	})
+	if errors.Is(backfillErr, service.ErrOnlineMigrationPaused) {
+		return false, backfillErr
+	}
+	if backfillErr != nil {
+		bw.logger.Errorf("Backfill batch of online migration %s failed: %s", name, backfillErr.Error())
+		err := bw.db.Transaction(func(tx *gorm.DB) error {
+			return bw.onlineMigrationService.RecordBackfillError(tx, name, backfillErr)
+		})
+		if err != nil {
+			bw.logger.Errorf("Failed to record backfill error of online migration %s: %s", name, err.Error())
+		}
+		return false, backfillErr
+	}
+	return done, nil
+}
+
+// backfillBackoff returns the pause before retrying a migration after the given number of batches failed in a row
+func backfillBackoff(failures int) time.Duration {
+	backoff := BackfillInterval
+	for range failures {
+		backoff *= 2
+		if backoff >= MaxBackfillBackoff {
+			return MaxBackfillBackoff
+		}
+	}
+	return backoff
+}
+
+func NewBackfillWorker(db *gorm.DB, onlineMigrationService service.OnlineMigrationService) BackfillWorker {
+	log := logger.NewNamedLogger("backfill_worker")
+	return &BackfillWorkerImpl{
+		db:                     db,
+		onlineMigrationService: onlineMigrationService,
+		logger:                 log,
+	}
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DraftCleanupInterval is how often drafts past their TTL are deleted
+const DraftCleanupInterval = time.Hour
+
+type DraftCleanupWorker interface {
+	// Start deletes expired drafts immediately and then periodically until the returned function is called
+	Start() context.CancelFunc
+}
+
+type DraftCleanupWorkerImpl struct {
+	db          *gorm.DB
+	taskService service.TaskService
+	logger      *zap.SugaredLogger
+}
+
+func (dw *DraftCleanupWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go dw.run(ctx)
+	return cancel
+}
+
+func (dw *DraftCleanupWorkerImpl) run(ctx context.Context) {
+	ticker := time.NewTicker(DraftCleanupInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := dw.taskService.DeleteExpiredDrafts(dw.db)
+		if err != nil {
+			dw.logger.Errorf("Draft cleanup failed: %s", err.Error())
+		} else if deleted > 0 {
+			dw.logger.Infof("Deleted %d expired drafts", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			dw.logger.Info("Stopping draft cleanup...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewDraftCleanupWorker(db *gorm.DB, taskService service.TaskService) DraftCleanupWorker {
+	log := logger.NewNamedLogger("draft_cleanup_worker")
+	return &DraftCleanupWorkerImpl{
+		db:          db,
+		taskService: taskService,
+		logger:      log,
+	}
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// JudgeAuditInterval is how often a sample of judged submissions is re-run
+const JudgeAuditInterval = 6 * time.Hour
+
+type JudgeAuditWorker interface {
+	// Start periodically re-runs a sample of judged submissions until the returned function is called.
+	// The first sample is taken after JudgeAuditInterval, so restarts do not add load on the judge
+	Start() context.CancelFunc
+}
+
+type JudgeAuditWorkerImpl struct {
+	db                *gorm.DB
+	judgeAuditService service.JudgeAuditService
+	sampleSize        int
+	logger            *zap.SugaredLogger
+}
+
+func (jw *JudgeAuditWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go jw.run(ctx)
+	return cancel
+}
+
+func (jw *JudgeAuditWorkerImpl) run(ctx context.Context) {
+	ticker := time.NewTicker(JudgeAuditInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			jw.logger.Info("Stopping judge audit...")
+			return
+		case <-ticker.C:
+		}
+
+		err := jw.db.Transaction(func(tx *gorm.DB) error {
+			started, err := jw.judgeAuditService.StartAudit(tx, jw.sampleSize)
+			if err == nil {
+				jw.logger.Infof("Started %d judge audits", started)
+			}
+			return err
+		})
+		if err != nil {
+			jw.logger.Errorf("Judge audit failed: %s", err.Error())
+		}
+	}
+}
+
+func NewJudgeAuditWorker(db *gorm.DB, judgeAuditService service.JudgeAuditService, sampleSize int) JudgeAuditWorker {
+	log := logger.NewNamedLogger("judge_audit_worker")
+	return &JudgeAuditWorkerImpl{
+		db:                db,
+		judgeAuditService: judgeAuditService,
+		sampleSize:        sampleSize,
+		logger:            log,
+	}
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PartitionMaintenanceInterval is how often upcoming monthly partitions are created
+const PartitionMaintenanceInterval = 24 * time.Hour
+
+type PartitionWorker interface {
+	// Start creates upcoming partitions immediately and then periodically until the returned function is called
+	Start() context.CancelFunc
+}
+
+type PartitionWorkerImpl struct {
+	db               *gorm.DB
+	partitionService service.PartitionService
+	logger           *zap.SugaredLogger
+}
+
+func (pw *PartitionWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go pw.run(ctx)
+	return cancel
+}
+
+func (pw *PartitionWorkerImpl) run(ctx context.Context) {
+	ticker := time.NewTicker(PartitionMaintenanceInterval)
+	defer ticker.Stop()
+	for {
+		err := pw.db.Transaction(func(tx *gorm.DB) error {
+			return pw.partitionService.EnsurePartitions(tx, time.Now())
+		})
+		if err != nil {
+			pw.logger.Errorf("Partition maintenance failed: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			pw.logger.Info("Stopping partition maintenance...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewPartitionWorker(db *gorm.DB, partitionService service.PartitionService) PartitionWorker {
+	log := logger.NewNamedLogger("partition_worker")
+	return &PartitionWorkerImpl{
+		db:               db,
+		partitionService: partitionService,
+		logger:           log,
+	}
+}
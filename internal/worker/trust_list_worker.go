@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TrustListReloadInterval is how often the trust list is reloaded, so changes made
+// through any replica apply everywhere without a restart
+const TrustListReloadInterval = 10 * time.Second
+
+type TrustListWorker interface {
+	// Start loads the trust list immediately and then periodically until the returned function is called
+	Start() context.CancelFunc
+}
+
+type TrustListWorkerImpl struct {
+	db               *gorm.DB
+	trustListService service.TrustListService
+	logger           *zap.SugaredLogger
+}
+
+func (tw *TrustListWorkerImpl) Start() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go tw.run(ctx)
+	return cancel
+}
+
+func (tw *TrustListWorkerImpl) run(ctx context.Context) {
+	ticker := time.NewTicker(TrustListReloadInterval)
+	defer ticker.Stop()
+	for {
+		err := tw.trustListService.Reload(tw.db)
+		if err != nil {
+			tw.logger.Errorf("Trust list reload failed: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			tw.logger.Info("Stopping trust list reload...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewTrustListWorker(db *gorm.DB, trustListService service.TrustListService) TrustListWorker {
+	log := logger.NewNamedLogger("trust_list_worker")
+	return &TrustListWorkerImpl{
+		db:               db,
+		trustListService: trustListService,
+		logger:           log,
+	}
+}
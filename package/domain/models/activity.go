@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type ActivityKind string
+
+const (
+	ActivityKindSubmission      ActivityKind = "submission"
+	ActivityKindRejudge         ActivityKind = "rejudge"
+	ActivityKindCoAuthorAdded   ActivityKind = "co_author_added"
+	ActivityKindCoAuthorRemoved ActivityKind = "co_author_removed"
+)
+
+// ActivityEvent is something that happened on a task, e.g. a new submission, a started rejudge or a co-author change.
+// ResourceId is the id of the submission or rejudge batch, or of the user added to or removed from the co-authors.
+// It is not stored
+type ActivityEvent struct {
+	Kind       ActivityKind
+	ResourceId int64
+	TaskId     int64
+	TaskTitle  string
+	ActorId    int64 // User who submitted, started the rejudge or changed the co-authors
+	OccurredAt time.Time
+}
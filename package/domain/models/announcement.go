@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Announcement is shown to every user, or only to members of the group, from PublishedAt until ExpiresAt
+type Announcement struct {
+	Id          int64     `gorm:"primaryKey;autoIncrement"`
+	Title       string    `gorm:"type:varchar(255);NOT NULL"`
+	Message     string    `gorm:"type:text;NOT NULL"`
+	GroupId     *int64    `gorm:"index"` // Null for announcements to every user
+	CreatedBy   int64     `gorm:"NOT NULL"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	PublishedAt time.Time `gorm:"NOT NULL;index"`
+	ExpiresAt   *time.Time
+	Author      User `gorm:"foreignKey:CreatedBy; references:Id"`
+}
+
+// AnnouncementRead records when a user first read an announcement
+type AnnouncementRead struct {
+	AnnouncementId int64     `gorm:"primaryKey"`
+	UserId         int64     `gorm:"primaryKey"`
+	ReadAt         time.Time `gorm:"NOT NULL"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditActionUserStatusChanged AuditAction = "user_status_changed"
+	AuditActionUserImpersonated  AuditAction = "user_impersonated"
+	AuditActionUserRoleChanged   AuditAction = "user_role_changed"
+)
+
+// AuditLogEntry records an action of an admin on the account of another user
+type AuditLogEntry struct {
+	Id           int64       `gorm:"primaryKey;autoIncrement"`
+	ActorId      int64       `gorm:"NOT NULL;index"`
+	Action       AuditAction `gorm:"type:varchar(50);NOT NULL"`
+	TargetUserId int64       `gorm:"NOT NULL;index"`
+	Detail       string      `gorm:"type:text;NOT NULL"`
+	CreatedAt    time.Time   `gorm:"autoCreateTime;index"`
+	Actor        User        `gorm:"foreignKey:ActorId; references:Id"`
+	TargetUser   User        `gorm:"foreignKey:TargetUserId; references:Id"`
+}
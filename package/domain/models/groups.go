@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Group struct {
 	Id   int64  `gorm:"primaryKey;"`
 	Name string `gorm:"not null;"`
@@ -13,4 +15,30 @@ type UserGroup struct {
 type TaskGroup struct {
 	TaskId  int64 `gorm:"primaryKey;"`
 	GroupId int64 `gorm:"primaryKey;"`
+	// Members of the group see only their own results of the task, not statistics of other users
+	HideStats bool `gorm:"NOT NULL;default:false"`
+}
+
+// GroupTaskResult aggregates submissions of a member of a group for a task of the group. It is not stored
+type GroupTaskResult struct {
+	UserId    int64
+	TaskId    int64
+	Attempts  int64
+	BestScore float64
+}
+
+// GroupTaskProgress aggregates best scores of members of a group for a task of the group. It is not stored
+type GroupTaskProgress struct {
+	TaskId          int64
+	Title           string
+	AttemptedUsers  int64
+	SolvedUsers     int64
+	MedianBestScore *float64 // Null when no member submitted
+}
+
+// GroupActivityDay counts submissions of members of a group for tasks of the group on a day. It is not stored
+type GroupActivityDay struct {
+	Day         time.Time
+	Submissions int64
+	Users       int64
 }
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type IncidentStatus string
+
+const (
+	IncidentStatusInvestigating IncidentStatus = "investigating"
+	IncidentStatusIdentified    IncidentStatus = "identified"
+	IncidentStatusMonitoring    IncidentStatus = "monitoring"
+	IncidentStatusResolved      IncidentStatus = "resolved"
+)
+
+// Incident is an outage or degradation reported on the status page
+type Incident struct {
+	Id         int64            `gorm:"primaryKey;autoIncrement"`
+	Title      string           `gorm:"type:varchar(255);NOT NULL"`
+	Status     IncidentStatus   `gorm:"type:varchar(32);NOT NULL"`
+	CreatedBy  int64            `gorm:"NOT NULL"`
+	CreatedAt  time.Time        `gorm:"autoCreateTime"`
+	ResolvedAt *time.Time       `gorm:"type:timestamp"`
+	Updates    []IncidentUpdate `gorm:"foreignKey:IncidentId; references:Id"`
+	Author     User             `gorm:"foreignKey:CreatedBy; references:Id"`
+}
+
+// IncidentUpdate is a progress message posted for an incident
+type IncidentUpdate struct {
+	Id         int64          `gorm:"primaryKey;autoIncrement"`
+	IncidentId int64          `gorm:"NOT NULL"`
+	Status     IncidentStatus `gorm:"type:varchar(32);NOT NULL"`
+	Message    string         `gorm:"type:text;NOT NULL"`
+	CreatedBy  int64          `gorm:"NOT NULL"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime"`
+	Author     User           `gorm:"foreignKey:CreatedBy; references:Id"`
+}
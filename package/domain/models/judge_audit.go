@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// JudgeAudit is a re-run of a judged submission whose verdict is compared with the recorded one.
+// Until the re-run result arrives the actual verdict and Discrepancy are null
+type JudgeAudit struct {
+	Id            int64   `gorm:"primaryKey;autoIncrement"`
+	SubmissionId  int64   `gorm:"NOT NULL;index"`
+	ExpectedCode  string  `gorm:"type:varchar(255);NOT NULL"`
+	ExpectedScore float64 `gorm:"NOT NULL"`
+	ActualCode    *string `gorm:"type:varchar(255)"`
+	ActualScore   *float64
+	Discrepancy   *bool      `gorm:"index"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	CheckedAt     *time.Time `gorm:"type:timestamp"`
+}
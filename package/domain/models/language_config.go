@@ -1,9 +1,81 @@
 package models
 
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
 type LanguageType string
 
+const (
+	LanguageTypeC      LanguageType = "c"
+	LanguageTypeCPP    LanguageType = "cpp"
+	LanguageTypePython LanguageType = "python"
+	LanguageTypeJava   LanguageType = "java"
+)
+
+// ExecutionMode tells the worker whether a source has to be compiled before it is run
+type ExecutionMode string
+
+const (
+	ExecutionModeCompile   ExecutionMode = "compile"
+	ExecutionModeInterpret ExecutionMode = "interpret"
+)
+
+var languageExtensions = map[LanguageType][]string{
+	LanguageTypeC:      {".c"},
+	LanguageTypeCPP:    {".cpp", ".cc", ".cxx"},
+	LanguageTypePython: {".py"},
+	LanguageTypeJava:   {".java"},
+}
+
+var languageContentTypes = map[LanguageType]string{
+	LanguageTypeC:      "text/x-c",
+	LanguageTypeCPP:    "text/x-c++src",
+	LanguageTypePython: "text/x-python",
+	LanguageTypeJava:   "text/x-java",
+}
+
+// Extensions returns file extensions accepted for sources in the language
+func (lt LanguageType) Extensions() []string {
+	return languageExtensions[lt]
+}
+
+// ContentType returns the media type of sources in the language, so clients can highlight their syntax
+func (lt LanguageType) ContentType() string {
+	contentType, ok := languageContentTypes[lt]
+	if !ok {
+		return "text/plain; charset=utf-8"
+	}
+	return contentType + "; charset=utf-8"
+}
+
+// AcceptsFile reports whether the file name has an extension of the language
+func (lt LanguageType) AcceptsFile(fileName string) bool {
+	return slices.Contains(languageExtensions[lt], strings.ToLower(filepath.Ext(fileName)))
+}
+
+// ExecutionMode returns how the worker runs sources in the language. Java is compiled to bytecode
+// with javac before it is run in the JVM
+func (lt LanguageType) ExecutionMode() ExecutionMode {
+	if lt == LanguageTypePython {
+		return ExecutionModeInterpret
+	}
+	return ExecutionModeCompile
+}
+
 type LanguageConfig struct {
 	Id      int64        `gorm:"primaryKey;"`
 	Type    LanguageType `gorm:"not null;"`
 	Version string       `gorm:"not null;"`
+	// Passed to the worker, which appends them to the compile and run commands of the language
+	CompilerFlags string `gorm:"type:varchar(255);not null;default:''"`
+	RunArgs       string `gorm:"type:varchar(255);not null;default:''"`
+	// Time and memory limits of tests are multiplied by these for submissions in the language
+	TimeMultiplier   float64 `gorm:"not null;default:1"`
+	MemoryMultiplier float64 `gorm:"not null;default:1"`
+	// Queue submissions in the language are published to, so they can be judged by dedicated workers.
+	// Empty for the default queue
+	QueueName string `gorm:"type:varchar(255);not null;default:''"`
 }
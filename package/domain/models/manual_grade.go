@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ManualGrade is a score and comment given by a teacher. It supersedes the judged score of the
+// submission and is kept when the submission is rejudged
+type ManualGrade struct {
+	SubmissionId int64     `gorm:"primaryKey;autoIncrement:false"`
+	Score        float64   `gorm:"not null"` // Percentage, like the judged score
+	Comment      string    `gorm:"type:varchar(1000);not null;default:''"`
+	GradedBy     int64     `gorm:"not null"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type NotificationType string
+
+const (
+	NotificationTypeCoAuthorAdded   NotificationType = "co_author_added"
+	NotificationTypeCoAuthorRemoved NotificationType = "co_author_removed"
+	NotificationTypeTaskUpdated     NotificationType = "task_updated"
+	// Announcement published to a group the user is a member of
+	NotificationTypeGroupAnnouncement NotificationType = "group_announcement"
+)
+
+// Notification tells a user about a change made by another user, such as being credited as co-author of a task
+type Notification struct {
+	Id        int64            `gorm:"primaryKey;autoIncrement"`
+	UserId    int64            `gorm:"NOT NULL;index"`
+	Type      NotificationType `gorm:"type:varchar(50);NOT NULL"`
+	ActorId   int64            `gorm:"NOT NULL"`
+	TaskId    *int64
+	Message   string     `gorm:"type:text;NOT NULL"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	ReadAt    *time.Time // Null until the user marks the notification read
+	User      User       `gorm:"foreignKey:UserId; references:Id"`
+	Actor     User       `gorm:"foreignKey:ActorId; references:Id"`
+}
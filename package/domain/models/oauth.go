@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// OAuthState is a one-time state of a login with an OAuth provider, checked when the provider redirects back.
+// Only the hash of the state is stored, together with the PKCE code verifier sent with the authorization code.
+// The state is bound to the client that started the login by a nonce kept in a cookie of the client
+type OAuthState struct {
+	Id              int64      `gorm:"primaryKey;autoIncrement"`
+	StateHash       string     `gorm:"type:varchar(64);NOT NULL;uniqueIndex"` // Hex encoded SHA-256 of the state
+	ClientNonceHash string     `gorm:"type:varchar(64);NOT NULL;default:''"`  // Hex encoded SHA-256 of the client nonce
+	Provider        string     `gorm:"NOT NULL"`
+	CodeVerifier    string     `gorm:"NOT NULL"`
+	ExpiresAt       time.Time  `gorm:"type:timestamp;NOT NULL"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+	UsedAt          *time.Time `gorm:"type:timestamp"`
+}
+
+// UserIdentity links an account to the subject of an OAuth provider, so renaming the email at the provider
+// does not lose the account
+type UserIdentity struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	UserId    int64     `gorm:"NOT NULL;index"`
+	Provider  string    `gorm:"NOT NULL;uniqueIndex:idx_user_identity_subject"`
+	Subject   string    `gorm:"NOT NULL;uniqueIndex:idx_user_identity_subject"`
+	Email     string    `gorm:"NOT NULL"` // Email at the provider at the last login
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	User User `gorm:"foreignKey:UserId; references:Id"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type OnlineMigrationPhase string
+
+const (
+	// Writers fill both the old and the new schema while existing rows are backfilled
+	OnlineMigrationPhaseDualWrite OnlineMigrationPhase = "dual_write"
+	// All rows are backfilled, reads still use the old schema
+	OnlineMigrationPhaseBackfilled OnlineMigrationPhase = "backfilled"
+	// Reads use the new schema
+	OnlineMigrationPhaseCutover OnlineMigrationPhase = "cutover"
+)
+
+// OnlineMigration tracks the progress of a schema migration applied without downtime
+type OnlineMigration struct {
+	Name        string               `gorm:"primaryKey;type:varchar(255)"`
+	Phase       OnlineMigrationPhase `gorm:"type:varchar(32);NOT NULL"`
+	LastId      int64                `gorm:"NOT NULL;default:0"` // Id of the last backfilled row
+	Processed   int64                `gorm:"NOT NULL;default:0"`
+	Total       int64                `gorm:"NOT NULL;default:0"`
+	Error       string               `gorm:"type:text"`          // Error of the last failed backfill batch
+	Failures    int                  `gorm:"NOT NULL;default:0"` // Backfill batches which failed in a row
+	PausedAt    *time.Time           // Set when the backfill is paused after too many failed batches
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
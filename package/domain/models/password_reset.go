@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a one-time token sent to the user to set a new password. Only the hash of the token is stored
+type PasswordResetToken struct {
+	Id        int64      `gorm:"primaryKey;autoIncrement"`
+	TokenHash string     `gorm:"type:varchar(64);NOT NULL;uniqueIndex"` // Hex encoded SHA-256 of the token
+	UserId    int64      `gorm:"NOT NULL;index"`
+	ExpiresAt time.Time  `gorm:"type:timestamp;NOT NULL"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UsedAt    *time.Time `gorm:"type:timestamp"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// PlagiarismCheck is the last comparison of the latest accepted submissions of each user for a task
+type PlagiarismCheck struct {
+	TaskId      int64     `gorm:"primaryKey;autoIncrement:false"`
+	Submissions int64     `gorm:"NOT NULL"` // Number of submissions compared
+	CheckedAt   time.Time `gorm:"NOT NULL"`
+	CheckedBy   int64     `gorm:"NOT NULL"`
+	Task        Task      `gorm:"foreignKey:TaskId; references:Id"`
+}
+
+// PlagiarismMatch is the similarity of two submissions in the same language found by the last check of the task.
+// FirstSubmissionId is the lower of the two ids. Pairs without anything in common are not stored
+type PlagiarismMatch struct {
+	TaskId             int64   `gorm:"primaryKey;autoIncrement:false"`
+	FirstSubmissionId  int64   `gorm:"primaryKey;autoIncrement:false"`
+	SecondSubmissionId int64   `gorm:"primaryKey;autoIncrement:false"`
+	FirstUserId        int64   `gorm:"NOT NULL"`
+	SecondUserId       int64   `gorm:"NOT NULL"`
+	Similarity         float64 `gorm:"NOT NULL"` // Share of fingerprints the sources have in common, from 0 to 1
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type UploadKind string
+
+const (
+	UploadKindTaskArchive UploadKind = "task_archive"
+	UploadKindSubmission  UploadKind = "submission"
+)
+
+// QuarantinedFile is an upload rejected by the file scanner. The file is kept under Path in the quarantine
+// directory so it can be reviewed, and is never stored in FileStorage
+type QuarantinedFile struct {
+	Id         int64      `gorm:"primaryKey;autoIncrement"`
+	Kind       UploadKind `gorm:"type:varchar(31);NOT NULL"`
+	Filename   string     `gorm:"type:varchar(255);NOT NULL"`
+	Path       string     `gorm:"type:varchar(255);NOT NULL"`
+	Sha256     string     `gorm:"type:varchar(64);NOT NULL"`
+	Size       int64      `gorm:"NOT NULL"`
+	Signature  string     `gorm:"type:varchar(255);NOT NULL"`
+	UploadedBy int64      `gorm:"NOT NULL;index"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	Uploader   User       `gorm:"foreignKey:UploadedBy; references:Id"`
+}
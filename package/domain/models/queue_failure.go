@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// QueueFailure is a submission the worker failed to evaluate on every attempt. It stays failed until an
+// admin requeues it
+type QueueFailure struct {
+	Id             int64  `gorm:"primaryKey;autoIncrement"`
+	MessageId      string `gorm:"not null;index"` // Last message published for the submission
+	SubmissionId   int64  `gorm:"not null;index"`
+	RejudgeBatchId *int64
+	QueueName      string     `gorm:"type:varchar(255);not null"`
+	Attempts       int64      `gorm:"not null"`
+	Error          string     `gorm:"type:text;not null;default:''"` // Message of the last internal error of the worker
+	FailedAt       time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	RequeuedAt     *time.Time // Set once an admin published the submission again
+	RequeuedBy     *int64
+	Submission     Submission `gorm:"foreignKey:SubmissionId;references:Id;constraint:-"`
+}
@@ -3,8 +3,11 @@ package models
 import "time"
 
 type QueueMessage struct {
-	Id           string     `gorm:"primaryKey;"`
-	SubmissionId int64      `gorm:"not null;"`
-	QueuedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
-	Submission   Submission `gorm:"foreignKey:SubmissionId;references:Id"`
+	Id             string     `gorm:"primaryKey;"`
+	SubmissionId   int64      `gorm:"not null;"`
+	QueuedAt       time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	AuditId        *int64     // Set when the message re-runs the submission for a judge audit
+	RejudgeBatchId *int64     // Set when the message rejudges the submission
+	Attempt        int64      `gorm:"not null;default:1"` // Number of the evaluation attempt, retries count up
+	Submission     Submission `gorm:"foreignKey:SubmissionId;references:Id;constraint:-"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived token exchanged for a new session. Only the hash of the token is stored.
+// Revoked tokens are kept, so reuse of a rotated token can be detected
+type RefreshToken struct {
+	Id        int64      `gorm:"primaryKey;autoIncrement"`
+	TokenHash string     `gorm:"type:varchar(64);NOT NULL;uniqueIndex"` // Hex encoded SHA-256 of the token
+	UserId    int64      `gorm:"NOT NULL;index"`
+	SessionId string     `gorm:"NOT NULL"` // Session issued with the token
+	ExpiresAt time.Time  `gorm:"type:timestamp;NOT NULL"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	RevokedAt *time.Time `gorm:"type:timestamp"`
+}
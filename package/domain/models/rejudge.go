@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RejudgeBatch tracks submissions of a task enqueued again, e.g. after its tests or limits changed.
+// Completed and Failed count judged submissions of the batch, it is finished once they add up to Total
+type RejudgeBatch struct {
+	Id         int64      `gorm:"primaryKey;autoIncrement"`
+	TaskId     int64      `gorm:"NOT NULL;index"`
+	CreatedBy  int64      `gorm:"NOT NULL"`
+	Total      int64      `gorm:"NOT NULL"`
+	Completed  int64      `gorm:"NOT NULL;default:0"`
+	Failed     int64      `gorm:"NOT NULL;default:0"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	FinishedAt *time.Time `gorm:"type:timestamp"`
+}
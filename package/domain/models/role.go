@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Role is a custom role defined by admins next to the built-in student, teacher and admin roles. Users with the
+// role are assigned its name and may perform exactly the actions of its permissions
+type Role struct {
+	Id          int64            `gorm:"primaryKey;autoIncrement"`
+	Name        string           `gorm:"type:varchar(30);NOT NULL;UNIQUE"`
+	Description string           `gorm:"type:varchar(255);NOT NULL;default:''"`
+	CreatedAt   time.Time        `gorm:"autoCreateTime"`
+	Permissions []RolePermission `gorm:"foreignKey:RoleId; references:Id"`
+}
+
+// RolePermission allows an action on a resource, either on resources of the user or, with the all scope, on every one
+type RolePermission struct {
+	RoleId   int64  `gorm:"primaryKey"`
+	Resource string `gorm:"type:varchar(30);primaryKey"`
+	Action   string `gorm:"type:varchar(30);primaryKey"`
+	Scope    string `gorm:"type:varchar(10);NOT NULL"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaVersion records the version of the schema the database was last initialized with. It has a single row
+type SchemaVersion struct {
+	Id        int64     `gorm:"primaryKey"`
+	Version   int64     `gorm:"NOT NULL"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
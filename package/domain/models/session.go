@@ -6,4 +6,6 @@ type Session struct {
 	Id        string
 	UserId    int64
 	ExpiresAt time.Time `gorm:"autoUpdateTime:false"`
+	// Set for sessions an admin opened as the user. They are not shared with the user's own session
+	ImpersonatedBy *int64
 }
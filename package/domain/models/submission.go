@@ -2,32 +2,54 @@ package models
 
 import "time"
 
+// Submission is partitioned monthly by SubmittedAt, which is therefore part of the primary key
 type Submission struct {
-	Id            int64          `gorm:"primaryKey;autoIncrement"`
-	TaskId        int64          `gorm:"not null; foreignKey:TaskID"`
-	UserId        int64          `gorm:"not null; foreignKey:UserID"`
-	Order         int64          `gorm:"not null"`
-	LanguageId    int64          `gorm:"not null; foreignKey:LanguageID"`
-	Status        string         `gorm:"type:varchar(50);not null"`
-	StatusMessage string         `gorm:"type:varchar"`
-	SubmittedAt   time.Time      `gorm:"type:timestamp;autoCreateTime"`
-	CheckedAt     *time.Time     `gorm:"type:timestamp"`
-	Language      LanguageConfig `gorm:"foreignKey:LanguageId;references:Id"`
-	Task          Task           `gorm:"foreignKey:TaskId;references:Id"`
-	User          User           `gorm:"foreignKey:UserId;references:Id"`
+	Id             int64          `gorm:"primaryKey;autoIncrement"`
+	TaskId         int64          `gorm:"not null; foreignKey:TaskID"`
+	UserId         int64          `gorm:"not null; foreignKey:UserID"`
+	Order          int64          `gorm:"not null"`
+	LanguageId     int64          `gorm:"not null; foreignKey:LanguageID"`
+	Status         string         `gorm:"type:varchar(50);not null"`
+	StatusMessage  string         `gorm:"type:varchar"`
+	SubmittedAt    time.Time      `gorm:"type:timestamp;primaryKey;autoCreateTime"`
+	CheckedAt      *time.Time     `gorm:"type:timestamp"`
+	TermId         *int64         `gorm:"index"`                                      // Term active when the submission was submitted
+	SourceHash     string         `gorm:"type:varchar(64);not null;default:'';index"` // Hex encoded SHA-256 of the source
+	EvaluationHash string         `gorm:"type:varchar(64);not null;default:''"`       // Hex encoded SHA-256 of the evaluation settings it was judged with
+	RedactedAt     *time.Time     `gorm:"type:timestamp"`                             // Set when the source was removed on a privacy request
+	ArchivedAt     *time.Time     `gorm:"type:timestamp"`                             // Set while the source is in the archive storage class
+	PrunedAt       *time.Time     `gorm:"type:timestamp"`                             // Set when the source of a superseded failing attempt was removed
+	Language       LanguageConfig `gorm:"foreignKey:LanguageId;references:Id"`
+	Task           Task           `gorm:"foreignKey:TaskId;references:Id"`
+	User           User           `gorm:"foreignKey:UserId;references:Id"`
+}
+
+// SubmissionKey is the position of a submission in listings ordered newest first
+type SubmissionKey struct {
+	SubmittedAt time.Time
+	Id          int64
 }
 
 type SubmissionResult struct {
-	Id           int64      `gorm:"primaryKey;autoIncrement"`
-	SubmissionId int64      `gorm:"not null"`
-	Code         string     `gorm:"not null"`
-	Message      string     `gorm:"type:varchar(255);not null"`
-	CreatedAt    time.Time  `gorm:"autoCreateTime"`
-	Submission   Submission `gorm:"foreignKey:SubmissionId;references:Id"`
+	Id           int64   `gorm:"primaryKey;autoIncrement"`
+	SubmissionId int64   `gorm:"not null"`
+	Code         string  `gorm:"not null"`
+	Message      string  `gorm:"type:varchar(255);not null"`
+	PassedTests  int64   `gorm:"not null;default:0"`
+	TotalTests   int64   `gorm:"not null;default:0"`
+	Score        float64 `gorm:"not null;default:0"` // Percentage of passed tests, or of points of passed test groups
+	// Environment the submission was judged in, as reported by the worker. Empty for results of older workers
+	WorkerVersion      string     `gorm:"type:varchar(255);not null;default:'';index"`
+	CompilerVersion    string     `gorm:"type:varchar(255);not null;default:'';index"`
+	SandboxImageDigest string     `gorm:"type:varchar(255);not null;default:'';index"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime"`
+	Submission         Submission `gorm:"foreignKey:SubmissionId;references:Id;constraint:-"` // Partitioned submissions have no unique id to reference
 }
 
+// TestResult is partitioned monthly by CreatedAt, which is therefore part of the primary key
 type TestResult struct {
 	ID                 int64            `gorm:"primaryKey;autoIncrement"`
+	CreatedAt          time.Time        `gorm:"primaryKey;autoCreateTime;default:CURRENT_TIMESTAMP"`
 	SubmissionResultId int64            `gorm:"not null"`
 	InputOutputId      int64            `gorm:"not null"`
 	Passed             bool             `gorm:"not null"`
@@ -35,3 +57,40 @@ type TestResult struct {
 	InputOutput        InputOutput      `gorm:"foreignKey:InputOutputId;references:Id"`
 	SubmissionResult   SubmissionResult `gorm:"foreignKey:SubmissionResultId;references:Id"`
 }
+
+// TestProgress is the outcome of a test reported while the submission is still being judged.
+// It is removed once the submission result is stored
+type TestProgress struct {
+	SubmissionId int64     `gorm:"primaryKey;autoIncrement:false"`
+	Order        int64     `gorm:"primaryKey;autoIncrement:false"`
+	Passed       bool      `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// JudgeEnvironment filters submissions by the environment they were judged in. Empty fields match any value
+type JudgeEnvironment struct {
+	WorkerVersion      string
+	CompilerVersion    string
+	SandboxImageDigest string
+}
+
+// SubmissionEvent is a checked submission with its verdict, as exported for learning analytics. It is not stored
+type SubmissionEvent struct {
+	SubmissionId     int64
+	UserId           int64
+	TaskId           int64
+	LanguageId       int64
+	Order            int64
+	Status           string
+	SubmittedAt      time.Time
+	CheckedAt        time.Time
+	Code             *string
+	Score            *float64
+	FirstSubmittedAt time.Time // First submission of the user for the task
+}
+
+// JudgeTiming is the time it took to judge a submission and its number of tests. It is not stored
+type JudgeTiming struct {
+	Seconds    float64
+	TotalTests int64
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Tag classifies tasks, e.g. by topic, so students can find practice problems
+type Tag struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"type:varchar(50);not null;unique"`
+	CreatedBy int64     `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	Author    User      `gorm:"foreignKey:CreatedBy; references:Id"`
+}
+
+type TaskTag struct {
+	TaskId int64 `gorm:"primaryKey"`
+	TagId  int64 `gorm:"primaryKey;index"`
+	Task   Task  `gorm:"foreignKey:TaskId; references:Id"`
+	Tag    Tag   `gorm:"foreignKey:TagId; references:Id"`
+}
@@ -9,9 +9,113 @@ type Task struct {
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 	CreatedBy int64     `gorm:"foreignKey:UserID"`
 	Author    User      `gorm:"foreignKey:CreatedBy; references:Id"`
+	// Sandbox tasks are listed in the public practice mode and readable without an account
+	Sandbox bool `gorm:"NOT NULL;default:false"`
+	// Version is incremented on every edit, edits based on an older version are rejected
+	Version          int64 `gorm:"NOT NULL;default:1"`
+	EvaluationPolicy `gorm:"embedded"`
+	TestVisibility   TestVisibility `gorm:"type:varchar(20);NOT NULL;default:'all'"`
+	// SubmissionRetention is which sources of submissions to the task are kept once they are old enough
+	SubmissionRetention SubmissionRetention `gorm:"type:varchar(20);NOT NULL;default:'default'"`
+	// Difficulty is empty until the author rates the task
+	Difficulty TaskDifficulty `gorm:"type:varchar(10);NOT NULL;default:''"`
+}
+
+// TaskSearch narrows down task listings. Empty fields match every task
+type TaskSearch struct {
+	// Tasks having every tag of the names
+	Tags       []string
+	Difficulty TaskDifficulty
+	// Case insensitive text in the title or a tag name of the task
+	Text string
+}
+
+// TestVisibility is how much of the test results of their submissions students see. Teachers of the task and
+// admins always see every test
+type TestVisibility string
+
+const (
+	TestVisibilityAll TestVisibility = "all"
+	// TestVisibilityFirstFailed shows tests up to and including the first failed one
+	TestVisibilityFirstFailed TestVisibility = "first_failed"
+	// TestVisibilityHidden shows only the verdict, the number of passed tests and the score
+	TestVisibilityHidden TestVisibility = "hidden"
+)
+
+type TaskDifficulty string
+
+const (
+	TaskDifficultyEasy   TaskDifficulty = "easy"
+	TaskDifficultyMedium TaskDifficulty = "medium"
+	TaskDifficultyHard   TaskDifficulty = "hard"
+)
+
+// SubmissionRetention is which sources of judged submissions are kept past the prune age of the platform
+type SubmissionRetention string
+
+const (
+	// SubmissionRetentionDefault follows the platform-wide policy
+	SubmissionRetentionDefault SubmissionRetention = "default"
+	SubmissionRetentionAll     SubmissionRetention = "all"
+	// SubmissionRetentionBestAndLatest prunes sources of failing attempts superseded by a later submission,
+	// keeping the best and the latest submission of every user
+	SubmissionRetentionBestAndLatest SubmissionRetention = "best_and_latest"
+)
+
+// EvaluationPolicy applies to every test of the task. Null limits are the defaults of the platform
+type EvaluationPolicy struct {
+	OutputLimit  *int64 // Kilobytes of standard output kept per test
+	StderrLimit  *int64 // Kilobytes of standard error captured per test
+	ProcessLimit *int64
 }
 
 type TaskUser struct {
 	TaskId int64 `gorm:"primaryKey"`
 	UserId int64 `gorm:"primaryKey"`
 }
+
+// TaskCoAuthor credits a user as co-author of a task. Co-authors are only displayed and get no access to the task
+type TaskCoAuthor struct {
+	TaskId      int64  `gorm:"primaryKey"`
+	UserId      int64  `gorm:"primaryKey"`
+	Position    int    `gorm:"NOT NULL"`
+	DisplayName string `gorm:"type:varchar(255);NOT NULL"`
+	Task        Task   `gorm:"foreignKey:TaskId; references:Id"`
+	User        User   `gorm:"foreignKey:UserId; references:Id"`
+}
+
+// TaskCoAuthorChange records a user added to or removed from the co-authors of a task, for the activity feed
+type TaskCoAuthorChange struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	TaskId    int64     `gorm:"NOT NULL;index"`
+	UserId    int64     `gorm:"NOT NULL"` // Co-author who was added or removed
+	Added     bool      `gorm:"NOT NULL"`
+	ChangedBy int64     `gorm:"NOT NULL"`
+	ChangedAt time.Time `gorm:"autoCreateTime"`
+	Task      Task      `gorm:"foreignKey:TaskId; references:Id"`
+	User      User      `gorm:"foreignKey:UserId; references:Id"`
+}
+
+// TaskPool groups equivalent tasks of a homework. Every student is assigned one of them, so students
+// see and solve different variants
+type TaskPool struct {
+	Id        int64          `gorm:"primaryKey;autoIncrement"`
+	Title     string         `gorm:"type:varchar(255);NOT NULL"`
+	CreatedBy int64          `gorm:"NOT NULL"`
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	Tasks     []TaskPoolTask `gorm:"foreignKey:PoolId; references:Id"`
+}
+
+// TaskPoolTask is a variant of a pool. A task is a variant of at most one pool
+type TaskPoolTask struct {
+	TaskId int64 `gorm:"primaryKey"`
+	PoolId int64 `gorm:"NOT NULL;index"`
+}
+
+// TaskStats aggregates submissions of a task, it is not stored
+type TaskStats struct {
+	Attempts    int64
+	Users       int64
+	Accepted    int64
+	SolvedUsers int64
+}
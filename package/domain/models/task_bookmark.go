@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// TaskBookmark marks a task as bookmarked by a user
+type TaskBookmark struct {
+	TaskId    int64     `gorm:"primaryKey"`
+	UserId    int64     `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	Task      Task      `gorm:"foreignKey:TaskId; references:Id"`
+	User      User      `gorm:"foreignKey:UserId; references:Id"`
+}
+
+// TaskNote is a private markdown note kept by a user for a task
+type TaskNote struct {
+	TaskId    int64     `gorm:"primaryKey"`
+	UserId    int64     `gorm:"primaryKey"`
+	Content   string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	Task      Task      `gorm:"foreignKey:TaskId; references:Id"`
+	User      User      `gorm:"foreignKey:UserId; references:Id"`
+}
+
+// TaskDraft is the in-progress source of a user for a task in a language, autosaved by the editor
+type TaskDraft struct {
+	TaskId     int64     `gorm:"primaryKey"`
+	UserId     int64     `gorm:"primaryKey"`
+	LanguageId int64     `gorm:"primaryKey"`
+	Content    string    `gorm:"type:text;not null"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime;index"`
+	Task       Task      `gorm:"foreignKey:TaskId; references:Id"`
+	User       User      `gorm:"foreignKey:UserId; references:Id"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TaskChange records a change of a field of a task which affects solutions, such as its limits.
+// Changes made while a term is in progress are announced to users who submitted to the task in the term
+type TaskChange struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	TaskId    int64     `gorm:"NOT NULL;index"`
+	ChangedBy int64     `gorm:"NOT NULL"`
+	Field     string    `gorm:"type:varchar(50);NOT NULL"`
+	OldValue  string    `gorm:"type:text;NOT NULL"`
+	NewValue  string    `gorm:"type:text;NOT NULL"`
+	TermId    *int64    // Term in progress when the task was changed
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	Task      Task      `gorm:"foreignKey:TaskId; references:Id"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TaskVerdictSummary caches how many judged submissions of a task got a verdict, so task lists do not
+// aggregate submissions of every task on every view. It is refreshed in the transaction storing a result for the task
+type TaskVerdictSummary struct {
+	TaskId      int64     `gorm:"primaryKey;autoIncrement:false"`
+	Code        string    `gorm:"primaryKey;type:varchar(255)"` // Verdict of the latest result of the submissions
+	Submissions int64     `gorm:"NOT NULL"`
+	Accepted    int64     `gorm:"NOT NULL"` // Submissions with full score
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
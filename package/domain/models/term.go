@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Term is an academic term. Submissions are tagged with the term they were submitted in,
+// so statistics of tasks reused across terms can be scoped to a single term
+type Term struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"type:varchar(100);not null;unique"`
+	StartsAt  time.Time `gorm:"not null"`
+	EndsAt    time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
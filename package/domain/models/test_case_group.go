@@ -0,0 +1,21 @@
+package models
+
+// TestCaseGroup is a subtask of a task worth Points. The points are awarded only when every test
+// of the group passes. Tasks with groups are scored by the points of passed groups instead of the
+// ratio of passed tests
+type TestCaseGroup struct {
+	Id       int64               `gorm:"primaryKey;autoIncrement"`
+	TaskId   int64               `gorm:"NOT NULL;index"`
+	Name     string              `gorm:"type:varchar(100);NOT NULL"`
+	Position int                 `gorm:"NOT NULL"`
+	Points   int64               `gorm:"NOT NULL"`
+	Tests    []TestCaseGroupTest `gorm:"foreignKey:GroupId; references:Id"`
+	Task     Task                `gorm:"foreignKey:TaskId; references:Id"`
+}
+
+// TestCaseGroupTest assigns the test of the task with the given order to a group. A test is in at most one group
+type TestCaseGroupTest struct {
+	TaskId  int64 `gorm:"primaryKey;autoIncrement:false"`
+	Order   int64 `gorm:"primaryKey;autoIncrement:false"`
+	GroupId int64 `gorm:"NOT NULL;index"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type TimelineEventType string
+
+const (
+	// TimelineEventEnqueued is a message of the submission published for the worker, once for every attempt
+	TimelineEventEnqueued TimelineEventType = "enqueued"
+	// TimelineEventPickedUp is the first progress report of the worker for a message
+	TimelineEventPickedUp TimelineEventType = "picked_up"
+	// TimelineEventTest is a test of the submission first reported by the worker
+	TimelineEventTest TimelineEventType = "test"
+	// TimelineEventWorkerError is an internal error of the worker evaluating a message
+	TimelineEventWorkerError TimelineEventType = "worker_error"
+	TimelineEventFailed      TimelineEventType = "failed"
+	// TimelineEventFinalized is the result of the submission being stored
+	TimelineEventFinalized TimelineEventType = "finalized"
+)
+
+// TimelineEvent is a step in the lifecycle of a submission, to tell where the time of judging it went
+type TimelineEvent struct {
+	Id           int64             `gorm:"primaryKey;autoIncrement"`
+	SubmissionId int64             `gorm:"not null;index"`
+	MessageId    string            `gorm:"type:varchar(36);not null;default:'';index"` // Queue message of the attempt, empty for events outside the queue
+	Type         TimelineEventType `gorm:"type:varchar(20);not null"`
+	TestOrder    *int64            // Set for test events
+	Detail       string            `gorm:"type:text;not null;default:''"`
+	CreatedAt    time.Time         `gorm:"autoCreateTime"`
+	Submission   Submission        `gorm:"foreignKey:SubmissionId;references:Id;constraint:-"` // Partitioned submissions have no unique id to reference
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type TrustListKind string
+
+const (
+	TrustListKindIPRange TrustListKind = "ip_range"
+	TrustListKindAPIKey  TrustListKind = "api_key"
+	TrustListKindUserId  TrustListKind = "user_id"
+)
+
+// TrustListEntry exempts matching requests from rate limiting and load shedding.
+// Value is a CIDR range, a hex encoded SHA-256 of an API key or a user id, depending on Kind
+type TrustListEntry struct {
+	Id          int64         `gorm:"primaryKey;autoIncrement"`
+	Kind        TrustListKind `gorm:"type:varchar(20);not null"`
+	Value       string        `gorm:"type:varchar(255);not null"`
+	Description string        `gorm:"type:varchar(255);not null;default:''"`
+	CreatedBy   int64         `gorm:"not null"`
+	CreatedAt   time.Time     `gorm:"autoCreateTime"`
+	Author      User          `gorm:"foreignKey:CreatedBy;references:Id"`
+}
@@ -7,15 +7,28 @@ import (
 )
 
 type User struct {
-	Id           int64    `gorm:"primaryKey;autoIncrement"`
-	Name         string   `gorm:"NOT NULL"`
-	Surname      string   `gorm:"NOT NULL"`
-	Email        string   `gorm:"NOT NULL;UNIQUE"`
-	Username     string   `gorm:"NOT NULL;UNIQUE"`
-	PasswordHash string   `gorm:"NOT NULL"`
-	Role         UserRole `gorm:"NOT NULL;default:'student'"` // student, teacher, admin
+	Id           int64      `gorm:"primaryKey;autoIncrement"`
+	Name         string     `gorm:"NOT NULL"`
+	Surname      string     `gorm:"NOT NULL"`
+	Email        string     `gorm:"NOT NULL;UNIQUE"`
+	Username     string     `gorm:"NOT NULL;UNIQUE"`
+	PasswordHash string     `gorm:"NOT NULL"`
+	Role         UserRole   `gorm:"NOT NULL;default:'student'"` // student, teacher, admin or the name of a custom role
+	Status       UserStatus `gorm:"type:varchar(20);NOT NULL;default:'active'"`
+	StatusReason string     `gorm:"type:varchar(500);NOT NULL;default:''"` // Given by the admin who deactivated or banned the user
 }
 
+// UserStatus tells whether the user can log in. Deactivated and banned users cannot log in and their sessions
+// are rejected, banned users were blocked for misconduct
+type UserStatus string
+
+const (
+	UserStatusActive      UserStatus = "active"
+	UserStatusDeactivated UserStatus = "deactivated"
+	UserStatusBanned      UserStatus = "banned"
+)
+
+// UserRole is one of the built-in roles or the name of a custom Role. Custom roles are checked when they are assigned
 type UserRole string
 
 func (ur *UserRole) Scan(value interface{}) error {
@@ -23,15 +36,18 @@ func (ur *UserRole) Scan(value interface{}) error {
 	if !ok {
 		return fmt.Errorf("UserRole must be a string")
 	}
-	role := UserRole(valueString)
-	availableRoles := []UserRole{UserRoleStudent, UserRoleTeacher, UserRoleAdmin}
-	if !slices.Contains(availableRoles, role) {
-		return fmt.Errorf("invalid UserRole: %s. Available roles: %s", role, availableRoles)
+	if valueString == "" {
+		return fmt.Errorf("UserRole cannot be empty")
 	}
-	*ur = role
+	*ur = UserRole(valueString)
 	return nil
 }
 
+// IsBuiltIn reports whether the role is student, teacher or admin rather than a custom role
+func (ur UserRole) IsBuiltIn() bool {
+	return slices.Contains(BuiltInRoles, ur)
+}
+
 func (ur UserRole) Value() (driver.Value, error) {
 	if ur == "" {
 		return nil, nil
@@ -44,3 +60,6 @@ const (
 	UserRoleTeacher UserRole = "teacher"
 	UserRoleAdmin   UserRole = "admin"
 )
+
+// BuiltInRoles are ordered by privilege, each role may perform the actions of the roles before it
+var BuiltInRoles = []UserRole{UserRoleStudent, UserRoleTeacher, UserRoleAdmin}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserTaskSummary caches the progress of a user on a task, so task lists do not aggregate submissions
+// on every view. It is refreshed in the transaction storing a result of the user for the task
+type UserTaskSummary struct {
+	UserId    int64     `gorm:"primaryKey;autoIncrement:false"`
+	TaskId    int64     `gorm:"primaryKey;autoIncrement:false;index"`
+	Attempts  int64     `gorm:"NOT NULL"` // Judged submissions
+	BestScore float64   `gorm:"NOT NULL"` // Best judged score, manual grades are not included
+	LastCode  string    `gorm:"NOT NULL"` // Verdict of the latest judged submission
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,14 @@
+package schemas
+
+import "time"
+
+// ActivityEvent is an entry of the activity feed. Kind is submission, rejudge, co_author_added or co_author_removed.
+// ResourceId is the id of the submission or rejudge batch, or of the user added to or removed from the co-authors
+type ActivityEvent struct {
+	Kind       string    `json:"kind"`
+	ResourceId int64     `json:"resource_id"`
+	TaskId     int64     `json:"task_id"`
+	TaskTitle  string    `json:"task_title"`
+	ActorId    int64     `json:"actor_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
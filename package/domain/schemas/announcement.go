@@ -0,0 +1,49 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/package/utils"
+)
+
+type Announcement struct {
+	Id          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	GroupId     *int64     `json:"group_id"` // Null for announcements to every user
+	CreatedBy   int64      `json:"created_by"`
+	PublishedAt time.Time  `json:"published_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	ReadAt      *time.Time `json:"read_at"` // Null while unread
+}
+
+type AnnouncementCreate struct {
+	Title   string `json:"title" validate:"required,max=255"`
+	Message string `json:"message" validate:"required"`
+	// Group whose members see the announcement, null for every user
+	GroupId     *int64     `json:"group_id" validate:"omitempty,gt=0"`
+	PublishedAt *time.Time `json:"published_at"` // Null to publish right away
+	ExpiresAt   *time.Time `json:"expires_at"`   // Null for announcements which do not expire
+}
+
+// StructRules rejects announcements expiring before they are published, right away when published_at is null
+func (a AnnouncementCreate) StructRules(sl validator.StructLevel) {
+	if a.ExpiresAt == nil {
+		return
+	}
+	if a.PublishedAt != nil && !a.ExpiresAt.After(*a.PublishedAt) {
+		sl.ReportError(a.ExpiresAt, "expires_at", "ExpiresAt", "gtfield", "PublishedAt")
+	}
+	if a.PublishedAt == nil && !a.ExpiresAt.After(time.Now()) {
+		sl.ReportError(a.ExpiresAt, "expires_at", "ExpiresAt", "gt", "")
+	}
+}
+
+func init() {
+	utils.RegisterStructRules(AnnouncementCreate{})
+}
+
+type UnreadAnnouncements struct {
+	Unread int64 `json:"unread"`
+}
@@ -2,13 +2,41 @@ package schemas
 
 type UserLoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,gte=8,lte=50"`
+	Password string `json:"password" validate:"password"`
 }
 
 type UserRegisterRequest struct {
-	Name     string `json:"name" validate:"required,gte=3,lte=50"`
-	Surname  string `json:"surname" validate:"required,gte=3,lte=50"`
+	Name     string `json:"name" validate:"person_name"`
+	Surname  string `json:"surname" validate:"person_name"`
 	Email    string `json:"email" validate:"required,email"`
 	Username string `json:"username" validate:"required,gte=3,lte=30,username"`
-	Password string `json:"password" validate:"required,gte=8,lte=50"`
+	Password string `json:"password" validate:"password"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"password"`
+}
+
+type OAuthLogin struct {
+	// AuthorizationUrl is the login page of the provider the client is sent to
+	AuthorizationUrl string `json:"authorization_url"`
+	// ClientNonce binds the login to the client. It is set as a cookie, never returned in the body
+	ClientNonce string `json:"-"`
+}
+
+// OAuthCallbackRequest holds the query parameters the provider redirects back with
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+	// ClientNonce is read from the cookie set when the login was started
+	ClientNonce string `json:"-"`
 }
@@ -0,0 +1,31 @@
+package schemas
+
+import "time"
+
+type ManualGrade struct {
+	Score     float64   `json:"score"`
+	Comment   string    `json:"comment"`
+	GradedBy  int64     `json:"graded_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GradeImportRow is a row of a grade import file. Comment is optional
+type GradeImportRow struct {
+	SubmissionId int64   `json:"submission_id" validate:"gt=0"`
+	ManualScore  float64 `json:"manual_score" validate:"gte=0,lte=100"`
+	Comment      string  `json:"comment" validate:"max=1000"`
+}
+
+type GradeImportRowResult struct {
+	// Row is the line number in the file, the header is line 1
+	Row          int    `json:"row"`
+	SubmissionId int64  `json:"submission_id"`
+	Error        string `json:"error,omitempty"`
+}
+
+// GradeImportReport lists errors of the rows. Grades are applied only if no row failed
+type GradeImportReport struct {
+	Applied int                    `json:"applied"`
+	Failed  int                    `json:"failed"`
+	Rows    []GradeImportRowResult `json:"rows"`
+}
@@ -0,0 +1,58 @@
+package schemas
+
+import "time"
+
+// GroupStats summarizes how members of a group do on tasks assigned to the group
+type GroupStats struct {
+	GroupId  int64              `json:"group_id"`
+	Name     string             `json:"name"`
+	Tasks    []GroupTaskStats   `json:"tasks"`
+	Members  []GroupMemberStats `json:"members"`
+	Activity []GroupActivityDay `json:"activity"` // One entry per day, oldest first
+}
+
+type GroupTaskStats struct {
+	TaskId         int64   `json:"task_id"`
+	Title          string  `json:"title"`
+	AttemptedUsers int64   `json:"attempted_users"`
+	SolvedUsers    int64   `json:"solved_users"`
+	AverageScore   float64 `json:"average_score"` // Average best score of all members, zero for members who did not submit
+}
+
+type GroupMemberStats struct {
+	UserId       int64   `json:"user_id"`
+	Name         string  `json:"name"`
+	Surname      string  `json:"surname"`
+	Solved       int64   `json:"solved"`
+	Attempts     int64   `json:"attempts"`
+	AverageScore float64 `json:"average_score"` // Average best score over all tasks of the group
+	// Best score for each task in the order of Tasks, null for tasks the member did not submit to
+	Scores []*float64 `json:"scores"`
+}
+
+// GroupProgress shows for each task assigned to a group how far its members got
+type GroupProgress struct {
+	GroupId int64               `json:"group_id"`
+	Name    string              `json:"name"`
+	Tasks   []GroupTaskProgress `json:"tasks"`
+}
+
+type GroupTaskProgress struct {
+	TaskId         int64  `json:"task_id"`
+	Title          string `json:"title"`
+	AttemptedUsers int64  `json:"attempted_users"`
+	SolvedUsers    int64  `json:"solved_users"`
+	// Median best score of the members who submitted, null when nobody did
+	MedianBestScore *float64 `json:"median_best_score"`
+}
+
+type GroupActivityDay struct {
+	Day         time.Time `json:"day"`
+	Submissions int64     `json:"submissions"`
+	Users       int64     `json:"users"`
+}
+
+// GroupTaskStatsVisibility sets whether members of a group see statistics of other users for a task of the group
+type GroupTaskStatsVisibility struct {
+	Hidden *bool `json:"hidden" validate:"required"`
+}
@@ -0,0 +1,30 @@
+package schemas
+
+type OrphanCleanupRequest struct {
+	// Only report orphans without deleting them. Defaults to true
+	DryRun *bool `json:"dry_run,omitempty"`
+	// Number of rows deleted in a single statement. Defaults to 1000
+	BatchSize int `json:"batch_size,omitempty" validate:"batch_size"`
+}
+
+type OrphanedRecords struct {
+	Table      string `json:"table"`
+	Column     string `json:"column"`
+	References string `json:"references"`
+	Orphaned   int64  `json:"orphaned"`
+	Deleted    int64  `json:"deleted"`
+}
+
+// OrphanedFiles are files in a directory which no row points to
+type OrphanedFiles struct {
+	Directory string `json:"directory"`
+	Orphaned  int64  `json:"orphaned"`
+	Deleted   int64  `json:"deleted"`
+}
+
+type IntegrityReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Records []OrphanedRecords `json:"records"`
+	// Files are only checked in the quarantine directory, when uploads are scanned
+	Files []OrphanedFiles `json:"files"`
+}
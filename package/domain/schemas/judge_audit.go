@@ -0,0 +1,17 @@
+package schemas
+
+import "time"
+
+// JudgeAudit is a re-run of a judged submission compared with its recorded verdict.
+// Actual verdict and discrepancy are null until the re-run result arrives
+type JudgeAudit struct {
+	Id            int64      `json:"id"`
+	SubmissionId  int64      `json:"submission_id"`
+	ExpectedCode  string     `json:"expected_code"`
+	ExpectedScore float64    `json:"expected_score"`
+	ActualCode    *string    `json:"actual_code"`
+	ActualScore   *float64   `json:"actual_score"`
+	Discrepancy   *bool      `json:"discrepancy"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CheckedAt     *time.Time `json:"checked_at"`
+}
@@ -4,3 +4,40 @@ type LanguageConfig struct {
 	Language string `json:"language"`
 	Version  string `json:"version"`
 }
+
+// LanguageConfigDetailed is a language with the configuration of its commands passed to the worker
+type LanguageConfigDetailed struct {
+	Id               int64   `json:"id"`
+	Language         string  `json:"language"`
+	Version          string  `json:"version"`
+	CompilerFlags    string  `json:"compiler_flags"`
+	RunArgs          string  `json:"run_args"`
+	TimeMultiplier   float64 `json:"time_multiplier"`
+	MemoryMultiplier float64 `json:"memory_multiplier"`
+	// Queue submissions are published to, empty for the default queue
+	QueueName string `json:"queue_name"`
+	// Extensions accepted for sources in the language
+	Extensions    []string `json:"extensions"`
+	ExecutionMode string   `json:"execution_mode"`
+}
+
+type LanguageConfigCreate struct {
+	Language      string `json:"language" validate:"required,oneof=c cpp python java"`
+	Version       string `json:"version" validate:"required,max=50"`
+	CompilerFlags string `json:"compiler_flags" validate:"max=255"`
+	RunArgs       string `json:"run_args" validate:"max=255"`
+	// Multipliers default to 1
+	TimeMultiplier   *float64 `json:"time_multiplier" validate:"omitempty,gt=0,lte=100"`
+	MemoryMultiplier *float64 `json:"memory_multiplier" validate:"omitempty,gt=0,lte=100"`
+	QueueName        string   `json:"queue_name" validate:"max=255"`
+}
+
+// LanguageConfigUpdate changes the fields which are set
+type LanguageConfigUpdate struct {
+	CompilerFlags    *string  `json:"compiler_flags" validate:"omitempty,max=255"`
+	RunArgs          *string  `json:"run_args" validate:"omitempty,max=255"`
+	TimeMultiplier   *float64 `json:"time_multiplier" validate:"omitempty,gt=0,lte=100"`
+	MemoryMultiplier *float64 `json:"memory_multiplier" validate:"omitempty,gt=0,lte=100"`
+	// An empty queue name routes submissions to the default queue again
+	QueueName *string `json:"queue_name" validate:"omitempty,max=255"`
+}
@@ -0,0 +1,22 @@
+package schemas
+
+// Limits are the effective limits for the current user, so clients can disable actions up front.
+// Limits that do not apply to the user are null
+type Limits struct {
+	MaxJSONBodySize      int64 `json:"max_json_body_size"`      // Bytes
+	MaxMultipartBodySize int64 `json:"max_multipart_body_size"` // Bytes
+	MaxSubmissionSize    int64 `json:"max_submission_size"`     // Bytes
+	// Sandbox requests per minute from a single IP. Null when the sandbox is disabled or the request is trusted
+	SandboxRateLimit      *int       `json:"sandbox_rate_limit"`
+	MaxRejudgeSubmissions *int64     `json:"max_rejudge_submissions"` // Teachers and admins only
+	MaxUserImportRows     *int64     `json:"max_user_import_rows"`    // Admins only
+	Languages             []Language `json:"languages"`               // Languages solutions can be submitted in
+}
+
+type Language struct {
+	Id       int64  `json:"id"`
+	Language string `json:"language"`
+	Version  string `json:"version"`
+	// Extensions accepted for sources in the language
+	Extensions []string `json:"extensions"`
+}
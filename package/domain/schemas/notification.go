@@ -0,0 +1,14 @@
+package schemas
+
+import "time"
+
+// Notification tells the user about a change made by another user. Type is co_author_added, co_author_removed, task_updated or group_announcement
+type Notification struct {
+	Id        int64      `json:"id"`
+	Type      string     `json:"type"`
+	ActorId   int64      `json:"actor_id"`
+	TaskId    *int64     `json:"task_id"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"` // Null while unread
+}
@@ -0,0 +1,20 @@
+package schemas
+
+import "time"
+
+type OnlineMigration struct {
+	Name        string     `json:"name"`
+	Phase       string     `json:"phase"`
+	Processed   int64      `json:"processed"`
+	Total       int64      `json:"total"`
+	Error       string     `json:"error,omitempty"`
+	Failures    int        `json:"failures"`  // Backfill batches which failed in a row
+	PausedAt    *time.Time `json:"paused_at"` // Set while the backfill is paused after too many failed batches
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+type OnlineMigrationCutover struct {
+	// Enabled switches reads to the new schema. Disabling it switches reads back to the old schema
+	Enabled *bool `json:"enabled" validate:"required"`
+}
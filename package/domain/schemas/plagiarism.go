@@ -0,0 +1,22 @@
+package schemas
+
+import "time"
+
+// PlagiarismReport lists pairs of submissions found by the last plagiarism check of the task with
+// similarity of at least the threshold, most similar first
+type PlagiarismReport struct {
+	TaskId      int64             `json:"task_id"`
+	Threshold   float64           `json:"threshold"`
+	Submissions int64             `json:"submissions"` // Number of submissions compared by the check
+	CheckedAt   time.Time         `json:"checked_at"`
+	CheckedBy   int64             `json:"checked_by"`
+	Matches     []PlagiarismMatch `json:"matches"`
+}
+
+type PlagiarismMatch struct {
+	FirstSubmissionId  int64   `json:"first_submission_id"`
+	FirstUserId        int64   `json:"first_user_id"`
+	SecondSubmissionId int64   `json:"second_submission_id"`
+	SecondUserId       int64   `json:"second_user_id"`
+	Similarity         float64 `json:"similarity"` // Share of fingerprints the sources have in common, from 0 to 1
+}
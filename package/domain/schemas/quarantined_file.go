@@ -0,0 +1,15 @@
+package schemas
+
+import "time"
+
+// QuarantinedFile is an upload rejected by the file scanner
+type QuarantinedFile struct {
+	Id         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	Filename   string    `json:"filename"`
+	Sha256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	Signature  string    `json:"signature"`
+	UploadedBy int64     `json:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -1,16 +1,41 @@
 package schemas
 
+import "time"
+
 const (
 	SubmissionStatusQueued = "queued"
 )
 
 type QueueMessage struct {
-	MessageId       string    `json:"message_id"`
-	TaskId          int64     `json:"task_id"`
-	UserId          int64     `json:"user_id"`
-	SumissionNumber int64     `json:"submission_number"`
-	LanguageType    string    `json:"language_type"`
-	LanguageVersion string    `json:"language_version"`
-	TimeLimits      []float64 `json:"time_limits"`
-	MemoryLimits    []float64 `json:"memory_limits"`
+	MessageId       string `json:"message_id"`
+	TaskId          int64  `json:"task_id"`
+	UserId          int64  `json:"user_id"`
+	SumissionNumber int64  `json:"submission_number"`
+	LanguageType    string `json:"language_type"`
+	LanguageVersion string `json:"language_version"`
+	// compile or interpret
+	ExecutionMode string `json:"execution_mode"`
+	CompilerFlags string `json:"compiler_flags"`
+	RunArgs       string `json:"run_args"`
+	// Limits of the task multiplied by the multipliers of the language
+	TimeLimits   []float64 `json:"time_limits"`
+	MemoryLimits []float64 `json:"memory_limits"`
+	// Evaluation policy of the task, the same for every test
+	OutputLimit  int64 `json:"output_limit"` // Kilobytes
+	StderrLimit  int64 `json:"stderr_limit"` // Kilobytes
+	ProcessLimit int64 `json:"process_limit"`
+}
+
+// QueueFailure is a submission the worker failed to evaluate on every attempt
+type QueueFailure struct {
+	Id           int64  `json:"id"`
+	MessageId    string `json:"message_id"` // Last message published for the submission
+	SubmissionId int64  `json:"submission_id"`
+	QueueName    string `json:"queue_name"`
+	Attempts     int64  `json:"attempts"`
+	// Message of the last internal error reported by the worker
+	Error      string     `json:"error"`
+	FailedAt   time.Time  `json:"failed_at"`
+	RequeuedAt *time.Time `json:"requeued_at"` // Null until an admin requeues the submission
+	RequeuedBy *int64     `json:"requeued_by"`
 }
@@ -0,0 +1,15 @@
+package schemas
+
+import "time"
+
+// RejudgeBatch reports progress of rejudged submissions. FinishedAt is null until all of them were judged
+type RejudgeBatch struct {
+	Id         int64      `json:"id"`
+	TaskId     int64      `json:"task_id"`
+	CreatedBy  int64      `json:"created_by"`
+	Total      int64      `json:"total"`
+	Completed  int64      `json:"completed"`
+	Failed     int64      `json:"failed"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
@@ -11,6 +11,10 @@ type Result struct {
 	Code        string       `json:"Code"`
 	Message     string       `json:"Message"`
 	TestResults []TestResult `json:"TestResults"`
+	// Environment the submission was judged in, not sent by older workers
+	WorkerVersion      string `json:"WorkerVersion"`
+	CompilerVersion    string `json:"CompilerVersion"`
+	SandboxImageDigest string `json:"SandboxImageDigest"`
 }
 
 type TestResult struct {
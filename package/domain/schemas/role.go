@@ -0,0 +1,35 @@
+package schemas
+
+// Permission allows an action on a resource. With the own scope it applies to resources of the user, such as tasks
+// they created, with the all scope to every resource
+type Permission struct {
+	Resource string `json:"resource" validate:"required,max=30"`
+	Action   string `json:"action" validate:"required,max=30"`
+	Scope    string `json:"scope" validate:"required,oneof=own all"`
+}
+
+// Role is a built-in role or a custom role defined by admins. Built-in roles have no id and cannot be changed
+type Role struct {
+	Id          int64        `json:"id,omitempty"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	BuiltIn     bool         `json:"built_in"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type RoleCreate struct {
+	Name        string       `json:"name" validate:"required,gte=3,lte=30,username,lowercase"`
+	Description string       `json:"description" validate:"max=255"`
+	Permissions []Permission `json:"permissions" validate:"max=100,dive"`
+}
+
+// RoleUpdate replaces the description and permissions of a custom role. Users of the role are affected right away
+type RoleUpdate struct {
+	Description string       `json:"description" validate:"max=255"`
+	Permissions []Permission `json:"permissions" validate:"max=100,dive"`
+}
+
+// UserRoleEdit assigns a built-in or custom role to a user
+type UserRoleEdit struct {
+	Role string `json:"role" validate:"required,max=30"`
+}
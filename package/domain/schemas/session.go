@@ -7,9 +7,15 @@ type Session struct {
 	UserId    int64     `json:"user_id"`
 	UserRole  string    `json:"user_role"`
 	ExpiresAt time.Time `json:"expires_at"`
+	// Set on login, register and refresh only. The refresh token is shown once
+	RefreshToken          string     `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt *time.Time `json:"refresh_token_expires_at,omitempty"`
+	// Id of the admin acting as the user, set for impersonation sessions only
+	ImpersonatedBy *int64 `json:"impersonated_by,omitempty"`
 }
 
 type ValidateSessionResponse struct {
-	Valid  bool  `json:"valid"`
-	UserId int64 `json:"user_id"`
+	Valid          bool   `json:"valid"`
+	UserId         int64  `json:"user_id"`
+	ImpersonatedBy *int64 `json:"impersonated_by,omitempty"`
 }
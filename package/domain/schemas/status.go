@@ -0,0 +1,82 @@
+package schemas
+
+import "time"
+
+type Status struct {
+	Components   []ComponentStatus `json:"components"`
+	JudgeLatency JudgeLatency      `json:"judge_latency"`
+	Incidents    []Incident        `json:"incidents"`
+}
+
+type ComponentStatus struct {
+	Name string `json:"name"`
+	// Either "operational" or "unavailable"
+	Status string `json:"status"`
+}
+
+type JudgeLatency struct {
+	Percentile    int `json:"percentile"`
+	WindowMinutes int `json:"window_minutes"`
+	// Seconds from submitting to checking a submission, null if nothing was checked in the window
+	Seconds *float64 `json:"seconds"`
+}
+
+type Incident struct {
+	Id         int64            `json:"id"`
+	Title      string           `json:"title"`
+	Status     string           `json:"status"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ResolvedAt *time.Time       `json:"resolved_at"`
+	Updates    []IncidentUpdate `json:"updates"`
+}
+
+type IncidentUpdate struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type IncidentCreate struct {
+	Title   string `json:"title" validate:"required,max=255"`
+	Status  string `json:"status" validate:"required,oneof=investigating identified monitoring resolved"`
+	Message string `json:"message" validate:"required,max=5000"`
+}
+
+type IncidentUpdateCreate struct {
+	Status  string `json:"status" validate:"required,oneof=investigating identified monitoring resolved"`
+	Message string `json:"message" validate:"required,max=5000"`
+}
+
+// CapacitySimulationRequest describes the expected load of an exam
+type CapacitySimulationRequest struct {
+	Participants int `json:"participants" validate:"required,gt=0,lte=100000"`
+	// Submissions of a participant per hour
+	SubmissionRate  float64 `json:"submission_rate" validate:"required,gt=0,lte=600"`
+	DurationMinutes int     `json:"duration_minutes" validate:"required,gt=0,lte=1440"`
+	Workers         int     `json:"workers" validate:"required,gt=0,lte=1000"`
+	// Tests of the exam tasks. When empty each simulated submission runs as many tests as a recent one
+	TestsPerSubmission int `json:"tests_per_submission" validate:"omitempty,gt=0,lte=1000"`
+	// When set, the fewest workers keeping the 95th percentile of latency within it are recommended
+	TargetLatencySeconds float64 `json:"target_latency_seconds" validate:"omitempty,gt=0"`
+}
+
+type CapacitySimulation struct {
+	Submissions int `json:"submissions"`
+	// Number of recent submissions the judging times were sampled from
+	Samples            int     `json:"samples"`
+	MeanServiceSeconds float64 `json:"mean_service_seconds"`
+	// Expected share of time the workers are busy. At 1 or more the queue keeps growing
+	Utilization    float64 `json:"utilization"`
+	MaxQueueDepth  int     `json:"max_queue_depth"`
+	MeanQueueDepth float64 `json:"mean_queue_depth"`
+	// Seconds from submitting to checking a submission
+	Latency CapacityLatency `json:"latency"`
+	// Null when no target latency was given or it cannot be met
+	RecommendedWorkers *int `json:"recommended_workers"`
+}
+
+type CapacityLatency struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	Max float64 `json:"max"`
+}
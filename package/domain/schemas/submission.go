@@ -0,0 +1,114 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/package/utils"
+)
+
+type Submission struct {
+	Id            int64             `json:"id"`
+	TaskId        int64             `json:"task_id"`
+	UserId        int64             `json:"user_id"`
+	Order         int64             `json:"order"`
+	Language      LanguageConfig    `json:"language"`
+	Status        string            `json:"status"`
+	StatusMessage string            `json:"status_message"`
+	SubmittedAt   time.Time         `json:"submitted_at"`
+	CheckedAt     *time.Time        `json:"checked_at"`
+	Redacted      bool              `json:"redacted"` // Source was removed on a privacy request
+	Pruned        bool              `json:"pruned"`   // Source of a superseded failing attempt was removed to save storage
+	Result        *SubmissionResult `json:"result"`
+	ManualGrade   *ManualGrade      `json:"manual_grade"` // Given by a teacher, supersedes the judged score
+	// Outcomes of tests reported so far while the submission is processing
+	Progress []SubmissionTestProgress `json:"progress,omitempty"`
+}
+
+// SubmissionSource is the source file of a submission, it is served as the file and not as JSON
+type SubmissionSource struct {
+	FileName        string
+	ContentType     string
+	Language        string
+	LanguageVersion string
+	Content         []byte
+}
+
+type SubmissionTestProgress struct {
+	Order  int64 `json:"order"`
+	Passed bool  `json:"passed"`
+}
+
+type SubmissionResult struct {
+	Code        string                 `json:"code"`
+	Message     string                 `json:"message"`
+	PassedTests int64                  `json:"passed_tests"`
+	TotalTests  int64                  `json:"total_tests"`
+	Score       float64                `json:"score"`
+	CreatedAt   time.Time              `json:"created_at"`
+	TestResults []SubmissionTestResult `json:"test_results"`
+	// Environment is only included for teachers and admins
+	Environment *JudgeEnvironment `json:"environment,omitempty"`
+}
+
+type JudgeEnvironment struct {
+	WorkerVersion      string `json:"worker_version"`
+	CompilerVersion    string `json:"compiler_version"`
+	SandboxImageDigest string `json:"sandbox_image_digest"`
+}
+
+// JudgeEnvironmentFilter selects submissions judged in an environment. At least one field has to be set,
+// empty fields match any value
+type JudgeEnvironmentFilter struct {
+	WorkerVersion      string `json:"worker_version"`
+	CompilerVersion    string `json:"compiler_version"`
+	SandboxImageDigest string `json:"sandbox_image_digest"`
+}
+
+func (f JudgeEnvironmentFilter) StructRules(sl validator.StructLevel) {
+	if f.WorkerVersion == "" && f.CompilerVersion == "" && f.SandboxImageDigest == "" {
+		sl.ReportError(f.WorkerVersion, "worker_version", "WorkerVersion", "required_without_all", "compiler_version sandbox_image_digest")
+	}
+}
+
+func init() {
+	utils.RegisterStructRules(JudgeEnvironmentFilter{})
+}
+
+type SubmissionTestResult struct {
+	Order        int    `json:"order"`
+	Passed       bool   `json:"passed"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type ScoreRecomputeRequest struct {
+	// DryRun only reports how many results would change. Defaults to true
+	DryRun    *bool `json:"dry_run"`
+	BatchSize int   `json:"batch_size" validate:"batch_size"`
+}
+
+type ScoreRecomputeReport struct {
+	DryRun    bool  `json:"dry_run"`
+	Processed int64 `json:"processed"`
+	Changed   int64 `json:"changed"`
+}
+
+// TimelineEventCreated is the first event of every timeline, the time the submission was created
+const TimelineEventCreated = "created"
+
+// SubmissionTimeline are the lifecycle events of a submission in the order they happened
+type SubmissionTimeline struct {
+	SubmissionId int64           `json:"submission_id"`
+	Status       string          `json:"status"`
+	Events       []TimelineEvent `json:"events"`
+}
+
+type TimelineEvent struct {
+	// One of created, enqueued, picked_up, test, worker_error, failed and finalized
+	Type      string    `json:"type"`
+	MessageId string    `json:"message_id,omitempty"` // Queue message of the attempt the event belongs to
+	TestOrder *int64    `json:"test_order,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ElapsedMs int64     `json:"elapsed_ms"` // Milliseconds since the submission was created
+}
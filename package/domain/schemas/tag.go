@@ -0,0 +1,17 @@
+package schemas
+
+type Tag struct {
+	Id        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedBy int64  `json:"created_by"`
+}
+
+// TagCreate creates a tag. Names are trimmed and lowercased
+type TagCreate struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+// TaskTagsEdit replaces the tags of a task with the existing tags of the names
+type TaskTagsEdit struct {
+	Tags []string `json:"tags" validate:"max=20,dive,required,max=50"`
+}
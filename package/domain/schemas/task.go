@@ -3,25 +3,224 @@ package schemas
 import "time"
 
 type UpdateTask struct {
-	Title string `json:"title"`
+	Title string `json:"title" validate:"max=255"`
+	// Difficulty is left unchanged when empty and cleared with none
+	Difficulty string `json:"difficulty" validate:"omitempty,oneof=easy medium hard none"`
+	// Version of the task the edit is based on
+	Version int64 `json:"version" validate:"required,gt=0"`
 }
 
 type Task struct {
+	Id         int64     `json:"id"`
+	Title      string    `json:"title"`
+	CreatedBy  int64     `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	Difficulty string    `json:"difficulty"` // easy, medium or hard, empty until the author rates the task
+	Tags       []string  `json:"tags"`
+	Bookmarked bool      `json:"bookmarked"`
+	HasNote    bool      `json:"has_note"`
+	// Set when the title or limits of the task changed during the term in progress
+	Updated bool `json:"updated"`
+	// Progress of the current user, null until a submission of the user is judged
+	Progress *TaskProgress `json:"progress"`
+	// Verdicts of submissions of all users, null until a submission of the task is judged or when a group
+	// of the user hides statistics of the task
+	Verdicts *TaskVerdicts `json:"verdicts"`
+}
+
+// TaskChange is a change of a field of the task, such as a limit. Fields are title, output_limit,
+// stderr_limit and process_limit. Limits reset to the default have the value default
+type TaskChange struct {
 	Id        int64     `json:"id"`
-	Title     string    `json:"title"`
-	CreatedBy int64     `json:"created_by"`
+	ChangedBy int64     `json:"changed_by"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type TaskProgress struct {
+	Attempts    int64   `json:"attempts"` // Judged submissions
+	BestScore   float64 `json:"best_score"`
+	LastVerdict string  `json:"last_verdict"` // Result code of the latest judged submission
+}
+
+// TaskVerdicts counts judged submissions of a task by the result code of their latest result
+type TaskVerdicts struct {
+	Submissions    int64            `json:"submissions"`
+	AcceptanceRate float64          `json:"acceptance_rate"` // Percentage of submissions with full score
+	Codes          map[string]int64 `json:"codes"`
+}
+
 type TaskDetailed struct {
-	Id             int64     `json:"id"`
-	Title          string    `json:"title"`
-	DescriptionURL string    `json:"description_url"`
-	CreatedBy      int64     `json:"created_by"`
-	CreatedByName  string    `json:"created_by_name"`
-	CreatedAt      time.Time `json:"created_at"`
+	Id             int64          `json:"id"`
+	Title          string         `json:"title"`
+	DescriptionURL string         `json:"description_url"`
+	CreatedBy      int64          `json:"created_by"`
+	CreatedByName  string         `json:"created_by_name"`
+	CoAuthors      []TaskCoAuthor `json:"co_authors"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Sandbox        bool           `json:"sandbox"`
+	Version        int64          `json:"version"`
+	TestVisibility string         `json:"test_visibility"` // How much of the test results students see: all, first_failed or hidden
+	// Which sources of old submissions are kept: default (the platform policy), all or best_and_latest
+	SubmissionRetention string   `json:"submission_retention"`
+	Difficulty          string   `json:"difficulty"`
+	Tags                []string `json:"tags"`
 }
 
 type TaskCreateResponse struct {
 	Id int64 `json:"id"`
 }
+
+// TaskFilter narrows down task listings. JSON names are those of the query parameters, which validation
+// errors refer to
+type TaskFilter struct {
+	// Return only tasks bookmarked by the requesting user
+	Bookmarked bool
+	// Return only tasks having every one of the tags
+	Tags       []string `json:"tag" validate:"max=20,dive,required,max=50"`
+	Difficulty string   `json:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	// Return only tasks with the text in their title or a tag name, case insensitive
+	Search string `json:"search" validate:"max=100"`
+}
+
+type TaskNote struct {
+	TaskId    int64     `json:"task_id"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TaskNoteEdit struct {
+	Content string `json:"content" validate:"required,max=10000"`
+}
+
+type TaskDraft struct {
+	TaskId     int64     `json:"task_id"`
+	LanguageId int64     `json:"language_id"`
+	Content    string    `json:"content"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// ExpiresAt is when the draft is deleted unless it is saved again
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TaskDraftEdit struct {
+	LanguageId int64  `json:"language_id" validate:"required,gt=0"`
+	Content    string `json:"content" validate:"max=65536"`
+}
+
+type TaskCoAuthor struct {
+	UserId      int64  `json:"user_id"`
+	DisplayName string `json:"display_name"`
+}
+
+type TaskCoAuthorEdit struct {
+	UserId int64 `json:"user_id" validate:"required,gt=0"`
+	// DisplayName defaults to the name and surname of the user
+	DisplayName string `json:"display_name" validate:"omitempty,max=255"`
+}
+
+// TaskCoAuthorsEdit replaces the co-authors of a task. Co-authors are displayed in the given order
+type TaskCoAuthorsEdit struct {
+	CoAuthors []TaskCoAuthorEdit `json:"co_authors" validate:"max=20,dive"`
+}
+
+// TestCaseGroup is a subtask worth Points, awarded when every test of the group passes. Tests are orders of the task tests
+type TestCaseGroup struct {
+	Id     int64   `json:"id"`
+	Name   string  `json:"name"`
+	Points int64   `json:"points"`
+	Tests  []int64 `json:"tests"`
+}
+
+type TestCaseGroupEdit struct {
+	Name   string  `json:"name" validate:"required,max=100"`
+	Points int64   `json:"points" validate:"gt=0,lte=1000"`
+	Tests  []int64 `json:"tests" validate:"required,min=1,max=500,dive,gt=0"`
+}
+
+// TestCaseGroupsEdit replaces the test groups of a task. Without groups the task is scored by the ratio of passed tests
+type TestCaseGroupsEdit struct {
+	Groups []TestCaseGroupEdit `json:"groups" validate:"max=50,dive"`
+}
+
+// TaskEvaluationPolicy is how tests of a task are evaluated. Limits are the effective ones, Max are the
+// highest limits the platform accepts
+type TaskEvaluationPolicy struct {
+	OutputLimit  int64                  `json:"output_limit"` // Kilobytes of standard output kept per test
+	StderrLimit  int64                  `json:"stderr_limit"` // Kilobytes of standard error captured per test
+	ProcessLimit int64                  `json:"process_limit"`
+	Max          EvaluationPolicyLimits `json:"max"`
+}
+
+type EvaluationPolicyLimits struct {
+	OutputLimit  int64 `json:"output_limit"`
+	StderrLimit  int64 `json:"stderr_limit"`
+	ProcessLimit int64 `json:"process_limit"`
+}
+
+// TaskEvaluationPolicyEdit replaces the evaluation policy of a task. Null limits are reset to the defaults of the platform
+type TaskEvaluationPolicyEdit struct {
+	OutputLimit  *int64 `json:"output_limit" validate:"omitempty,gt=0"`
+	StderrLimit  *int64 `json:"stderr_limit" validate:"omitempty,gt=0"`
+	ProcessLimit *int64 `json:"process_limit" validate:"omitempty,gt=0"`
+}
+
+// TaskSandboxEdit adds a task to or removes it from the public sandbox
+type TaskSandboxEdit struct {
+	Sandbox *bool `json:"sandbox" validate:"required"`
+}
+
+// TaskTestVisibilityEdit sets how much of the test results of their submissions students see
+type TaskTestVisibilityEdit struct {
+	TestVisibility string `json:"test_visibility" validate:"required,oneof=all first_failed hidden"`
+}
+
+// TaskSubmissionRetentionEdit sets which sources of old submissions to the task are kept
+type TaskSubmissionRetentionEdit struct {
+	SubmissionRetention string `json:"submission_retention" validate:"required,oneof=default all best_and_latest"`
+}
+
+// TaskPool is a pool of equivalent tasks, every student is assigned one of them
+type TaskPool struct {
+	Id        int64     `json:"id"`
+	Title     string    `json:"title"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	TaskIds   []int64   `json:"task_ids"`
+}
+
+type TaskPoolCreate struct {
+	Title   string  `json:"title" validate:"required,max=255"`
+	TaskIds []int64 `json:"task_ids" validate:"min=2,max=50,unique,dive,gt=0"`
+}
+
+// TaskStats aggregates submissions of a task within a term, or of all time when Term is null
+type TaskStats struct {
+	TaskId      int64 `json:"task_id"`
+	Term        *Term `json:"term"`
+	Attempts    int64 `json:"attempts"`
+	Users       int64 `json:"users"`
+	Accepted    int64 `json:"accepted"`
+	SolvedUsers int64 `json:"solved_users"`
+	// Percentage of attempts that passed all tests
+	AcceptanceRate float64 `json:"acceptance_rate"`
+	// Only submissions of the current user are counted, as a group of the user hides statistics of the task
+	OwnOnly bool `json:"own_only"`
+}
+
+// TaskManifest describes a task in a portable task archive, next to its files in the format tasks are uploaded in
+type TaskManifest struct {
+	FormatVersion    int                      `json:"format_version"`
+	Title            string                   `json:"title" validate:"required,max=255"`
+	EvaluationPolicy TaskEvaluationPolicyEdit `json:"evaluation_policy"`
+	Tests            []TaskManifestTest       `json:"tests" validate:"max=500,dive"`
+	TestGroups       []TestCaseGroupEdit      `json:"test_groups" validate:"max=50,dive"`
+}
+
+// TaskManifestTest is a test of a task with its limits
+type TaskManifestTest struct {
+	Order       int     `json:"order" validate:"gte=0"`
+	TimeLimit   float64 `json:"time_limit" validate:"gt=0"`
+	MemoryLimit float64 `json:"memory_limit" validate:"gt=0"`
+}
@@ -0,0 +1,16 @@
+package schemas
+
+import "time"
+
+type Term struct {
+	Id       int64     `json:"id"`
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+type TermCreate struct {
+	Name     string    `json:"name" validate:"required,max=100"`
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+}
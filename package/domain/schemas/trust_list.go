@@ -0,0 +1,22 @@
+package schemas
+
+import "time"
+
+type TrustListEntry struct {
+	Id   int64  `json:"id"`
+	Kind string `json:"kind"`
+	// CIDR range or user id. Empty for API keys, which are only shown once when created
+	Value       string    `json:"value"`
+	Description string    `json:"description"`
+	CreatedBy   int64     `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Generated API key, set only in the response to creating an api_key entry
+	ApiKey string `json:"api_key,omitempty"`
+}
+
+type TrustListEntryCreate struct {
+	Kind string `json:"kind" validate:"required,oneof=ip_range api_key user_id"`
+	// CIDR range or single IP for ip_range, user id for user_id, empty for api_key
+	Value       string `json:"value" validate:"max=255"`
+	Description string `json:"description" validate:"max=255"`
+}
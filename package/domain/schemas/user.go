@@ -1,5 +1,7 @@
 package schemas
 
+import "time"
+
 type User struct {
 	Id       int64  `json:"id"`
 	Name     string `json:"name"`
@@ -7,6 +9,34 @@ type User struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Status   string `json:"status"` // active, deactivated or banned
+	// Permissions of a custom role, resolved when the user is authenticated. Built-in roles use the permission matrix
+	Permissions []Permission `json:"-" gorm:"-"`
+}
+
+// UserStatusEdit deactivates, bans or reactivates a user. Reason is kept for deactivated and banned users
+type UserStatusEdit struct {
+	Status string `json:"status" validate:"required,oneof=active deactivated banned"`
+	Reason string `json:"reason,omitempty" validate:"max=500"`
+}
+
+type AuditLogEntry struct {
+	Id           int64     `json:"id"`
+	ActorId      int64     `json:"actor_id"`
+	Action       string    `json:"action"`
+	TargetUserId int64     `json:"target_user_id"`
+	Detail       string    `json:"detail"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserCapabilities are the actions the current user may perform, so clients can render menus without
+// repeating the role checks of the backend
+type UserCapabilities struct {
+	Role    string   `json:"role"`
+	Actions []string `json:"actions"`
+	// Tasks created by the user. Task actions such as check_plagiarism apply to these, or to every task with all_tasks
+	AuthoredTasks int64 `json:"authored_tasks"`
+	AllTasks      bool  `json:"all_tasks"` // Users who may edit every task, such as admins
 }
 
 type UserEdit struct {
@@ -15,3 +45,30 @@ type UserEdit struct {
 	Email    *string `json:"email,omitempty"`
 	Username *string `json:"username,omitempty"`
 }
+
+// UserImportRow is a row of a user import file. Password is optional, one is generated when it is empty
+type UserImportRow struct {
+	Name     string `json:"name" validate:"person_name"`
+	Surname  string `json:"surname" validate:"person_name"`
+	Email    string `json:"email" validate:"required,email"`
+	Username string `json:"username" validate:"required,gte=3,lte=30,username"`
+	Role     string `json:"role" validate:"required,oneof=student teacher admin"`
+	Password string `json:"password" validate:"omitempty,password"`
+}
+
+type UserImportRowResult struct {
+	// Row is the line number in the file, the header is line 1
+	Row      int    `json:"row"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	UserId   int64  `json:"user_id,omitempty"`
+	// Password is set only when it was generated, it is not stored and cannot be retrieved again
+	Password string `json:"password,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type UserImportReport struct {
+	Created int                   `json:"created"`
+	Failed  int                   `json:"failed"`
+	Rows    []UserImportRowResult `json:"rows"`
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type ActivityRepository interface {
+	// GetTaskActivity returns events on tasks created by the author, or on all tasks when authorId is nil,
+	// newest first
+	GetTaskActivity(tx *gorm.DB, authorId *int64, limit, offset int64) ([]models.ActivityEvent, error)
+}
+
+type ActivityRepositoryImpl struct{}
+
+func (ar *ActivityRepositoryImpl) GetTaskActivity(tx *gorm.DB, authorId *int64, limit, offset int64) ([]models.ActivityEvent, error) {
+	filter := "TRUE"
+	args := []interface{}{}
+	if authorId != nil {
+		filter = "tasks.created_by = ?"
+		args = append(args, *authorId)
+	}
+	query := `SELECT CAST(? AS varchar) AS kind, submissions.id AS resource_id, submissions.task_id, tasks.title AS task_title,
+			submissions.user_id AS actor_id, submissions.submitted_at AS occurred_at
+		FROM submissions JOIN tasks ON tasks.id = submissions.task_id WHERE ` + filter + `
+		UNION ALL
+		SELECT CAST(? AS varchar) AS kind, rejudge_batches.id AS resource_id, rejudge_batches.task_id, tasks.title AS task_title,
+			rejudge_batches.created_by AS actor_id, rejudge_batches.created_at AS occurred_at
+		FROM rejudge_batches JOIN tasks ON tasks.id = rejudge_batches.task_id WHERE ` + filter + `
+		UNION ALL
+		SELECT CASE WHEN task_co_author_changes.added THEN CAST(? AS varchar) ELSE CAST(? AS varchar) END AS kind,
+			task_co_author_changes.user_id AS resource_id, task_co_author_changes.task_id, tasks.title AS task_title,
+			task_co_author_changes.changed_by AS actor_id, task_co_author_changes.changed_at AS occurred_at
+		FROM task_co_author_changes JOIN tasks ON tasks.id = task_co_author_changes.task_id WHERE ` + filter + `
+		ORDER BY occurred_at DESC, resource_id DESC LIMIT ? OFFSET ?`
+	queryArgs := []interface{}{models.ActivityKindSubmission}
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, models.ActivityKindRejudge)
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, models.ActivityKindCoAuthorAdded, models.ActivityKindCoAuthorRemoved)
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, limit, offset)
+
+	var events []models.ActivityEvent
+	err := tx.Raw(query, queryArgs...).Scan(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func NewActivityRepository() ActivityRepository {
+	return &ActivityRepositoryImpl{}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AnnouncementRepository interface {
+	CreateAnnouncement(tx *gorm.DB, announcement *models.Announcement) (int64, error)
+	// GetVisibleAnnouncements returns announcements published to the user at the given time which did not expire,
+	// newest first. With unreadOnly announcements the user read are left out
+	GetVisibleAnnouncements(tx *gorm.DB, userId int64, at time.Time, unreadOnly bool, limit, offset int64) ([]models.Announcement, error)
+	// GetGroupAnnouncements returns announcements to the group published by the given time, expired ones included,
+	// newest first
+	GetGroupAnnouncements(tx *gorm.DB, groupId int64, at time.Time, limit, offset int64) ([]models.Announcement, error)
+	// CountUnread returns the number of announcements visible to the user at the given time which the user did not read
+	CountUnread(tx *gorm.DB, userId int64, at time.Time) (int64, error)
+	// IsVisible reports whether the announcement is visible to the user at the given time
+	IsVisible(tx *gorm.DB, announcementId int64, userId int64, at time.Time) (bool, error)
+	// GetReadTimes returns when the user read each of the announcements, unread ones are left out
+	GetReadTimes(tx *gorm.DB, userId int64, announcementIds []int64) (map[int64]time.Time, error)
+	// MarkRead records that the user read the announcement. Announcements read earlier keep the time they were first read
+	MarkRead(tx *gorm.DB, announcementId int64, userId int64) error
+}
+
+type AnnouncementRepositoryImpl struct{}
+
+func (ar *AnnouncementRepositoryImpl) CreateAnnouncement(tx *gorm.DB, announcement *models.Announcement) (int64, error) {
+	err := tx.Create(announcement).Error
+	if err != nil {
+		return 0, err
+	}
+	return announcement.Id, nil
+}
+
+// visibleAnnouncements limits the query to announcements visible to the user at the given time
+func visibleAnnouncements(tx *gorm.DB, userId int64, at time.Time) *gorm.DB {
+	return tx.Model(&models.Announcement{}).
+		Where("announcements.published_at <= ? AND (announcements.expires_at IS NULL OR announcements.expires_at > ?)", at, at).
+		Where("announcements.group_id IS NULL OR announcements.group_id IN (SELECT group_id FROM user_groups WHERE user_id = ?)", userId)
+}
+
+func unreadAnnouncements(tx *gorm.DB, userId int64) *gorm.DB {
+	return tx.Where("NOT EXISTS (SELECT 1 FROM announcement_reads WHERE announcement_reads.announcement_id = announcements.id AND announcement_reads.user_id = ?)", userId)
+}
+
+func (ar *AnnouncementRepositoryImpl) GetVisibleAnnouncements(tx *gorm.DB, userId int64, at time.Time, unreadOnly bool, limit, offset int64) ([]models.Announcement, error) {
+	announcements := []models.Announcement{}
+	query := visibleAnnouncements(tx, userId, at)
+	if unreadOnly {
+		query = unreadAnnouncements(query, userId)
+	}
+	err := query.Order("announcements.published_at DESC, announcements.id DESC").
+		Limit(int(limit)).Offset(int(offset)).Find(&announcements).Error
+	if err != nil {
+		return nil, err
+	}
+	return announcements, nil
+}
+
+func (ar *AnnouncementRepositoryImpl) GetGroupAnnouncements(tx *gorm.DB, groupId int64, at time.Time, limit, offset int64) ([]models.Announcement, error) {
+	announcements := []models.Announcement{}
+	err := tx.Where("group_id = ? AND published_at <= ?", groupId, at).
+		Order("published_at DESC, id DESC").
+		Limit(int(limit)).Offset(int(offset)).Find(&announcements).Error
+	if err != nil {
+		return nil, err
+	}
+	return announcements, nil
+}
+
+func (ar *AnnouncementRepositoryImpl) CountUnread(tx *gorm.DB, userId int64, at time.Time) (int64, error) {
+	var count int64
+	err := unreadAnnouncements(visibleAnnouncements(tx, userId, at), userId).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (ar *AnnouncementRepositoryImpl) IsVisible(tx *gorm.DB, announcementId int64, userId int64, at time.Time) (bool, error) {
+	var count int64
+	err := visibleAnnouncements(tx, userId, at).Where("announcements.id = ?", announcementId).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (ar *AnnouncementRepositoryImpl) GetReadTimes(tx *gorm.DB, userId int64, announcementIds []int64) (map[int64]time.Time, error) {
+	var reads []models.AnnouncementRead
+	err := tx.Where("user_id = ? AND announcement_id IN ?", userId, announcementIds).Find(&reads).Error
+	if err != nil {
+		return nil, err
+	}
+	readTimes := make(map[int64]time.Time, len(reads))
+	for _, read := range reads {
+		readTimes[read.AnnouncementId] = read.ReadAt
+	}
+	return readTimes, nil
+}
+
+func (ar *AnnouncementRepositoryImpl) MarkRead(tx *gorm.DB, announcementId int64, userId int64) error {
+	read := models.AnnouncementRead{AnnouncementId: announcementId, UserId: userId, ReadAt: time.Now()}
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&read).Error
+	return err
+}
+
+func NewAnnouncementRepository(db *gorm.DB) (AnnouncementRepository, error) {
+	tables := []interface{}{&models.Announcement{}, &models.AnnouncementRead{}}
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			err := db.Migrator().CreateTable(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &AnnouncementRepositoryImpl{}, nil
+}
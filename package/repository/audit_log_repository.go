@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository interface {
+	CreateEntry(tx *gorm.DB, entry *models.AuditLogEntry) error
+	// GetEntries returns entries of the audit log, newest first. With a target user only entries about that user are returned
+	GetEntries(tx *gorm.DB, targetUserId *int64, limit, offset int64) ([]models.AuditLogEntry, error)
+}
+
+type AuditLogRepositoryImpl struct{}
+
+func (ar *AuditLogRepositoryImpl) CreateEntry(tx *gorm.DB, entry *models.AuditLogEntry) error {
+	return tx.Create(entry).Error
+}
+
+func (ar *AuditLogRepositoryImpl) GetEntries(tx *gorm.DB, targetUserId *int64, limit, offset int64) ([]models.AuditLogEntry, error) {
+	entries := []models.AuditLogEntry{}
+	query := tx.Model(&models.AuditLogEntry{})
+	if targetUserId != nil {
+		query = query.Where("target_user_id = ?", *targetUserId)
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(int(limit)).Offset(int(offset)).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func NewAuditLogRepository(db *gorm.DB) (AuditLogRepository, error) {
+	if !db.Migrator().HasTable(&models.AuditLogEntry{}) {
+		err := db.Migrator().CreateTable(&models.AuditLogEntry{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &AuditLogRepositoryImpl{}, nil
+}
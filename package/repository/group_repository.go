@@ -9,6 +9,11 @@ type GroupRepository interface {
 	CreateGroup(tx *gorm.DB, group models.Group) (int64, error)
 	GetGroup(tx *gorm.DB, groupId int64) (*models.Group, error)
 	DeleteGroup(tx *gorm.DB, groupId int64) error
+	// GetGroupMembers returns users of the group ordered by surname and name
+	GetGroupMembers(tx *gorm.DB, groupId int64) ([]models.User, error)
+	// SetTaskStatsHidden sets whether members of the group see statistics of the task assigned to the group.
+	// Returns gorm.ErrRecordNotFound when the task is not assigned to the group
+	SetTaskStatsHidden(tx *gorm.DB, groupId int64, taskId int64, hidden bool) error
 }
 
 type GroupRepositoryImpl struct {
@@ -39,6 +44,31 @@ func (gr *GroupRepositoryImpl) DeleteGroup(tx *gorm.DB, groupId int64) error {
 	return nil
 }
 
+func (gr *GroupRepositoryImpl) GetGroupMembers(tx *gorm.DB, groupId int64) ([]models.User, error) {
+	var users []models.User
+	err := tx.Joins("JOIN user_groups ON user_groups.user_id = users.id").
+		Where("user_groups.group_id = ?", groupId).
+		Order("users.surname, users.name, users.id").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (gr *GroupRepositoryImpl) SetTaskStatsHidden(tx *gorm.DB, groupId int64, taskId int64, hidden bool) error {
+	result := tx.Model(&models.TaskGroup{}).
+		Where("group_id = ? AND task_id = ?", groupId, taskId).
+		Update("hide_stats", hidden)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func NewGroupRepository(db *gorm.DB) (GroupRepository, error) {
 	tables := []interface{}{&models.Group{}, &models.UserGroup{}, &models.TaskGroup{}}
 	for _, table := range tables {
@@ -49,5 +79,11 @@ func NewGroupRepository(db *gorm.DB) (GroupRepository, error) {
 			}
 		}
 	}
+	if !db.Migrator().HasColumn(&models.TaskGroup{}, "HideStats") {
+		err := db.Migrator().AddColumn(&models.TaskGroup{}, "HideStats")
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &GroupRepositoryImpl{}, nil
 }
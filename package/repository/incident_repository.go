@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type IncidentRepository interface {
+	CreateIncident(tx *gorm.DB, incident *models.Incident) (int64, error)
+	GetIncident(tx *gorm.DB, incidentId int64) (*models.Incident, error)
+	UpdateIncident(tx *gorm.DB, incident *models.Incident) error
+	CreateIncidentUpdate(tx *gorm.DB, update *models.IncidentUpdate) error
+	// GetRecentIncidents returns unresolved incidents and ones created after since, newest first, with their updates
+	GetRecentIncidents(tx *gorm.DB, since time.Time, limit int) ([]models.Incident, error)
+}
+
+type IncidentRepositoryImpl struct{}
+
+func (ir *IncidentRepositoryImpl) CreateIncident(tx *gorm.DB, incident *models.Incident) (int64, error) {
+	err := tx.Create(incident).Error
+	if err != nil {
+		return 0, err
+	}
+	return incident.Id, nil
+}
+
+func (ir *IncidentRepositoryImpl) GetIncident(tx *gorm.DB, incidentId int64) (*models.Incident, error) {
+	incident := &models.Incident{}
+	err := tx.Preload("Updates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC, id DESC")
+	}).Where("id = ?", incidentId).First(incident).Error
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+func (ir *IncidentRepositoryImpl) UpdateIncident(tx *gorm.DB, incident *models.Incident) error {
+	err := tx.Model(&models.Incident{}).Where("id = ?", incident.Id).Updates(map[string]interface{}{
+		"status":      incident.Status,
+		"resolved_at": incident.ResolvedAt,
+	}).Error
+	return err
+}
+
+func (ir *IncidentRepositoryImpl) CreateIncidentUpdate(tx *gorm.DB, update *models.IncidentUpdate) error {
+	err := tx.Create(update).Error
+	return err
+}
+
+func (ir *IncidentRepositoryImpl) GetRecentIncidents(tx *gorm.DB, since time.Time, limit int) ([]models.Incident, error) {
+	var incidents []models.Incident
+	err := tx.Preload("Updates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC, id DESC")
+	}).Where("resolved_at IS NULL OR created_at >= ?", since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&incidents).Error
+	if err != nil {
+		return nil, err
+	}
+	return incidents, nil
+}
+
+func NewIncidentRepository(db *gorm.DB) (IncidentRepository, error) {
+	if !db.Migrator().HasTable(&models.Incident{}) {
+		err := db.Migrator().CreateTable(&models.Incident{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !db.Migrator().HasTable(&models.IncidentUpdate{}) {
+		err := db.Migrator().CreateTable(&models.IncidentUpdate{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &IncidentRepositoryImpl{}, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// OrphanReference describes a column which is expected to reference an existing row in another table
+type OrphanReference struct {
+	Table            string
+	Column           string
+	ReferencedTable  string
+	ReferencedColumn string
+}
+
+// notExistsCondition matches rows whose reference is set and points to a missing row
+func (or OrphanReference) notExistsCondition() string {
+	return fmt.Sprintf("%s.%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.%s)", or.Table, or.Column, or.ReferencedTable, or.ReferencedTable, or.ReferencedColumn, or.Table, or.Column)
+}
+
+type IntegrityRepository interface {
+	// TableExists reports whether the table exists. Some tables are only created for optional features,
+	// such as sessions, which are not stored in the database when Redis is used
+	TableExists(tx *gorm.DB, table string) (bool, error)
+	// CountOrphans returns the number of rows whose reference points to a missing row
+	CountOrphans(tx *gorm.DB, reference OrphanReference) (int64, error)
+	// DeleteOrphans deletes at most batchSize orphaned rows and returns the number of deleted rows
+	DeleteOrphans(tx *gorm.DB, reference OrphanReference, batchSize int) (int64, error)
+}
+
+type IntegrityRepositoryImpl struct{}
+
+func (ir *IntegrityRepositoryImpl) TableExists(tx *gorm.DB, table string) (bool, error) {
+	var exists bool
+	err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", table).Scan(&exists).Error
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (ir *IntegrityRepositoryImpl) CountOrphans(tx *gorm.DB, reference OrphanReference) (int64, error) {
+	var count int64
+	err := tx.Table(reference.Table).Where(reference.notExistsCondition()).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (ir *IntegrityRepositoryImpl) DeleteOrphans(tx *gorm.DB, reference OrphanReference, batchSize int) (int64, error) {
+	// A ctid is only unique within one partition, so rows of partitioned tables are matched with their partition
+	query := fmt.Sprintf("DELETE FROM %s WHERE (tableoid, ctid) IN (SELECT tableoid, ctid FROM %s WHERE %s LIMIT ?)", reference.Table, reference.Table, reference.notExistsCondition())
+	result := tx.Exec(query, batchSize)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func NewIntegrityRepository() IntegrityRepository {
+	return &IntegrityRepositoryImpl{}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type JudgeAuditRepository interface {
+	CreateAudit(tx *gorm.DB, audit *models.JudgeAudit) (int64, error)
+	GetAudit(tx *gorm.DB, auditId int64) (*models.JudgeAudit, error)
+	// CompleteAudit saves the actual verdict of the audit and whether it differs from the expected one
+	CompleteAudit(tx *gorm.DB, audit *models.JudgeAudit) error
+	// GetAudits returns audits newest first. With discrepanciesOnly only audits with a differing verdict are returned
+	GetAudits(tx *gorm.DB, discrepanciesOnly bool, limit, offset int64) ([]models.JudgeAudit, error)
+}
+
+type JudgeAuditRepositoryImpl struct{}
+
+func (jar *JudgeAuditRepositoryImpl) CreateAudit(tx *gorm.DB, audit *models.JudgeAudit) (int64, error) {
+	err := tx.Create(audit).Error
+	if err != nil {
+		return 0, err
+	}
+	return audit.Id, nil
+}
+
+func (jar *JudgeAuditRepositoryImpl) GetAudit(tx *gorm.DB, auditId int64) (*models.JudgeAudit, error) {
+	audit := &models.JudgeAudit{}
+	err := tx.Where("id = ?", auditId).First(audit).Error
+	if err != nil {
+		return nil, err
+	}
+	return audit, nil
+}
+
+func (jar *JudgeAuditRepositoryImpl) CompleteAudit(tx *gorm.DB, audit *models.JudgeAudit) error {
+	err := tx.Model(&models.JudgeAudit{}).Where("id = ?", audit.Id).Updates(map[string]interface{}{
+		"actual_code":  audit.ActualCode,
+		"actual_score": audit.ActualScore,
+		"discrepancy":  audit.Discrepancy,
+		"checked_at":   audit.CheckedAt,
+	}).Error
+	return err
+}
+
+func (jar *JudgeAuditRepositoryImpl) GetAudits(tx *gorm.DB, discrepanciesOnly bool, limit, offset int64) ([]models.JudgeAudit, error) {
+	var audits []models.JudgeAudit
+	query := tx.Model(&models.JudgeAudit{})
+	if discrepanciesOnly {
+		query = query.Where("discrepancy")
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(int(limit)).Offset(int(offset)).Find(&audits).Error
+	if err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
+
+func NewJudgeAuditRepository(db *gorm.DB) (JudgeAuditRepository, error) {
+	if !db.Migrator().HasTable(&models.JudgeAudit{}) {
+		err := db.Migrator().CreateTable(&models.JudgeAudit{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &JudgeAuditRepositoryImpl{}, nil
+}
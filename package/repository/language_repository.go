@@ -5,20 +5,63 @@ import (
 	"gorm.io/gorm"
 )
 
+// defaultLanguages are created when there is no language of their type yet. Interpreted and JVM languages
+// are slower than C, so their limits are scaled up
+var defaultLanguages = []models.LanguageConfig{
+	{Type: models.LanguageTypePython, Version: "3.12", TimeMultiplier: 3, MemoryMultiplier: 2},
+	{Type: models.LanguageTypeJava, Version: "21", TimeMultiplier: 2, MemoryMultiplier: 4},
+}
+
 type LanguageRepository interface {
 	GetLanguages(tx *gorm.DB) ([]models.LanguageConfig, error)
 	GetLanguage(tx *gorm.DB, languageId int64) (*models.LanguageConfig, error)
+	// GetLanguageByVersion returns the language of the given type and version, or gorm.ErrRecordNotFound
+	GetLanguageByVersion(tx *gorm.DB, languageType models.LanguageType, version string) (*models.LanguageConfig, error)
+	CreateLanguage(tx *gorm.DB, language *models.LanguageConfig) (int64, error)
+	UpdateLanguage(tx *gorm.DB, language *models.LanguageConfig) error
 }
 
 type LanguageRepositoryImpl struct {
 }
 
 func (l *LanguageRepositoryImpl) GetLanguages(tx *gorm.DB) ([]models.LanguageConfig, error) {
-	panic("implement me")
+	var languages []models.LanguageConfig
+	err := tx.Order("id").Find(&languages).Error
+	if err != nil {
+		return nil, err
+	}
+	return languages, nil
 }
 
 func (l *LanguageRepositoryImpl) GetLanguage(tx *gorm.DB, languageId int64) (*models.LanguageConfig, error) {
-	panic("implement me")
+	var language models.LanguageConfig
+	err := tx.Where("id = ?", languageId).First(&language).Error
+	if err != nil {
+		return nil, err
+	}
+	return &language, nil
+}
+
+func (l *LanguageRepositoryImpl) GetLanguageByVersion(tx *gorm.DB, languageType models.LanguageType, version string) (*models.LanguageConfig, error) {
+	var language models.LanguageConfig
+	err := tx.Where("type = ? AND version = ?", languageType, version).First(&language).Error
+	if err != nil {
+		return nil, err
+	}
+	return &language, nil
+}
+
+func (l *LanguageRepositoryImpl) CreateLanguage(tx *gorm.DB, language *models.LanguageConfig) (int64, error) {
+	err := tx.Create(language).Error
+	if err != nil {
+		return 0, err
+	}
+	return language.Id, nil
+}
+
+func (l *LanguageRepositoryImpl) UpdateLanguage(tx *gorm.DB, language *models.LanguageConfig) error {
+	err := tx.Save(language).Error
+	return err
 }
 
 func NewLanguageRepository(db *gorm.DB) (LanguageRepository, error) {
@@ -28,5 +71,25 @@ func NewLanguageRepository(db *gorm.DB) (LanguageRepository, error) {
 			return nil, err
 		}
 	}
+	for _, column := range []string{"CompilerFlags", "RunArgs", "TimeMultiplier", "MemoryMultiplier", "QueueName"} {
+		if !db.Migrator().HasColumn(&models.LanguageConfig{}, column) {
+			err := db.Migrator().AddColumn(&models.LanguageConfig{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, language := range defaultLanguages {
+		var count int64
+		err := db.Model(&models.LanguageConfig{}).Where("type = ?", language.Type).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			if err := db.Create(&language).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
 	return &LanguageRepositoryImpl{}, nil
 }
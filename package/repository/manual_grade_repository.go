@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ManualGradeRepository interface {
+	// SaveGrades stores the grades, replacing earlier grades of the same submissions
+	SaveGrades(tx *gorm.DB, grades []models.ManualGrade) error
+	GetGrade(tx *gorm.DB, submissionId int64) (*models.ManualGrade, error)
+	// GetGrades returns grades of those of the submissions which were graded
+	GetGrades(tx *gorm.DB, submissionIds []int64) ([]models.ManualGrade, error)
+}
+
+type ManualGradeRepositoryImpl struct{}
+
+func (mr *ManualGradeRepositoryImpl) SaveGrades(tx *gorm.DB, grades []models.ManualGrade) error {
+	if len(grades) == 0 {
+		return nil
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "submission_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"score", "comment", "graded_by", "updated_at"}),
+	}).Create(&grades).Error
+	return err
+}
+
+func (mr *ManualGradeRepositoryImpl) GetGrade(tx *gorm.DB, submissionId int64) (*models.ManualGrade, error) {
+	grade := &models.ManualGrade{}
+	err := tx.Where("submission_id = ?", submissionId).First(grade).Error
+	if err != nil {
+		return nil, err
+	}
+	return grade, nil
+}
+
+func (mr *ManualGradeRepositoryImpl) GetGrades(tx *gorm.DB, submissionIds []int64) ([]models.ManualGrade, error) {
+	var grades []models.ManualGrade
+	if len(submissionIds) == 0 {
+		return grades, nil
+	}
+	err := tx.Where("submission_id IN ?", submissionIds).Find(&grades).Error
+	if err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+func NewManualGradeRepository(db *gorm.DB) (ManualGradeRepository, error) {
+	if !db.Migrator().HasTable(&models.ManualGrade{}) {
+		err := db.Migrator().CreateTable(&models.ManualGrade{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ManualGradeRepositoryImpl{}, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	CreateNotifications(tx *gorm.DB, notifications []models.Notification) error
+	// GetNotifications returns notifications of the user, newest first
+	GetNotifications(tx *gorm.DB, userId int64, limit, offset int64) ([]models.Notification, error)
+	// MarkRead marks the notification of the user read. Returns false when the user has no such notification
+	MarkRead(tx *gorm.DB, userId int64, notificationId int64) (bool, error)
+}
+
+type NotificationRepositoryImpl struct{}
+
+func (nr *NotificationRepositoryImpl) CreateNotifications(tx *gorm.DB, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	err := tx.Create(&notifications).Error
+	return err
+}
+
+func (nr *NotificationRepositoryImpl) GetNotifications(tx *gorm.DB, userId int64, limit, offset int64) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	err := tx.Model(&models.Notification{}).Where("user_id = ?", userId).Order("created_at DESC, id DESC").
+		Limit(int(limit)).Offset(int(offset)).Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (nr *NotificationRepositoryImpl) MarkRead(tx *gorm.DB, userId int64, notificationId int64) (bool, error) {
+	// Notifications read earlier keep the time they were first read
+	result := tx.Model(&models.Notification{}).Where("id = ? AND user_id = ?", notificationId, userId).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func NewNotificationRepository(db *gorm.DB) (NotificationRepository, error) {
+	if !db.Migrator().HasTable(&models.Notification{}) {
+		err := db.Migrator().CreateTable(&models.Notification{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &NotificationRepositoryImpl{}, nil
+}
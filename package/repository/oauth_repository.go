@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OAuthRepository interface {
+	CreateState(tx *gorm.DB, state *models.OAuthState) error
+	// UseState marks the unused state with the hash used and returns it. Returns gorm.ErrRecordNotFound when
+	// the state is unknown or was already used, so a state cannot be used twice even by concurrent requests
+	UseState(tx *gorm.DB, stateHash string) (*models.OAuthState, error)
+	GetIdentity(tx *gorm.DB, provider string, subject string) (*models.UserIdentity, error)
+	CreateIdentity(tx *gorm.DB, identity *models.UserIdentity) error
+	UpdateIdentityEmail(tx *gorm.DB, identityId int64, email string) error
+}
+
+type OAuthRepositoryImpl struct{}
+
+func (or *OAuthRepositoryImpl) CreateState(tx *gorm.DB, state *models.OAuthState) error {
+	return tx.Create(state).Error
+}
+
+func (or *OAuthRepositoryImpl) UseState(tx *gorm.DB, stateHash string) (*models.OAuthState, error) {
+	state := &models.OAuthState{}
+	result := tx.Model(state).Clauses(clause.Returning{}).
+		Where("state_hash = ? AND used_at IS NULL", stateHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return state, nil
+}
+
+func (or *OAuthRepositoryImpl) GetIdentity(tx *gorm.DB, provider string, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := tx.Where("provider = ? AND subject = ?", provider, subject).First(identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (or *OAuthRepositoryImpl) CreateIdentity(tx *gorm.DB, identity *models.UserIdentity) error {
+	return tx.Create(identity).Error
+}
+
+func (or *OAuthRepositoryImpl) UpdateIdentityEmail(tx *gorm.DB, identityId int64, email string) error {
+	return tx.Model(&models.UserIdentity{}).Where("id = ?", identityId).Update("email", email).Error
+}
+
+func NewOAuthRepository(db *gorm.DB) (OAuthRepository, error) {
+	if !db.Migrator().HasTable(&models.OAuthState{}) {
+		err := db.Migrator().CreateTable(&models.OAuthState{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !db.Migrator().HasColumn(&models.OAuthState{}, "ClientNonceHash") {
+		err := db.Migrator().AddColumn(&models.OAuthState{}, "ClientNonceHash")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !db.Migrator().HasTable(&models.UserIdentity{}) {
+		err := db.Migrator().CreateTable(&models.UserIdentity{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &OAuthRepositoryImpl{}, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OnlineMigrationRepository interface {
+	// EnsureMigration creates the migration in the dual write phase unless it already exists
+	EnsureMigration(tx *gorm.DB, name string) error
+	GetMigration(tx *gorm.DB, name string) (*models.OnlineMigration, error)
+	// LockMigration locks the migration for the rest of the transaction.
+	// Returns gorm.ErrRecordNotFound if it does not exist or is locked by another transaction
+	LockMigration(tx *gorm.DB, name string) (*models.OnlineMigration, error)
+	GetAllMigrations(tx *gorm.DB) ([]models.OnlineMigration, error)
+	UpdateMigration(tx *gorm.DB, migration *models.OnlineMigration) error
+}
+
+type OnlineMigrationRepositoryImpl struct{}
+
+func (omr *OnlineMigrationRepositoryImpl) EnsureMigration(tx *gorm.DB, name string) error {
+	migration := models.OnlineMigration{
+		Name:  name,
+		Phase: models.OnlineMigrationPhaseDualWrite,
+	}
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&migration).Error
+	return err
+}
+
+func (omr *OnlineMigrationRepositoryImpl) GetMigration(tx *gorm.DB, name string) (*models.OnlineMigration, error) {
+	migration := &models.OnlineMigration{}
+	err := tx.Model(&models.OnlineMigration{}).Where("name = ?", name).First(migration).Error
+	if err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+func (omr *OnlineMigrationRepositoryImpl) LockMigration(tx *gorm.DB, name string) (*models.OnlineMigration, error) {
+	migration := &models.OnlineMigration{}
+	err := tx.Model(&models.OnlineMigration{}).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("name = ?", name).
+		First(migration).Error
+	if err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+func (omr *OnlineMigrationRepositoryImpl) GetAllMigrations(tx *gorm.DB) ([]models.OnlineMigration, error) {
+	var migrations []models.OnlineMigration
+	err := tx.Model(&models.OnlineMigration{}).Order("name").Find(&migrations).Error
+	if err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func (omr *OnlineMigrationRepositoryImpl) UpdateMigration(tx *gorm.DB, migration *models.OnlineMigration) error {
+	err := tx.Save(migration).Error
+	return err
+}
+
+func NewOnlineMigrationRepository(db *gorm.DB) (OnlineMigrationRepository, error) {
+	if !db.Migrator().HasTable(&models.OnlineMigration{}) {
+		err := db.Migrator().CreateTable(&models.OnlineMigration{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, column := range []string{"Failures", "PausedAt"} {
+		if !db.Migrator().HasColumn(&models.OnlineMigration{}, column) {
+			err := db.Migrator().AddColumn(&models.OnlineMigration{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &OnlineMigrationRepositoryImpl{}, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PartitionRepository interface {
+	// IsPartitioned reports whether the table is partitioned. Tables created before partitioning was introduced are not
+	IsPartitioned(tx *gorm.DB, table string) (bool, error)
+	// CreateMonthlyPartition creates the partition of the table holding rows of the month containing month, unless it exists
+	CreateMonthlyPartition(tx *gorm.DB, table string, month time.Time) error
+}
+
+type PartitionRepositoryImpl struct{}
+
+func (pr *PartitionRepositoryImpl) IsPartitioned(tx *gorm.DB, table string) (bool, error) {
+	var partitioned bool
+	err := tx.Raw("SELECT EXISTS (SELECT 1 FROM pg_partitioned_table pt JOIN pg_class c ON c.oid = pt.partrelid WHERE c.relname = ?)", table).Scan(&partitioned).Error
+	if err != nil {
+		return false, err
+	}
+	return partitioned, nil
+}
+
+func (pr *PartitionRepositoryImpl) CreateMonthlyPartition(tx *gorm.DB, table string, month time.Time) error {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	partition := fmt.Sprintf("%s_%04d_%02d", table, from.Year(), from.Month())
+	err := tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+		partition, table, from.Format(time.DateOnly), to.Format(time.DateOnly))).Error
+	return err
+}
+
+// createPartitionedTable creates the table partitioned by range of partitionColumn, together with
+// a default partition, so rows outside of the monthly partitions can always be inserted
+func createPartitionedTable(db *gorm.DB, model interface{}, table string, partitionColumn string) error {
+	err := db.Set("gorm:table_options", fmt.Sprintf(" PARTITION BY RANGE (%s)", partitionColumn)).Migrator().CreateTable(model)
+	if err != nil {
+		return err
+	}
+	err = db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s_default PARTITION OF %s DEFAULT", table, table)).Error
+	return err
+}
+
+func NewPartitionRepository() PartitionRepository {
+	return &PartitionRepositoryImpl{}
+}
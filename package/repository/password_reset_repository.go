@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	CreateToken(tx *gorm.DB, token *models.PasswordResetToken) error
+	GetTokenByHash(tx *gorm.DB, tokenHash string) (*models.PasswordResetToken, error)
+	// GetLatestToken returns the last token created for the user
+	GetLatestToken(tx *gorm.DB, userId int64) (*models.PasswordResetToken, error)
+	// UseUserTokens marks all unused tokens of the user used, so none of them can be used again
+	UseUserTokens(tx *gorm.DB, userId int64) error
+}
+
+type PasswordResetRepositoryImpl struct{}
+
+func (pr *PasswordResetRepositoryImpl) CreateToken(tx *gorm.DB, token *models.PasswordResetToken) error {
+	return tx.Create(token).Error
+}
+
+func (pr *PasswordResetRepositoryImpl) GetTokenByHash(tx *gorm.DB, tokenHash string) (*models.PasswordResetToken, error) {
+	token := &models.PasswordResetToken{}
+	err := tx.Where("token_hash = ?", tokenHash).First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (pr *PasswordResetRepositoryImpl) GetLatestToken(tx *gorm.DB, userId int64) (*models.PasswordResetToken, error) {
+	token := &models.PasswordResetToken{}
+	err := tx.Where("user_id = ?", userId).Order("created_at DESC").First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (pr *PasswordResetRepositoryImpl) UseUserTokens(tx *gorm.DB, userId int64) error {
+	return tx.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Update("used_at", time.Now()).Error
+}
+
+func NewPasswordResetRepository(db *gorm.DB) (PasswordResetRepository, error) {
+	if !db.Migrator().HasTable(&models.PasswordResetToken{}) {
+		err := db.Migrator().CreateTable(&models.PasswordResetToken{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &PasswordResetRepositoryImpl{}, nil
+}
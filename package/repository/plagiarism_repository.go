@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type PlagiarismRepository interface {
+	// ReplaceCheck stores the check of the task with its matches, replacing the previous check
+	ReplaceCheck(tx *gorm.DB, check *models.PlagiarismCheck, matches []models.PlagiarismMatch) error
+	GetCheck(tx *gorm.DB, taskId int64) (*models.PlagiarismCheck, error)
+	// GetMatches returns matches of the task with similarity of at least minSimilarity, most similar first
+	GetMatches(tx *gorm.DB, taskId int64, minSimilarity float64) ([]models.PlagiarismMatch, error)
+	// DeleteSubmissionMatches deletes matches of the submission with other submissions of the task
+	DeleteSubmissionMatches(tx *gorm.DB, taskId int64, submissionId int64) error
+}
+
+type PlagiarismRepositoryImpl struct{}
+
+func (pr *PlagiarismRepositoryImpl) ReplaceCheck(tx *gorm.DB, check *models.PlagiarismCheck, matches []models.PlagiarismMatch) error {
+	err := tx.Where("task_id = ?", check.TaskId).Delete(&models.PlagiarismMatch{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Where("task_id = ?", check.TaskId).Delete(&models.PlagiarismCheck{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Create(check).Error
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	err = tx.CreateInBatches(matches, 1000).Error
+	return err
+}
+
+func (pr *PlagiarismRepositoryImpl) GetCheck(tx *gorm.DB, taskId int64) (*models.PlagiarismCheck, error) {
+	var check models.PlagiarismCheck
+	err := tx.Where("task_id = ?", taskId).First(&check).Error
+	if err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+func (pr *PlagiarismRepositoryImpl) GetMatches(tx *gorm.DB, taskId int64, minSimilarity float64) ([]models.PlagiarismMatch, error) {
+	var matches []models.PlagiarismMatch
+	err := tx.Where("task_id = ? AND similarity >= ?", taskId, minSimilarity).
+		Order("similarity DESC, first_submission_id, second_submission_id").
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (pr *PlagiarismRepositoryImpl) DeleteSubmissionMatches(tx *gorm.DB, taskId int64, submissionId int64) error {
+	err := tx.Where("task_id = ? AND (first_submission_id = ? OR second_submission_id = ?)", taskId, submissionId, submissionId).Delete(&models.PlagiarismMatch{}).Error
+	return err
+}
+
+func NewPlagiarismRepository(db *gorm.DB) (PlagiarismRepository, error) {
+	tables := []interface{}{&models.PlagiarismCheck{}, &models.PlagiarismMatch{}}
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			err := db.Migrator().CreateTable(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &PlagiarismRepositoryImpl{}, nil
+}
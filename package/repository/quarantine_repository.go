@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type QuarantineRepository interface {
+	CreateFile(tx *gorm.DB, file *models.QuarantinedFile) (int64, error)
+	// GetFiles returns quarantined files newest first
+	GetFiles(tx *gorm.DB, limit, offset int64) ([]models.QuarantinedFile, error)
+	// GetPaths returns paths of all quarantined files. Files uploaded more than once share a path
+	GetPaths(tx *gorm.DB) ([]string, error)
+}
+
+type QuarantineRepositoryImpl struct{}
+
+func (qr *QuarantineRepositoryImpl) CreateFile(tx *gorm.DB, file *models.QuarantinedFile) (int64, error) {
+	err := tx.Create(file).Error
+	if err != nil {
+		return 0, err
+	}
+	return file.Id, nil
+}
+
+func (qr *QuarantineRepositoryImpl) GetFiles(tx *gorm.DB, limit, offset int64) ([]models.QuarantinedFile, error) {
+	var files []models.QuarantinedFile
+	err := tx.Model(&models.QuarantinedFile{}).Order("created_at DESC, id DESC").Limit(int(limit)).Offset(int(offset)).Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (qr *QuarantineRepositoryImpl) GetPaths(tx *gorm.DB) ([]string, error) {
+	var paths []string
+	err := tx.Model(&models.QuarantinedFile{}).Distinct("path").Pluck("path", &paths).Error
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func NewQuarantineRepository(db *gorm.DB) (QuarantineRepository, error) {
+	if !db.Migrator().HasTable(&models.QuarantinedFile{}) {
+		err := db.Migrator().CreateTable(&models.QuarantinedFile{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &QuarantineRepositoryImpl{}, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type QueueFailureRepository interface {
+	CreateFailure(tx *gorm.DB, failure *models.QueueFailure) (int64, error)
+	GetFailure(tx *gorm.DB, failureId int64) (*models.QueueFailure, error)
+	// GetFailures returns failures newest first. With pendingOnly failures which were requeued are left out
+	GetFailures(tx *gorm.DB, pendingOnly bool, limit, offset int64) ([]models.QueueFailure, error)
+	// MarkRequeued records that the user published the submission of the failure again. Returns false when
+	// the failure was already requeued
+	MarkRequeued(tx *gorm.DB, failureId int64, userId int64) (bool, error)
+}
+
+type QueueFailureRepositoryImpl struct{}
+
+func (qfr *QueueFailureRepositoryImpl) CreateFailure(tx *gorm.DB, failure *models.QueueFailure) (int64, error) {
+	err := tx.Create(failure).Error
+	if err != nil {
+		return 0, err
+	}
+	return failure.Id, nil
+}
+
+func (qfr *QueueFailureRepositoryImpl) GetFailure(tx *gorm.DB, failureId int64) (*models.QueueFailure, error) {
+	failure := &models.QueueFailure{}
+	err := tx.Where("id = ?", failureId).First(failure).Error
+	if err != nil {
+		return nil, err
+	}
+	return failure, nil
+}
+
+func (qfr *QueueFailureRepositoryImpl) GetFailures(tx *gorm.DB, pendingOnly bool, limit, offset int64) ([]models.QueueFailure, error) {
+	var failures []models.QueueFailure
+	query := tx.Model(&models.QueueFailure{})
+	if pendingOnly {
+		query = query.Where("requeued_at IS NULL")
+	}
+	err := query.Order("failed_at DESC, id DESC").Limit(int(limit)).Offset(int(offset)).Find(&failures).Error
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+func (qfr *QueueFailureRepositoryImpl) MarkRequeued(tx *gorm.DB, failureId int64, userId int64) (bool, error) {
+	result := tx.Model(&models.QueueFailure{}).
+		Where("id = ? AND requeued_at IS NULL", failureId).
+		Updates(map[string]interface{}{"requeued_at": time.Now(), "requeued_by": userId})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func NewQueueFailureRepository(db *gorm.DB) (QueueFailureRepository, error) {
+	if !db.Migrator().HasTable(&models.QueueFailure{}) {
+		err := db.Migrator().CreateTable(&models.QueueFailure{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &QueueFailureRepositoryImpl{}, nil
+}
@@ -25,7 +25,7 @@ func (qm *QueueMessageRepositoryImpl) CreateQueueMessage(tx *gorm.DB, queueMessa
 
 func (qm *QueueMessageRepositoryImpl) GetQueueMessage(tx *gorm.DB, messageId string) (*models.QueueMessage, error) {
 	queueMessage := &models.QueueMessage{}
-	err := tx.Model(&models.QueueMessage{}).Where("message_id = ?", messageId).First(queueMessage).Error
+	err := tx.Model(&models.QueueMessage{}).Where("id = ?", messageId).First(queueMessage).Error
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +33,7 @@ func (qm *QueueMessageRepositoryImpl) GetQueueMessage(tx *gorm.DB, messageId str
 }
 
 func (qm *QueueMessageRepositoryImpl) DeleteQueueMessage(tx *gorm.DB, messageId string) error {
-	err := tx.Model(&models.QueueMessage{}).Where("message_id = ?", messageId).Delete(&models.QueueMessage{}).Error
+	err := tx.Model(&models.QueueMessage{}).Where("id = ?", messageId).Delete(&models.QueueMessage{}).Error
 	if err != nil {
 		return err
 	}
@@ -47,6 +47,14 @@ func NewQueueMessageRepository(db *gorm.DB) (QueueMessageRepository, error) {
 			return nil, err
 		}
 	}
+	for _, column := range []string{"AuditId", "RejudgeBatchId", "Attempt"} {
+		if !db.Migrator().HasColumn(&models.QueueMessage{}, column) {
+			err := db.Migrator().AddColumn(&models.QueueMessage{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return &QueueMessageRepositoryImpl{}, nil
 }
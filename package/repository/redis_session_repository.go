@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	redisSessionKeyPrefix     = "session:"
+	redisUserSessionKeyPrefix = "user_session:"
+	redisTimeout              = 2 * time.Second
+)
+
+// RedisSessionRepository stores sessions in Redis so they are shared between replicas.
+// A session is kept as a hash under session:<id> and indexed by user_session:<user id>.
+// Both keys expire together with the session. Impersonation sessions are not indexed, so they never
+// replace the session of the user. The tx argument is ignored.
+type RedisSessionRepository struct {
+	client *redis.Client
+}
+
+func (s *RedisSessionRepository) sessionKey(sessionId string) string {
+	return redisSessionKeyPrefix + sessionId
+}
+
+func (s *RedisSessionRepository) userSessionKey(userId int64) string {
+	return redisUserSessionKeyPrefix + strconv.FormatInt(userId, 10)
+}
+
+func (s *RedisSessionRepository) CreateSession(tx *gorm.DB, session *models.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	fields := map[string]interface{}{
+		"user_id":    session.UserId,
+		"expires_at": session.ExpiresAt.Format(time.RFC3339Nano),
+	}
+	if session.ImpersonatedBy != nil {
+		fields["impersonated_by"] = *session.ImpersonatedBy
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.sessionKey(session.Id), fields)
+		pipe.ExpireAt(ctx, s.sessionKey(session.Id), session.ExpiresAt)
+		if session.ImpersonatedBy == nil {
+			pipe.Set(ctx, s.userSessionKey(session.UserId), session.Id, 0)
+			pipe.ExpireAt(ctx, s.userSessionKey(session.UserId), session.ExpiresAt)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisSessionRepository) GetSession(tx *gorm.DB, sessionId string) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	values, err := s.client.HGetAll(ctx, s.sessionKey(sessionId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	userId, err := strconv.ParseInt(values["user_id"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, values["expires_at"])
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		Id:        sessionId,
+		UserId:    userId,
+		ExpiresAt: expiresAt,
+	}
+	if impersonatedByStr, ok := values["impersonated_by"]; ok {
+		impersonatedBy, err := strconv.ParseInt(impersonatedByStr, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		session.ImpersonatedBy = &impersonatedBy
+	}
+	return session, nil
+}
+
+func (s *RedisSessionRepository) GetSessionByUserId(tx *gorm.DB, userId int64) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	sessionId, err := s.client.Get(ctx, s.userSessionKey(userId)).Result()
+	if err == redis.Nil {
+		return nil, gorm.ErrRecordNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.GetSession(tx, sessionId)
+}
+
+func (s *RedisSessionRepository) UpdateExpiration(tx *gorm.DB, sessionId string, expires_at time.Time) error {
+	session, err := s.GetSession(tx, sessionId)
+	if err != nil {
+		return err
+	}
+	session.ExpiresAt = expires_at
+	return s.CreateSession(tx, session)
+}
+
+func (s *RedisSessionRepository) DeleteSession(tx *gorm.DB, sessionId string) error {
+	session, err := s.GetSession(tx, sessionId)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if session.ImpersonatedBy != nil {
+		return s.client.Del(ctx, s.sessionKey(sessionId)).Err()
+	}
+	return s.client.Del(ctx, s.sessionKey(sessionId), s.userSessionKey(session.UserId)).Err()
+}
+
+func NewRedisSessionRepository(client *redis.Client) SessionRepository {
+	return &RedisSessionRepository{client: client}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	CreateToken(tx *gorm.DB, token *models.RefreshToken) error
+	GetTokenByHash(tx *gorm.DB, tokenHash string) (*models.RefreshToken, error)
+	// RevokeToken revokes the token unless it is revoked already. It returns false when it was, so of two
+	// concurrent revocations only one succeeds
+	RevokeToken(tx *gorm.DB, tokenId int64) (bool, error)
+	// RevokeUserTokens revokes all tokens of the user that are not revoked yet
+	RevokeUserTokens(tx *gorm.DB, userId int64) error
+}
+
+type RefreshTokenRepositoryImpl struct{}
+
+func (rr *RefreshTokenRepositoryImpl) CreateToken(tx *gorm.DB, token *models.RefreshToken) error {
+	return tx.Create(token).Error
+}
+
+func (rr *RefreshTokenRepositoryImpl) GetTokenByHash(tx *gorm.DB, tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	err := tx.Where("token_hash = ?", tokenHash).First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (rr *RefreshTokenRepositoryImpl) RevokeToken(tx *gorm.DB, tokenId int64) (bool, error) {
+	result := tx.Model(&models.RefreshToken{}).Where("id = ? AND revoked_at IS NULL", tokenId).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (rr *RefreshTokenRepositoryImpl) RevokeUserTokens(tx *gorm.DB, userId int64) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) (RefreshTokenRepository, error) {
+	if !db.Migrator().HasTable(&models.RefreshToken{}) {
+		err := db.Migrator().CreateTable(&models.RefreshToken{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &RefreshTokenRepositoryImpl{}, nil
+}
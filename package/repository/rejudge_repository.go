@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type RejudgeRepository interface {
+	CreateBatch(tx *gorm.DB, batch *models.RejudgeBatch) (int64, error)
+	GetBatch(tx *gorm.DB, batchId int64) (*models.RejudgeBatch, error)
+	// RecordJudged counts a judged submission of the batch and marks the batch finished with its last submission
+	RecordJudged(tx *gorm.DB, batchId int64, failed bool) error
+}
+
+type RejudgeRepositoryImpl struct{}
+
+func (rr *RejudgeRepositoryImpl) CreateBatch(tx *gorm.DB, batch *models.RejudgeBatch) (int64, error) {
+	err := tx.Create(batch).Error
+	if err != nil {
+		return 0, err
+	}
+	return batch.Id, nil
+}
+
+func (rr *RejudgeRepositoryImpl) GetBatch(tx *gorm.DB, batchId int64) (*models.RejudgeBatch, error) {
+	batch := &models.RejudgeBatch{}
+	err := tx.Where("id = ?", batchId).First(batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (rr *RejudgeRepositoryImpl) RecordJudged(tx *gorm.DB, batchId int64, failed bool) error {
+	column := "completed"
+	if failed {
+		column = "failed"
+	}
+	err := tx.Model(&models.RejudgeBatch{}).Where("id = ?", batchId).Updates(map[string]interface{}{
+		column:        gorm.Expr(column + " + 1"),
+		"finished_at": gorm.Expr("CASE WHEN completed + failed + 1 >= total THEN ? ELSE finished_at END", time.Now()),
+	}).Error
+	return err
+}
+
+func NewRejudgeRepository(db *gorm.DB) (RejudgeRepository, error) {
+	if !db.Migrator().HasTable(&models.RejudgeBatch{}) {
+		err := db.Migrator().CreateTable(&models.RejudgeBatch{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &RejudgeRepositoryImpl{}, nil
+}
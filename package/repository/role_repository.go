@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	// CreateRole creates the role together with its permissions and returns the role ID
+	CreateRole(tx *gorm.DB, role *models.Role) (int64, error)
+	// GetRoles returns custom roles with their permissions ordered by name
+	GetRoles(tx *gorm.DB) ([]models.Role, error)
+	GetRole(tx *gorm.DB, roleId int64) (*models.Role, error)
+	GetRoleByName(tx *gorm.DB, name string) (*models.Role, error)
+	// UpdateRole updates the description of the role and replaces its permissions
+	UpdateRole(tx *gorm.DB, role *models.Role) error
+	// DeleteRole deletes the role and its permissions and returns the number of deleted roles
+	DeleteRole(tx *gorm.DB, roleId int64) (int64, error)
+	// CountUsers returns the number of users assigned the role
+	CountUsers(tx *gorm.DB, name string) (int64, error)
+}
+
+type RoleRepositoryImpl struct{}
+
+func (rr *RoleRepositoryImpl) CreateRole(tx *gorm.DB, role *models.Role) (int64, error) {
+	err := tx.Create(role).Error
+	if err != nil {
+		return 0, err
+	}
+	return role.Id, nil
+}
+
+func (rr *RoleRepositoryImpl) GetRoles(tx *gorm.DB) ([]models.Role, error) {
+	var roles []models.Role
+	err := tx.Preload("Permissions", rolePermissionOrder).Order("name").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (rr *RoleRepositoryImpl) GetRole(tx *gorm.DB, roleId int64) (*models.Role, error) {
+	role := &models.Role{}
+	err := tx.Preload("Permissions", rolePermissionOrder).Where("id = ?", roleId).First(role).Error
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (rr *RoleRepositoryImpl) GetRoleByName(tx *gorm.DB, name string) (*models.Role, error) {
+	role := &models.Role{}
+	err := tx.Preload("Permissions", rolePermissionOrder).Where("name = ?", name).First(role).Error
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (rr *RoleRepositoryImpl) UpdateRole(tx *gorm.DB, role *models.Role) error {
+	err := tx.Model(&models.Role{}).Where("id = ?", role.Id).Update("description", role.Description).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Where("role_id = ?", role.Id).Delete(&models.RolePermission{}).Error
+	if err != nil {
+		return err
+	}
+	if len(role.Permissions) == 0 {
+		return nil
+	}
+	for i := range role.Permissions {
+		role.Permissions[i].RoleId = role.Id
+	}
+	return tx.Create(&role.Permissions).Error
+}
+
+func (rr *RoleRepositoryImpl) DeleteRole(tx *gorm.DB, roleId int64) (int64, error) {
+	err := tx.Where("role_id = ?", roleId).Delete(&models.RolePermission{}).Error
+	if err != nil {
+		return 0, err
+	}
+	result := tx.Where("id = ?", roleId).Delete(&models.Role{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (rr *RoleRepositoryImpl) CountUsers(tx *gorm.DB, name string) (int64, error) {
+	var count int64
+	err := tx.Model(&models.User{}).Where("role = ?", name).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func rolePermissionOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("resource, action")
+}
+
+func NewRoleRepository(db *gorm.DB) (RoleRepository, error) {
+	tables := []interface{}{&models.Role{}, &models.RolePermission{}}
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			err := db.Migrator().CreateTable(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &RoleRepositoryImpl{}, nil
+}
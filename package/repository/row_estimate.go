@@ -0,0 +1,26 @@
+package repository
+
+import "gorm.io/gorm"
+
+// estimateRows returns the number of rows of the table from the planner statistics in pg_class instead of
+// scanning it with COUNT(*). Partitions of a partitioned table are summed. Statistics are refreshed by ANALYZE
+// and autovacuum, so the estimate lags behind recent writes. Tables which were never analyzed are counted
+func estimateRows(tx *gorm.DB, table string) (int64, error) {
+	var estimate struct {
+		Rows       int64
+		Unanalyzed bool
+	}
+	err := tx.Raw(`SELECT COALESCE(SUM(GREATEST(c.reltuples, 0)), 0)::bigint AS rows, COALESCE(BOOL_OR(c.reltuples < 0), true) AS unanalyzed
+		FROM pg_class c
+		WHERE c.relkind = 'r' AND (c.relname = ? OR c.oid IN (
+			SELECT i.inhrelid FROM pg_inherits i JOIN pg_class p ON p.oid = i.inhparent WHERE p.relname = ?))`, table, table).Scan(&estimate).Error
+	if err != nil {
+		return 0, err
+	}
+	if estimate.Unanalyzed {
+		var count int64
+		err := tx.Table(table).Count(&count).Error
+		return count, err
+	}
+	return estimate.Rows, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchemaVersion is the version of the schema created by the repositories of this build. Increase it with
+// changes of the models older builds cannot run against, such as renamed or dropped columns
+const SchemaVersion int64 = 1
+
+const schemaVersionRowId = 1
+
+type SchemaVersionRepository interface {
+	// GetVersion returns the recorded version of the schema, 0 for databases initialized before versions were recorded
+	GetVersion(tx *gorm.DB) (int64, error)
+	SetVersion(tx *gorm.DB, version int64) error
+}
+
+type SchemaVersionRepositoryImpl struct{}
+
+func (svr *SchemaVersionRepositoryImpl) GetVersion(tx *gorm.DB) (int64, error) {
+	versions := []models.SchemaVersion{}
+	err := tx.Model(&models.SchemaVersion{}).Where("id = ?", schemaVersionRowId).Find(&versions).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	return versions[0].Version, nil
+}
+
+func (svr *SchemaVersionRepositoryImpl) SetVersion(tx *gorm.DB, version int64) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"version", "updated_at"}),
+	}).Create(&models.SchemaVersion{Id: schemaVersionRowId, Version: version}).Error
+	return err
+}
+
+func NewSchemaVersionRepository(db *gorm.DB) (SchemaVersionRepository, error) {
+	if !db.Migrator().HasTable(&models.SchemaVersion{}) {
+		err := db.Migrator().CreateTable(&models.SchemaVersion{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &SchemaVersionRepositoryImpl{}, nil
+}
@@ -10,6 +10,7 @@ import (
 type SessionRepository interface {
 	CreateSession(tx *gorm.DB, session *models.Session) error
 	GetSession(tx *gorm.DB, sessionId string) (*models.Session, error)
+	// GetSessionByUserId returns the session of the user, sessions of admins impersonating the user are left out
 	GetSessionByUserId(tx *gorm.DB, userId int64) (*models.Session, error)
 	UpdateExpiration(tx *gorm.DB, sessionId string, expires_at time.Time) error
 	DeleteSession(tx *gorm.DB, sessionId string) error
@@ -34,7 +35,7 @@ func (s *SessionRepositoryImpl) GetSession(tx *gorm.DB, sessionId string) (*mode
 
 func (s *SessionRepositoryImpl) GetSessionByUserId(tx *gorm.DB, userId int64) (*models.Session, error) {
 	session := &models.Session{}
-	err := tx.Model(&models.Session{}).Where("user_id = ?", userId).First(session).Error
+	err := tx.Model(&models.Session{}).Where("user_id = ? AND impersonated_by IS NULL", userId).First(session).Error
 	if err != nil {
 		return nil, err
 	}
@@ -58,5 +59,11 @@ func NewSessionRepository(db *gorm.DB) (SessionRepository, error) {
 			return nil, err
 		}
 	}
+	if !db.Migrator().HasColumn(&models.Session{}, "ImpersonatedBy") {
+		err := db.Migrator().AddColumn(&models.Session{}, "ImpersonatedBy")
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &SessionRepositoryImpl{}, nil
 }
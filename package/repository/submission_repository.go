@@ -1,23 +1,86 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/mini-maxit/backend/package/domain/models"
 	"gorm.io/gorm"
 )
 
 type SubmissionRepository interface {
 	GetSubmission(tx *gorm.DB, submissionId int64) (*models.Submission, error)
+	// GetSubmissionsByIds returns the submissions which exist together with their tasks
+	GetSubmissionsByIds(tx *gorm.DB, submissionIds []int64) ([]models.Submission, error)
+	// RedactSubmission marks the submission redacted and clears its source hash
+	RedactSubmission(tx *gorm.DB, submissionId int64) error
+	// GetArchivableSubmissions returns judged submissions submitted before the given time whose source
+	// is neither archived nor redacted, oldest first
+	GetArchivableSubmissions(tx *gorm.DB, submittedBefore time.Time, limit int) ([]models.Submission, error)
+	// SetSubmissionArchived records when the source was archived, nil once it is restored
+	SetSubmissionArchived(tx *gorm.DB, submissionId int64, archivedAt *time.Time) error
+	// GetPrunableSubmissions returns judged failing submissions submitted before the given time whose source
+	// is neither pruned nor redacted, and which are neither the best nor the latest submission of the user
+	// to the task, oldest first. Only tasks retaining the best and latest submissions are included, and
+	// tasks following the platform policy if pruneByDefault is set
+	GetPrunableSubmissions(tx *gorm.DB, submittedBefore time.Time, pruneByDefault bool, limit int) ([]models.Submission, error)
+	SetSubmissionPruned(tx *gorm.DB, submissionId int64) error
+	// GetSubmissionsByEnvironment returns submissions with a result judged in the environment, newest first.
+	// If after is set, the offset is ignored and the submissions following after are returned
+	GetSubmissionsByEnvironment(tx *gorm.DB, environment models.JudgeEnvironment, after *models.SubmissionKey, limit int64, offset int64) ([]models.Submission, error)
 	CreateSubmission(tx *gorm.DB, submission models.Submission) (int64, error)
 	MarkSubmissionProcessing(tx *gorm.DB, submissionId int64) error
+	// SetEvaluationHash records the hash of the evaluation settings the submission is judged with
+	SetEvaluationHash(tx *gorm.DB, submissionId int64, evaluationHash string) error
 	MarkSubmissionComplete(tx *gorm.DB, submissionId int64) error
 	MarkSubmissionFailed(db *gorm.DB, submissionId int64, errorMsg string) error
+	GetAllForTaskAndUser(tx *gorm.DB, taskId int64, userId int64) ([]models.Submission, error)
+	// GetJudgeLatencyPercentile returns the given percentile (0-1) of seconds between submitting and checking
+	// of submissions checked after since, or nil if there are none
+	GetJudgeLatencyPercentile(tx *gorm.DB, percentile float64, since time.Time) (*float64, error)
+	// GetCompletedIdenticalSubmission returns the latest completed submission other than the given one
+	// with the same task, language, source hash and evaluation hash
+	GetCompletedIdenticalSubmission(tx *gorm.DB, submission *models.Submission) (*models.Submission, error)
+	// GetTaskStats aggregates submissions of the task. If termId is not nil only submissions of the term are counted,
+	// if userId is not nil only submissions of the user
+	GetTaskStats(tx *gorm.DB, taskId int64, termId *int64, userId *int64) (*models.TaskStats, error)
+	// GetSubmissionEvents returns at most limit submissions checked from from (inclusive) to to (exclusive)
+	// with id greater than afterId in id order
+	GetSubmissionEvents(tx *gorm.DB, from time.Time, to time.Time, afterId int64, limit int) ([]models.SubmissionEvent, error)
+	// GetJudgeTimings returns at most limit of the latest submissions checked after since that ran tests
+	GetJudgeTimings(tx *gorm.DB, since time.Time, limit int) ([]models.JudgeTiming, error)
+	// GetAuditSample returns at most limit random completed submissions checked after since whose source
+	// was neither redacted nor pruned
+	GetAuditSample(tx *gorm.DB, since time.Time, limit int) ([]models.Submission, error)
+	// GetRejudgeableSubmissionIds returns ids of judged submissions of the task whose source was neither
+	// redacted nor pruned
+	GetRejudgeableSubmissionIds(tx *gorm.DB, taskId int64) ([]int64, error)
+	CountSubmissions(tx *gorm.DB) (int64, error)
+	// EstimateSubmissions returns an estimate of the number of submissions from the planner statistics, which is
+	// faster than CountSubmissions on large tables
+	EstimateSubmissions(tx *gorm.DB) (int64, error)
+	// GetTermParticipantIds returns ids of users who submitted to the task in the term
+	GetTermParticipantIds(tx *gorm.DB, taskId int64, termId int64) ([]int64, error)
+	// GetSubmissionsAfter returns at most limit submissions with id greater than afterId in id order
+	GetSubmissionsAfter(tx *gorm.DB, afterId int64, limit int) ([]models.Submission, error)
+	// GetLatestAcceptedSubmissions returns the latest submission with full score of each user for the task, with
+	// its language. Redacted submissions are left out
+	GetLatestAcceptedSubmissions(tx *gorm.DB, taskId int64) ([]models.Submission, error)
+	// GetGroupTaskResults aggregates submissions of each member of the group for each task of the group.
+	// Pairs without submissions are left out
+	GetGroupTaskResults(tx *gorm.DB, groupId int64) ([]models.GroupTaskResult, error)
+	// GetGroupTaskProgress counts members of the group who submitted to and solved each task of the group, with
+	// the median of their best scores, in task order. Tasks nobody submitted to are included
+	GetGroupTaskProgress(tx *gorm.DB, groupId int64) ([]models.GroupTaskProgress, error)
+	// GetGroupActivity counts submissions of members of the group for tasks of the group per day since the
+	// given time, in day order. Days without submissions are left out
+	GetGroupActivity(tx *gorm.DB, groupId int64, since time.Time) ([]models.GroupActivityDay, error)
 }
 
 type SubmissionRepositoryImpl struct{}
 
 func (us *SubmissionRepositoryImpl) GetSubmission(tx *gorm.DB, submissionId int64) (*models.Submission, error) {
 	var submission models.Submission
-	err := tx.Where("id = ?", submissionId).First(&submission).Error
+	err := tx.Preload("Language").Where("id = ?", submissionId).First(&submission).Error
 	if err != nil {
 		return nil, err
 	}
@@ -37,8 +100,16 @@ func (us *SubmissionRepositoryImpl) MarkSubmissionProcessing(tx *gorm.DB, submis
 	return err
 }
 
+func (us *SubmissionRepositoryImpl) SetEvaluationHash(tx *gorm.DB, submissionId int64, evaluationHash string) error {
+	err := tx.Model(&models.Submission{}).Where("id = ?", submissionId).Update("evaluation_hash", evaluationHash).Error
+	return err
+}
+
 func (us *SubmissionRepositoryImpl) MarkSubmissionComplete(tx *gorm.DB, submissionId int64) error {
-	err := tx.Model(&models.Submission{}).Where("id = ?", submissionId).Update("status", "completed").Error
+	err := tx.Model(&models.Submission{}).Where("id = ?", submissionId).Updates(map[string]interface{}{
+		"status":     "completed",
+		"checked_at": time.Now(),
+	}).Error
 	return err
 }
 
@@ -46,16 +117,349 @@ func (us *SubmissionRepositoryImpl) MarkSubmissionFailed(db *gorm.DB, submission
 	err := db.Model(&models.Submission{}).Where("id = ?", submissionId).Updates(map[string]interface{}{
 		"status":         "failed",
 		"status_message": errorMsg,
+		"checked_at":     time.Now(),
+	}).Error
+	return err
+}
+
+func (us *SubmissionRepositoryImpl) GetAllForTaskAndUser(tx *gorm.DB, taskId int64, userId int64) ([]models.Submission, error) {
+	var submissions []models.Submission
+	err := tx.Preload("Language").Where("task_id = ? AND user_id = ?", taskId, userId).Order("submitted_at").Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetJudgeLatencyPercentile(tx *gorm.DB, percentile float64, since time.Time) (*float64, error) {
+	var latency *float64
+	err := tx.Model(&models.Submission{}).
+		Select("percentile_cont(?) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM checked_at - submitted_at))", percentile).
+		Where("checked_at IS NOT NULL AND checked_at >= ?", since).
+		Scan(&latency).Error
+	if err != nil {
+		return nil, err
+	}
+	return latency, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetCompletedIdenticalSubmission(tx *gorm.DB, submission *models.Submission) (*models.Submission, error) {
+	var identical models.Submission
+	err := tx.Where("source_hash = ? AND evaluation_hash = ? AND task_id = ? AND language_id = ? AND status = ? AND id <> ?",
+		submission.SourceHash, submission.EvaluationHash, submission.TaskId, submission.LanguageId, "completed", submission.Id).
+		Order("checked_at DESC").
+		First(&identical).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identical, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetTaskStats(tx *gorm.DB, taskId int64, termId *int64, userId *int64) (*models.TaskStats, error) {
+	stats := &models.TaskStats{}
+	query := tx.Model(&models.Submission{}).
+		Select("COUNT(*) AS attempts, "+
+			"COUNT(DISTINCT submissions.user_id) AS users, "+
+			"COUNT(*) FILTER (WHERE submission_results.score = 100) AS accepted, "+
+			"COUNT(DISTINCT submissions.user_id) FILTER (WHERE submission_results.score = 100) AS solved_users").
+		Joins("LEFT JOIN submission_results ON submission_results.submission_id = submissions.id").
+		Where("submissions.task_id = ?", taskId)
+	if termId != nil {
+		query = query.Where("submissions.term_id = ?", *termId)
+	}
+	if userId != nil {
+		query = query.Where("submissions.user_id = ?", *userId)
+	}
+	err := query.Scan(stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetSubmissionEvents(tx *gorm.DB, from time.Time, to time.Time, afterId int64, limit int) ([]models.SubmissionEvent, error) {
+	var events []models.SubmissionEvent
+	err := tx.Model(&models.Submission{}).
+		Select("submissions.id AS submission_id, submissions.user_id, submissions.task_id, submissions.language_id, "+
+			"submissions.\"order\", submissions.status, submissions.submitted_at, submissions.checked_at, "+
+			"submission_results.code, submission_results.score, "+
+			"(SELECT MIN(first.submitted_at) FROM submissions first WHERE first.task_id = submissions.task_id AND first.user_id = submissions.user_id) AS first_submitted_at").
+		Joins("LEFT JOIN submission_results ON submission_results.submission_id = submissions.id").
+		Where("submissions.checked_at >= ? AND submissions.checked_at < ? AND submissions.id > ?", from, to, afterId).
+		Order("submissions.id").
+		Limit(limit).
+		Scan(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetJudgeTimings(tx *gorm.DB, since time.Time, limit int) ([]models.JudgeTiming, error) {
+	var timings []models.JudgeTiming
+	err := tx.Model(&models.Submission{}).
+		Select("EXTRACT(EPOCH FROM submissions.checked_at - submissions.submitted_at) AS seconds, submission_results.total_tests").
+		Joins("JOIN submission_results ON submission_results.submission_id = submissions.id").
+		Where("submissions.checked_at IS NOT NULL AND submissions.checked_at >= ? AND submission_results.total_tests > 0", since).
+		Order("submissions.checked_at DESC").
+		Limit(limit).
+		Scan(&timings).Error
+	if err != nil {
+		return nil, err
+	}
+	return timings, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetAuditSample(tx *gorm.DB, since time.Time, limit int) ([]models.Submission, error) {
+	var submissions []models.Submission
+	err := tx.Model(&models.Submission{}).
+		Where("status = ? AND checked_at >= ? AND redacted_at IS NULL AND pruned_at IS NULL", "completed", since).
+		Order("random()").
+		Limit(limit).
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetRejudgeableSubmissionIds(tx *gorm.DB, taskId int64) ([]int64, error) {
+	var submissionIds []int64
+	err := tx.Model(&models.Submission{}).
+		Where("task_id = ? AND status IN ? AND redacted_at IS NULL AND pruned_at IS NULL", taskId, []string{"completed", "failed"}).
+		Order("id").
+		Pluck("id", &submissionIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissionIds, nil
+}
+
+func (us *SubmissionRepositoryImpl) CountSubmissions(tx *gorm.DB) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Submission{}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (us *SubmissionRepositoryImpl) EstimateSubmissions(tx *gorm.DB) (int64, error) {
+	return estimateRows(tx, "submissions")
+}
+
+func (us *SubmissionRepositoryImpl) GetTermParticipantIds(tx *gorm.DB, taskId int64, termId int64) ([]int64, error) {
+	var userIds []int64
+	err := tx.Model(&models.Submission{}).Distinct("user_id").Where("task_id = ? AND term_id = ?", taskId, termId).Pluck("user_id", &userIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIds, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetSubmissionsAfter(tx *gorm.DB, afterId int64, limit int) ([]models.Submission, error) {
+	var submissions []models.Submission
+	err := tx.Where("id > ?", afterId).Order("id").Limit(limit).Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetLatestAcceptedSubmissions(tx *gorm.DB, taskId int64) ([]models.Submission, error) {
+	var submissions []models.Submission
+	err := tx.Model(&models.Submission{}).Preload("Language").
+		Select("DISTINCT ON (submissions.user_id) submissions.*").
+		Where("submissions.task_id = ? AND submissions.redacted_at IS NULL", taskId).
+		Where("EXISTS (SELECT 1 FROM submission_results WHERE submission_results.submission_id = submissions.id AND submission_results.score = 100)").
+		Order("submissions.user_id, submissions.id DESC").
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) RedactSubmission(tx *gorm.DB, submissionId int64) error {
+	err := tx.Model(&models.Submission{}).Where("id = ?", submissionId).Updates(map[string]interface{}{
+		"redacted_at": time.Now(),
+		"source_hash": "",
 	}).Error
 	return err
 }
 
+func (us *SubmissionRepositoryImpl) GetSubmissionsByIds(tx *gorm.DB, submissionIds []int64) ([]models.Submission, error) {
+	var submissions []models.Submission
+	err := tx.Preload("Task").Where("id IN ?", submissionIds).Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetArchivableSubmissions(tx *gorm.DB, submittedBefore time.Time, limit int) ([]models.Submission, error) {
+	var submissions []models.Submission
+	err := tx.Where("submitted_at < ? AND status IN ? AND archived_at IS NULL AND redacted_at IS NULL AND pruned_at IS NULL",
+		submittedBefore, []string{"completed", "failed"}).Order("id").Limit(limit).Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) SetSubmissionArchived(tx *gorm.DB, submissionId int64, archivedAt *time.Time) error {
+	err := tx.Model(&models.Submission{}).Where("id = ?", submissionId).Update("archived_at", archivedAt).Error
+	return err
+}
+
+func (us *SubmissionRepositoryImpl) GetPrunableSubmissions(tx *gorm.DB, submittedBefore time.Time, pruneByDefault bool, limit int) ([]models.Submission, error) {
+	var submissions []models.Submission
+	scores := tx.Model(&models.SubmissionResult{}).
+		Select("submission_id, MAX(score) AS score").
+		Group("submission_id")
+	// Ties of the best score keep the latest of them
+	ranked := tx.Model(&models.Submission{}).
+		Select("submissions.id, COALESCE(scores.score, 0) AS score, "+
+			"ROW_NUMBER() OVER (PARTITION BY submissions.user_id, submissions.task_id ORDER BY submissions.id DESC) AS latest_rank, "+
+			"ROW_NUMBER() OVER (PARTITION BY submissions.user_id, submissions.task_id ORDER BY COALESCE(scores.score, 0) DESC, submissions.id DESC) AS best_rank").
+		Joins("LEFT JOIN (?) AS scores ON scores.submission_id = submissions.id", scores)
+	retentions := []models.SubmissionRetention{models.SubmissionRetentionBestAndLatest}
+	if pruneByDefault {
+		retentions = append(retentions, models.SubmissionRetentionDefault)
+	}
+	err := tx.Model(&models.Submission{}).
+		Joins("JOIN (?) AS ranked ON ranked.id = submissions.id", ranked).
+		Joins("JOIN tasks ON tasks.id = submissions.task_id").
+		Where("submissions.submitted_at < ? AND submissions.status IN ? AND submissions.pruned_at IS NULL AND submissions.redacted_at IS NULL",
+			submittedBefore, []string{"completed", "failed"}).
+		Where("ranked.score < 100 AND ranked.latest_rank > 1 AND ranked.best_rank > 1").
+		Where("tasks.submission_retention IN ?", retentions).
+		Order("submissions.id").
+		Limit(limit).
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) SetSubmissionPruned(tx *gorm.DB, submissionId int64) error {
+	err := tx.Model(&models.Submission{}).Where("id = ?", submissionId).Updates(map[string]interface{}{
+		"pruned_at":   time.Now(),
+		"archived_at": nil,
+	}).Error
+	return err
+}
+
+func (us *SubmissionRepositoryImpl) GetSubmissionsByEnvironment(tx *gorm.DB, environment models.JudgeEnvironment, after *models.SubmissionKey, limit int64, offset int64) ([]models.Submission, error) {
+	results := tx.Model(&models.SubmissionResult{}).Select("submission_id")
+	if environment.WorkerVersion != "" {
+		results = results.Where("worker_version = ?", environment.WorkerVersion)
+	}
+	if environment.CompilerVersion != "" {
+		results = results.Where("compiler_version = ?", environment.CompilerVersion)
+	}
+	if environment.SandboxImageDigest != "" {
+		results = results.Where("sandbox_image_digest = ?", environment.SandboxImageDigest)
+	}
+
+	query := tx.Preload("Language").Where("id IN (?)", results)
+	if after != nil {
+		// Seeking by the order keeps later pages as fast as the first one, submitted_at also prunes partitions
+		query = query.Where("(submitted_at, id) < (?, ?)", after.SubmittedAt, after.Id)
+	} else {
+		query = query.Offset(int(offset))
+	}
+	var submissions []models.Submission
+	err := query.Order("submitted_at DESC, id DESC").Limit(int(limit)).Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetGroupTaskResults(tx *gorm.DB, groupId int64) ([]models.GroupTaskResult, error) {
+	var results []models.GroupTaskResult
+	err := tx.Model(&models.Submission{}).
+		Select("submissions.user_id, submissions.task_id, COUNT(*) AS attempts, COALESCE(MAX(submission_results.score), 0) AS best_score").
+		Joins("LEFT JOIN submission_results ON submission_results.submission_id = submissions.id").
+		Where("submissions.user_id IN (SELECT user_id FROM user_groups WHERE group_id = ?)", groupId).
+		Where("submissions.task_id IN (SELECT task_id FROM task_groups WHERE group_id = ?)", groupId).
+		Group("submissions.user_id, submissions.task_id").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetGroupTaskProgress(tx *gorm.DB, groupId int64) ([]models.GroupTaskProgress, error) {
+	var progress []models.GroupTaskProgress
+	bestScores := tx.Model(&models.Submission{}).
+		Select("submissions.user_id, submissions.task_id, COALESCE(MAX(submission_results.score), 0) AS best_score").
+		Joins("LEFT JOIN submission_results ON submission_results.submission_id = submissions.id").
+		Where("submissions.user_id IN (SELECT user_id FROM user_groups WHERE group_id = ?)", groupId).
+		Group("submissions.user_id, submissions.task_id")
+	err := tx.Table("task_groups").
+		Select("tasks.id AS task_id, tasks.title, COUNT(best.user_id) AS attempted_users, "+
+			"COUNT(best.user_id) FILTER (WHERE best.best_score = 100) AS solved_users, "+
+			"PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY best.best_score) AS median_best_score").
+		Joins("JOIN tasks ON tasks.id = task_groups.task_id").
+		Joins("LEFT JOIN (?) AS best ON best.task_id = task_groups.task_id", bestScores).
+		Where("task_groups.group_id = ?", groupId).
+		Group("tasks.id, tasks.title").
+		Order("tasks.id").
+		Scan(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (us *SubmissionRepositoryImpl) GetGroupActivity(tx *gorm.DB, groupId int64, since time.Time) ([]models.GroupActivityDay, error) {
+	var days []models.GroupActivityDay
+	err := tx.Model(&models.Submission{}).
+		Select("DATE_TRUNC('day', submissions.submitted_at) AS day, COUNT(*) AS submissions, COUNT(DISTINCT submissions.user_id) AS users").
+		Where("submissions.user_id IN (SELECT user_id FROM user_groups WHERE group_id = ?)", groupId).
+		Where("submissions.task_id IN (SELECT task_id FROM task_groups WHERE group_id = ?)", groupId).
+		Where("submissions.submitted_at >= ?", since).
+		Group("day").
+		Order("day").
+		Scan(&days).Error
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
 func NewSubmissionRepository(db *gorm.DB) (SubmissionRepository, error) {
 	if !db.Migrator().HasTable(&models.Submission{}) {
-		err := db.Migrator().CreateTable(&models.Submission{})
+		err := createPartitionedTable(db, &models.Submission{}, "submissions", "submitted_at")
 		if err != nil {
 			return nil, err
 		}
 	}
+	for _, column := range []string{"SourceHash", "TermId"} {
+		if !db.Migrator().HasColumn(&models.Submission{}, column) {
+			err := db.Migrator().AddColumn(&models.Submission{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !db.Migrator().HasIndex(&models.Submission{}, column) {
+			err := db.Migrator().CreateIndex(&models.Submission{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, column := range []string{"RedactedAt", "ArchivedAt", "PrunedAt", "EvaluationHash"} {
+		if !db.Migrator().HasColumn(&models.Submission{}, column) {
+			err := db.Migrator().AddColumn(&models.Submission{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	return &SubmissionRepositoryImpl{}, nil
 }
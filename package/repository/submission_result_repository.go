@@ -7,6 +7,14 @@ import (
 
 type SubmissionResultRepository interface {
 	CreateSubmissionResult(tx *gorm.DB, solutionResult models.SubmissionResult) (int64, error)
+	GetSubmissionResultBySubmissionId(tx *gorm.DB, submissionId int64) (*models.SubmissionResult, error)
+	// GetLatestSubmissionResults returns the latest result of each of the submissions which has one
+	GetLatestSubmissionResults(tx *gorm.DB, submissionIds []int64) ([]models.SubmissionResult, error)
+	// GetSubmissionResultsAfter returns at most limit results with id greater than afterId in id order, with their submissions
+	GetSubmissionResultsAfter(tx *gorm.DB, afterId int64, limit int) ([]models.SubmissionResult, error)
+	UpdateSubmissionResultScore(tx *gorm.DB, submissionResult *models.SubmissionResult) error
+	// ClearMessages clears messages of all results of the submission
+	ClearMessages(tx *gorm.DB, submissionId int64) error
 }
 
 type SubmissionResultRepositoryImpl struct{}
@@ -19,12 +27,78 @@ func (usr *SubmissionResultRepositoryImpl) CreateSubmissionResult(tx *gorm.DB, s
 	return submissionResult.Id, nil
 }
 
+func (usr *SubmissionResultRepositoryImpl) GetSubmissionResultBySubmissionId(tx *gorm.DB, submissionId int64) (*models.SubmissionResult, error) {
+	submissionResult := &models.SubmissionResult{}
+	err := tx.Where("submission_id = ?", submissionId).Order("created_at DESC").First(submissionResult).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissionResult, nil
+}
+
+func (usr *SubmissionResultRepositoryImpl) GetLatestSubmissionResults(tx *gorm.DB, submissionIds []int64) ([]models.SubmissionResult, error) {
+	var submissionResults []models.SubmissionResult
+	if len(submissionIds) == 0 {
+		return submissionResults, nil
+	}
+	err := tx.Select("DISTINCT ON (submission_id) *").
+		Where("submission_id IN ?", submissionIds).Order("submission_id, created_at DESC").Find(&submissionResults).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissionResults, nil
+}
+
+func (usr *SubmissionResultRepositoryImpl) GetSubmissionResultsAfter(tx *gorm.DB, afterId int64, limit int) ([]models.SubmissionResult, error) {
+	var submissionResults []models.SubmissionResult
+	err := tx.Preload("Submission").Where("id > ?", afterId).Order("id").Limit(limit).Find(&submissionResults).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissionResults, nil
+}
+
+func (usr *SubmissionResultRepositoryImpl) UpdateSubmissionResultScore(tx *gorm.DB, submissionResult *models.SubmissionResult) error {
+	err := tx.Model(&models.SubmissionResult{}).Where("id = ?", submissionResult.Id).Updates(map[string]interface{}{
+		"passed_tests": submissionResult.PassedTests,
+		"total_tests":  submissionResult.TotalTests,
+		"score":        submissionResult.Score,
+	}).Error
+	return err
+}
+
+func (usr *SubmissionResultRepositoryImpl) ClearMessages(tx *gorm.DB, submissionId int64) error {
+	err := tx.Model(&models.SubmissionResult{}).Where("submission_id = ?", submissionId).Update("message", "").Error
+	return err
+}
+
 func NewSubmissionResultRepository(db *gorm.DB) (SubmissionResultRepository, error) {
 	if !db.Migrator().HasTable(&models.SubmissionResult{}) {
 		if err := db.Migrator().CreateTable(&models.SubmissionResult{}); err != nil {
 			return nil, err
 		}
 	}
+	// Score columns were added after the table, existing results get scores from the recompute tool
+	for _, column := range []string{"PassedTests", "TotalTests", "Score"} {
+		if !db.Migrator().HasColumn(&models.SubmissionResult{}, column) {
+			if err := db.Migrator().AddColumn(&models.SubmissionResult{}, column); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Environment columns were added after the table, older results have an unknown environment
+	for _, column := range []string{"WorkerVersion", "CompilerVersion", "SandboxImageDigest"} {
+		if !db.Migrator().HasColumn(&models.SubmissionResult{}, column) {
+			if err := db.Migrator().AddColumn(&models.SubmissionResult{}, column); err != nil {
+				return nil, err
+			}
+		}
+		if !db.Migrator().HasIndex(&models.SubmissionResult{}, column) {
+			if err := db.Migrator().CreateIndex(&models.SubmissionResult{}, column); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return &SubmissionResultRepositoryImpl{}, nil
 
 }
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TagRepository interface {
+	CreateTag(tx *gorm.DB, tag *models.Tag) (int64, error)
+	// GetTags returns all tags in name order
+	GetTags(tx *gorm.DB) ([]models.Tag, error)
+	GetTag(tx *gorm.DB, tagId int64) (*models.Tag, error)
+	GetTagByName(tx *gorm.DB, name string) (*models.Tag, error)
+	// GetTagsByNames returns the tags of the names which exist
+	GetTagsByNames(tx *gorm.DB, names []string) ([]models.Tag, error)
+	// DeleteTag deletes the tag and removes it from its tasks
+	DeleteTag(tx *gorm.DB, tagId int64) error
+	// SetTaskTags replaces the tags of the task
+	SetTaskTags(tx *gorm.DB, taskId int64, tagIds []int64) error
+	// GetTaskTagNames returns names of the tags of each of the tasks in name order. Tasks without tags are left out
+	GetTaskTagNames(tx *gorm.DB, taskIds []int64) (map[int64][]string, error)
+}
+
+type TagRepositoryImpl struct{}
+
+func (tr *TagRepositoryImpl) CreateTag(tx *gorm.DB, tag *models.Tag) (int64, error) {
+	err := tx.Create(tag).Error
+	if err != nil {
+		return 0, err
+	}
+	return tag.Id, nil
+}
+
+func (tr *TagRepositoryImpl) GetTags(tx *gorm.DB) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := tx.Order("name").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (tr *TagRepositoryImpl) GetTag(tx *gorm.DB, tagId int64) (*models.Tag, error) {
+	tag := &models.Tag{}
+	err := tx.Where("id = ?", tagId).First(tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (tr *TagRepositoryImpl) GetTagByName(tx *gorm.DB, name string) (*models.Tag, error) {
+	tag := &models.Tag{}
+	err := tx.Where("name = ?", name).First(tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (tr *TagRepositoryImpl) GetTagsByNames(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := tx.Where("name IN ?", names).Order("name").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (tr *TagRepositoryImpl) DeleteTag(tx *gorm.DB, tagId int64) error {
+	err := tx.Where("tag_id = ?", tagId).Delete(&models.TaskTag{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Where("id = ?", tagId).Delete(&models.Tag{}).Error
+	return err
+}
+
+func (tr *TagRepositoryImpl) SetTaskTags(tx *gorm.DB, taskId int64, tagIds []int64) error {
+	err := tx.Where("task_id = ?", taskId).Delete(&models.TaskTag{}).Error
+	if err != nil {
+		return err
+	}
+	if len(tagIds) == 0 {
+		return nil
+	}
+	taskTags := make([]models.TaskTag, 0, len(tagIds))
+	for _, tagId := range tagIds {
+		taskTags = append(taskTags, models.TaskTag{TaskId: taskId, TagId: tagId})
+	}
+	err = tx.Create(&taskTags).Error
+	return err
+}
+
+func (tr *TagRepositoryImpl) GetTaskTagNames(tx *gorm.DB, taskIds []int64) (map[int64][]string, error) {
+	var rows []struct {
+		TaskId int64
+		Name   string
+	}
+	err := tx.Model(&models.TaskTag{}).
+		Select("task_tags.task_id, tags.name").
+		Joins("JOIN tags ON tags.id = task_tags.tag_id").
+		Where("task_tags.task_id IN ?", taskIds).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64][]string)
+	for _, row := range rows {
+		names[row.TaskId] = append(names[row.TaskId], row.Name)
+	}
+	return names, nil
+}
+
+func NewTagRepository(db *gorm.DB) (TagRepository, error) {
+	if !db.Migrator().HasTable(&models.Tag{}) {
+		err := db.Migrator().CreateTable(&models.Tag{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !db.Migrator().HasTable(&models.TaskTag{}) {
+		err := db.Migrator().CreateTable(&models.TaskTag{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TagRepositoryImpl{}, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskBookmarkRepository interface {
+	// AddBookmark bookmarks the task for the user. Bookmarking an already bookmarked task is a no-op
+	AddBookmark(tx *gorm.DB, taskId int64, userId int64) error
+	RemoveBookmark(tx *gorm.DB, taskId int64, userId int64) error
+	GetBookmarkedTaskIds(tx *gorm.DB, userId int64) ([]int64, error)
+}
+
+type TaskBookmarkRepositoryImpl struct{}
+
+func (tbr *TaskBookmarkRepositoryImpl) AddBookmark(tx *gorm.DB, taskId int64, userId int64) error {
+	bookmark := models.TaskBookmark{
+		TaskId: taskId,
+		UserId: userId,
+	}
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark).Error
+	return err
+}
+
+func (tbr *TaskBookmarkRepositoryImpl) RemoveBookmark(tx *gorm.DB, taskId int64, userId int64) error {
+	err := tx.Where("task_id = ? AND user_id = ?", taskId, userId).Delete(&models.TaskBookmark{}).Error
+	return err
+}
+
+func (tbr *TaskBookmarkRepositoryImpl) GetBookmarkedTaskIds(tx *gorm.DB, userId int64) ([]int64, error) {
+	var taskIds []int64
+	err := tx.Model(&models.TaskBookmark{}).Where("user_id = ?", userId).Pluck("task_id", &taskIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return taskIds, nil
+}
+
+func NewTaskBookmarkRepository(db *gorm.DB) (TaskBookmarkRepository, error) {
+	if !db.Migrator().HasTable(&models.TaskBookmark{}) {
+		err := db.Migrator().CreateTable(&models.TaskBookmark{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TaskBookmarkRepositoryImpl{}, nil
+}
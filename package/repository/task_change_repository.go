@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TaskChangeRepository interface {
+	CreateChanges(tx *gorm.DB, changes []models.TaskChange) error
+	// GetChanges returns changes of the task, newest first
+	GetChanges(tx *gorm.DB, taskId int64, limit, offset int64) ([]models.TaskChange, error)
+	// GetChangedTaskIds returns ids of tasks changed at or after since
+	GetChangedTaskIds(tx *gorm.DB, since time.Time) ([]int64, error)
+}
+
+type TaskChangeRepositoryImpl struct{}
+
+func (tcr *TaskChangeRepositoryImpl) CreateChanges(tx *gorm.DB, changes []models.TaskChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	err := tx.Create(&changes).Error
+	return err
+}
+
+func (tcr *TaskChangeRepositoryImpl) GetChanges(tx *gorm.DB, taskId int64, limit, offset int64) ([]models.TaskChange, error) {
+	var changes []models.TaskChange
+	err := tx.Where("task_id = ?", taskId).Order("created_at DESC, id DESC").Limit(int(limit)).Offset(int(offset)).Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (tcr *TaskChangeRepositoryImpl) GetChangedTaskIds(tx *gorm.DB, since time.Time) ([]int64, error) {
+	var taskIds []int64
+	err := tx.Model(&models.TaskChange{}).Distinct("task_id").Where("created_at >= ?", since).Pluck("task_id", &taskIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return taskIds, nil
+}
+
+func NewTaskChangeRepository(db *gorm.DB) (TaskChangeRepository, error) {
+	if !db.Migrator().HasTable(&models.TaskChange{}) {
+		err := db.Migrator().CreateTable(&models.TaskChange{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TaskChangeRepositoryImpl{}, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TaskCoAuthorRepository interface {
+	// GetCoAuthors returns co-authors of the task ordered by their position
+	GetCoAuthors(tx *gorm.DB, taskId int64) ([]models.TaskCoAuthor, error)
+	// ReplaceCoAuthors removes all co-authors of the task and stores the given ones
+	ReplaceCoAuthors(tx *gorm.DB, taskId int64, coAuthors []models.TaskCoAuthor) error
+	// RecordChanges stores co-authors added to and removed from tasks
+	RecordChanges(tx *gorm.DB, changes []models.TaskCoAuthorChange) error
+	// IsCoAuthor reports whether the user is a co-author of the task
+	IsCoAuthor(tx *gorm.DB, taskId int64, userId int64) (bool, error)
+}
+
+type TaskCoAuthorRepositoryImpl struct{}
+
+func (tcr *TaskCoAuthorRepositoryImpl) GetCoAuthors(tx *gorm.DB, taskId int64) ([]models.TaskCoAuthor, error) {
+	var coAuthors []models.TaskCoAuthor
+	err := tx.Model(&models.TaskCoAuthor{}).Where("task_id = ?", taskId).Order("position").Find(&coAuthors).Error
+	if err != nil {
+		return nil, err
+	}
+	return coAuthors, nil
+}
+
+func (tcr *TaskCoAuthorRepositoryImpl) ReplaceCoAuthors(tx *gorm.DB, taskId int64, coAuthors []models.TaskCoAuthor) error {
+	err := tx.Where("task_id = ?", taskId).Delete(&models.TaskCoAuthor{}).Error
+	if err != nil {
+		return err
+	}
+	if len(coAuthors) == 0 {
+		return nil
+	}
+	err = tx.Create(&coAuthors).Error
+	return err
+}
+
+func (tcr *TaskCoAuthorRepositoryImpl) RecordChanges(tx *gorm.DB, changes []models.TaskCoAuthorChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&changes).Error
+}
+
+func (tcr *TaskCoAuthorRepositoryImpl) IsCoAuthor(tx *gorm.DB, taskId int64, userId int64) (bool, error) {
+	var count int64
+	err := tx.Model(&models.TaskCoAuthor{}).Where("task_id = ? AND user_id = ?", taskId, userId).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func NewTaskCoAuthorRepository(db *gorm.DB) (TaskCoAuthorRepository, error) {
+	tables := []interface{}{&models.TaskCoAuthor{}, &models.TaskCoAuthorChange{}}
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			err := db.Migrator().CreateTable(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &TaskCoAuthorRepositoryImpl{}, nil
+}
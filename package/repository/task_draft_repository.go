@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskDraftRepository interface {
+	// GetDrafts returns drafts of the user for the task updated after the given time
+	GetDrafts(tx *gorm.DB, taskId int64, userId int64, updatedAfter time.Time) ([]models.TaskDraft, error)
+	// PutDraft creates the draft or replaces the content of an existing one
+	PutDraft(tx *gorm.DB, draft *models.TaskDraft) error
+	// DeleteDraftsBefore deletes drafts last updated before the given time and returns how many were deleted
+	DeleteDraftsBefore(tx *gorm.DB, updatedBefore time.Time) (int64, error)
+}
+
+type TaskDraftRepositoryImpl struct{}
+
+func (tdr *TaskDraftRepositoryImpl) GetDrafts(tx *gorm.DB, taskId int64, userId int64, updatedAfter time.Time) ([]models.TaskDraft, error) {
+	var drafts []models.TaskDraft
+	err := tx.Where("task_id = ? AND user_id = ? AND updated_at > ?", taskId, userId, updatedAfter).
+		Order("language_id").Find(&drafts).Error
+	if err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+func (tdr *TaskDraftRepositoryImpl) PutDraft(tx *gorm.DB, draft *models.TaskDraft) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}, {Name: "user_id"}, {Name: "language_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "updated_at"}),
+	}).Create(draft).Error
+	return err
+}
+
+func (tdr *TaskDraftRepositoryImpl) DeleteDraftsBefore(tx *gorm.DB, updatedBefore time.Time) (int64, error) {
+	result := tx.Where("updated_at <= ?", updatedBefore).Delete(&models.TaskDraft{})
+	return result.RowsAffected, result.Error
+}
+
+func NewTaskDraftRepository(db *gorm.DB) (TaskDraftRepository, error) {
+	if !db.Migrator().HasTable(&models.TaskDraft{}) {
+		err := db.Migrator().CreateTable(&models.TaskDraft{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TaskDraftRepositoryImpl{}, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskNoteRepository interface {
+	GetNote(tx *gorm.DB, taskId int64, userId int64) (*models.TaskNote, error)
+	// PutNote creates the note or replaces the content of an existing one
+	PutNote(tx *gorm.DB, note *models.TaskNote) error
+	DeleteNote(tx *gorm.DB, taskId int64, userId int64) error
+	GetNotedTaskIds(tx *gorm.DB, userId int64) ([]int64, error)
+}
+
+type TaskNoteRepositoryImpl struct{}
+
+func (tnr *TaskNoteRepositoryImpl) GetNote(tx *gorm.DB, taskId int64, userId int64) (*models.TaskNote, error) {
+	note := &models.TaskNote{}
+	err := tx.Model(&models.TaskNote{}).Where("task_id = ? AND user_id = ?", taskId, userId).First(note).Error
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (tnr *TaskNoteRepositoryImpl) PutNote(tx *gorm.DB, note *models.TaskNote) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "updated_at"}),
+	}).Create(note).Error
+	return err
+}
+
+func (tnr *TaskNoteRepositoryImpl) DeleteNote(tx *gorm.DB, taskId int64, userId int64) error {
+	err := tx.Where("task_id = ? AND user_id = ?", taskId, userId).Delete(&models.TaskNote{}).Error
+	return err
+}
+
+func (tnr *TaskNoteRepositoryImpl) GetNotedTaskIds(tx *gorm.DB, userId int64) ([]int64, error) {
+	var taskIds []int64
+	err := tx.Model(&models.TaskNote{}).Where("user_id = ?", userId).Pluck("task_id", &taskIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return taskIds, nil
+}
+
+func NewTaskNoteRepository(db *gorm.DB) (TaskNoteRepository, error) {
+	if !db.Migrator().HasTable(&models.TaskNote{}) {
+		err := db.Migrator().CreateTable(&models.TaskNote{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TaskNoteRepositoryImpl{}, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TaskPoolRepository interface {
+	// CreatePool creates the pool together with its tasks and returns the pool ID
+	CreatePool(tx *gorm.DB, pool *models.TaskPool) (int64, error)
+	// GetPool returns the pool with its tasks ordered by task id
+	GetPool(tx *gorm.DB, poolId int64) (*models.TaskPool, error)
+	// GetPoolsOfTasks returns pools having any of the tasks as a variant, with all of their tasks
+	GetPoolsOfTasks(tx *gorm.DB, taskIds []int64) ([]models.TaskPool, error)
+	// DeletePool deletes the pool and its tasks and returns the number of deleted pools
+	DeletePool(tx *gorm.DB, poolId int64) (int64, error)
+}
+
+type TaskPoolRepositoryImpl struct{}
+
+func (tpr *TaskPoolRepositoryImpl) CreatePool(tx *gorm.DB, pool *models.TaskPool) (int64, error) {
+	err := tx.Create(pool).Error
+	if err != nil {
+		return 0, err
+	}
+	return pool.Id, nil
+}
+
+func (tpr *TaskPoolRepositoryImpl) GetPool(tx *gorm.DB, poolId int64) (*models.TaskPool, error) {
+	pool := &models.TaskPool{}
+	err := tx.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("task_id")
+	}).Where("id = ?", poolId).First(pool).Error
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (tpr *TaskPoolRepositoryImpl) GetPoolsOfTasks(tx *gorm.DB, taskIds []int64) ([]models.TaskPool, error) {
+	var pools []models.TaskPool
+	if len(taskIds) == 0 {
+		return pools, nil
+	}
+	err := tx.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("task_id")
+	}).Where("id IN (?)", tx.Model(&models.TaskPoolTask{}).Select("pool_id").Where("task_id IN ?", taskIds)).
+		Find(&pools).Error
+	if err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+func (tpr *TaskPoolRepositoryImpl) DeletePool(tx *gorm.DB, poolId int64) (int64, error) {
+	err := tx.Where("pool_id = ?", poolId).Delete(&models.TaskPoolTask{}).Error
+	if err != nil {
+		return 0, err
+	}
+	result := tx.Where("id = ?", poolId).Delete(&models.TaskPool{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func NewTaskPoolRepository(db *gorm.DB) (TaskPoolRepository, error) {
+	tables := []interface{}{&models.TaskPool{}, &models.TaskPoolTask{}}
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			err := db.Migrator().CreateTable(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &TaskPoolRepositoryImpl{}, nil
+}
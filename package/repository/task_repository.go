@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/mini-maxit/backend/package/domain/models"
 	"gorm.io/gorm"
 )
@@ -10,12 +14,31 @@ type TaskRepository interface {
 	Create(tx *gorm.DB, task models.Task) (int64, error)
 	GetTask(tx *gorm.DB, taskId int64) (*models.Task, error)
 	GetAllTasks(tx *gorm.DB) ([]models.Task, error)
+	// SearchTasks returns tasks matching the search in id order
+	SearchTasks(tx *gorm.DB, search models.TaskSearch) ([]models.Task, error)
 	GetAllForUser(tx *gorm.DB, userId int64) ([]models.Task, error)
 	GetAllForGroup(tx *gorm.DB, groupId int64) ([]models.Task, error)
 	GetTaskByTitle(tx *gorm.DB, title string) (*models.Task, error)
 	GetTaskTimeLimits(tx *gorm.DB, taskId int64) ([]float64, error)
 	GetTaskMemoryLimits(tx *gorm.DB, taskId int64) ([]float64, error)
-	UpdateTask(tx *gorm.DB, taskId int64, task *models.Task) error
+	// GetInputOutputs returns the tests of a task with their limits in order
+	GetInputOutputs(tx *gorm.DB, taskId int64) ([]models.InputOutput, error)
+	// ReplaceInputOutputs replaces the tests of a task with their limits
+	ReplaceInputOutputs(tx *gorm.DB, taskId int64, inputOutputs []models.InputOutput) error
+	// UpdateTask saves the task if it is still at the given version and increments its version.
+	// Returns false without saving when the task is at another version
+	UpdateTask(tx *gorm.DB, taskId int64, task *models.Task, version int64) (bool, error)
+	GetSandboxTasks(tx *gorm.DB, limit, offset int64) ([]models.Task, error)
+	SetSandbox(tx *gorm.DB, taskId int64, sandbox bool) error
+	SetTestVisibility(tx *gorm.DB, taskId int64, visibility models.TestVisibility) error
+	SetSubmissionRetention(tx *gorm.DB, taskId int64, retention models.SubmissionRetention) error
+	SetEvaluationPolicy(tx *gorm.DB, taskId int64, policy models.EvaluationPolicy) error
+	// CountCreatedBy returns the number of tasks created by the user
+	CountCreatedBy(tx *gorm.DB, userId int64) (int64, error)
+	// GetStatsHiddenTaskIds returns the tasks of taskIds assigned to a group of the user which hides their statistics
+	GetStatsHiddenTaskIds(tx *gorm.DB, userId int64, taskIds []int64) ([]int64, error)
+	// GetTestVisibilities returns the test visibility of each of the tasks by task id
+	GetTestVisibilities(tx *gorm.DB, taskIds []int64) (map[int64]models.TestVisibility, error)
 }
 
 type TaskRepositoryImpl struct {
@@ -56,6 +79,45 @@ func (tr *TaskRepositoryImpl) GetAllTasks(tx *gorm.DB) ([]models.Task, error) {
 	return tasks, nil
 }
 
+func (tr *TaskRepositoryImpl) SearchTasks(tx *gorm.DB, search models.TaskSearch) ([]models.Task, error) {
+	tasks := []models.Task{}
+	query := tx.Model(&models.Task{})
+	if len(search.Tags) > 0 {
+		tagged := tx.Model(&models.TaskTag{}).
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name IN ?", search.Tags).
+			Group("task_tags.task_id").
+			Having("COUNT(*) = ?", len(search.Tags))
+		query = query.Where("tasks.id IN (?)", tagged)
+	}
+	if search.Difficulty != "" {
+		query = query.Where("tasks.difficulty = ?", search.Difficulty)
+	}
+	if search.Text != "" {
+		pattern := "%" + escapeLike(search.Text) + "%"
+		taggedWith := tx.Model(&models.TaskTag{}).
+			Select("1").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("task_tags.task_id = tasks.id AND tags.name ILIKE ?", pattern)
+		query = query.Where("tasks.title ILIKE ? OR EXISTS (?)", pattern, taggedWith)
+	}
+	err := query.Order("tasks.id").Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (tr *TaskRepositoryImpl) CountCreatedBy(tx *gorm.DB, userId int64) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Task{}).Where("created_by = ?", userId).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (tr *TaskRepositoryImpl) GetAllForUser(tx *gorm.DB, userId int64) ([]models.Task, error) {
 	var tasks []models.Task
 
@@ -116,14 +178,119 @@ func (tr *TaskRepositoryImpl) GetTaskMemoryLimits(tx *gorm.DB, taskId int64) ([]
 	return memoryLimits, nil
 }
 
-func (tr *TaskRepositoryImpl) UpdateTask(tx *gorm.DB, taskId int64, task *models.Task) error {
-	err := tx.Model(&models.Task{}).Where("id = ?", taskId).Updates(task).Error
+func (tr *TaskRepositoryImpl) GetInputOutputs(tx *gorm.DB, taskId int64) ([]models.InputOutput, error) {
+	inputOutputs := []models.InputOutput{}
+	err := tx.Model(&models.InputOutput{}).Where("task_id = ?", taskId).Find(&inputOutputs).Error
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(inputOutputs, func(a, b models.InputOutput) int { return a.Order - b.Order })
+	return inputOutputs, nil
+}
+
+func (tr *TaskRepositoryImpl) ReplaceInputOutputs(tx *gorm.DB, taskId int64, inputOutputs []models.InputOutput) error {
+	err := tx.Where("task_id = ?", taskId).Delete(&models.InputOutput{}).Error
+	if err != nil {
+		return err
+	}
+	if len(inputOutputs) == 0 {
+		return nil
+	}
+	return tx.Create(&inputOutputs).Error
+}
+
+func (tr *TaskRepositoryImpl) UpdateTask(tx *gorm.DB, taskId int64, task *models.Task, version int64) (bool, error) {
+	result := tx.Model(&models.Task{}).Where("id = ? AND version = ?", taskId, version).Updates(map[string]interface{}{
+		"title":      task.Title,
+		"difficulty": task.Difficulty,
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (tr *TaskRepositoryImpl) GetSandboxTasks(tx *gorm.DB, limit, offset int64) ([]models.Task, error) {
+	tasks := []models.Task{}
+	err := tx.Model(&models.Task{}).Where("sandbox = ?", true).Order("id").Limit(int(limit)).Offset(int(offset)).Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (tr *TaskRepositoryImpl) SetSandbox(tx *gorm.DB, taskId int64, sandbox bool) error {
+	// Update the column directly, Updates with a struct skips false values
+	err := tx.Model(&models.Task{}).Where("id = ?", taskId).Update("sandbox", sandbox).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *TaskRepositoryImpl) SetTestVisibility(tx *gorm.DB, taskId int64, visibility models.TestVisibility) error {
+	err := tx.Model(&models.Task{}).Where("id = ?", taskId).Update("test_visibility", visibility).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (tr *TaskRepositoryImpl) SetSubmissionRetention(tx *gorm.DB, taskId int64, retention models.SubmissionRetention) error {
+	err := tx.Model(&models.Task{}).Where("id = ?", taskId).Update("submission_retention", retention).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *TaskRepositoryImpl) SetEvaluationPolicy(tx *gorm.DB, taskId int64, policy models.EvaluationPolicy) error {
+	// Update the columns directly, Updates with a struct skips null values
+	err := tx.Model(&models.Task{}).Where("id = ?", taskId).Updates(map[string]interface{}{
+		"output_limit":  policy.OutputLimit,
+		"stderr_limit":  policy.StderrLimit,
+		"process_limit": policy.ProcessLimit,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *TaskRepositoryImpl) GetStatsHiddenTaskIds(tx *gorm.DB, userId int64, taskIds []int64) ([]int64, error) {
+	var hidden []int64
+	if len(taskIds) == 0 {
+		return hidden, nil
+	}
+	err := tx.Model(&models.TaskGroup{}).
+		Distinct("task_groups.task_id").
+		Joins("JOIN user_groups ON user_groups.group_id = task_groups.group_id").
+		Where("user_groups.user_id = ? AND task_groups.task_id IN ? AND task_groups.hide_stats", userId, taskIds).
+		Pluck("task_groups.task_id", &hidden).Error
+	if err != nil {
+		return nil, err
+	}
+	return hidden, nil
+}
+
+func (tr *TaskRepositoryImpl) GetTestVisibilities(tx *gorm.DB, taskIds []int64) (map[int64]models.TestVisibility, error) {
+	visibilities := make(map[int64]models.TestVisibility, len(taskIds))
+	if len(taskIds) == 0 {
+		return visibilities, nil
+	}
+	var tasks []models.Task
+	err := tx.Model(&models.Task{}).Select("id", "test_visibility").Where("id IN ?", taskIds).Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		visibilities[task.Id] = task.TestVisibility
+	}
+	return visibilities, nil
+}
+
 func NewTaskRepository(db *gorm.DB) (TaskRepository, error) {
 	tables := []interface{}{&models.Task{}, &models.InputOutput{}, &models.TaskUser{}}
 	for _, table := range tables {
@@ -134,6 +301,19 @@ func NewTaskRepository(db *gorm.DB) (TaskRepository, error) {
 			}
 		}
 	}
+	for _, column := range []string{"Sandbox", "Version", "OutputLimit", "StderrLimit", "ProcessLimit", "TestVisibility", "SubmissionRetention", "Difficulty"} {
+		if !db.Migrator().HasColumn(&models.Task{}, column) {
+			err := db.Migrator().AddColumn(&models.Task{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return &TaskRepositoryImpl{}, nil
 }
+
+// escapeLike escapes wildcards of LIKE patterns, so text is matched literally
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
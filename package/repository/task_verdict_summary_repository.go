@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TaskVerdictSummaryRepository interface {
+	// Refresh recomputes summaries of the task from its submissions, replacing the stored ones
+	Refresh(tx *gorm.DB, taskId int64) error
+	// GetSummaries returns stored summaries of the tasks
+	GetSummaries(tx *gorm.DB, taskIds []int64) ([]models.TaskVerdictSummary, error)
+	// ComputeSummaries aggregates summaries of the tasks from submissions without reading stored summaries
+	ComputeSummaries(tx *gorm.DB, taskIds []int64) ([]models.TaskVerdictSummary, error)
+}
+
+type TaskVerdictSummaryRepositoryImpl struct{}
+
+// summaryQuery aggregates judged submissions of the tasks by verdict. Only the latest result of a rejudged submission counts
+func (tvr *TaskVerdictSummaryRepositoryImpl) summaryQuery(tx *gorm.DB, taskIds []int64) *gorm.DB {
+	return tx.Table("submissions").
+		Select("submissions.task_id, latest.code, COUNT(*) AS submissions, COUNT(*) FILTER (WHERE latest.score = 100) AS accepted").
+		Joins("JOIN LATERAL (SELECT score, code FROM submission_results WHERE submission_results.submission_id = submissions.id "+
+			"ORDER BY submission_results.created_at DESC, submission_results.id DESC LIMIT 1) latest ON true").
+		Where("submissions.task_id IN ?", taskIds).
+		Group("submissions.task_id, latest.code")
+}
+
+func (tvr *TaskVerdictSummaryRepositoryImpl) Refresh(tx *gorm.DB, taskId int64) error {
+	var summaries []models.TaskVerdictSummary
+	err := tvr.summaryQuery(tx, []int64{taskId}).Scan(&summaries).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Where("task_id = ?", taskId).Delete(&models.TaskVerdictSummary{}).Error
+	if err != nil || len(summaries) == 0 {
+		return err
+	}
+	err = tx.Create(&summaries).Error
+	return err
+}
+
+func (tvr *TaskVerdictSummaryRepositoryImpl) GetSummaries(tx *gorm.DB, taskIds []int64) ([]models.TaskVerdictSummary, error) {
+	var summaries []models.TaskVerdictSummary
+	if len(taskIds) == 0 {
+		return summaries, nil
+	}
+	err := tx.Where("task_id IN ?", taskIds).Find(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func (tvr *TaskVerdictSummaryRepositoryImpl) ComputeSummaries(tx *gorm.DB, taskIds []int64) ([]models.TaskVerdictSummary, error) {
+	var summaries []models.TaskVerdictSummary
+	if len(taskIds) == 0 {
+		return summaries, nil
+	}
+	err := tvr.summaryQuery(tx, taskIds).Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func NewTaskVerdictSummaryRepository(db *gorm.DB) (TaskVerdictSummaryRepository, error) {
+	if !db.Migrator().HasTable(&models.TaskVerdictSummary{}) {
+		err := db.Migrator().CreateTable(&models.TaskVerdictSummary{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TaskVerdictSummaryRepositoryImpl{}, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TermRepository interface {
+	CreateTerm(tx *gorm.DB, term *models.Term) (int64, error)
+	GetTerm(tx *gorm.DB, termId int64) (*models.Term, error)
+	GetAllTerms(tx *gorm.DB) ([]models.Term, error)
+	// GetTermAt returns the term in progress at the given time
+	GetTermAt(tx *gorm.DB, at time.Time) (*models.Term, error)
+	// HasOverlappingTerm reports whether a term overlaps the period from startsAt to endsAt
+	HasOverlappingTerm(tx *gorm.DB, startsAt time.Time, endsAt time.Time) (bool, error)
+}
+
+type TermRepositoryImpl struct{}
+
+func (tr *TermRepositoryImpl) CreateTerm(tx *gorm.DB, term *models.Term) (int64, error) {
+	err := tx.Create(term).Error
+	if err != nil {
+		return 0, err
+	}
+	return term.Id, nil
+}
+
+func (tr *TermRepositoryImpl) GetTerm(tx *gorm.DB, termId int64) (*models.Term, error) {
+	term := &models.Term{}
+	err := tx.Where("id = ?", termId).First(term).Error
+	if err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+func (tr *TermRepositoryImpl) GetAllTerms(tx *gorm.DB) ([]models.Term, error) {
+	var terms []models.Term
+	err := tx.Order("starts_at DESC").Find(&terms).Error
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+func (tr *TermRepositoryImpl) GetTermAt(tx *gorm.DB, at time.Time) (*models.Term, error) {
+	term := &models.Term{}
+	err := tx.Where("starts_at <= ? AND ends_at > ?", at, at).First(term).Error
+	if err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+func (tr *TermRepositoryImpl) HasOverlappingTerm(tx *gorm.DB, startsAt time.Time, endsAt time.Time) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Term{}).Where("starts_at < ? AND ends_at > ?", endsAt, startsAt).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func NewTermRepository(db *gorm.DB) (TermRepository, error) {
+	if !db.Migrator().HasTable(&models.Term{}) {
+		err := db.Migrator().CreateTable(&models.Term{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TermRepositoryImpl{}, nil
+}
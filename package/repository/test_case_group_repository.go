@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TestCaseGroupRepository interface {
+	// GetGroups returns test groups of the task with their tests, ordered by their position
+	GetGroups(tx *gorm.DB, taskId int64) ([]models.TestCaseGroup, error)
+	// GetGroupsForTasks returns test groups with their tests of each of the tasks. Tasks without groups are left out
+	GetGroupsForTasks(tx *gorm.DB, taskIds []int64) (map[int64][]models.TestCaseGroup, error)
+	// ReplaceGroups removes all test groups of the task and stores the given ones with their tests
+	ReplaceGroups(tx *gorm.DB, taskId int64, groups []models.TestCaseGroup) error
+}
+
+type TestCaseGroupRepositoryImpl struct{}
+
+func (tgr *TestCaseGroupRepositoryImpl) GetGroups(tx *gorm.DB, taskId int64) ([]models.TestCaseGroup, error) {
+	var groups []models.TestCaseGroup
+	err := tx.Model(&models.TestCaseGroup{}).Preload("Tests", func(db *gorm.DB) *gorm.DB {
+		return db.Order(`"order"`)
+	}).Where("task_id = ?", taskId).Order("position").Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (tgr *TestCaseGroupRepositoryImpl) GetGroupsForTasks(tx *gorm.DB, taskIds []int64) (map[int64][]models.TestCaseGroup, error) {
+	var groups []models.TestCaseGroup
+	err := tx.Model(&models.TestCaseGroup{}).Preload("Tests").Where("task_id IN ?", taskIds).Order("position").Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	groupsByTask := make(map[int64][]models.TestCaseGroup)
+	for _, group := range groups {
+		groupsByTask[group.TaskId] = append(groupsByTask[group.TaskId], group)
+	}
+	return groupsByTask, nil
+}
+
+func (tgr *TestCaseGroupRepositoryImpl) ReplaceGroups(tx *gorm.DB, taskId int64, groups []models.TestCaseGroup) error {
+	err := tx.Where("task_id = ?", taskId).Delete(&models.TestCaseGroupTest{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Where("task_id = ?", taskId).Delete(&models.TestCaseGroup{}).Error
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	// Tests are created with their groups
+	err = tx.Create(&groups).Error
+	return err
+}
+
+func NewTestCaseGroupRepository(db *gorm.DB) (TestCaseGroupRepository, error) {
+	tables := []interface{}{&models.TestCaseGroup{}, &models.TestCaseGroupTest{}}
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			err := db.Migrator().CreateTable(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &TestCaseGroupRepositoryImpl{}, nil
+}
@@ -1,12 +1,30 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/mini-maxit/backend/package/domain/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TestResult interface {
 	CreateTestResults(tx *gorm.DB, testResult models.TestResult) error
+	// GetTestResultsBySubmissionResult returns test results of the submission result. Test results are created
+	// after their submission result, so only partitions newer than the result are scanned
+	GetTestResultsBySubmissionResult(tx *gorm.DB, submissionResult *models.SubmissionResult) ([]models.TestResult, error)
+	// GetTestResultsBySubmissionResultIds returns test results of the submission results created after createdAfter,
+	// with their tests
+	GetTestResultsBySubmissionResultIds(tx *gorm.DB, submissionResultIds []int64, createdAfter time.Time) ([]models.TestResult, error)
+	// ClearErrorMessages clears error messages of test results of all results of the submission
+	ClearErrorMessages(tx *gorm.DB, submissionId int64) error
+	// SaveTestProgress stores outcomes of tests of a submission being judged, replacing earlier reports of the same tests
+	SaveTestProgress(tx *gorm.DB, progress []models.TestProgress) error
+	// GetTestProgress returns reported outcomes of tests of the submission ordered by test
+	GetTestProgress(tx *gorm.DB, submissionId int64) ([]models.TestProgress, error)
+	// GetTestProgressForSubmissions returns reported outcomes of tests of the submissions ordered by submission and test
+	GetTestProgressForSubmissions(tx *gorm.DB, submissionIds []int64) ([]models.TestProgress, error)
+	ClearTestProgress(tx *gorm.DB, submissionId int64) error
 }
 
 type TestResultRepository struct{}
@@ -16,9 +34,85 @@ func (tr *TestResultRepository) CreateTestResults(tx *gorm.DB, testResult models
 	return err
 }
 
+func (tr *TestResultRepository) GetTestResultsBySubmissionResult(tx *gorm.DB, submissionResult *models.SubmissionResult) ([]models.TestResult, error) {
+	var testResults []models.TestResult
+	err := tx.Preload("InputOutput").
+		Where("submission_result_id = ? AND created_at >= ?", submissionResult.Id, submissionResult.CreatedAt).
+		Find(&testResults).Error
+	if err != nil {
+		return nil, err
+	}
+	return testResults, nil
+}
+
+func (tr *TestResultRepository) GetTestResultsBySubmissionResultIds(tx *gorm.DB, submissionResultIds []int64, createdAfter time.Time) ([]models.TestResult, error) {
+	var testResults []models.TestResult
+	err := tx.Preload("InputOutput").Where("submission_result_id IN ? AND created_at >= ?", submissionResultIds, createdAfter).Find(&testResults).Error
+	if err != nil {
+		return nil, err
+	}
+	return testResults, nil
+}
+
+func (tr *TestResultRepository) ClearErrorMessages(tx *gorm.DB, submissionId int64) error {
+	submissionResultIds := tx.Model(&models.SubmissionResult{}).Select("id").Where("submission_id = ?", submissionId)
+	err := tx.Model(&models.TestResult{}).Where("submission_result_id IN (?)", submissionResultIds).Update("error_message", "").Error
+	return err
+}
+
+func (tr *TestResultRepository) SaveTestProgress(tx *gorm.DB, progress []models.TestProgress) error {
+	if len(progress) == 0 {
+		return nil
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "submission_id"}, {Name: "order"}},
+		DoUpdates: clause.AssignmentColumns([]string{"passed"}),
+	}).Create(&progress).Error
+	return err
+}
+
+func (tr *TestResultRepository) GetTestProgress(tx *gorm.DB, submissionId int64) ([]models.TestProgress, error) {
+	var progress []models.TestProgress
+	err := tx.Where("submission_id = ?", submissionId).Order(`"order"`).Find(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (tr *TestResultRepository) GetTestProgressForSubmissions(tx *gorm.DB, submissionIds []int64) ([]models.TestProgress, error) {
+	var progress []models.TestProgress
+	if len(submissionIds) == 0 {
+		return progress, nil
+	}
+	err := tx.Where("submission_id IN ?", submissionIds).Order(`submission_id, "order"`).Find(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (tr *TestResultRepository) ClearTestProgress(tx *gorm.DB, submissionId int64) error {
+	err := tx.Where("submission_id = ?", submissionId).Delete(&models.TestProgress{}).Error
+	return err
+}
+
 func NewTestResultRepository(db *gorm.DB) (TestResult, error) {
 	if !db.Migrator().HasTable(&models.TestResult{}) {
-		err := db.Migrator().CreateTable(&models.TestResult{})
+		err := createPartitionedTable(db, &models.TestResult{}, "test_results", "created_at")
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Tables created before partitioning have no created_at, existing rows get the time of the migration
+	if !db.Migrator().HasColumn(&models.TestResult{}, "CreatedAt") {
+		err := db.Migrator().AddColumn(&models.TestResult{}, "CreatedAt")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !db.Migrator().HasTable(&models.TestProgress{}) {
+		err := db.Migrator().CreateTable(&models.TestProgress{})
 		if err != nil {
 			return nil, err
 		}
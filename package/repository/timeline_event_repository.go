@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TimelineEventRepository interface {
+	CreateEvents(tx *gorm.DB, events []models.TimelineEvent) error
+	// GetEvents returns events of the submission in the order they happened
+	GetEvents(tx *gorm.DB, submissionId int64) ([]models.TimelineEvent, error)
+	// GetMessageEvents returns events recorded for the queue message in the order they happened
+	GetMessageEvents(tx *gorm.DB, messageId string) ([]models.TimelineEvent, error)
+}
+
+type TimelineEventRepositoryImpl struct{}
+
+func (ser *TimelineEventRepositoryImpl) CreateEvents(tx *gorm.DB, events []models.TimelineEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+func (ser *TimelineEventRepositoryImpl) GetEvents(tx *gorm.DB, submissionId int64) ([]models.TimelineEvent, error) {
+	var events []models.TimelineEvent
+	err := tx.Where("submission_id = ?", submissionId).Order("created_at, id").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (ser *TimelineEventRepositoryImpl) GetMessageEvents(tx *gorm.DB, messageId string) ([]models.TimelineEvent, error) {
+	var events []models.TimelineEvent
+	err := tx.Where("message_id = ?", messageId).Order("created_at, id").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func NewTimelineEventRepository(db *gorm.DB) (TimelineEventRepository, error) {
+	if !db.Migrator().HasTable(&models.TimelineEvent{}) {
+		err := db.Migrator().CreateTable(&models.TimelineEvent{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TimelineEventRepositoryImpl{}, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type TrustListRepository interface {
+	CreateEntry(tx *gorm.DB, entry *models.TrustListEntry) (int64, error)
+	GetAllEntries(tx *gorm.DB) ([]models.TrustListEntry, error)
+	// DeleteEntry deletes the entry and returns the number of deleted rows
+	DeleteEntry(tx *gorm.DB, entryId int64) (int64, error)
+}
+
+type TrustListRepositoryImpl struct{}
+
+func (tr *TrustListRepositoryImpl) CreateEntry(tx *gorm.DB, entry *models.TrustListEntry) (int64, error) {
+	err := tx.Create(entry).Error
+	if err != nil {
+		return 0, err
+	}
+	return entry.Id, nil
+}
+
+func (tr *TrustListRepositoryImpl) GetAllEntries(tx *gorm.DB) ([]models.TrustListEntry, error) {
+	var entries []models.TrustListEntry
+	err := tx.Order("id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (tr *TrustListRepositoryImpl) DeleteEntry(tx *gorm.DB, entryId int64) (int64, error) {
+	result := tx.Where("id = ?", entryId).Delete(&models.TrustListEntry{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func NewTrustListRepository(db *gorm.DB) (TrustListRepository, error) {
+	if !db.Migrator().HasTable(&models.TrustListEntry{}) {
+		err := db.Migrator().CreateTable(&models.TrustListEntry{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TrustListRepositoryImpl{}, nil
+}
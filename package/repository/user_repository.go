@@ -11,8 +11,15 @@ type UserRepository interface {
 	CreateUser(tx *gorm.DB, user *models.User) (int64, error)
 	GetUser(tx *gorm.DB, userId int64) (*models.User, error)
 	GetUserByEmail(tx *gorm.DB, email string) (*models.User, error)
+	// CreateUsers creates the users in batches and sets their IDs
+	CreateUsers(tx *gorm.DB, users []*models.User) error
+	// GetUsersByEmailsOrUsernames returns users with any of the emails or usernames
+	GetUsersByEmailsOrUsernames(tx *gorm.DB, emails []string, usernames []string) ([]models.User, error)
 	GetAllUsers(tx *gorm.DB) ([]models.User, error)
 	EditUser(tx *gorm.DB, user *schemas.User) error
+	UpdatePassword(tx *gorm.DB, userId int64, passwordHash string) error
+	UpdateRole(tx *gorm.DB, userId int64, role models.UserRole) error
+	UpdateStatus(tx *gorm.DB, userId int64, status models.UserStatus, reason string) error
 }
 
 type UserRepositoryImpl struct {
@@ -44,6 +51,22 @@ func (ur *UserRepositoryImpl) GetUserByEmail(tx *gorm.DB, email string) (*models
 	return user, nil
 }
 
+func (ur *UserRepositoryImpl) CreateUsers(tx *gorm.DB, users []*models.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	return tx.Model(&models.User{}).CreateInBatches(users, 500).Error
+}
+
+func (ur *UserRepositoryImpl) GetUsersByEmailsOrUsernames(tx *gorm.DB, emails []string, usernames []string) ([]models.User, error) {
+	var users []models.User
+	err := tx.Model(&models.User{}).Where("email IN ? OR username IN ?", emails, usernames).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (ur *UserRepositoryImpl) GetAllUsers(tx *gorm.DB) ([]models.User, error) {
 	users := &[]models.User{}
 	err := tx.Model(&models.User{}).Find(users).Error
@@ -54,7 +77,26 @@ func (ur *UserRepositoryImpl) GetAllUsers(tx *gorm.DB) ([]models.User, error) {
 }
 
 func (ur *UserRepositoryImpl) EditUser(tx *gorm.DB, user *schemas.User) error {
-	err := tx.Model(&models.User{}).Where("id = ?", user.Id).Updates(user).Error
+	// The status is only changed by admins with UpdateStatus
+	err := tx.Model(&models.User{}).Where("id = ?", user.Id).Omit("Status").Updates(user).Error
+	return err
+}
+
+func (ur *UserRepositoryImpl) UpdatePassword(tx *gorm.DB, userId int64, passwordHash string) error {
+	err := tx.Model(&models.User{}).Where("id = ?", userId).Update("password_hash", passwordHash).Error
+	return err
+}
+
+func (ur *UserRepositoryImpl) UpdateRole(tx *gorm.DB, userId int64, role models.UserRole) error {
+	err := tx.Model(&models.User{}).Where("id = ?", userId).Update("role", role).Error
+	return err
+}
+
+func (ur *UserRepositoryImpl) UpdateStatus(tx *gorm.DB, userId int64, status models.UserStatus, reason string) error {
+	err := tx.Model(&models.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"status":        status,
+		"status_reason": reason,
+	}).Error
 	return err
 }
 
@@ -65,6 +107,14 @@ func NewUserRepository(db *gorm.DB) (UserRepository, error) {
 			return nil, err
 		}
 	}
+	for _, column := range []string{"Status", "StatusReason"} {
+		if !db.Migrator().HasColumn(&models.User{}, column) {
+			err := db.Migrator().AddColumn(&models.User{}, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return &UserRepositoryImpl{}, nil
 }
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/mini-maxit/backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserTaskSummaryRepository interface {
+	// Refresh recomputes the summary of the user for the task from its submissions. The summary is removed
+	// when the user has no judged submission for the task
+	Refresh(tx *gorm.DB, userId int64, taskId int64) error
+	// GetSummaries returns stored summaries of the user
+	GetSummaries(tx *gorm.DB, userId int64) ([]models.UserTaskSummary, error)
+	// ComputeSummaries aggregates summaries of the user from submissions without reading stored summaries
+	ComputeSummaries(tx *gorm.DB, userId int64) ([]models.UserTaskSummary, error)
+}
+
+type UserTaskSummaryRepositoryImpl struct{}
+
+// summaryQuery aggregates judged submissions of the user by task. Only the latest result of a rejudged submission counts
+func (usr *UserTaskSummaryRepositoryImpl) summaryQuery(tx *gorm.DB, userId int64) *gorm.DB {
+	return tx.Table("submissions").
+		Select("submissions.user_id, submissions.task_id, COUNT(*) AS attempts, MAX(latest.score) AS best_score, "+
+			"(ARRAY_AGG(latest.code ORDER BY submissions.id DESC))[1] AS last_code").
+		Joins("JOIN LATERAL (SELECT score, code FROM submission_results WHERE submission_results.submission_id = submissions.id "+
+			"ORDER BY submission_results.created_at DESC, submission_results.id DESC LIMIT 1) latest ON true").
+		Where("submissions.user_id = ?", userId).
+		Group("submissions.user_id, submissions.task_id")
+}
+
+func (usr *UserTaskSummaryRepositoryImpl) Refresh(tx *gorm.DB, userId int64, taskId int64) error {
+	var summaries []models.UserTaskSummary
+	err := usr.summaryQuery(tx, userId).Where("submissions.task_id = ?", taskId).Scan(&summaries).Error
+	if err != nil {
+		return err
+	}
+	if len(summaries) == 0 {
+		err = tx.Where("user_id = ? AND task_id = ?", userId, taskId).Delete(&models.UserTaskSummary{}).Error
+		return err
+	}
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "task_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"attempts", "best_score", "last_code", "updated_at"}),
+	}).Create(&summaries[0]).Error
+	return err
+}
+
+func (usr *UserTaskSummaryRepositoryImpl) GetSummaries(tx *gorm.DB, userId int64) ([]models.UserTaskSummary, error) {
+	var summaries []models.UserTaskSummary
+	err := tx.Where("user_id = ?", userId).Find(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func (usr *UserTaskSummaryRepositoryImpl) ComputeSummaries(tx *gorm.DB, userId int64) ([]models.UserTaskSummary, error) {
+	var summaries []models.UserTaskSummary
+	err := usr.summaryQuery(tx, userId).Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func NewUserTaskSummaryRepository(db *gorm.DB) (UserTaskSummaryRepository, error) {
+	if !db.Migrator().HasTable(&models.UserTaskSummary{}) {
+		err := db.Migrator().CreateTable(&models.UserTaskSummary{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &UserTaskSummaryRepositoryImpl{}, nil
+}
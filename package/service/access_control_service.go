@@ -0,0 +1,492 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrOwnRole           = errors.New("admins cannot change their own role")
+)
+
+// Resource is a row group of the permission matrix
+type Resource string
+
+const (
+	ResourceTask         Resource = "task"
+	ResourceTaskPool     Resource = "task_pool"
+	ResourceTag          Resource = "tag"
+	ResourceSubmission   Resource = "submission"
+	ResourceGrade        Resource = "grade"
+	ResourceGroup        Resource = "group"
+	ResourceAnnouncement Resource = "announcement"
+	ResourceActivity     Resource = "activity"
+	ResourceTerm         Resource = "term"
+	ResourceLanguage     Resource = "language"
+	ResourceTrustList    Resource = "trust_list"
+	ResourceStatus       Resource = "status"
+	ResourceMigration    Resource = "migration"
+	ResourceIntegrity    Resource = "integrity"
+	ResourceJudgeAudit   Resource = "judge_audit"
+	ResourceQueue        Resource = "queue"
+	ResourceQuarantine   Resource = "quarantine"
+	ResourceUser         Resource = "user"
+	ResourceAuditLog     Resource = "audit_log"
+	ResourceRole         Resource = "role"
+)
+
+type Action string
+
+const (
+	ActionView             Action = "view"
+	ActionCreate           Action = "create"
+	ActionEdit             Action = "edit"
+	ActionManage           Action = "manage"
+	ActionImport           Action = "import"
+	ActionExport           Action = "export"
+	ActionManageSandbox    Action = "manage_sandbox"
+	ActionRejudge          Action = "rejudge"
+	ActionCheckPlagiarism  Action = "check_plagiarism"
+	ActionBrowse           Action = "browse"
+	ActionRedact           Action = "redact"
+	ActionRecompute        Action = "recompute"
+	ActionViewStats        Action = "view_stats"
+	ActionManageStats      Action = "manage_stats"
+	ActionPublish          Action = "publish"
+	ActionBroadcast        Action = "broadcast"
+	ActionManageIncidents  Action = "manage_incidents"
+	ActionSimulateCapacity Action = "simulate_capacity"
+	ActionCheck            Action = "check"
+	ActionImpersonate      Action = "impersonate"
+)
+
+// Scopes of a permission. Own applies to resources of the user, such as tasks they created, all to every resource
+const (
+	ScopeOwn = "own"
+	ScopeAll = "all"
+)
+
+type permission struct {
+	resource Resource
+	action   Action
+}
+
+// roleScopes are the scopes of a permission for the built-in roles, empty when the role does not have it
+type roleScopes struct {
+	student string
+	teacher string
+	admin   string
+}
+
+func (rs roleScopes) of(role models.UserRole) string {
+	switch role {
+	case models.UserRoleStudent:
+		return rs.student
+	case models.UserRoleTeacher:
+		return rs.teacher
+	case models.UserRoleAdmin:
+		return rs.admin
+	}
+	return ""
+}
+
+// permissionMatrix lists every permission checked by the services with its scope for the built-in roles.
+// Custom roles may only grant permissions listed here
+var permissionMatrix = map[permission]roleScopes{
+	{ResourceTask, ActionCreate}:             {ScopeAll, ScopeAll, ScopeAll},
+	{ResourceTask, ActionEdit}:               {ScopeOwn, ScopeOwn, ScopeAll},
+	{ResourceTask, ActionExport}:             {ScopeOwn, ScopeOwn, ScopeAll},
+	{ResourceTask, ActionImport}:             {"", ScopeAll, ScopeAll},
+	{ResourceTask, ActionManageSandbox}:      {"", "", ScopeAll},
+	{ResourceTask, ActionRejudge}:            {"", ScopeOwn, ScopeAll},
+	{ResourceTask, ActionCheckPlagiarism}:    {"", ScopeOwn, ScopeAll},
+	{ResourceTaskPool, ActionManage}:         {"", ScopeOwn, ScopeAll},
+	{ResourceTag, ActionManage}:              {"", ScopeOwn, ScopeAll},
+	{ResourceSubmission, ActionCreate}:       {ScopeAll, ScopeAll, ScopeAll},
+	{ResourceSubmission, ActionBrowse}:       {"", ScopeOwn, ScopeAll},
+	{ResourceSubmission, ActionRedact}:       {"", ScopeOwn, ScopeAll},
+	{ResourceSubmission, ActionRecompute}:    {"", "", ScopeAll},
+	{ResourceGrade, ActionImport}:            {"", ScopeOwn, ScopeAll},
+	{ResourceGroup, ActionViewStats}:         {"", ScopeAll, ScopeAll},
+	{ResourceGroup, ActionManageStats}:       {"", ScopeAll, ScopeAll},
+	{ResourceAnnouncement, ActionPublish}:    {"", ScopeAll, ScopeAll},
+	{ResourceAnnouncement, ActionBroadcast}:  {"", "", ScopeAll},
+	{ResourceActivity, ActionView}:           {"", ScopeOwn, ScopeAll},
+	{ResourceTerm, ActionManage}:             {"", "", ScopeAll},
+	{ResourceLanguage, ActionManage}:         {"", "", ScopeAll},
+	{ResourceTrustList, ActionManage}:        {"", "", ScopeAll},
+	{ResourceStatus, ActionManageIncidents}:  {"", "", ScopeAll},
+	{ResourceStatus, ActionSimulateCapacity}: {"", "", ScopeAll},
+	{ResourceMigration, ActionManage}:        {"", "", ScopeAll},
+	{ResourceIntegrity, ActionCheck}:         {"", "", ScopeAll},
+	{ResourceJudgeAudit, ActionView}:         {"", "", ScopeAll},
+	{ResourceQueue, ActionManage}:            {"", "", ScopeAll},
+	{ResourceQuarantine, ActionView}:         {"", "", ScopeAll},
+	{ResourceUser, ActionImport}:             {"", "", ScopeAll},
+	{ResourceUser, ActionManage}:             {"", "", ScopeAll},
+	{ResourceUser, ActionImpersonate}:        {"", "", ScopeAll},
+	{ResourceAuditLog, ActionView}:           {"", "", ScopeAll},
+	{ResourceRole, ActionManage}:             {"", "", ScopeAll},
+}
+
+type AccessControlService interface {
+	// Can reports whether the user may perform the action, at least on their own resources
+	Can(user schemas.User, resource Resource, action Action) bool
+	// CanAll reports whether the user may perform the action on every resource, not only on their own
+	CanAll(user schemas.User, resource Resource, action Action) bool
+	// CanAccess reports whether the user may perform the action on a resource created by ownerId
+	CanAccess(user schemas.User, resource Resource, action Action, ownerId int64) bool
+	// Covers reports whether the user has every permission of other, with at least the same scope. Users may only
+	// act as users they cover, or they could gain the permissions they lack
+	Covers(user schemas.User, other schemas.User) bool
+	// ResolvePermissions sets the permissions of the custom role of the user. Users of built-in roles and of
+	// deleted roles are left without them
+	ResolvePermissions(tx *gorm.DB, user *schemas.User) error
+	// GetRoles returns the built-in roles followed by custom roles, with their permissions
+	GetRoles(tx *gorm.DB, currentUser schemas.User) ([]schemas.Role, error)
+	// CreateRole defines a custom role out of permissions of the matrix. Names of built-in roles are taken
+	CreateRole(tx *gorm.DB, currentUser schemas.User, role schemas.RoleCreate) (*schemas.Role, error)
+	// UpdateRole replaces the description and permissions of a custom role
+	UpdateRole(tx *gorm.DB, currentUser schemas.User, roleId int64, update schemas.RoleUpdate) (*schemas.Role, error)
+	// DeleteRole deletes a custom role which is not assigned to any user
+	DeleteRole(tx *gorm.DB, currentUser schemas.User, roleId int64) error
+	// AssignRole assigns a built-in or custom role to another user. The change is recorded in the audit log
+	AssignRole(tx *gorm.DB, currentUser schemas.User, userId int64, edit schemas.UserRoleEdit) (*schemas.User, error)
+}
+
+type AccessControlServiceImpl struct {
+	roleRepository     repository.RoleRepository
+	userRepository     repository.UserRepository
+	auditLogRepository repository.AuditLogRepository
+	logger             *zap.SugaredLogger
+}
+
+func (acs *AccessControlServiceImpl) scope(user schemas.User, resource Resource, action Action) string {
+	role := models.UserRole(user.Role)
+	if role.IsBuiltIn() {
+		return permissionMatrix[permission{resource, action}].of(role)
+	}
+	scope := ""
+	for _, p := range user.Permissions {
+		if p.Resource != string(resource) || p.Action != string(action) {
+			continue
+		}
+		if p.Scope == ScopeAll {
+			return ScopeAll
+		}
+		scope = p.Scope
+	}
+	return scope
+}
+
+func (acs *AccessControlServiceImpl) Can(user schemas.User, resource Resource, action Action) bool {
+	return acs.scope(user, resource, action) != ""
+}
+
+func (acs *AccessControlServiceImpl) CanAll(user schemas.User, resource Resource, action Action) bool {
+	return acs.scope(user, resource, action) == ScopeAll
+}
+
+func (acs *AccessControlServiceImpl) CanAccess(user schemas.User, resource Resource, action Action, ownerId int64) bool {
+	scope := acs.scope(user, resource, action)
+	return scope == ScopeAll || (scope == ScopeOwn && ownerId == user.Id)
+}
+
+// scopeRank orders scopes from no permission to every resource
+func scopeRank(scope string) int {
+	switch scope {
+	case ScopeOwn:
+		return 1
+	case ScopeAll:
+		return 2
+	}
+	return 0
+}
+
+func (acs *AccessControlServiceImpl) Covers(user schemas.User, other schemas.User) bool {
+	for key := range permissionMatrix {
+		if scopeRank(acs.scope(other, key.resource, key.action)) > scopeRank(acs.scope(user, key.resource, key.action)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (acs *AccessControlServiceImpl) ResolvePermissions(tx *gorm.DB, user *schemas.User) error {
+	user.Permissions = nil
+	if models.UserRole(user.Role).IsBuiltIn() {
+		return nil
+	}
+	role, err := acs.roleRepository.GetRoleByName(tx, user.Role)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			acs.logger.Warnf("User %d has unknown role %s", user.Id, user.Role)
+			return nil
+		}
+		acs.logger.Errorf("Error getting role: %v", err.Error())
+		return err
+	}
+	user.Permissions = rolePermissionsToSchema(role.Permissions)
+	return nil
+}
+
+func (acs *AccessControlServiceImpl) GetRoles(tx *gorm.DB, currentUser schemas.User) ([]schemas.Role, error) {
+	if !acs.Can(currentUser, ResourceRole, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	roles, err := acs.roleRepository.GetRoles(tx)
+	if err != nil {
+		acs.logger.Errorf("Error getting roles: %v", err.Error())
+		return nil, err
+	}
+	result := make([]schemas.Role, 0, len(models.BuiltInRoles)+len(roles))
+	for _, role := range models.BuiltInRoles {
+		result = append(result, schemas.Role{Name: string(role), BuiltIn: true, Permissions: builtInPermissions(role)})
+	}
+	for i := range roles {
+		result = append(result, *roleModelToSchema(&roles[i]))
+	}
+	return result, nil
+}
+
+func (acs *AccessControlServiceImpl) CreateRole(tx *gorm.DB, currentUser schemas.User, role schemas.RoleCreate) (*schemas.Role, error) {
+	if !acs.Can(currentUser, ResourceRole, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(role); err != nil {
+		acs.logger.Errorf("Error validating role: %v", err.Error())
+		return nil, err
+	}
+	permissions, err := validatePermissions(role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if models.UserRole(role.Name).IsBuiltIn() {
+		return nil, ErrRoleAlreadyExists
+	}
+	_, err = acs.roleRepository.GetRoleByName(tx, role.Name)
+	if err == nil {
+		return nil, ErrRoleAlreadyExists
+	}
+	if err != gorm.ErrRecordNotFound {
+		acs.logger.Errorf("Error getting role: %v", err.Error())
+		return nil, err
+	}
+
+	model := &models.Role{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+	}
+	_, err = acs.roleRepository.CreateRole(tx, model)
+	if err != nil {
+		acs.logger.Errorf("Error creating role: %v", err.Error())
+		return nil, err
+	}
+	acs.logger.Infof("Role %s created by user %d", model.Name, currentUser.Id)
+	return roleModelToSchema(model), nil
+}
+
+func (acs *AccessControlServiceImpl) UpdateRole(tx *gorm.DB, currentUser schemas.User, roleId int64, update schemas.RoleUpdate) (*schemas.Role, error) {
+	if !acs.Can(currentUser, ResourceRole, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(update); err != nil {
+		acs.logger.Errorf("Error validating role update: %v", err.Error())
+		return nil, err
+	}
+	permissions, err := validatePermissions(update.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	role, err := acs.roleRepository.GetRole(tx, roleId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRoleNotFound
+		}
+		acs.logger.Errorf("Error getting role: %v", err.Error())
+		return nil, err
+	}
+
+	role.Description = update.Description
+	role.Permissions = permissions
+	err = acs.roleRepository.UpdateRole(tx, role)
+	if err != nil {
+		acs.logger.Errorf("Error updating role: %v", err.Error())
+		return nil, err
+	}
+	acs.logger.Infof("Role %s updated by user %d", role.Name, currentUser.Id)
+	return roleModelToSchema(role), nil
+}
+
+func (acs *AccessControlServiceImpl) DeleteRole(tx *gorm.DB, currentUser schemas.User, roleId int64) error {
+	if !acs.Can(currentUser, ResourceRole, ActionManage) {
+		return ErrNotAuthorized
+	}
+
+	role, err := acs.roleRepository.GetRole(tx, roleId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrRoleNotFound
+		}
+		acs.logger.Errorf("Error getting role: %v", err.Error())
+		return err
+	}
+	users, err := acs.roleRepository.CountUsers(tx, role.Name)
+	if err != nil {
+		acs.logger.Errorf("Error counting users of role: %v", err.Error())
+		return err
+	}
+	if users > 0 {
+		return ErrRoleInUse
+	}
+	_, err = acs.roleRepository.DeleteRole(tx, roleId)
+	if err != nil {
+		acs.logger.Errorf("Error deleting role: %v", err.Error())
+		return err
+	}
+	acs.logger.Infof("Role %s deleted by user %d", role.Name, currentUser.Id)
+	return nil
+}
+
+func (acs *AccessControlServiceImpl) AssignRole(tx *gorm.DB, currentUser schemas.User, userId int64, edit schemas.UserRoleEdit) (*schemas.User, error) {
+	if !acs.Can(currentUser, ResourceRole, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		acs.logger.Errorf("Error validating user role: %v", err.Error())
+		return nil, err
+	}
+	if userId == currentUser.Id {
+		return nil, ErrOwnRole
+	}
+	role := models.UserRole(edit.Role)
+	if !role.IsBuiltIn() {
+		_, err := acs.roleRepository.GetRoleByName(tx, edit.Role)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrRoleNotFound
+			}
+			acs.logger.Errorf("Error getting role: %v", err.Error())
+			return nil, err
+		}
+	}
+	user, err := acs.userRepository.GetUser(tx, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		acs.logger.Errorf("Error getting user: %v", err.Error())
+		return nil, err
+	}
+
+	err = acs.userRepository.UpdateRole(tx, userId, role)
+	if err != nil {
+		acs.logger.Errorf("Error updating user role: %v", err.Error())
+		return nil, err
+	}
+	err = acs.auditLogRepository.CreateEntry(tx, &models.AuditLogEntry{
+		ActorId:      currentUser.Id,
+		Action:       models.AuditActionUserRoleChanged,
+		TargetUserId: userId,
+		Detail:       fmt.Sprintf("%s -> %s", user.Role, role),
+	})
+	if err != nil {
+		acs.logger.Errorf("Error creating audit log entry: %v", err.Error())
+		return nil, err
+	}
+	acs.logger.Infof("Role of user %d changed to %s by user %d", userId, role, currentUser.Id)
+
+	return &schemas.User{
+		Id:       user.Id,
+		Name:     user.Name,
+		Surname:  user.Surname,
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     string(role),
+		Status:   string(user.Status),
+	}, nil
+}
+
+// validatePermissions checks the permissions are listed in the matrix and converts them, ignoring duplicates
+func validatePermissions(permissions []schemas.Permission) ([]models.RolePermission, error) {
+	result := make([]models.RolePermission, 0, len(permissions))
+	seen := make(map[permission]bool, len(permissions))
+	for _, p := range permissions {
+		key := permission{Resource(p.Resource), Action(p.Action)}
+		if _, ok := permissionMatrix[key]; !ok {
+			return nil, fmt.Errorf("%w: %s:%s", ErrUnknownPermission, p.Resource, p.Action)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, models.RolePermission{Resource: p.Resource, Action: p.Action, Scope: p.Scope})
+	}
+	return result, nil
+}
+
+// builtInPermissions returns the permissions of a built-in role ordered by resource and action
+func builtInPermissions(role models.UserRole) []schemas.Permission {
+	permissions := []schemas.Permission{}
+	for key, scopes := range permissionMatrix {
+		if scope := scopes.of(role); scope != "" {
+			permissions = append(permissions, schemas.Permission{Resource: string(key.resource), Action: string(key.action), Scope: scope})
+		}
+	}
+	slices.SortFunc(permissions, func(a, b schemas.Permission) int {
+		if c := strings.Compare(a.Resource, b.Resource); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Action, b.Action)
+	})
+	return permissions
+}
+
+func rolePermissionsToSchema(permissions []models.RolePermission) []schemas.Permission {
+	result := make([]schemas.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		result = append(result, schemas.Permission{Resource: p.Resource, Action: p.Action, Scope: p.Scope})
+	}
+	return result
+}
+
+func roleModelToSchema(model *models.Role) *schemas.Role {
+	return &schemas.Role{
+		Id:          model.Id,
+		Name:        model.Name,
+		Description: model.Description,
+		Permissions: rolePermissionsToSchema(model.Permissions),
+	}
+}
+
+func NewAccessControlService(roleRepository repository.RoleRepository, userRepository repository.UserRepository, auditLogRepository repository.AuditLogRepository) AccessControlService {
+	log := logger.NewNamedLogger("access_control_service")
+	return &AccessControlServiceImpl{
+		roleRepository:     roleRepository,
+		userRepository:     userRepository,
+		auditLogRepository: auditLogRepository,
+		logger:             log,
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newAccessControlServiceTest returns an access control service for tests of services checking permissions
+func newAccessControlServiceTest(t *testing.T, tx *gorm.DB) AccessControlService {
+	rr, err := repository.NewRoleRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	alr, err := repository.NewAuditLogRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return NewAccessControlService(rr, ur, alr)
+}
+
+func TestPermissionMatrix(t *testing.T) {
+	acs := NewAccessControlService(nil, nil, nil)
+	teacher := schemas.User{Id: 1, Role: string(models.UserRoleTeacher)}
+	admin := schemas.User{Id: 2, Role: string(models.UserRoleAdmin)}
+	student := schemas.User{Id: 3, Role: string(models.UserRoleStudent)}
+
+	assert.True(t, acs.Can(teacher, ResourceTask, ActionRejudge))
+	assert.False(t, acs.CanAll(teacher, ResourceTask, ActionRejudge))
+	assert.True(t, acs.CanAccess(teacher, ResourceTask, ActionRejudge, teacher.Id))
+	assert.False(t, acs.CanAccess(teacher, ResourceTask, ActionRejudge, admin.Id))
+	assert.True(t, acs.CanAccess(admin, ResourceTask, ActionRejudge, teacher.Id))
+	assert.False(t, acs.Can(student, ResourceTask, ActionRejudge))
+	assert.True(t, acs.CanAccess(student, ResourceTask, ActionEdit, student.Id))
+	assert.False(t, acs.Can(teacher, ResourceRole, ActionManage))
+	assert.False(t, acs.Can(schemas.User{Id: 4, Role: "guest"}, ResourceTask, ActionCreate))
+}
+
+func TestCustomRoles(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	acs := newAccessControlServiceTest(t, tx)
+	users := []*models.User{
+		{Name: "Admin", Surname: "User", Email: "admin@email.com", Username: "admin", PasswordHash: "password", Role: models.UserRoleAdmin},
+		{Name: "Assistant", Surname: "User", Email: "assistant@email.com", Username: "assistant", PasswordHash: "password"},
+	}
+	if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: users[0].Id, Role: string(models.UserRoleAdmin)}
+	savePoint := "users"
+	tx.SavePoint(savePoint)
+
+	t.Run("Assign and resolve", func(t *testing.T) {
+		role, err := acs.CreateRole(tx, admin, schemas.RoleCreate{
+			Name:        "assistant",
+			Description: "Teaching assistant",
+			Permissions: []schemas.Permission{
+				{Resource: string(ResourceTask), Action: string(ActionRejudge), Scope: ScopeAll},
+				{Resource: string(ResourceSubmission), Action: string(ActionBrowse), Scope: ScopeOwn},
+			},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Len(t, role.Permissions, 2)
+
+		user, err := acs.AssignRole(tx, admin, users[1].Id, schemas.UserRoleEdit{Role: "assistant"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NoError(t, acs.ResolvePermissions(tx, user))
+		assert.True(t, acs.CanAll(*user, ResourceTask, ActionRejudge))
+		assert.True(t, acs.Can(*user, ResourceSubmission, ActionBrowse))
+		assert.False(t, acs.CanAll(*user, ResourceSubmission, ActionBrowse))
+		assert.False(t, acs.Can(*user, ResourceRole, ActionManage))
+
+		_, err = acs.UpdateRole(tx, admin, role.Id, schemas.RoleUpdate{Description: "Read only"})
+		assert.NoError(t, err)
+		assert.NoError(t, acs.ResolvePermissions(tx, user))
+		assert.False(t, acs.Can(*user, ResourceTask, ActionRejudge))
+
+		err = acs.DeleteRole(tx, admin, role.Id)
+		assert.ErrorIs(t, err, ErrRoleInUse)
+		_, err = acs.AssignRole(tx, admin, users[1].Id, schemas.UserRoleEdit{Role: string(models.UserRoleStudent)})
+		assert.NoError(t, err)
+		assert.NoError(t, acs.DeleteRole(tx, admin, role.Id))
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Invalid roles", func(t *testing.T) {
+		_, err := acs.CreateRole(tx, admin, schemas.RoleCreate{Name: "teacher"})
+		assert.ErrorIs(t, err, ErrRoleAlreadyExists)
+		_, err = acs.CreateRole(tx, admin, schemas.RoleCreate{
+			Name:        "grader",
+			Permissions: []schemas.Permission{{Resource: "task", Action: "delete", Scope: ScopeAll}},
+		})
+		assert.ErrorIs(t, err, ErrUnknownPermission)
+		_, err = acs.AssignRole(tx, admin, users[1].Id, schemas.UserRoleEdit{Role: "grader"})
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+		_, err = acs.AssignRole(tx, admin, admin.Id, schemas.UserRoleEdit{Role: string(models.UserRoleStudent)})
+		assert.ErrorIs(t, err, ErrOwnRole)
+		_, err = acs.GetRoles(tx, schemas.User{Id: users[1].Id, Role: string(models.UserRoleTeacher)})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
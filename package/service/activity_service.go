@@ -0,0 +1,60 @@
+package service
+
+import (
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ActivityService interface {
+	// GetActivity returns recent submissions, rejudges and co-author changes on tasks the user manages, newest first.
+	// Teachers see their own tasks, admins all tasks
+	GetActivity(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.ActivityEvent, error)
+}
+
+type ActivityServiceImpl struct {
+	activityRepository   repository.ActivityRepository
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (as *ActivityServiceImpl) GetActivity(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.ActivityEvent, error) {
+	var authorId *int64
+	switch {
+	case as.accessControlService.CanAll(currentUser, ResourceActivity, ActionView):
+	case as.accessControlService.Can(currentUser, ResourceActivity, ActionView):
+		authorId = &currentUser.Id
+	default:
+		return nil, ErrNotAuthorized
+	}
+
+	events, err := as.activityRepository.GetTaskActivity(tx, authorId, limit, offset)
+	if err != nil {
+		as.logger.Errorf("Error getting task activity: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.ActivityEvent, 0, len(events))
+	for _, event := range events {
+		result = append(result, schemas.ActivityEvent{
+			Kind:       string(event.Kind),
+			ResourceId: event.ResourceId,
+			TaskId:     event.TaskId,
+			TaskTitle:  event.TaskTitle,
+			ActorId:    event.ActorId,
+			OccurredAt: event.OccurredAt,
+		})
+	}
+	return result, nil
+}
+
+func NewActivityService(activityRepository repository.ActivityRepository, accessControlService AccessControlService) ActivityService {
+	log := logger.NewNamedLogger("activity_service")
+	return &ActivityServiceImpl{
+		activityRepository:   activityRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetActivity(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	rr, err := repository.NewRejudgeRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tcar, err := repository.NewTaskCoAuthorRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	as := NewActivityService(repository.NewActivityRepository(), newAccessControlServiceTest(t, tx))
+
+	teacherId, err := ur.CreateUser(tx, &models.User{
+		Name:         "Test User",
+		Surname:      "Test Surname",
+		Email:        "email@email.com",
+		Username:     "testuser",
+		PasswordHash: "password",
+		Role:         models.UserRoleTeacher,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	taskId, err := tr.Create(tx, models.Task{Title: "Test Task", CreatedBy: teacherId})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	language := &models.LanguageConfig{Type: "c", Version: "99"}
+	if !assert.NoError(t, tx.Create(language).Error) {
+		t.FailNow()
+	}
+	submissionId, err := sr.CreateSubmission(tx, models.Submission{
+		TaskId:     taskId,
+		UserId:     teacherId,
+		Order:      1,
+		LanguageId: language.Id,
+		Status:     "completed",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	batchId, err := rr.CreateBatch(tx, &models.RejudgeBatch{TaskId: taskId, CreatedBy: teacherId, Total: 1})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	coAuthorId, err := ur.CreateUser(tx, &models.User{
+		Name:         "Co",
+		Surname:      "Author",
+		Email:        "coauthor@email.com",
+		Username:     "coauthor",
+		PasswordHash: "password",
+		Role:         models.UserRoleTeacher,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = tcar.RecordChanges(tx, []models.TaskCoAuthorChange{
+		{TaskId: taskId, UserId: coAuthorId, Added: true, ChangedBy: teacherId, ChangedAt: time.Now().Add(time.Minute)},
+		{TaskId: taskId, UserId: coAuthorId, Added: false, ChangedBy: teacherId, ChangedAt: time.Now().Add(2 * time.Minute)},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Run("Teacher sees activity on own tasks", func(t *testing.T) {
+		events, err := as.GetActivity(tx, schemas.User{Id: teacherId, Role: string(models.UserRoleTeacher)}, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, events, 4) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.ActivityKindCoAuthorRemoved), events[0].Kind)
+		assert.Equal(t, coAuthorId, events[0].ResourceId)
+		assert.Equal(t, string(models.ActivityKindCoAuthorAdded), events[1].Kind)
+		assert.Equal(t, coAuthorId, events[1].ResourceId)
+		assert.Equal(t, teacherId, events[1].ActorId)
+		assert.Equal(t, string(models.ActivityKindRejudge), events[2].Kind)
+		assert.Equal(t, batchId, events[2].ResourceId)
+		assert.Equal(t, string(models.ActivityKindSubmission), events[3].Kind)
+		assert.Equal(t, submissionId, events[3].ResourceId)
+		assert.Equal(t, "Test Task", events[3].TaskTitle)
+	})
+
+	t.Run("Other teacher", func(t *testing.T) {
+		events, err := as.GetActivity(tx, schemas.User{Id: teacherId + 1, Role: string(models.UserRoleTeacher)}, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Student", func(t *testing.T) {
+		_, err := as.GetActivity(tx, schemas.User{Id: teacherId, Role: string(models.UserRoleStudent)}, 10, 0)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+	tx.Rollback()
+}
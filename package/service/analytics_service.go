@@ -0,0 +1,142 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const AnalyticsExportBatchSize = 1000
+
+var analyticsExportHeader = []string{
+	"submission_id", "user", "task_id", "language_id", "attempt", "status", "verdict", "score",
+	"submitted_at", "checked_at", "seconds_since_first_attempt",
+}
+
+type AnalyticsService interface {
+	// ExportDay writes submissions checked on the given UTC day to a CSV file in the export directory
+	// and returns its path. Users are identified by pseudonyms stable across exports. A day that was
+	// already exported is skipped
+	ExportDay(tx *gorm.DB, day time.Time) (string, error)
+}
+
+type AnalyticsServiceImpl struct {
+	submissionRepository repository.SubmissionRepository
+	exportDir            string
+	salt                 []byte
+	logger               *zap.SugaredLogger
+}
+
+func (as *AnalyticsServiceImpl) ExportDay(tx *gorm.DB, day time.Time) (string, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	path := filepath.Join(as.exportDir, fmt.Sprintf("submissions_%s.csv", from.Format(time.DateOnly)))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	// Write to a temporary file first, so an interrupted export is not mistaken for a finished one
+	file, err := os.CreateTemp(as.exportDir, "submissions_*.csv.tmp")
+	if err != nil {
+		as.logger.Errorf("Error creating analytics export file: %v", err.Error())
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer, err := utils.NewCSVWriter(file, utils.CSVOptions{})
+	if err != nil {
+		return "", err
+	}
+	if err := writer.Write(analyticsExportHeader); err != nil {
+		return "", err
+	}
+	exported := 0
+	afterId := int64(0)
+	for {
+		events, err := as.submissionRepository.GetSubmissionEvents(tx, from, to, afterId, AnalyticsExportBatchSize)
+		if err != nil {
+			as.logger.Errorf("Error getting submission events: %v", err.Error())
+			return "", err
+		}
+		for _, event := range events {
+			if err := writer.Write(as.eventToRecord(&event)); err != nil {
+				return "", err
+			}
+		}
+		exported += len(events)
+		if len(events) < AnalyticsExportBatchSize {
+			break
+		}
+		afterId = events[len(events)-1].SubmissionId
+	}
+	if err := writer.Flush(); err != nil {
+		as.logger.Errorf("Error writing analytics export: %v", err.Error())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		as.logger.Errorf("Error saving analytics export: %v", err.Error())
+		return "", err
+	}
+
+	as.logger.Infof("Exported %d submission events of %s to %s", exported, from.Format(time.DateOnly), path)
+	return path, nil
+}
+
+func (as *AnalyticsServiceImpl) eventToRecord(event *models.SubmissionEvent) []string {
+	verdict := ""
+	if event.Code != nil {
+		verdict = *event.Code
+	}
+	score := ""
+	secondsSinceFirstAttempt := ""
+	if event.Score != nil {
+		score = strconv.FormatFloat(*event.Score, 'f', -1, 64)
+		secondsSinceFirstAttempt = strconv.FormatInt(int64(event.CheckedAt.Sub(event.FirstSubmittedAt).Seconds()), 10)
+	}
+	return []string{
+		strconv.FormatInt(event.SubmissionId, 10),
+		as.pseudonym(event.UserId),
+		strconv.FormatInt(event.TaskId, 10),
+		strconv.FormatInt(event.LanguageId, 10),
+		strconv.FormatInt(event.Order, 10),
+		event.Status,
+		verdict,
+		score,
+		event.SubmittedAt.UTC().Format(time.RFC3339),
+		event.CheckedAt.UTC().Format(time.RFC3339),
+		secondsSinceFirstAttempt,
+	}
+}
+
+// pseudonym returns a keyed hash of the user id, so exports can be joined by user without identifying them
+func (as *AnalyticsServiceImpl) pseudonym(userId int64) string {
+	mac := hmac.New(sha256.New, as.salt)
+	mac.Write([]byte(strconv.FormatInt(userId, 10)))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func NewAnalyticsService(submissionRepository repository.SubmissionRepository, exportDir string, salt string) AnalyticsService {
+	log := logger.NewNamedLogger("analytics_service")
+	return &AnalyticsServiceImpl{
+		submissionRepository: submissionRepository,
+		exportDir:            exportDir,
+		salt:                 []byte(salt),
+		logger:               log,
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportDay(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = repository.NewSubmissionResultRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	exportDir := t.TempDir()
+	as := NewAnalyticsService(sr, exportDir, "salt")
+
+	userId, err := ur.CreateUser(tx, &models.User{
+		Name:         "Test User",
+		Surname:      "Test Surname",
+		Email:        "email@email.com",
+		Username:     "testuser",
+		PasswordHash: "password",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	taskId, err := tr.Create(tx, models.Task{Title: "Test Task", CreatedBy: userId})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	language := &models.LanguageConfig{Type: "c", Version: "99"}
+	if !assert.NoError(t, tx.Create(language).Error) {
+		t.FailNow()
+	}
+	submissionId, err := sr.CreateSubmission(tx, models.Submission{
+		TaskId:     taskId,
+		UserId:     userId,
+		Order:      1,
+		LanguageId: language.Id,
+		Status:     "received",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, sr.MarkSubmissionComplete(tx, submissionId)) {
+		t.FailNow()
+	}
+	result := &models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Message: "accepted", PassedTests: 1, TotalTests: 1, Score: 100}
+	if !assert.NoError(t, tx.Create(result).Error) {
+		t.FailNow()
+	}
+
+	path, err := as.ExportDay(tx, time.Now().UTC())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	file, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if !assert.NoError(t, err) || !assert.Len(t, records, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, analyticsExportHeader, records[0])
+	assert.Equal(t, strconv.FormatInt(submissionId, 10), records[1][0])
+	assert.NotEqual(t, strconv.FormatInt(userId, 10), records[1][1])
+	assert.Equal(t, "OK", records[1][6])
+	assert.Equal(t, "100", records[1][7])
+
+	// Already exported days are not exported again
+	samePath, err := as.ExportDay(tx, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, path, samePath)
+	tx.Rollback()
+}
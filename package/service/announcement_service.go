@@ -0,0 +1,233 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrAnnouncementNotFound = errors.New("announcement not found")
+
+type AnnouncementService interface {
+	// CreateAnnouncement publishes an announcement. Admins can announce to every user, teachers and admins
+	// can announce to members of a group. Members of the group are notified about announcements published right away,
+	// scheduled announcements only show up once published
+	CreateAnnouncement(tx *gorm.DB, currentUser schemas.User, announcement schemas.AnnouncementCreate) (*schemas.Announcement, error)
+	// GetAnnouncements returns announcements currently visible to the user, newest first
+	GetAnnouncements(tx *gorm.DB, currentUser schemas.User, unreadOnly bool, limit, offset int64) ([]schemas.Announcement, error)
+	// GetGroupAnnouncements returns published announcements to the group, expired ones included, newest first.
+	// Available to members of the group and to users who can announce to groups
+	GetGroupAnnouncements(tx *gorm.DB, currentUser schemas.User, groupId int64, limit, offset int64) ([]schemas.Announcement, error)
+	CountUnreadAnnouncements(tx *gorm.DB, currentUser schemas.User) (*schemas.UnreadAnnouncements, error)
+	// MarkAnnouncementRead marks an announcement currently visible to the user read
+	MarkAnnouncementRead(tx *gorm.DB, currentUser schemas.User, announcementId int64) error
+}
+
+type AnnouncementServiceImpl struct {
+	announcementRepository repository.AnnouncementRepository
+	groupRepository        repository.GroupRepository
+	notificationService    NotificationService
+	accessControlService   AccessControlService
+	logger                 *zap.SugaredLogger
+}
+
+func (as *AnnouncementServiceImpl) CreateAnnouncement(tx *gorm.DB, currentUser schemas.User, announcement schemas.AnnouncementCreate) (*schemas.Announcement, error) {
+	if announcement.GroupId == nil && !as.accessControlService.Can(currentUser, ResourceAnnouncement, ActionBroadcast) {
+		return nil, ErrNotAuthorized
+	}
+	if announcement.GroupId != nil && !as.accessControlService.Can(currentUser, ResourceAnnouncement, ActionPublish) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(announcement); err != nil {
+		as.logger.Errorf("Error validating announcement: %v", err.Error())
+		return nil, err
+	}
+	publishedAt := time.Now()
+	if announcement.PublishedAt != nil {
+		publishedAt = *announcement.PublishedAt
+	}
+	if announcement.GroupId != nil {
+		_, err := as.groupRepository.GetGroup(tx, *announcement.GroupId)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrGroupNotFound
+			}
+			as.logger.Errorf("Error getting group: %v", err.Error())
+			return nil, err
+		}
+	}
+
+	model := &models.Announcement{
+		Title:       announcement.Title,
+		Message:     announcement.Message,
+		GroupId:     announcement.GroupId,
+		CreatedBy:   currentUser.Id,
+		PublishedAt: publishedAt,
+		ExpiresAt:   announcement.ExpiresAt,
+	}
+	_, err := as.announcementRepository.CreateAnnouncement(tx, model)
+	if err != nil {
+		as.logger.Errorf("Error creating announcement: %v", err.Error())
+		return nil, err
+	}
+	as.logger.Infof("Announcement %d published by user %d", model.Id, currentUser.Id)
+
+	if model.GroupId != nil && announcement.PublishedAt == nil {
+		members, err := as.groupRepository.GetGroupMembers(tx, *model.GroupId)
+		if err != nil {
+			as.logger.Errorf("Error getting group members: %v", err.Error())
+			return nil, err
+		}
+		memberIds := make([]int64, 0, len(members))
+		for _, member := range members {
+			memberIds = append(memberIds, member.Id)
+		}
+		actorName := currentUser.Name + " " + currentUser.Surname
+		message := fmt.Sprintf("%s posted announcement \"%s\" to your group.\n\n%s", actorName, model.Title, model.Message)
+		err = as.notificationService.Notify(tx, currentUser, memberIds, models.NotificationTypeGroupAnnouncement, nil, message)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return announcementModelToSchema(model, nil), nil
+}
+
+func (as *AnnouncementServiceImpl) GetAnnouncements(tx *gorm.DB, currentUser schemas.User, unreadOnly bool, limit, offset int64) ([]schemas.Announcement, error) {
+	announcements, err := as.announcementRepository.GetVisibleAnnouncements(tx, currentUser.Id, time.Now(), unreadOnly, limit, offset)
+	if err != nil {
+		as.logger.Errorf("Error getting announcements: %v", err.Error())
+		return nil, err
+	}
+	ids := make([]int64, 0, len(announcements))
+	for _, announcement := range announcements {
+		ids = append(ids, announcement.Id)
+	}
+	readTimes, err := as.announcementRepository.GetReadTimes(tx, currentUser.Id, ids)
+	if err != nil {
+		as.logger.Errorf("Error getting announcement read times: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.Announcement, 0, len(announcements))
+	for i := range announcements {
+		var readAt *time.Time
+		if at, ok := readTimes[announcements[i].Id]; ok {
+			readAt = &at
+		}
+		result = append(result, *announcementModelToSchema(&announcements[i], readAt))
+	}
+	return result, nil
+}
+
+func (as *AnnouncementServiceImpl) GetGroupAnnouncements(tx *gorm.DB, currentUser schemas.User, groupId int64, limit, offset int64) ([]schemas.Announcement, error) {
+	_, err := as.groupRepository.GetGroup(tx, groupId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrGroupNotFound
+		}
+		as.logger.Errorf("Error getting group: %v", err.Error())
+		return nil, err
+	}
+	if !as.accessControlService.Can(currentUser, ResourceAnnouncement, ActionPublish) {
+		members, err := as.groupRepository.GetGroupMembers(tx, groupId)
+		if err != nil {
+			as.logger.Errorf("Error getting group members: %v", err.Error())
+			return nil, err
+		}
+		member := false
+		for _, user := range members {
+			if user.Id == currentUser.Id {
+				member = true
+				break
+			}
+		}
+		if !member {
+			return nil, ErrNotAuthorized
+		}
+	}
+
+	announcements, err := as.announcementRepository.GetGroupAnnouncements(tx, groupId, time.Now(), limit, offset)
+	if err != nil {
+		as.logger.Errorf("Error getting group announcements: %v", err.Error())
+		return nil, err
+	}
+	ids := make([]int64, 0, len(announcements))
+	for _, announcement := range announcements {
+		ids = append(ids, announcement.Id)
+	}
+	readTimes, err := as.announcementRepository.GetReadTimes(tx, currentUser.Id, ids)
+	if err != nil {
+		as.logger.Errorf("Error getting announcement read times: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.Announcement, 0, len(announcements))
+	for i := range announcements {
+		var readAt *time.Time
+		if at, ok := readTimes[announcements[i].Id]; ok {
+			readAt = &at
+		}
+		result = append(result, *announcementModelToSchema(&announcements[i], readAt))
+	}
+	return result, nil
+}
+
+func (as *AnnouncementServiceImpl) CountUnreadAnnouncements(tx *gorm.DB, currentUser schemas.User) (*schemas.UnreadAnnouncements, error) {
+	count, err := as.announcementRepository.CountUnread(tx, currentUser.Id, time.Now())
+	if err != nil {
+		as.logger.Errorf("Error counting unread announcements: %v", err.Error())
+		return nil, err
+	}
+	return &schemas.UnreadAnnouncements{Unread: count}, nil
+}
+
+func (as *AnnouncementServiceImpl) MarkAnnouncementRead(tx *gorm.DB, currentUser schemas.User, announcementId int64) error {
+	visible, err := as.announcementRepository.IsVisible(tx, announcementId, currentUser.Id, time.Now())
+	if err != nil {
+		as.logger.Errorf("Error checking announcement: %v", err.Error())
+		return err
+	}
+	if !visible {
+		return ErrAnnouncementNotFound
+	}
+	err = as.announcementRepository.MarkRead(tx, announcementId, currentUser.Id)
+	if err != nil {
+		as.logger.Errorf("Error marking announcement read: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func announcementModelToSchema(model *models.Announcement, readAt *time.Time) *schemas.Announcement {
+	return &schemas.Announcement{
+		Id:          model.Id,
+		Title:       model.Title,
+		Message:     model.Message,
+		GroupId:     model.GroupId,
+		CreatedBy:   model.CreatedBy,
+		PublishedAt: model.PublishedAt,
+		ExpiresAt:   model.ExpiresAt,
+		ReadAt:      readAt,
+	}
+}
+
+func NewAnnouncementService(announcementRepository repository.AnnouncementRepository, groupRepository repository.GroupRepository, notificationService NotificationService, accessControlService AccessControlService) AnnouncementService {
+	log := logger.NewNamedLogger("announcement_service")
+	return &AnnouncementServiceImpl{
+		announcementRepository: announcementRepository,
+		groupRepository:        groupRepository,
+		notificationService:    notificationService,
+		accessControlService:   accessControlService,
+		logger:                 log,
+	}
+}
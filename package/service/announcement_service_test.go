@@ -0,0 +1,173 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnouncements(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	gr, err := repository.NewGroupRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ar, err := repository.NewAnnouncementRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	nr, err := repository.NewNotificationRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	as := NewAnnouncementService(ar, gr, NewNotificationService(nr, ur, nil), newAccessControlServiceTest(t, tx))
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	users := []*models.User{
+		{Name: "Admin", Surname: "User", Email: "admin@email.com", Username: "admin", PasswordHash: "password", Role: models.UserRoleAdmin},
+		{Name: "Teacher", Surname: "User", Email: "teacher@email.com", Username: "teacher", PasswordHash: "password", Role: models.UserRoleTeacher},
+		{Name: "Member", Surname: "User", Email: "member@email.com", Username: "member", PasswordHash: "password"},
+		{Name: "Other", Surname: "User", Email: "other@email.com", Username: "other", PasswordHash: "password"},
+	}
+	if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+		t.FailNow()
+	}
+	groupId, err := gr.CreateGroup(tx, models.Group{Name: "Group"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, tx.Create(&models.UserGroup{UserId: users[2].Id, GroupId: groupId}).Error) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: users[0].Id, Role: string(models.UserRoleAdmin)}
+	teacher := schemas.User{Id: users[1].Id, Role: string(models.UserRoleTeacher)}
+	member := schemas.User{Id: users[2].Id, Role: string(models.UserRoleStudent)}
+	other := schemas.User{Id: users[3].Id, Role: string(models.UserRoleStudent)}
+	savePoint = "users"
+	tx.SavePoint(savePoint)
+
+	t.Run("Visibility and read tracking", func(t *testing.T) {
+		_, err := as.CreateAnnouncement(tx, admin, schemas.AnnouncementCreate{Title: "Judging delays", Message: "Judging is slower today."})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		groupAnnouncement, err := as.CreateAnnouncement(tx, teacher, schemas.AnnouncementCreate{Title: "Lab", Message: "No lab next week.", GroupId: &groupId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		future := time.Now().Add(time.Hour)
+		_, err = as.CreateAnnouncement(tx, admin, schemas.AnnouncementCreate{Title: "Later", Message: "Not yet.", PublishedAt: &future})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		announcements, err := as.GetAnnouncements(tx, member, false, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, announcements, 2)
+		announcements, err = as.GetAnnouncements(tx, other, false, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, announcements, 1)
+
+		err = as.MarkAnnouncementRead(tx, member, groupAnnouncement.Id)
+		assert.NoError(t, err)
+		unread, err := as.CountUnreadAnnouncements(tx, member)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), unread.Unread)
+		announcements, err = as.GetAnnouncements(tx, member, true, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, announcements, 1)
+
+		err = as.MarkAnnouncementRead(tx, other, groupAnnouncement.Id)
+		assert.ErrorIs(t, err, ErrAnnouncementNotFound)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Members are notified about group announcements", func(t *testing.T) {
+		_, err := as.CreateAnnouncement(tx, teacher, schemas.AnnouncementCreate{Title: "Lab", Message: "No lab next week.", GroupId: &groupId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		future := time.Now().Add(time.Hour)
+		_, err = as.CreateAnnouncement(tx, teacher, schemas.AnnouncementCreate{Title: "Later", Message: "Not yet.", GroupId: &groupId, PublishedAt: &future})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		notifications, err := nr.GetNotifications(tx, member.Id, 10, 0)
+		assert.NoError(t, err)
+		if assert.Len(t, notifications, 1) {
+			assert.Equal(t, models.NotificationTypeGroupAnnouncement, notifications[0].Type)
+			assert.Equal(t, teacher.Id, notifications[0].ActorId)
+		}
+		notifications, err = nr.GetNotifications(tx, other.Id, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, notifications)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Group announcement history", func(t *testing.T) {
+		published := time.Now().Add(-2 * time.Hour)
+		expired := time.Now().Add(-time.Hour)
+		_, err := as.CreateAnnouncement(tx, teacher, schemas.AnnouncementCreate{Title: "Old", Message: "Expired.", GroupId: &groupId, PublishedAt: &published, ExpiresAt: &expired})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = as.CreateAnnouncement(tx, teacher, schemas.AnnouncementCreate{Title: "New", Message: "Current.", GroupId: &groupId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		announcements, err := as.GetGroupAnnouncements(tx, member, groupId, 10, 0)
+		assert.NoError(t, err)
+		if assert.Len(t, announcements, 2) {
+			assert.Equal(t, "New", announcements[0].Title)
+			assert.Equal(t, "Old", announcements[1].Title)
+		}
+		announcements, err = as.GetGroupAnnouncements(tx, teacher, groupId, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, announcements, 2)
+
+		_, err = as.GetGroupAnnouncements(tx, other, groupId, 10, 0)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = as.GetGroupAnnouncements(tx, member, groupId+1, 10, 0)
+		assert.ErrorIs(t, err, ErrGroupNotFound)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Teacher announcing to everyone", func(t *testing.T) {
+		_, err := as.CreateAnnouncement(tx, teacher, schemas.AnnouncementCreate{Title: "Global", Message: "Message"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Expires before published", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		_, err := as.CreateAnnouncement(tx, admin, schemas.AnnouncementCreate{Title: "Expired", Message: "Message", ExpiresAt: &past})
+		var validationErrors validator.ValidationErrors
+		if assert.ErrorAs(t, err, &validationErrors) && assert.Len(t, validationErrors, 1) {
+			assert.Equal(t, "expires_at", validationErrors[0].Field())
+			assert.Equal(t, "gt", validationErrors[0].Tag())
+		}
+
+		published := time.Now().Add(2 * time.Hour)
+		expires := time.Now().Add(time.Hour)
+		_, err = as.CreateAnnouncement(tx, admin, schemas.AnnouncementCreate{Title: "Scheduled", Message: "Message", PublishedAt: &published, ExpiresAt: &expires})
+		if assert.ErrorAs(t, err, &validationErrors) && assert.Len(t, validationErrors, 1) {
+			assert.Equal(t, "expires_at", validationErrors[0].Field())
+			assert.Equal(t, "gtfield", validationErrors[0].Tag())
+		}
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
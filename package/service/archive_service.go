@@ -0,0 +1,125 @@
+package service
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ArchiveBatchSize is the number of submissions loaded at once when archiving or pruning
+const ArchiveBatchSize = 100
+
+type ArchiveService interface {
+	// ArchiveSubmissions moves sources of judged submissions submitted before the given time to the
+	// archive storage class and returns the number of archived submissions. Every submission is recorded
+	// as it is archived, so tx should not be a long running transaction
+	ArchiveSubmissions(tx *gorm.DB, submittedBefore time.Time) (int, error)
+	// PruneSubmissions deletes sources of failing attempts submitted before the given time which are superseded
+	// by a later submission, keeping the best and the latest submission of every user to every task, and
+	// returns the number of pruned submissions. Only tasks retaining the best and latest submissions are
+	// pruned, and tasks following the platform policy if pruneByDefault is set. Verdicts and scores are kept.
+	// Every submission is recorded as it is pruned, so tx should not be a long running transaction
+	PruneSubmissions(tx *gorm.DB, submittedBefore time.Time, pruneByDefault bool) (int, error)
+	// RestoreSubmission moves the source of an archived submission back to the standard storage class
+	// before it is accessed. Does nothing for submissions which are not archived
+	RestoreSubmission(tx *gorm.DB, submission *models.Submission) error
+}
+
+type ArchiveServiceImpl struct {
+	submissionRepository repository.SubmissionRepository
+	fileStorageService   FileStorageService
+	logger               *zap.SugaredLogger
+}
+
+func (as *ArchiveServiceImpl) ArchiveSubmissions(tx *gorm.DB, submittedBefore time.Time) (int, error) {
+	archived := 0
+	for {
+		submissions, err := as.submissionRepository.GetArchivableSubmissions(tx, submittedBefore, ArchiveBatchSize)
+		if err != nil {
+			as.logger.Errorf("Error getting archivable submissions: %v", err.Error())
+			return archived, err
+		}
+		for _, submission := range submissions {
+			err := as.fileStorageService.ArchiveUserSolution(submission.TaskId, submission.UserId, submission.Order)
+			if err != nil && err != ErrFileNotFound {
+				as.logger.Errorf("Error archiving source of submission %d: %v", submission.Id, err.Error())
+				return archived, err
+			}
+			if err == ErrFileNotFound {
+				// Recorded as archived anyway, so the submission is not picked again
+				as.logger.Warnf("Source of submission %d not found in file storage", submission.Id)
+			}
+			now := time.Now()
+			err = as.submissionRepository.SetSubmissionArchived(tx, submission.Id, &now)
+			if err != nil {
+				as.logger.Errorf("Error marking submission %d archived: %v", submission.Id, err.Error())
+				return archived, err
+			}
+			archived++
+		}
+		if len(submissions) < ArchiveBatchSize {
+			return archived, nil
+		}
+	}
+}
+
+func (as *ArchiveServiceImpl) PruneSubmissions(tx *gorm.DB, submittedBefore time.Time, pruneByDefault bool) (int, error) {
+	pruned := 0
+	for {
+		submissions, err := as.submissionRepository.GetPrunableSubmissions(tx, submittedBefore, pruneByDefault, ArchiveBatchSize)
+		if err != nil {
+			as.logger.Errorf("Error getting prunable submissions: %v", err.Error())
+			return pruned, err
+		}
+		for _, submission := range submissions {
+			err := as.fileStorageService.DeleteUserSolution(submission.TaskId, submission.UserId, submission.Order)
+			if err != nil && err != ErrFileNotFound {
+				as.logger.Errorf("Error pruning source of submission %d: %v", submission.Id, err.Error())
+				return pruned, err
+			}
+			err = as.submissionRepository.SetSubmissionPruned(tx, submission.Id)
+			if err != nil {
+				as.logger.Errorf("Error marking submission %d pruned: %v", submission.Id, err.Error())
+				return pruned, err
+			}
+			pruned++
+		}
+		if len(submissions) < ArchiveBatchSize {
+			return pruned, nil
+		}
+	}
+}
+
+func (as *ArchiveServiceImpl) RestoreSubmission(tx *gorm.DB, submission *models.Submission) error {
+	if submission.ArchivedAt == nil || submission.RedactedAt != nil || submission.PrunedAt != nil {
+		return nil
+	}
+
+	start := time.Now()
+	err := as.fileStorageService.RestoreUserSolution(submission.TaskId, submission.UserId, submission.Order)
+	if err != nil && err != ErrFileNotFound {
+		as.logger.Errorf("Error restoring source of submission %d: %v", submission.Id, err.Error())
+		return err
+	}
+	err = as.submissionRepository.SetSubmissionArchived(tx, submission.Id, nil)
+	if err != nil {
+		as.logger.Errorf("Error marking submission %d restored: %v", submission.Id, err.Error())
+		return err
+	}
+	submission.ArchivedAt = nil
+	as.logger.Warnf("Restored archived source of submission %d in %s. Accessing archived submissions is slow", submission.Id, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func NewArchiveService(submissionRepository repository.SubmissionRepository, fileStorageService FileStorageService) ArchiveService {
+	log := logger.NewNamedLogger("archive_service")
+	return &ArchiveServiceImpl{
+		submissionRepository: submissionRepository,
+		fileStorageService:   fileStorageService,
+		logger:               log,
+	}
+}
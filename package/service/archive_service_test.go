@@ -0,0 +1,148 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveSubmissions(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+	as := NewArchiveService(sst.sr, sst.fileStorage)
+
+	taskId, userId := sst.createSubmission(t)
+	submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+	if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+		t.FailNow()
+	}
+	submissionId := submissions[0].Id
+
+	t.Run("Submissions not judged are kept", func(t *testing.T) {
+		archived, err := as.ArchiveSubmissions(sst.tx, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, archived)
+	})
+
+	if !assert.NoError(t, sst.sr.MarkSubmissionComplete(sst.tx, submissionId)) {
+		t.FailNow()
+	}
+
+	t.Run("Recent submissions are kept", func(t *testing.T) {
+		archived, err := as.ArchiveSubmissions(sst.tx, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, archived)
+		assert.Empty(t, sst.fileStorage.archived)
+	})
+
+	t.Run("Old submissions are archived once", func(t *testing.T) {
+		archived, err := as.ArchiveSubmissions(sst.tx, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, archived)
+		archived, err = as.ArchiveSubmissions(sst.tx, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, archived)
+		assert.Equal(t, []int64{1}, sst.fileStorage.archived)
+
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.NotNil(t, submission.ArchivedAt)
+	})
+
+	t.Run("Archived sources are restored on access", func(t *testing.T) {
+		_, err := sst.submissionService.ExportUserSubmissions(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []int64{1}, sst.fileStorage.restored)
+
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.Nil(t, submission.ArchivedAt)
+
+		// Restored sources are read from the standard storage class again
+		_, err = sst.submissionService.ExportUserSubmissions(sst.tx, taskId, userId)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1}, sst.fileStorage.restored)
+	})
+	sst.tx.Rollback()
+}
+
+func TestPruneSubmissions(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+	as := NewArchiveService(sst.sr, sst.fileStorage)
+
+	taskId, userId := sst.createSubmission(t)
+	submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+	if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+		t.FailNow()
+	}
+	// The first attempt is the best and the fourth the latest, the two in between are superseded
+	for i, score := range []float64{50, 20, 0, 10} {
+		submissionId := submissions[0].Id
+		if i > 0 {
+			submissionId, err = sst.sr.CreateSubmission(sst.tx, models.Submission{
+				TaskId:     taskId,
+				UserId:     userId,
+				Order:      int64(i + 1),
+				LanguageId: submissions[0].LanguageId,
+				Status:     "received",
+			})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+		}
+		if !assert.NoError(t, sst.sr.MarkSubmissionComplete(sst.tx, submissionId)) {
+			t.FailNow()
+		}
+		result := &models.SubmissionResult{SubmissionId: submissionId, Code: "WA", Message: "checked", Score: score}
+		if !assert.NoError(t, sst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+	}
+
+	t.Run("Tasks following a platform policy keeping everything are kept", func(t *testing.T) {
+		pruned, err := as.PruneSubmissions(sst.tx, time.Now().Add(time.Hour), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, pruned)
+	})
+
+	t.Run("Tasks keeping everything are kept", func(t *testing.T) {
+		if !assert.NoError(t, sst.tr.SetSubmissionRetention(sst.tx, taskId, models.SubmissionRetentionAll)) {
+			t.FailNow()
+		}
+		pruned, err := as.PruneSubmissions(sst.tx, time.Now().Add(time.Hour), true)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, pruned)
+		assert.Empty(t, sst.fileStorage.deleted)
+	})
+
+	t.Run("Recent submissions are kept", func(t *testing.T) {
+		if !assert.NoError(t, sst.tr.SetSubmissionRetention(sst.tx, taskId, models.SubmissionRetentionBestAndLatest)) {
+			t.FailNow()
+		}
+		pruned, err := as.PruneSubmissions(sst.tx, time.Now().Add(-time.Hour), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, pruned)
+	})
+
+	t.Run("Superseded failing attempts are pruned once", func(t *testing.T) {
+		pruned, err := as.PruneSubmissions(sst.tx, time.Now().Add(time.Hour), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, pruned)
+		pruned, err = as.PruneSubmissions(sst.tx, time.Now().Add(time.Hour), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, pruned)
+		assert.Equal(t, []int64{2, 3}, sst.fileStorage.deleted)
+
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		for _, submission := range submissions {
+			assert.Equal(t, submission.Order == 2 || submission.Order == 3, submission.PrunedAt != nil, "submission %d", submission.Order)
+		}
+	})
+	sst.tx.Rollback()
+}
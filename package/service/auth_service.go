@@ -1,7 +1,18 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mini-maxit/backend/internal/logger"
 	"github.com/mini-maxit/backend/package/domain/models"
@@ -13,19 +24,72 @@ import (
 	"gorm.io/gorm"
 )
 
+// RefreshTokenLifetime is how long a refresh token can be exchanged for a session
+const RefreshTokenLifetime = 30 * 24 * time.Hour
+
+// PasswordResetTokenLifetime is how long a password reset token can be used
+const PasswordResetTokenLifetime = time.Hour
+
+// PasswordResetCooldown is how long after a reset mail no other one is sent to the user
+const PasswordResetCooldown = 5 * time.Minute
+
+// OAuthStateLifetime is how long a user has to log in at an OAuth provider
+const OAuthStateLifetime = 10 * time.Minute
+
+// AuthTokenBytes is the number of random bytes of generated refresh and password reset tokens
+const AuthTokenBytes = 32
+
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCredentials        = errors.New("invalid credentials")
+	ErrInvalidRefreshToken       = errors.New("refresh token is invalid, expired or revoked")
+	ErrInvalidPasswordResetToken = errors.New("password reset token is invalid, expired or used")
+	ErrPasswordResetDisabled     = errors.New("password reset is disabled")
+	ErrOAuthProviderNotFound     = errors.New("oauth provider not found")
+	ErrInvalidOAuthState         = errors.New("oauth state is invalid, expired or used")
+	ErrOAuthLoginFailed          = errors.New("login with the oauth provider failed")
+	ErrOAuthEmailNotVerified     = errors.New("email is not verified by the oauth provider")
 )
 
 type AuthService interface {
+	// Login returns a session together with a refresh token
 	Login(tx *gorm.DB, userLogin schemas.UserLoginRequest) (*schemas.Session, error)
 	Register(tx *gorm.DB, userRegister schemas.UserRegisterRequest) (*schemas.Session, error)
+	// Refresh exchanges a refresh token for an extended session and a new refresh token, the old one is revoked.
+	// A revoked token used again is taken as stolen and all refresh tokens of its user are revoked
+	Refresh(tx *gorm.DB, request schemas.RefreshTokenRequest) (*schemas.Session, error)
+	// Logout revokes the refresh token and invalidates the session issued with it
+	Logout(tx *gorm.DB, request schemas.RefreshTokenRequest) error
+	// ForgotPassword mails a password reset link to the user. Unknown emails are not reported,
+	// so registered emails cannot be discovered
+	ForgotPassword(tx *gorm.DB, request schemas.ForgotPasswordRequest) error
+	// ResetPassword sets a new password with a reset token. All reset and refresh tokens of the user are revoked
+	ResetPassword(tx *gorm.DB, request schemas.ResetPasswordRequest) error
+	// OAuthLogin starts a login with the provider and returns the url of its login page, together with the
+	// nonce the client has to present in the callback
+	OAuthLogin(tx *gorm.DB, provider string) (*schemas.OAuthLogin, error)
+	// UseOAuthState marks the state of the callback as used and returns its PKCE code verifier. The state must
+	// have been started by the same client. It should be committed before OAuthCallback, so a failed login
+	// does not make the state usable again
+	UseOAuthState(tx *gorm.DB, provider string, request schemas.OAuthCallbackRequest) (string, error)
+	// OAuthCallback finishes a login with the provider. The account linked to the identity at the provider is
+	// logged in. Otherwise the account with the same verified email is linked, or a new one is created with the
+	// role given by the role rules of the provider
+	OAuthCallback(tx *gorm.DB, provider string, code string, codeVerifier string) (*schemas.Session, error)
 }
 
 type AuthServiceImpl struct {
-	userRepository repository.UserRepository
-	sessionService SessionService
-	logger         *zap.SugaredLogger
+	userRepository          repository.UserRepository
+	refreshTokenRepository  repository.RefreshTokenRepository
+	passwordResetRepository repository.PasswordResetRepository
+	oauthRepository         repository.OAuthRepository
+	sessionService          SessionService
+	// Nil when password reset is disabled
+	mailService      MailService
+	passwordResetUrl string
+	oauthProviders   map[string]OAuthProvider
+	// Nil when ldap login is disabled
+	ldapAuthenticator LDAPAuthenticator
+	logger            *zap.SugaredLogger
 }
 
 func (as *AuthServiceImpl) Login(tx *gorm.DB, userLogin schemas.UserLoginRequest) (*schemas.Session, error) {
@@ -35,6 +99,13 @@ func (as *AuthServiceImpl) Login(tx *gorm.DB, userLogin schemas.UserLoginRequest
 		return nil, err
 	}
 
+	if as.ldapAuthenticator != nil {
+		session, err := as.ldapLogin(tx, userLogin)
+		if err != ErrLDAPUserNotFound {
+			return session, err
+		}
+	}
+
 	user, err := as.userRepository.GetUserByEmail(tx, userLogin.Email)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -55,6 +126,10 @@ func (as *AuthServiceImpl) Login(tx *gorm.DB, userLogin schemas.UserLoginRequest
 		as.logger.Errorf("Error creating session: %v", err.Error())
 		return nil, err
 	}
+	err = as.issueRefreshToken(tx, session)
+	if err != nil {
+		return nil, err
+	}
 	as.logger.Infof("User logged in successfully")
 	return session, nil
 }
@@ -103,15 +178,551 @@ func (as *AuthServiceImpl) Register(tx *gorm.DB, userRegister schemas.UserRegist
 		return nil, err
 	}
 
+	err = as.issueRefreshToken(tx, session)
+	if err != nil {
+		return nil, err
+	}
 	as.logger.Infof("User registered successfully")
 	return session, nil
 }
 
-func NewAuthService(userRepository repository.UserRepository, sessionService SessionService) AuthService {
+func (as *AuthServiceImpl) Refresh(tx *gorm.DB, request schemas.RefreshTokenRequest) (*schemas.Session, error) {
+	token, err := as.getRefreshToken(tx, request)
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, as.revokeReusedToken(tx, token)
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := as.userRepository.GetUser(tx, token.UserId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidRefreshToken
+		}
+		as.logger.Errorf("Error getting user by id: %v", err.Error())
+		return nil, err
+	}
+	revoked, err := as.refreshTokenRepository.RevokeToken(tx, token.Id)
+	if err != nil {
+		as.logger.Errorf("Error revoking refresh token: %v", err.Error())
+		return nil, err
+	}
+	// A concurrent refresh with the same token revoked it first
+	if !revoked {
+		return nil, as.revokeReusedToken(tx, token)
+	}
+	// Sessions are shared by all clients of the user, so a still valid one is extended rather than replaced
+	session, err := as.sessionService.CreateSession(tx, user.Id)
+	if err != nil {
+		as.logger.Errorf("Error creating session: %v", err.Error())
+		return nil, err
+	}
+	session, err = as.sessionService.RefreshSession(tx, session.Id)
+	if err != nil {
+		return nil, err
+	}
+	session.UserRole = string(user.Role)
+	err = as.issueRefreshToken(tx, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// revokeReusedToken handles a revoked refresh token used again, which means it was stolen. All refresh tokens of
+// the user and the session, which the thief shares with the user, are revoked. It returns ErrInvalidRefreshToken
+func (as *AuthServiceImpl) revokeReusedToken(tx *gorm.DB, token *models.RefreshToken) error {
+	as.logger.Warnf("Revoked refresh token %d of user %d was used again, revoking all refresh tokens and the session of the user", token.Id, token.UserId)
+	err := as.refreshTokenRepository.RevokeUserTokens(tx, token.UserId)
+	if err != nil {
+		as.logger.Errorf("Error revoking refresh tokens: %v", err.Error())
+		return err
+	}
+	err = as.sessionService.InvalidateSession(tx, token.SessionId)
+	if err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+func (as *AuthServiceImpl) Logout(tx *gorm.DB, request schemas.RefreshTokenRequest) error {
+	token, err := as.getRefreshToken(tx, request)
+	if err != nil {
+		return err
+	}
+	if token.RevokedAt == nil {
+		_, err = as.refreshTokenRepository.RevokeToken(tx, token.Id)
+		if err != nil {
+			as.logger.Errorf("Error revoking refresh token: %v", err.Error())
+			return err
+		}
+	}
+	err = as.sessionService.InvalidateSession(tx, token.SessionId)
+	if err != nil {
+		return err
+	}
+	as.logger.Infof("User %d logged out", token.UserId)
+	return nil
+}
+
+// getRefreshToken validates the request and returns the stored token, revoked and expired ones included
+func (as *AuthServiceImpl) getRefreshToken(tx *gorm.DB, request schemas.RefreshTokenRequest) (*models.RefreshToken, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		as.logger.Errorf("Error validating refresh token request: %v", err.Error())
+		return nil, err
+	}
+
+	token, err := as.refreshTokenRepository.GetTokenByHash(tx, hashToken(request.RefreshToken))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidRefreshToken
+		}
+		as.logger.Errorf("Error getting refresh token: %v", err.Error())
+		return nil, err
+	}
+	return token, nil
+}
+
+// issueRefreshToken generates a refresh token for the session and sets it on the session
+func (as *AuthServiceImpl) issueRefreshToken(tx *gorm.DB, session *schemas.Session) error {
+	refreshToken, err := generateAuthToken()
+	if err != nil {
+		as.logger.Errorf("Error generating refresh token: %v", err.Error())
+		return err
+	}
+	expiresAt := time.Now().Add(RefreshTokenLifetime)
+	err = as.refreshTokenRepository.CreateToken(tx, &models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		UserId:    session.UserId,
+		SessionId: session.Id,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		as.logger.Errorf("Error creating refresh token: %v", err.Error())
+		return err
+	}
+	session.RefreshToken = refreshToken
+	session.RefreshTokenExpiresAt = &expiresAt
+	return nil
+}
+
+func (as *AuthServiceImpl) ForgotPassword(tx *gorm.DB, request schemas.ForgotPasswordRequest) error {
+	if as.mailService == nil {
+		return ErrPasswordResetDisabled
+	}
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		as.logger.Errorf("Error validating forgot password request: %v", err.Error())
+		return err
+	}
+
+	user, err := as.userRepository.GetUserByEmail(tx, request.Email)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		as.logger.Errorf("Error getting user by email: %v", err.Error())
+		return err
+	}
+	latest, err := as.passwordResetRepository.GetLatestToken(tx, user.Id)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		as.logger.Errorf("Error getting password reset token: %v", err.Error())
+		return err
+	}
+	if err == nil && latest.CreatedAt.After(time.Now().Add(-PasswordResetCooldown)) {
+		as.logger.Infof("Password reset of user %d requested again within cooldown, no mail sent", user.Id)
+		return nil
+	}
+
+	resetToken, err := generateAuthToken()
+	if err != nil {
+		as.logger.Errorf("Error generating password reset token: %v", err.Error())
+		return err
+	}
+	err = as.passwordResetRepository.CreateToken(tx, &models.PasswordResetToken{
+		TokenHash: hashToken(resetToken),
+		UserId:    user.Id,
+		ExpiresAt: time.Now().Add(PasswordResetTokenLifetime),
+	})
+	if err != nil {
+		as.logger.Errorf("Error creating password reset token: %v", err.Error())
+		return err
+	}
+	resetLink, err := url.Parse(as.passwordResetUrl)
+	if err != nil {
+		as.logger.Errorf("Error parsing password reset url: %v", err.Error())
+		return err
+	}
+	query := resetLink.Query()
+	query.Set("token", resetToken)
+	resetLink.RawQuery = query.Encode()
+	body := fmt.Sprintf("A password reset was requested for your account. Set a new password within %d minutes at:\n\n%s\n\n"+
+		"If you did not request it, ignore this mail.", int(PasswordResetTokenLifetime.Minutes()), resetLink.String())
+	// Sent last, so a failure leaves the transaction to roll back without an unsent token. Mails are sent in the
+	// background, so the response takes as long as for unknown emails
+	err = as.mailService.Send(user.Email, "Password reset", body)
+	if err != nil {
+		return err
+	}
+	as.logger.Infof("Password reset mail sent to user %d", user.Id)
+	return nil
+}
+
+func (as *AuthServiceImpl) ResetPassword(tx *gorm.DB, request schemas.ResetPasswordRequest) error {
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		as.logger.Errorf("Error validating reset password request: %v", err.Error())
+		return err
+	}
+
+	token, err := as.passwordResetRepository.GetTokenByHash(tx, hashToken(request.Token))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrInvalidPasswordResetToken
+		}
+		as.logger.Errorf("Error getting password reset token: %v", err.Error())
+		return err
+	}
+	if token.UsedAt != nil || token.ExpiresAt.Before(time.Now()) {
+		return ErrInvalidPasswordResetToken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		as.logger.Errorf("Error generating password hash: %v", err.Error())
+		return err
+	}
+	err = as.userRepository.UpdatePassword(tx, token.UserId, string(hash))
+	if err != nil {
+		as.logger.Errorf("Error updating password: %v", err.Error())
+		return err
+	}
+	err = as.passwordResetRepository.UseUserTokens(tx, token.UserId)
+	if err != nil {
+		as.logger.Errorf("Error using password reset tokens: %v", err.Error())
+		return err
+	}
+	err = as.refreshTokenRepository.RevokeUserTokens(tx, token.UserId)
+	if err != nil {
+		as.logger.Errorf("Error revoking refresh tokens: %v", err.Error())
+		return err
+	}
+	// Whoever knew the old password may still hold the session
+	err = as.sessionService.InvalidateUserSession(tx, token.UserId)
+	if err != nil {
+		return err
+	}
+	as.logger.Infof("Password of user %d reset", token.UserId)
+	return nil
+}
+
+func (as *AuthServiceImpl) OAuthLogin(tx *gorm.DB, provider string) (*schemas.OAuthLogin, error) {
+	oauthProvider, ok := as.oauthProviders[provider]
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+	state, err := generateAuthToken()
+	if err != nil {
+		as.logger.Errorf("Error generating oauth state: %v", err.Error())
+		return nil, err
+	}
+	codeVerifier, err := generateAuthToken()
+	if err != nil {
+		as.logger.Errorf("Error generating oauth code verifier: %v", err.Error())
+		return nil, err
+	}
+	clientNonce, err := generateAuthToken()
+	if err != nil {
+		as.logger.Errorf("Error generating oauth client nonce: %v", err.Error())
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authorizationUrl, err := oauthProvider.AuthorizationUrl(state, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		as.logger.Errorf("Error getting authorization url of oauth provider %s: %v", provider, err.Error())
+		return nil, ErrOAuthLoginFailed
+	}
+	err = as.oauthRepository.CreateState(tx, &models.OAuthState{
+		StateHash:       hashToken(state),
+		ClientNonceHash: hashToken(clientNonce),
+		Provider:        provider,
+		CodeVerifier:    codeVerifier,
+		ExpiresAt:       time.Now().Add(OAuthStateLifetime),
+	})
+	if err != nil {
+		as.logger.Errorf("Error creating oauth state: %v", err.Error())
+		return nil, err
+	}
+	return &schemas.OAuthLogin{AuthorizationUrl: authorizationUrl, ClientNonce: clientNonce}, nil
+}
+
+func (as *AuthServiceImpl) UseOAuthState(tx *gorm.DB, provider string, request schemas.OAuthCallbackRequest) (string, error) {
+	if _, ok := as.oauthProviders[provider]; !ok {
+		return "", ErrOAuthProviderNotFound
+	}
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		as.logger.Errorf("Error validating oauth callback request: %v", err.Error())
+		return "", err
+	}
+
+	state, err := as.oauthRepository.UseState(tx, hashToken(request.State))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", ErrInvalidOAuthState
+		}
+		as.logger.Errorf("Error using oauth state: %v", err.Error())
+		return "", err
+	}
+	if state.Provider != provider || state.ExpiresAt.Before(time.Now()) {
+		return "", ErrInvalidOAuthState
+	}
+	// A state sent by another client is rejected, so nobody can log a victim into the attacker's account
+	if subtle.ConstantTimeCompare([]byte(hashToken(request.ClientNonce)), []byte(state.ClientNonceHash)) != 1 {
+		return "", ErrInvalidOAuthState
+	}
+	return state.CodeVerifier, nil
+}
+
+func (as *AuthServiceImpl) OAuthCallback(tx *gorm.DB, provider string, code string, codeVerifier string) (*schemas.Session, error) {
+	oauthProvider, ok := as.oauthProviders[provider]
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+	identity, err := oauthProvider.Exchange(code, codeVerifier)
+	if err != nil {
+		as.logger.Errorf("Error logging in with oauth provider %s: %v", provider, err.Error())
+		return nil, ErrOAuthLoginFailed
+	}
+
+	user, err := as.getOAuthUser(tx, provider, oauthProvider, identity)
+	if err != nil {
+		return nil, err
+	}
+	session, err := as.sessionService.CreateSession(tx, user.Id)
+	if err != nil {
+		as.logger.Errorf("Error creating session: %v", err.Error())
+		return nil, err
+	}
+	err = as.issueRefreshToken(tx, session)
+	if err != nil {
+		return nil, err
+	}
+	as.logger.Infof("User %d logged in with oauth provider %s", user.Id, provider)
+	return session, nil
+}
+
+// getOAuthUser returns the account linked to the identity, linking or creating one on the first login
+func (as *AuthServiceImpl) getOAuthUser(tx *gorm.DB, provider string, oauthProvider OAuthProvider, identity *OAuthIdentity) (*models.User, error) {
+	linked, err := as.oauthRepository.GetIdentity(tx, provider, identity.Subject)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		as.logger.Errorf("Error getting user identity: %v", err.Error())
+		return nil, err
+	}
+	if err == nil {
+		if linked.Email != identity.Email {
+			err = as.oauthRepository.UpdateIdentityEmail(tx, linked.Id, identity.Email)
+			if err != nil {
+				as.logger.Errorf("Error updating user identity: %v", err.Error())
+				return nil, err
+			}
+		}
+		user, err := as.userRepository.GetUser(tx, linked.UserId)
+		if err != nil {
+			as.logger.Errorf("Error getting user by id: %v", err.Error())
+			return nil, err
+		}
+		return user, nil
+	}
+
+	// Accounts are matched by email only when the provider verified it, otherwise anyone could take over
+	// an account by registering its email at the provider
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOAuthEmailNotVerified
+	}
+	user, err := as.userRepository.GetUserByEmail(tx, identity.Email)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		as.logger.Errorf("Error getting user by email: %v", err.Error())
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		user, err = as.createOAuthUser(tx, oauthProvider, identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = as.oauthRepository.CreateIdentity(tx, &models.UserIdentity{
+		UserId:   user.Id,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		as.logger.Errorf("Error creating user identity: %v", err.Error())
+		return nil, err
+	}
+	as.logger.Infof("User %d linked to oauth provider %s", user.Id, provider)
+	return user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// createOAuthUser creates an account without a password, named after the identity. The username is made
+// from the local part of the email
+func (as *AuthServiceImpl) createOAuthUser(tx *gorm.DB, oauthProvider OAuthProvider, identity *OAuthIdentity) (*models.User, error) {
+	localPart, _, _ := strings.Cut(identity.Email, "@")
+	username, err := as.availableUsername(tx, localPart)
+	if err != nil {
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = localPart
+	}
+	user := &models.User{
+		Name:     name,
+		Surname:  identity.Surname,
+		Email:    identity.Email,
+		Username: username,
+		// No bcrypt hash matches an empty one, so the account can only log in with the provider
+		// until a password is set with a password reset
+		PasswordHash: "",
+		Role:         oauthProvider.Role(identity.Email),
+	}
+	userId, err := as.userRepository.CreateUser(tx, user)
+	if err != nil {
+		as.logger.Errorf("Error creating user: %v", err.Error())
+		return nil, err
+	}
+	user.Id = userId
+	as.logger.Infof("User %d created on first login with an oauth provider as %s", userId, user.Role)
+	return user, nil
+}
+
+// ldapLogin logs in a directory user, creating the account on the first login and updating its role to the
+// groups of the user on later ones. Returns ErrLDAPUserNotFound when the email is not in the directory
+func (as *AuthServiceImpl) ldapLogin(tx *gorm.DB, userLogin schemas.UserLoginRequest) (*schemas.Session, error) {
+	ldapUser, err := as.ldapAuthenticator.Authenticate(userLogin.Email, userLogin.Password)
+	if err != nil {
+		if err == ErrLDAPUserNotFound || err == ErrInvalidCredentials {
+			return nil, err
+		}
+		as.logger.Errorf("Error authenticating with ldap: %v", err.Error())
+		return nil, ErrLDAPUnavailable
+	}
+
+	user, err := as.userRepository.GetUserByEmail(tx, userLogin.Email)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		as.logger.Errorf("Error getting user by email: %v", err.Error())
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		username, err := as.availableUsername(tx, ldapUser.Username)
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{
+			Name:     ldapUser.Name,
+			Surname:  ldapUser.Surname,
+			Email:    userLogin.Email,
+			Username: username,
+			// The password is checked by the directory, so the account has none
+			PasswordHash: "",
+			Role:         ldapUser.Role,
+		}
+		user.Id, err = as.userRepository.CreateUser(tx, user)
+		if err != nil {
+			as.logger.Errorf("Error creating user: %v", err.Error())
+			return nil, err
+		}
+		as.logger.Infof("User %d created on first ldap login as %s", user.Id, user.Role)
+	} else if user.Role.IsBuiltIn() && user.Role != ldapUser.Role {
+		// Custom roles are assigned by admins and are not known to the directory, so they are kept
+		err = as.userRepository.UpdateRole(tx, user.Id, ldapUser.Role)
+		if err != nil {
+			as.logger.Errorf("Error updating user role: %v", err.Error())
+			return nil, err
+		}
+		as.logger.Infof("Role of user %d changed from %s to %s by ldap groups", user.Id, user.Role, ldapUser.Role)
+	}
+
+	session, err := as.sessionService.CreateSession(tx, user.Id)
+	if err != nil {
+		as.logger.Errorf("Error creating session: %v", err.Error())
+		return nil, err
+	}
+	err = as.issueRefreshToken(tx, session)
+	if err != nil {
+		return nil, err
+	}
+	as.logger.Infof("User %d logged in with ldap", user.Id)
+	return session, nil
+}
+
+// maxUsernameAttempts is the number of numbered usernames tried when the one wanted is taken
+const maxUsernameAttempts = 100
+
+// availableUsername returns a free valid username made from wanted, numbered if it is taken
+func (as *AuthServiceImpl) availableUsername(tx *gorm.DB, wanted string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(wanted, "_")
+	if base == "" || !(base[0] >= 'a' && base[0] <= 'z' || base[0] >= 'A' && base[0] <= 'Z') {
+		base = "user_" + base
+	}
+	base = strings.TrimRight(base[:min(len(base), 26)], "_")
+	for len(base) < 3 {
+		base += "_"
+	}
+	for attempt := 1; attempt <= maxUsernameAttempts; attempt++ {
+		candidate := base
+		if attempt > 1 {
+			candidate += strconv.Itoa(attempt)
+		}
+		taken, err := as.userRepository.GetUsersByEmailsOrUsernames(tx, nil, []string{candidate})
+		if err != nil {
+			as.logger.Errorf("Error getting users by username: %v", err.Error())
+			return "", err
+		}
+		if len(taken) == 0 {
+			return candidate, nil
+		}
+	}
+	return "", ErrUserAlreadyExists
+}
+
+// generateAuthToken returns a random hex encoded token of AuthTokenBytes bytes
+func generateAuthToken() (string, error) {
+	key := make([]byte, AuthTokenBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// NewAuthService creates the service. Password reset is disabled when mailService is nil, oauthProviders are the
+// providers users can log in with by name. Ldap login is disabled when ldapAuthenticator is nil
+func NewAuthService(userRepository repository.UserRepository, refreshTokenRepository repository.RefreshTokenRepository, passwordResetRepository repository.PasswordResetRepository, oauthRepository repository.OAuthRepository, sessionService SessionService, mailService MailService, passwordResetUrl string, oauthProviders map[string]OAuthProvider, ldapAuthenticator LDAPAuthenticator) AuthService {
 	log := logger.NewNamedLogger("auth_service")
 	return &AuthServiceImpl{
-		userRepository: userRepository,
-		sessionService: sessionService,
-		logger:         log,
+		userRepository:          userRepository,
+		refreshTokenRepository:  refreshTokenRepository,
+		passwordResetRepository: passwordResetRepository,
+		oauthRepository:         oauthRepository,
+		sessionService:          sessionService,
+		mailService:             mailService,
+		passwordResetUrl:        passwordResetUrl,
+		oauthProviders:          oauthProviders,
+		ldapAuthenticator:       ldapAuthenticator,
+		logger:                  log,
 	}
 }
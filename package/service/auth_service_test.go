@@ -1,6 +1,8 @@
 package service
 
 import (
+	"errors"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -12,14 +14,66 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+type mailServiceStub struct {
+	sent []string
+}
+
+func (ms *mailServiceStub) Send(to string, subject string, body string) error {
+	ms.sent = append(ms.sent, body)
+	return nil
+}
+
+type oauthProviderStub struct {
+	identity OAuthIdentity
+}
+
+func (ps *oauthProviderStub) AuthorizationUrl(state string, codeChallenge string) (string, error) {
+	return "https://sso.example.edu/authorize?" + url.Values{"state": {state}, "code_challenge": {codeChallenge}}.Encode(), nil
+}
+
+func (ps *oauthProviderStub) Exchange(code string, codeVerifier string) (*OAuthIdentity, error) {
+	if code != "code" {
+		return nil, errors.New("invalid code")
+	}
+	identity := ps.identity
+	return &identity, nil
+}
+
+func (ps *oauthProviderStub) Role(email string) models.UserRole {
+	if strings.HasSuffix(email, "@staff.example.edu") {
+		return models.UserRoleTeacher
+	}
+	return models.UserRoleStudent
+}
+
+type ldapAuthenticatorStub struct {
+	users    map[string]LDAPUser
+	password string
+}
+
+func (ls *ldapAuthenticatorStub) Authenticate(email string, password string) (*LDAPUser, error) {
+	user, ok := ls.users[email]
+	if !ok {
+		return nil, ErrLDAPUserNotFound
+	}
+	if password != ls.password {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
 func TestRegister(t *testing.T) {
 	tx := testutils.NewTestTx(t)
 	ur, err := repository.NewUserRepository(tx)
 	assert.NoError(t, err)
 	sr, err := repository.NewSessionRepository(tx)
 	assert.NoError(t, err)
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	assert.NoError(t, err)
+	prr, err := repository.NewPasswordResetRepository(tx)
+	assert.NoError(t, err)
 	ss := NewSessionService(sr, ur)
-	as := NewAuthService(ur, ss)
+	as := NewAuthService(ur, rtr, prr, nil, ss, nil, "", nil, nil)
 	savePoint := "before"
 	tx.SavePoint(savePoint)
 
@@ -113,8 +167,12 @@ func TestLogin(t *testing.T) {
 	assert.NoError(t, err)
 	sr, err := repository.NewSessionRepository(tx)
 	assert.NoError(t, err)
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	assert.NoError(t, err)
+	prr, err := repository.NewPasswordResetRepository(tx)
+	assert.NoError(t, err)
 	ss := NewSessionService(sr, ur)
-	as := NewAuthService(ur, ss)
+	as := NewAuthService(ur, rtr, prr, nil, ss, nil, "", nil, nil)
 	savePoint := "before"
 	tx.SavePoint(savePoint)
 
@@ -195,3 +253,376 @@ func TestLogin(t *testing.T) {
 
 	tx.Rollback()
 }
+
+func TestRefresh(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	assert.NoError(t, err)
+	sr, err := repository.NewSessionRepository(tx)
+	assert.NoError(t, err)
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	assert.NoError(t, err)
+	prr, err := repository.NewPasswordResetRepository(tx)
+	assert.NoError(t, err)
+	ss := NewSessionService(sr, ur)
+	as := NewAuthService(ur, rtr, prr, nil, ss, nil, "", nil, nil)
+	savePoint := "before"
+	tx.SavePoint(savePoint)
+
+	register := func(t *testing.T) *schemas.Session {
+		session, err := as.Register(tx, schemas.UserRegisterRequest{
+			Name:     "name",
+			Surname:  "surname",
+			Email:    "email@email.com",
+			Username: "username",
+			Password: strings.Repeat("a", 13),
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NotEmpty(t, session.RefreshToken)
+		return session
+	}
+
+	t.Run("refresh rotates the refresh token", func(t *testing.T) {
+		session := register(t)
+		refreshed, err := as.Refresh(tx, schemas.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, session.UserId, refreshed.UserId)
+		assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+
+		// Reuse of the rotated token revokes the new one as well
+		_, err = as.Refresh(tx, schemas.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		_, err = as.Refresh(tx, schemas.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		// The session is shared with whoever reused the token, so it is invalidated too
+		_, err = ss.ValidateSession(tx, refreshed.Id)
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("a token is revoked only once", func(t *testing.T) {
+		session := register(t)
+		token, err := rtr.GetTokenByHash(tx, hashToken(session.RefreshToken))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		// Of two concurrent refreshes with the same token, the second one finds it revoked
+		revoked, err := rtr.RevokeToken(tx, token.Id)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		revoked, err = rtr.RevokeToken(tx, token.Id)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("logout revokes the refresh token", func(t *testing.T) {
+		session := register(t)
+		err := as.Logout(tx, schemas.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ss.ValidateSession(tx, session.Id)
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+		_, err = as.Refresh(tx, schemas.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("unknown refresh token", func(t *testing.T) {
+		_, err := as.Refresh(tx, schemas.RefreshTokenRequest{RefreshToken: "unknown"})
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	tx.Rollback()
+}
+
+func TestPasswordReset(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	assert.NoError(t, err)
+	sr, err := repository.NewSessionRepository(tx)
+	assert.NoError(t, err)
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	assert.NoError(t, err)
+	prr, err := repository.NewPasswordResetRepository(tx)
+	assert.NoError(t, err)
+	ss := NewSessionService(sr, ur)
+	mailService := &mailServiceStub{}
+	as := NewAuthService(ur, rtr, prr, nil, ss, mailService, "https://maxit.example/reset", nil, nil)
+	savePoint := "before"
+	tx.SavePoint(savePoint)
+
+	password := strings.Repeat("a", 13)
+	newPassword := strings.Repeat("b", 13)
+	session, err := as.Register(tx, schemas.UserRegisterRequest{
+		Name:     "name",
+		Surname:  "surname",
+		Email:    "email@email.com",
+		Username: "username",
+		Password: password,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tx.SavePoint(savePoint)
+
+	t.Run("reset with mailed token", func(t *testing.T) {
+		mailService.sent = nil
+		err := as.ForgotPassword(tx, schemas.ForgotPasswordRequest{Email: "email@email.com"})
+		if !assert.NoError(t, err) || !assert.Len(t, mailService.sent, 1) {
+			t.FailNow()
+		}
+		_, token, found := strings.Cut(mailService.sent[0], "?token=")
+		if !assert.True(t, found) {
+			t.FailNow()
+		}
+		token = strings.Fields(token)[0]
+
+		// Requests within the cooldown do not send another mail
+		err = as.ForgotPassword(tx, schemas.ForgotPasswordRequest{Email: "email@email.com"})
+		assert.NoError(t, err)
+		assert.Len(t, mailService.sent, 1)
+
+		err = as.ResetPassword(tx, schemas.ResetPasswordRequest{Token: token, Password: newPassword})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ss.ValidateSession(tx, session.Id)
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+		_, err = as.Login(tx, schemas.UserLoginRequest{Email: "email@email.com", Password: newPassword})
+		assert.NoError(t, err)
+		_, err = as.Refresh(tx, schemas.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		err = as.ResetPassword(tx, schemas.ResetPasswordRequest{Token: token, Password: password})
+		assert.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("unknown email is not reported", func(t *testing.T) {
+		mailService.sent = nil
+		err := as.ForgotPassword(tx, schemas.ForgotPasswordRequest{Email: "unknown@email.com"})
+		assert.NoError(t, err)
+		assert.Empty(t, mailService.sent)
+	})
+
+	t.Run("password reset disabled", func(t *testing.T) {
+		disabled := NewAuthService(ur, rtr, prr, nil, ss, nil, "", nil, nil)
+		err := disabled.ForgotPassword(tx, schemas.ForgotPasswordRequest{Email: "email@email.com"})
+		assert.ErrorIs(t, err, ErrPasswordResetDisabled)
+	})
+
+	tx.Rollback()
+}
+
+func TestOAuthLogin(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	assert.NoError(t, err)
+	sr, err := repository.NewSessionRepository(tx)
+	assert.NoError(t, err)
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	assert.NoError(t, err)
+	prr, err := repository.NewPasswordResetRepository(tx)
+	assert.NoError(t, err)
+	or, err := repository.NewOAuthRepository(tx)
+	assert.NoError(t, err)
+	ss := NewSessionService(sr, ur)
+	provider := &oauthProviderStub{}
+	as := NewAuthService(ur, rtr, prr, or, ss, nil, "", map[string]OAuthProvider{"sso": provider}, nil)
+	savePoint := "before"
+	tx.SavePoint(savePoint)
+
+	// login starts a login and returns the callback the provider redirects back with
+	login := func(t *testing.T) schemas.OAuthCallbackRequest {
+		start, err := as.OAuthLogin(tx, "sso")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		authorizationUrl, err := url.Parse(start.AuthorizationUrl)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return schemas.OAuthCallbackRequest{Code: "code", State: authorizationUrl.Query().Get("state"), ClientNonce: start.ClientNonce}
+	}
+	callback := func(request schemas.OAuthCallbackRequest) (*schemas.Session, error) {
+		codeVerifier, err := as.UseOAuthState(tx, "sso", request)
+		if err != nil {
+			return nil, err
+		}
+		return as.OAuthCallback(tx, "sso", request.Code, codeVerifier)
+	}
+
+	t.Run("account is created on the first login", func(t *testing.T) {
+		provider.identity = OAuthIdentity{Subject: "1", Email: "jan.kowalski@staff.example.edu", EmailVerified: true, Name: "Jan", Surname: "Kowalski"}
+		request := login(t)
+		session, err := callback(request)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NotEmpty(t, session.RefreshToken)
+		user, err := ur.GetUser(tx, session.UserId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "jan_kowalski", user.Username)
+		assert.Equal(t, models.UserRoleTeacher, user.Role)
+
+		// The state can be used once
+		_, err = callback(request)
+		assert.ErrorIs(t, err, ErrInvalidOAuthState)
+
+		// Later logins find the account by the subject, also after the email changed at the provider
+		provider.identity.Email = "jkowalski@staff.example.edu"
+		again, err := callback(login(t))
+		assert.NoError(t, err)
+		assert.Equal(t, session.UserId, again.UserId)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("registered account is linked by verified email", func(t *testing.T) {
+		registered, err := as.Register(tx, schemas.UserRegisterRequest{
+			Name:     "name",
+			Surname:  "surname",
+			Email:    "student@example.edu",
+			Username: "username",
+			Password: strings.Repeat("a", 13),
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		provider.identity = OAuthIdentity{Subject: "2", Email: "student@example.edu"}
+		_, err = callback(login(t))
+		assert.ErrorIs(t, err, ErrOAuthEmailNotVerified)
+
+		provider.identity.EmailVerified = true
+		session, err := callback(login(t))
+		assert.NoError(t, err)
+		assert.Equal(t, registered.UserId, session.UserId)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("unknown provider and state", func(t *testing.T) {
+		_, err := as.OAuthLogin(tx, "unknown")
+		assert.ErrorIs(t, err, ErrOAuthProviderNotFound)
+		_, err = callback(schemas.OAuthCallbackRequest{Code: "code", State: "unknown"})
+		assert.ErrorIs(t, err, ErrInvalidOAuthState)
+	})
+
+	t.Run("state of another client", func(t *testing.T) {
+		provider.identity = OAuthIdentity{Subject: "3", Email: "attacker@example.edu", EmailVerified: true}
+		request := login(t)
+		request.ClientNonce = login(t).ClientNonce
+		_, err := callback(request)
+		assert.ErrorIs(t, err, ErrInvalidOAuthState)
+		request.ClientNonce = ""
+		_, err = callback(request)
+		assert.ErrorIs(t, err, ErrInvalidOAuthState)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("state stays used after a failed login", func(t *testing.T) {
+		request := login(t)
+		codeVerifier, err := as.UseOAuthState(tx, "sso", request)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		tx.SavePoint("used")
+		_, err = as.OAuthCallback(tx, "sso", "invalid", codeVerifier)
+		assert.ErrorIs(t, err, ErrOAuthLoginFailed)
+		tx.RollbackTo("used")
+		_, err = as.UseOAuthState(tx, "sso", request)
+		assert.ErrorIs(t, err, ErrInvalidOAuthState)
+		tx.RollbackTo(savePoint)
+	})
+
+	tx.Rollback()
+}
+
+func TestLDAPLogin(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	assert.NoError(t, err)
+	sr, err := repository.NewSessionRepository(tx)
+	assert.NoError(t, err)
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	assert.NoError(t, err)
+	prr, err := repository.NewPasswordResetRepository(tx)
+	assert.NoError(t, err)
+	ss := NewSessionService(sr, ur)
+	password := strings.Repeat("a", 13)
+	directory := &ldapAuthenticatorStub{
+		users: map[string]LDAPUser{
+			"jan@example.edu": {Email: "jan@example.edu", Username: "jkowalski", Name: "Jan", Surname: "Kowalski", Role: models.UserRoleTeacher},
+		},
+		password: password,
+	}
+	as := NewAuthService(ur, rtr, prr, nil, ss, nil, "", nil, directory)
+	savePoint := "before"
+	tx.SavePoint(savePoint)
+
+	t.Run("directory user is created and its role follows the groups", func(t *testing.T) {
+		session, err := as.Login(tx, schemas.UserLoginRequest{Email: "jan@example.edu", Password: password})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		user, err := ur.GetUser(tx, session.UserId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "jkowalski", user.Username)
+		assert.Equal(t, models.UserRoleTeacher, user.Role)
+
+		directory.users["jan@example.edu"] = LDAPUser{Email: "jan@example.edu", Username: "jkowalski", Role: models.UserRoleAdmin}
+		again, err := as.Login(tx, schemas.UserLoginRequest{Email: "jan@example.edu", Password: password})
+		assert.NoError(t, err)
+		assert.Equal(t, session.UserId, again.UserId)
+		user, err = ur.GetUser(tx, session.UserId)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserRoleAdmin, user.Role)
+
+		_, err = as.Login(tx, schemas.UserLoginRequest{Email: "jan@example.edu", Password: strings.Repeat("b", 13)})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("custom roles are kept", func(t *testing.T) {
+		session, err := as.Login(tx, schemas.UserLoginRequest{Email: "jan@example.edu", Password: password})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, ur.UpdateRole(tx, session.UserId, "assistant")) {
+			t.FailNow()
+		}
+		_, err = as.Login(tx, schemas.UserLoginRequest{Email: "jan@example.edu", Password: password})
+		assert.NoError(t, err)
+		user, err := ur.GetUser(tx, session.UserId)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserRole("assistant"), user.Role)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("emails not in the directory log in with the local password", func(t *testing.T) {
+		_, err := as.Register(tx, schemas.UserRegisterRequest{
+			Name:     "name",
+			Surname:  "surname",
+			Email:    "local@example.edu",
+			Username: "username",
+			Password: password,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = as.Login(tx, schemas.UserLoginRequest{Email: "local@example.edu", Password: password})
+		assert.NoError(t, err)
+		tx.RollbackTo(savePoint)
+	})
+
+	tx.Rollback()
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/config"
+)
+
+// clamdChunkSize is the size of INSTREAM chunks. clamd closes the connection when a file exceeds its
+// StreamMaxLength, which has to be at least the maximum multipart body size
+const clamdChunkSize = 64 << 10
+
+var ErrScannerUnavailable = errors.New("file scanner is unavailable")
+
+// ScanResult is the verdict of a file scanner. Signature names the detected malware of an infected file
+type ScanResult struct {
+	Infected  bool
+	Signature string
+}
+
+type FileScanner interface {
+	// Scan reads the file to its end and reports whether it is infected. Returns ErrScannerUnavailable when
+	// the scanner cannot be reached or fails to scan the file
+	Scan(file io.Reader) (*ScanResult, error)
+}
+
+// ClamAVScanner scans files with a clamd daemon over TCP
+type ClamAVScanner struct {
+	address string
+	timeout time.Duration
+}
+
+func (cs *ClamAVScanner) Scan(file io.Reader) (*ScanResult, error) {
+	conn, err := net.DialTimeout("tcp", cs.address, cs.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(cs.timeout)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+
+	// The z prefix makes clamd read the command and reply up to a null byte
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+	buffer := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := file.Read(buffer)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+			}
+			if _, err := conn.Write(buffer[:n]); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+	// Replies are "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+	reply = strings.TrimPrefix(strings.TrimSuffix(reply, "\x00"), "stream: ")
+	switch {
+	case reply == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrScannerUnavailable, reply)
+	}
+}
+
+// HTTPScanner posts files to an external scanner, which answers with a JSON verdict
+type HTTPScanner struct {
+	url    string
+	client *http.Client
+}
+
+type httpScanResponse struct {
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature"`
+}
+
+func (hs *HTTPScanner) Scan(file io.Reader) (*ScanResult, error) {
+	resp, err := hs.client.Post(hs.url, "application/octet-stream", file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d: %s", ErrScannerUnavailable, resp.StatusCode, string(bytes.TrimSpace(body)))
+	}
+	result := httpScanResponse{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrScannerUnavailable, err)
+	}
+	return &ScanResult{Infected: result.Infected, Signature: result.Signature}, nil
+}
+
+// NewFileScanner returns the scanner set in the config, or nil when scanning is disabled
+func NewFileScanner(cfg config.ScanConfig) FileScanner {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.ClamAVAddress != "" {
+		return &ClamAVScanner{address: cfg.ClamAVAddress, timeout: cfg.Timeout}
+	}
+	return &HTTPScanner{url: cfg.HttpUrl, client: &http.Client{Timeout: cfg.Timeout}}
+}
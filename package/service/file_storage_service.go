@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+var ErrFileNotFound = fmt.Errorf("file not found in file storage")
+
+// FileStorageService is a client for the FileStorage service
+type FileStorageService interface {
+	// GetUserSolution returns the source file of the given submission together with its file name
+	GetUserSolution(taskId int64, userId int64, submissionNumber int64) ([]byte, string, error)
+	// DeleteUserSolution removes the source file of the given submission. Returns ErrFileNotFound if there is none
+	DeleteUserSolution(taskId int64, userId int64, submissionNumber int64) error
+	// ArchiveUserSolution moves the source file of the given submission to the archive storage class.
+	// Returns ErrFileNotFound if there is none
+	ArchiveUserSolution(taskId int64, userId int64, submissionNumber int64) error
+	// RestoreUserSolution moves an archived source file back to the standard storage class, so it can be
+	// read by the backend and the worker. Returns once the file is restored
+	RestoreUserSolution(taskId int64, userId int64, submissionNumber int64) error
+	// GetTaskFiles returns the files of a task, its description and tests, as a .tar.gz archive in the format
+	// tasks are uploaded in. Returns ErrFileNotFound if the task has no files
+	GetTaskFiles(taskId int64) ([]byte, error)
+	// CreateTask stores the files of a new task from a .zip or .tar.gz archive
+	CreateTask(taskId int64, archiveName string, archive []byte) error
+}
+
+type FileStorageServiceImpl struct {
+	fileStorageUrl string
+	client         *http.Client
+	logger         *zap.SugaredLogger
+}
+
+func (fs *FileStorageServiceImpl) GetUserSolution(taskId int64, userId int64, submissionNumber int64) ([]byte, string, error) {
+	query := url.Values{}
+	query.Set("taskID", strconv.FormatInt(taskId, 10))
+	query.Set("userID", strconv.FormatInt(userId, 10))
+	query.Set("submissionNumber", strconv.FormatInt(submissionNumber, 10))
+
+	resp, err := fs.client.Get(fs.fileStorageUrl + "/getUserSolution?" + query.Encode())
+	if err != nil {
+		fs.logger.Errorf("Error requesting user solution: %v", err.Error())
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fs.logger.Errorf("Error reading user solution: %v", err.Error())
+		return nil, "", err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", ErrFileNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		fs.logger.Errorf("Error getting user solution from FileStorage: %s", string(body))
+		return nil, "", fmt.Errorf("failed to get user solution from FileStorage: %s", string(body))
+	}
+
+	fileName := fmt.Sprintf("solution_%d", submissionNumber)
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err == nil && params["filename"] != "" {
+		fileName = params["filename"]
+	}
+
+	return body, fileName, nil
+}
+
+func (fs *FileStorageServiceImpl) DeleteUserSolution(taskId int64, userId int64, submissionNumber int64) error {
+	query := url.Values{}
+	query.Set("taskID", strconv.FormatInt(taskId, 10))
+	query.Set("userID", strconv.FormatInt(userId, 10))
+	query.Set("submissionNumber", strconv.FormatInt(submissionNumber, 10))
+
+	req, err := http.NewRequest(http.MethodDelete, fs.fileStorageUrl+"/deleteUserSolution?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		fs.logger.Errorf("Error requesting user solution deletion: %v", err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrFileNotFound
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		fs.logger.Errorf("Error deleting user solution from FileStorage: %s", string(body))
+		return fmt.Errorf("failed to delete user solution from FileStorage: %s", string(body))
+	}
+	return nil
+}
+
+func (fs *FileStorageServiceImpl) ArchiveUserSolution(taskId int64, userId int64, submissionNumber int64) error {
+	return fs.moveUserSolution("/archiveUserSolution", taskId, userId, submissionNumber)
+}
+
+func (fs *FileStorageServiceImpl) RestoreUserSolution(taskId int64, userId int64, submissionNumber int64) error {
+	return fs.moveUserSolution("/restoreUserSolution", taskId, userId, submissionNumber)
+}
+
+// moveUserSolution requests FileStorage to move the source file of the submission between storage classes
+func (fs *FileStorageServiceImpl) moveUserSolution(path string, taskId int64, userId int64, submissionNumber int64) error {
+	query := url.Values{}
+	query.Set("taskID", strconv.FormatInt(taskId, 10))
+	query.Set("userID", strconv.FormatInt(userId, 10))
+	query.Set("submissionNumber", strconv.FormatInt(submissionNumber, 10))
+
+	resp, err := fs.client.Post(fs.fileStorageUrl+path+"?"+query.Encode(), "", nil)
+	if err != nil {
+		fs.logger.Errorf("Error requesting %s: %v", path, err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrFileNotFound
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		fs.logger.Errorf("Error requesting %s from FileStorage: %s", path, string(body))
+		return fmt.Errorf("failed to request %s from FileStorage: %s", path, string(body))
+	}
+	return nil
+}
+
+func (fs *FileStorageServiceImpl) GetTaskFiles(taskId int64) ([]byte, error) {
+	query := url.Values{}
+	query.Set("taskID", strconv.FormatInt(taskId, 10))
+
+	resp, err := fs.client.Get(fs.fileStorageUrl + "/getTaskFiles?" + query.Encode())
+	if err != nil {
+		fs.logger.Errorf("Error requesting task files: %v", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fs.logger.Errorf("Error reading task files: %v", err.Error())
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFileNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		fs.logger.Errorf("Error getting task files from FileStorage: %s", string(body))
+		return nil, fmt.Errorf("failed to get task files from FileStorage: %s", string(body))
+	}
+	return body, nil
+}
+
+func (fs *FileStorageServiceImpl) CreateTask(taskId int64, archiveName string, archive []byte) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("taskID", strconv.FormatInt(taskId, 10)); err != nil {
+		return err
+	}
+	if err := writer.WriteField("overwrite", "false"); err != nil {
+		return err
+	}
+	part, err := writer.CreateFormFile("archive", archiveName)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(archive); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	resp, err := fs.client.Post(fs.fileStorageUrl+"/createTask", writer.FormDataContentType(), body)
+	if err != nil {
+		fs.logger.Errorf("Error sending task files: %v", err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		fs.logger.Errorf("Error creating task in FileStorage: %s", string(respBody))
+		return fmt.Errorf("failed to create task in FileStorage: %s", string(respBody))
+	}
+	return nil
+}
+
+func NewFileStorageService(fileStorageUrl string) FileStorageService {
+	log := logger.NewNamedLogger("file_storage_service")
+	return &FileStorageServiceImpl{
+		fileStorageUrl: fileStorageUrl,
+		client:         &http.Client{},
+		logger:         log,
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxGradeImportRows is the maximum number of grades in a single import
+const MaxGradeImportRows = 5000
+
+// GradeImportColumns are the columns required in the header of a grade import file
+var GradeImportColumns = []string{"submission_id", "manual_score"}
+
+var ErrInvalidGradeImport = errors.New("invalid grade import file")
+
+type GradingService interface {
+	// ImportGrades applies manual grades from a CSV file with a header naming the submission_id and manual_score
+	// columns, and optionally comment. Teachers can grade submissions of their tasks, admins all submissions.
+	// Grades are applied only if every row is valid, otherwise none is and the report lists the failed rows
+	ImportGrades(tx *gorm.DB, currentUser schemas.User, file io.Reader) (*schemas.GradeImportReport, error)
+}
+
+type GradingServiceImpl struct {
+	submissionRepository  repository.SubmissionRepository
+	manualGradeRepository repository.ManualGradeRepository
+	accessControlService  AccessControlService
+	logger                *zap.SugaredLogger
+}
+
+// gradeImportRow is a parsed row of a grade import file. Error is set when a value could not be parsed
+type gradeImportRow struct {
+	schemas.GradeImportRow
+	Error string
+}
+
+func (gs *GradingServiceImpl) ImportGrades(tx *gorm.DB, currentUser schemas.User, file io.Reader) (*schemas.GradeImportReport, error) {
+	if !gs.accessControlService.Can(currentUser, ResourceGrade, ActionImport) {
+		return nil, ErrNotAuthorized
+	}
+
+	rows, err := gs.readGradeImport(file)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &schemas.GradeImportReport{Rows: make([]schemas.GradeImportRowResult, len(rows))}
+	validate := utils.NewValidator()
+	submissionIds := make([]int64, 0, len(rows))
+	for i, row := range rows {
+		report.Rows[i] = schemas.GradeImportRowResult{Row: i + 2, SubmissionId: row.SubmissionId, Error: row.Error}
+		if row.Error != "" {
+			continue
+		}
+		if err := validate.Struct(row.GradeImportRow); err != nil {
+			validationErrors, ok := err.(validator.ValidationErrors)
+			if !ok {
+				return nil, err
+			}
+			fields := make([]string, 0, len(validationErrors))
+			for _, fe := range validationErrors {
+				fields = append(fields, fe.Field())
+			}
+			report.Rows[i].Error = "invalid " + strings.Join(fields, ", ")
+			continue
+		}
+		submissionIds = append(submissionIds, row.SubmissionId)
+	}
+
+	submissions, err := gs.submissionRepository.GetSubmissionsByIds(tx, submissionIds)
+	if err != nil {
+		gs.logger.Errorf("Error getting submissions: %v", err.Error())
+		return nil, err
+	}
+	submissionsById := make(map[int64]*models.Submission, len(submissions))
+	for i := range submissions {
+		submissionsById[submissions[i].Id] = &submissions[i]
+	}
+
+	grades := make([]models.ManualGrade, 0, len(rows))
+	graded := make(map[int64]bool, len(rows))
+	for i, row := range rows {
+		result := &report.Rows[i]
+		if result.Error != "" {
+			continue
+		}
+		submission, ok := submissionsById[row.SubmissionId]
+		// Submissions of other teachers are reported as missing, so their ids cannot be probed
+		if !ok || !gs.accessControlService.CanAccess(currentUser, ResourceGrade, ActionImport, submission.Task.CreatedBy) {
+			result.Error = "submission not found"
+			continue
+		}
+		if submission.Status == "received" || submission.Status == "processing" {
+			result.Error = "submission is not judged yet"
+			continue
+		}
+		if graded[row.SubmissionId] {
+			result.Error = "submission is graded more than once"
+			continue
+		}
+		graded[row.SubmissionId] = true
+		grades = append(grades, models.ManualGrade{
+			SubmissionId: row.SubmissionId,
+			Score:        row.ManualScore,
+			Comment:      row.Comment,
+			GradedBy:     currentUser.Id,
+		})
+	}
+	report.Failed = len(rows) - len(grades)
+	if report.Failed > 0 {
+		gs.logger.Infof("User %d grade import rejected, %d of %d rows failed", currentUser.Id, report.Failed, len(rows))
+		return report, nil
+	}
+
+	err = gs.manualGradeRepository.SaveGrades(tx, grades)
+	if err != nil {
+		gs.logger.Errorf("Error saving grades: %v", err.Error())
+		return nil, err
+	}
+	report.Applied = len(grades)
+
+	gs.logger.Infof("User %d imported %d grades", currentUser.Id, report.Applied)
+	return report, nil
+}
+
+// readGradeImport parses the rows of a grade import file, mapping columns by the header
+func (gs *GradingServiceImpl) readGradeImport(file io.Reader) ([]gradeImportRow, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: file is empty", ErrInvalidGradeImport)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGradeImport, err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range GradeImportColumns {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column, required columns are %s", ErrInvalidGradeImport, column, strings.Join(GradeImportColumns, ", "))
+		}
+	}
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []gradeImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidGradeImport, err.Error())
+		}
+		if len(rows) == MaxGradeImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidGradeImport, MaxGradeImportRows)
+		}
+		row := gradeImportRow{GradeImportRow: schemas.GradeImportRow{Comment: value(record, "comment")}}
+		row.SubmissionId, err = strconv.ParseInt(value(record, "submission_id"), 10, 64)
+		if err != nil {
+			row.Error = "invalid submission_id"
+		}
+		// Spreadsheets in some locales export decimal commas
+		row.ManualScore, err = strconv.ParseFloat(strings.Replace(value(record, "manual_score"), ",", ".", 1), 64)
+		if err != nil && row.Error == "" {
+			row.Error = "invalid manual_score"
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no grades", ErrInvalidGradeImport)
+	}
+	return rows, nil
+}
+
+func NewGradingService(submissionRepository repository.SubmissionRepository, manualGradeRepository repository.ManualGradeRepository, accessControlService AccessControlService) GradingService {
+	log := logger.NewNamedLogger("grading_service")
+	return &GradingServiceImpl{
+		submissionRepository:  submissionRepository,
+		manualGradeRepository: manualGradeRepository,
+		accessControlService:  accessControlService,
+		logger:                log,
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestImportGrades(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+	gs := NewGradingService(sst.sr, sst.mgr, newAccessControlServiceTest(t, sst.tx))
+
+	taskId, userId := sst.createSubmission(t)
+	submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+	if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+		t.FailNow()
+	}
+	submissionId := submissions[0].Id
+	// The task is created by the submitting user, so they are the task author here
+	teacher := schemas.User{Id: userId, Role: string(models.UserRoleTeacher)}
+	sst.tx.SavePoint(sst.savePoint)
+
+	t.Run("Submissions not judged cannot be graded", func(t *testing.T) {
+		report, err := gs.ImportGrades(sst.tx, teacher, strings.NewReader(fmt.Sprintf("submission_id,manual_score\n%d,80\n", submissionId)))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Applied)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, "submission is not judged yet", report.Rows[0].Error)
+	})
+
+	if !assert.NoError(t, sst.sr.MarkSubmissionComplete(sst.tx, submissionId)) {
+		t.FailNow()
+	}
+	sst.tx.SavePoint(sst.savePoint)
+
+	t.Run("Teacher grades submission of their task", func(t *testing.T) {
+		report, err := gs.ImportGrades(sst.tx, teacher, strings.NewReader(fmt.Sprintf("Submission_ID,manual_score,comment\n%d,\"87,5\",Nice work\n", submissionId)))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Applied)
+		assert.Equal(t, 0, report.Failed)
+
+		grade, err := sst.mgr.GetGrade(sst.tx, submissionId)
+		if !assert.NoError(t, err) || !assert.NotNil(t, grade) {
+			t.FailNow()
+		}
+		assert.Equal(t, 87.5, grade.Score)
+		assert.Equal(t, "Nice work", grade.Comment)
+		assert.Equal(t, userId, grade.GradedBy)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Invalid row rejects the whole import", func(t *testing.T) {
+		report, err := gs.ImportGrades(sst.tx, teacher, strings.NewReader(fmt.Sprintf("submission_id,manual_score\n%d,90\n%d,150\nabc,10\n", submissionId, submissionId)))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Applied)
+		assert.Equal(t, 2, report.Failed)
+		assert.Empty(t, report.Rows[0].Error)
+		assert.Equal(t, 3, report.Rows[1].Row)
+		assert.NotEmpty(t, report.Rows[1].Error)
+		assert.Equal(t, "invalid submission_id", report.Rows[2].Error)
+
+		_, err = sst.mgr.GetGrade(sst.tx, submissionId)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Submissions of other teachers are not found", func(t *testing.T) {
+		other := schemas.User{Id: userId + 1, Role: string(models.UserRoleTeacher)}
+		report, err := gs.ImportGrades(sst.tx, other, strings.NewReader(fmt.Sprintf("submission_id,manual_score\n%d,80\n", submissionId)))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, "submission not found", report.Rows[0].Error)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Missing column", func(t *testing.T) {
+		_, err := gs.ImportGrades(sst.tx, teacher, strings.NewReader(fmt.Sprintf("submission_id,score\n%d,80\n", submissionId)))
+		assert.ErrorIs(t, err, ErrInvalidGradeImport)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Not authorized", func(t *testing.T) {
+		student := schemas.User{Id: userId, Role: string(models.UserRoleStudent)}
+		_, err := gs.ImportGrades(sst.tx, student, strings.NewReader(fmt.Sprintf("submission_id,manual_score\n%d,80\n", submissionId)))
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
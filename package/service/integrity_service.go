@@ -0,0 +1,263 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const DefaultOrphanCleanupBatchSize = 1000
+
+// orphanReferences lists references checked for orphans. Rows pointing to other
+// checked tables come after them, so a single cleanup pass also removes rows
+// orphaned by earlier deletions. Nullable references are only checked when set.
+// References of tables which do not exist in the database are skipped.
+var orphanReferences = []repository.OrphanReference{
+	{Table: "input_outputs", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "test_case_groups", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "test_case_group_tests", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "test_case_group_tests", Column: "group_id", ReferencedTable: "test_case_groups", ReferencedColumn: "id"},
+	{Table: "submissions", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "submissions", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "queue_messages", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "queue_failures", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "submission_results", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "test_results", Column: "submission_result_id", ReferencedTable: "submission_results", ReferencedColumn: "id"},
+	{Table: "test_results", Column: "input_output_id", ReferencedTable: "input_outputs", ReferencedColumn: "id"},
+	{Table: "test_progresses", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "manual_grades", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "timeline_events", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "judge_audits", Column: "submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "plagiarism_checks", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "plagiarism_matches", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "plagiarism_matches", Column: "first_submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "plagiarism_matches", Column: "second_submission_id", ReferencedTable: "submissions", ReferencedColumn: "id"},
+	{Table: "plagiarism_matches", Column: "first_user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "plagiarism_matches", Column: "second_user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "rejudge_batches", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_users", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_users", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "task_groups", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_groups", Column: "group_id", ReferencedTable: "groups", ReferencedColumn: "id"},
+	{Table: "user_groups", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "user_groups", Column: "group_id", ReferencedTable: "groups", ReferencedColumn: "id"},
+	{Table: "task_bookmarks", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_bookmarks", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "task_notes", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_notes", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "task_drafts", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_drafts", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "task_co_authors", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_co_authors", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "task_co_author_changes", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_co_author_changes", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "task_changes", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_tags", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_tags", Column: "tag_id", ReferencedTable: "tags", ReferencedColumn: "id"},
+	{Table: "task_pool_tasks", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "task_pool_tasks", Column: "pool_id", ReferencedTable: "task_pools", ReferencedColumn: "id"},
+	{Table: "task_verdict_summaries", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "user_task_summaries", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "user_task_summaries", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "notifications", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"},
+	{Table: "notifications", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "announcements", Column: "group_id", ReferencedTable: "groups", ReferencedColumn: "id"},
+	{Table: "announcement_reads", Column: "announcement_id", ReferencedTable: "announcements", ReferencedColumn: "id"},
+	{Table: "announcement_reads", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "sessions", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "refresh_tokens", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "password_reset_tokens", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "user_identities", Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "audit_log_entries", Column: "actor_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "audit_log_entries", Column: "target_user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+	{Table: "role_permissions", Column: "role_id", ReferencedTable: "roles", ReferencedColumn: "id"},
+	{Table: "incident_updates", Column: "incident_id", ReferencedTable: "incidents", ReferencedColumn: "id"},
+	{Table: "quarantined_files", Column: "uploaded_by", ReferencedTable: "users", ReferencedColumn: "id"},
+}
+
+// OrphanedFileMinAge is how old an unreferenced quarantined file has to be to be reported. Younger files may
+// belong to an upload whose row is not committed yet
+const OrphanedFileMinAge = time.Hour
+
+type IntegrityService interface {
+	// CheckOrphans reports rows referencing missing records and files in the quarantine directory without a row.
+	// Unless it is a dry run, rows are deleted in batches and the files are removed. Files in FileStorage are not
+	// checked, because it cannot list them
+	CheckOrphans(tx *gorm.DB, currentUser schemas.User, request schemas.OrphanCleanupRequest) (*schemas.IntegrityReport, error)
+}
+
+type IntegrityServiceImpl struct {
+	integrityRepository  repository.IntegrityRepository
+	quarantineRepository repository.QuarantineRepository
+	quarantineDir        string
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (is *IntegrityServiceImpl) CheckOrphans(tx *gorm.DB, currentUser schemas.User, request schemas.OrphanCleanupRequest) (*schemas.IntegrityReport, error) {
+	if !is.accessControlService.Can(currentUser, ResourceIntegrity, ActionCheck) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		is.logger.Errorf("Error validating orphan cleanup request: %v", err.Error())
+		return nil, err
+	}
+
+	dryRun := request.DryRun == nil || *request.DryRun
+	batchSize := request.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultOrphanCleanupBatchSize
+	}
+
+	report := &schemas.IntegrityReport{
+		DryRun:  dryRun,
+		Records: make([]schemas.OrphanedRecords, 0, len(orphanReferences)),
+	}
+	tableExists := make(map[string]bool)
+	for _, reference := range orphanReferences {
+		exists, err := is.tablesExist(tx, tableExists, reference.Table, reference.ReferencedTable)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		orphaned, err := is.integrityRepository.CountOrphans(tx, reference)
+		if err != nil {
+			is.logger.Errorf("Error counting orphans in %s.%s: %v", reference.Table, reference.Column, err.Error())
+			return nil, err
+		}
+
+		records := schemas.OrphanedRecords{
+			Table:      reference.Table,
+			Column:     reference.Column,
+			References: reference.ReferencedTable + "." + reference.ReferencedColumn,
+			Orphaned:   orphaned,
+		}
+		for !dryRun && records.Deleted < orphaned {
+			deleted, err := is.integrityRepository.DeleteOrphans(tx, reference, batchSize)
+			if err != nil {
+				is.logger.Errorf("Error deleting orphans in %s.%s: %v", reference.Table, reference.Column, err.Error())
+				return nil, err
+			}
+			if deleted == 0 {
+				break
+			}
+			records.Deleted += deleted
+		}
+		if records.Deleted > 0 {
+			is.logger.Infof("Deleted %d orphaned rows from %s (%s)", records.Deleted, reference.Table, reference.Column)
+		}
+		report.Records = append(report.Records, records)
+	}
+
+	if is.quarantineDir != "" {
+		files, err := is.checkQuarantinedFiles(tx, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		report.Files = append(report.Files, *files)
+	}
+	return report, nil
+}
+
+// tablesExist reports whether all the tables exist, remembering tables checked before in known
+func (is *IntegrityServiceImpl) tablesExist(tx *gorm.DB, known map[string]bool, tables ...string) (bool, error) {
+	for _, table := range tables {
+		exists, ok := known[table]
+		if !ok {
+			var err error
+			exists, err = is.integrityRepository.TableExists(tx, table)
+			if err != nil {
+				is.logger.Errorf("Error checking table %s: %v", table, err.Error())
+				return false, err
+			}
+			known[table] = exists
+		}
+		if !exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkQuarantinedFiles finds files in the quarantine directory which no quarantined file row points to,
+// e.g. because the row was deleted as an orphan above, and removes them unless it is a dry run
+func (is *IntegrityServiceImpl) checkQuarantinedFiles(tx *gorm.DB, dryRun bool) (*schemas.OrphanedFiles, error) {
+	files := &schemas.OrphanedFiles{Directory: is.quarantineDir}
+	entries, err := os.ReadDir(is.quarantineDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		is.logger.Errorf("Error reading quarantine directory: %v", err.Error())
+		return nil, err
+	}
+	paths, err := is.quarantineRepository.GetPaths(tx)
+	if err != nil {
+		is.logger.Errorf("Error getting quarantined file paths: %v", err.Error())
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		referenced[filepath.Clean(path)] = true
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(is.quarantineDir, entry.Name())
+		if referenced[path] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			is.logger.Errorf("Error reading quarantined file %s: %v", path, err.Error())
+			return nil, err
+		}
+		if time.Since(info.ModTime()) < OrphanedFileMinAge {
+			continue
+		}
+		files.Orphaned++
+		if dryRun {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			is.logger.Errorf("Error removing orphaned file %s: %v", path, err.Error())
+			return nil, err
+		}
+		files.Deleted++
+	}
+	if files.Deleted > 0 {
+		is.logger.Infof("Deleted %d orphaned files from %s", files.Deleted, is.quarantineDir)
+	}
+	return files, nil
+}
+
+func NewIntegrityService(integrityRepository repository.IntegrityRepository, quarantineRepository repository.QuarantineRepository, quarantineDir string, accessControlService AccessControlService) IntegrityService {
+	log := logger.NewNamedLogger("integrity_service")
+	if quarantineDir != "" {
+		quarantineDir = filepath.Clean(quarantineDir)
+	}
+	return &IntegrityServiceImpl{
+		integrityRepository:  integrityRepository,
+		quarantineRepository: quarantineRepository,
+		quarantineDir:        quarantineDir,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
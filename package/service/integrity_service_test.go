@@ -0,0 +1,264 @@
+package service
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+func orphanedSessions(report *schemas.IntegrityReport) schemas.OrphanedRecords {
+	for _, records := range report.Records {
+		if records.Table == "sessions" {
+			return records
+		}
+	}
+	return schemas.OrphanedRecords{}
+}
+
+func TestCheckOrphans(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	sr, err := repository.NewSessionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	qr, err := repository.NewQuarantineRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	quarantineDir := t.TempDir()
+	is := NewIntegrityService(repository.NewIntegrityRepository(), qr, quarantineDir, newAccessControlServiceTest(t, tx))
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	createOrphanedSession := func(t *testing.T) {
+		err := sr.CreateSession(tx, &models.Session{
+			Id:        "orphaned-session",
+			UserId:    -1,
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	t.Run("Not an admin", func(t *testing.T) {
+		report, err := is.CheckOrphans(tx, schemas.User{Role: string(models.UserRoleStudent)}, schemas.OrphanCleanupRequest{})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		assert.Nil(t, report)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Dry run by default", func(t *testing.T) {
+		createOrphanedSession(t)
+		report, err := is.CheckOrphans(tx, admin, schemas.OrphanCleanupRequest{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.True(t, report.DryRun)
+		sessions := orphanedSessions(report)
+		assert.GreaterOrEqual(t, sessions.Orphaned, int64(1))
+		assert.Equal(t, int64(0), sessions.Deleted)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Cleanup in batches", func(t *testing.T) {
+		createOrphanedSession(t)
+		dryRun := false
+		report, err := is.CheckOrphans(tx, admin, schemas.OrphanCleanupRequest{DryRun: &dryRun, BatchSize: 1})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		sessions := orphanedSessions(report)
+		assert.Equal(t, sessions.Orphaned, sessions.Deleted)
+
+		report, err = is.CheckOrphans(tx, admin, schemas.OrphanCleanupRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), orphanedSessions(report).Orphaned)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Sessions stored in Redis", func(t *testing.T) {
+		// Without the database session store the sessions table is never created
+		if !assert.NoError(t, tx.Migrator().DropTable(&models.Session{})) {
+			t.FailNow()
+		}
+		report, err := is.CheckOrphans(tx, admin, schemas.OrphanCleanupRequest{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		for _, records := range report.Records {
+			assert.NotEqual(t, "sessions", records.Table)
+		}
+		assert.NotEmpty(t, report.Records)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Quarantined files", func(t *testing.T) {
+		ur, err := repository.NewUserRepository(tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		userId, err := ur.CreateUser(tx, &models.User{Name: "Name", Surname: "Surname", Email: "uploader@email.com", Username: "uploader", PasswordHash: "password"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		old := time.Now().Add(-2 * OrphanedFileMinAge)
+		writeFile := func(name string, modified time.Time) string {
+			path := filepath.Join(quarantineDir, name)
+			if !assert.NoError(t, os.WriteFile(path, []byte(name), 0o600)) || !assert.NoError(t, os.Chtimes(path, modified, modified)) {
+				t.FailNow()
+			}
+			return path
+		}
+		referenced := writeFile("referenced", old)
+		orphaned := writeFile("orphaned", old)
+		uploading := writeFile("uploading", time.Now())
+		_, err = qr.CreateFile(tx, &models.QuarantinedFile{Kind: models.UploadKindSubmission, Filename: "main.c", Path: referenced, Sha256: "referenced", Signature: "Eicar-Test-Signature", UploadedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		report, err := is.CheckOrphans(tx, admin, schemas.OrphanCleanupRequest{})
+		if !assert.NoError(t, err) || !assert.Len(t, report.Files, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(1), report.Files[0].Orphaned)
+		assert.Equal(t, int64(0), report.Files[0].Deleted)
+		assert.FileExists(t, orphaned)
+
+		dryRun := false
+		report, err = is.CheckOrphans(tx, admin, schemas.OrphanCleanupRequest{DryRun: &dryRun})
+		if !assert.NoError(t, err) || !assert.Len(t, report.Files, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(1), report.Files[0].Deleted)
+		assert.NoFileExists(t, orphaned)
+		assert.FileExists(t, referenced)
+		assert.FileExists(t, uploading)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Partitioned table", func(t *testing.T) {
+		submissionRepository, err := repository.NewSubmissionRepository(tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ur, err := repository.NewUserRepository(tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		tr, err := repository.NewTaskRepository(tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		userId, err := ur.CreateUser(tx, &models.User{Name: "Name", Surname: "Surname", Email: "partitioned@email.com", Username: "partitioned", PasswordHash: "password"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		taskId, err := tr.Create(tx, models.Task{Title: "Partitioned", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: "c", Version: "99"}
+		if !assert.NoError(t, tx.Create(language).Error) {
+			t.FailNow()
+		}
+
+		// Orphans are left by databases without the constraint, the drop is rolled back with the test
+		if tx.Migrator().HasConstraint(&models.Submission{}, "Task") {
+			if !assert.NoError(t, tx.Migrator().DropConstraint(&models.Submission{}, "Task")) {
+				t.FailNow()
+			}
+		}
+		// The first rows of two new partitions have the same ctid
+		orphanedMonth := time.Date(2001, time.January, 15, 0, 0, 0, 0, time.UTC)
+		validMonth := orphanedMonth.AddDate(0, 1, 0)
+		pr := repository.NewPartitionRepository()
+		for _, month := range []time.Time{orphanedMonth, validMonth} {
+			if !assert.NoError(t, pr.CreateMonthlyPartition(tx, "submissions", month)) {
+				t.FailNow()
+			}
+		}
+		_, err = submissionRepository.CreateSubmission(tx, models.Submission{TaskId: -1, UserId: userId, Order: 1, LanguageId: language.Id, Status: "received", SubmittedAt: orphanedMonth})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		validId, err := submissionRepository.CreateSubmission(tx, models.Submission{TaskId: taskId, UserId: userId, Order: 1, LanguageId: language.Id, Status: "received", SubmittedAt: validMonth})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		reference := repository.OrphanReference{Table: "submissions", Column: "task_id", ReferencedTable: "tasks", ReferencedColumn: "id"}
+		_, err = repository.NewIntegrityRepository().DeleteOrphans(tx, reference, DefaultOrphanCleanupBatchSize)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = submissionRepository.GetSubmission(tx, validId)
+		assert.NoError(t, err)
+		var orphaned int64
+		assert.NoError(t, tx.Model(&models.Submission{}).Where("task_id = ?", -1).Count(&orphaned).Error)
+		assert.Equal(t, int64(0), orphaned)
+		tx.RollbackTo(savePoint)
+	})
+
+	tx.Rollback()
+}
+
+// TestOrphanReferencesCoverModels fails when a stored model references users, tasks or submissions
+// without the reference being checked for orphans
+func TestOrphanReferencesCoverModels(t *testing.T) {
+	// Models which are query results or exports, not tables
+	notStored := map[string]bool{
+		"ActivityEvent":     true,
+		"GroupTaskResult":   true,
+		"GroupTaskProgress": true,
+		"SubmissionEvent":   true,
+	}
+	checked := make(map[string]bool, len(orphanReferences))
+	for _, reference := range orphanReferences {
+		checked[reference.Table+"."+reference.Column] = true
+	}
+
+	packages, err := parser.ParseDir(token.NewFileSet(), filepath.Join("..", "domain", "models"), nil, 0)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	naming := schema.NamingStrategy{}
+	for _, file := range packages["models"].Files {
+		for _, declaration := range file.Decls {
+			typeDeclaration, ok := declaration.(*ast.GenDecl)
+			if !ok || typeDeclaration.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range typeDeclaration.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok || notStored[typeSpec.Name.Name] {
+					continue
+				}
+				for _, field := range structType.Fields.List {
+					for _, name := range field.Names {
+						if !strings.HasSuffix(name.Name, "UserId") && !strings.HasSuffix(name.Name, "TaskId") && !strings.HasSuffix(name.Name, "SubmissionId") {
+							continue
+						}
+						reference := naming.TableName(typeSpec.Name.Name) + "." + naming.ColumnName("", name.Name)
+						assert.True(t, checked[reference], "%s.%s is not in orphanReferences as %s", typeSpec.Name.Name, name.Name, reference)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// JudgeAuditWindow is how recently audited submissions must have been judged
+const JudgeAuditWindow = 24 * time.Hour
+
+var ErrJudgeAuditNotFound = errors.New("judge audit not found")
+
+type JudgeAuditService interface {
+	// StartAudit publishes a random sample of submissions judged within JudgeAuditWindow to be judged again
+	// and returns the number of started audits
+	StartAudit(tx *gorm.DB, sampleSize int) (int, error)
+	// RecordAuditResult compares the result of the re-run with the recorded verdict of the submission.
+	// Discrepancies are logged as warnings for admins
+	RecordAuditResult(tx *gorm.DB, auditId int64, responseMessage schemas.ResponseMessage) (*schemas.JudgeAudit, error)
+	// GetAudits returns audits newest first, optionally only those with a discrepancy. Only admins can see audits
+	GetAudits(tx *gorm.DB, currentUser schemas.User, discrepanciesOnly bool, limit, offset int64) ([]schemas.JudgeAudit, error)
+}
+
+type JudgeAuditServiceImpl struct {
+	submissionRepository       repository.SubmissionRepository
+	submissionResultRepository repository.SubmissionResultRepository
+	judgeAuditRepository       repository.JudgeAuditRepository
+	testGroupRepository        repository.TestCaseGroupRepository
+	queueService               QueueService
+	accessControlService       AccessControlService
+	logger                     *zap.SugaredLogger
+}
+
+func (js *JudgeAuditServiceImpl) StartAudit(tx *gorm.DB, sampleSize int) (int, error) {
+	submissions, err := js.submissionRepository.GetAuditSample(tx, time.Now().Add(-JudgeAuditWindow), sampleSize)
+	if err != nil {
+		js.logger.Errorf("Error getting audit sample: %v", err.Error())
+		return 0, err
+	}
+
+	started := 0
+	for _, submission := range submissions {
+		result, err := js.submissionResultRepository.GetSubmissionResultBySubmissionId(tx, submission.Id)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			js.logger.Errorf("Error getting submission result: %v", err.Error())
+			return started, err
+		}
+
+		auditId, err := js.judgeAuditRepository.CreateAudit(tx, &models.JudgeAudit{
+			SubmissionId:  submission.Id,
+			ExpectedCode:  result.Code,
+			ExpectedScore: result.Score,
+		})
+		if err != nil {
+			js.logger.Errorf("Error creating judge audit: %v", err.Error())
+			return started, err
+		}
+		err = js.queueService.PublishAudit(tx, submission.Id, auditId)
+		if err != nil {
+			return started, err
+		}
+		started++
+	}
+	return started, nil
+}
+
+func (js *JudgeAuditServiceImpl) RecordAuditResult(tx *gorm.DB, auditId int64, responseMessage schemas.ResponseMessage) (*schemas.JudgeAudit, error) {
+	audit, err := js.judgeAuditRepository.GetAudit(tx, auditId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrJudgeAuditNotFound
+		}
+		js.logger.Errorf("Error getting judge audit: %v", err.Error())
+		return nil, err
+	}
+
+	submission, err := js.submissionRepository.GetSubmission(tx, audit.SubmissionId)
+	if err != nil {
+		js.logger.Errorf("Error getting audited submission: %v", err.Error())
+		return nil, err
+	}
+	groups, err := js.testGroupRepository.GetGroups(tx, submission.TaskId)
+	if err != nil {
+		js.logger.Errorf("Error getting test groups: %v", err.Error())
+		return nil, err
+	}
+
+	code := responseMessage.Result.Code
+	score := computeResultScore(responseMessage.Result.TestResults, groups)
+	discrepancy := code != audit.ExpectedCode || score != audit.ExpectedScore
+	now := time.Now()
+	audit.ActualCode = &code
+	audit.ActualScore = &score
+	audit.Discrepancy = &discrepancy
+	audit.CheckedAt = &now
+	err = js.judgeAuditRepository.CompleteAudit(tx, audit)
+	if err != nil {
+		js.logger.Errorf("Error completing judge audit: %v", err.Error())
+		return nil, err
+	}
+
+	if discrepancy {
+		js.logger.Warnf("Judge audit %d: submission %d was judged %s (%.2f%%) but re-run gave %s (%.2f%%)",
+			audit.Id, audit.SubmissionId, audit.ExpectedCode, audit.ExpectedScore, code, score)
+	}
+	return js.modelToSchema(audit), nil
+}
+
+func (js *JudgeAuditServiceImpl) GetAudits(tx *gorm.DB, currentUser schemas.User, discrepanciesOnly bool, limit, offset int64) ([]schemas.JudgeAudit, error) {
+	if !js.accessControlService.Can(currentUser, ResourceJudgeAudit, ActionView) {
+		return nil, ErrNotAuthorized
+	}
+
+	audits, err := js.judgeAuditRepository.GetAudits(tx, discrepanciesOnly, limit, offset)
+	if err != nil {
+		js.logger.Errorf("Error getting judge audits: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.JudgeAudit, 0, len(audits))
+	for _, audit := range audits {
+		result = append(result, *js.modelToSchema(&audit))
+	}
+	return result, nil
+}
+
+func (js *JudgeAuditServiceImpl) modelToSchema(model *models.JudgeAudit) *schemas.JudgeAudit {
+	return &schemas.JudgeAudit{
+		Id:            model.Id,
+		SubmissionId:  model.SubmissionId,
+		ExpectedCode:  model.ExpectedCode,
+		ExpectedScore: model.ExpectedScore,
+		ActualCode:    model.ActualCode,
+		ActualScore:   model.ActualScore,
+		Discrepancy:   model.Discrepancy,
+		CreatedAt:     model.CreatedAt,
+		CheckedAt:     model.CheckedAt,
+	}
+}
+
+func NewJudgeAuditService(submissionRepository repository.SubmissionRepository, submissionResultRepository repository.SubmissionResultRepository, judgeAuditRepository repository.JudgeAuditRepository, testGroupRepository repository.TestCaseGroupRepository, queueService QueueService, accessControlService AccessControlService) JudgeAuditService {
+	log := logger.NewNamedLogger("judge_audit_service")
+	return &JudgeAuditServiceImpl{
+		submissionRepository:       submissionRepository,
+		submissionResultRepository: submissionResultRepository,
+		judgeAuditRepository:       judgeAuditRepository,
+		testGroupRepository:        testGroupRepository,
+		queueService:               queueService,
+		accessControlService:       accessControlService,
+		logger:                     log,
+	}
+}
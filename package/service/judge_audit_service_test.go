@@ -0,0 +1,207 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type queueServiceStub struct {
+	audited  []int64
+	rejudged []int64
+}
+
+func (qs *queueServiceStub) PublishSubmission(tx *gorm.DB, submissionId int64) error {
+	return nil
+}
+
+func (qs *queueServiceStub) GetSubmissionId(tx *gorm.DB, messageId string) (int64, error) {
+	return 0, nil
+}
+
+func (qs *queueServiceStub) PublishAudit(tx *gorm.DB, submissionId int64, auditId int64) error {
+	qs.audited = append(qs.audited, submissionId)
+	return nil
+}
+
+func (qs *queueServiceStub) GetAuditId(tx *gorm.DB, messageId string) (*int64, error) {
+	return nil, nil
+}
+
+func (qs *queueServiceStub) PublishRejudge(tx *gorm.DB, submissionId int64, batchId int64) error {
+	qs.rejudged = append(qs.rejudged, submissionId)
+	return nil
+}
+
+func (qs *queueServiceStub) GetRejudgeBatchId(tx *gorm.DB, messageId string) (*int64, error) {
+	return nil, nil
+}
+
+func (qs *queueServiceStub) RetrySubmission(tx *gorm.DB, messageId string, reason string) (bool, error) {
+	return false, nil
+}
+
+func (qs *queueServiceStub) GetQueueFailures(tx *gorm.DB, currentUser schemas.User, pendingOnly bool, limit, offset int64) ([]schemas.QueueFailure, error) {
+	return nil, nil
+}
+
+func (qs *queueServiceStub) RequeueFailure(tx *gorm.DB, currentUser schemas.User, failureId int64) (*schemas.QueueFailure, error) {
+	return nil, nil
+}
+
+func TestJudgeAudit(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srr, err := repository.NewSubmissionResultRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	jar, err := repository.NewJudgeAuditRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tgr, err := repository.NewTestCaseGroupRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	queueService := &queueServiceStub{}
+	js := NewJudgeAuditService(sr, srr, jar, tgr, queueService, newAccessControlServiceTest(t, tx))
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	createJudgedSubmission := func(t *testing.T) int64 {
+		userId, err := ur.CreateUser(tx, &models.User{
+			Name:         "Test User",
+			Surname:      "Test Surname",
+			Email:        "email@email.com",
+			Username:     "testuser",
+			PasswordHash: "password",
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		taskId, err := tr.Create(tx, models.Task{Title: "Test Task", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: "c", Version: "99"}
+		if !assert.NoError(t, tx.Create(language).Error) {
+			t.FailNow()
+		}
+		checkedAt := time.Now()
+		submissionId, err := sr.CreateSubmission(tx, models.Submission{
+			TaskId:     taskId,
+			UserId:     userId,
+			Order:      1,
+			LanguageId: language.Id,
+			Status:     "completed",
+			CheckedAt:  &checkedAt,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = srr.CreateSubmissionResult(tx, models.SubmissionResult{
+			SubmissionId: submissionId,
+			Code:         "1",
+			PassedTests:  2,
+			TotalTests:   2,
+			Score:        100,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return submissionId
+	}
+
+	t.Run("Discrepancy is recorded", func(t *testing.T) {
+		submissionId := createJudgedSubmission(t)
+		queueService.audited = nil
+		started, err := js.StartAudit(tx, 5)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, 1, started)
+		assert.Equal(t, []int64{submissionId}, queueService.audited)
+
+		audits, err := js.GetAudits(tx, admin, false, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, audits, 1) {
+			t.FailNow()
+		}
+		assert.Nil(t, audits[0].Discrepancy)
+
+		audit, err := js.RecordAuditResult(tx, audits[0].Id, schemas.ResponseMessage{
+			Result: schemas.Result{
+				Code:        "2",
+				TestResults: []schemas.TestResult{{Order: 1, Passed: true}, {Order: 2, Passed: false}},
+			},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if assert.NotNil(t, audit.Discrepancy) {
+			assert.True(t, *audit.Discrepancy)
+		}
+		assert.Equal(t, 50.0, *audit.ActualScore)
+
+		audits, err = js.GetAudits(tx, admin, true, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, audits, 1)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Matching verdict", func(t *testing.T) {
+		createJudgedSubmission(t)
+		_, err := js.StartAudit(tx, 5)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		audits, err := js.GetAudits(tx, admin, false, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, audits, 1) {
+			t.FailNow()
+		}
+		audit, err := js.RecordAuditResult(tx, audits[0].Id, schemas.ResponseMessage{
+			Result: schemas.Result{
+				Code:        "1",
+				TestResults: []schemas.TestResult{{Order: 1, Passed: true}, {Order: 2, Passed: true}},
+			},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.False(t, *audit.Discrepancy)
+
+		audits, err = js.GetAudits(tx, admin, true, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, audits)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		_, err := js.GetAudits(tx, schemas.User{Role: string(models.UserRoleTeacher)}, false, 10, 0)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+
+	t.Run("Audit not found", func(t *testing.T) {
+		_, err := js.RecordAuditResult(tx, -1, schemas.ResponseMessage{})
+		assert.ErrorIs(t, err, ErrJudgeAuditNotFound)
+	})
+	tx.Rollback()
+}
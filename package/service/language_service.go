@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrLanguageNotFound = errors.New("language not found")
+var ErrLanguageExists = errors.New("language with this version already exists")
+var ErrInvalidSourceExtension = errors.New("file extension does not match the language")
+
+type LanguageService interface {
+	// GetLanguages returns languages with their configuration. Only admins can see the configuration
+	GetLanguages(tx *gorm.DB, currentUser schemas.User) ([]schemas.LanguageConfigDetailed, error)
+	// CreateLanguage adds a language version submissions can be made in. Only admins can manage languages
+	CreateLanguage(tx *gorm.DB, currentUser schemas.User, language schemas.LanguageConfigCreate) (*schemas.LanguageConfigDetailed, error)
+	// UpdateLanguage changes the configuration of a language. Submissions judged afterwards use the new configuration,
+	// existing results are kept
+	UpdateLanguage(tx *gorm.DB, currentUser schemas.User, languageId int64, update schemas.LanguageConfigUpdate) (*schemas.LanguageConfigDetailed, error)
+	// ValidateSourceFile checks that a source with the file name can be submitted in the language.
+	// Returns ErrLanguageNotFound or ErrInvalidSourceExtension otherwise
+	ValidateSourceFile(tx *gorm.DB, languageId int64, fileName string) error
+}
+
+type LanguageServiceImpl struct {
+	languageRepository   repository.LanguageRepository
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ls *LanguageServiceImpl) GetLanguages(tx *gorm.DB, currentUser schemas.User) ([]schemas.LanguageConfigDetailed, error) {
+	if !ls.accessControlService.Can(currentUser, ResourceLanguage, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	languages, err := ls.languageRepository.GetLanguages(tx)
+	if err != nil {
+		ls.logger.Errorf("Error getting languages: %v", err.Error())
+		return nil, err
+	}
+	result := make([]schemas.LanguageConfigDetailed, 0, len(languages))
+	for _, language := range languages {
+		result = append(result, *ls.modelToSchema(&language))
+	}
+	return result, nil
+}
+
+func (ls *LanguageServiceImpl) CreateLanguage(tx *gorm.DB, currentUser schemas.User, language schemas.LanguageConfigCreate) (*schemas.LanguageConfigDetailed, error) {
+	if !ls.accessControlService.Can(currentUser, ResourceLanguage, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(language); err != nil {
+		ls.logger.Errorf("Error validating language: %v", err.Error())
+		return nil, err
+	}
+
+	_, err := ls.languageRepository.GetLanguageByVersion(tx, models.LanguageType(language.Language), language.Version)
+	if err == nil {
+		return nil, ErrLanguageExists
+	}
+	if err != gorm.ErrRecordNotFound {
+		ls.logger.Errorf("Error getting language: %v", err.Error())
+		return nil, err
+	}
+
+	model := &models.LanguageConfig{
+		Type:             models.LanguageType(language.Language),
+		Version:          language.Version,
+		CompilerFlags:    language.CompilerFlags,
+		RunArgs:          language.RunArgs,
+		QueueName:        language.QueueName,
+		TimeMultiplier:   1,
+		MemoryMultiplier: 1,
+	}
+	if language.TimeMultiplier != nil {
+		model.TimeMultiplier = *language.TimeMultiplier
+	}
+	if language.MemoryMultiplier != nil {
+		model.MemoryMultiplier = *language.MemoryMultiplier
+	}
+	_, err = ls.languageRepository.CreateLanguage(tx, model)
+	if err != nil {
+		ls.logger.Errorf("Error creating language: %v", err.Error())
+		return nil, err
+	}
+	ls.logger.Infof("Language %s %s created by user %d", model.Type, model.Version, currentUser.Id)
+	return ls.modelToSchema(model), nil
+}
+
+func (ls *LanguageServiceImpl) UpdateLanguage(tx *gorm.DB, currentUser schemas.User, languageId int64, update schemas.LanguageConfigUpdate) (*schemas.LanguageConfigDetailed, error) {
+	if !ls.accessControlService.Can(currentUser, ResourceLanguage, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(update); err != nil {
+		ls.logger.Errorf("Error validating language update: %v", err.Error())
+		return nil, err
+	}
+
+	language, err := ls.languageRepository.GetLanguage(tx, languageId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrLanguageNotFound
+		}
+		ls.logger.Errorf("Error getting language: %v", err.Error())
+		return nil, err
+	}
+	if update.CompilerFlags != nil {
+		language.CompilerFlags = *update.CompilerFlags
+	}
+	if update.RunArgs != nil {
+		language.RunArgs = *update.RunArgs
+	}
+	if update.TimeMultiplier != nil {
+		language.TimeMultiplier = *update.TimeMultiplier
+	}
+	if update.MemoryMultiplier != nil {
+		language.MemoryMultiplier = *update.MemoryMultiplier
+	}
+	if update.QueueName != nil {
+		language.QueueName = *update.QueueName
+	}
+	err = ls.languageRepository.UpdateLanguage(tx, language)
+	if err != nil {
+		ls.logger.Errorf("Error updating language: %v", err.Error())
+		return nil, err
+	}
+	ls.logger.Infof("Language %d updated by user %d", languageId, currentUser.Id)
+	return ls.modelToSchema(language), nil
+}
+
+func (ls *LanguageServiceImpl) ValidateSourceFile(tx *gorm.DB, languageId int64, fileName string) error {
+	language, err := ls.languageRepository.GetLanguage(tx, languageId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrLanguageNotFound
+		}
+		ls.logger.Errorf("Error getting language: %v", err.Error())
+		return err
+	}
+	if !language.Type.AcceptsFile(fileName) {
+		return ErrInvalidSourceExtension
+	}
+	return nil
+}
+
+func (ls *LanguageServiceImpl) modelToSchema(model *models.LanguageConfig) *schemas.LanguageConfigDetailed {
+	return &schemas.LanguageConfigDetailed{
+		Id:               model.Id,
+		Language:         string(model.Type),
+		Version:          model.Version,
+		CompilerFlags:    model.CompilerFlags,
+		RunArgs:          model.RunArgs,
+		TimeMultiplier:   model.TimeMultiplier,
+		MemoryMultiplier: model.MemoryMultiplier,
+		QueueName:        model.QueueName,
+		Extensions:       model.Type.Extensions(),
+		ExecutionMode:    string(model.Type.ExecutionMode()),
+	}
+}
+
+func NewLanguageService(languageRepository repository.LanguageRepository, accessControlService AccessControlService) LanguageService {
+	log := logger.NewNamedLogger("language_service")
+	return &LanguageServiceImpl{
+		languageRepository:   languageRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
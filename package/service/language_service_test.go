@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguageConfiguration(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	lr, err := repository.NewLanguageRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ls := NewLanguageService(lr, newAccessControlServiceTest(t, tx))
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+
+	timeMultiplier := 3.0
+	language, err := ls.CreateLanguage(tx, admin, schemas.LanguageConfigCreate{
+		Language:       "python",
+		Version:        "3.13",
+		RunArgs:        "-OO",
+		TimeMultiplier: &timeMultiplier,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 3.0, language.TimeMultiplier)
+	assert.Equal(t, 1.0, language.MemoryMultiplier)
+
+	t.Run("Duplicate version", func(t *testing.T) {
+		_, err := ls.CreateLanguage(tx, admin, schemas.LanguageConfigCreate{Language: "python", Version: "3.13"})
+		assert.ErrorIs(t, err, ErrLanguageExists)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		compilerFlags := "-O2"
+		updated, err := ls.UpdateLanguage(tx, admin, language.Id, schemas.LanguageConfigUpdate{CompilerFlags: &compilerFlags})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "-O2", updated.CompilerFlags)
+		assert.Equal(t, "-OO", updated.RunArgs)
+		assert.Equal(t, 3.0, updated.TimeMultiplier)
+
+		queueName := "python_queue"
+		updated, err = ls.UpdateLanguage(tx, admin, language.Id, schemas.LanguageConfigUpdate{QueueName: &queueName})
+		assert.NoError(t, err)
+		assert.Equal(t, queueName, updated.QueueName)
+		assert.Equal(t, "-O2", updated.CompilerFlags)
+	})
+
+	t.Run("Invalid multiplier", func(t *testing.T) {
+		zero := 0.0
+		_, err := ls.UpdateLanguage(tx, admin, language.Id, schemas.LanguageConfigUpdate{MemoryMultiplier: &zero})
+		assert.Error(t, err)
+	})
+
+	t.Run("Nonexistent language", func(t *testing.T) {
+		_, err := ls.UpdateLanguage(tx, admin, language.Id+1, schemas.LanguageConfigUpdate{})
+		assert.ErrorIs(t, err, ErrLanguageNotFound)
+	})
+
+	t.Run("Source file extension", func(t *testing.T) {
+		assert.NoError(t, ls.ValidateSourceFile(tx, language.Id, "Main.PY"))
+		assert.ErrorIs(t, ls.ValidateSourceFile(tx, language.Id, "main.c"), ErrInvalidSourceExtension)
+		assert.ErrorIs(t, ls.ValidateSourceFile(tx, language.Id+1, "main.py"), ErrLanguageNotFound)
+	})
+
+	t.Run("Default languages", func(t *testing.T) {
+		languages, err := ls.GetLanguages(tx, admin)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		modes := map[string]string{}
+		for _, language := range languages {
+			modes[language.Language] = language.ExecutionMode
+		}
+		assert.Equal(t, string(models.ExecutionModeInterpret), modes["python"])
+		assert.Equal(t, string(models.ExecutionModeCompile), modes["java"])
+	})
+
+	t.Run("Teacher", func(t *testing.T) {
+		_, err := ls.UpdateLanguage(tx, schemas.User{Id: 2, Role: string(models.UserRoleTeacher)}, language.Id, schemas.LanguageConfigUpdate{})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+	tx.Rollback()
+}
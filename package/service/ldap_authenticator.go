@@ -0,0 +1,137 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/package/domain/models"
+)
+
+// LDAPTimeout bounds connecting to and each request of the LDAP server
+const LDAPTimeout = 10 * time.Second
+
+var (
+	ErrLDAPUserNotFound = errors.New("no ldap user with the email")
+	ErrLDAPUnavailable  = errors.New("ldap server is unavailable")
+)
+
+// LDAPUser is a directory user who logged in
+type LDAPUser struct {
+	Email    string
+	Username string
+	Name     string
+	Surname  string
+	Role     models.UserRole
+}
+
+type LDAPAuthenticator interface {
+	// Authenticate binds as the directory user with the email. Returns ErrLDAPUserNotFound when no user has
+	// the email and ErrInvalidCredentials when the password is wrong
+	Authenticate(email string, password string) (*LDAPUser, error)
+}
+
+type LDAPAuthenticatorImpl struct {
+	config config.LDAPConfig
+}
+
+func (la *LDAPAuthenticatorImpl) Authenticate(email string, password string) (*LDAPUser, error) {
+	// A bind without a password is an anonymous bind, which most servers accept
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := la.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if la.config.BindDN != "" {
+		if err := conn.Bind(la.config.BindDN, la.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("binding as the service account: %w", err)
+		}
+	}
+	attributes := []string{la.config.EmailAttribute, la.config.UsernameAttribute, la.config.NameAttribute, la.config.SurnameAttribute, la.config.GroupAttribute}
+	search := ldap.NewSearchRequest(
+		la.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(LDAPTimeout.Seconds()), false,
+		fmt.Sprintf("(%s=%s)", la.config.EmailAttribute, ldap.EscapeFilter(email)),
+		attributes, nil,
+	)
+	result, err := conn.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("searching user: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrLDAPUserNotFound
+	}
+	// The email could belong to either of the users, so none of them is logged in
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("%d ldap users have the email %s", len(result.Entries), email)
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("binding as the user: %w", err)
+	}
+	return &LDAPUser{
+		Email:    strings.ToLower(entry.GetAttributeValue(la.config.EmailAttribute)),
+		Username: entry.GetAttributeValue(la.config.UsernameAttribute),
+		Name:     entry.GetAttributeValue(la.config.NameAttribute),
+		Surname:  entry.GetAttributeValue(la.config.SurnameAttribute),
+		Role:     la.role(entry.GetAttributeValues(la.config.GroupAttribute)),
+	}, nil
+}
+
+// role returns the role of a member of the groups. Admin groups take precedence over teacher groups
+func (la *LDAPAuthenticatorImpl) role(groups []string) models.UserRole {
+	memberOf := func(roleGroups []string) bool {
+		for _, group := range groups {
+			for _, roleGroup := range roleGroups {
+				if strings.EqualFold(group, roleGroup) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if memberOf(la.config.AdminGroups) {
+		return models.UserRoleAdmin
+	}
+	if memberOf(la.config.TeacherGroups) {
+		return models.UserRoleTeacher
+	}
+	return models.UserRoleStudent
+}
+
+func (la *LDAPAuthenticatorImpl) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(la.config.Url, ldap.DialWithDialer(&net.Dialer{Timeout: LDAPTimeout}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+	conn.SetTimeout(LDAPTimeout)
+	if la.config.StartTLS {
+		serverUrl, err := url.Parse(la.config.Url)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: serverUrl.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: starting TLS: %v", ErrLDAPUnavailable, err)
+		}
+	}
+	return conn, nil
+}
+
+func NewLDAPAuthenticator(cfg config.LDAPConfig) LDAPAuthenticator {
+	return &LDAPAuthenticatorImpl{config: cfg}
+}
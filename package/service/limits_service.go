@@ -0,0 +1,73 @@
+package service
+
+import (
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type LimitsService interface {
+	// GetLimits returns the limits applying to the user. Trusted requests are not rate limited
+	GetLimits(tx *gorm.DB, currentUser schemas.User, trusted bool) (*schemas.Limits, error)
+}
+
+type LimitsServiceImpl struct {
+	languageRepository   repository.LanguageRepository
+	maxJSONBodySize      int64
+	maxMultipartBodySize int64
+	maxSubmissionSize    int64
+	sandboxRateLimit     int
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ls *LimitsServiceImpl) GetLimits(tx *gorm.DB, currentUser schemas.User, trusted bool) (*schemas.Limits, error) {
+	languages, err := ls.languageRepository.GetLanguages(tx)
+	if err != nil {
+		ls.logger.Errorf("Error getting languages: %v", err.Error())
+		return nil, err
+	}
+
+	limits := &schemas.Limits{
+		MaxJSONBodySize:      ls.maxJSONBodySize,
+		MaxMultipartBodySize: ls.maxMultipartBodySize,
+		MaxSubmissionSize:    ls.maxSubmissionSize,
+		Languages:            make([]schemas.Language, 0, len(languages)),
+	}
+	if ls.sandboxRateLimit > 0 && !trusted {
+		limits.SandboxRateLimit = &ls.sandboxRateLimit
+	}
+	if ls.accessControlService.Can(currentUser, ResourceTask, ActionRejudge) {
+		maxRejudgeSubmissions := int64(MaxRejudgeSubmissions)
+		limits.MaxRejudgeSubmissions = &maxRejudgeSubmissions
+	}
+	if ls.accessControlService.Can(currentUser, ResourceUser, ActionImport) {
+		maxUserImportRows := int64(MaxUserImportRows)
+		limits.MaxUserImportRows = &maxUserImportRows
+	}
+	for _, language := range languages {
+		limits.Languages = append(limits.Languages, schemas.Language{
+			Id:         language.Id,
+			Language:   string(language.Type),
+			Version:    language.Version,
+			Extensions: language.Type.Extensions(),
+		})
+	}
+	return limits, nil
+}
+
+// NewLimitsService creates the service. A sandboxRateLimit of 0 means the sandbox is disabled
+func NewLimitsService(languageRepository repository.LanguageRepository, accessControlService AccessControlService, maxJSONBodySize, maxMultipartBodySize, maxSubmissionSize int64, sandboxRateLimit int) LimitsService {
+	log := logger.NewNamedLogger("limits_service")
+	return &LimitsServiceImpl{
+		languageRepository:   languageRepository,
+		maxJSONBodySize:      maxJSONBodySize,
+		maxMultipartBodySize: maxMultipartBodySize,
+		maxSubmissionSize:    maxSubmissionSize,
+		sandboxRateLimit:     sandboxRateLimit,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
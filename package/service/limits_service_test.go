@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLimits(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	lr, err := repository.NewLanguageRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ls := NewLimitsService(lr, newAccessControlServiceTest(t, tx), 1024, 2048, 4096, 30)
+
+	t.Run("Student", func(t *testing.T) {
+		limits, err := ls.GetLimits(tx, schemas.User{Id: 1, Role: string(models.UserRoleStudent)}, false)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(4096), limits.MaxSubmissionSize)
+		if assert.NotNil(t, limits.SandboxRateLimit) {
+			assert.Equal(t, 30, *limits.SandboxRateLimit)
+		}
+		assert.Nil(t, limits.MaxRejudgeSubmissions)
+		assert.Nil(t, limits.MaxUserImportRows)
+		assert.NotNil(t, limits.Languages)
+	})
+
+	t.Run("Trusted admin", func(t *testing.T) {
+		limits, err := ls.GetLimits(tx, schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}, true)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Nil(t, limits.SandboxRateLimit)
+		assert.NotNil(t, limits.MaxRejudgeSubmissions)
+		assert.NotNil(t, limits.MaxUserImportRows)
+	})
+	tx.Rollback()
+}
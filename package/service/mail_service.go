@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"go.uber.org/zap"
+)
+
+type MailService interface {
+	// Send sends a plain text mail to the address
+	Send(to string, subject string, body string) error
+}
+
+type SMTPMailService struct {
+	addr   string
+	auth   smtp.Auth
+	from   string
+	logger *zap.SugaredLogger
+}
+
+func (ms *SMTPMailService) Send(to string, subject string, body string) error {
+	// Addresses come from validated user emails, but headers must never be injected through them
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	message := "From: " + ms.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	err := smtp.SendMail(ms.addr, ms.auth, ms.from, []string{to}, []byte(message))
+	if err != nil {
+		ms.logger.Errorf("Error sending mail: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+// AsyncMailService hands mails to the wrapped service in the background, so requests neither wait for the mail
+// server nor take longer when a mail is sent. Send only fails for invalid headers, delivery errors are logged
+type AsyncMailService struct {
+	mailService MailService
+	logger      *zap.SugaredLogger
+}
+
+func (ms *AsyncMailService) Send(to string, subject string, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	go func() {
+		if err := ms.mailService.Send(to, subject, body); err != nil {
+			ms.logger.Warnf("Mail %q was not delivered: %v", subject, err.Error())
+		}
+	}()
+	return nil
+}
+
+func NewAsyncMailService(mailService MailService) MailService {
+	log := logger.NewNamedLogger("mail_service")
+	return &AsyncMailService{mailService: mailService, logger: log}
+}
+
+// NewSMTPMailService creates a mail service sending through the SMTP server. Authentication is skipped
+// when username is empty
+func NewSMTPMailService(host string, port uint16, username, password, from string) MailService {
+	log := logger.NewNamedLogger("mail_service")
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailService{
+		addr:   fmt.Sprintf("%s:%d", host, port),
+		auth:   auth,
+		from:   from,
+		logger: log,
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrNotificationNotFound = fmt.Errorf("notification not found")
+
+type NotificationService interface {
+	// Notify notifies the users about a change made by the actor and mails them when mail is enabled.
+	// The actor is not notified about own changes. Failed mails are logged and do not fail the change
+	Notify(tx *gorm.DB, actor schemas.User, userIds []int64, notificationType models.NotificationType, taskId *int64, message string) error
+	// GetNotifications returns notifications of the current user, newest first
+	GetNotifications(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.Notification, error)
+	MarkNotificationRead(tx *gorm.DB, currentUser schemas.User, notificationId int64) error
+}
+
+type NotificationServiceImpl struct {
+	notificationRepository repository.NotificationRepository
+	userRepository         repository.UserRepository
+	mailService            MailService
+	logger                 *zap.SugaredLogger
+}
+
+func (ns *NotificationServiceImpl) Notify(tx *gorm.DB, actor schemas.User, userIds []int64, notificationType models.NotificationType, taskId *int64, message string) error {
+	notifications := make([]models.Notification, 0, len(userIds))
+	for _, userId := range userIds {
+		if userId == actor.Id {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			UserId:  userId,
+			Type:    notificationType,
+			ActorId: actor.Id,
+			TaskId:  taskId,
+			Message: message,
+		})
+	}
+	err := ns.notificationRepository.CreateNotifications(tx, notifications)
+	if err != nil {
+		ns.logger.Errorf("Error creating notifications: %v", err.Error())
+		return err
+	}
+
+	if ns.mailService == nil {
+		return nil
+	}
+	for _, notification := range notifications {
+		user, err := ns.userRepository.GetUser(tx, notification.UserId)
+		if err != nil {
+			ns.logger.Errorf("Error getting user: %v", err.Error())
+			return err
+		}
+		err = ns.mailService.Send(user.Email, "Mini Maxit notification", message)
+		if err != nil {
+			ns.logger.Warnf("Notification %d was not mailed to user %d: %v", notification.Id, user.Id, err.Error())
+		}
+	}
+	return nil
+}
+
+func (ns *NotificationServiceImpl) GetNotifications(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.Notification, error) {
+	notifications, err := ns.notificationRepository.GetNotifications(tx, currentUser.Id, limit, offset)
+	if err != nil {
+		ns.logger.Errorf("Error getting notifications: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		result = append(result, schemas.Notification{
+			Id:        notification.Id,
+			Type:      string(notification.Type),
+			ActorId:   notification.ActorId,
+			TaskId:    notification.TaskId,
+			Message:   notification.Message,
+			CreatedAt: notification.CreatedAt,
+			ReadAt:    notification.ReadAt,
+		})
+	}
+	return result, nil
+}
+
+func (ns *NotificationServiceImpl) MarkNotificationRead(tx *gorm.DB, currentUser schemas.User, notificationId int64) error {
+	found, err := ns.notificationRepository.MarkRead(tx, currentUser.Id, notificationId)
+	if err != nil {
+		ns.logger.Errorf("Error marking notification read: %v", err.Error())
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// NewNotificationService creates the service. Notifications are not mailed when mailService is nil
+func NewNotificationService(notificationRepository repository.NotificationRepository, userRepository repository.UserRepository, mailService MailService) NotificationService {
+	log := logger.NewNamedLogger("notification_service")
+	return &NotificationServiceImpl{
+		notificationRepository: notificationRepository,
+		userRepository:         userRepository,
+		mailService:            mailService,
+		logger:                 log,
+	}
+}
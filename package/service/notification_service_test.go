@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifications(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	nr, err := repository.NewNotificationRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	mail := &mailServiceStub{}
+	ns := NewNotificationService(nr, ur, mail)
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	createUsers := func(t *testing.T) (schemas.User, schemas.User) {
+		users := []*models.User{
+			{Name: "Actor", Surname: "User", Email: "actor@email.com", Username: "actor", PasswordHash: "password"},
+			{Name: "Other", Surname: "User", Email: "other@email.com", Username: "other", PasswordHash: "password"},
+		}
+		if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+			t.FailNow()
+		}
+		return schemas.User{Id: users[0].Id}, schemas.User{Id: users[1].Id}
+	}
+
+	t.Run("Notify", func(t *testing.T) {
+		mail.sent = nil
+		actor, other := createUsers(t)
+		err := ns.Notify(tx, actor, []int64{actor.Id, other.Id}, models.NotificationTypeCoAuthorAdded, nil, "Added")
+		assert.NoError(t, err)
+
+		notifications, err := ns.GetNotifications(tx, other, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, notifications, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, "Added", notifications[0].Message)
+		assert.Nil(t, notifications[0].ReadAt)
+		assert.Equal(t, []string{"Added"}, mail.sent)
+
+		// The actor is not notified about own changes
+		notifications, err = ns.GetNotifications(tx, actor, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, notifications)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Mark read", func(t *testing.T) {
+		actor, other := createUsers(t)
+		err := ns.Notify(tx, actor, []int64{other.Id}, models.NotificationTypeCoAuthorRemoved, nil, "Removed")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		notifications, err := ns.GetNotifications(tx, other, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, notifications, 1) {
+			t.FailNow()
+		}
+
+		err = ns.MarkNotificationRead(tx, actor, notifications[0].Id)
+		assert.ErrorIs(t, err, ErrNotificationNotFound)
+		err = ns.MarkNotificationRead(tx, other, notifications[0].Id)
+		assert.NoError(t, err)
+
+		notifications, err = ns.GetNotifications(tx, other, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, notifications, 1) {
+			t.FailNow()
+		}
+		assert.NotNil(t, notifications[0].ReadAt)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
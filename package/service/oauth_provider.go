@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/config"
+	"github.com/mini-maxit/backend/package/domain/models"
+)
+
+// OAuthProviderTimeout bounds each request to an OAuth provider
+const OAuthProviderTimeout = 10 * time.Second
+
+// OAuthIdentity is the user who logged in with a provider, as described by its userinfo endpoint
+type OAuthIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Surname       string
+}
+
+type OAuthProvider interface {
+	// AuthorizationUrl returns the login page of the provider, which redirects back with the state and an authorization code
+	AuthorizationUrl(state string, codeChallenge string) (string, error)
+	// Exchange exchanges an authorization code for the identity of the user who logged in
+	Exchange(code string, codeVerifier string) (*OAuthIdentity, error)
+	// Role returns the role of a new account with the email
+	Role(email string) models.UserRole
+}
+
+// oidcDiscovery is the part of the OpenID Connect discovery document of an issuer used for login
+type oidcDiscovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// OIDCProvider logs users in with the authorization code flow with PKCE. The identity is read from the userinfo
+// endpoint with the access token, which the token endpoint returns directly to the backend over TLS, so the ID
+// token does not need to be verified
+type OIDCProvider struct {
+	config config.OAuthProviderConfig
+	client *http.Client
+	// The discovery document is fetched on the first login, so an unreachable provider does not stop the backend
+	discoveryMu sync.Mutex
+	discovery   *oidcDiscovery
+}
+
+func (op *OIDCProvider) AuthorizationUrl(state string, codeChallenge string) (string, error) {
+	discovery, err := op.discover()
+	if err != nil {
+		return "", err
+	}
+	authorizationUrl, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	query := authorizationUrl.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", op.config.ClientId)
+	query.Set("redirect_uri", op.config.RedirectUrl)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authorizationUrl.RawQuery = query.Encode()
+	return authorizationUrl.String(), nil
+}
+
+func (op *OIDCProvider) Exchange(code string, codeVerifier string) (*OAuthIdentity, error) {
+	discovery, err := op.discover()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {op.config.RedirectUrl},
+		"code_verifier": {codeVerifier},
+	}
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(op.config.ClientId), url.QueryEscape(op.config.ClientSecret))
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := op.doJSON(request, &token); err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token endpoint returned no access token")
+	}
+
+	request, err = http.NewRequest(http.MethodGet, discovery.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	claims := struct {
+		Subject    string `json:"sub"`
+		Email      string `json:"email"`
+		GivenName  string `json:"given_name"`
+		FamilyName string `json:"family_name"`
+		// Some providers send the flag as a string
+		EmailVerified any `json:"email_verified"`
+	}{}
+	if err := op.doJSON(request, &claims); err != nil {
+		return nil, fmt.Errorf("getting user info: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("userinfo endpoint returned no subject")
+	}
+	return &OAuthIdentity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.GivenName,
+		Surname:       claims.FamilyName,
+	}, nil
+}
+
+func (op *OIDCProvider) Role(email string) models.UserRole {
+	email = strings.ToLower(email)
+	for _, rule := range op.config.RoleRules {
+		if matched, _ := path.Match(rule.EmailPattern, email); matched {
+			return models.UserRole(rule.Role)
+		}
+	}
+	return models.UserRoleStudent
+}
+
+func (op *OIDCProvider) discover() (*oidcDiscovery, error) {
+	op.discoveryMu.Lock()
+	defer op.discoveryMu.Unlock()
+	if op.discovery != nil {
+		return op.discovery, nil
+	}
+	request, err := http.NewRequest(http.MethodGet, op.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	discovery := &oidcDiscovery{}
+	if err := op.doJSON(request, discovery); err != nil {
+		return nil, fmt.Errorf("getting discovery document of %s: %w", op.config.Issuer, err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("discovery document of %s has no authorization, token or userinfo endpoint", op.config.Issuer)
+	}
+	op.discovery = discovery
+	return discovery, nil
+}
+
+// doJSON sends the request and decodes a successful JSON response into result
+func (op *OIDCProvider) doJSON(request *http.Request, result any) error {
+	request.Header.Set("Accept", "application/json")
+	response, err := op.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", request.URL.Host, response.StatusCode, body)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// NewOAuthProviders creates a provider for each configured one, by name
+func NewOAuthProviders(cfg config.OAuthConfig) map[string]OAuthProvider {
+	providers := make(map[string]OAuthProvider, len(cfg.Providers))
+	for name, providerConfig := range cfg.Providers {
+		providers[name] = &OIDCProvider{
+			config: providerConfig,
+			client: &http.Client{Timeout: OAuthProviderTimeout},
+		}
+	}
+	return providers
+}
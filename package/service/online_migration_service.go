@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const DefaultBackfillBatchSize = 500
+
+// MaxBackfillFailures is the number of backfill batches failing in a row after which the backfill is paused
+// until an admin resumes it
+const MaxBackfillFailures = 10
+
+var ErrOnlineMigrationNotFound = errors.New("online migration not found")
+var ErrOnlineMigrationNotBackfilled = errors.New("online migration is not backfilled yet")
+var ErrOnlineMigrationPaused = errors.New("online migration backfill is paused")
+
+// Backfill copies existing rows into the schema introduced by an online migration.
+//
+// An online migration adds the new schema next to the old one and goes through three phases:
+// writers fill both schemas while Backfill processes existing rows, then reads stay on the old
+// schema until an admin switches them over with the cutover switch. Code reading the affected
+// data checks IsCutOver to pick the schema. Once a migration is cut over in every deployment
+// the old schema and the dual writes can be dropped.
+type Backfill interface {
+	// Name uniquely identifies the migration
+	Name() string
+	// Count returns the number of rows to backfill, used for progress reporting
+	Count(tx *gorm.DB) (int64, error)
+	// BackfillBatch backfills at most batchSize rows with id greater than afterId in id order.
+	// Returns the id of the last processed row and the number of processed rows.
+	// Rows written during the dual write phase may be backfilled again, so it has to be idempotent
+	BackfillBatch(tx *gorm.DB, afterId int64, batchSize int) (int64, int64, error)
+}
+
+type OnlineMigrationService interface {
+	// Register adds a backfill run by the backfill worker
+	Register(backfill Backfill)
+	// RegisteredMigrations returns names of registered migrations in registration order
+	RegisteredMigrations() []string
+	// BackfillBatch backfills the next batch of the migration. Returns true once there is nothing left to backfill.
+	// If another transaction is already backfilling the migration it returns false without doing anything.
+	// Returns ErrOnlineMigrationPaused while the backfill is paused
+	BackfillBatch(tx *gorm.DB, name string) (bool, error)
+	// RecordBackfillError stores the error of a failed batch so it is visible to admins. The backfill is paused
+	// once MaxBackfillFailures batches failed in a row
+	RecordBackfillError(tx *gorm.DB, name string, backfillErr error) error
+	// ResumeBackfill resumes a backfill paused after too many failed batches
+	ResumeBackfill(tx *gorm.DB, currentUser schemas.User, name string) (*schemas.OnlineMigration, error)
+	// IsCutOver reports whether reads of the migration should use the new schema
+	IsCutOver(tx *gorm.DB, name string) (bool, error)
+	GetMigrations(tx *gorm.DB, currentUser schemas.User) ([]schemas.OnlineMigration, error)
+	// SetCutover switches reads of a backfilled migration to the new schema or back to the old one
+	SetCutover(tx *gorm.DB, currentUser schemas.User, name string, request schemas.OnlineMigrationCutover) (*schemas.OnlineMigration, error)
+}
+
+type OnlineMigrationServiceImpl struct {
+	migrationRepository  repository.OnlineMigrationRepository
+	backfills            map[string]Backfill
+	names                []string
+	batchSize            int
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (oms *OnlineMigrationServiceImpl) Register(backfill Backfill) {
+	if _, ok := oms.backfills[backfill.Name()]; ok {
+		oms.logger.Panicf("Online migration %s is already registered", backfill.Name())
+	}
+	oms.backfills[backfill.Name()] = backfill
+	oms.names = append(oms.names, backfill.Name())
+}
+
+func (oms *OnlineMigrationServiceImpl) RegisteredMigrations() []string {
+	return oms.names
+}
+
+func (oms *OnlineMigrationServiceImpl) BackfillBatch(tx *gorm.DB, name string) (bool, error) {
+	backfill, ok := oms.backfills[name]
+	if !ok {
+		return false, ErrOnlineMigrationNotFound
+	}
+
+	err := oms.migrationRepository.EnsureMigration(tx, name)
+	if err != nil {
+		oms.logger.Errorf("Error creating online migration: %v", err.Error())
+		return false, err
+	}
+	migration, err := oms.migrationRepository.LockMigration(tx, name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Another replica is backfilling this migration
+			return false, nil
+		}
+		oms.logger.Errorf("Error locking online migration: %v", err.Error())
+		return false, err
+	}
+	if migration.Phase != models.OnlineMigrationPhaseDualWrite {
+		return true, nil
+	}
+	if migration.PausedAt != nil {
+		return false, ErrOnlineMigrationPaused
+	}
+
+	if migration.StartedAt == nil {
+		total, err := backfill.Count(tx)
+		if err != nil {
+			oms.logger.Errorf("Error counting rows of online migration %s: %v", name, err.Error())
+			return false, err
+		}
+		now := time.Now()
+		migration.StartedAt = &now
+		migration.Total = total
+	}
+
+	lastId, processed, err := backfill.BackfillBatch(tx, migration.LastId, oms.batchSize)
+	if err != nil {
+		oms.logger.Errorf("Error backfilling online migration %s: %v", name, err.Error())
+		return false, err
+	}
+	if processed > 0 {
+		migration.LastId = lastId
+	}
+	migration.Processed += processed
+	migration.Error = ""
+	migration.Failures = 0
+	done := processed < int64(oms.batchSize)
+	if done {
+		now := time.Now()
+		migration.Phase = models.OnlineMigrationPhaseBackfilled
+		migration.CompletedAt = &now
+		oms.logger.Infof("Online migration %s backfilled %d rows", name, migration.Processed)
+	}
+
+	err = oms.migrationRepository.UpdateMigration(tx, migration)
+	if err != nil {
+		oms.logger.Errorf("Error updating online migration: %v", err.Error())
+		return false, err
+	}
+	return done, nil
+}
+
+func (oms *OnlineMigrationServiceImpl) RecordBackfillError(tx *gorm.DB, name string, backfillErr error) error {
+	migration, err := oms.migrationRepository.GetMigration(tx, name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrOnlineMigrationNotFound
+		}
+		oms.logger.Errorf("Error getting online migration: %v", err.Error())
+		return err
+	}
+
+	migration.Error = backfillErr.Error()
+	migration.Failures++
+	if migration.Failures >= MaxBackfillFailures && migration.PausedAt == nil {
+		now := time.Now()
+		migration.PausedAt = &now
+		oms.logger.Warnf("Backfill of online migration %s paused after %d failed batches", name, migration.Failures)
+	}
+	err = oms.migrationRepository.UpdateMigration(tx, migration)
+	if err != nil {
+		oms.logger.Errorf("Error updating online migration: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (oms *OnlineMigrationServiceImpl) ResumeBackfill(tx *gorm.DB, currentUser schemas.User, name string) (*schemas.OnlineMigration, error) {
+	if !oms.accessControlService.Can(currentUser, ResourceMigration, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	migration, err := oms.migrationRepository.GetMigration(tx, name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrOnlineMigrationNotFound
+		}
+		oms.logger.Errorf("Error getting online migration: %v", err.Error())
+		return nil, err
+	}
+	if migration.PausedAt == nil {
+		return oms.modelToSchema(migration), nil
+	}
+
+	migration.PausedAt = nil
+	migration.Failures = 0
+	err = oms.migrationRepository.UpdateMigration(tx, migration)
+	if err != nil {
+		oms.logger.Errorf("Error updating online migration: %v", err.Error())
+		return nil, err
+	}
+	oms.logger.Infof("Backfill of online migration %s resumed by user %d", name, currentUser.Id)
+
+	return oms.modelToSchema(migration), nil
+}
+
+func (oms *OnlineMigrationServiceImpl) IsCutOver(tx *gorm.DB, name string) (bool, error) {
+	migration, err := oms.migrationRepository.GetMigration(tx, name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		oms.logger.Errorf("Error getting online migration: %v", err.Error())
+		return false, err
+	}
+	return migration.Phase == models.OnlineMigrationPhaseCutover, nil
+}
+
+func (oms *OnlineMigrationServiceImpl) GetMigrations(tx *gorm.DB, currentUser schemas.User) ([]schemas.OnlineMigration, error) {
+	if !oms.accessControlService.Can(currentUser, ResourceMigration, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	migrations, err := oms.migrationRepository.GetAllMigrations(tx)
+	if err != nil {
+		oms.logger.Errorf("Error getting online migrations: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.OnlineMigration, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, *oms.modelToSchema(&migration))
+	}
+	return result, nil
+}
+
+func (oms *OnlineMigrationServiceImpl) SetCutover(tx *gorm.DB, currentUser schemas.User, name string, request schemas.OnlineMigrationCutover) (*schemas.OnlineMigration, error) {
+	if !oms.accessControlService.Can(currentUser, ResourceMigration, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		oms.logger.Errorf("Error validating cutover request: %v", err.Error())
+		return nil, err
+	}
+
+	migration, err := oms.migrationRepository.GetMigration(tx, name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrOnlineMigrationNotFound
+		}
+		oms.logger.Errorf("Error getting online migration: %v", err.Error())
+		return nil, err
+	}
+	if migration.Phase == models.OnlineMigrationPhaseDualWrite {
+		return nil, ErrOnlineMigrationNotBackfilled
+	}
+
+	if *request.Enabled {
+		migration.Phase = models.OnlineMigrationPhaseCutover
+	} else {
+		migration.Phase = models.OnlineMigrationPhaseBackfilled
+	}
+	err = oms.migrationRepository.UpdateMigration(tx, migration)
+	if err != nil {
+		oms.logger.Errorf("Error updating online migration: %v", err.Error())
+		return nil, err
+	}
+	oms.logger.Infof("Online migration %s switched to phase %s by user %d", name, migration.Phase, currentUser.Id)
+
+	return oms.modelToSchema(migration), nil
+}
+
+func (oms *OnlineMigrationServiceImpl) modelToSchema(model *models.OnlineMigration) *schemas.OnlineMigration {
+	return &schemas.OnlineMigration{
+		Name:        model.Name,
+		Phase:       string(model.Phase),
+		Processed:   model.Processed,
+		Total:       model.Total,
+		Error:       model.Error,
+		Failures:    model.Failures,
+		PausedAt:    model.PausedAt,
+		StartedAt:   model.StartedAt,
+		CompletedAt: model.CompletedAt,
+	}
+}
+
+func NewOnlineMigrationService(migrationRepository repository.OnlineMigrationRepository, accessControlService AccessControlService, batchSize int) OnlineMigrationService {
+	log := logger.NewNamedLogger("online_migration_service")
+	return &OnlineMigrationServiceImpl{
+		migrationRepository:  migrationRepository,
+		backfills:            make(map[string]Backfill),
+		batchSize:            batchSize,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
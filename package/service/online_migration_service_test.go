@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// userBackfill pretends to backfill users, remembering ids of processed rows
+type userBackfill struct {
+	ids []int64
+}
+
+func (ub *userBackfill) Name() string {
+	return "test_user_backfill"
+}
+
+func (ub *userBackfill) Count(tx *gorm.DB) (int64, error) {
+	var count int64
+	err := tx.Model(&models.User{}).Count(&count).Error
+	return count, err
+}
+
+func (ub *userBackfill) BackfillBatch(tx *gorm.DB, afterId int64, batchSize int) (int64, int64, error) {
+	var ids []int64
+	err := tx.Model(&models.User{}).Where("id > ?", afterId).Order("id").Limit(batchSize).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return afterId, 0, err
+	}
+	ub.ids = append(ub.ids, ids...)
+	return ids[len(ids)-1], int64(len(ids)), nil
+}
+
+func TestOnlineMigration(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	omr, err := repository.NewOnlineMigrationRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+	enabled := true
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	newService := func(t *testing.T) (OnlineMigrationService, *userBackfill) {
+		for i := range 3 {
+			_, err := ur.CreateUser(tx, &models.User{
+				Name:         "Test User",
+				Surname:      "Test Surname",
+				Email:        fmt.Sprintf("backfill%d@email.com", i),
+				Username:     fmt.Sprintf("backfill%d", i),
+				PasswordHash: "password",
+			})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+		}
+		oms := NewOnlineMigrationService(omr, newAccessControlServiceTest(t, tx), 2)
+		backfill := &userBackfill{}
+		oms.Register(backfill)
+		return oms, backfill
+	}
+
+	t.Run("Backfill and cutover", func(t *testing.T) {
+		oms, backfill := newService(t)
+		name := backfill.Name()
+
+		_, err := oms.SetCutover(tx, admin, name, schemas.OnlineMigrationCutover{Enabled: &enabled})
+		assert.ErrorIs(t, err, ErrOnlineMigrationNotFound)
+
+		done := false
+		for range 100 {
+			done, err = oms.BackfillBatch(tx, name)
+			if !assert.NoError(t, err) || done {
+				break
+			}
+			// Reads stay on the old schema until the backfill finishes
+			_, err = oms.SetCutover(tx, admin, name, schemas.OnlineMigrationCutover{Enabled: &enabled})
+			assert.ErrorIs(t, err, ErrOnlineMigrationNotBackfilled)
+		}
+		assert.True(t, done)
+
+		migrations, err := oms.GetMigrations(tx, admin)
+		assert.NoError(t, err)
+		if assert.Len(t, migrations, 1) {
+			assert.Equal(t, string(models.OnlineMigrationPhaseBackfilled), migrations[0].Phase)
+			assert.Equal(t, int64(len(backfill.ids)), migrations[0].Processed)
+			assert.GreaterOrEqual(t, migrations[0].Processed, int64(3))
+			assert.NotNil(t, migrations[0].CompletedAt)
+		}
+
+		cutOver, err := oms.IsCutOver(tx, name)
+		assert.NoError(t, err)
+		assert.False(t, cutOver)
+		migration, err := oms.SetCutover(tx, admin, name, schemas.OnlineMigrationCutover{Enabled: &enabled})
+		assert.NoError(t, err)
+		assert.Equal(t, string(models.OnlineMigrationPhaseCutover), migration.Phase)
+		cutOver, err = oms.IsCutOver(tx, name)
+		assert.NoError(t, err)
+		assert.True(t, cutOver)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Paused after failed batches", func(t *testing.T) {
+		oms, backfill := newService(t)
+		name := backfill.Name()
+		_, err := oms.BackfillBatch(tx, name)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		for range MaxBackfillFailures {
+			assert.NoError(t, oms.RecordBackfillError(tx, name, errors.New("batch failed")))
+		}
+		_, err = oms.BackfillBatch(tx, name)
+		assert.ErrorIs(t, err, ErrOnlineMigrationPaused)
+		migrations, err := oms.GetMigrations(tx, admin)
+		assert.NoError(t, err)
+		if assert.Len(t, migrations, 1) {
+			assert.Equal(t, MaxBackfillFailures, migrations[0].Failures)
+			assert.NotNil(t, migrations[0].PausedAt)
+			assert.Equal(t, "batch failed", migrations[0].Error)
+		}
+
+		migration, err := oms.ResumeBackfill(tx, admin, name)
+		assert.NoError(t, err)
+		assert.Nil(t, migration.PausedAt)
+		assert.Equal(t, 0, migration.Failures)
+		_, err = oms.BackfillBatch(tx, name)
+		assert.NoError(t, err)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Failed batch followed by a successful one", func(t *testing.T) {
+		oms, backfill := newService(t)
+		name := backfill.Name()
+		_, err := oms.BackfillBatch(tx, name)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NoError(t, oms.RecordBackfillError(tx, name, errors.New("batch failed")))
+		_, err = oms.BackfillBatch(tx, name)
+		assert.NoError(t, err)
+		migrations, err := oms.GetMigrations(tx, admin)
+		assert.NoError(t, err)
+		if assert.Len(t, migrations, 1) {
+			assert.Equal(t, 0, migrations[0].Failures)
+			assert.Nil(t, migrations[0].PausedAt)
+			assert.Empty(t, migrations[0].Error)
+		}
+		_, err = oms.ResumeBackfill(tx, admin, "unknown")
+		assert.ErrorIs(t, err, ErrOnlineMigrationNotFound)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		oms, backfill := newService(t)
+		student := schemas.User{Role: string(models.UserRoleStudent)}
+		_, err := oms.GetMigrations(tx, student)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = oms.SetCutover(tx, student, backfill.Name(), schemas.OnlineMigrationCutover{Enabled: &enabled})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = oms.ResumeBackfill(tx, student, backfill.Name())
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PartitionMonthsAhead is the number of future monthly partitions kept in addition to the current one
+const PartitionMonthsAhead = 2
+
+// partitionedTables lists tables partitioned monthly
+var partitionedTables = []string{"submissions", "test_results"}
+
+type PartitionService interface {
+	// EnsurePartitions creates monthly partitions for the month of now and PartitionMonthsAhead following months.
+	// Tables which are not partitioned are skipped
+	EnsurePartitions(tx *gorm.DB, now time.Time) error
+}
+
+type PartitionServiceImpl struct {
+	partitionRepository repository.PartitionRepository
+	logger              *zap.SugaredLogger
+}
+
+func (ps *PartitionServiceImpl) EnsurePartitions(tx *gorm.DB, now time.Time) error {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, table := range partitionedTables {
+		partitioned, err := ps.partitionRepository.IsPartitioned(tx, table)
+		if err != nil {
+			ps.logger.Errorf("Error checking partitioning of %s: %v", table, err.Error())
+			return err
+		}
+		if !partitioned {
+			ps.logger.Warnf("Table %s is not partitioned, skipping partition maintenance", table)
+			continue
+		}
+		for i := range PartitionMonthsAhead + 1 {
+			err = ps.partitionRepository.CreateMonthlyPartition(tx, table, month.AddDate(0, i, 0))
+			if err != nil {
+				ps.logger.Errorf("Error creating partition of %s: %v", table, err.Error())
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func NewPartitionService(partitionRepository repository.PartitionRepository) PartitionService {
+	log := logger.NewNamedLogger("partition_service")
+	return &PartitionServiceImpl{
+		partitionRepository: partitionRepository,
+		logger:              log,
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsurePartitions(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	pr := repository.NewPartitionRepository()
+	ps := NewPartitionService(pr)
+	now := time.Date(2030, time.November, 15, 12, 0, 0, 0, time.UTC)
+
+	err := ps.EnsurePartitions(tx, now)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// Running maintenance again is a no-op
+	assert.NoError(t, ps.EnsurePartitions(tx, now))
+
+	for _, table := range partitionedTables {
+		partitioned, err := pr.IsPartitioned(tx, table)
+		assert.NoError(t, err)
+		if !partitioned {
+			continue
+		}
+		for _, month := range []string{"2030_11", "2030_12", "2031_01"} {
+			assert.True(t, tx.Migrator().HasTable(fmt.Sprintf("%s_%s", table, month)))
+		}
+		assert.False(t, tx.Migrator().HasTable(fmt.Sprintf("%s_2031_02", table)))
+	}
+	tx.Rollback()
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"hash/fnv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPlagiarismThreshold is the similarity above which pairs of submissions are reported when
+	// the request does not set a threshold
+	DefaultPlagiarismThreshold = 0.8
+	// MaxPlagiarismSubmissions is the largest number of submissions checked at once, as every pair of them
+	// is compared within the request
+	MaxPlagiarismSubmissions = 1000
+)
+
+// Fingerprints are hashes of plagiarismKGram consecutive tokens. The lowest hash of every window of
+// plagiarismWindow consecutive hashes is kept, so any copied run of plagiarismKGram+plagiarismWindow-1
+// tokens is found
+const (
+	plagiarismKGram  = 5
+	plagiarismWindow = 4
+)
+
+var ErrPlagiarismNotChecked = errors.New("submissions of the task were not checked for plagiarism")
+var ErrPlagiarismTooLarge = errors.New("too many submissions to check for plagiarism at once")
+
+type PlagiarismService interface {
+	// CheckTask compares the latest accepted submission of each user for the task with the submissions of other
+	// users in the same language and stores their similarity, replacing the previous check. Returns the report
+	// with the default threshold. Only admins and the task author can check
+	CheckTask(tx *gorm.DB, currentUser schemas.User, taskId int64) (*schemas.PlagiarismReport, error)
+	// GetReport returns pairs found by the last check of the task with similarity of at least the threshold
+	GetReport(tx *gorm.DB, currentUser schemas.User, taskId int64, threshold float64) (*schemas.PlagiarismReport, error)
+}
+
+type PlagiarismServiceImpl struct {
+	submissionRepository repository.SubmissionRepository
+	taskRepository       repository.TaskRepository
+	plagiarismRepository repository.PlagiarismRepository
+	fileStorageService   FileStorageService
+	archiveService       ArchiveService
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ps *PlagiarismServiceImpl) CheckTask(tx *gorm.DB, currentUser schemas.User, taskId int64) (*schemas.PlagiarismReport, error) {
+	err := ps.authorize(tx, currentUser, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions, err := ps.submissionRepository.GetLatestAcceptedSubmissions(tx, taskId)
+	if err != nil {
+		ps.logger.Errorf("Error getting accepted submissions: %v", err.Error())
+		return nil, err
+	}
+	if len(submissions) > MaxPlagiarismSubmissions {
+		return nil, ErrPlagiarismTooLarge
+	}
+
+	fingerprints := make([]map[uint64]struct{}, len(submissions))
+	for i := range submissions {
+		submission := &submissions[i]
+		err = ps.archiveService.RestoreSubmission(tx, submission)
+		if err != nil {
+			return nil, err
+		}
+		source, _, err := ps.fileStorageService.GetUserSolution(submission.TaskId, submission.UserId, submission.Order)
+		if err != nil {
+			ps.logger.Errorf("Error getting source of submission %d: %v", submission.Id, err.Error())
+			return nil, err
+		}
+		fingerprints[i] = fingerprint(tokenize(string(source), submission.Language.Type))
+	}
+
+	var matches []models.PlagiarismMatch
+	for i := range submissions {
+		for j := i + 1; j < len(submissions); j++ {
+			// Sources in different languages are tokenized differently and cannot be compared
+			if submissions[i].Language.Type != submissions[j].Language.Type {
+				continue
+			}
+			similarity := fingerprintSimilarity(fingerprints[i], fingerprints[j])
+			if similarity == 0 {
+				continue
+			}
+			first, second := submissions[i], submissions[j]
+			if first.Id > second.Id {
+				first, second = second, first
+			}
+			matches = append(matches, models.PlagiarismMatch{
+				TaskId:             taskId,
+				FirstSubmissionId:  first.Id,
+				SecondSubmissionId: second.Id,
+				FirstUserId:        first.UserId,
+				SecondUserId:       second.UserId,
+				Similarity:         similarity,
+			})
+		}
+	}
+
+	check := &models.PlagiarismCheck{
+		TaskId:      taskId,
+		Submissions: int64(len(submissions)),
+		CheckedAt:   time.Now(),
+		CheckedBy:   currentUser.Id,
+	}
+	err = ps.plagiarismRepository.ReplaceCheck(tx, check, matches)
+	if err != nil {
+		ps.logger.Errorf("Error storing plagiarism check: %v", err.Error())
+		return nil, err
+	}
+	ps.logger.Infof("Plagiarism check of %d submissions of task %d by user %d found %d similar pairs", len(submissions), taskId, currentUser.Id, len(matches))
+	return ps.report(tx, check, DefaultPlagiarismThreshold)
+}
+
+func (ps *PlagiarismServiceImpl) GetReport(tx *gorm.DB, currentUser schemas.User, taskId int64, threshold float64) (*schemas.PlagiarismReport, error) {
+	err := ps.authorize(tx, currentUser, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := ps.plagiarismRepository.GetCheck(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPlagiarismNotChecked
+		}
+		ps.logger.Errorf("Error getting plagiarism check: %v", err.Error())
+		return nil, err
+	}
+	return ps.report(tx, check, threshold)
+}
+
+func (ps *PlagiarismServiceImpl) report(tx *gorm.DB, check *models.PlagiarismCheck, threshold float64) (*schemas.PlagiarismReport, error) {
+	matches, err := ps.plagiarismRepository.GetMatches(tx, check.TaskId, threshold)
+	if err != nil {
+		ps.logger.Errorf("Error getting plagiarism matches: %v", err.Error())
+		return nil, err
+	}
+
+	report := &schemas.PlagiarismReport{
+		TaskId:      check.TaskId,
+		Threshold:   threshold,
+		Submissions: check.Submissions,
+		CheckedAt:   check.CheckedAt,
+		CheckedBy:   check.CheckedBy,
+		Matches:     make([]schemas.PlagiarismMatch, 0, len(matches)),
+	}
+	for _, match := range matches {
+		report.Matches = append(report.Matches, schemas.PlagiarismMatch{
+			FirstSubmissionId:  match.FirstSubmissionId,
+			FirstUserId:        match.FirstUserId,
+			SecondSubmissionId: match.SecondSubmissionId,
+			SecondUserId:       match.SecondUserId,
+			Similarity:         match.Similarity,
+		})
+	}
+	return report, nil
+}
+
+// authorize checks the user may check plagiarism of every task, or of their own tasks and created the task
+func (ps *PlagiarismServiceImpl) authorize(tx *gorm.DB, currentUser schemas.User, taskId int64) error {
+	task, err := ps.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotFound
+		}
+		ps.logger.Errorf("Error getting task: %v", err.Error())
+		return err
+	}
+	if !ps.accessControlService.CanAccess(currentUser, ResourceTask, ActionCheckPlagiarism, task.CreatedBy) {
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+// plagiarismKeywords are kept as they are by tokenize, other identifiers are replaced by a placeholder
+var plagiarismKeywords = map[string]bool{
+	"if": true, "else": true, "elif": true, "for": true, "while": true, "do": true, "switch": true, "case": true,
+	"default": true, "break": true, "continue": true, "return": true, "goto": true, "try": true, "catch": true,
+	"except": true, "finally": true, "throw": true, "throws": true, "raise": true, "new": true, "delete": true,
+	"class": true, "struct": true, "enum": true, "union": true, "def": true, "lambda": true, "yield": true,
+	"import": true, "from": true, "include": true, "using": true, "namespace": true, "package": true,
+	"public": true, "private": true, "protected": true, "static": true, "const": true, "final": true,
+	"int": true, "long": true, "short": true, "char": true, "float": true, "double": true, "void": true,
+	"bool": true, "boolean": true, "unsigned": true, "signed": true, "auto": true, "in": true, "is": true,
+	"and": true, "or": true, "not": true, "with": true, "as": true, "pass": true, "global": true,
+}
+
+// tokenize splits the source into tokens. Identifiers, numbers and string literals are replaced by a
+// placeholder of their kind, so renaming variables or changing constants does not hide a copy. Comments
+// and whitespace are dropped
+func tokenize(source string, languageType models.LanguageType) []string {
+	lineComment := "//"
+	blockComments := true
+	if languageType == models.LanguageTypePython {
+		lineComment = "#"
+		blockComments = false
+	}
+
+	var tokens []string
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		rest := string(runes[i:min(i+2, len(runes))])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.HasPrefix(rest, lineComment):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case blockComments && rest == "/*":
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '"' || r == '\'':
+			i++
+			for i < len(runes) && runes[i] != r && runes[i] != '\n' {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			tokens = append(tokens, "str")
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, "num")
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := string(runes[start:i])
+			if plagiarismKeywords[word] {
+				tokens = append(tokens, word)
+			} else {
+				tokens = append(tokens, "id")
+			}
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// fingerprint winnows hashes of k-grams of the tokens. Sources shorter than a k-gram are hashed as a whole
+func fingerprint(tokens []string) map[uint64]struct{} {
+	fingerprints := make(map[uint64]struct{})
+	if len(tokens) == 0 {
+		return fingerprints
+	}
+
+	kGram := min(plagiarismKGram, len(tokens))
+	hashes := make([]uint64, len(tokens)-kGram+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, token := range tokens[i : i+kGram] {
+			h.Write([]byte(token))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+
+	window := min(plagiarismWindow, len(hashes))
+	selected := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		// The rightmost lowest hash is selected, so a window sharing it with the previous one adds nothing
+		lowest := start
+		for i := start + 1; i < start+window; i++ {
+			if hashes[i] <= hashes[lowest] {
+				lowest = i
+			}
+		}
+		if lowest != selected {
+			fingerprints[hashes[lowest]] = struct{}{}
+			selected = lowest
+		}
+	}
+	return fingerprints
+}
+
+// fingerprintSimilarity returns the Jaccard index of the fingerprints
+func fingerprintSimilarity(a, b map[uint64]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for hash := range a {
+		if _, ok := b[hash]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+func NewPlagiarismService(submissionRepository repository.SubmissionRepository, taskRepository repository.TaskRepository, plagiarismRepository repository.PlagiarismRepository, fileStorageService FileStorageService, archiveService ArchiveService, accessControlService AccessControlService) PlagiarismService {
+	log := logger.NewNamedLogger("plagiarism_service")
+	return &PlagiarismServiceImpl{
+		submissionRepository: submissionRepository,
+		taskRepository:       taskRepository,
+		plagiarismRepository: plagiarismRepository,
+		fileStorageService:   fileStorageService,
+		archiveService:       archiveService,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
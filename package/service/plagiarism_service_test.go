@@ -0,0 +1,167 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const plagiarismOriginal = `#include <stdio.h>
+
+int main() {
+	int n, sum = 0;
+	scanf("%d", &n);
+	for (int i = 1; i <= n; i++) {
+		sum += i * i;
+	}
+	printf("%d\n", sum);
+	return 0;
+}
+`
+
+// plagiarismRenamed is plagiarismOriginal with renamed variables, other constants and comments
+const plagiarismRenamed = `#include <stdio.h>
+
+/* Sum of squares */
+int main() {
+	int count, total = 0; // accumulator
+	scanf("%d", &count);
+	for (int k = 0; k <= count; k++) {
+		total += k * k;
+	}
+	printf("%d\n", total);
+	return 0;
+}
+`
+
+const plagiarismUnrelated = `#include <stdio.h>
+
+int main() {
+	char line[100];
+	while (fgets(line, sizeof line, stdin) != NULL) {
+		if (line[0] == '#') continue;
+		puts(line);
+	}
+	return 0;
+}
+`
+
+func TestFingerprintSimilarity(t *testing.T) {
+	similarity := func(a, b string) float64 {
+		return fingerprintSimilarity(fingerprint(tokenize(a, models.LanguageTypeC)), fingerprint(tokenize(b, models.LanguageTypeC)))
+	}
+	assert.Equal(t, 1.0, similarity(plagiarismOriginal, plagiarismOriginal))
+	assert.Equal(t, 1.0, similarity(plagiarismOriginal, plagiarismRenamed))
+	assert.Less(t, similarity(plagiarismOriginal, plagiarismUnrelated), 0.5)
+	assert.Equal(t, 0.0, similarity("", plagiarismOriginal))
+
+	t.Run("Python comments", func(t *testing.T) {
+		tokens := tokenize("x = 1 # comment\nprint(x)", models.LanguageTypePython)
+		assert.Equal(t, []string{"id", "=", "num", "id", "(", "id", ")"}, tokens)
+	})
+}
+
+// plagiarismSourcesStub serves sources of submissions by their user
+type plagiarismSourcesStub struct {
+	fileStorageServiceStub
+	sources map[int64]string
+}
+
+func (fs *plagiarismSourcesStub) GetUserSolution(taskId int64, userId int64, submissionNumber int64) ([]byte, string, error) {
+	return []byte(fs.sources[userId]), "main.c", nil
+}
+
+func TestCheckPlagiarism(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pr, err := repository.NewPlagiarismRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	fileStorage := &plagiarismSourcesStub{sources: make(map[int64]string)}
+	ps := NewPlagiarismService(sr, tr, pr, fileStorage, NewArchiveService(sr, fileStorage), newAccessControlServiceTest(t, tx))
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	// createTask creates a task with an accepted C submission of each source and returns the task and its author
+	createTask := func(t *testing.T, sources ...string) (int64, schemas.User) {
+		users := []*models.User{{Name: "Author", Surname: "User", Email: "author@email.com", Username: "author", PasswordHash: "password", Role: models.UserRoleTeacher}}
+		for i := range sources {
+			users = append(users, &models.User{Name: "Student", Surname: "User", Email: string(rune('a'+i)) + "@email.com", Username: string(rune('a' + i)), PasswordHash: "password"})
+		}
+		if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+			t.FailNow()
+		}
+		taskId, err := tr.Create(tx, models.Task{Title: "Test Task", CreatedBy: users[0].Id})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: models.LanguageTypeC, Version: "99"}
+		if !assert.NoError(t, tx.Create(language).Error) {
+			t.FailNow()
+		}
+		for i, source := range sources {
+			userId := users[i+1].Id
+			fileStorage.sources[userId] = source
+			submissionId, err := sr.CreateSubmission(tx, models.Submission{TaskId: taskId, UserId: userId, Order: 1, LanguageId: language.Id, Status: "completed"})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			result := &models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Score: 100}
+			if !assert.NoError(t, tx.Create(result).Error) {
+				t.FailNow()
+			}
+		}
+		return taskId, schemas.User{Id: users[0].Id, Role: string(models.UserRoleTeacher)}
+	}
+
+	t.Run("Check task", func(t *testing.T) {
+		taskId, author := createTask(t, plagiarismOriginal, plagiarismRenamed, plagiarismUnrelated)
+		report, err := ps.CheckTask(tx, author, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(3), report.Submissions)
+		assert.Equal(t, DefaultPlagiarismThreshold, report.Threshold)
+		if assert.Len(t, report.Matches, 1) {
+			assert.Equal(t, 1.0, report.Matches[0].Similarity)
+			assert.Less(t, report.Matches[0].FirstSubmissionId, report.Matches[0].SecondSubmissionId)
+		}
+
+		// Pairs below the default threshold are stored too
+		report, err = ps.GetReport(tx, author, taskId, 0)
+		assert.NoError(t, err)
+		assert.Greater(t, len(report.Matches), 1)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Not checked", func(t *testing.T) {
+		taskId, author := createTask(t, plagiarismOriginal)
+		_, err := ps.GetReport(tx, author, taskId, DefaultPlagiarismThreshold)
+		assert.ErrorIs(t, err, ErrPlagiarismNotChecked)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Other teacher", func(t *testing.T) {
+		taskId, author := createTask(t, plagiarismOriginal)
+		_, err := ps.CheckTask(tx, schemas.User{Id: author.Id + 100, Role: string(models.UserRoleTeacher)}, taskId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
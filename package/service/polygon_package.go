@@ -0,0 +1,170 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/mini-maxit/backend/package/domain/schemas"
+)
+
+const (
+	PolygonProblemName = "problem.xml"
+	// Directory and names of the task files in the format tasks are uploaded in. Tests are numbered from 1
+	taskFilesDir        = "task"
+	taskDescriptionName = "description.pdf"
+	taskInputPattern    = "input/%d.in"
+	taskOutputPattern   = "output/%d.out"
+)
+
+// polygonProblem is the part of problem.xml of a Polygon package needed to import the problem
+type polygonProblem struct {
+	Names []struct {
+		Language string `xml:"language,attr"`
+		Value    string `xml:"value,attr"`
+	} `xml:"names>name"`
+	Statements []struct {
+		Language string `xml:"language,attr"`
+		Path     string `xml:"path,attr"`
+		Type     string `xml:"type,attr"`
+	} `xml:"statements>statement"`
+	Testsets []struct {
+		Name string `xml:"name,attr"`
+		// Milliseconds
+		TimeLimit int64 `xml:"time-limit"`
+		// Bytes
+		MemoryLimit       int64  `xml:"memory-limit"`
+		TestCount         int    `xml:"test-count"`
+		InputPathPattern  string `xml:"input-path-pattern"`
+		AnswerPathPattern string `xml:"answer-path-pattern"`
+		Tests             []struct {
+			Group  string  `xml:"group,attr"`
+			Points float64 `xml:"points,attr"`
+		} `xml:"tests>test"`
+		Groups []struct {
+			Name   string  `xml:"name,attr"`
+			Points float64 `xml:"points,attr"`
+		} `xml:"groups>group"`
+	} `xml:"judging>testset"`
+}
+
+// convertPolygonPackage converts a Polygon package with its tests, as downloaded with the full package, to a task
+// manifest and task files. The tests testset is imported with its PDF statement, preferring the English one.
+// Polygon groups are scored as a whole, groups without points are left out
+func convertPolygonPackage(reader *zip.Reader, maxSize int64) (*schemas.TaskManifest, []byte, error) {
+	problemXml, err := readZipEntry(reader, PolygonProblemName, maxTaskManifestSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	problem := polygonProblem{}
+	if err := xml.Unmarshal(problemXml, &problem); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid %s: %s", ErrInvalidTaskArchive, PolygonProblemName, err.Error())
+	}
+
+	title := ""
+	for _, name := range problem.Names {
+		if title == "" || name.Language == "english" {
+			title = name.Value
+		}
+	}
+	statementPath := ""
+	for _, statement := range problem.Statements {
+		if statement.Type == "application/pdf" && (statementPath == "" || statement.Language == "english") {
+			statementPath = statement.Path
+		}
+	}
+	if statementPath == "" {
+		return nil, nil, fmt.Errorf("%w: the package has no PDF statement, build the statements in Polygon first", ErrInvalidTaskArchive)
+	}
+	testsetIndex := -1
+	for i, testset := range problem.Testsets {
+		if testset.Name == "tests" {
+			testsetIndex = i
+		}
+	}
+	if testsetIndex == -1 {
+		return nil, nil, fmt.Errorf("%w: the package has no tests testset", ErrInvalidTaskArchive)
+	}
+	testset := problem.Testsets[testsetIndex]
+	if testset.TestCount == 0 || testset.InputPathPattern == "" || testset.AnswerPathPattern == "" {
+		return nil, nil, fmt.Errorf("%w: the tests testset has no tests", ErrInvalidTaskArchive)
+	}
+
+	files := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(files)
+	tarWriter := tar.NewWriter(gzipWriter)
+	remaining := maxSize
+	addFile := func(zipPath string, name string) error {
+		content, err := readZipEntry(reader, zipPath, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= int64(len(content))
+		header := &tar.Header{Name: taskFilesDir + "/" + name, Mode: 0o644, Size: int64(len(content))}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	}
+	if err := addFile(statementPath, taskDescriptionName); err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &schemas.TaskManifest{
+		FormatVersion: TaskManifestVersion,
+		Title:         strings.TrimSpace(title),
+		Tests:         make([]schemas.TaskManifestTest, 0, testset.TestCount),
+	}
+	groupTests := map[string][]int64{}
+	groupPoints := map[string]float64{}
+	groupOrder := []string{}
+	for i := 1; i <= testset.TestCount; i++ {
+		if err := addFile(fmt.Sprintf(testset.InputPathPattern, i), fmt.Sprintf(taskInputPattern, i)); err != nil {
+			return nil, nil, err
+		}
+		if err := addFile(fmt.Sprintf(testset.AnswerPathPattern, i), fmt.Sprintf(taskOutputPattern, i)); err != nil {
+			return nil, nil, err
+		}
+		manifest.Tests = append(manifest.Tests, schemas.TaskManifestTest{
+			Order:       i,
+			TimeLimit:   float64(testset.TimeLimit) / 1000,
+			MemoryLimit: float64(testset.MemoryLimit) / (1 << 20),
+		})
+
+		if i > len(testset.Tests) || testset.Tests[i-1].Group == "" {
+			continue
+		}
+		test := testset.Tests[i-1]
+		if _, ok := groupTests[test.Group]; !ok {
+			groupOrder = append(groupOrder, test.Group)
+		}
+		groupTests[test.Group] = append(groupTests[test.Group], int64(i))
+		groupPoints[test.Group] += test.Points
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	// Points of a group are set on the group, or on its tests when the group is scored by each test
+	for _, group := range testset.Groups {
+		if group.Points > 0 {
+			groupPoints[group.Name] = group.Points
+		}
+	}
+	for _, name := range groupOrder {
+		points := int64(groupPoints[name])
+		if points <= 0 {
+			continue
+		}
+		manifest.TestGroups = append(manifest.TestGroups, schemas.TestCaseGroupEdit{Name: name, Points: points, Tests: groupTests[name]})
+	}
+	return manifest, files.Bytes(), nil
+}
@@ -3,9 +3,16 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mini-maxit/backend/internal/broker"
+	"github.com/mini-maxit/backend/internal/config"
 	"github.com/mini-maxit/backend/internal/logger"
 	"github.com/mini-maxit/backend/package/domain/models"
 	"github.com/mini-maxit/backend/package/domain/schemas"
@@ -19,93 +26,448 @@ type QueueService interface {
 	// PublishTask publishes a task to the queue
 	PublishSubmission(tx *gorm.DB, submissionId int64) error
 	GetSubmissionId(tx *gorm.DB, messageId string) (int64, error)
+	// PublishAudit publishes a judged submission again for the judge audit. The submission is left as it is,
+	// the result of the message belongs to the audit
+	PublishAudit(tx *gorm.DB, submissionId int64, auditId int64) error
+	// GetAuditId returns the audit the message was published for, or nil for messages of submissions
+	GetAuditId(tx *gorm.DB, messageId string) (*int64, error)
+	// PublishRejudge publishes a judged submission again as part of the rejudge batch. Its new result
+	// supersedes the previous one
+	PublishRejudge(tx *gorm.DB, submissionId int64, batchId int64) error
+	// GetRejudgeBatchId returns the rejudge batch the message was published for, or nil for other messages
+	GetRejudgeBatchId(tx *gorm.DB, messageId string) (*int64, error)
+	// RetrySubmission publishes the submission of a message the worker failed to evaluate again, delayed by a
+	// backoff doubling with every attempt. Once the attempts are used up the submission is stored as a queue
+	// failure and false is returned, the submission has to be marked failed then
+	RetrySubmission(tx *gorm.DB, messageId string, reason string) (bool, error)
+	// GetQueueFailures returns submissions which failed on every attempt, newest first. Only admins can see them
+	GetQueueFailures(tx *gorm.DB, currentUser schemas.User, pendingOnly bool, limit, offset int64) ([]schemas.QueueFailure, error)
+	// RequeueFailure publishes the submission of the failure again with a fresh set of attempts. A failure can be
+	// requeued once. Only admins can requeue
+	RequeueFailure(tx *gorm.DB, currentUser schemas.User, failureId int64) (*schemas.QueueFailure, error)
 }
 
+var ErrQueueFailureNotFound = errors.New("queue failure not found")
+var ErrQueueFailureRequeued = errors.New("queue failure was already requeued")
+
 type QueueServiceImpl struct {
 	taskRepository       repository.TaskRepository
 	submissionRepository repository.SubmissionRepository
 	queueRepository      repository.QueueMessageRepository
-	channel              *amqp.Channel
-	queue                amqp.Queue
+	failureRepository    repository.QueueFailureRepository
+	timelineRepository   repository.TimelineEventRepository
+	archiveService       ArchiveService
+	evaluationDefaults   config.EvaluationLimits
+	connection           *broker.Connection
+	queueName            string
 	responseQueueName    string
+	highMemoryQueueName  string
+	highMemoryThreshold  float64
+	maxAttempts          int64
+	retryBackoff         time.Duration
+	// Queues declared on the current channel, language queues are declared when first used
+	declaredQueues       map[string]bool
+	queuesMu             sync.Mutex
+	accessControlService AccessControlService
 	logger               *zap.SugaredLogger
 }
 
-func (qs *QueueServiceImpl) publishMessage(msq schemas.QueueMessage) error {
+// routeSubmission returns the queue the submission is published to. Tasks with high memory limits go to the
+// high memory queue, as only its workers can run them, otherwise the queue of the language is used
+func (qs *QueueServiceImpl) routeSubmission(language models.LanguageConfig, memoryLimits []float64) string {
+	if qs.highMemoryQueueName != "" && len(memoryLimits) > 0 && slices.Max(memoryLimits) >= qs.highMemoryThreshold {
+		return qs.highMemoryQueueName
+	}
+	if language.QueueName != "" {
+		return language.QueueName
+	}
+	return qs.queueName
+}
+
+// retryQueueName returns the queue delayed retries are published to. Messages expire from it into the queue
+func retryQueueName(queueName string) string {
+	return queueName + "_retry"
+}
+
+// declareQueue declares the queue unless it was already declared on the channel
+func (qs *QueueServiceImpl) declareQueue(channel *amqp.Channel, queueName string, args amqp.Table) error {
+	qs.queuesMu.Lock()
+	defer qs.queuesMu.Unlock()
+	if qs.declaredQueues[queueName] {
+		return nil
+	}
+	_, err := channel.QueueDeclare(
+		queueName, // name of the queue
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		args,      // arguments
+	)
+	if err != nil {
+		return err
+	}
+	qs.declaredQueues[queueName] = true
+	return nil
+}
+
+// publishMessage publishes the message to the queue. With a delay the message waits in the retry queue of the
+// queue until it expires into it
+func (qs *QueueServiceImpl) publishMessage(msq schemas.QueueMessage, queueName string, delay time.Duration) error {
 	msgBytes, err := json.Marshal(msq)
 	if err != nil {
 		qs.logger.Errorf("Error marshalling message: %v", err.Error())
 		return err
 	}
 
+	// Messages cannot be published while the broker is reconnecting
+	channel, err := qs.connection.Channel()
+	if err != nil {
+		qs.logger.Errorf("Error publishing message: %v", err.Error())
+		return err
+	}
+	err = qs.declareQueue(channel, queueName, nil)
+	if err != nil {
+		qs.logger.Errorf("Error declaring queue %s: %v", queueName, err.Error())
+		return err
+	}
+	routingKey := queueName
+	expiration := ""
+	if delay > 0 {
+		routingKey = retryQueueName(queueName)
+		expiration = strconv.FormatInt(delay.Milliseconds(), 10)
+		err = qs.declareQueue(channel, routingKey, amqp.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		})
+		if err != nil {
+			qs.logger.Errorf("Error declaring queue %s: %v", routingKey, err.Error())
+			return err
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err = qs.channel.PublishWithContext(ctx, "", qs.queue.Name, false, false, amqp.Publishing{
+	err = channel.PublishWithContext(ctx, "", routingKey, false, false, amqp.Publishing{
 		ContentType: "application/json",
 		Body:        msgBytes,
 		ReplyTo:     qs.responseQueueName,
+		Expiration:  expiration,
 	})
 	if err != nil {
 		qs.logger.Errorf("Error publishing message: %v", err.Error())
 		return err
 	}
 
-	qs.logger.Info("Message published")
+	qs.logger.Infof("Message published to %s", routingKey)
 	return nil
 }
 
 func (qs *QueueServiceImpl) PublishSubmission(tx *gorm.DB, submissionId int64) error {
+	return qs.publishSubmission(tx, submissionId, nil, 1, 0)
+}
+
+func (qs *QueueServiceImpl) PublishRejudge(tx *gorm.DB, submissionId int64, batchId int64) error {
+	return qs.publishSubmission(tx, submissionId, &batchId, 1, 0)
+}
+
+func (qs *QueueServiceImpl) RetrySubmission(tx *gorm.DB, messageId string, reason string) (bool, error) {
+	queueMessage, err := qs.queueRepository.GetQueueMessage(tx, messageId)
+	if err != nil {
+		qs.logger.Errorf("Error getting queue message: %v", err.Error())
+		return false, err
+	}
+	attempt := max(queueMessage.Attempt, 1)
+	err = qs.timelineRepository.CreateEvents(tx, []models.TimelineEvent{{
+		SubmissionId: queueMessage.SubmissionId,
+		MessageId:    messageId,
+		Type:         models.TimelineEventWorkerError,
+		Detail:       reason,
+	}})
+	if err != nil {
+		qs.logger.Errorf("Error creating timeline event: %v", err.Error())
+		return false, err
+	}
+	if attempt < qs.maxAttempts {
+		delay := qs.retryBackoff << (attempt - 1)
+		err = qs.publishSubmission(tx, queueMessage.SubmissionId, queueMessage.RejudgeBatchId, attempt+1, delay)
+		if err != nil {
+			return false, err
+		}
+		qs.logger.Infof("Submission %d retried in %s after attempt %d failed: %s", queueMessage.SubmissionId, delay, attempt, reason)
+		return true, nil
+	}
+
+	submission, err := qs.submissionRepository.GetSubmission(tx, queueMessage.SubmissionId)
+	if err != nil {
+		qs.logger.Errorf("Error getting submission: %v", err.Error())
+		return false, err
+	}
+	memoryLimits, err := qs.taskRepository.GetTaskMemoryLimits(tx, submission.TaskId)
+	if err != nil {
+		qs.logger.Errorf("Error getting task memory limits: %v", err.Error())
+		return false, err
+	}
+	for i := range memoryLimits {
+		memoryLimits[i] *= submission.Language.MemoryMultiplier
+	}
+	_, err = qs.failureRepository.CreateFailure(tx, &models.QueueFailure{
+		MessageId:      messageId,
+		SubmissionId:   queueMessage.SubmissionId,
+		RejudgeBatchId: queueMessage.RejudgeBatchId,
+		QueueName:      qs.routeSubmission(submission.Language, memoryLimits),
+		Attempts:       attempt,
+		Error:          reason,
+	})
+	if err != nil {
+		qs.logger.Errorf("Error creating queue failure: %v", err.Error())
+		return false, err
+	}
+	qs.logger.Warnf("Submission %d failed on all %d attempts: %s", queueMessage.SubmissionId, attempt, reason)
+	return false, nil
+}
+
+func (qs *QueueServiceImpl) GetQueueFailures(tx *gorm.DB, currentUser schemas.User, pendingOnly bool, limit, offset int64) ([]schemas.QueueFailure, error) {
+	if !qs.accessControlService.Can(currentUser, ResourceQueue, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	failures, err := qs.failureRepository.GetFailures(tx, pendingOnly, limit, offset)
+	if err != nil {
+		qs.logger.Errorf("Error getting queue failures: %v", err.Error())
+		return nil, err
+	}
+	result := make([]schemas.QueueFailure, 0, len(failures))
+	for i := range failures {
+		result = append(result, *queueFailureToSchema(&failures[i]))
+	}
+	return result, nil
+}
+
+func (qs *QueueServiceImpl) RequeueFailure(tx *gorm.DB, currentUser schemas.User, failureId int64) (*schemas.QueueFailure, error) {
+	if !qs.accessControlService.Can(currentUser, ResourceQueue, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	failure, err := qs.failureRepository.GetFailure(tx, failureId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrQueueFailureNotFound
+		}
+		qs.logger.Errorf("Error getting queue failure: %v", err.Error())
+		return nil, err
+	}
+	requeued, err := qs.failureRepository.MarkRequeued(tx, failureId, currentUser.Id)
+	if err != nil {
+		qs.logger.Errorf("Error marking queue failure requeued: %v", err.Error())
+		return nil, err
+	}
+	if !requeued {
+		return nil, ErrQueueFailureRequeued
+	}
+	// The failed submission was already counted in its rejudge batch, so it is published on its own
+	err = qs.publishSubmission(tx, failure.SubmissionId, nil, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	qs.logger.Infof("Queue failure %d of submission %d requeued by user %d", failureId, failure.SubmissionId, currentUser.Id)
+
+	failure, err = qs.failureRepository.GetFailure(tx, failureId)
+	if err != nil {
+		qs.logger.Errorf("Error getting queue failure: %v", err.Error())
+		return nil, err
+	}
+	return queueFailureToSchema(failure), nil
+}
+
+func queueFailureToSchema(failure *models.QueueFailure) *schemas.QueueFailure {
+	return &schemas.QueueFailure{
+		Id:           failure.Id,
+		MessageId:    failure.MessageId,
+		SubmissionId: failure.SubmissionId,
+		QueueName:    failure.QueueName,
+		Attempts:     failure.Attempts,
+		Error:        failure.Error,
+		FailedAt:     failure.FailedAt,
+		RequeuedAt:   failure.RequeuedAt,
+		RequeuedBy:   failure.RequeuedBy,
+	}
+}
+
+// publishSubmission publishes the submission for evaluation after the delay and marks it processing
+func (qs *QueueServiceImpl) publishSubmission(tx *gorm.DB, submissionId int64, rejudgeBatchId *int64, attempt int64, delay time.Duration) error {
+	msq, queueName, err := qs.submissionMessage(tx, submissionId)
+	if err != nil {
+		return err
+	}
+	qs.queueRepository.CreateQueueMessage(tx, models.QueueMessage{
+		Id:             msq.MessageId,
+		SubmissionId:   submissionId,
+		RejudgeBatchId: rejudgeBatchId,
+		Attempt:        attempt,
+	})
+	err = qs.publishMessage(msq, queueName, delay)
+	if err != nil {
+		err2 := qs.submissionRepository.MarkSubmissionFailed(tx, submissionId, err.Error())
+		if err2 != nil {
+			qs.logger.Errorf("Error marking submission failed: %v. When error occured publishing message: %s", err2.Error(), err.Error())
+			return err
+		}
+		err2 = qs.timelineRepository.CreateEvents(tx, []models.TimelineEvent{{
+			SubmissionId: submissionId,
+			MessageId:    msq.MessageId,
+			Type:         models.TimelineEventFailed,
+			Detail:       "Publishing failed: " + err.Error(),
+		}})
+		if err2 != nil {
+			qs.logger.Errorf("Error creating timeline event: %v. When error occured publishing message: %s", err2.Error(), err.Error())
+			return err
+		}
+		qs.logger.Errorf("Error publishing message: %v", err.Error())
+		return err
+	}
+	err = qs.submissionRepository.MarkSubmissionProcessing(tx, submissionId)
+	if err != nil {
+		qs.logger.Errorf("Error marking submission processing: %v", err.Error())
+		return err
+	}
+	err = qs.recordEvaluationHash(tx, submissionId)
+	if err != nil {
+		return err
+	}
+	detail := fmt.Sprintf("queue %s, attempt %d", queueName, attempt)
+	if delay > 0 {
+		detail += fmt.Sprintf(", delayed by %s", delay)
+	}
+	err = qs.timelineRepository.CreateEvents(tx, []models.TimelineEvent{{
+		SubmissionId: submissionId,
+		MessageId:    msq.MessageId,
+		Type:         models.TimelineEventEnqueued,
+		Detail:       detail,
+	}})
+	if err != nil {
+		qs.logger.Errorf("Error creating timeline event: %v", err.Error())
+		return err
+	}
+	qs.logger.Info("Submission published")
+	return nil
+}
+
+// recordEvaluationHash stores the hash of the settings the submission is published with, its result is only
+// reused by identical submissions while these settings are unchanged
+func (qs *QueueServiceImpl) recordEvaluationHash(tx *gorm.DB, submissionId int64) error {
 	submission, err := qs.submissionRepository.GetSubmission(tx, submissionId)
 	if err != nil {
 		qs.logger.Errorf("Error getting submission: %v", err.Error())
 		return err
 	}
+	hash, err := evaluationHash(tx, qs.taskRepository, submission)
+	if err != nil {
+		qs.logger.Errorf("Error computing evaluation hash: %v", err.Error())
+		return err
+	}
+	err = qs.submissionRepository.SetEvaluationHash(tx, submissionId, hash)
+	if err != nil {
+		qs.logger.Errorf("Error saving evaluation hash: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+// submissionMessage builds the message evaluating the submission and returns the queue it is published to
+func (qs *QueueServiceImpl) submissionMessage(tx *gorm.DB, submissionId int64) (schemas.QueueMessage, string, error) {
+	submission, err := qs.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		qs.logger.Errorf("Error getting submission: %v", err.Error())
+		return schemas.QueueMessage{}, "", err
+	}
+	// The worker reads the source from file storage, which cannot serve archived sources
+	err = qs.archiveService.RestoreSubmission(tx, submission)
+	if err != nil {
+		return schemas.QueueMessage{}, "", err
+	}
 
 	timeLimits, err := qs.taskRepository.GetTaskTimeLimits(tx, submission.TaskId)
 	if err != nil {
 		qs.logger.Errorf("Error getting task time limits: %v", err.Error())
-		return err
+		return schemas.QueueMessage{}, "", err
 	}
 	memoryLimits, err := qs.taskRepository.GetTaskMemoryLimits(tx, submission.TaskId)
 	if err != nil {
 		qs.logger.Errorf("Error getting task memory limits: %v", err.Error())
-		return err
+		return schemas.QueueMessage{}, "", err
+	}
+	task, err := qs.taskRepository.GetTask(tx, submission.TaskId)
+	if err != nil {
+		qs.logger.Errorf("Error getting task: %v", err.Error())
+		return schemas.QueueMessage{}, "", err
+	}
+	evaluationLimits := effectiveEvaluationLimits(task.EvaluationPolicy, qs.evaluationDefaults)
+	for i := range timeLimits {
+		timeLimits[i] *= submission.Language.TimeMultiplier
+	}
+	for i := range memoryLimits {
+		memoryLimits[i] *= submission.Language.MemoryMultiplier
 	}
 
-	msq := schemas.QueueMessage{
+	return schemas.QueueMessage{
 		MessageId:       uuid.New().String(),
 		TaskId:          submission.TaskId,
 		UserId:          submission.UserId,
 		SumissionNumber: submission.Order,
 		LanguageType:    string(submission.Language.Type),
 		LanguageVersion: submission.Language.Version,
+		ExecutionMode:   string(submission.Language.Type.ExecutionMode()),
+		CompilerFlags:   submission.Language.CompilerFlags,
+		RunArgs:         submission.Language.RunArgs,
 		TimeLimits:      timeLimits,
 		MemoryLimits:    memoryLimits,
+		OutputLimit:     evaluationLimits.OutputLimit,
+		StderrLimit:     evaluationLimits.StderrLimit,
+		ProcessLimit:    evaluationLimits.ProcessLimit,
+	}, qs.routeSubmission(submission.Language, memoryLimits), nil
+}
+
+func (qs *QueueServiceImpl) PublishAudit(tx *gorm.DB, submissionId int64, auditId int64) error {
+	msq, queueName, err := qs.submissionMessage(tx, submissionId)
+	if err != nil {
+		return err
 	}
-	qs.queueRepository.CreateQueueMessage(tx, models.QueueMessage{
+	_, err = qs.queueRepository.CreateQueueMessage(tx, models.QueueMessage{
 		Id:           msq.MessageId,
 		SubmissionId: submissionId,
+		AuditId:      &auditId,
 	})
-	err = qs.publishMessage(msq)
 	if err != nil {
-		err2 := qs.submissionRepository.MarkSubmissionFailed(tx, submissionId, err.Error())
-		if err2 != nil {
-			qs.logger.Errorf("Error marking submission failed: %v. When error occured publishing message: %s", err2.Error(), err.Error())
-			return err
-		}
-		qs.logger.Errorf("Error publishing message: %v", err.Error())
+		qs.logger.Errorf("Error creating queue message: %v", err.Error())
 		return err
 	}
-	err = qs.submissionRepository.MarkSubmissionProcessing(tx, submissionId)
+	err = qs.publishMessage(msq, queueName, 0)
 	if err != nil {
-		qs.logger.Errorf("Error marking submission processing: %v", err.Error())
+		qs.logger.Errorf("Error publishing audit message: %v", err.Error())
 		return err
 	}
-	qs.logger.Info("Submission published")
+	qs.logger.Infof("Submission %d published for audit %d", submissionId, auditId)
 	return nil
 }
 
+func (qs *QueueServiceImpl) GetAuditId(tx *gorm.DB, messageId string) (*int64, error) {
+	queueMessage, err := qs.queueRepository.GetQueueMessage(tx, messageId)
+	if err != nil {
+		return nil, err
+	}
+	return queueMessage.AuditId, nil
+}
+
+func (qs *QueueServiceImpl) GetRejudgeBatchId(tx *gorm.DB, messageId string) (*int64, error) {
+	queueMessage, err := qs.queueRepository.GetQueueMessage(tx, messageId)
+	if err != nil {
+		return nil, err
+	}
+	return queueMessage.RejudgeBatchId, nil
+}
+
 func (qs *QueueServiceImpl) GetSubmissionId(tx *gorm.DB, messageId string) (int64, error) {
 	queueMessage, err := qs.queueRepository.GetQueueMessage(tx, messageId)
 	if err != nil {
@@ -116,26 +478,41 @@ func (qs *QueueServiceImpl) GetSubmissionId(tx *gorm.DB, messageId string) (int6
 	return queueMessage.SubmissionId, nil
 }
 
-func NewQueueService(taskRepository repository.TaskRepository, submissionRepository repository.SubmissionRepository, queueMessageRepository repository.QueueMessageRepository, conn *amqp.Connection, channel *amqp.Channel, queueName string, responseQueueName string) (*QueueServiceImpl, error) {
-	q, err := channel.QueueDeclare(
-		queueName, // name of the queue
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
-	)
-	if err != nil {
-		return nil, err
-	}
+// NewQueueService creates the service publishing to the queues of the broker config. Submissions are routed to
+// the queue of their language when it has one, and to the high memory queue when their task needs it
+func NewQueueService(taskRepository repository.TaskRepository, submissionRepository repository.SubmissionRepository, queueMessageRepository repository.QueueMessageRepository, queueFailureRepository repository.QueueFailureRepository, timelineEventRepository repository.TimelineEventRepository, archiveService ArchiveService, accessControlService AccessControlService, evaluationDefaults config.EvaluationLimits, connection *broker.Connection, brokerConfig config.BrokerConfig) (*QueueServiceImpl, error) {
 	log := logger.NewNamedLogger("queue_service")
-	return &QueueServiceImpl{
+	qs := &QueueServiceImpl{
 		taskRepository:       taskRepository,
 		submissionRepository: submissionRepository,
 		queueRepository:      queueMessageRepository,
-		queue:                q,
-		channel:              channel,
-		responseQueueName:    responseQueueName,
+		failureRepository:    queueFailureRepository,
+		timelineRepository:   timelineEventRepository,
+		archiveService:       archiveService,
+		evaluationDefaults:   evaluationDefaults,
+		connection:           connection,
+		queueName:            brokerConfig.QueueName,
+		responseQueueName:    brokerConfig.ResponseQueueName,
+		highMemoryQueueName:  brokerConfig.HighMemoryQueueName,
+		highMemoryThreshold:  float64(brokerConfig.HighMemoryThreshold),
+		maxAttempts:          brokerConfig.MaxAttempts,
+		retryBackoff:         brokerConfig.RetryBackoff,
+		accessControlService: accessControlService,
 		logger:               log,
-	}, nil
+	}
+	// Queues are declared again after every reconnect, the broker may have lost them on restart
+	err := connection.OnChannel(func(channel *amqp.Channel) error {
+		qs.queuesMu.Lock()
+		qs.declaredQueues = make(map[string]bool)
+		qs.queuesMu.Unlock()
+		err := qs.declareQueue(channel, qs.queueName, nil)
+		if err != nil || qs.highMemoryQueueName == "" {
+			return err
+		}
+		return qs.declareQueue(channel, qs.highMemoryQueueName, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return qs, nil
 }
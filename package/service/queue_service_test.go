@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
 	"github.com/mini-maxit/backend/package/domain/schemas"
 	"github.com/mini-maxit/backend/package/repository"
 	"github.com/stretchr/testify/assert"
@@ -25,16 +26,21 @@ func TestPublishMessage(t *testing.T) {
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	conn, ch := testutils.NewTestChannel(t)
+	qfr, err := repository.NewQueueFailureRepository(tx)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
+	ter, err := repository.NewTimelineEventRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	connection := testutils.NewTestConnection(t)
 
-	qs, err := NewQueueService(tr, subR, qr, conn, ch, config.BrokerConfig.QueueName, config.BrokerConfig.ResponseQueueName)
+	qs, err := NewQueueService(tr, subR, qr, qfr, ter, NewArchiveService(subR, &fileStorageServiceStub{}), newAccessControlServiceTest(t, tx), config.Evaluation.Defaults, connection, config.BrokerConfig)
 	assert.NoError(t, err)
 
 	// Nothing to test here, just checking if the function doesn't panic
-	err = qs.publishMessage(schemas.QueueMessage{})
+	err = qs.publishMessage(schemas.QueueMessage{}, config.BrokerConfig.QueueName, 0)
 	assert.NoError(t, err)
 }
 
@@ -57,12 +63,17 @@ func TestPublishSubmission(t *testing.T) {
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	conn, ch := testutils.NewTestChannel(t)
+	qfr, err := repository.NewQueueFailureRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ter, err := repository.NewTimelineEventRepository(tx)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
+	connection := testutils.NewTestConnection(t)
 
-	qs, err := NewQueueService(tr, subR, qr, conn, ch, config.BrokerConfig.QueueName, config.BrokerConfig.ResponseQueueName)
+	qs, err := NewQueueService(tr, subR, qr, qfr, ter, NewArchiveService(subR, &fileStorageServiceStub{}), newAccessControlServiceTest(t, tx), config.Evaluation.Defaults, connection, config.BrokerConfig)
 	assert.NoError(t, err)
 	savePoint := "before"
 	tx.SavePoint(savePoint)
@@ -110,3 +121,72 @@ func TestPublishSubmission(t *testing.T) {
 	// })
 	tx.Rollback()
 }
+
+func TestRouteSubmission(t *testing.T) {
+	qs := &QueueServiceImpl{queueName: "worker_queue", highMemoryQueueName: "big_memory_queue", highMemoryThreshold: 1024}
+	java := models.LanguageConfig{Type: models.LanguageTypeJava, QueueName: "java_queue"}
+	c := models.LanguageConfig{Type: models.LanguageTypeC}
+
+	assert.Equal(t, "worker_queue", qs.routeSubmission(c, []float64{256, 512}))
+	assert.Equal(t, "java_queue", qs.routeSubmission(java, []float64{256}))
+	assert.Equal(t, "big_memory_queue", qs.routeSubmission(java, []float64{256, 1024}))
+
+	qs.highMemoryQueueName = ""
+	assert.Equal(t, "worker_queue", qs.routeSubmission(c, []float64{4096}))
+}
+
+func TestQueueFailures(t *testing.T) {
+	config := testutils.NewTestConfig()
+	tx := testutils.NewTestTx(t)
+	_, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	subR, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	qr, err := repository.NewQueueMessageRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	qfr, err := repository.NewQueueFailureRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ter, err := repository.NewTimelineEventRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	connection := testutils.NewTestConnection(t)
+
+	qs, err := NewQueueService(tr, subR, qr, qfr, ter, NewArchiveService(subR, &fileStorageServiceStub{}), newAccessControlServiceTest(t, tx), config.Evaluation.Defaults, connection, config.BrokerConfig)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+	student := schemas.User{Id: 2, Role: string(models.UserRoleStudent)}
+
+	t.Run("Not admin", func(t *testing.T) {
+		_, err := qs.GetQueueFailures(tx, student, false, 10, 0)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = qs.RequeueFailure(tx, student, 1)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+
+	t.Run("Nonexistent failure", func(t *testing.T) {
+		_, err := qs.RequeueFailure(tx, admin, 0)
+		assert.ErrorIs(t, err, ErrQueueFailureNotFound)
+	})
+
+	t.Run("No failures", func(t *testing.T) {
+		failures, err := qs.GetQueueFailures(tx, admin, true, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, failures)
+	})
+	tx.Rollback()
+}
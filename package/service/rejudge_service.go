@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxRejudgeSubmissions is the largest number of submissions rejudged in one batch,
+// as they are published within the request
+const MaxRejudgeSubmissions = 5000
+
+var ErrRejudgeBatchNotFound = errors.New("rejudge batch not found")
+var ErrRejudgeTooLarge = errors.New("too many submissions to rejudge in one batch")
+var ErrSubmissionNotJudged = errors.New("submission is still being judged")
+var ErrSubmissionRedacted = errors.New("source of the submission was redacted")
+var ErrSubmissionPruned = errors.New("source of the submission was pruned")
+
+type RejudgeService interface {
+	// RejudgeTask publishes all judged submissions of the task again, e.g. after its tests or limits changed.
+	// Redacted and pruned submissions are skipped as their source was removed. Only admins and the task author can rejudge
+	RejudgeTask(tx *gorm.DB, currentUser schemas.User, taskId int64) (*schemas.RejudgeBatch, error)
+	// RejudgeSubmission publishes a single judged submission again as a batch of one
+	RejudgeSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.RejudgeBatch, error)
+	GetBatch(tx *gorm.DB, currentUser schemas.User, batchId int64) (*schemas.RejudgeBatch, error)
+	// RecordJudged counts a rejudged submission of the batch once its result arrived
+	RecordJudged(tx *gorm.DB, batchId int64, failed bool) error
+}
+
+type RejudgeServiceImpl struct {
+	submissionRepository repository.SubmissionRepository
+	taskRepository       repository.TaskRepository
+	rejudgeRepository    repository.RejudgeRepository
+	queueService         QueueService
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (rs *RejudgeServiceImpl) RejudgeTask(tx *gorm.DB, currentUser schemas.User, taskId int64) (*schemas.RejudgeBatch, error) {
+	err := rs.authorize(tx, currentUser, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	submissionIds, err := rs.submissionRepository.GetRejudgeableSubmissionIds(tx, taskId)
+	if err != nil {
+		rs.logger.Errorf("Error getting submissions to rejudge: %v", err.Error())
+		return nil, err
+	}
+	if len(submissionIds) > MaxRejudgeSubmissions {
+		return nil, ErrRejudgeTooLarge
+	}
+	return rs.rejudge(tx, currentUser, taskId, submissionIds)
+}
+
+func (rs *RejudgeServiceImpl) RejudgeSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.RejudgeBatch, error) {
+	submission, err := rs.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSubmissionNotFound
+		}
+		rs.logger.Errorf("Error getting submission: %v", err.Error())
+		return nil, err
+	}
+	err = rs.authorize(tx, currentUser, submission.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	if submission.Status != "completed" && submission.Status != "failed" {
+		return nil, ErrSubmissionNotJudged
+	}
+	if submission.RedactedAt != nil {
+		return nil, ErrSubmissionRedacted
+	}
+	if submission.PrunedAt != nil {
+		return nil, ErrSubmissionPruned
+	}
+	return rs.rejudge(tx, currentUser, submission.TaskId, []int64{submissionId})
+}
+
+// rejudge creates a batch of the submissions and publishes them. A batch without submissions is finished at once
+func (rs *RejudgeServiceImpl) rejudge(tx *gorm.DB, currentUser schemas.User, taskId int64, submissionIds []int64) (*schemas.RejudgeBatch, error) {
+	batch := &models.RejudgeBatch{
+		TaskId:    taskId,
+		CreatedBy: currentUser.Id,
+		Total:     int64(len(submissionIds)),
+	}
+	if len(submissionIds) == 0 {
+		now := time.Now()
+		batch.FinishedAt = &now
+	}
+	batchId, err := rs.rejudgeRepository.CreateBatch(tx, batch)
+	if err != nil {
+		rs.logger.Errorf("Error creating rejudge batch: %v", err.Error())
+		return nil, err
+	}
+
+	for _, submissionId := range submissionIds {
+		err = rs.queueService.PublishRejudge(tx, submissionId, batchId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rs.logger.Infof("Rejudge batch %d of %d submissions of task %d started by user %d", batchId, len(submissionIds), taskId, currentUser.Id)
+	return rs.modelToSchema(batch), nil
+}
+
+func (rs *RejudgeServiceImpl) GetBatch(tx *gorm.DB, currentUser schemas.User, batchId int64) (*schemas.RejudgeBatch, error) {
+	batch, err := rs.rejudgeRepository.GetBatch(tx, batchId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRejudgeBatchNotFound
+		}
+		rs.logger.Errorf("Error getting rejudge batch: %v", err.Error())
+		return nil, err
+	}
+	err = rs.authorize(tx, currentUser, batch.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	return rs.modelToSchema(batch), nil
+}
+
+func (rs *RejudgeServiceImpl) RecordJudged(tx *gorm.DB, batchId int64, failed bool) error {
+	err := rs.rejudgeRepository.RecordJudged(tx, batchId, failed)
+	if err != nil {
+		rs.logger.Errorf("Error recording rejudge progress: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+// authorize checks the user may rejudge every task, or their own tasks and created the task
+func (rs *RejudgeServiceImpl) authorize(tx *gorm.DB, currentUser schemas.User, taskId int64) error {
+	if rs.accessControlService.CanAll(currentUser, ResourceTask, ActionRejudge) {
+		return nil
+	}
+	if !rs.accessControlService.Can(currentUser, ResourceTask, ActionRejudge) {
+		return ErrNotAuthorized
+	}
+	task, err := rs.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotFound
+		}
+		rs.logger.Errorf("Error getting task: %v", err.Error())
+		return err
+	}
+	if !rs.accessControlService.CanAccess(currentUser, ResourceTask, ActionRejudge, task.CreatedBy) {
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+func (rs *RejudgeServiceImpl) modelToSchema(model *models.RejudgeBatch) *schemas.RejudgeBatch {
+	return &schemas.RejudgeBatch{
+		Id:         model.Id,
+		TaskId:     model.TaskId,
+		CreatedBy:  model.CreatedBy,
+		Total:      model.Total,
+		Completed:  model.Completed,
+		Failed:     model.Failed,
+		CreatedAt:  model.CreatedAt,
+		FinishedAt: model.FinishedAt,
+	}
+}
+
+func NewRejudgeService(submissionRepository repository.SubmissionRepository, taskRepository repository.TaskRepository, rejudgeRepository repository.RejudgeRepository, queueService QueueService, accessControlService AccessControlService) RejudgeService {
+	log := logger.NewNamedLogger("rejudge_service")
+	return &RejudgeServiceImpl{
+		submissionRepository: submissionRepository,
+		taskRepository:       taskRepository,
+		rejudgeRepository:    rejudgeRepository,
+		queueService:         queueService,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejudge(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	rr, err := repository.NewRejudgeRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	queueService := &queueServiceStub{}
+	rs := NewRejudgeService(sr, tr, rr, queueService, newAccessControlServiceTest(t, tx))
+
+	teacherId, err := ur.CreateUser(tx, &models.User{
+		Name:         "Test User",
+		Surname:      "Test Surname",
+		Email:        "email@email.com",
+		Username:     "testuser",
+		PasswordHash: "password",
+		Role:         models.UserRoleTeacher,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	teacher := schemas.User{Id: teacherId, Role: string(models.UserRoleTeacher)}
+	taskId, err := tr.Create(tx, models.Task{Title: "Test Task", CreatedBy: teacherId})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	language := &models.LanguageConfig{Type: "c", Version: "99"}
+	if !assert.NoError(t, tx.Create(language).Error) {
+		t.FailNow()
+	}
+	judgedId, err := sr.CreateSubmission(tx, models.Submission{
+		TaskId:     taskId,
+		UserId:     teacherId,
+		Order:      1,
+		LanguageId: language.Id,
+		Status:     "completed",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	processingId, err := sr.CreateSubmission(tx, models.Submission{
+		TaskId:     taskId,
+		UserId:     teacherId,
+		Order:      2,
+		LanguageId: language.Id,
+		Status:     "processing",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Run("Rejudge task", func(t *testing.T) {
+		queueService.rejudged = nil
+		batch, err := rs.RejudgeTask(tx, teacher, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(1), batch.Total)
+		assert.Nil(t, batch.FinishedAt)
+		assert.Equal(t, []int64{judgedId}, queueService.rejudged)
+
+		err = rs.RecordJudged(tx, batch.Id, false)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		batch, err = rs.GetBatch(tx, teacher, batch.Id)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(1), batch.Completed)
+		assert.NotNil(t, batch.FinishedAt)
+	})
+
+	t.Run("Submission still being judged", func(t *testing.T) {
+		_, err := rs.RejudgeSubmission(tx, teacher, processingId)
+		assert.ErrorIs(t, err, ErrSubmissionNotJudged)
+	})
+
+	t.Run("Not the task author", func(t *testing.T) {
+		_, err := rs.RejudgeTask(tx, schemas.User{Id: teacherId + 1, Role: string(models.UserRoleTeacher)}, taskId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = rs.RejudgeSubmission(tx, schemas.User{Role: string(models.UserRoleStudent)}, judgedId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+
+	t.Run("Batch not found", func(t *testing.T) {
+		_, err := rs.GetBatch(tx, teacher, -1)
+		assert.ErrorIs(t, err, ErrRejudgeBatchNotFound)
+	})
+	tx.Rollback()
+}
@@ -13,17 +13,33 @@ import (
 	"gorm.io/gorm"
 )
 
+// SessionLifetime is how long a session is valid. Clients extend it with a refresh token
+const SessionLifetime = time.Hour
+
+// ImpersonationSessionLifetime is how long an admin can act as another user. Impersonation sessions
+// come without a refresh token and cannot be extended
+const ImpersonationSessionLifetime = 15 * time.Minute
+
 var (
 	ErrSessionNotFound     = fmt.Errorf("session not found")
 	ErrSessionExpired      = fmt.Errorf("session expired")
 	ErrSessionUserNotFound = fmt.Errorf("session user not found")
 	ErrSessionRefresh      = fmt.Errorf("session refresh failed")
+	ErrUserInactive        = fmt.Errorf("user is deactivated or banned")
 )
 
 type SessionService interface {
 	CreateSession(tx *gorm.DB, userId int64) (*schemas.Session, error)
+	// CreateImpersonationSession creates a session of the user for the impersonating admin, separate from
+	// the session of the user, valid for ImpersonationSessionLifetime
+	CreateImpersonationSession(tx *gorm.DB, userId int64, impersonatorId int64) (*schemas.Session, error)
 	ValidateSession(tx *gorm.DB, sessionId string) (schemas.ValidateSessionResponse, error)
 	InvalidateSession(tx *gorm.DB, sessionId string) error
+	// InvalidateUserSession invalidates the session of the user if there is one. Sessions of admins impersonating
+	// the user are kept
+	InvalidateUserSession(tx *gorm.DB, userId int64) error
+	// RefreshSession extends the session by SessionLifetime
+	RefreshSession(tx *gorm.DB, sessionId string) (*schemas.Session, error)
 }
 
 type SessionServiceImpl struct {
@@ -40,10 +56,11 @@ func (s *SessionServiceImpl) generateSessionToken() string {
 // Converts a session model to a session schema
 func (s *SessionServiceImpl) modelToSchema(session *models.Session) *schemas.Session {
 	return &schemas.Session{
-		Id:        session.Id,
-		UserId:    session.UserId,
-		ExpiresAt: session.ExpiresAt,
-		UserRole:  "invalid",
+		Id:             session.Id,
+		UserId:         session.UserId,
+		ExpiresAt:      session.ExpiresAt,
+		UserRole:       "invalid",
+		ImpersonatedBy: session.ImpersonatedBy,
 	}
 }
 
@@ -57,6 +74,9 @@ func (s *SessionServiceImpl) CreateSession(tx *gorm.DB, userId int64) (*schemas.
 		}
 		return nil, err
 	}
+	if user.Status != models.UserStatusActive {
+		return nil, ErrUserInactive
+	}
 
 	session, err := s.sessionRepository.GetSessionByUserId(tx, userId)
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -79,7 +99,7 @@ func (s *SessionServiceImpl) CreateSession(tx *gorm.DB, userId int64) (*schemas.
 	session = &models.Session{
 		Id:        sessionToken,
 		UserId:    userId,
-		ExpiresAt: time.Now().Add(time.Hour * 24),
+		ExpiresAt: time.Now().Add(SessionLifetime),
 	}
 
 	err = s.sessionRepository.CreateSession(tx, session)
@@ -93,6 +113,36 @@ func (s *SessionServiceImpl) CreateSession(tx *gorm.DB, userId int64) (*schemas.
 	return resp, nil
 }
 
+func (s *SessionServiceImpl) CreateImpersonationSession(tx *gorm.DB, userId int64, impersonatorId int64) (*schemas.Session, error) {
+	user, err := s.userRepository.GetUser(tx, userId)
+	if err != nil {
+		s.logger.Errorf("Error getting user by id: %v", err.Error())
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSessionUserNotFound
+		}
+		return nil, err
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, ErrUserInactive
+	}
+
+	session := &models.Session{
+		Id:             s.generateSessionToken(),
+		UserId:         userId,
+		ExpiresAt:      time.Now().Add(ImpersonationSessionLifetime),
+		ImpersonatedBy: &impersonatorId,
+	}
+	err = s.sessionRepository.CreateSession(tx, session)
+	if err != nil {
+		s.logger.Errorf("Error creating impersonation session: %v", err.Error())
+		return nil, err
+	}
+
+	resp := s.modelToSchema(session)
+	resp.UserRole = string(user.Role)
+	return resp, nil
+}
+
 func (s *SessionServiceImpl) ValidateSession(tx *gorm.DB, sessionId string) (schemas.ValidateSessionResponse, error) {
 	session, err := s.sessionRepository.GetSession(tx, sessionId)
 	if err != nil {
@@ -102,7 +152,7 @@ func (s *SessionServiceImpl) ValidateSession(tx *gorm.DB, sessionId string) (sch
 		}
 		return schemas.ValidateSessionResponse{Valid: false, UserId: -1}, err
 	}
-	_, err = s.userRepository.GetUser(tx, session.UserId)
+	user, err := s.userRepository.GetUser(tx, session.UserId)
 	if err != nil {
 		s.logger.Errorf("Error getting user by id: %v", err.Error())
 		if err == gorm.ErrRecordNotFound {
@@ -110,17 +160,20 @@ func (s *SessionServiceImpl) ValidateSession(tx *gorm.DB, sessionId string) (sch
 		}
 		return schemas.ValidateSessionResponse{Valid: false, UserId: -1}, err
 	}
+	if user.Status != models.UserStatusActive {
+		return schemas.ValidateSessionResponse{Valid: false, UserId: -1}, ErrUserInactive
+	}
 
 	if session.ExpiresAt.Before(time.Now()) {
 		s.logger.Error("Session expired")
 		return schemas.ValidateSessionResponse{Valid: false, UserId: -1}, ErrSessionExpired
 	}
 
-	return schemas.ValidateSessionResponse{Valid: true, UserId: session.UserId}, nil
+	return schemas.ValidateSessionResponse{Valid: true, UserId: session.UserId, ImpersonatedBy: session.ImpersonatedBy}, nil
 }
 
 func (s *SessionServiceImpl) RefreshSession(tx *gorm.DB, sessionId string) (*schemas.Session, error) {
-	err := s.sessionRepository.UpdateExpiration(tx, sessionId, time.Now().Add(time.Hour*24))
+	err := s.sessionRepository.UpdateExpiration(tx, sessionId, time.Now().Add(SessionLifetime))
 	if err != nil {
 		s.logger.Errorf("Error updating session expiration: %v", err.Error())
 		return nil, err
@@ -142,6 +195,17 @@ func (s *SessionServiceImpl) InvalidateSession(tx *gorm.DB, sessionId string) er
 	return nil
 }
 
+func (s *SessionServiceImpl) InvalidateUserSession(tx *gorm.DB, userId int64) error {
+	session, err := s.sessionRepository.GetSessionByUserId(tx, userId)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		s.logger.Errorf("Error getting session: %v", err.Error())
+		return err
+	}
+	return s.InvalidateSession(tx, session.Id)
+}
+
 func NewSessionService(sessionRepository repository.SessionRepository, userRepository repository.UserRepository) SessionService {
 	log := logger.NewNamedLogger("session_service")
 	return &SessionServiceImpl{
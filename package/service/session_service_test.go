@@ -2,11 +2,13 @@ package service
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mini-maxit/backend/internal/testutils"
 	"github.com/mini-maxit/backend/package/domain/models"
 	"github.com/mini-maxit/backend/package/repository"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestValidateSession(t *testing.T) {
@@ -84,3 +86,82 @@ func TestInvalidateSession(t *testing.T) {
 	})
 	tx.Rollback()
 }
+
+func TestRedisSessionRepository(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	userRepo, err := repository.NewUserRepository(tx)
+	if err != nil {
+		t.Fatalf("failed to create a new user repository: %v", err)
+	}
+	sessionRepo := repository.NewRedisSessionRepository(testutils.NewTestRedisClient(t))
+	sessionService := NewSessionService(sessionRepo, userRepo)
+	userId, err := userRepo.CreateUser(tx, &models.User{
+		Name:         "test-name",
+		Surname:      "test-surname",
+		Email:        "test-email",
+		Username:     "test-username",
+		PasswordHash: "test-password-hash",
+		Role:         "admin",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Run("Session not found", func(t *testing.T) {
+		_, err := sessionService.ValidateSession(tx, "test-session-id")
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+		_, err = sessionRepo.GetSessionByUserId(tx, userId)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, sessionRepo.DeleteSession(tx, "test-session-id"))
+	})
+	t.Run("Session is indexed by user and expires", func(t *testing.T) {
+		session, err := sessionService.CreateSession(tx, userId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		validateSession, err := sessionService.ValidateSession(tx, session.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, userId, validateSession.UserId)
+		byUser, err := sessionRepo.GetSessionByUserId(tx, userId)
+		if assert.NoError(t, err) {
+			assert.Equal(t, session.Id, byUser.Id)
+		}
+
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+		assert.NoError(t, sessionRepo.UpdateExpiration(tx, session.Id, expiresAt))
+		updated, err := sessionRepo.GetSession(tx, session.Id)
+		if assert.NoError(t, err) {
+			assert.True(t, expiresAt.Equal(updated.ExpiresAt))
+		}
+
+		assert.NoError(t, sessionService.InvalidateSession(tx, session.Id))
+		_, err = sessionRepo.GetSession(tx, session.Id)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = sessionRepo.GetSessionByUserId(tx, userId)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+	t.Run("Impersonation session does not replace the session of the user", func(t *testing.T) {
+		session, err := sessionService.CreateSession(tx, userId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		impersonatedBy := userId + 1
+		impersonation := &models.Session{Id: "impersonation-session-id", UserId: userId, ExpiresAt: time.Now().Add(time.Hour), ImpersonatedBy: &impersonatedBy}
+		assert.NoError(t, sessionRepo.CreateSession(tx, impersonation))
+
+		stored, err := sessionRepo.GetSession(tx, impersonation.Id)
+		if assert.NoError(t, err) && assert.NotNil(t, stored.ImpersonatedBy) {
+			assert.Equal(t, impersonatedBy, *stored.ImpersonatedBy)
+		}
+		byUser, err := sessionRepo.GetSessionByUserId(tx, userId)
+		if assert.NoError(t, err) {
+			assert.Equal(t, session.Id, byUser.Id)
+		}
+
+		// Ending the impersonation keeps the session of the user
+		assert.NoError(t, sessionRepo.DeleteSession(tx, impersonation.Id))
+		_, err = sessionRepo.GetSessionByUserId(tx, userId)
+		assert.NoError(t, err)
+	})
+	tx.Rollback()
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultGroupActivityDays is the number of days of activity returned when the request does not set it
+	DefaultGroupActivityDays = 30
+	MaxGroupActivityDays     = 365
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+var ErrTaskNotInGroup = errors.New("task is not assigned to the group")
+
+type StatsService interface {
+	// GetGroupStats returns solved tasks and best scores of each member of the group for tasks of the group,
+	// and submissions of the members per day for the given number of days up to today. Only teachers and
+	// admins can see statistics of groups
+	GetGroupStats(tx *gorm.DB, currentUser schemas.User, groupId int64, days int) (*schemas.GroupStats, error)
+	// GetGroupProgress returns for each task of the group how many members submitted to and solved it, and the
+	// median of their best scores. Only teachers and admins can see progress of groups
+	GetGroupProgress(tx *gorm.DB, currentUser schemas.User, groupId int64) (*schemas.GroupProgress, error)
+	// SetTaskStatsVisibility sets whether members of the group see statistics of other users for the task, or only
+	// their own results. Only admins and teachers who author or co-author the task can change it
+	SetTaskStatsVisibility(tx *gorm.DB, currentUser schemas.User, groupId int64, taskId int64, edit schemas.GroupTaskStatsVisibility) error
+}
+
+type StatsServiceImpl struct {
+	groupRepository      repository.GroupRepository
+	taskRepository       repository.TaskRepository
+	coAuthorRepository   repository.TaskCoAuthorRepository
+	submissionRepository repository.SubmissionRepository
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ss *StatsServiceImpl) GetGroupStats(tx *gorm.DB, currentUser schemas.User, groupId int64, days int) (*schemas.GroupStats, error) {
+	if !ss.accessControlService.Can(currentUser, ResourceGroup, ActionViewStats) {
+		return nil, ErrNotAuthorized
+	}
+
+	group, err := ss.groupRepository.GetGroup(tx, groupId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrGroupNotFound
+		}
+		ss.logger.Errorf("Error getting group: %v", err.Error())
+		return nil, err
+	}
+	members, err := ss.groupRepository.GetGroupMembers(tx, groupId)
+	if err != nil {
+		ss.logger.Errorf("Error getting group members: %v", err.Error())
+		return nil, err
+	}
+	tasks, err := ss.taskRepository.GetAllForGroup(tx, groupId)
+	if err != nil {
+		ss.logger.Errorf("Error getting tasks of group: %v", err.Error())
+		return nil, err
+	}
+	results, err := ss.submissionRepository.GetGroupTaskResults(tx, groupId)
+	if err != nil {
+		ss.logger.Errorf("Error getting group task results: %v", err.Error())
+		return nil, err
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, 1-days)
+	activity, err := ss.submissionRepository.GetGroupActivity(tx, groupId, since)
+	if err != nil {
+		ss.logger.Errorf("Error getting group activity: %v", err.Error())
+		return nil, err
+	}
+
+	type key struct{ userId, taskId int64 }
+	resultsByKey := make(map[key]models.GroupTaskResult, len(results))
+	for _, result := range results {
+		resultsByKey[key{result.UserId, result.TaskId}] = result
+	}
+
+	stats := &schemas.GroupStats{
+		GroupId:  group.Id,
+		Name:     group.Name,
+		Tasks:    make([]schemas.GroupTaskStats, len(tasks)),
+		Members:  make([]schemas.GroupMemberStats, 0, len(members)),
+		Activity: make([]schemas.GroupActivityDay, 0, days),
+	}
+	taskScores := make([]float64, len(tasks))
+	for i, task := range tasks {
+		stats.Tasks[i] = schemas.GroupTaskStats{TaskId: task.Id, Title: task.Title}
+	}
+	for _, member := range members {
+		memberStats := schemas.GroupMemberStats{
+			UserId:  member.Id,
+			Name:    member.Name,
+			Surname: member.Surname,
+			Scores:  make([]*float64, len(tasks)),
+		}
+		var total float64
+		for i, task := range tasks {
+			result, ok := resultsByKey[key{member.Id, task.Id}]
+			if !ok {
+				continue
+			}
+			score := result.BestScore
+			memberStats.Scores[i] = &score
+			memberStats.Attempts += result.Attempts
+			total += score
+			taskScores[i] += score
+			stats.Tasks[i].AttemptedUsers++
+			if score == 100 {
+				memberStats.Solved++
+				stats.Tasks[i].SolvedUsers++
+			}
+		}
+		memberStats.AverageScore = average(total, len(tasks))
+		stats.Members = append(stats.Members, memberStats)
+	}
+	for i := range stats.Tasks {
+		stats.Tasks[i].AverageScore = average(taskScores[i], len(members))
+	}
+
+	// Days without submissions are filled in, so the activity can be plotted as it is
+	activityByDay := make(map[time.Time]models.GroupActivityDay, len(activity))
+	for _, day := range activity {
+		activityByDay[day.Day.UTC().Truncate(24*time.Hour)] = day
+	}
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		entry := activityByDay[day]
+		stats.Activity = append(stats.Activity, schemas.GroupActivityDay{Day: day, Submissions: entry.Submissions, Users: entry.Users})
+	}
+	return stats, nil
+}
+
+func (ss *StatsServiceImpl) GetGroupProgress(tx *gorm.DB, currentUser schemas.User, groupId int64) (*schemas.GroupProgress, error) {
+	if !ss.accessControlService.Can(currentUser, ResourceGroup, ActionViewStats) {
+		return nil, ErrNotAuthorized
+	}
+
+	group, err := ss.groupRepository.GetGroup(tx, groupId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrGroupNotFound
+		}
+		ss.logger.Errorf("Error getting group: %v", err.Error())
+		return nil, err
+	}
+	tasks, err := ss.submissionRepository.GetGroupTaskProgress(tx, groupId)
+	if err != nil {
+		ss.logger.Errorf("Error getting group task progress: %v", err.Error())
+		return nil, err
+	}
+
+	progress := &schemas.GroupProgress{
+		GroupId: group.Id,
+		Name:    group.Name,
+		Tasks:   make([]schemas.GroupTaskProgress, 0, len(tasks)),
+	}
+	for _, task := range tasks {
+		var median *float64
+		if task.MedianBestScore != nil {
+			rounded := math.Round(*task.MedianBestScore*100) / 100
+			median = &rounded
+		}
+		progress.Tasks = append(progress.Tasks, schemas.GroupTaskProgress{
+			TaskId:          task.TaskId,
+			Title:           task.Title,
+			AttemptedUsers:  task.AttemptedUsers,
+			SolvedUsers:     task.SolvedUsers,
+			MedianBestScore: median,
+		})
+	}
+	return progress, nil
+}
+
+// average returns the average rounded to two decimal places, zero when there is nothing to average
+func average(total float64, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(total*100/float64(count)) / 100
+}
+
+func (ss *StatsServiceImpl) SetTaskStatsVisibility(tx *gorm.DB, currentUser schemas.User, groupId int64, taskId int64, edit schemas.GroupTaskStatsVisibility) error {
+	if !ss.accessControlService.Can(currentUser, ResourceGroup, ActionManageStats) {
+		return ErrNotAuthorized
+	}
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ss.logger.Errorf("Error validating task statistics visibility: %v", err.Error())
+		return err
+	}
+
+	_, err := ss.groupRepository.GetGroup(tx, groupId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrGroupNotFound
+		}
+		ss.logger.Errorf("Error getting group: %v", err.Error())
+		return err
+	}
+	task, err := ss.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotFound
+		}
+		ss.logger.Errorf("Error getting task: %v", err.Error())
+		return err
+	}
+	if !ss.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		if !ss.accessControlService.Can(currentUser, ResourceTask, ActionEdit) {
+			return ErrNotAuthorized
+		}
+		isCoAuthor, err := ss.coAuthorRepository.IsCoAuthor(tx, taskId, currentUser.Id)
+		if err != nil {
+			ss.logger.Errorf("Error checking task co-author: %v", err.Error())
+			return err
+		}
+		if !isCoAuthor {
+			return ErrNotAuthorized
+		}
+	}
+	err = ss.groupRepository.SetTaskStatsHidden(tx, groupId, taskId, *edit.Hidden)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotInGroup
+		}
+		ss.logger.Errorf("Error setting task statistics visibility: %v", err.Error())
+		return err
+	}
+	ss.logger.Infof("Statistics of task %d hidden from group %d set to %t by user %d", taskId, groupId, *edit.Hidden, currentUser.Id)
+	return nil
+}
+
+func NewStatsService(groupRepository repository.GroupRepository, taskRepository repository.TaskRepository, coAuthorRepository repository.TaskCoAuthorRepository, submissionRepository repository.SubmissionRepository, accessControlService AccessControlService) StatsService {
+	log := logger.NewNamedLogger("stats_service")
+	return &StatsServiceImpl{
+		groupRepository:      groupRepository,
+		taskRepository:       taskRepository,
+		coAuthorRepository:   coAuthorRepository,
+		submissionRepository: submissionRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetGroupStats(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	gr, err := repository.NewGroupRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tcar, err := repository.NewTaskCoAuthorRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ss := NewStatsService(gr, tr, tcar, sr, newAccessControlServiceTest(t, tx))
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+	teacher := schemas.User{Id: 1, Role: string(models.UserRoleTeacher)}
+
+	t.Run("Success", func(t *testing.T) {
+		users := []*models.User{
+			{Name: "Teacher", Surname: "User", Email: "teacher@email.com", Username: "teacher", PasswordHash: "password", Role: models.UserRoleTeacher},
+			{Name: "First", Surname: "Student", Email: "first@email.com", Username: "first", PasswordHash: "password"},
+			{Name: "Second", Surname: "Student", Email: "second@email.com", Username: "second", PasswordHash: "password"},
+		}
+		if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+			t.FailNow()
+		}
+		groupId, err := gr.CreateGroup(tx, models.Group{Name: "Group"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: models.LanguageTypeC, Version: "99"}
+		if !assert.NoError(t, tx.Create(language).Error) {
+			t.FailNow()
+		}
+		var taskIds []int64
+		for _, title := range []string{"First Task", "Second Task"} {
+			taskId, err := tr.Create(tx, models.Task{Title: title, CreatedBy: users[0].Id})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.NoError(t, tx.Create(&models.TaskGroup{TaskId: taskId, GroupId: groupId}).Error)
+			taskIds = append(taskIds, taskId)
+		}
+		for _, user := range users[1:] {
+			assert.NoError(t, tx.Create(&models.UserGroup{UserId: user.Id, GroupId: groupId}).Error)
+		}
+		// The first student solves the first task on the second attempt, the second student does not submit
+		for order, score := range []float64{50, 100} {
+			submissionId, err := sr.CreateSubmission(tx, models.Submission{TaskId: taskIds[0], UserId: users[1].Id, Order: int64(order + 1), LanguageId: language.Id, Status: "completed"})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.NoError(t, tx.Create(&models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Score: score}).Error)
+		}
+
+		stats, err := ss.GetGroupStats(tx, teacher, groupId, 7)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Len(t, stats.Tasks, 2)
+		assert.Len(t, stats.Activity, 7)
+		if assert.Len(t, stats.Members, 2) {
+			first := stats.Members[0]
+			assert.Equal(t, users[1].Id, first.UserId)
+			assert.Equal(t, int64(1), first.Solved)
+			assert.Equal(t, int64(2), first.Attempts)
+			assert.Equal(t, 50.0, first.AverageScore)
+			assert.Len(t, first.Scores, 2)
+			assert.Equal(t, []*float64{nil, nil}, stats.Members[1].Scores)
+		}
+		var submissions int64
+		for _, day := range stats.Activity {
+			submissions += day.Submissions
+		}
+		assert.Equal(t, int64(2), submissions)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Progress", func(t *testing.T) {
+		users := []*models.User{
+			{Name: "Teacher", Surname: "User", Email: "teacher@email.com", Username: "teacher", PasswordHash: "password", Role: models.UserRoleTeacher},
+			{Name: "First", Surname: "Student", Email: "first@email.com", Username: "first", PasswordHash: "password"},
+			{Name: "Second", Surname: "Student", Email: "second@email.com", Username: "second", PasswordHash: "password"},
+			{Name: "Third", Surname: "Student", Email: "third@email.com", Username: "third", PasswordHash: "password"},
+		}
+		if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+			t.FailNow()
+		}
+		groupId, err := gr.CreateGroup(tx, models.Group{Name: "Group"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: models.LanguageTypeC, Version: "99"}
+		if !assert.NoError(t, tx.Create(language).Error) {
+			t.FailNow()
+		}
+		var taskIds []int64
+		for _, title := range []string{"First Task", "Second Task"} {
+			taskId, err := tr.Create(tx, models.Task{Title: title, CreatedBy: users[0].Id})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.NoError(t, tx.Create(&models.TaskGroup{TaskId: taskId, GroupId: groupId}).Error)
+			taskIds = append(taskIds, taskId)
+		}
+		for _, user := range users[1:] {
+			assert.NoError(t, tx.Create(&models.UserGroup{UserId: user.Id, GroupId: groupId}).Error)
+		}
+		// Best scores of the first task are 100, 40 and 20, nobody submits to the second task
+		submissions := []struct {
+			userId int64
+			score  float64
+		}{{users[1].Id, 30}, {users[1].Id, 100}, {users[2].Id, 40}, {users[3].Id, 20}}
+		for order, submission := range submissions {
+			submissionId, err := sr.CreateSubmission(tx, models.Submission{TaskId: taskIds[0], UserId: submission.userId, Order: int64(order + 1), LanguageId: language.Id, Status: "completed"})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.NoError(t, tx.Create(&models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Score: submission.score}).Error)
+		}
+
+		progress, err := ss.GetGroupProgress(tx, teacher, groupId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if assert.Len(t, progress.Tasks, 2) {
+			first := progress.Tasks[0]
+			assert.Equal(t, taskIds[0], first.TaskId)
+			assert.Equal(t, int64(3), first.AttemptedUsers)
+			assert.Equal(t, int64(1), first.SolvedUsers)
+			if assert.NotNil(t, first.MedianBestScore) {
+				assert.Equal(t, 40.0, *first.MedianBestScore)
+			}
+			assert.Equal(t, int64(0), progress.Tasks[1].AttemptedUsers)
+			assert.Nil(t, progress.Tasks[1].MedianBestScore)
+		}
+
+		_, err = ss.GetGroupProgress(tx, schemas.User{Id: users[1].Id, Role: string(models.UserRoleStudent)}, groupId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Nonexistent group", func(t *testing.T) {
+		_, err := ss.GetGroupStats(tx, teacher, 0, DefaultGroupActivityDays)
+		assert.ErrorIs(t, err, ErrGroupNotFound)
+	})
+
+	t.Run("Student", func(t *testing.T) {
+		_, err := ss.GetGroupStats(tx, schemas.User{Id: 2, Role: string(models.UserRoleStudent)}, 0, DefaultGroupActivityDays)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+
+	t.Run("Task statistics visibility", func(t *testing.T) {
+		hidden := true
+		edit := schemas.GroupTaskStatsVisibility{Hidden: &hidden}
+		err := ss.SetTaskStatsVisibility(tx, schemas.User{Id: 2, Role: string(models.UserRoleStudent)}, 0, 0, edit)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		err = ss.SetTaskStatsVisibility(tx, teacher, 0, 0, edit)
+		assert.ErrorIs(t, err, ErrGroupNotFound)
+
+		groupId, err := gr.CreateGroup(tx, models.Group{Name: "Visibility"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		err = ss.SetTaskStatsVisibility(tx, teacher, groupId, 0, edit)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+
+		users := []*models.User{
+			{Name: "Author", Surname: "User", Email: "author@email.com", Username: "author", PasswordHash: "password", Role: models.UserRoleTeacher},
+			{Name: "Other", Surname: "User", Email: "other@email.com", Username: "other", PasswordHash: "password", Role: models.UserRoleTeacher},
+		}
+		if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+			t.FailNow()
+		}
+		author := schemas.User{Id: users[0].Id, Role: string(models.UserRoleTeacher)}
+		other := schemas.User{Id: users[1].Id, Role: string(models.UserRoleTeacher)}
+		taskId, err := tr.Create(tx, models.Task{Title: "Visibility Task", CreatedBy: author.Id})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		err = ss.SetTaskStatsVisibility(tx, author, groupId, taskId, edit)
+		assert.ErrorIs(t, err, ErrTaskNotInGroup)
+
+		assert.NoError(t, tx.Create(&models.TaskGroup{TaskId: taskId, GroupId: groupId}).Error)
+		err = ss.SetTaskStatsVisibility(tx, other, groupId, taskId, edit)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		assert.NoError(t, ss.SetTaskStatsVisibility(tx, author, groupId, taskId, edit))
+
+		err = tcar.ReplaceCoAuthors(tx, taskId, []models.TaskCoAuthor{{TaskId: taskId, UserId: other.Id, Position: 1, DisplayName: "Other User"}})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NoError(t, ss.SetTaskStatsVisibility(tx, other, groupId, taskId, edit))
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	JudgeLatencyPercentile = 95
+	JudgeLatencyWindow     = time.Hour
+	RecentIncidentsWindow  = 7 * 24 * time.Hour
+	RecentIncidentsLimit   = 20
+)
+
+const (
+	ComponentStatusOperational = "operational"
+	ComponentStatusUnavailable = "unavailable"
+)
+
+const (
+	CapacitySimulationWindow  = 7 * 24 * time.Hour
+	CapacitySimulationSamples = 10000
+	MaxSimulatedSubmissions   = 1000000
+	MaxRecommendedWorkers     = 1000
+	// Fixed seed, so the same request gives the same result
+	capacitySimulationSeed = 1
+)
+
+var ErrIncidentNotFound = errors.New("incident not found")
+var ErrNoJudgeTimings = errors.New("no submissions were judged recently")
+var ErrSimulationTooLarge = errors.New("too many expected submissions to simulate")
+
+// Component is a dependency whose health is shown on the status page.
+// Check returns an error when the component is unavailable
+type Component struct {
+	Name  string
+	Check func() error
+}
+
+type StatusService interface {
+	// GetStatus returns component health, judge latency and recent incidents
+	GetStatus(tx *gorm.DB) (*schemas.Status, error)
+	CreateIncident(tx *gorm.DB, currentUser schemas.User, incident schemas.IncidentCreate) (*schemas.Incident, error)
+	// AddIncidentUpdate posts an update to an incident. An update with status resolved resolves the incident
+	AddIncidentUpdate(tx *gorm.DB, currentUser schemas.User, incidentId int64, update schemas.IncidentUpdateCreate) (*schemas.Incident, error)
+	// SimulateCapacity simulates judging of the expected exam load with the given number of workers.
+	// Submissions arrive at random at the given rate and judging times per test are sampled from recent submissions
+	SimulateCapacity(tx *gorm.DB, currentUser schemas.User, request schemas.CapacitySimulationRequest) (*schemas.CapacitySimulation, error)
+}
+
+type StatusServiceImpl struct {
+	submissionRepository repository.SubmissionRepository
+	incidentRepository   repository.IncidentRepository
+	components           []Component
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ss *StatusServiceImpl) GetStatus(tx *gorm.DB) (*schemas.Status, error) {
+	components := make([]schemas.ComponentStatus, 0, len(ss.components))
+	for _, component := range ss.components {
+		status := ComponentStatusOperational
+		if err := component.Check(); err != nil {
+			ss.logger.Warnf("Component %s is unavailable: %v", component.Name, err.Error())
+			status = ComponentStatusUnavailable
+		}
+		components = append(components, schemas.ComponentStatus{Name: component.Name, Status: status})
+	}
+
+	latency, err := ss.submissionRepository.GetJudgeLatencyPercentile(tx, JudgeLatencyPercentile/100.0, time.Now().Add(-JudgeLatencyWindow))
+	if err != nil {
+		ss.logger.Errorf("Error getting judge latency: %v", err.Error())
+		return nil, err
+	}
+
+	incidents, err := ss.incidentRepository.GetRecentIncidents(tx, time.Now().Add(-RecentIncidentsWindow), RecentIncidentsLimit)
+	if err != nil {
+		ss.logger.Errorf("Error getting recent incidents: %v", err.Error())
+		return nil, err
+	}
+	result := make([]schemas.Incident, 0, len(incidents))
+	for _, incident := range incidents {
+		result = append(result, *ss.incidentModelToSchema(&incident))
+	}
+
+	return &schemas.Status{
+		Components: components,
+		JudgeLatency: schemas.JudgeLatency{
+			Percentile:    JudgeLatencyPercentile,
+			WindowMinutes: int(JudgeLatencyWindow.Minutes()),
+			Seconds:       latency,
+		},
+		Incidents: result,
+	}, nil
+}
+
+func (ss *StatusServiceImpl) CreateIncident(tx *gorm.DB, currentUser schemas.User, incident schemas.IncidentCreate) (*schemas.Incident, error) {
+	if !ss.accessControlService.Can(currentUser, ResourceStatus, ActionManageIncidents) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(incident); err != nil {
+		ss.logger.Errorf("Error validating incident: %v", err.Error())
+		return nil, err
+	}
+
+	model := &models.Incident{
+		Title:     incident.Title,
+		Status:    models.IncidentStatus(incident.Status),
+		CreatedBy: currentUser.Id,
+	}
+	if model.Status == models.IncidentStatusResolved {
+		now := time.Now()
+		model.ResolvedAt = &now
+	}
+	incidentId, err := ss.incidentRepository.CreateIncident(tx, model)
+	if err != nil {
+		ss.logger.Errorf("Error creating incident: %v", err.Error())
+		return nil, err
+	}
+
+	err = ss.incidentRepository.CreateIncidentUpdate(tx, &models.IncidentUpdate{
+		IncidentId: incidentId,
+		Status:     model.Status,
+		Message:    incident.Message,
+		CreatedBy:  currentUser.Id,
+	})
+	if err != nil {
+		ss.logger.Errorf("Error creating incident update: %v", err.Error())
+		return nil, err
+	}
+
+	return ss.getIncident(tx, incidentId)
+}
+
+func (ss *StatusServiceImpl) AddIncidentUpdate(tx *gorm.DB, currentUser schemas.User, incidentId int64, update schemas.IncidentUpdateCreate) (*schemas.Incident, error) {
+	if !ss.accessControlService.Can(currentUser, ResourceStatus, ActionManageIncidents) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(update); err != nil {
+		ss.logger.Errorf("Error validating incident update: %v", err.Error())
+		return nil, err
+	}
+
+	incident, err := ss.incidentRepository.GetIncident(tx, incidentId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrIncidentNotFound
+		}
+		ss.logger.Errorf("Error getting incident: %v", err.Error())
+		return nil, err
+	}
+
+	incident.Status = models.IncidentStatus(update.Status)
+	if incident.Status == models.IncidentStatusResolved {
+		if incident.ResolvedAt == nil {
+			now := time.Now()
+			incident.ResolvedAt = &now
+		}
+	} else {
+		// Reopened incident
+		incident.ResolvedAt = nil
+	}
+	err = ss.incidentRepository.UpdateIncident(tx, incident)
+	if err != nil {
+		ss.logger.Errorf("Error updating incident: %v", err.Error())
+		return nil, err
+	}
+
+	err = ss.incidentRepository.CreateIncidentUpdate(tx, &models.IncidentUpdate{
+		IncidentId: incidentId,
+		Status:     incident.Status,
+		Message:    update.Message,
+		CreatedBy:  currentUser.Id,
+	})
+	if err != nil {
+		ss.logger.Errorf("Error creating incident update: %v", err.Error())
+		return nil, err
+	}
+
+	return ss.getIncident(tx, incidentId)
+}
+
+func (ss *StatusServiceImpl) SimulateCapacity(tx *gorm.DB, currentUser schemas.User, request schemas.CapacitySimulationRequest) (*schemas.CapacitySimulation, error) {
+	if !ss.accessControlService.Can(currentUser, ResourceStatus, ActionSimulateCapacity) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		ss.logger.Errorf("Error validating capacity simulation request: %v", err.Error())
+		return nil, err
+	}
+
+	duration := float64(request.DurationMinutes * 60)
+	arrivalRate := float64(request.Participants) * request.SubmissionRate / 3600
+	if arrivalRate*duration > MaxSimulatedSubmissions {
+		return nil, ErrSimulationTooLarge
+	}
+
+	timings, err := ss.submissionRepository.GetJudgeTimings(tx, time.Now().Add(-CapacitySimulationWindow), CapacitySimulationSamples)
+	if err != nil {
+		ss.logger.Errorf("Error getting judge timings: %v", err.Error())
+		return nil, err
+	}
+	if len(timings) == 0 {
+		return nil, ErrNoJudgeTimings
+	}
+
+	random := rand.New(rand.NewSource(capacitySimulationSeed))
+	var arrivals, services []float64
+	serviceTotal := 0.0
+	for t := random.ExpFloat64() / arrivalRate; t < duration; t += random.ExpFloat64() / arrivalRate {
+		timing := timings[random.Intn(len(timings))]
+		tests := timing.TotalTests
+		if request.TestsPerSubmission > 0 {
+			tests = int64(request.TestsPerSubmission)
+		}
+		serviceSeconds := timing.Seconds / float64(timing.TotalTests) * float64(tests)
+		arrivals = append(arrivals, t)
+		services = append(services, serviceSeconds)
+		serviceTotal += serviceSeconds
+	}
+
+	result := &schemas.CapacitySimulation{
+		Submissions: len(arrivals),
+		Samples:     len(timings),
+	}
+	if len(arrivals) == 0 {
+		return result, nil
+	}
+	result.MeanServiceSeconds = serviceTotal / float64(len(arrivals))
+	result.Utilization = arrivalRate * result.MeanServiceSeconds / float64(request.Workers)
+
+	queue := simulateJudgeQueue(arrivals, services, request.Workers)
+	result.MaxQueueDepth = queue.maxDepth
+	result.MeanQueueDepth = queue.meanDepth
+	result.Latency = queue.latency
+
+	if request.TargetLatencySeconds > 0 {
+		// Latency does not grow with more workers, so the fewest workers meeting the target can be searched for
+		low, high := 1, MaxRecommendedWorkers
+		if simulateJudgeQueue(arrivals, services, high).latency.P95 <= request.TargetLatencySeconds {
+			for low < high {
+				workers := (low + high) / 2
+				if simulateJudgeQueue(arrivals, services, workers).latency.P95 <= request.TargetLatencySeconds {
+					high = workers
+				} else {
+					low = workers + 1
+				}
+			}
+			result.RecommendedWorkers = &high
+		}
+	}
+
+	ss.logger.Infof("User %d simulated %d submissions on %d workers", currentUser.Id, result.Submissions, request.Workers)
+	return result, nil
+}
+
+type judgeQueueResult struct {
+	maxDepth  int
+	meanDepth float64
+	latency   schemas.CapacityLatency
+}
+
+// workerQueue is a min-heap of times the workers become free
+type workerQueue []float64
+
+func (wq workerQueue) Len() int           { return len(wq) }
+func (wq workerQueue) Less(i, j int) bool { return wq[i] < wq[j] }
+func (wq workerQueue) Swap(i, j int)      { wq[i], wq[j] = wq[j], wq[i] }
+func (wq *workerQueue) Push(x any)        { *wq = append(*wq, x.(float64)) }
+func (wq *workerQueue) Pop() any {
+	old := *wq
+	x := old[len(old)-1]
+	*wq = old[:len(old)-1]
+	return x
+}
+
+// simulateJudgeQueue judges submissions arriving at the given times in order on the first free worker.
+// Queue depth is the number of submissions waiting for a worker, as seen by arriving submissions
+func simulateJudgeQueue(arrivals []float64, services []float64, workers int) judgeQueueResult {
+	free := make(workerQueue, workers)
+	heap.Init(&free)
+	starts := make([]float64, len(arrivals))
+	latencies := make([]float64, len(arrivals))
+	result := judgeQueueResult{}
+	depthTotal := 0
+	started := 0
+	for i, arrival := range arrivals {
+		start := math.Max(arrival, heap.Pop(&free).(float64))
+		heap.Push(&free, start+services[i])
+		starts[i] = start
+		latencies[i] = start + services[i] - arrival
+
+		// Submissions start in arrival order, so the ones started by now are a prefix
+		for started < i && starts[started] <= arrival {
+			started++
+		}
+		depth := i - started
+		depthTotal += depth
+		result.maxDepth = max(result.maxDepth, depth)
+	}
+	result.meanDepth = float64(depthTotal) / float64(len(arrivals))
+
+	slices.Sort(latencies)
+	result.latency = schemas.CapacityLatency{
+		P50: latencies[len(latencies)*50/100],
+		P95: latencies[len(latencies)*95/100],
+		Max: latencies[len(latencies)-1],
+	}
+	return result
+}
+
+func (ss *StatusServiceImpl) getIncident(tx *gorm.DB, incidentId int64) (*schemas.Incident, error) {
+	incident, err := ss.incidentRepository.GetIncident(tx, incidentId)
+	if err != nil {
+		ss.logger.Errorf("Error getting incident: %v", err.Error())
+		return nil, err
+	}
+	return ss.incidentModelToSchema(incident), nil
+}
+
+func (ss *StatusServiceImpl) incidentModelToSchema(model *models.Incident) *schemas.Incident {
+	updates := make([]schemas.IncidentUpdate, 0, len(model.Updates))
+	for _, update := range model.Updates {
+		updates = append(updates, schemas.IncidentUpdate{
+			Status:    string(update.Status),
+			Message:   update.Message,
+			CreatedAt: update.CreatedAt,
+		})
+	}
+	return &schemas.Incident{
+		Id:         model.Id,
+		Title:      model.Title,
+		Status:     string(model.Status),
+		CreatedAt:  model.CreatedAt,
+		ResolvedAt: model.ResolvedAt,
+		Updates:    updates,
+	}
+}
+
+func NewStatusService(submissionRepository repository.SubmissionRepository, incidentRepository repository.IncidentRepository, accessControlService AccessControlService, components []Component) StatusService {
+	log := logger.NewNamedLogger("status_service")
+	return &StatusServiceImpl{
+		submissionRepository: submissionRepository,
+		incidentRepository:   incidentRepository,
+		components:           components,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ir, err := repository.NewIncidentRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srr, err := repository.NewSubmissionResultRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	components := []Component{
+		{Name: "up", Check: func() error { return nil }},
+		{Name: "down", Check: func() error { return errors.New("down") }},
+	}
+	ss := NewStatusService(sr, ir, newAccessControlServiceTest(t, tx), components)
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	createAdmin := func(t *testing.T) schemas.User {
+		adminId, err := ur.CreateUser(tx, &models.User{
+			Name:         "Test Admin",
+			Surname:      "Test Surname",
+			Email:        "admin@email.com",
+			Username:     "testadmin",
+			PasswordHash: "password",
+			Role:         models.UserRoleAdmin,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return schemas.User{Id: adminId, Role: string(models.UserRoleAdmin)}
+	}
+
+	t.Run("Incident lifecycle", func(t *testing.T) {
+		admin := createAdmin(t)
+		incident, err := ss.CreateIncident(tx, admin, schemas.IncidentCreate{
+			Title:   "Judging delays",
+			Status:  string(models.IncidentStatusInvestigating),
+			Message: "Submissions are judged slowly",
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Nil(t, incident.ResolvedAt)
+		assert.Len(t, incident.Updates, 1)
+
+		incident, err = ss.AddIncidentUpdate(tx, admin, incident.Id, schemas.IncidentUpdateCreate{
+			Status:  string(models.IncidentStatusResolved),
+			Message: "Workers were scaled up",
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.IncidentStatusResolved), incident.Status)
+		assert.NotNil(t, incident.ResolvedAt)
+		if assert.Len(t, incident.Updates, 2) {
+			assert.Equal(t, "Workers were scaled up", incident.Updates[0].Message)
+		}
+
+		status, err := ss.GetStatus(tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []schemas.ComponentStatus{
+			{Name: "up", Status: ComponentStatusOperational},
+			{Name: "down", Status: ComponentStatusUnavailable},
+		}, status.Components)
+		assert.Equal(t, JudgeLatencyPercentile, status.JudgeLatency.Percentile)
+		if assert.NotEmpty(t, status.Incidents) {
+			assert.Equal(t, incident.Id, status.Incidents[0].Id)
+		}
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		student := schemas.User{Role: string(models.UserRoleStudent)}
+		_, err := ss.CreateIncident(tx, student, schemas.IncidentCreate{Title: "t", Status: "resolved", Message: "m"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = ss.AddIncidentUpdate(tx, student, 1, schemas.IncidentUpdateCreate{Status: "resolved", Message: "m"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Capacity simulation", func(t *testing.T) {
+		admin := createAdmin(t)
+		taskId, err := tr.Create(tx, models.Task{Title: "Test Task", CreatedBy: admin.Id})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: "c", Version: "99"}
+		if !assert.NoError(t, tx.Create(language).Error) {
+			t.FailNow()
+		}
+		// Judged in 8 seconds, 2 seconds per test
+		checkedAt := time.Now()
+		submissionId, err := sr.CreateSubmission(tx, models.Submission{
+			TaskId:      taskId,
+			UserId:      admin.Id,
+			Order:       1,
+			LanguageId:  language.Id,
+			Status:      "completed",
+			SubmittedAt: checkedAt.Add(-8 * time.Second),
+			CheckedAt:   &checkedAt,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = srr.CreateSubmissionResult(tx, models.SubmissionResult{SubmissionId: submissionId, Code: "1", TotalTests: 4})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		request := schemas.CapacitySimulationRequest{
+			Participants:         60,
+			SubmissionRate:       6,
+			DurationMinutes:      60,
+			Workers:              1,
+			TestsPerSubmission:   2,
+			TargetLatencySeconds: 4.5,
+		}
+		simulation, err := ss.SimulateCapacity(tx, admin, request)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NotZero(t, simulation.Submissions)
+		assert.InDelta(t, 4, simulation.MeanServiceSeconds, 0.01)
+		assert.InDelta(t, 0.4, simulation.Utilization, 0.01)
+		assert.GreaterOrEqual(t, simulation.Latency.P95, 4.0)
+		if assert.NotNil(t, simulation.RecommendedWorkers) {
+			assert.Greater(t, *simulation.RecommendedWorkers, 1)
+		}
+
+		request.Participants = 100000
+		request.SubmissionRate = 600
+		request.DurationMinutes = 1440
+		_, err = ss.SimulateCapacity(tx, admin, request)
+		assert.ErrorIs(t, err, ErrSimulationTooLarge)
+
+		_, err = ss.SimulateCapacity(tx, schemas.User{Role: string(models.UserRoleTeacher)}, request)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Incident not found", func(t *testing.T) {
+		admin := createAdmin(t)
+		_, err := ss.AddIncidentUpdate(tx, admin, -1, schemas.IncidentUpdateCreate{Status: "resolved", Message: "m"})
+		assert.ErrorIs(t, err, ErrIncidentNotFound)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
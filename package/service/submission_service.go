@@ -1,26 +1,89 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"time"
+
 	"github.com/mini-maxit/backend/internal/logger"
 	"github.com/mini-maxit/backend/package/domain/models"
 	"github.com/mini-maxit/backend/package/domain/schemas"
 	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+var ErrNoSubmissions = fmt.Errorf("no submissions found")
+var ErrSubmissionNotFound = fmt.Errorf("submission not found")
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+
+const DefaultScoreRecomputeBatchSize = 1000
+
 type SubmissionService interface {
 	MarkSubmissionFailed(tx *gorm.DB, submissionId int64, errorMsg string) error
 	MarkSubmissionComplete(tx *gorm.DB, submissionId int64) error
 	MarkSubmissionProcessing(tx *gorm.DB, submissionId int64) error
 	CreateSubmissionResult(tx *gorm.DB, submissionId int64, responseMessage schemas.ResponseMessage) (int64, error)
+	GetAllForTaskAndUser(tx *gorm.DB, taskId int64, userId int64) ([]schemas.Submission, error)
+	// GetSubmissionsByEnvironment returns submissions judged in the environment with their judge environment,
+	// to find submissions affected by a toolchain bug. Only teachers and admins can list them. Listings are paginated
+	// by offset, or by the returned cursor of the next page, which is empty on the last page
+	GetSubmissionsByEnvironment(tx *gorm.DB, currentUser schemas.User, filter schemas.JudgeEnvironmentFilter, limit int64, offset int64, cursor string) ([]schemas.Submission, string, error)
+	// ExportUserSubmissions returns a zip archive with sources and a verdict summary of all user submissions for a task
+	ExportUserSubmissions(tx *gorm.DB, taskId int64, userId int64) ([]byte, error)
+	// RecomputeScores recomputes aggregate scores of all submission results from their stored test results.
+	// Unless it is a dry run changed scores are saved. Only admins can recompute scores
+	RecomputeScores(tx *gorm.DB, currentUser schemas.User, request schemas.ScoreRecomputeRequest) (*schemas.ScoreRecomputeReport, error)
+	// RecomputeScoresBatch recomputes scores of at most batchSize results with id greater than afterId.
+	// Returns the id of the last processed result. Nothing is left to process once the report has no processed results
+	RecomputeScoresBatch(tx *gorm.DB, afterId int64, batchSize int, dryRun bool) (int64, *schemas.ScoreRecomputeReport, error)
+	// ReuseIdenticalResult completes the submission with a copy of the result of an already checked submission
+	// with identical source for the same task and language, judged with the current evaluation settings of the
+	// task and language. Returns false when there is no such submission or reuse is disabled, in which case the
+	// submission has to be published for evaluation
+	ReuseIdenticalResult(tx *gorm.DB, submissionId int64) (bool, error)
+	// RedactSubmission removes the source of the submission from file storage and clears result messages,
+	// which may quote it, and its plagiarism matches. Verdicts and scores are kept for statistics. Only admins and
+	// the task author can redact
+	RedactSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) error
+	// GetSubmission returns the submission with its result, or the tests reported so far while it is processing.
+	// Users can see their own submissions, teachers submissions of their tasks and admins all submissions
+	GetSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.Submission, error)
+	// GetSubmissionSource returns the source file of the submission with its language. It can be read by the same
+	// users as the submission. Archived sources are restored first
+	GetSubmissionSource(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.SubmissionSource, error)
+	// RecordTestProgress stores outcomes of tests reported by the worker before the submission is judged
+	RecordTestProgress(tx *gorm.DB, submissionId int64, responseMessage schemas.ResponseMessage) error
+	// GetSubmissionTimeline returns the lifecycle events of the submission from its creation to its result,
+	// to see where judging it was slow. Only admins and teachers of the task can see it
+	GetSubmissionTimeline(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.SubmissionTimeline, error)
 }
 
 type SubmissionServiceImpl struct {
 	submissionRepository       repository.SubmissionRepository
 	submissionResultRepository repository.SubmissionResultRepository
-	inputOutputRepository      repository.InputOutputRepository
-	testResultRepository       repository.TestResultRepository
+	taskRepository             repository.TaskRepository
+	coAuthorRepository         repository.TaskCoAuthorRepository
+	inputOutputRepository      repository.InputOutput
+	testResultRepository       repository.TestResult
+	manualGradeRepository      repository.ManualGradeRepository
+	testGroupRepository        repository.TestCaseGroupRepository
+	summaryRepository          repository.UserTaskSummaryRepository
+	verdictRepository          repository.TaskVerdictSummaryRepository
+	timelineRepository         repository.TimelineEventRepository
+	plagiarismRepository       repository.PlagiarismRepository
+	fileStorageService         FileStorageService
+	archiveService             ArchiveService
+	reuseIdenticalResults      bool
+	accessControlService       AccessControlService
 	logger                     *zap.SugaredLogger
 }
 
@@ -30,6 +93,20 @@ func (us *SubmissionServiceImpl) MarkSubmissionFailed(tx *gorm.DB, submissionId
 		us.logger.Errorf("Error marking submission failed: %v", err.Error())
 		return err
 	}
+	err = us.testResultRepository.ClearTestProgress(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error clearing test progress: %v", err.Error())
+		return err
+	}
+	err = us.timelineRepository.CreateEvents(tx, []models.TimelineEvent{{
+		SubmissionId: submissionId,
+		Type:         models.TimelineEventFailed,
+		Detail:       errorMsg,
+	}})
+	if err != nil {
+		us.logger.Errorf("Error creating timeline event: %v", err.Error())
+		return err
+	}
 
 	return nil
 }
@@ -61,10 +138,30 @@ func (us *SubmissionServiceImpl) CreateSubmissionResult(tx *gorm.DB, submissionI
 		return -1, err
 	}
 
+	groups, err := us.testGroupRepository.GetGroups(tx, submission.TaskId)
+	if err != nil {
+		us.logger.Errorf("Error getting test groups: %v", err.Error())
+		return -1, err
+	}
+
+	var passedTests int64
+	for _, testResult := range responseMessage.Result.TestResults {
+		if testResult.Passed {
+			passedTests++
+		}
+	}
+	totalTests := int64(len(responseMessage.Result.TestResults))
 	submissionResult := models.SubmissionResult{
 		SubmissionId: submissionId,
 		Code:         responseMessage.Result.Code,
 		Message:      responseMessage.Result.Message,
+		PassedTests:  passedTests,
+		TotalTests:   totalTests,
+		Score:        computeResultScore(responseMessage.Result.TestResults, groups),
+
+		WorkerVersion:      responseMessage.Result.WorkerVersion,
+		CompilerVersion:    responseMessage.Result.CompilerVersion,
+		SandboxImageDigest: responseMessage.Result.SandboxImageDigest,
 	}
 	id, err := us.submissionResultRepository.CreateSubmissionResult(tx, submissionResult)
 	if err != nil {
@@ -84,10 +181,603 @@ func (us *SubmissionServiceImpl) CreateSubmissionResult(tx *gorm.DB, submissionI
 			return -1, err
 		}
 	}
+	err = us.testResultRepository.ClearTestProgress(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error clearing test progress: %v", err.Error())
+		return -1, err
+	}
+	err = us.recordWorkerEvents(tx, submissionId, responseMessage, true)
+	if err != nil {
+		return -1, err
+	}
+	err = us.summaryRepository.Refresh(tx, submission.UserId, submission.TaskId)
+	if err != nil {
+		us.logger.Errorf("Error refreshing task summary: %v", err.Error())
+		return -1, err
+	}
+	err = us.verdictRepository.Refresh(tx, submission.TaskId)
+	if err != nil {
+		us.logger.Errorf("Error refreshing task verdict summary: %v", err.Error())
+		return -1, err
+	}
 
 	return id, nil
 }
 
+func (us *SubmissionServiceImpl) RecordTestProgress(tx *gorm.DB, submissionId int64, responseMessage schemas.ResponseMessage) error {
+	progress := make([]models.TestProgress, 0, len(responseMessage.Result.TestResults))
+	for _, testResult := range responseMessage.Result.TestResults {
+		progress = append(progress, models.TestProgress{
+			SubmissionId: submissionId,
+			Order:        testResult.Order,
+			Passed:       testResult.Passed,
+		})
+	}
+	err := us.testResultRepository.SaveTestProgress(tx, progress)
+	if err != nil {
+		us.logger.Errorf("Error saving test progress: %v", err.Error())
+		return err
+	}
+	return us.recordWorkerEvents(tx, submissionId, responseMessage, false)
+}
+
+// recordWorkerEvents records the events of a message of the worker. The first progress report picks the message up,
+// tests are recorded when they are first reported and the final message finalizes the submission. Tests the worker
+// did not report progress of are recorded at the time of the final message
+func (us *SubmissionServiceImpl) recordWorkerEvents(tx *gorm.DB, submissionId int64, responseMessage schemas.ResponseMessage, final bool) error {
+	recorded, err := us.timelineRepository.GetMessageEvents(tx, responseMessage.MessageId)
+	if err != nil {
+		us.logger.Errorf("Error getting timeline events: %v", err.Error())
+		return err
+	}
+	pickedUp := false
+	reported := make(map[int64]bool)
+	for _, event := range recorded {
+		switch event.Type {
+		case models.TimelineEventPickedUp:
+			pickedUp = true
+		case models.TimelineEventTest:
+			if event.TestOrder != nil {
+				reported[*event.TestOrder] = true
+			}
+		}
+	}
+
+	events := make([]models.TimelineEvent, 0, len(responseMessage.Result.TestResults)+1)
+	if !pickedUp && !final {
+		events = append(events, models.TimelineEvent{
+			SubmissionId: submissionId,
+			MessageId:    responseMessage.MessageId,
+			Type:         models.TimelineEventPickedUp,
+		})
+	}
+	var passedTests int64
+	for _, testResult := range responseMessage.Result.TestResults {
+		if testResult.Passed {
+			passedTests++
+		}
+		if reported[testResult.Order] {
+			continue
+		}
+		reported[testResult.Order] = true
+		detail := "passed"
+		if !testResult.Passed {
+			detail = "failed"
+			if testResult.ErrorMessage != "" {
+				detail += ": " + testResult.ErrorMessage
+			}
+		}
+		events = append(events, models.TimelineEvent{
+			SubmissionId: submissionId,
+			MessageId:    responseMessage.MessageId,
+			Type:         models.TimelineEventTest,
+			TestOrder:    &testResult.Order,
+			Detail:       detail,
+		})
+	}
+	if final {
+		events = append(events, models.TimelineEvent{
+			SubmissionId: submissionId,
+			MessageId:    responseMessage.MessageId,
+			Type:         models.TimelineEventFinalized,
+			Detail:       fmt.Sprintf("%s, %d of %d tests passed", responseMessage.Result.Code, passedTests, len(responseMessage.Result.TestResults)),
+		})
+	}
+	err = us.timelineRepository.CreateEvents(tx, events)
+	if err != nil {
+		us.logger.Errorf("Error creating timeline events: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (us *SubmissionServiceImpl) GetSubmissionTimeline(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.SubmissionTimeline, error) {
+	if !us.accessControlService.Can(currentUser, ResourceSubmission, ActionBrowse) {
+		return nil, ErrNotAuthorized
+	}
+	submission, err := us.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSubmissionNotFound
+		}
+		us.logger.Errorf("Error getting submission: %v", err.Error())
+		return nil, err
+	}
+	_, err = us.authorizeSubmissionRead(tx, currentUser, submission)
+	if err != nil {
+		return nil, err
+	}
+	events, err := us.timelineRepository.GetEvents(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error getting timeline events: %v", err.Error())
+		return nil, err
+	}
+
+	timeline := &schemas.SubmissionTimeline{
+		SubmissionId: submission.Id,
+		Status:       submission.Status,
+		Events:       make([]schemas.TimelineEvent, 0, len(events)+1),
+	}
+	// Creation is not recorded as an event, the submission keeps its time
+	timeline.Events = append(timeline.Events, schemas.TimelineEvent{
+		Type:      schemas.TimelineEventCreated,
+		CreatedAt: submission.SubmittedAt,
+	})
+	for _, event := range events {
+		timeline.Events = append(timeline.Events, schemas.TimelineEvent{
+			Type:      string(event.Type),
+			MessageId: event.MessageId,
+			TestOrder: event.TestOrder,
+			Detail:    event.Detail,
+			CreatedAt: event.CreatedAt,
+			ElapsedMs: event.CreatedAt.Sub(submission.SubmittedAt).Milliseconds(),
+		})
+	}
+	return timeline, nil
+}
+
+func (us *SubmissionServiceImpl) GetSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.Submission, error) {
+	submission, err := us.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSubmissionNotFound
+		}
+		us.logger.Errorf("Error getting submission: %v", err.Error())
+		return nil, err
+	}
+	isTeacherOrAdmin, err := us.authorizeSubmissionRead(tx, currentUser, submission)
+	if err != nil {
+		return nil, err
+	}
+	return us.modelToSchema(tx, submission, isTeacherOrAdmin)
+}
+
+func (us *SubmissionServiceImpl) GetSubmissionSource(tx *gorm.DB, currentUser schemas.User, submissionId int64) (*schemas.SubmissionSource, error) {
+	submission, err := us.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSubmissionNotFound
+		}
+		us.logger.Errorf("Error getting submission: %v", err.Error())
+		return nil, err
+	}
+	_, err = us.authorizeSubmissionRead(tx, currentUser, submission)
+	if err != nil {
+		return nil, err
+	}
+	if submission.RedactedAt != nil {
+		return nil, ErrSubmissionRedacted
+	}
+	if submission.PrunedAt != nil {
+		return nil, ErrSubmissionPruned
+	}
+
+	err = us.archiveService.RestoreSubmission(tx, submission)
+	if err != nil {
+		return nil, err
+	}
+	content, fileName, err := us.fileStorageService.GetUserSolution(submission.TaskId, submission.UserId, submission.Order)
+	if err != nil {
+		if err != ErrFileNotFound {
+			us.logger.Errorf("Error getting source of submission %d: %v", submission.Id, err.Error())
+		}
+		return nil, err
+	}
+
+	return &schemas.SubmissionSource{
+		FileName:        filepath.Base(fileName),
+		ContentType:     submission.Language.Type.ContentType(),
+		Language:        string(submission.Language.Type),
+		LanguageVersion: submission.Language.Version,
+		Content:         content,
+	}, nil
+}
+
+// authorizeSubmissionRead checks that the user can read the submission. Users can read their own submissions, and
+// users who may browse submissions those of tasks they created or co-author, or all of them. Reports whether the
+// user reads it as a reviewer
+func (us *SubmissionServiceImpl) authorizeSubmissionRead(tx *gorm.DB, currentUser schemas.User, submission *models.Submission) (bool, error) {
+	switch {
+	case us.accessControlService.CanAll(currentUser, ResourceSubmission, ActionBrowse):
+		return true, nil
+	case us.accessControlService.Can(currentUser, ResourceSubmission, ActionBrowse) && submission.UserId != currentUser.Id:
+		task, err := us.taskRepository.GetTask(tx, submission.TaskId)
+		if err != nil {
+			us.logger.Errorf("Error getting task: %v", err.Error())
+			return false, err
+		}
+		if task.CreatedBy == currentUser.Id {
+			return true, nil
+		}
+		isCoAuthor, err := us.coAuthorRepository.IsCoAuthor(tx, submission.TaskId, currentUser.Id)
+		if err != nil {
+			us.logger.Errorf("Error checking task co-author: %v", err.Error())
+			return false, err
+		}
+		if !isCoAuthor {
+			return false, ErrNotAuthorized
+		}
+		return true, nil
+	case submission.UserId != currentUser.Id:
+		return false, ErrNotAuthorized
+	}
+	return false, nil
+}
+
+func (us *SubmissionServiceImpl) ReuseIdenticalResult(tx *gorm.DB, submissionId int64) (bool, error) {
+	if !us.reuseIdenticalResults {
+		return false, nil
+	}
+
+	submission, err := us.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error getting submission: %v", err.Error())
+		return false, err
+	}
+	if submission.SourceHash == "" {
+		return false, nil
+	}
+	submission.EvaluationHash, err = evaluationHash(tx, us.taskRepository, submission)
+	if err != nil {
+		us.logger.Errorf("Error computing evaluation hash: %v", err.Error())
+		return false, err
+	}
+	identical, err := us.submissionRepository.GetCompletedIdenticalSubmission(tx, submission)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		us.logger.Errorf("Error getting identical submission: %v", err.Error())
+		return false, err
+	}
+	identicalResult, err := us.submissionResultRepository.GetSubmissionResultBySubmissionId(tx, identical.Id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		us.logger.Errorf("Error getting submission result: %v", err.Error())
+		return false, err
+	}
+	testResults, err := us.testResultRepository.GetTestResultsBySubmissionResult(tx, identicalResult)
+	if err != nil {
+		us.logger.Errorf("Error getting test results: %v", err.Error())
+		return false, err
+	}
+
+	resultId, err := us.submissionResultRepository.CreateSubmissionResult(tx, models.SubmissionResult{
+		SubmissionId: submissionId,
+		Code:         identicalResult.Code,
+		Message:      identicalResult.Message,
+		PassedTests:  identicalResult.PassedTests,
+		TotalTests:   identicalResult.TotalTests,
+		Score:        identicalResult.Score,
+
+		WorkerVersion:      identicalResult.WorkerVersion,
+		CompilerVersion:    identicalResult.CompilerVersion,
+		SandboxImageDigest: identicalResult.SandboxImageDigest,
+	})
+	if err != nil {
+		us.logger.Errorf("Error creating submission result: %v", err.Error())
+		return false, err
+	}
+	for _, testResult := range testResults {
+		err = us.testResultRepository.CreateTestResults(tx, models.TestResult{
+			SubmissionResultId: resultId,
+			InputOutputId:      testResult.InputOutputId,
+			Passed:             testResult.Passed,
+			ErrorMessage:       testResult.ErrorMessage,
+		})
+		if err != nil {
+			us.logger.Errorf("Error creating test result: %v", err.Error())
+			return false, err
+		}
+	}
+	// The copied result holds for later identical submissions judged with the same settings
+	err = us.submissionRepository.SetEvaluationHash(tx, submissionId, submission.EvaluationHash)
+	if err != nil {
+		us.logger.Errorf("Error saving evaluation hash: %v", err.Error())
+		return false, err
+	}
+	err = us.submissionRepository.MarkSubmissionComplete(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error marking submission complete: %v", err.Error())
+		return false, err
+	}
+	err = us.summaryRepository.Refresh(tx, submission.UserId, submission.TaskId)
+	if err != nil {
+		us.logger.Errorf("Error refreshing task summary: %v", err.Error())
+		return false, err
+	}
+	err = us.verdictRepository.Refresh(tx, submission.TaskId)
+	if err != nil {
+		us.logger.Errorf("Error refreshing task verdict summary: %v", err.Error())
+		return false, err
+	}
+
+	err = us.timelineRepository.CreateEvents(tx, []models.TimelineEvent{{
+		SubmissionId: submissionId,
+		Type:         models.TimelineEventFinalized,
+		Detail:       fmt.Sprintf("Result reused from identical submission %d", identical.Id),
+	}})
+	if err != nil {
+		us.logger.Errorf("Error creating timeline event: %v", err.Error())
+		return false, err
+	}
+
+	us.logger.Infof("Submission %d reused the result of identical submission %d", submissionId, identical.Id)
+	return true, nil
+}
+
+// evaluationHash returns the hex encoded SHA-256 of the settings the submission is evaluated with: the language
+// configuration, the evaluation policy of the task and the limits of its tests. Results are only reused between
+// submissions with the same hash, so a change of any of these settings stops reuse of earlier verdicts
+func evaluationHash(tx *gorm.DB, taskRepository repository.TaskRepository, submission *models.Submission) (string, error) {
+	task, err := taskRepository.GetTask(tx, submission.TaskId)
+	if err != nil {
+		return "", err
+	}
+	timeLimits, err := taskRepository.GetTaskTimeLimits(tx, submission.TaskId)
+	if err != nil {
+		return "", err
+	}
+	memoryLimits, err := taskRepository.GetTaskMemoryLimits(tx, submission.TaskId)
+	if err != nil {
+		return "", err
+	}
+	language := submission.Language
+	hash := sha256.New()
+	fmt.Fprintln(hash, language.Type, language.Version, language.CompilerFlags, language.RunArgs, language.TimeMultiplier, language.MemoryMultiplier)
+	fmt.Fprintln(hash, formatLimit(task.OutputLimit), formatLimit(task.StderrLimit), formatLimit(task.ProcessLimit))
+	fmt.Fprintln(hash, timeLimits, memoryLimits)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (us *SubmissionServiceImpl) RedactSubmission(tx *gorm.DB, currentUser schemas.User, submissionId int64) error {
+	submission, err := us.submissionRepository.GetSubmission(tx, submissionId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrSubmissionNotFound
+		}
+		us.logger.Errorf("Error getting submission: %v", err.Error())
+		return err
+	}
+	if !us.accessControlService.CanAll(currentUser, ResourceSubmission, ActionRedact) {
+		task, err := us.taskRepository.GetTask(tx, submission.TaskId)
+		if err != nil {
+			us.logger.Errorf("Error getting task: %v", err.Error())
+			return err
+		}
+		if !us.accessControlService.CanAccess(currentUser, ResourceSubmission, ActionRedact, task.CreatedBy) {
+			return ErrNotAuthorized
+		}
+	}
+	if submission.RedactedAt != nil {
+		return nil
+	}
+
+	err = us.submissionRepository.RedactSubmission(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error redacting submission: %v", err.Error())
+		return err
+	}
+	err = us.submissionResultRepository.ClearMessages(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error clearing submission result messages: %v", err.Error())
+		return err
+	}
+	err = us.testResultRepository.ClearErrorMessages(tx, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error clearing test result error messages: %v", err.Error())
+		return err
+	}
+	// Matches would still show the similarity to the removed source
+	err = us.plagiarismRepository.DeleteSubmissionMatches(tx, submission.TaskId, submissionId)
+	if err != nil {
+		us.logger.Errorf("Error deleting plagiarism matches: %v", err.Error())
+		return err
+	}
+	// Removed last, so a failure leaves the transaction to roll back with the source still in place
+	err = us.fileStorageService.DeleteUserSolution(submission.TaskId, submission.UserId, submission.Order)
+	if err != nil && err != ErrFileNotFound {
+		us.logger.Errorf("Error deleting source of submission %d: %v", submissionId, err.Error())
+		return err
+	}
+
+	us.logger.Infof("Submission %d redacted by user %d", submissionId, currentUser.Id)
+	return nil
+}
+
+func (us *SubmissionServiceImpl) RecomputeScores(tx *gorm.DB, currentUser schemas.User, request schemas.ScoreRecomputeRequest) (*schemas.ScoreRecomputeReport, error) {
+	if !us.accessControlService.Can(currentUser, ResourceSubmission, ActionRecompute) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(request); err != nil {
+		us.logger.Errorf("Error validating score recompute request: %v", err.Error())
+		return nil, err
+	}
+
+	dryRun := request.DryRun == nil || *request.DryRun
+	batchSize := request.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultScoreRecomputeBatchSize
+	}
+
+	report := &schemas.ScoreRecomputeReport{DryRun: dryRun}
+	afterId := int64(0)
+	for {
+		lastId, batch, err := us.RecomputeScoresBatch(tx, afterId, batchSize, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Processed == 0 {
+			break
+		}
+		report.Processed += batch.Processed
+		report.Changed += batch.Changed
+		afterId = lastId
+	}
+
+	us.logger.Infof("Recomputed scores of %d submission results, %d changed (dry run: %v)", report.Processed, report.Changed, dryRun)
+	return report, nil
+}
+
+func (us *SubmissionServiceImpl) RecomputeScoresBatch(tx *gorm.DB, afterId int64, batchSize int, dryRun bool) (int64, *schemas.ScoreRecomputeReport, error) {
+	submissionResults, err := us.submissionResultRepository.GetSubmissionResultsAfter(tx, afterId, batchSize)
+	if err != nil {
+		us.logger.Errorf("Error getting submission results: %v", err.Error())
+		return afterId, nil, err
+	}
+	report := &schemas.ScoreRecomputeReport{DryRun: dryRun}
+	if len(submissionResults) == 0 {
+		return afterId, report, nil
+	}
+
+	ids := make([]int64, 0, len(submissionResults))
+	createdAfter := submissionResults[0].CreatedAt
+	for _, submissionResult := range submissionResults {
+		ids = append(ids, submissionResult.Id)
+		if submissionResult.CreatedAt.Before(createdAfter) {
+			createdAfter = submissionResult.CreatedAt
+		}
+	}
+	testResults, err := us.testResultRepository.GetTestResultsBySubmissionResultIds(tx, ids, createdAfter)
+	if err != nil {
+		us.logger.Errorf("Error getting test results: %v", err.Error())
+		return afterId, nil, err
+	}
+	taskIds := make([]int64, 0, len(submissionResults))
+	for _, submissionResult := range submissionResults {
+		taskIds = append(taskIds, submissionResult.Submission.TaskId)
+	}
+	groups, err := us.testGroupRepository.GetGroupsForTasks(tx, taskIds)
+	if err != nil {
+		us.logger.Errorf("Error getting test groups: %v", err.Error())
+		return afterId, nil, err
+	}
+	passedTests := make(map[int64]int64, len(submissionResults))
+	totalTests := make(map[int64]int64, len(submissionResults))
+	passedByOrder := make(map[int64]map[int64]bool, len(submissionResults))
+	for _, testResult := range testResults {
+		totalTests[testResult.SubmissionResultId]++
+		if testResult.Passed {
+			passedTests[testResult.SubmissionResultId]++
+		}
+		if passedByOrder[testResult.SubmissionResultId] == nil {
+			passedByOrder[testResult.SubmissionResultId] = make(map[int64]bool)
+		}
+		passedByOrder[testResult.SubmissionResultId][int64(testResult.InputOutput.Order)] = testResult.Passed
+	}
+
+	// Summaries of users and tasks with changed scores, refreshed once per batch
+	changed := make(map[[2]int64]bool)
+	for _, submissionResult := range submissionResults {
+		passed := passedTests[submissionResult.Id]
+		total := totalTests[submissionResult.Id]
+		score := computeScore(passed, total)
+		if taskGroups := groups[submissionResult.Submission.TaskId]; len(taskGroups) > 0 {
+			score = computeGroupScore(taskGroups, passedByOrder[submissionResult.Id])
+		}
+		report.Processed++
+		if submissionResult.PassedTests == passed && submissionResult.TotalTests == total && submissionResult.Score == score {
+			continue
+		}
+		report.Changed++
+		if dryRun {
+			continue
+		}
+		submissionResult.PassedTests = passed
+		submissionResult.TotalTests = total
+		submissionResult.Score = score
+		err = us.submissionResultRepository.UpdateSubmissionResultScore(tx, &submissionResult)
+		if err != nil {
+			us.logger.Errorf("Error updating submission result score: %v", err.Error())
+			return afterId, nil, err
+		}
+		changed[[2]int64{submissionResult.Submission.UserId, submissionResult.Submission.TaskId}] = true
+	}
+	changedTasks := make(map[int64]bool)
+	for key := range changed {
+		err = us.summaryRepository.Refresh(tx, key[0], key[1])
+		if err != nil {
+			us.logger.Errorf("Error refreshing task summary: %v", err.Error())
+			return afterId, nil, err
+		}
+		changedTasks[key[1]] = true
+	}
+	for taskId := range changedTasks {
+		err = us.verdictRepository.Refresh(tx, taskId)
+		if err != nil {
+			us.logger.Errorf("Error refreshing task verdict summary: %v", err.Error())
+			return afterId, nil, err
+		}
+	}
+
+	return ids[len(ids)-1], report, nil
+}
+
+// computeScore returns the percentage of passed tests rounded to two decimal places
+func computeScore(passedTests int64, totalTests int64) float64 {
+	if totalTests == 0 {
+		return 0
+	}
+	return math.Round(float64(passedTests)*10000/float64(totalTests)) / 100
+}
+
+// computeGroupScore returns the percentage of points of test groups whose tests all passed rounded to two
+// decimal places. passedByOrder has the outcome of each judged test by its order. Tests outside of groups are not scored
+func computeGroupScore(groups []models.TestCaseGroup, passedByOrder map[int64]bool) float64 {
+	var points, totalPoints int64
+	for _, group := range groups {
+		totalPoints += group.Points
+		passed := true
+		for _, test := range group.Tests {
+			passed = passed && passedByOrder[test.Order]
+		}
+		if passed {
+			points += group.Points
+		}
+	}
+	return computeScore(points, totalPoints)
+}
+
+// computeResultScore scores test results reported by the worker, by points of test groups when the task has them
+func computeResultScore(testResults []schemas.TestResult, groups []models.TestCaseGroup) float64 {
+	var passedTests int64
+	passedByOrder := make(map[int64]bool, len(testResults))
+	for _, testResult := range testResults {
+		if testResult.Passed {
+			passedTests++
+		}
+		passedByOrder[testResult.Order] = testResult.Passed
+	}
+	if len(groups) > 0 {
+		return computeGroupScore(groups, passedByOrder)
+	}
+	return computeScore(passedTests, int64(len(testResults)))
+}
+
 func (us *SubmissionServiceImpl) createTestResult(tx *gorm.DB, submissionResultId int64, inputOutputId int64, testResult schemas.TestResult) error {
 	testResultModel := models.TestResult{
 		SubmissionResultId: submissionResultId,
@@ -98,11 +788,323 @@ func (us *SubmissionServiceImpl) createTestResult(tx *gorm.DB, submissionResultI
 	return us.testResultRepository.CreateTestResults(tx, testResultModel)
 }
 
-func NewSubmissionService(submissionRepository repository.SubmissionRepository, submissionResultRepository repository.SubmissionResultRepository) SubmissionService {
+func (us *SubmissionServiceImpl) GetAllForTaskAndUser(tx *gorm.DB, taskId int64, userId int64) ([]schemas.Submission, error) {
+	submissions, err := us.submissionRepository.GetAllForTaskAndUser(tx, taskId, userId)
+	if err != nil {
+		us.logger.Errorf("Error getting submissions: %v", err.Error())
+		return nil, err
+	}
+
+	return us.modelsToSchemas(tx, submissions, false)
+}
+
+// submissionCursor is the position encoded in cursors of submission listings
+type submissionCursor struct {
+	SubmittedAt time.Time `json:"t"`
+	Id          int64     `json:"i"`
+}
+
+func (us *SubmissionServiceImpl) GetSubmissionsByEnvironment(tx *gorm.DB, currentUser schemas.User, filter schemas.JudgeEnvironmentFilter, limit int64, offset int64, cursor string) ([]schemas.Submission, string, error) {
+	if !us.accessControlService.Can(currentUser, ResourceSubmission, ActionBrowse) {
+		return nil, "", ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(filter); err != nil {
+		us.logger.Errorf("Error validating judge environment filter: %v", err.Error())
+		return nil, "", err
+	}
+	var after *models.SubmissionKey
+	if cursor != "" {
+		position := submissionCursor{}
+		if err := utils.DecodeCursor(cursor, &position); err != nil || position.Id == 0 {
+			return nil, "", ErrInvalidCursor
+		}
+		after = &models.SubmissionKey{SubmittedAt: position.SubmittedAt, Id: position.Id}
+	}
+
+	// One more submission than the limit tells whether there is a next page
+	submissions, err := us.submissionRepository.GetSubmissionsByEnvironment(tx, models.JudgeEnvironment{
+		WorkerVersion:      filter.WorkerVersion,
+		CompilerVersion:    filter.CompilerVersion,
+		SandboxImageDigest: filter.SandboxImageDigest,
+	}, after, limit+1, offset)
+	if err != nil {
+		us.logger.Errorf("Error getting submissions: %v", err.Error())
+		return nil, "", err
+	}
+	nextCursor := ""
+	if int64(len(submissions)) > limit {
+		submissions = submissions[:limit]
+		last := submissions[len(submissions)-1]
+		nextCursor, err = utils.EncodeCursor(submissionCursor{SubmittedAt: last.SubmittedAt, Id: last.Id})
+		if err != nil {
+			us.logger.Errorf("Error encoding submission cursor: %v", err.Error())
+			return nil, "", err
+		}
+	}
+
+	result, err := us.modelsToSchemas(tx, submissions, true)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, nextCursor, nil
+}
+
+func (us *SubmissionServiceImpl) ExportUserSubmissions(tx *gorm.DB, taskId int64, userId int64) ([]byte, error) {
+	submissions, err := us.GetAllForTaskAndUser(tx, taskId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(submissions) == 0 {
+		return nil, ErrNoSubmissions
+	}
+	// Archived sources have to be restored before they can be read
+	stored, err := us.submissionRepository.GetAllForTaskAndUser(tx, taskId, userId)
+	if err != nil {
+		us.logger.Errorf("Error getting submissions: %v", err.Error())
+		return nil, err
+	}
+	for i := range stored {
+		if err := us.archiveService.RestoreSubmission(tx, &stored[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	archive := zip.NewWriter(buffer)
+	for _, submission := range submissions {
+		if submission.Redacted || submission.Pruned {
+			continue
+		}
+		source, fileName, err := us.fileStorageService.GetUserSolution(taskId, userId, submission.Order)
+		if err != nil {
+			us.logger.Errorf("Error getting source of submission %d: %v", submission.Id, err.Error())
+			return nil, err
+		}
+		file, err := archive.Create(fmt.Sprintf("submission_%d/%s", submission.Order, filepath.Base(fileName)))
+		if err != nil {
+			us.logger.Errorf("Error adding source to archive: %v", err.Error())
+			return nil, err
+		}
+		if _, err := file.Write(source); err != nil {
+			us.logger.Errorf("Error writing source to archive: %v", err.Error())
+			return nil, err
+		}
+	}
+
+	summary, err := archive.Create("summary.json")
+	if err != nil {
+		us.logger.Errorf("Error adding summary to archive: %v", err.Error())
+		return nil, err
+	}
+	encoder := json.NewEncoder(summary)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(submissions); err != nil {
+		us.logger.Errorf("Error writing summary to archive: %v", err.Error())
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		us.logger.Errorf("Error closing archive: %v", err.Error())
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// modelToSchema converts the submission with its latest result. The judge environment is only included when withEnvironment is set
+// modelToSchema converts the submission with its result. Teachers and admins see every test and the judge environment,
+// other users the tests the test visibility of the task shows
+func (us *SubmissionServiceImpl) modelToSchema(tx *gorm.DB, submission *models.Submission, isTeacherOrAdmin bool) (*schemas.Submission, error) {
+	result, err := us.modelsToSchemas(tx, []models.Submission{*submission}, isTeacherOrAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// modelsToSchemas loads progress, grades and results of a page of submissions with one query each,
+// instead of a query each per submission
+func (us *SubmissionServiceImpl) modelsToSchemas(tx *gorm.DB, submissions []models.Submission, isTeacherOrAdmin bool) ([]schemas.Submission, error) {
+	result := make([]schemas.Submission, 0, len(submissions))
+	if len(submissions) == 0 {
+		return result, nil
+	}
+	submissionIds := make([]int64, 0, len(submissions))
+	processingIds := make([]int64, 0)
+	taskIds := make([]int64, 0)
+	for _, submission := range submissions {
+		submissionIds = append(submissionIds, submission.Id)
+		if submission.Status == "processing" {
+			processingIds = append(processingIds, submission.Id)
+		}
+		if !slices.Contains(taskIds, submission.TaskId) {
+			taskIds = append(taskIds, submission.TaskId)
+		}
+	}
+
+	var visibilities map[int64]models.TestVisibility
+	if !isTeacherOrAdmin {
+		var err error
+		visibilities, err = us.taskRepository.GetTestVisibilities(tx, taskIds)
+		if err != nil {
+			us.logger.Errorf("Error getting test visibilities: %v", err.Error())
+			return nil, err
+		}
+	}
+
+	progress, err := us.testResultRepository.GetTestProgressForSubmissions(tx, processingIds)
+	if err != nil {
+		us.logger.Errorf("Error getting test progress: %v", err.Error())
+		return nil, err
+	}
+	progressBySubmission := make(map[int64][]models.TestProgress)
+	for _, testProgress := range progress {
+		progressBySubmission[testProgress.SubmissionId] = append(progressBySubmission[testProgress.SubmissionId], testProgress)
+	}
+
+	grades, err := us.manualGradeRepository.GetGrades(tx, submissionIds)
+	if err != nil {
+		us.logger.Errorf("Error getting manual grades: %v", err.Error())
+		return nil, err
+	}
+	gradeBySubmission := make(map[int64]*models.ManualGrade, len(grades))
+	for i := range grades {
+		gradeBySubmission[grades[i].SubmissionId] = &grades[i]
+	}
+
+	submissionResults, err := us.submissionResultRepository.GetLatestSubmissionResults(tx, submissionIds)
+	if err != nil {
+		us.logger.Errorf("Error getting submission results: %v", err.Error())
+		return nil, err
+	}
+	resultBySubmission := make(map[int64]*models.SubmissionResult, len(submissionResults))
+	testResultsByResult := make(map[int64][]models.TestResult, len(submissionResults))
+	if len(submissionResults) > 0 {
+		resultIds := make([]int64, 0, len(submissionResults))
+		createdAfter := submissionResults[0].CreatedAt
+		for i := range submissionResults {
+			resultBySubmission[submissionResults[i].SubmissionId] = &submissionResults[i]
+			resultIds = append(resultIds, submissionResults[i].Id)
+			if submissionResults[i].CreatedAt.Before(createdAfter) {
+				createdAfter = submissionResults[i].CreatedAt
+			}
+		}
+		testResults, err := us.testResultRepository.GetTestResultsBySubmissionResultIds(tx, resultIds, createdAfter)
+		if err != nil {
+			us.logger.Errorf("Error getting test results: %v", err.Error())
+			return nil, err
+		}
+		for _, testResult := range testResults {
+			testResultsByResult[testResult.SubmissionResultId] = append(testResultsByResult[testResult.SubmissionResultId], testResult)
+		}
+	}
+
+	for i := range submissions {
+		submission := &submissions[i]
+		submissionVisibility := models.TestVisibilityAll
+		if !isTeacherOrAdmin {
+			submissionVisibility = visibilities[submission.TaskId]
+		}
+		schema := schemas.Submission{
+			Id:     submission.Id,
+			TaskId: submission.TaskId,
+			UserId: submission.UserId,
+			Order:  submission.Order,
+			Language: schemas.LanguageConfig{
+				Language: string(submission.Language.Type),
+				Version:  submission.Language.Version,
+			},
+			Status:        submission.Status,
+			StatusMessage: submission.StatusMessage,
+			SubmittedAt:   submission.SubmittedAt,
+			CheckedAt:     submission.CheckedAt,
+			Redacted:      submission.RedactedAt != nil,
+			Pruned:        submission.PrunedAt != nil,
+		}
+		if submission.Status == "processing" && submissionVisibility != models.TestVisibilityHidden {
+			for _, testProgress := range progressBySubmission[submission.Id] {
+				schema.Progress = append(schema.Progress, schemas.SubmissionTestProgress{
+					Order:  testProgress.Order,
+					Passed: testProgress.Passed,
+				})
+				if submissionVisibility == models.TestVisibilityFirstFailed && !testProgress.Passed {
+					break
+				}
+			}
+		}
+		if grade, ok := gradeBySubmission[submission.Id]; ok {
+			schema.ManualGrade = &schemas.ManualGrade{
+				Score:     grade.Score,
+				Comment:   grade.Comment,
+				GradedBy:  grade.GradedBy,
+				UpdatedAt: grade.UpdatedAt,
+			}
+		}
+		if submissionResult, ok := resultBySubmission[submission.Id]; ok {
+			schema.Result = submissionResultToSchema(submissionResult, testResultsByResult[submissionResult.Id], submissionVisibility, isTeacherOrAdmin)
+		}
+		result = append(result, schema)
+	}
+	return result, nil
+}
+
+func submissionResultToSchema(submissionResult *models.SubmissionResult, testResults []models.TestResult, visibility models.TestVisibility, isTeacherOrAdmin bool) *schemas.SubmissionResult {
+	slices.SortFunc(testResults, func(a, b models.TestResult) int {
+		return a.InputOutput.Order - b.InputOutput.Order
+	})
+
+	result := &schemas.SubmissionResult{
+		Code:        submissionResult.Code,
+		Message:     submissionResult.Message,
+		PassedTests: submissionResult.PassedTests,
+		TotalTests:  submissionResult.TotalTests,
+		Score:       submissionResult.Score,
+		CreatedAt:   submissionResult.CreatedAt,
+		TestResults: make([]schemas.SubmissionTestResult, 0, len(testResults)),
+	}
+	if visibility == models.TestVisibilityHidden {
+		testResults = nil
+	}
+	for _, testResult := range testResults {
+		result.TestResults = append(result.TestResults, schemas.SubmissionTestResult{
+			Order:        testResult.InputOutput.Order,
+			Passed:       testResult.Passed,
+			ErrorMessage: testResult.ErrorMessage,
+		})
+		if visibility == models.TestVisibilityFirstFailed && !testResult.Passed {
+			break
+		}
+	}
+	if isTeacherOrAdmin {
+		result.Environment = &schemas.JudgeEnvironment{
+			WorkerVersion:      submissionResult.WorkerVersion,
+			CompilerVersion:    submissionResult.CompilerVersion,
+			SandboxImageDigest: submissionResult.SandboxImageDigest,
+		}
+	}
+	return result
+}
+
+func NewSubmissionService(submissionRepository repository.SubmissionRepository, submissionResultRepository repository.SubmissionResultRepository, taskRepository repository.TaskRepository, coAuthorRepository repository.TaskCoAuthorRepository, inputOutputRepository repository.InputOutput, testResultRepository repository.TestResult, manualGradeRepository repository.ManualGradeRepository, testGroupRepository repository.TestCaseGroupRepository, summaryRepository repository.UserTaskSummaryRepository, verdictRepository repository.TaskVerdictSummaryRepository, timelineRepository repository.TimelineEventRepository, plagiarismRepository repository.PlagiarismRepository, fileStorageService FileStorageService, archiveService ArchiveService, accessControlService AccessControlService, reuseIdenticalResults bool) SubmissionService {
 	log := logger.NewNamedLogger("submission_service")
 	return &SubmissionServiceImpl{
 		submissionRepository:       submissionRepository,
 		submissionResultRepository: submissionResultRepository,
+		taskRepository:             taskRepository,
+		coAuthorRepository:         coAuthorRepository,
+		inputOutputRepository:      inputOutputRepository,
+		testResultRepository:       testResultRepository,
+		manualGradeRepository:      manualGradeRepository,
+		testGroupRepository:        testGroupRepository,
+		summaryRepository:          summaryRepository,
+		verdictRepository:          verdictRepository,
+		timelineRepository:         timelineRepository,
+		plagiarismRepository:       plagiarismRepository,
+		fileStorageService:         fileStorageService,
+		archiveService:             archiveService,
+		reuseIdenticalResults:      reuseIdenticalResults,
+		accessControlService:       accessControlService,
 		logger:                     log,
 	}
 }
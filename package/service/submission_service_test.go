@@ -0,0 +1,1024 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fileStorageServiceStub struct {
+	deleted   []int64
+	archived  []int64
+	restored  []int64
+	taskFiles map[int64][]byte
+}
+
+func (fs *fileStorageServiceStub) GetUserSolution(taskId int64, userId int64, submissionNumber int64) ([]byte, string, error) {
+	return []byte(fmt.Sprintf("solution %d", submissionNumber)), "main.c", nil
+}
+
+func (fs *fileStorageServiceStub) DeleteUserSolution(taskId int64, userId int64, submissionNumber int64) error {
+	fs.deleted = append(fs.deleted, submissionNumber)
+	return nil
+}
+
+func (fs *fileStorageServiceStub) ArchiveUserSolution(taskId int64, userId int64, submissionNumber int64) error {
+	fs.archived = append(fs.archived, submissionNumber)
+	return nil
+}
+
+func (fs *fileStorageServiceStub) RestoreUserSolution(taskId int64, userId int64, submissionNumber int64) error {
+	fs.restored = append(fs.restored, submissionNumber)
+	return nil
+}
+
+func (fs *fileStorageServiceStub) GetTaskFiles(taskId int64) ([]byte, error) {
+	files, ok := fs.taskFiles[taskId]
+	if !ok {
+		return nil, ErrFileNotFound
+	}
+	return files, nil
+}
+
+func (fs *fileStorageServiceStub) CreateTask(taskId int64, archiveName string, archive []byte) error {
+	if fs.taskFiles == nil {
+		fs.taskFiles = map[int64][]byte{}
+	}
+	fs.taskFiles[taskId] = archive
+	return nil
+}
+
+type submissionServiceTest struct {
+	tx                *gorm.DB
+	ur                repository.UserRepository
+	tr                repository.TaskRepository
+	tcar              repository.TaskCoAuthorRepository
+	sr                repository.SubmissionRepository
+	pr                repository.PlagiarismRepository
+	mgr               repository.ManualGradeRepository
+	tgr               repository.TestCaseGroupRepository
+	utsr              repository.UserTaskSummaryRepository
+	fileStorage       *fileStorageServiceStub
+	submissionService SubmissionService
+	savePoint         string
+}
+
+func newSubmissionServiceTest(t *testing.T) *submissionServiceTest {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tcar, err := repository.NewTaskCoAuthorRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSubmissionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srr, err := repository.NewSubmissionResultRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ior, err := repository.NewInputOutputRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	trr, err := repository.NewTestResultRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	mgr, err := repository.NewManualGradeRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tgr, err := repository.NewTestCaseGroupRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	utsr, err := repository.NewUserTaskSummaryRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tvsr, err := repository.NewTaskVerdictSummaryRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ter, err := repository.NewTimelineEventRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pr, err := repository.NewPlagiarismRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	fileStorage := &fileStorageServiceStub{}
+	ss := NewSubmissionService(sr, srr, tr, tcar, ior, trr, mgr, tgr, utsr, tvsr, ter, pr, fileStorage, NewArchiveService(sr, fileStorage), newAccessControlServiceTest(t, tx), true)
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+	return &submissionServiceTest{
+		tx:                tx,
+		ur:                ur,
+		tr:                tr,
+		tcar:              tcar,
+		sr:                sr,
+		pr:                pr,
+		mgr:               mgr,
+		tgr:               tgr,
+		utsr:              utsr,
+		fileStorage:       fileStorage,
+		submissionService: ss,
+		savePoint:         savePoint,
+	}
+}
+
+// createSubmission creates a user, a task, a language and a single submission and returns ids of the task and the user
+func (sst *submissionServiceTest) createSubmission(t *testing.T) (int64, int64) {
+	userId, err := sst.ur.CreateUser(sst.tx, &models.User{
+		Name:         "Test User",
+		Surname:      "Test Surname",
+		Email:        "email@email.com",
+		Username:     "testuser",
+		PasswordHash: "password",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	taskId, err := sst.tr.Create(sst.tx, models.Task{
+		Title:     "Test Task",
+		CreatedBy: userId,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	language := &models.LanguageConfig{Type: "c", Version: "99"}
+	if !assert.NoError(t, sst.tx.Create(language).Error) {
+		t.FailNow()
+	}
+	_, err = sst.sr.CreateSubmission(sst.tx, models.Submission{
+		TaskId:     taskId,
+		UserId:     userId,
+		Order:      1,
+		LanguageId: language.Id,
+		Status:     "received",
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return taskId, userId
+}
+
+func (sst *submissionServiceTest) rollbackToSavePoint() {
+	sst.tx.RollbackTo(sst.savePoint)
+}
+
+func TestExportUserSubmissions(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	t.Run("Success", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		archive, err := sst.submissionService.ExportUserSubmissions(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		names := []string{}
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+		}
+		assert.ElementsMatch(t, []string{"submission_1/main.c", "summary.json"}, names)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("No submissions", func(t *testing.T) {
+		archive, err := sst.submissionService.ExportUserSubmissions(sst.tx, 0, 0)
+		assert.ErrorIs(t, err, ErrNoSubmissions)
+		assert.Nil(t, archive)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestRecomputeScores(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+
+	// createResult creates a submission result with a stale score and two tests, one of them passed
+	createResult := func(t *testing.T) int64 {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		result := &models.SubmissionResult{SubmissionId: submissions[0].Id, Code: "OK", Message: "stale", Score: 100}
+		if !assert.NoError(t, sst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+		for order, passed := range []bool{true, false} {
+			inputOutput := &models.InputOutput{TaskId: uint(taskId), Order: order + 1, TimeLimit: 1, MemoryLimit: 1}
+			if !assert.NoError(t, sst.tx.Create(inputOutput).Error) {
+				t.FailNow()
+			}
+			testResult := &models.TestResult{SubmissionResultId: result.Id, InputOutputId: int64(inputOutput.Id), Passed: passed}
+			if !assert.NoError(t, sst.tx.Create(testResult).Error) {
+				t.FailNow()
+			}
+		}
+		return result.Id
+	}
+
+	t.Run("Dry run by default", func(t *testing.T) {
+		resultId := createResult(t)
+		report, err := sst.submissionService.RecomputeScores(sst.tx, admin, schemas.ScoreRecomputeRequest{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.True(t, report.DryRun)
+		assert.GreaterOrEqual(t, report.Changed, int64(1))
+
+		var result models.SubmissionResult
+		assert.NoError(t, sst.tx.First(&result, resultId).Error)
+		assert.Equal(t, float64(100), result.Score)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Recompute", func(t *testing.T) {
+		resultId := createResult(t)
+		dryRun := false
+		_, err := sst.submissionService.RecomputeScores(sst.tx, admin, schemas.ScoreRecomputeRequest{DryRun: &dryRun, BatchSize: 1})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		var result models.SubmissionResult
+		assert.NoError(t, sst.tx.First(&result, resultId).Error)
+		assert.Equal(t, int64(1), result.PassedTests)
+		assert.Equal(t, int64(2), result.TotalTests)
+		assert.Equal(t, float64(50), result.Score)
+
+		report, err := sst.submissionService.RecomputeScores(sst.tx, admin, schemas.ScoreRecomputeRequest{DryRun: &dryRun})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), report.Changed)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Recompute by test groups", func(t *testing.T) {
+		resultId := createResult(t)
+		var result models.SubmissionResult
+		if !assert.NoError(t, sst.tx.Preload("Submission").First(&result, resultId).Error) {
+			t.FailNow()
+		}
+		taskId := result.Submission.TaskId
+		err := sst.tgr.ReplaceGroups(sst.tx, taskId, []models.TestCaseGroup{
+			{TaskId: taskId, Name: "Small", Position: 0, Points: 30, Tests: []models.TestCaseGroupTest{{TaskId: taskId, Order: 1}}},
+			{TaskId: taskId, Name: "Large", Position: 1, Points: 70, Tests: []models.TestCaseGroupTest{{TaskId: taskId, Order: 2}}},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		dryRun := false
+		_, err = sst.submissionService.RecomputeScores(sst.tx, admin, schemas.ScoreRecomputeRequest{DryRun: &dryRun})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		assert.NoError(t, sst.tx.First(&result, resultId).Error)
+		assert.Equal(t, int64(1), result.PassedTests)
+		assert.Equal(t, float64(30), result.Score)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		report, err := sst.submissionService.RecomputeScores(sst.tx, schemas.User{Role: string(models.UserRoleStudent)}, schemas.ScoreRecomputeRequest{})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		assert.Nil(t, report)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestReuseIdenticalResult(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	// createIdentical completes the submission of createSubmission with a result and adds a second
+	// received submission with the same source. Returns the id of the second submission
+	createIdentical := func(t *testing.T, sourceHash string) int64 {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		checked := submissions[0]
+		inputOutput := &models.InputOutput{TaskId: uint(taskId), Order: 1, TimeLimit: 1, MemoryLimit: 1}
+		if !assert.NoError(t, sst.tx.Create(inputOutput).Error) {
+			t.FailNow()
+		}
+		hash, err := evaluationHash(sst.tx, sst.tr, &checked)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, sst.tx.Model(&checked).Updates(map[string]interface{}{"source_hash": "hash", "evaluation_hash": hash, "status": "completed"}).Error) {
+			t.FailNow()
+		}
+		result := &models.SubmissionResult{SubmissionId: checked.Id, Code: "OK", Message: "checked", PassedTests: 1, TotalTests: 1, Score: 100}
+		if !assert.NoError(t, sst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+		testResult := &models.TestResult{SubmissionResultId: result.Id, InputOutputId: int64(inputOutput.Id), Passed: true}
+		if !assert.NoError(t, sst.tx.Create(testResult).Error) {
+			t.FailNow()
+		}
+
+		submissionId, err := sst.sr.CreateSubmission(sst.tx, models.Submission{
+			TaskId:     taskId,
+			UserId:     userId,
+			Order:      2,
+			LanguageId: checked.LanguageId,
+			Status:     "received",
+			SourceHash: sourceHash,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return submissionId
+	}
+
+	t.Run("Identical source", func(t *testing.T) {
+		submissionId := createIdentical(t, "hash")
+		reused, err := sst.submissionService.ReuseIdenticalResult(sst.tx, submissionId)
+		if !assert.NoError(t, err) || !assert.True(t, reused) {
+			t.FailNow()
+		}
+
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.Equal(t, "completed", submission.Status)
+		var result models.SubmissionResult
+		assert.NoError(t, sst.tx.Where("submission_id = ?", submissionId).First(&result).Error)
+		assert.Equal(t, "OK", result.Code)
+		assert.Equal(t, float64(100), result.Score)
+		var testResults int64
+		assert.NoError(t, sst.tx.Model(&models.TestResult{}).Where("submission_result_id = ?", result.Id).Count(&testResults).Error)
+		assert.Equal(t, int64(1), testResults)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Different source", func(t *testing.T) {
+		submissionId := createIdentical(t, "other")
+		reused, err := sst.submissionService.ReuseIdenticalResult(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.False(t, reused)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Evaluation policy changed", func(t *testing.T) {
+		submissionId := createIdentical(t, "hash")
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		outputLimit := int64(1024)
+		if !assert.NoError(t, sst.tr.SetEvaluationPolicy(sst.tx, submission.TaskId, models.EvaluationPolicy{OutputLimit: &outputLimit})) {
+			t.FailNow()
+		}
+		reused, err := sst.submissionService.ReuseIdenticalResult(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.False(t, reused)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Language multipliers changed", func(t *testing.T) {
+		submissionId := createIdentical(t, "hash")
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, sst.tx.Model(&submission.Language).Update("time_multiplier", 2).Error) {
+			t.FailNow()
+		}
+		reused, err := sst.submissionService.ReuseIdenticalResult(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.False(t, reused)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestUserTaskSummary(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	t.Run("Refreshed with results", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		summaries, err := sst.utsr.GetSummaries(sst.tx, userId)
+		assert.NoError(t, err)
+		assert.Empty(t, summaries)
+
+		_, err = sst.submissionService.CreateSubmissionResult(sst.tx, submissions[0].Id, schemas.ResponseMessage{
+			Result: schemas.Result{Code: "CE", Message: "main.c:1: error"},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		summaries, err = sst.utsr.GetSummaries(sst.tx, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, summaries, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, taskId, summaries[0].TaskId)
+		assert.Equal(t, int64(1), summaries[0].Attempts)
+		assert.Equal(t, 0.0, summaries[0].BestScore)
+		assert.Equal(t, "CE", summaries[0].LastCode)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Backfill", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		// A result stored before summaries were kept
+		result := &models.SubmissionResult{SubmissionId: submissions[0].Id, Code: "OK", Score: 100}
+		if !assert.NoError(t, sst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+		computed, err := sst.utsr.ComputeSummaries(sst.tx, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, computed, 1) {
+			t.FailNow()
+		}
+
+		backfill := NewUserTaskSummaryBackfill(sst.sr, sst.utsr, false)
+		lastId, processed, err := backfill.BackfillBatch(sst.tx, 0, DefaultBackfillBatchSize)
+		assert.NoError(t, err)
+		assert.Equal(t, submissions[0].Id, lastId)
+		assert.Equal(t, int64(1), processed)
+		summaries, err := sst.utsr.GetSummaries(sst.tx, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, summaries, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, computed[0].Attempts, summaries[0].Attempts)
+		assert.Equal(t, 100.0, summaries[0].BestScore)
+		assert.Equal(t, "OK", summaries[0].LastCode)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Estimated count", func(t *testing.T) {
+		sst.createSubmission(t)
+		sst.createSubmission(t)
+		count, err := sst.sr.CountSubmissions(sst.tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		// Rows inserted by the transaction itself are counted as live by ANALYZE
+		if !assert.NoError(t, sst.tx.Exec("ANALYZE submissions").Error) {
+			t.FailNow()
+		}
+		estimate, err := NewUserTaskSummaryBackfill(sst.sr, sst.utsr, true).Count(sst.tx)
+		assert.NoError(t, err)
+		assert.Equal(t, count, estimate)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestGetSubmissionsByEnvironment(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+	teacher := schemas.User{Id: 1, Role: string(models.UserRoleTeacher)}
+
+	// createJudged creates a submission with a result reported by a worker with the given sandbox image
+	createJudged := func(t *testing.T, sandboxImageDigest string) int64 {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		_, err = sst.submissionService.CreateSubmissionResult(sst.tx, submissions[0].Id, schemas.ResponseMessage{
+			Result: schemas.Result{
+				Code:               "OK",
+				WorkerVersion:      "1.2.0",
+				CompilerVersion:    "gcc 13.2",
+				SandboxImageDigest: sandboxImageDigest,
+			},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return submissions[0].Id
+	}
+
+	t.Run("Matching environment", func(t *testing.T) {
+		submissionId := createJudged(t, "sha256:abc")
+		submissions, _, err := sst.submissionService.GetSubmissionsByEnvironment(sst.tx, teacher, schemas.JudgeEnvironmentFilter{SandboxImageDigest: "sha256:abc"}, 10, 0, "")
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, submissionId, submissions[0].Id)
+		assert.Equal(t, &schemas.JudgeEnvironment{
+			WorkerVersion:      "1.2.0",
+			CompilerVersion:    "gcc 13.2",
+			SandboxImageDigest: "sha256:abc",
+		}, submissions[0].Result.Environment)
+
+		submissions, _, err = sst.submissionService.GetSubmissionsByEnvironment(sst.tx, teacher, schemas.JudgeEnvironmentFilter{SandboxImageDigest: "sha256:other"}, 10, 0, "")
+		assert.NoError(t, err)
+		assert.Empty(t, submissions)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Cursor pagination", func(t *testing.T) {
+		ids := []int64{createJudged(t, "sha256:page"), createJudged(t, "sha256:page"), createJudged(t, "sha256:page")}
+		filter := schemas.JudgeEnvironmentFilter{SandboxImageDigest: "sha256:page"}
+		firstPage, cursor, err := sst.submissionService.GetSubmissionsByEnvironment(sst.tx, teacher, filter, 2, 0, "")
+		if !assert.NoError(t, err) || !assert.Len(t, firstPage, 2) || !assert.NotEmpty(t, cursor) {
+			t.FailNow()
+		}
+		lastPage, cursor, err := sst.submissionService.GetSubmissionsByEnvironment(sst.tx, teacher, filter, 2, 0, cursor)
+		if !assert.NoError(t, err) || !assert.Len(t, lastPage, 1) {
+			t.FailNow()
+		}
+		assert.Empty(t, cursor)
+		assert.ElementsMatch(t, ids, []int64{firstPage[0].Id, firstPage[1].Id, lastPage[0].Id})
+
+		_, _, err = sst.submissionService.GetSubmissionsByEnvironment(sst.tx, teacher, filter, 2, 0, "not a cursor")
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Empty filter", func(t *testing.T) {
+		_, _, err := sst.submissionService.GetSubmissionsByEnvironment(sst.tx, teacher, schemas.JudgeEnvironmentFilter{}, 10, 0, "")
+		var validationErrors validator.ValidationErrors
+		if assert.ErrorAs(t, err, &validationErrors) && assert.Len(t, validationErrors, 1) {
+			assert.Equal(t, "worker_version", validationErrors[0].Field())
+		}
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Student", func(t *testing.T) {
+		_, _, err := sst.submissionService.GetSubmissionsByEnvironment(sst.tx, schemas.User{Role: string(models.UserRoleStudent)}, schemas.JudgeEnvironmentFilter{WorkerVersion: "1.2.0"}, 10, 0, "")
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestRedactSubmission(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	// createChecked creates a checked submission with a result message. Returns the submission and the task author
+	createChecked := func(t *testing.T) (int64, int64) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		submission := submissions[0]
+		if !assert.NoError(t, sst.tx.Model(&submission).Updates(map[string]interface{}{"source_hash": "hash", "status": "completed"}).Error) {
+			t.FailNow()
+		}
+		result := &models.SubmissionResult{SubmissionId: submission.Id, Code: "CE", Message: "main.c:1: my password is hunter2"}
+		if !assert.NoError(t, sst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+		return submission.Id, userId
+	}
+
+	t.Run("Task author", func(t *testing.T) {
+		submissionId, authorId := createChecked(t)
+		err := sst.submissionService.RedactSubmission(sst.tx, schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		assert.NoError(t, err)
+		assert.NotNil(t, submission.RedactedAt)
+		assert.Empty(t, submission.SourceHash)
+		var result models.SubmissionResult
+		assert.NoError(t, sst.tx.Where("submission_id = ?", submissionId).First(&result).Error)
+		assert.Equal(t, "CE", result.Code)
+		assert.Empty(t, result.Message)
+		assert.Equal(t, []int64{submission.Order}, sst.fileStorage.deleted)
+		sst.fileStorage.deleted = nil
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Plagiarism matches are deleted", func(t *testing.T) {
+		submissionId, authorId := createChecked(t)
+		submission, err := sst.sr.GetSubmission(sst.tx, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		check := &models.PlagiarismCheck{TaskId: submission.TaskId, Submissions: 3, CheckedAt: time.Now(), CheckedBy: authorId}
+		matches := []models.PlagiarismMatch{
+			{TaskId: submission.TaskId, FirstSubmissionId: submissionId, SecondSubmissionId: submissionId + 1, FirstUserId: authorId, SecondUserId: authorId, Similarity: 0.9},
+			{TaskId: submission.TaskId, FirstSubmissionId: submissionId + 1, SecondSubmissionId: submissionId + 2, FirstUserId: authorId, SecondUserId: authorId, Similarity: 0.8},
+		}
+		if !assert.NoError(t, sst.pr.ReplaceCheck(sst.tx, check, matches)) {
+			t.FailNow()
+		}
+
+		err = sst.submissionService.RedactSubmission(sst.tx, schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		remaining, err := sst.pr.GetMatches(sst.tx, submission.TaskId, 0)
+		if assert.NoError(t, err) && assert.Len(t, remaining, 1) {
+			assert.Equal(t, submissionId+1, remaining[0].FirstSubmissionId)
+		}
+		sst.fileStorage.deleted = nil
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Other teacher", func(t *testing.T) {
+		submissionId, authorId := createChecked(t)
+		err := sst.submissionService.RedactSubmission(sst.tx, schemas.User{Id: authorId + 1, Role: string(models.UserRoleTeacher)}, submissionId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		assert.Empty(t, sst.fileStorage.deleted)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Nonexistent submission", func(t *testing.T) {
+		err := sst.submissionService.RedactSubmission(sst.tx, schemas.User{Role: string(models.UserRoleAdmin)}, 0)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestGetSubmissionSource(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	// getSubmissionId returns the id of the only submission of the user for the task
+	getSubmissionId := func(t *testing.T, taskId, userId int64) int64 {
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		return submissions[0].Id
+	}
+
+	t.Run("Owner", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissionId := getSubmissionId(t, taskId, userId)
+		source, err := sst.submissionService.GetSubmissionSource(sst.tx, schemas.User{Id: userId, Role: string(models.UserRoleStudent)}, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "main.c", source.FileName)
+		assert.Equal(t, "text/x-c; charset=utf-8", source.ContentType)
+		assert.Equal(t, "c", source.Language)
+		assert.Equal(t, "99", source.LanguageVersion)
+		assert.Equal(t, []byte("solution 1"), source.Content)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Other student", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissionId := getSubmissionId(t, taskId, userId)
+		_, err := sst.submissionService.GetSubmissionSource(sst.tx, schemas.User{Id: userId + 1, Role: string(models.UserRoleStudent)}, submissionId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Co-author of the task", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissionId := getSubmissionId(t, taskId, userId)
+		coAuthorId, err := sst.ur.CreateUser(sst.tx, &models.User{
+			Name:         "Co",
+			Surname:      "Author",
+			Email:        "coauthor@email.com",
+			Username:     "coauthor",
+			PasswordHash: "password",
+			Role:         models.UserRoleTeacher,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		coAuthor := schemas.User{Id: coAuthorId, Role: string(models.UserRoleTeacher)}
+		_, err = sst.submissionService.GetSubmissionSource(sst.tx, coAuthor, submissionId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		err = sst.tcar.ReplaceCoAuthors(sst.tx, taskId, []models.TaskCoAuthor{{TaskId: taskId, UserId: coAuthorId, DisplayName: "Co Author"}})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		source, err := sst.submissionService.GetSubmissionSource(sst.tx, coAuthor, submissionId)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte("solution 1"), source.Content)
+		}
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Redacted submission", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissionId := getSubmissionId(t, taskId, userId)
+		if !assert.NoError(t, sst.tx.Model(&models.Submission{}).Where("id = ?", submissionId).Update("redacted_at", time.Now()).Error) {
+			t.FailNow()
+		}
+		_, err := sst.submissionService.GetSubmissionSource(sst.tx, schemas.User{Role: string(models.UserRoleAdmin)}, submissionId)
+		assert.ErrorIs(t, err, ErrSubmissionRedacted)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Nonexistent submission", func(t *testing.T) {
+		_, err := sst.submissionService.GetSubmissionSource(sst.tx, schemas.User{Role: string(models.UserRoleAdmin)}, 0)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestRecordTestProgress(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	// createProcessing creates a submission being judged. Returns the submission and its author
+	createProcessing := func(t *testing.T) (int64, int64) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		submission := submissions[0]
+		if !assert.NoError(t, sst.tx.Model(&submission).Update("status", "processing").Error) {
+			t.FailNow()
+		}
+		return submission.Id, userId
+	}
+	progressMessage := func(results ...schemas.TestResult) schemas.ResponseMessage {
+		return schemas.ResponseMessage{Result: schemas.Result{StatusCode: 4, TestResults: results}}
+	}
+
+	t.Run("Tests reported one by one", func(t *testing.T) {
+		submissionId, userId := createProcessing(t)
+		user := schemas.User{Id: userId, Role: string(models.UserRoleStudent)}
+		err := sst.submissionService.RecordTestProgress(sst.tx, submissionId, progressMessage(schemas.TestResult{Order: 1, Passed: true}))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		submission, err := sst.submissionService.GetSubmission(sst.tx, user, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []schemas.SubmissionTestProgress{{Order: 1, Passed: true}}, submission.Progress)
+
+		// Workers may resend all tests finished so far
+		err = sst.submissionService.RecordTestProgress(sst.tx, submissionId, progressMessage(
+			schemas.TestResult{Order: 1, Passed: true},
+			schemas.TestResult{Order: 2, Passed: false},
+		))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		submission, err = sst.submissionService.GetSubmission(sst.tx, user, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, []schemas.SubmissionTestProgress{{Order: 1, Passed: true}, {Order: 2, Passed: false}}, submission.Progress)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Progress cleared when judging fails", func(t *testing.T) {
+		submissionId, userId := createProcessing(t)
+		err := sst.submissionService.RecordTestProgress(sst.tx, submissionId, progressMessage(schemas.TestResult{Order: 1, Passed: true}))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, sst.submissionService.MarkSubmissionFailed(sst.tx, submissionId, "worker crashed")) {
+			t.FailNow()
+		}
+		submission, err := sst.submissionService.GetSubmission(sst.tx, schemas.User{Id: userId, Role: string(models.UserRoleStudent)}, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Empty(t, submission.Progress)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Other student", func(t *testing.T) {
+		submissionId, userId := createProcessing(t)
+		_, err := sst.submissionService.GetSubmission(sst.tx, schemas.User{Id: userId + 1, Role: string(models.UserRoleStudent)}, submissionId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestGetSubmissionTimeline(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+
+	t.Run("Events of a judged submission", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		submissionId := submissions[0].Id
+		for order := 1; order <= 2; order++ {
+			inputOutput := &models.InputOutput{TaskId: uint(taskId), Order: order, TimeLimit: 1, MemoryLimit: 1}
+			if !assert.NoError(t, sst.tx.Create(inputOutput).Error) {
+				t.FailNow()
+			}
+		}
+		messageId := "message"
+		progress := schemas.ResponseMessage{MessageId: messageId, Result: schemas.Result{StatusCode: 4, TestResults: []schemas.TestResult{{Order: 1, Passed: true}}}}
+		if !assert.NoError(t, sst.submissionService.RecordTestProgress(sst.tx, submissionId, progress)) {
+			t.FailNow()
+		}
+		final := schemas.ResponseMessage{MessageId: messageId, Result: schemas.Result{StatusCode: 1, Code: "WA", TestResults: []schemas.TestResult{
+			{Order: 1, Passed: true},
+			{Order: 2, Passed: false, ErrorMessage: "wrong answer"},
+		}}}
+		_, err = sst.submissionService.CreateSubmissionResult(sst.tx, submissionId, final)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		timeline, err := sst.submissionService.GetSubmissionTimeline(sst.tx, admin, submissionId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		types := []string{}
+		for _, event := range timeline.Events {
+			types = append(types, event.Type)
+		}
+		assert.Equal(t, []string{"created", "picked_up", "test", "test", "finalized"}, types)
+		assert.Equal(t, "failed: wrong answer", timeline.Events[3].Detail)
+		assert.Equal(t, "WA, 1 of 2 tests passed", timeline.Events[4].Detail)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Student", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		_, err = sst.submissionService.GetSubmissionTimeline(sst.tx, schemas.User{Id: userId, Role: string(models.UserRoleStudent)}, submissions[0].Id)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := sst.submissionService.GetSubmissionTimeline(sst.tx, admin, 0)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestTestVisibility(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	// createJudged creates a judged submission with three tests of which the second failed. Returns the task,
+	// the submission and its author
+	createJudged := func(t *testing.T) (int64, int64, schemas.User) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		result := &models.SubmissionResult{SubmissionId: submissions[0].Id, Code: "WA", Message: "", PassedTests: 2, TotalTests: 3}
+		if !assert.NoError(t, sst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+		for order, passed := range []bool{true, false, true} {
+			inputOutput := &models.InputOutput{TaskId: uint(taskId), Order: order + 1, TimeLimit: 1, MemoryLimit: 1}
+			if !assert.NoError(t, sst.tx.Create(inputOutput).Error) {
+				t.FailNow()
+			}
+			testResult := &models.TestResult{SubmissionResultId: result.Id, InputOutputId: int64(inputOutput.Id), Passed: passed}
+			if !assert.NoError(t, sst.tx.Create(testResult).Error) {
+				t.FailNow()
+			}
+		}
+		return taskId, submissions[0].Id, schemas.User{Id: userId, Role: string(models.UserRoleStudent)}
+	}
+	visibleTests := func(t *testing.T, user schemas.User, submissionId int64) []int {
+		submission, err := sst.submissionService.GetSubmission(sst.tx, user, submissionId)
+		if !assert.NoError(t, err) || !assert.NotNil(t, submission.Result) {
+			t.FailNow()
+		}
+		orders := []int{}
+		for _, testResult := range submission.Result.TestResults {
+			orders = append(orders, testResult.Order)
+		}
+		return orders
+	}
+
+	t.Run("All by default", func(t *testing.T) {
+		_, submissionId, student := createJudged(t)
+		assert.Equal(t, []int{1, 2, 3}, visibleTests(t, student, submissionId))
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("First failed", func(t *testing.T) {
+		taskId, submissionId, student := createJudged(t)
+		if !assert.NoError(t, sst.tr.SetTestVisibility(sst.tx, taskId, models.TestVisibilityFirstFailed)) {
+			t.FailNow()
+		}
+		assert.Equal(t, []int{1, 2}, visibleTests(t, student, submissionId))
+		sst.rollbackToSavePoint()
+	})
+
+	t.Run("Hidden", func(t *testing.T) {
+		taskId, submissionId, student := createJudged(t)
+		if !assert.NoError(t, sst.tr.SetTestVisibility(sst.tx, taskId, models.TestVisibilityHidden)) {
+			t.FailNow()
+		}
+		submission, err := sst.submissionService.GetSubmission(sst.tx, student, submissionId)
+		if !assert.NoError(t, err) || !assert.NotNil(t, submission.Result) {
+			t.FailNow()
+		}
+		assert.Empty(t, submission.Result.TestResults)
+		assert.Equal(t, int64(2), submission.Result.PassedTests)
+
+		// Admins see every test regardless of the visibility
+		admin := schemas.User{Id: student.Id + 1, Role: string(models.UserRoleAdmin)}
+		assert.Equal(t, []int{1, 2, 3}, visibleTests(t, admin, submissionId))
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
+
+func TestGetAllForTaskAndUser(t *testing.T) {
+	sst := newSubmissionServiceTest(t)
+
+	t.Run("Results and grades of each submission", func(t *testing.T) {
+		taskId, userId := sst.createSubmission(t)
+		submissions, err := sst.sr.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, submissions, 1) {
+			t.FailNow()
+		}
+		judgedId := submissions[0].Id
+		gradedId, err := sst.sr.CreateSubmission(sst.tx, models.Submission{
+			TaskId:     taskId,
+			UserId:     userId,
+			Order:      2,
+			LanguageId: submissions[0].LanguageId,
+			Status:     "received",
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		// A rejudge leaves the earlier result, only the latest one is listed
+		earlier := &models.SubmissionResult{SubmissionId: judgedId, Code: "WA", TotalTests: 1, CreatedAt: time.Now().Add(-time.Hour)}
+		latest := &models.SubmissionResult{SubmissionId: judgedId, Code: "OK", PassedTests: 1, TotalTests: 1}
+		for _, result := range []*models.SubmissionResult{earlier, latest} {
+			if !assert.NoError(t, sst.tx.Create(result).Error) {
+				t.FailNow()
+			}
+		}
+		inputOutput := &models.InputOutput{TaskId: uint(taskId), Order: 1, TimeLimit: 1, MemoryLimit: 1}
+		if !assert.NoError(t, sst.tx.Create(inputOutput).Error) {
+			t.FailNow()
+		}
+		testResult := &models.TestResult{SubmissionResultId: latest.Id, InputOutputId: int64(inputOutput.Id), Passed: true}
+		if !assert.NoError(t, sst.tx.Create(testResult).Error) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, sst.mgr.SaveGrades(sst.tx, []models.ManualGrade{{SubmissionId: gradedId, Score: 50, GradedBy: userId}})) {
+			t.FailNow()
+		}
+
+		result, err := sst.submissionService.GetAllForTaskAndUser(sst.tx, taskId, userId)
+		if !assert.NoError(t, err) || !assert.Len(t, result, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, judgedId, result[0].Id)
+		if assert.NotNil(t, result[0].Result) {
+			assert.Equal(t, "OK", result[0].Result.Code)
+			assert.Len(t, result[0].Result.TestResults, 1)
+		}
+		assert.Nil(t, result[0].ManualGrade)
+		assert.Equal(t, gradedId, result[1].Id)
+		assert.Nil(t, result[1].Result)
+		if assert.NotNil(t, result[1].ManualGrade) {
+			assert.Equal(t, float64(50), result[1].ManualGrade.Score)
+		}
+		sst.rollbackToSavePoint()
+	})
+	sst.tx.Rollback()
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrTagNotFound = errors.New("tag not found")
+var ErrTagAlreadyExists = errors.New("tag already exists")
+
+type TagService interface {
+	// GetTags returns all tags in name order
+	GetTags(tx *gorm.DB) ([]schemas.Tag, error)
+	// CreateTag creates a tag with the trimmed and lowercased name. Teachers and admins can create tags
+	CreateTag(tx *gorm.DB, currentUser schemas.User, tag schemas.TagCreate) (*schemas.Tag, error)
+	// DeleteTag deletes the tag and removes it from its tasks. Teachers can delete their own tags, admins any tags
+	DeleteTag(tx *gorm.DB, currentUser schemas.User, tagId int64) error
+	// SetTaskTags replaces the tags of the task and returns their names. Every tag has to exist. Only the task
+	// author and admins can set them
+	SetTaskTags(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskTagsEdit) ([]string, error)
+}
+
+type TagServiceImpl struct {
+	tagRepository        repository.TagRepository
+	taskRepository       repository.TaskRepository
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ts *TagServiceImpl) GetTags(tx *gorm.DB) ([]schemas.Tag, error) {
+	tags, err := ts.tagRepository.GetTags(tx)
+	if err != nil {
+		ts.logger.Errorf("Error getting tags: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.Tag, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, *tagModelToSchema(&tag))
+	}
+	return result, nil
+}
+
+func (ts *TagServiceImpl) CreateTag(tx *gorm.DB, currentUser schemas.User, tag schemas.TagCreate) (*schemas.Tag, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTag, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	tag.Name = normalizeTagName(tag.Name)
+	validate := utils.NewValidator()
+	if err := validate.Struct(tag); err != nil {
+		ts.logger.Errorf("Error validating tag: %v", err.Error())
+		return nil, err
+	}
+
+	_, err := ts.tagRepository.GetTagByName(tx, tag.Name)
+	if err == nil {
+		return nil, ErrTagAlreadyExists
+	}
+	if err != gorm.ErrRecordNotFound {
+		ts.logger.Errorf("Error getting tag: %v", err.Error())
+		return nil, err
+	}
+
+	model := &models.Tag{
+		Name:      tag.Name,
+		CreatedBy: currentUser.Id,
+	}
+	_, err = ts.tagRepository.CreateTag(tx, model)
+	if err != nil {
+		ts.logger.Errorf("Error creating tag: %v", err.Error())
+		return nil, err
+	}
+	return tagModelToSchema(model), nil
+}
+
+func (ts *TagServiceImpl) DeleteTag(tx *gorm.DB, currentUser schemas.User, tagId int64) error {
+	tag, err := ts.tagRepository.GetTag(tx, tagId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTagNotFound
+		}
+		ts.logger.Errorf("Error getting tag: %v", err.Error())
+		return err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTag, ActionManage, tag.CreatedBy) {
+		return ErrNotAuthorized
+	}
+
+	err = ts.tagRepository.DeleteTag(tx, tagId)
+	if err != nil {
+		ts.logger.Errorf("Error deleting tag: %v", err.Error())
+		return err
+	}
+	ts.logger.Infof("Tag %s deleted by user %d", tag.Name, currentUser.Id)
+	return nil
+}
+
+func (ts *TagServiceImpl) SetTaskTags(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskTagsEdit) ([]string, error) {
+	for i := range edit.Tags {
+		edit.Tags[i] = normalizeTagName(edit.Tags[i])
+	}
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating task tags: %v", err.Error())
+		return nil, err
+	}
+
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		return nil, ErrNotAuthorized
+	}
+
+	names := make([]string, 0, len(edit.Tags))
+	tagIds := make([]int64, 0, len(edit.Tags))
+	if len(edit.Tags) > 0 {
+		tags, err := ts.tagRepository.GetTagsByNames(tx, edit.Tags)
+		if err != nil {
+			ts.logger.Errorf("Error getting tags: %v", err.Error())
+			return nil, err
+		}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+			tagIds = append(tagIds, tag.Id)
+		}
+		for _, name := range edit.Tags {
+			if !slices.Contains(names, name) {
+				return nil, ErrTagNotFound
+			}
+		}
+	}
+
+	err = ts.tagRepository.SetTaskTags(tx, taskId, tagIds)
+	if err != nil {
+		ts.logger.Errorf("Error setting task tags: %v", err.Error())
+		return nil, err
+	}
+	return names, nil
+}
+
+// normalizeTagName trims and lowercases the name, so tags differing only in case are the same tag
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func tagModelToSchema(model *models.Tag) *schemas.Tag {
+	return &schemas.Tag{
+		Id:        model.Id,
+		Name:      model.Name,
+		CreatedBy: model.CreatedBy,
+	}
+}
+
+func NewTagService(tagRepository repository.TagRepository, taskRepository repository.TaskRepository, accessControlService AccessControlService) TagService {
+	log := logger.NewNamedLogger("tag_service")
+	return &TagServiceImpl{
+		tagRepository:        tagRepository,
+		taskRepository:       taskRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tagr, err := repository.NewTagRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ts := NewTagService(tagr, tr, newAccessControlServiceTest(t, tx))
+
+	users := []*models.User{
+		{Name: "Teacher", Surname: "User", Email: "teacher@email.com", Username: "teacher", PasswordHash: "password", Role: models.UserRoleTeacher},
+		{Name: "Other", Surname: "User", Email: "other@email.com", Username: "other", PasswordHash: "password", Role: models.UserRoleTeacher},
+		{Name: "Student", Surname: "User", Email: "student@email.com", Username: "student", PasswordHash: "password"},
+	}
+	if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+		t.FailNow()
+	}
+	teacher := schemas.User{Id: users[0].Id, Role: string(models.UserRoleTeacher)}
+	other := schemas.User{Id: users[1].Id, Role: string(models.UserRoleTeacher)}
+	student := schemas.User{Id: users[2].Id, Role: string(models.UserRoleStudent)}
+	taskId, err := tr.Create(tx, models.Task{Title: "Task", CreatedBy: teacher.Id})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	savePoint := "users"
+	tx.SavePoint(savePoint)
+
+	t.Run("Create and assign", func(t *testing.T) {
+		tag, err := ts.CreateTag(tx, teacher, schemas.TagCreate{Name: " Graphs "})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "graphs", tag.Name)
+		_, err = ts.CreateTag(tx, other, schemas.TagCreate{Name: "GRAPHS"})
+		assert.ErrorIs(t, err, ErrTagAlreadyExists)
+
+		names, err := ts.SetTaskTags(tx, teacher, taskId, schemas.TaskTagsEdit{Tags: []string{"Graphs"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"graphs"}, names)
+		_, err = ts.SetTaskTags(tx, teacher, taskId, schemas.TaskTagsEdit{Tags: []string{"graphs", "unknown"}})
+		assert.ErrorIs(t, err, ErrTagNotFound)
+		_, err = ts.SetTaskTags(tx, other, taskId, schemas.TaskTagsEdit{Tags: []string{"graphs"}})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		tags, err := tagr.GetTaskTagNames(tx, []int64{taskId})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"graphs"}, tags[taskId])
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Students cannot create tags", func(t *testing.T) {
+		_, err := ts.CreateTag(tx, student, schemas.TagCreate{Name: "graphs"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		tag, err := ts.CreateTag(tx, teacher, schemas.TagCreate{Name: "graphs"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ts.SetTaskTags(tx, teacher, taskId, schemas.TaskTagsEdit{Tags: []string{"graphs"}})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		err = ts.DeleteTag(tx, other, tag.Id)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		err = ts.DeleteTag(tx, teacher, tag.Id)
+		assert.NoError(t, err)
+
+		tags, err := ts.GetTags(tx)
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+		names, err := tagr.GetTaskTagNames(tx, []int64{taskId})
+		assert.NoError(t, err)
+		assert.Empty(t, names)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
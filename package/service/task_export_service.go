@@ -0,0 +1,244 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// TaskManifestVersion is the version of the portable task archive format written by exports. Imports
+	// reject archives of other versions
+	TaskManifestVersion = 1
+	TaskManifestName    = "manifest.json"
+	// TaskFilesName is the entry of the task files, in the format tasks are uploaded in
+	TaskFilesName = "task.tar.gz"
+	// maxTaskManifestSize guards imports against a manifest decompressing to an unbounded size
+	maxTaskManifestSize = 1 << 20 // 1 MB
+)
+
+var ErrInvalidTaskArchive = errors.New("invalid task archive")
+
+type TaskExportService interface {
+	// ExportTask returns a portable archive of the task, a zip of its manifest and files. Only the author
+	// of the task and admins can export it
+	ExportTask(tx *gorm.DB, currentUser schemas.User, taskId int64) ([]byte, error)
+	// ImportTask creates a task of the current user from a portable archive or a Polygon package. Only teachers
+	// and admins can import tasks. Returns ErrInvalidTaskArchive wrapped with the reason when the archive cannot be imported
+	ImportTask(tx *gorm.DB, currentUser schemas.User, archive io.ReaderAt, size int64) (*schemas.TaskCreateResponse, error)
+}
+
+type TaskExportServiceImpl struct {
+	taskService        TaskService
+	taskRepository     repository.TaskRepository
+	fileStorageService FileStorageService
+	// maxFilesSize bounds the decompressed task files of an import
+	maxFilesSize         int64
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (es *TaskExportServiceImpl) ExportTask(tx *gorm.DB, currentUser schemas.User, taskId int64) ([]byte, error) {
+	task, err := es.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		es.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	if !es.accessControlService.CanAccess(currentUser, ResourceTask, ActionExport, task.CreatedBy) {
+		return nil, ErrNotAuthorized
+	}
+
+	inputOutputs, err := es.taskRepository.GetInputOutputs(tx, taskId)
+	if err != nil {
+		es.logger.Errorf("Error getting task tests: %v", err.Error())
+		return nil, err
+	}
+	groups, err := es.taskService.GetTestCaseGroups(tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	manifest := schemas.TaskManifest{
+		FormatVersion: TaskManifestVersion,
+		Title:         task.Title,
+		EvaluationPolicy: schemas.TaskEvaluationPolicyEdit{
+			OutputLimit:  task.OutputLimit,
+			StderrLimit:  task.StderrLimit,
+			ProcessLimit: task.ProcessLimit,
+		},
+		Tests:      make([]schemas.TaskManifestTest, 0, len(inputOutputs)),
+		TestGroups: make([]schemas.TestCaseGroupEdit, 0, len(groups)),
+	}
+	for _, inputOutput := range inputOutputs {
+		manifest.Tests = append(manifest.Tests, schemas.TaskManifestTest{
+			Order:       inputOutput.Order,
+			TimeLimit:   inputOutput.TimeLimit,
+			MemoryLimit: inputOutput.MemoryLimit,
+		})
+	}
+	for _, group := range groups {
+		manifest.TestGroups = append(manifest.TestGroups, schemas.TestCaseGroupEdit{Name: group.Name, Points: group.Points, Tests: group.Tests})
+	}
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := es.fileStorageService.GetTaskFiles(taskId)
+	if err != nil {
+		es.logger.Errorf("Error getting task files: %v", err.Error())
+		return nil, err
+	}
+
+	archive := &bytes.Buffer{}
+	writer := zip.NewWriter(archive)
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{{TaskManifestName, manifestJson}, {TaskFilesName, files}} {
+		entryWriter, err := writer.Create(entry.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entryWriter.Write(entry.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	es.logger.Infof("Task %d exported by user %d", taskId, currentUser.Id)
+	return archive.Bytes(), nil
+}
+
+func (es *TaskExportServiceImpl) ImportTask(tx *gorm.DB, currentUser schemas.User, archive io.ReaderAt, size int64) (*schemas.TaskCreateResponse, error) {
+	if !es.accessControlService.Can(currentUser, ResourceTask, ActionImport) {
+		return nil, ErrNotAuthorized
+	}
+
+	reader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTaskArchive, err.Error())
+	}
+	var manifest *schemas.TaskManifest
+	var files []byte
+	if hasZipEntry(reader, PolygonProblemName) && !hasZipEntry(reader, TaskManifestName) {
+		manifest, files, err = convertPolygonPackage(reader, es.maxFilesSize)
+	} else {
+		manifest, files, err = readTaskArchive(reader, es.maxFilesSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	validate := utils.NewValidator()
+	if err := validate.Struct(manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %s", ErrInvalidTaskArchive, err.Error())
+	}
+
+	taskId, err := es.taskService.Create(tx, currentUser, &schemas.Task{Title: manifest.Title})
+	if err != nil {
+		return nil, err
+	}
+	inputOutputs := make([]models.InputOutput, 0, len(manifest.Tests))
+	for _, test := range manifest.Tests {
+		inputOutputs = append(inputOutputs, models.InputOutput{
+			TaskId:      uint(taskId),
+			Order:       test.Order,
+			TimeLimit:   test.TimeLimit,
+			MemoryLimit: test.MemoryLimit,
+		})
+	}
+	err = es.taskRepository.ReplaceInputOutputs(tx, taskId, inputOutputs)
+	if err != nil {
+		es.logger.Errorf("Error saving task tests: %v", err.Error())
+		return nil, err
+	}
+	_, err = es.taskService.UpdateTaskEvaluationPolicy(tx, currentUser, taskId, manifest.EvaluationPolicy)
+	if err != nil {
+		return nil, err
+	}
+	_, err = es.taskService.UpdateTestCaseGroups(tx, currentUser, taskId, schemas.TestCaseGroupsEdit{Groups: manifest.TestGroups})
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are stored last, so an archive rejected by the checks above leaves nothing behind in FileStorage
+	err = es.fileStorageService.CreateTask(taskId, TaskFilesName, files)
+	if err != nil {
+		es.logger.Errorf("Error storing task files: %v", err.Error())
+		return nil, err
+	}
+	es.logger.Infof("Task %d imported by user %d", taskId, currentUser.Id)
+	return &schemas.TaskCreateResponse{Id: taskId}, nil
+}
+
+// readTaskArchive reads the manifest and files of a portable task archive
+func readTaskArchive(reader *zip.Reader, maxSize int64) (*schemas.TaskManifest, []byte, error) {
+	manifestJson, err := readZipEntry(reader, TaskManifestName, maxTaskManifestSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := &schemas.TaskManifest{}
+	if err := json.Unmarshal(manifestJson, manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid %s: %s", ErrInvalidTaskArchive, TaskManifestName, err.Error())
+	}
+	if manifest.FormatVersion != TaskManifestVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported format version %d, expected %d", ErrInvalidTaskArchive, manifest.FormatVersion, TaskManifestVersion)
+	}
+	files, err := readZipEntry(reader, TaskFilesName, maxSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, files, nil
+}
+
+func hasZipEntry(reader *zip.Reader, name string) bool {
+	for _, file := range reader.File {
+		if file.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readZipEntry reads the entry with the name, failing when it is missing or decompresses to more than maxSize bytes
+func readZipEntry(reader *zip.Reader, name string, maxSize int64) ([]byte, error) {
+	file, err := reader.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidTaskArchive, name)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %s", ErrInvalidTaskArchive, name, err.Error())
+	}
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidTaskArchive, name, maxSize)
+	}
+	return content, nil
+}
+
+func NewTaskExportService(taskService TaskService, taskRepository repository.TaskRepository, fileStorageService FileStorageService, accessControlService AccessControlService, maxFilesSize int64) TaskExportService {
+	log := logger.NewNamedLogger("task_export_service")
+	return &TaskExportServiceImpl{
+		taskService:          taskService,
+		taskRepository:       taskRepository,
+		fileStorageService:   fileStorageService,
+		maxFilesSize:         maxFilesSize,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
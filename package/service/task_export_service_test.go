@@ -0,0 +1,184 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskExport(t *testing.T) {
+	tst := newTaskServiceTest(t)
+	fileStorage := &fileStorageServiceStub{taskFiles: map[int64][]byte{}}
+	es := NewTaskExportService(tst.taskService, tst.tr, fileStorage, newAccessControlServiceTest(t, tst.tx), 1<<20)
+
+	t.Run("Export and import", func(t *testing.T) {
+		author := schemas.User{Id: tst.createUser(t), Role: string(models.UserRoleTeacher)}
+		taskId, err := tst.taskService.Create(tst.tx, author, &schemas.Task{Title: "Exported Task"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		err = tst.tr.ReplaceInputOutputs(tst.tx, taskId, []models.InputOutput{
+			{TaskId: uint(taskId), Order: 1, TimeLimit: 1, MemoryLimit: 256},
+			{TaskId: uint(taskId), Order: 2, TimeLimit: 2, MemoryLimit: 512},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = tst.taskService.UpdateTestCaseGroups(tst.tx, author, taskId, schemas.TestCaseGroupsEdit{
+			Groups: []schemas.TestCaseGroupEdit{{Name: "All", Points: 100, Tests: []int64{1, 2}}},
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		fileStorage.taskFiles[taskId] = []byte("task files")
+
+		archive, err := es.ExportTask(tst.tx, author, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = es.ExportTask(tst.tx, schemas.User{Id: author.Id + 1, Role: string(models.UserRoleTeacher)}, taskId)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		_, err = es.ImportTask(tst.tx, author, bytes.NewReader(archive), int64(len(archive)))
+		assert.ErrorIs(t, err, ErrTaskExists)
+		if !assert.NoError(t, tst.tx.Model(&models.Task{}).Where("id = ?", taskId).Update("title", "Original Task").Error) {
+			t.FailNow()
+		}
+
+		imported, err := es.ImportTask(tst.tx, author, bytes.NewReader(archive), int64(len(archive)))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NotEqual(t, taskId, imported.Id)
+		assert.Equal(t, []byte("task files"), fileStorage.taskFiles[imported.Id])
+		inputOutputs, err := tst.tr.GetInputOutputs(tst.tx, imported.Id)
+		assert.NoError(t, err)
+		if assert.Len(t, inputOutputs, 2) {
+			assert.Equal(t, 512.0, inputOutputs[1].MemoryLimit)
+		}
+		groups, err := tst.taskService.GetTestCaseGroups(tst.tx, imported.Id)
+		assert.NoError(t, err)
+		if assert.Len(t, groups, 1) {
+			assert.Equal(t, []int64{1, 2}, groups[0].Tests)
+		}
+		tst.tx.RollbackTo(tst.savePoint)
+	})
+
+	t.Run("Polygon package", func(t *testing.T) {
+		teacher := schemas.User{Id: tst.createUser(t), Role: string(models.UserRoleTeacher)}
+		archive := polygonPackage(t, map[string]string{
+			"problem.xml": `<problem short-name="a-plus-b">
+	<names><name language="polish" value="A + B (pl)"/><name language="english" value="A + B"/></names>
+	<statements><statement language="english" path="statements/.pdf/english/problem.pdf" type="application/pdf"/></statements>
+	<judging>
+		<testset name="tests">
+			<time-limit>2000</time-limit>
+			<memory-limit>268435456</memory-limit>
+			<test-count>2</test-count>
+			<input-path-pattern>tests/%02d</input-path-pattern>
+			<answer-path-pattern>tests/%02d.a</answer-path-pattern>
+			<tests><test method="manual" group="samples"/><test method="generated" group="main" points="50"/></tests>
+			<groups><group name="samples" points="0"/><group name="main" points-policy="each-test"/></groups>
+		</testset>
+	</judging>
+</problem>`,
+			"statements/.pdf/english/problem.pdf": "statement",
+			"tests/01":                            "1 2",
+			"tests/01.a":                          "3",
+			"tests/02":                            "2 2",
+			"tests/02.a":                          "4",
+		})
+
+		imported, err := es.ImportTask(tst.tx, teacher, bytes.NewReader(archive), int64(len(archive)))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		task, err := tst.taskService.GetTask(tst.tx, imported.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "A + B", task.Title)
+		inputOutputs, err := tst.tr.GetInputOutputs(tst.tx, imported.Id)
+		assert.NoError(t, err)
+		if assert.Len(t, inputOutputs, 2) {
+			assert.Equal(t, 2.0, inputOutputs[0].TimeLimit)
+			assert.Equal(t, 256.0, inputOutputs[0].MemoryLimit)
+		}
+		groups, err := tst.taskService.GetTestCaseGroups(tst.tx, imported.Id)
+		assert.NoError(t, err)
+		if assert.Len(t, groups, 1) {
+			assert.Equal(t, "main", groups[0].Name)
+			assert.Equal(t, int64(50), groups[0].Points)
+			assert.Equal(t, []int64{2}, groups[0].Tests)
+		}
+
+		gzipReader, err := gzip.NewReader(bytes.NewReader(fileStorage.taskFiles[imported.Id]))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		files := map[string]string{}
+		tarReader := tar.NewReader(gzipReader)
+		for header, err := tarReader.Next(); err == nil; header, err = tarReader.Next() {
+			content, _ := io.ReadAll(tarReader)
+			files[header.Name] = string(content)
+		}
+		assert.Equal(t, map[string]string{
+			"task/description.pdf": "statement",
+			"task/input/1.in":      "1 2",
+			"task/output/1.out":    "3",
+			"task/input/2.in":      "2 2",
+			"task/output/2.out":    "4",
+		}, files)
+		tst.tx.RollbackTo(tst.savePoint)
+	})
+
+	t.Run("Polygon package without tests", func(t *testing.T) {
+		teacher := schemas.User{Id: tst.createUser(t), Role: string(models.UserRoleTeacher)}
+		archive := polygonPackage(t, map[string]string{
+			"problem.xml": `<problem><names><name language="english" value="Empty"/></names>
+	<statements><statement language="english" path="problem.pdf" type="application/pdf"/></statements></problem>`,
+			"problem.pdf": "statement",
+		})
+		_, err := es.ImportTask(tst.tx, teacher, bytes.NewReader(archive), int64(len(archive)))
+		assert.ErrorIs(t, err, ErrInvalidTaskArchive)
+		tst.tx.RollbackTo(tst.savePoint)
+	})
+
+	t.Run("Invalid archive", func(t *testing.T) {
+		teacher := schemas.User{Id: tst.createUser(t), Role: string(models.UserRoleTeacher)}
+		archive := []byte("not a zip")
+		_, err := es.ImportTask(tst.tx, teacher, bytes.NewReader(archive), int64(len(archive)))
+		assert.ErrorIs(t, err, ErrInvalidTaskArchive)
+		tst.tx.RollbackTo(tst.savePoint)
+	})
+
+	t.Run("Student", func(t *testing.T) {
+		_, err := es.ImportTask(tst.tx, schemas.User{Id: 1, Role: string(models.UserRoleStudent)}, bytes.NewReader(nil), 0)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+	tst.tx.Rollback()
+}
+
+func polygonPackage(t *testing.T, files map[string]string) []byte {
+	archive := &bytes.Buffer{}
+	writer := zip.NewWriter(archive)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = entry.Write([]byte(content))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	if !assert.NoError(t, writer.Close()) {
+		t.FailNow()
+	}
+	return archive.Bytes()
+}
@@ -2,12 +2,18 @@ package service
 
 import (
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mini-maxit/backend/internal/config"
 	"github.com/mini-maxit/backend/internal/logger"
 	"github.com/mini-maxit/backend/package/domain/models"
 	"github.com/mini-maxit/backend/package/domain/schemas"
 	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -15,27 +21,117 @@ import (
 var ErrDatabaseConnection = fmt.Errorf("failed to connect to the database")
 var ErrTaskExists = fmt.Errorf("task with this title already exists")
 var ErrTaskNotFound = fmt.Errorf("task not found")
+var ErrTaskNoteNotFound = fmt.Errorf("task note not found")
+var ErrTaskVersionConflict = fmt.Errorf("task was modified since the given version")
+var ErrInvalidCoAuthor = fmt.Errorf("co-author must be an existing user other than the task author, listed once")
+var ErrTaskPoolNotFound = fmt.Errorf("task pool not found")
+var ErrInvalidTaskPool = fmt.Errorf("pool tasks must be existing tasks of the pool author that are not in another pool")
+var ErrTaskNotAssigned = fmt.Errorf("another variant of the task is assigned to the user")
+var ErrInvalidTestCaseGroups = fmt.Errorf("group names must be unique and every test can be in one group only")
+var ErrEvaluationLimitExceeded = fmt.Errorf("evaluation policy exceeds the limits of the platform")
+
+// TaskDraftTTL is how long a draft is kept after it was last saved
+const TaskDraftTTL = 30 * 24 * time.Hour
 
 type TaskService interface {
-	// Create creates a new empty task and returns the task ID
-	Create(tx *gorm.DB, task *schemas.Task) (int64, error)
-	GetAll(tx *gorm.DB, limit, offset int64) ([]schemas.Task, error)
-	GetAllForUser(tx *gorm.DB, userId, limit, offset int64) ([]schemas.Task, error)
-	GetAllForGroup(tx *gorm.DB, groupId, limit, offset int64) ([]schemas.Task, error)
+	// Create creates a new empty task authored by the current user and returns the task ID.
+	// CreatedBy of the task is ignored
+	Create(tx *gorm.DB, currentUser schemas.User, task *schemas.Task) (int64, error)
+	// GetAll returns tasks matching the filter annotated with bookmarks, notes and progress of the given user.
+	// Tasks changed during the term in progress are marked updated
+	GetAll(tx *gorm.DB, userId int64, filter schemas.TaskFilter, limit, offset int64) ([]schemas.Task, error)
+	// GetAllForUser returns tasks of the user. Verdicts are left out of tasks whose statistics a group of
+	// currentUserId hides, as in GetAllForGroup
+	GetAllForUser(tx *gorm.DB, currentUserId, userId, limit, offset int64) ([]schemas.Task, error)
+	GetAllForGroup(tx *gorm.DB, currentUserId, groupId, limit, offset int64) ([]schemas.Task, error)
 	GetTask(tx *gorm.DB, taskId int64) (*schemas.TaskDetailed, error)
 	GetTaskByTitle(tx *gorm.DB, title string) (*schemas.Task, error)
-	UpdateTask(tx *gorm.DB, taskId int64, updateInfo schemas.UpdateTask) error
-	CreateSubmission(tx *gorm.DB, taskId int64, userId int64, languageId int64, order int64) (int64, error)
+	// UpdateTask edits the task if it is still at the version the edit is based on, otherwise it returns
+	// ErrTaskVersionConflict. Only the task author and admins can edit a task. A changed title is recorded as a task change
+	UpdateTask(tx *gorm.DB, currentUser schemas.User, taskId int64, updateInfo schemas.UpdateTask) (*schemas.TaskDetailed, error)
+	// AuthorizeSubmission checks that the current user may submit solutions of the task before the solution is uploaded.
+	// Returns ErrNotAuthorized without the permission to submit solutions and ErrTaskNotAssigned when another
+	// variant of a task pool is assigned to the user
+	AuthorizeSubmission(tx *gorm.DB, currentUser schemas.User, taskId int64) error
+	// CreateSubmission creates a received submission. sourceHash is the hex encoded SHA-256 of the source
+	CreateSubmission(tx *gorm.DB, taskId int64, userId int64, languageId int64, order int64, sourceHash string) (int64, error)
+	BookmarkTask(tx *gorm.DB, taskId int64, userId int64) error
+	UnbookmarkTask(tx *gorm.DB, taskId int64, userId int64) error
+	GetTaskNote(tx *gorm.DB, taskId int64, userId int64) (*schemas.TaskNote, error)
+	PutTaskNote(tx *gorm.DB, taskId int64, userId int64, note schemas.TaskNoteEdit) (*schemas.TaskNote, error)
+	DeleteTaskNote(tx *gorm.DB, taskId int64, userId int64) error
+	// GetTaskDrafts returns unexpired drafts of the user for the task, one per language
+	GetTaskDrafts(tx *gorm.DB, taskId int64, userId int64) ([]schemas.TaskDraft, error)
+	// PutTaskDraft saves the draft of the user for the task in the language, extending its expiry
+	PutTaskDraft(tx *gorm.DB, taskId int64, userId int64, draft schemas.TaskDraftEdit) (*schemas.TaskDraft, error)
+	// DeleteExpiredDrafts deletes drafts not saved within TaskDraftTTL and returns how many were deleted
+	DeleteExpiredDrafts(tx *gorm.DB) (int64, error)
+	// UpdateTaskCoAuthors replaces the co-authors of a task and notifies users added or removed by the change.
+	// Only the task author and admins can edit co-authors
+	UpdateTaskCoAuthors(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskCoAuthorsEdit) ([]schemas.TaskCoAuthor, error)
+	// GetTestCaseGroups returns the test groups of a task in order. A task without groups is scored by passed tests
+	GetTestCaseGroups(tx *gorm.DB, taskId int64) ([]schemas.TestCaseGroup, error)
+	// UpdateTestCaseGroups replaces the test groups of a task. Scores of existing submissions are not changed
+	// until they are recomputed. Only the task author and admins can edit test groups
+	UpdateTestCaseGroups(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TestCaseGroupsEdit) ([]schemas.TestCaseGroup, error)
+	// GetTaskEvaluationPolicy returns the effective evaluation policy of a task with the limits of the platform
+	GetTaskEvaluationPolicy(tx *gorm.DB, taskId int64) (*schemas.TaskEvaluationPolicy, error)
+	// UpdateTaskEvaluationPolicy replaces the evaluation policy of a task. It applies to submissions published
+	// afterwards. Changed limits are recorded as task changes. Only the task author and admins can edit the policy
+	UpdateTaskEvaluationPolicy(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskEvaluationPolicyEdit) (*schemas.TaskEvaluationPolicy, error)
+	// GetTaskChanges returns recorded changes of the title and limits of a task, newest first
+	GetTaskChanges(tx *gorm.DB, taskId int64, limit, offset int64) ([]schemas.TaskChange, error)
+	// GetSandboxTasks returns tasks of the public sandbox, available without an account
+	GetSandboxTasks(tx *gorm.DB, limit, offset int64) ([]schemas.Task, error)
+	// GetSandboxTask returns a sandbox task. Tasks outside of the sandbox are reported as not found
+	GetSandboxTask(tx *gorm.DB, taskId int64) (*schemas.TaskDetailed, error)
+	// SetTaskSandbox adds a task to or removes it from the sandbox. The sandbox is curated by admins
+	SetTaskSandbox(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskSandboxEdit) error
+	// SetTaskTestVisibility sets how much of the test results of their submissions students see. Only the task
+	// author and admins can set it
+	SetTaskTestVisibility(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskTestVisibilityEdit) error
+	// SetTaskSubmissionRetention sets which sources of old submissions to the task are kept, overriding the
+	// platform policy. Only the task author and admins can set it
+	SetTaskSubmissionRetention(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskSubmissionRetentionEdit) error
+	// GetTaskStats returns statistics of the task within the term. Without a term it uses the term in progress,
+	// or all submissions when allTime is set or no term is in progress. Members of a group hiding statistics of
+	// the task get statistics of their own submissions only
+	GetTaskStats(tx *gorm.DB, currentUserId int64, taskId int64, termId *int64, allTime bool) (*schemas.TaskStats, error)
+	// CreateTaskPool creates a pool of equivalent tasks of which every student is assigned one.
+	// Teachers can pool their own tasks, admins any tasks
+	CreateTaskPool(tx *gorm.DB, currentUser schemas.User, pool schemas.TaskPoolCreate) (*schemas.TaskPool, error)
+	GetTaskPool(tx *gorm.DB, currentUser schemas.User, poolId int64) (*schemas.TaskPool, error)
+	// DeleteTaskPool deletes the pool, its tasks are available to every student again
+	DeleteTaskPool(tx *gorm.DB, currentUser schemas.User, poolId int64) error
 }
 
 type TaskServiceImpl struct {
 	cfg                  *config.Config
 	taskRepository       repository.TaskRepository
 	submissionRepository repository.SubmissionRepository
+	bookmarkRepository   repository.TaskBookmarkRepository
+	noteRepository       repository.TaskNoteRepository
+	draftRepository      repository.TaskDraftRepository
+	coAuthorRepository   repository.TaskCoAuthorRepository
+	testGroupRepository  repository.TestCaseGroupRepository
+	poolRepository       repository.TaskPoolRepository
+	userRepository       repository.UserRepository
+	termRepository       repository.TermRepository
+	summaryRepository    repository.UserTaskSummaryRepository
+	verdictRepository    repository.TaskVerdictSummaryRepository
+	changeRepository     repository.TaskChangeRepository
+	tagRepository        repository.TagRepository
+	notificationService  NotificationService
+	migrationService     OnlineMigrationService
+	accessControlService AccessControlService
 	logger               *zap.SugaredLogger
 }
 
-func (ts *TaskServiceImpl) Create(tx *gorm.DB, task *schemas.Task) (int64, error) {
+func (ts *TaskServiceImpl) Create(tx *gorm.DB, currentUser schemas.User, task *schemas.Task) (int64, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTask, ActionCreate) {
+		return 0, ErrNotAuthorized
+	}
+
 	// Create a new task
 	_, err := ts.GetTaskByTitle(tx, task.Title)
 	if err != nil && err != ErrTaskNotFound {
@@ -47,7 +143,7 @@ func (ts *TaskServiceImpl) Create(tx *gorm.DB, task *schemas.Task) (int64, error
 
 	model := models.Task{
 		Title:     task.Title,
-		CreatedBy: task.CreatedBy,
+		CreatedBy: currentUser.Id,
 	}
 	taskId, err := ts.taskRepository.Create(tx, model)
 	if err != nil {
@@ -76,18 +172,82 @@ func (ts *TaskServiceImpl) GetTaskByTitle(tx *gorm.DB, title string) (*schemas.T
 	}, nil
 }
 
-func (ts *TaskServiceImpl) GetAll(tx *gorm.DB, limit, offset int64) ([]schemas.Task, error) {
-	// Get all tasks
-	tasks, err := ts.taskRepository.GetAllTasks(tx)
+func (ts *TaskServiceImpl) GetAll(tx *gorm.DB, userId int64, filter schemas.TaskFilter, limit, offset int64) ([]schemas.Task, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(filter); err != nil {
+		ts.logger.Errorf("Error validating task filter: %v", err.Error())
+		return nil, err
+	}
+
+	for i := range filter.Tags {
+		filter.Tags[i] = normalizeTagName(filter.Tags[i])
+	}
+	tasks, err := ts.taskRepository.SearchTasks(tx, models.TaskSearch{
+		Tags:       filter.Tags,
+		Difficulty: models.TaskDifficulty(filter.Difficulty),
+		Text:       strings.TrimSpace(filter.Search),
+	})
+	if err != nil {
+		ts.logger.Errorf("Error searching tasks: %v", err.Error())
+		return nil, err
+	}
+	restricted, err := ts.isRestrictedToAssignedTasks(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if restricted {
+		tasks, err = ts.filterUnassignedTasks(tx, userId, tasks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bookmarked, err := ts.bookmarkRepository.GetBookmarkedTaskIds(tx, userId)
+	if err != nil {
+		ts.logger.Errorf("Error getting bookmarked tasks: %v", err.Error())
+		return nil, err
+	}
+	noted, err := ts.noteRepository.GetNotedTaskIds(tx, userId)
+	if err != nil {
+		ts.logger.Errorf("Error getting noted tasks: %v", err.Error())
+		return nil, err
+	}
+	summaries, err := ts.getTaskSummaries(tx, userId)
 	if err != nil {
-		ts.logger.Errorf("Error getting all tasks: %v", err.Error())
 		return nil, err
 	}
+	var changed []int64
+	term, err := ts.termRepository.GetTermAt(tx, time.Now())
+	if err != nil && err != gorm.ErrRecordNotFound {
+		ts.logger.Errorf("Error getting current term: %v", err.Error())
+		return nil, err
+	}
+	if err == nil {
+		changed, err = ts.changeRepository.GetChangedTaskIds(tx, term.StartsAt)
+		if err != nil {
+			ts.logger.Errorf("Error getting changed tasks: %v", err.Error())
+			return nil, err
+		}
+	}
 
 	// Convert the models to schemas
 	var result []schemas.Task
 	for _, task := range tasks {
-		result = append(result, ts.modelToSchema(task))
+		taskSchema := ts.modelToSchema(task)
+		taskSchema.Bookmarked = slices.Contains(bookmarked, task.Id)
+		taskSchema.HasNote = slices.Contains(noted, task.Id)
+		taskSchema.Updated = slices.Contains(changed, task.Id)
+		if summary, ok := summaries[task.Id]; ok {
+			taskSchema.Progress = &schemas.TaskProgress{
+				Attempts:    summary.Attempts,
+				BestScore:   summary.BestScore,
+				LastVerdict: summary.LastCode,
+			}
+		}
+		if filter.Bookmarked && !taskSchema.Bookmarked {
+			continue
+		}
+		result = append(result, taskSchema)
 	}
 
 	// Handle pagination
@@ -100,16 +260,49 @@ func (ts *TaskServiceImpl) GetAll(tx *gorm.DB, limit, offset int64) ([]schemas.T
 		end = int64(len(result))
 	}
 
-	return result[offset:end], nil
+	page := result[offset:end]
+	err = ts.setTaskVerdicts(tx, userId, page)
+	if err != nil {
+		return nil, err
+	}
+	err = ts.setTaskTags(tx, page)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// setTaskTags sets the tag names of the tasks
+func (ts *TaskServiceImpl) setTaskTags(tx *gorm.DB, tasks []schemas.Task) error {
+	taskIds := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		taskIds = append(taskIds, task.Id)
+	}
+	names, err := ts.tagRepository.GetTaskTagNames(tx, taskIds)
+	if err != nil {
+		ts.logger.Errorf("Error getting task tags: %v", err.Error())
+		return err
+	}
+	for i := range tasks {
+		tasks[i].Tags = names[tasks[i].Id]
+		if tasks[i].Tags == nil {
+			tasks[i].Tags = []string{}
+		}
+	}
+	return nil
 }
 
-func (ts *TaskServiceImpl) GetAllForUser(tx *gorm.DB, userId, limit, offset int64) ([]schemas.Task, error) {
+func (ts *TaskServiceImpl) GetAllForUser(tx *gorm.DB, currentUserId, userId, limit, offset int64) ([]schemas.Task, error) {
 	// Get all tasks
 	tasks, err := ts.taskRepository.GetAllForUser(tx, userId)
 	if err != nil {
 		ts.logger.Errorf("Error getting all tasks for user: %v", err.Error())
 		return nil, err
 	}
+	tasks, err = ts.filterUnassignedTasks(tx, userId, tasks)
+	if err != nil {
+		return nil, err
+	}
 
 	// Convert the models to schemas
 	var result []schemas.Task
@@ -127,10 +320,15 @@ func (ts *TaskServiceImpl) GetAllForUser(tx *gorm.DB, userId, limit, offset int6
 		end = int64(len(result))
 	}
 
-	return result[offset:end], nil
+	page := result[offset:end]
+	err = ts.setTaskVerdicts(tx, currentUserId, page)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
-func (ts *TaskServiceImpl) GetAllForGroup(tx *gorm.DB, groupId, limit, offset int64) ([]schemas.Task, error) {
+func (ts *TaskServiceImpl) GetAllForGroup(tx *gorm.DB, currentUserId, groupId, limit, offset int64) ([]schemas.Task, error) {
 	// Get all tasks
 	tasks, err := ts.taskRepository.GetAllForGroup(tx, groupId)
 	if err != nil {
@@ -154,7 +352,12 @@ func (ts *TaskServiceImpl) GetAllForGroup(tx *gorm.DB, groupId, limit, offset in
 		end = int64(len(result))
 	}
 
-	return result[offset:end], nil
+	page := result[offset:end]
+	err = ts.setTaskVerdicts(tx, currentUserId, page)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
 func (ts *TaskServiceImpl) GetTask(tx *gorm.DB, taskId int64) (*schemas.TaskDetailed, error) {
@@ -165,41 +368,118 @@ func (ts *TaskServiceImpl) GetTask(tx *gorm.DB, taskId int64) (*schemas.TaskDeta
 		return nil, err
 	}
 
+	coAuthors, err := ts.coAuthorRepository.GetCoAuthors(tx, taskId)
+	if err != nil {
+		ts.logger.Errorf("Error getting task co-authors: %v", err.Error())
+		return nil, err
+	}
+
+	tags, err := ts.tagRepository.GetTaskTagNames(tx, []int64{taskId})
+	if err != nil {
+		ts.logger.Errorf("Error getting task tags: %v", err.Error())
+		return nil, err
+	}
+	taskTags := tags[taskId]
+	if taskTags == nil {
+		taskTags = []string{}
+	}
+
 	// Convert the model to schema
 	result := &schemas.TaskDetailed{
-		Id:             task.Id,
-		Title:          task.Title,
-		DescriptionURL: fmt.Sprintf("%s/getTaskDescription?taskID=%d", ts.cfg.FileStorageUrl, task.Id),
-		CreatedBy:      task.CreatedBy,
-		CreatedByName:  task.Author.Name,
-		CreatedAt:      task.CreatedAt,
+		Id:                  task.Id,
+		Title:               task.Title,
+		DescriptionURL:      fmt.Sprintf("%s/getTaskDescription?taskID=%d", ts.cfg.FileStorageUrl, task.Id),
+		CreatedBy:           task.CreatedBy,
+		CreatedByName:       task.Author.Name,
+		CoAuthors:           ts.coAuthorModelsToSchemas(coAuthors),
+		CreatedAt:           task.CreatedAt,
+		Sandbox:             task.Sandbox,
+		Version:             task.Version,
+		TestVisibility:      string(task.TestVisibility),
+		SubmissionRetention: string(task.SubmissionRetention),
+		Difficulty:          string(task.Difficulty),
+		Tags:                taskTags,
 	}
 
 	return result, nil
 }
 
-func (ts *TaskServiceImpl) UpdateTask(tx *gorm.DB, taskId int64, updateInfo schemas.UpdateTask) error {
+func (ts *TaskServiceImpl) UpdateTask(tx *gorm.DB, currentUser schemas.User, taskId int64, updateInfo schemas.UpdateTask) (*schemas.TaskDetailed, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(updateInfo); err != nil {
+		ts.logger.Errorf("Error validating task update: %v", err.Error())
+		return nil, err
+	}
+
 	currentTask, err := ts.taskRepository.GetTask(tx, taskId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return ErrTaskNotFound
+			return nil, ErrTaskNotFound
 		}
 		ts.logger.Errorf("Error getting task: %v", err.Error())
-		return err
+		return nil, err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, currentTask.CreatedBy) {
+		return nil, ErrNotAuthorized
+	}
+	if currentTask.Version != updateInfo.Version {
+		return nil, ErrTaskVersionConflict
 	}
 
+	previousTitle := currentTask.Title
 	ts.updateModel(currentTask, &updateInfo)
 
-	// Update the task
-	err = ts.taskRepository.UpdateTask(tx, taskId, currentTask)
+	// Update the task, unless it was edited after it was read
+	updated, err := ts.taskRepository.UpdateTask(tx, taskId, currentTask, updateInfo.Version)
 	if err != nil {
 		ts.logger.Errorf("Error updating task: %v", err.Error())
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrTaskVersionConflict
+	}
+	if currentTask.Title != previousTitle {
+		err = ts.recordTaskChanges(tx, currentUser, taskId, currentTask.Title, []models.TaskChange{
+			{Field: "title", OldValue: previousTitle, NewValue: currentTask.Title},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ts.GetTask(tx, taskId)
+}
+
+func (ts *TaskServiceImpl) AuthorizeSubmission(tx *gorm.DB, currentUser schemas.User, taskId int64) error {
+	if !ts.accessControlService.Can(currentUser, ResourceSubmission, ActionCreate) {
+		return ErrNotAuthorized
+	}
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return err
+	}
+	// Students of a task pool can only submit their own variant
+	assigned, err := ts.isTaskAssigned(tx, taskId, currentUser.Id)
+	if err != nil {
 		return err
 	}
+	if !assigned {
+		return ErrTaskNotAssigned
+	}
 	return nil
 }
 
-func (ts *TaskServiceImpl) CreateSubmission(tx *gorm.DB, taskId int64, userId int64, languageId int64, order int64) (int64, error) {
+func (ts *TaskServiceImpl) CreateSubmission(tx *gorm.DB, taskId int64, userId int64, languageId int64, order int64, sourceHash string) (int64, error) {
+	// Tag the submission with the term in progress, if there is one
+	var termId *int64
+	term, err := ts.termRepository.GetTermAt(tx, time.Now())
+	if err != nil && err != gorm.ErrRecordNotFound {
+		ts.logger.Errorf("Error getting current term: %v", err.Error())
+		return 0, err
+	}
+	if err == nil {
+		termId = &term.Id
+	}
+
 	// Create a new submission
 	submission := models.Submission{
 		TaskId:     taskId,
@@ -208,6 +488,8 @@ func (ts *TaskServiceImpl) CreateSubmission(tx *gorm.DB, taskId int64, userId in
 		LanguageId: languageId,
 		Status:     "received",
 		CheckedAt:  nil,
+		TermId:     termId,
+		SourceHash: sourceHash,
 	}
 	submissionId, err := ts.submissionRepository.CreateSubmission(tx, submission)
 
@@ -219,27 +501,990 @@ func (ts *TaskServiceImpl) CreateSubmission(tx *gorm.DB, taskId int64, userId in
 	return submissionId, nil
 }
 
-func (ts *TaskServiceImpl) updateModel(currentModel *models.Task, updateInfo *schemas.UpdateTask) {
-	if updateInfo.Title != "" {
-		currentModel.Title = updateInfo.Title
+func (ts *TaskServiceImpl) BookmarkTask(tx *gorm.DB, taskId int64, userId int64) error {
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return err
+	}
+
+	err = ts.bookmarkRepository.AddBookmark(tx, taskId, userId)
+	if err != nil {
+		ts.logger.Errorf("Error bookmarking task: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (ts *TaskServiceImpl) UnbookmarkTask(tx *gorm.DB, taskId int64, userId int64) error {
+	err := ts.bookmarkRepository.RemoveBookmark(tx, taskId, userId)
+	if err != nil {
+		ts.logger.Errorf("Error removing task bookmark: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (ts *TaskServiceImpl) GetTaskNote(tx *gorm.DB, taskId int64, userId int64) (*schemas.TaskNote, error) {
+	note, err := ts.noteRepository.GetNote(tx, taskId, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNoteNotFound
+		}
+		ts.logger.Errorf("Error getting task note: %v", err.Error())
+		return nil, err
+	}
+
+	return ts.noteModelToSchema(note), nil
+}
+
+func (ts *TaskServiceImpl) PutTaskNote(tx *gorm.DB, taskId int64, userId int64, note schemas.TaskNoteEdit) (*schemas.TaskNote, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(note); err != nil {
+		ts.logger.Errorf("Error validating task note: %v", err.Error())
+		return nil, err
+	}
+
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &models.TaskNote{
+		TaskId:  taskId,
+		UserId:  userId,
+		Content: note.Content,
+	}
+	err = ts.noteRepository.PutNote(tx, model)
+	if err != nil {
+		ts.logger.Errorf("Error saving task note: %v", err.Error())
+		return nil, err
+	}
+
+	return ts.noteModelToSchema(model), nil
+}
+
+func (ts *TaskServiceImpl) DeleteTaskNote(tx *gorm.DB, taskId int64, userId int64) error {
+	err := ts.noteRepository.DeleteNote(tx, taskId, userId)
+	if err != nil {
+		ts.logger.Errorf("Error deleting task note: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (ts *TaskServiceImpl) GetTaskDrafts(tx *gorm.DB, taskId int64, userId int64) ([]schemas.TaskDraft, error) {
+	drafts, err := ts.draftRepository.GetDrafts(tx, taskId, userId, time.Now().Add(-TaskDraftTTL))
+	if err != nil {
+		ts.logger.Errorf("Error getting task drafts: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.TaskDraft, 0, len(drafts))
+	for _, draft := range drafts {
+		result = append(result, *ts.draftModelToSchema(&draft))
+	}
+	return result, nil
+}
+
+func (ts *TaskServiceImpl) PutTaskDraft(tx *gorm.DB, taskId int64, userId int64, draft schemas.TaskDraftEdit) (*schemas.TaskDraft, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(draft); err != nil {
+		ts.logger.Errorf("Error validating task draft: %v", err.Error())
+		return nil, err
+	}
+
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &models.TaskDraft{
+		TaskId:     taskId,
+		UserId:     userId,
+		LanguageId: draft.LanguageId,
+		Content:    draft.Content,
+	}
+	err = ts.draftRepository.PutDraft(tx, model)
+	if err != nil {
+		ts.logger.Errorf("Error saving task draft: %v", err.Error())
+		return nil, err
+	}
+
+	return ts.draftModelToSchema(model), nil
+}
+
+func (ts *TaskServiceImpl) DeleteExpiredDrafts(tx *gorm.DB) (int64, error) {
+	deleted, err := ts.draftRepository.DeleteDraftsBefore(tx, time.Now().Add(-TaskDraftTTL))
+	if err != nil {
+		ts.logger.Errorf("Error deleting expired task drafts: %v", err.Error())
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (ts *TaskServiceImpl) UpdateTaskCoAuthors(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskCoAuthorsEdit) ([]schemas.TaskCoAuthor, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating task co-authors: %v", err.Error())
+		return nil, err
+	}
+
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		return nil, ErrNotAuthorized
+	}
+
+	coAuthors := make([]models.TaskCoAuthor, 0, len(edit.CoAuthors))
+	seen := make(map[int64]bool, len(edit.CoAuthors))
+	for i, coAuthor := range edit.CoAuthors {
+		if coAuthor.UserId == task.CreatedBy || seen[coAuthor.UserId] {
+			return nil, ErrInvalidCoAuthor
+		}
+		seen[coAuthor.UserId] = true
+
+		user, err := ts.userRepository.GetUser(tx, coAuthor.UserId)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrInvalidCoAuthor
+			}
+			ts.logger.Errorf("Error getting co-author: %v", err.Error())
+			return nil, err
+		}
+		displayName := coAuthor.DisplayName
+		if displayName == "" {
+			displayName = user.Name + " " + user.Surname
+		}
+		coAuthors = append(coAuthors, models.TaskCoAuthor{
+			TaskId:      taskId,
+			UserId:      coAuthor.UserId,
+			Position:    i,
+			DisplayName: displayName,
+		})
+	}
+
+	previous, err := ts.coAuthorRepository.GetCoAuthors(tx, taskId)
+	if err != nil {
+		ts.logger.Errorf("Error getting task co-authors: %v", err.Error())
+		return nil, err
+	}
+	err = ts.coAuthorRepository.ReplaceCoAuthors(tx, taskId, coAuthors)
+	if err != nil {
+		ts.logger.Errorf("Error saving task co-authors: %v", err.Error())
+		return nil, err
+	}
+
+	added := make([]int64, 0, len(coAuthors))
+	removed := make([]int64, 0, len(previous))
+	wasCoAuthor := make(map[int64]bool, len(previous))
+	for _, coAuthor := range previous {
+		wasCoAuthor[coAuthor.UserId] = true
+		if !seen[coAuthor.UserId] {
+			removed = append(removed, coAuthor.UserId)
+		}
+	}
+	for _, coAuthor := range coAuthors {
+		if !wasCoAuthor[coAuthor.UserId] {
+			added = append(added, coAuthor.UserId)
+		}
+	}
+	changes := make([]models.TaskCoAuthorChange, 0, len(added)+len(removed))
+	for _, userId := range added {
+		changes = append(changes, models.TaskCoAuthorChange{TaskId: taskId, UserId: userId, Added: true, ChangedBy: currentUser.Id})
+	}
+	for _, userId := range removed {
+		changes = append(changes, models.TaskCoAuthorChange{TaskId: taskId, UserId: userId, Added: false, ChangedBy: currentUser.Id})
+	}
+	err = ts.coAuthorRepository.RecordChanges(tx, changes)
+	if err != nil {
+		ts.logger.Errorf("Error recording task co-author changes: %v", err.Error())
+		return nil, err
+	}
+
+	actorName := currentUser.Name + " " + currentUser.Surname
+	err = ts.notificationService.Notify(tx, currentUser, added, models.NotificationTypeCoAuthorAdded, &taskId,
+		fmt.Sprintf("%s added you as a co-author of task \"%s\".", actorName, task.Title))
+	if err != nil {
+		return nil, err
+	}
+	err = ts.notificationService.Notify(tx, currentUser, removed, models.NotificationTypeCoAuthorRemoved, &taskId,
+		fmt.Sprintf("%s removed you from the co-authors of task \"%s\".", actorName, task.Title))
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.coAuthorModelsToSchemas(coAuthors), nil
+}
+
+func (ts *TaskServiceImpl) GetTestCaseGroups(tx *gorm.DB, taskId int64) ([]schemas.TestCaseGroup, error) {
+	_, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+
+	groups, err := ts.testGroupRepository.GetGroups(tx, taskId)
+	if err != nil {
+		ts.logger.Errorf("Error getting test groups: %v", err.Error())
+		return nil, err
+	}
+	return ts.testGroupModelsToSchemas(groups), nil
+}
+
+func (ts *TaskServiceImpl) UpdateTestCaseGroups(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TestCaseGroupsEdit) ([]schemas.TestCaseGroup, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating test groups: %v", err.Error())
+		return nil, err
+	}
+
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		return nil, ErrNotAuthorized
+	}
+
+	groups := make([]models.TestCaseGroup, 0, len(edit.Groups))
+	names := make(map[string]bool, len(edit.Groups))
+	grouped := make(map[int64]bool)
+	for i, group := range edit.Groups {
+		if names[group.Name] {
+			return nil, ErrInvalidTestCaseGroups
+		}
+		names[group.Name] = true
+		tests := make([]models.TestCaseGroupTest, 0, len(group.Tests))
+		for _, order := range group.Tests {
+			if grouped[order] {
+				return nil, ErrInvalidTestCaseGroups
+			}
+			grouped[order] = true
+			tests = append(tests, models.TestCaseGroupTest{TaskId: taskId, Order: order})
+		}
+		groups = append(groups, models.TestCaseGroup{
+			TaskId:   taskId,
+			Name:     group.Name,
+			Position: i,
+			Points:   group.Points,
+			Tests:    tests,
+		})
+	}
+
+	err = ts.testGroupRepository.ReplaceGroups(tx, taskId, groups)
+	if err != nil {
+		ts.logger.Errorf("Error saving test groups: %v", err.Error())
+		return nil, err
+	}
+	ts.logger.Infof("Test groups of task %d replaced by user %d, %d groups", taskId, currentUser.Id, len(groups))
+
+	return ts.testGroupModelsToSchemas(groups), nil
+}
+
+func (ts *TaskServiceImpl) testGroupModelsToSchemas(groups []models.TestCaseGroup) []schemas.TestCaseGroup {
+	result := make([]schemas.TestCaseGroup, 0, len(groups))
+	for _, group := range groups {
+		tests := make([]int64, 0, len(group.Tests))
+		for _, test := range group.Tests {
+			tests = append(tests, test.Order)
+		}
+		result = append(result, schemas.TestCaseGroup{
+			Id:     group.Id,
+			Name:   group.Name,
+			Points: group.Points,
+			Tests:  tests,
+		})
+	}
+	return result
+}
+
+func (ts *TaskServiceImpl) GetTaskEvaluationPolicy(tx *gorm.DB, taskId int64) (*schemas.TaskEvaluationPolicy, error) {
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	return ts.evaluationPolicyToSchema(task.EvaluationPolicy), nil
+}
+
+func (ts *TaskServiceImpl) UpdateTaskEvaluationPolicy(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskEvaluationPolicyEdit) (*schemas.TaskEvaluationPolicy, error) {
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating evaluation policy: %v", err.Error())
+		return nil, err
+	}
+	maxLimits := ts.cfg.Evaluation.Max
+	if exceedsLimit(edit.OutputLimit, maxLimits.OutputLimit) || exceedsLimit(edit.StderrLimit, maxLimits.StderrLimit) || exceedsLimit(edit.ProcessLimit, maxLimits.ProcessLimit) {
+		return nil, ErrEvaluationLimitExceeded
+	}
+
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		return nil, ErrNotAuthorized
+	}
+
+	policy := models.EvaluationPolicy{
+		OutputLimit:  edit.OutputLimit,
+		StderrLimit:  edit.StderrLimit,
+		ProcessLimit: edit.ProcessLimit,
+	}
+	err = ts.taskRepository.SetEvaluationPolicy(tx, taskId, policy)
+	if err != nil {
+		ts.logger.Errorf("Error saving evaluation policy: %v", err.Error())
+		return nil, err
+	}
+	ts.logger.Infof("Evaluation policy of task %d replaced by user %d", taskId, currentUser.Id)
+
+	var changes []models.TaskChange
+	limits := []struct {
+		field         string
+		previous, new *int64
+	}{
+		{"output_limit", task.OutputLimit, policy.OutputLimit},
+		{"stderr_limit", task.StderrLimit, policy.StderrLimit},
+		{"process_limit", task.ProcessLimit, policy.ProcessLimit},
+	}
+	for _, limit := range limits {
+		if formatLimit(limit.previous) != formatLimit(limit.new) {
+			changes = append(changes, models.TaskChange{Field: limit.field, OldValue: formatLimit(limit.previous), NewValue: formatLimit(limit.new)})
+		}
+	}
+	err = ts.recordTaskChanges(tx, currentUser, taskId, task.Title, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.evaluationPolicyToSchema(policy), nil
+}
+
+// formatLimit returns the limit as recorded in task changes, unset limits use the default
+func formatLimit(limit *int64) string {
+	if limit == nil {
+		return "default"
+	}
+	return strconv.FormatInt(*limit, 10)
+}
+
+// recordTaskChanges stores changes of the task. While a term is in progress users who submitted to the task in
+// the term are notified, as the change may affect their solutions
+func (ts *TaskServiceImpl) recordTaskChanges(tx *gorm.DB, currentUser schemas.User, taskId int64, title string, changes []models.TaskChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	var termId *int64
+	term, err := ts.termRepository.GetTermAt(tx, time.Now())
+	if err != nil && err != gorm.ErrRecordNotFound {
+		ts.logger.Errorf("Error getting current term: %v", err.Error())
+		return err
+	}
+	if err == nil {
+		termId = &term.Id
+	}
+
+	fields := make([]string, 0, len(changes))
+	for i := range changes {
+		changes[i].TaskId = taskId
+		changes[i].ChangedBy = currentUser.Id
+		changes[i].TermId = termId
+		fields = append(fields, strings.ReplaceAll(changes[i].Field, "_", " "))
+	}
+	err = ts.changeRepository.CreateChanges(tx, changes)
+	if err != nil {
+		ts.logger.Errorf("Error saving task changes: %v", err.Error())
+		return err
+	}
+	if termId == nil {
+		return nil
+	}
+
+	participants, err := ts.submissionRepository.GetTermParticipantIds(tx, taskId, *termId)
+	if err != nil {
+		ts.logger.Errorf("Error getting participants of task: %v", err.Error())
+		return err
+	}
+	return ts.notificationService.Notify(tx, currentUser, participants, models.NotificationTypeTaskUpdated, &taskId,
+		fmt.Sprintf("Task \"%s\" was updated, changed: %s.", title, strings.Join(fields, ", ")))
+}
+
+func (ts *TaskServiceImpl) GetTaskChanges(tx *gorm.DB, taskId int64, limit, offset int64) ([]schemas.TaskChange, error) {
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := ts.changeRepository.GetChanges(tx, taskId, limit, offset)
+	if err != nil {
+		ts.logger.Errorf("Error getting task changes: %v", err.Error())
+		return nil, err
+	}
+	result := make([]schemas.TaskChange, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, schemas.TaskChange{
+			Id:        change.Id,
+			ChangedBy: change.ChangedBy,
+			Field:     change.Field,
+			OldValue:  change.OldValue,
+			NewValue:  change.NewValue,
+			CreatedAt: change.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+func (ts *TaskServiceImpl) evaluationPolicyToSchema(policy models.EvaluationPolicy) *schemas.TaskEvaluationPolicy {
+	limits := effectiveEvaluationLimits(policy, ts.cfg.Evaluation.Defaults)
+	maxLimits := ts.cfg.Evaluation.Max
+	return &schemas.TaskEvaluationPolicy{
+		OutputLimit:  limits.OutputLimit,
+		StderrLimit:  limits.StderrLimit,
+		ProcessLimit: limits.ProcessLimit,
+		Max: schemas.EvaluationPolicyLimits{
+			OutputLimit:  maxLimits.OutputLimit,
+			StderrLimit:  maxLimits.StderrLimit,
+			ProcessLimit: maxLimits.ProcessLimit,
+		},
+	}
+}
+
+// effectiveEvaluationLimits returns the limits of the policy, using the defaults for limits it does not set
+func effectiveEvaluationLimits(policy models.EvaluationPolicy, defaults config.EvaluationLimits) config.EvaluationLimits {
+	limits := defaults
+	if policy.OutputLimit != nil {
+		limits.OutputLimit = *policy.OutputLimit
+	}
+	if policy.StderrLimit != nil {
+		limits.StderrLimit = *policy.StderrLimit
+	}
+	if policy.ProcessLimit != nil {
+		limits.ProcessLimit = *policy.ProcessLimit
+	}
+	return limits
+}
+
+func exceedsLimit(limit *int64, maxLimit int64) bool {
+	return limit != nil && *limit > maxLimit
+}
+
+func (ts *TaskServiceImpl) GetSandboxTasks(tx *gorm.DB, limit, offset int64) ([]schemas.Task, error) {
+	tasks, err := ts.taskRepository.GetSandboxTasks(tx, limit, offset)
+	if err != nil {
+		ts.logger.Errorf("Error getting sandbox tasks: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.Task, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, ts.modelToSchema(task))
+	}
+	return result, nil
+}
+
+func (ts *TaskServiceImpl) GetSandboxTask(tx *gorm.DB, taskId int64) (*schemas.TaskDetailed, error) {
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return nil, err
+	}
+	if !task.Sandbox {
+		return nil, ErrTaskNotFound
+	}
+	return ts.GetTask(tx, taskId)
+}
+
+func (ts *TaskServiceImpl) SetTaskSandbox(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskSandboxEdit) error {
+	if !ts.accessControlService.Can(currentUser, ResourceTask, ActionManageSandbox) {
+		return ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating task sandbox edit: %v", err.Error())
+		return err
+	}
+
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return err
+	}
+
+	err = ts.taskRepository.SetSandbox(tx, taskId, *edit.Sandbox)
+	if err != nil {
+		ts.logger.Errorf("Error updating task sandbox flag: %v", err.Error())
+		return err
+	}
+	ts.logger.Infof("Task %d sandbox flag set to %t by user %d", taskId, *edit.Sandbox, currentUser.Id)
+	return nil
+}
+
+func (ts *TaskServiceImpl) SetTaskTestVisibility(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskTestVisibilityEdit) error {
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating task test visibility edit: %v", err.Error())
+		return err
+	}
+
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		return ErrNotAuthorized
+	}
+
+	err = ts.taskRepository.SetTestVisibility(tx, taskId, models.TestVisibility(edit.TestVisibility))
+	if err != nil {
+		ts.logger.Errorf("Error updating task test visibility: %v", err.Error())
+		return err
+	}
+	ts.logger.Infof("Task %d test visibility set to %s by user %d", taskId, edit.TestVisibility, currentUser.Id)
+	return nil
+}
+
+func (ts *TaskServiceImpl) SetTaskSubmissionRetention(tx *gorm.DB, currentUser schemas.User, taskId int64, edit schemas.TaskSubmissionRetentionEdit) error {
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		ts.logger.Errorf("Error validating task submission retention edit: %v", err.Error())
+		return err
+	}
+
+	task, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTask, ActionEdit, task.CreatedBy) {
+		return ErrNotAuthorized
+	}
+
+	err = ts.taskRepository.SetSubmissionRetention(tx, taskId, models.SubmissionRetention(edit.SubmissionRetention))
+	if err != nil {
+		ts.logger.Errorf("Error updating task submission retention: %v", err.Error())
+		return err
+	}
+	ts.logger.Infof("Task %d submission retention set to %s by user %d", taskId, edit.SubmissionRetention, currentUser.Id)
+	return nil
+}
+
+func (ts *TaskServiceImpl) GetTaskStats(tx *gorm.DB, currentUserId int64, taskId int64, termId *int64, allTime bool) (*schemas.TaskStats, error) {
+	err := ts.ensureTaskExists(tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := ts.taskRepository.GetStatsHiddenTaskIds(tx, currentUserId, []int64{taskId})
+	if err != nil {
+		ts.logger.Errorf("Error getting tasks with hidden statistics: %v", err.Error())
+		return nil, err
+	}
+
+	var term *models.Term
+	if termId != nil {
+		term, err = ts.termRepository.GetTerm(tx, *termId)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrTermNotFound
+			}
+			ts.logger.Errorf("Error getting term: %v", err.Error())
+			return nil, err
+		}
+	} else if !allTime {
+		// term stays nil when no term is in progress
+		term, err = ts.termRepository.GetTermAt(tx, time.Now())
+		if err != nil && err != gorm.ErrRecordNotFound {
+			ts.logger.Errorf("Error getting current term: %v", err.Error())
+			return nil, err
+		}
+	}
+
+	result := &schemas.TaskStats{TaskId: taskId, OwnOnly: len(hidden) > 0}
+	var scope *int64
+	if term != nil {
+		scope = &term.Id
+		result.Term = termModelToSchema(term)
+	}
+	var user *int64
+	if result.OwnOnly {
+		user = &currentUserId
+	}
+	stats, err := ts.submissionRepository.GetTaskStats(tx, taskId, scope, user)
+	if err != nil {
+		ts.logger.Errorf("Error getting task stats: %v", err.Error())
+		return nil, err
+	}
+	result.Attempts = stats.Attempts
+	result.Users = stats.Users
+	result.Accepted = stats.Accepted
+	result.SolvedUsers = stats.SolvedUsers
+	result.AcceptanceRate = computeScore(stats.Accepted, stats.Attempts)
+	return result, nil
+}
+
+// getTaskSummaries returns summaries of the user by task. Until UserTaskSummariesMigration is cut over
+// they are aggregated from submissions, as summaries of older submissions may still be missing
+func (ts *TaskServiceImpl) getTaskSummaries(tx *gorm.DB, userId int64) (map[int64]models.UserTaskSummary, error) {
+	cutOver, err := ts.migrationService.IsCutOver(tx, UserTaskSummariesMigration)
+	if err != nil {
+		return nil, err
+	}
+	var summaries []models.UserTaskSummary
+	if cutOver {
+		summaries, err = ts.summaryRepository.GetSummaries(tx, userId)
+	} else {
+		summaries, err = ts.summaryRepository.ComputeSummaries(tx, userId)
+	}
+	if err != nil {
+		ts.logger.Errorf("Error getting task summaries: %v", err.Error())
+		return nil, err
+	}
+
+	result := make(map[int64]models.UserTaskSummary, len(summaries))
+	for _, summary := range summaries {
+		result[summary.TaskId] = summary
+	}
+	return result, nil
+}
+
+// setTaskVerdicts sets verdicts of the tasks, except the ones whose statistics a group of the user hides.
+// Until TaskVerdictSummariesMigration is cut over they are aggregated from submissions, as summaries of
+// tasks judged earlier may still be missing
+func (ts *TaskServiceImpl) setTaskVerdicts(tx *gorm.DB, userId int64, tasks []schemas.Task) error {
+	cutOver, err := ts.migrationService.IsCutOver(tx, TaskVerdictSummariesMigration)
+	if err != nil {
+		return err
+	}
+	allTaskIds := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		allTaskIds = append(allTaskIds, task.Id)
+	}
+	hidden, err := ts.taskRepository.GetStatsHiddenTaskIds(tx, userId, allTaskIds)
+	if err != nil {
+		ts.logger.Errorf("Error getting tasks with hidden statistics: %v", err.Error())
+		return err
+	}
+	taskIds := slices.DeleteFunc(allTaskIds, func(taskId int64) bool {
+		return slices.Contains(hidden, taskId)
+	})
+	var summaries []models.TaskVerdictSummary
+	if cutOver {
+		summaries, err = ts.verdictRepository.GetSummaries(tx, taskIds)
+	} else {
+		summaries, err = ts.verdictRepository.ComputeSummaries(tx, taskIds)
+	}
+	if err != nil {
+		ts.logger.Errorf("Error getting task verdict summaries: %v", err.Error())
+		return err
+	}
+
+	verdicts := make(map[int64]*schemas.TaskVerdicts)
+	accepted := make(map[int64]int64)
+	for _, summary := range summaries {
+		taskVerdicts, ok := verdicts[summary.TaskId]
+		if !ok {
+			taskVerdicts = &schemas.TaskVerdicts{Codes: make(map[string]int64)}
+			verdicts[summary.TaskId] = taskVerdicts
+		}
+		taskVerdicts.Submissions += summary.Submissions
+		taskVerdicts.Codes[summary.Code] = summary.Submissions
+		accepted[summary.TaskId] += summary.Accepted
+	}
+	for i := range tasks {
+		if taskVerdicts, ok := verdicts[tasks[i].Id]; ok {
+			taskVerdicts.AcceptanceRate = computeScore(accepted[tasks[i].Id], taskVerdicts.Submissions)
+			tasks[i].Verdicts = taskVerdicts
+		}
+	}
+	return nil
+}
+
+func (ts *TaskServiceImpl) ensureTaskExists(tx *gorm.DB, taskId int64) error {
+	_, err := ts.taskRepository.GetTask(tx, taskId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskNotFound
+		}
+		ts.logger.Errorf("Error getting task: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (ts *TaskServiceImpl) noteModelToSchema(model *models.TaskNote) *schemas.TaskNote {
+	return &schemas.TaskNote{
+		TaskId:    model.TaskId,
+		Content:   model.Content,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+func (ts *TaskServiceImpl) draftModelToSchema(model *models.TaskDraft) *schemas.TaskDraft {
+	return &schemas.TaskDraft{
+		TaskId:     model.TaskId,
+		LanguageId: model.LanguageId,
+		Content:    model.Content,
+		UpdatedAt:  model.UpdatedAt,
+		ExpiresAt:  model.UpdatedAt.Add(TaskDraftTTL),
+	}
+}
+
+func (ts *TaskServiceImpl) coAuthorModelsToSchemas(coAuthors []models.TaskCoAuthor) []schemas.TaskCoAuthor {
+	result := make([]schemas.TaskCoAuthor, 0, len(coAuthors))
+	for _, model := range coAuthors {
+		result = append(result, schemas.TaskCoAuthor{
+			UserId:      model.UserId,
+			DisplayName: model.DisplayName,
+		})
+	}
+	return result
+}
+
+func (ts *TaskServiceImpl) CreateTaskPool(tx *gorm.DB, currentUser schemas.User, pool schemas.TaskPoolCreate) (*schemas.TaskPool, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTaskPool, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(pool); err != nil {
+		ts.logger.Errorf("Error validating task pool: %v", err.Error())
+		return nil, err
+	}
+
+	model := &models.TaskPool{
+		Title:     pool.Title,
+		CreatedBy: currentUser.Id,
+		Tasks:     make([]models.TaskPoolTask, 0, len(pool.TaskIds)),
+	}
+	for _, taskId := range pool.TaskIds {
+		task, err := ts.taskRepository.GetTask(tx, taskId)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrInvalidTaskPool
+			}
+			ts.logger.Errorf("Error getting task: %v", err.Error())
+			return nil, err
+		}
+		if !ts.accessControlService.CanAccess(currentUser, ResourceTaskPool, ActionManage, task.CreatedBy) {
+			return nil, ErrInvalidTaskPool
+		}
+		model.Tasks = append(model.Tasks, models.TaskPoolTask{TaskId: taskId})
+	}
+	pooled, err := ts.poolRepository.GetPoolsOfTasks(tx, pool.TaskIds)
+	if err != nil {
+		ts.logger.Errorf("Error getting pools of tasks: %v", err.Error())
+		return nil, err
+	}
+	if len(pooled) > 0 {
+		return nil, ErrInvalidTaskPool
+	}
+
+	poolId, err := ts.poolRepository.CreatePool(tx, model)
+	if err != nil {
+		ts.logger.Errorf("Error creating task pool: %v", err.Error())
+		return nil, err
+	}
+	ts.logger.Infof("Task pool %d of %d tasks created by user %d", poolId, len(model.Tasks), currentUser.Id)
+
+	return ts.GetTaskPool(tx, currentUser, poolId)
+}
+
+func (ts *TaskServiceImpl) GetTaskPool(tx *gorm.DB, currentUser schemas.User, poolId int64) (*schemas.TaskPool, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTaskPool, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	pool, err := ts.poolRepository.GetPool(tx, poolId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTaskPoolNotFound
+		}
+		ts.logger.Errorf("Error getting task pool: %v", err.Error())
+		return nil, err
+	}
+
+	taskIds := make([]int64, 0, len(pool.Tasks))
+	for _, task := range pool.Tasks {
+		taskIds = append(taskIds, task.TaskId)
+	}
+	return &schemas.TaskPool{
+		Id:        pool.Id,
+		Title:     pool.Title,
+		CreatedBy: pool.CreatedBy,
+		CreatedAt: pool.CreatedAt,
+		TaskIds:   taskIds,
+	}, nil
+}
+
+func (ts *TaskServiceImpl) DeleteTaskPool(tx *gorm.DB, currentUser schemas.User, poolId int64) error {
+	pool, err := ts.poolRepository.GetPool(tx, poolId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrTaskPoolNotFound
+		}
+		ts.logger.Errorf("Error getting task pool: %v", err.Error())
+		return err
+	}
+	if !ts.accessControlService.CanAccess(currentUser, ResourceTaskPool, ActionManage, pool.CreatedBy) {
+		return ErrNotAuthorized
+	}
+
+	_, err = ts.poolRepository.DeletePool(tx, poolId)
+	if err != nil {
+		ts.logger.Errorf("Error deleting task pool: %v", err.Error())
+		return err
+	}
+	ts.logger.Infof("Task pool %d deleted by user %d", poolId, currentUser.Id)
+	return nil
+}
+
+// isTaskAssigned reports whether the user is assigned the task. Students are assigned only one task of
+// each pool, other tasks and users other than students are not restricted
+func (ts *TaskServiceImpl) isTaskAssigned(tx *gorm.DB, taskId int64, userId int64) (bool, error) {
+	restricted, err := ts.isRestrictedToAssignedTasks(tx, userId)
+	if err != nil || !restricted {
+		return !restricted, err
+	}
+
+	pools, err := ts.poolRepository.GetPoolsOfTasks(tx, []int64{taskId})
+	if err != nil {
+		ts.logger.Errorf("Error getting pools of task: %v", err.Error())
+		return false, err
+	}
+	for _, pool := range pools {
+		if assignedPoolTask(&pool, userId) != taskId {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isRestrictedToAssignedTasks reports whether the user only sees their variant of pooled tasks.
+// Users who cannot manage task pools are, and so are unknown users
+func (ts *TaskServiceImpl) isRestrictedToAssignedTasks(tx *gorm.DB, userId int64) (bool, error) {
+	user, err := ts.userRepository.GetUser(tx, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return true, nil
+		}
+		ts.logger.Errorf("Error getting user: %v", err.Error())
+		return false, err
+	}
+	current := schemas.User{Id: user.Id, Role: string(user.Role)}
+	if err := ts.accessControlService.ResolvePermissions(tx, &current); err != nil {
+		return false, err
+	}
+	return !ts.accessControlService.Can(current, ResourceTaskPool, ActionManage), nil
+}
+
+// filterUnassignedTasks removes pooled tasks that are not the variant assigned to the user
+func (ts *TaskServiceImpl) filterUnassignedTasks(tx *gorm.DB, userId int64, tasks []models.Task) ([]models.Task, error) {
+	taskIds := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		taskIds = append(taskIds, task.Id)
+	}
+	pools, err := ts.poolRepository.GetPoolsOfTasks(tx, taskIds)
+	if err != nil {
+		ts.logger.Errorf("Error getting pools of tasks: %v", err.Error())
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return tasks, nil
+	}
+
+	unassigned := make(map[int64]bool)
+	for _, pool := range pools {
+		assigned := assignedPoolTask(&pool, userId)
+		for _, task := range pool.Tasks {
+			if task.TaskId != assigned {
+				unassigned[task.TaskId] = true
+			}
+		}
+	}
+	return slices.DeleteFunc(tasks, func(task models.Task) bool {
+		return unassigned[task.Id]
+	}), nil
+}
+
+// assignedPoolTask picks the variant of the user by hashing the pool and user id, so the user keeps
+// getting the same variant as long as the pool is not recreated. Pool tasks must be ordered by task id
+func assignedPoolTask(pool *models.TaskPool, userId int64) int64 {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d:%d", pool.Id, userId)
+	return pool.Tasks[hash.Sum64()%uint64(len(pool.Tasks))].TaskId
+}
+
+func (ts *TaskServiceImpl) updateModel(currentModel *models.Task, updateInfo *schemas.UpdateTask) {
+	if updateInfo.Title != "" {
+		currentModel.Title = updateInfo.Title
+	}
+	if updateInfo.Difficulty == "none" {
+		currentModel.Difficulty = ""
+	} else if updateInfo.Difficulty != "" {
+		currentModel.Difficulty = models.TaskDifficulty(updateInfo.Difficulty)
 	}
 }
 
 func (ts *TaskServiceImpl) modelToSchema(model models.Task) schemas.Task {
 	return schemas.Task{
-		Id:        model.Id,
-		Title:     model.Title,
-		CreatedBy: model.CreatedBy,
-		CreatedAt: model.CreatedAt,
+		Id:         model.Id,
+		Title:      model.Title,
+		CreatedBy:  model.CreatedBy,
+		CreatedAt:  model.CreatedAt,
+		Difficulty: string(model.Difficulty),
 	}
 }
 
-func NewTaskService(cfg *config.Config, taskRepository repository.TaskRepository, submissionRepository repository.SubmissionRepository) TaskService {
+func NewTaskService(cfg *config.Config, taskRepository repository.TaskRepository, submissionRepository repository.SubmissionRepository, bookmarkRepository repository.TaskBookmarkRepository, noteRepository repository.TaskNoteRepository, draftRepository repository.TaskDraftRepository, coAuthorRepository repository.TaskCoAuthorRepository, testGroupRepository repository.TestCaseGroupRepository, poolRepository repository.TaskPoolRepository, userRepository repository.UserRepository, termRepository repository.TermRepository, summaryRepository repository.UserTaskSummaryRepository, verdictRepository repository.TaskVerdictSummaryRepository, changeRepository repository.TaskChangeRepository, tagRepository repository.TagRepository, notificationService NotificationService, migrationService OnlineMigrationService, accessControlService AccessControlService) TaskService {
 	log := logger.NewNamedLogger("task_service")
 	return &TaskServiceImpl{
 		cfg:                  cfg,
 		taskRepository:       taskRepository,
 		submissionRepository: submissionRepository,
+		bookmarkRepository:   bookmarkRepository,
+		noteRepository:       noteRepository,
+		draftRepository:      draftRepository,
+		coAuthorRepository:   coAuthorRepository,
+		testGroupRepository:  testGroupRepository,
+		poolRepository:       poolRepository,
+		userRepository:       userRepository,
+		termRepository:       termRepository,
+		summaryRepository:    summaryRepository,
+		verdictRepository:    verdictRepository,
+		changeRepository:     changeRepository,
+		tagRepository:        tagRepository,
+		notificationService:  notificationService,
+		migrationService:     migrationService,
+		accessControlService: accessControlService,
 		logger:               log,
 	}
 }
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/mini-maxit/backend/internal/config"
 	"github.com/mini-maxit/backend/internal/testutils"
 	"github.com/mini-maxit/backend/package/domain/models"
@@ -18,6 +21,13 @@ type taskServiceTest struct {
 	ur          repository.UserRepository
 	tr          repository.TaskRepository
 	sr          repository.SubmissionRepository
+	br          repository.TaskBookmarkRepository
+	nr          repository.TaskNoteRepository
+	dr          repository.TaskDraftRepository
+	car         repository.TaskCoAuthorRepository
+	termr       repository.TermRepository
+	tagr        repository.TagRepository
+	ns          NotificationService
 	taskService TaskService
 	savePoint   string
 }
@@ -37,7 +47,61 @@ func newTaskServiceTest(t *testing.T) *taskServiceTest {
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	ts := NewTaskService(config, tr, sr)
+	br, err := repository.NewTaskBookmarkRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	nr, err := repository.NewTaskNoteRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	dr, err := repository.NewTaskDraftRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	car, err := repository.NewTaskCoAuthorRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tgr, err := repository.NewTestCaseGroupRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pr, err := repository.NewTaskPoolRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	termr, err := repository.NewTermRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	nor, err := repository.NewNotificationRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	utsr, err := repository.NewUserTaskSummaryRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tvsr, err := repository.NewTaskVerdictSummaryRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	omr, err := repository.NewOnlineMigrationRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tcr, err := repository.NewTaskChangeRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tagr, err := repository.NewTagRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ns := NewNotificationService(nor, ur, nil)
+	accessControlService := newAccessControlServiceTest(t, tx)
+	ts := NewTaskService(config, tr, sr, br, nr, dr, car, tgr, pr, ur, termr, utsr, tvsr, tcr, tagr, ns, NewOnlineMigrationService(omr, accessControlService, DefaultBackfillBatchSize), accessControlService)
 	savePoint := "savepoint"
 	tx.SavePoint(savePoint)
 	return &taskServiceTest{
@@ -46,6 +110,13 @@ func newTaskServiceTest(t *testing.T) *taskServiceTest {
 		ur:          ur,
 		tr:          tr,
 		sr:          sr,
+		br:          br,
+		nr:          nr,
+		dr:          dr,
+		car:         car,
+		termr:       termr,
+		tagr:        tagr,
+		ns:          ns,
 		taskService: ts,
 		savePoint:   savePoint,
 	}
@@ -65,6 +136,11 @@ func (tst *taskServiceTest) createUser(t *testing.T) int64 {
 	return userId
 }
 
+// teacher returns the user with the given id as a teacher, for calls made on behalf of the author of a task
+func (tst *taskServiceTest) teacher(userId int64) schemas.User {
+	return schemas.User{Id: userId, Role: string(models.UserRoleTeacher)}
+}
+
 func (tst *taskServiceTest) rollbackToSavePoint() {
 	tst.tx.RollbackTo(tst.savePoint)
 }
@@ -74,7 +150,7 @@ func TestCreateTask(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		userId := tst.createUser(t)
-		taskId, err := tst.taskService.Create(tst.tx, &schemas.Task{
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
 			Title:     "Test Task",
 			CreatedBy: userId,
 		})
@@ -85,13 +161,13 @@ func TestCreateTask(t *testing.T) {
 
 	t.Run("Non unique title", func(t *testing.T) {
 		userId := tst.createUser(t)
-		taskId, err := tst.taskService.Create(tst.tx, &schemas.Task{
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
 			Title:     "Test Task",
 			CreatedBy: userId,
 		})
 		assert.NoError(t, err)
 		assert.NotEqual(t, int64(0), taskId)
-		taskId, err = tst.taskService.Create(tst.tx, &schemas.Task{
+		taskId, err = tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
 			Title:     "Test Task",
 			CreatedBy: userId,
 		})
@@ -99,6 +175,49 @@ func TestCreateTask(t *testing.T) {
 		assert.Equal(t, int64(0), taskId)
 		tst.rollbackToSavePoint()
 	})
+
+	t.Run("Author is the current user", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId + 100,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		task, err := tst.taskService.GetTask(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.Equal(t, userId, task.CreatedBy)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Custom role without the permission", func(t *testing.T) {
+		userId := tst.createUser(t)
+		grader := schemas.User{Id: userId, Role: "grader", Permissions: []schemas.Permission{
+			{Resource: string(ResourceTask), Action: string(ActionRejudge), Scope: ScopeAll},
+		}}
+		_, err := tst.taskService.Create(tst.tx, grader, &schemas.Task{Title: "Test Task"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestAuthorizeSubmission(t *testing.T) {
+	tst := newTaskServiceTest(t)
+	userId := tst.createUser(t)
+	taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Test Task"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	student := schemas.User{Id: userId, Role: string(models.UserRoleStudent)}
+
+	assert.NoError(t, tst.taskService.AuthorizeSubmission(tst.tx, student, taskId))
+	assert.ErrorIs(t, tst.taskService.AuthorizeSubmission(tst.tx, student, taskId+100), ErrTaskNotFound)
+	grader := schemas.User{Id: userId, Role: "grader", Permissions: []schemas.Permission{
+		{Resource: string(ResourceSubmission), Action: string(ActionBrowse), Scope: ScopeAll},
+	}}
+	assert.ErrorIs(t, tst.taskService.AuthorizeSubmission(tst.tx, grader, taskId), ErrNotAuthorized)
 	tst.tx.Rollback()
 }
 
@@ -110,7 +229,7 @@ func TestGetTaskByTitle(t *testing.T) {
 			Title:     "Test Task",
 			CreatedBy: userId,
 		}
-		taskId, err := tst.taskService.Create(tst.tx, task)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(task.CreatedBy), task)
 		assert.NoError(t, err)
 		assert.NotEqual(t, 0, taskId)
 		taskResp, err := tst.taskService.GetTaskByTitle(tst.tx, task.Title)
@@ -138,17 +257,110 @@ func TestGetAllTasks(t *testing.T) {
 			Title:     "Test Task",
 			CreatedBy: userId,
 		}
-		taskId, err := tst.taskService.Create(tst.tx, task)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(task.CreatedBy), task)
 		assert.NoError(t, err)
 		assert.NotEqual(t, 0, taskId)
-		tasks, err := tst.taskService.GetAll(tst.tx, 10, 0)
+		tasks, err := tst.taskService.GetAll(tst.tx, 0, schemas.TaskFilter{}, 10, 0)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, tasks)
 		tst.rollbackToSavePoint()
 	})
 
+	t.Run("Progress", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Test Task", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: models.LanguageTypeC, Version: "99"}
+		if !assert.NoError(t, tst.tx.Create(language).Error) {
+			t.FailNow()
+		}
+		for order, score := range []float64{100, 50} {
+			submissionId, err := tst.sr.CreateSubmission(tst.tx, models.Submission{TaskId: taskId, UserId: userId, Order: int64(order + 1), LanguageId: language.Id, Status: "completed"})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			result := &models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Score: score}
+			if !assert.NoError(t, tst.tx.Create(result).Error) {
+				t.FailNow()
+			}
+		}
+
+		tasks, err := tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{}, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, tasks, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, &schemas.TaskProgress{Attempts: 2, BestScore: 100, LastVerdict: "OK"}, tasks[0].Progress)
+		verdicts := &schemas.TaskVerdicts{Submissions: 2, AcceptanceRate: 50, Codes: map[string]int64{"OK": 2}}
+		assert.Equal(t, verdicts, tasks[0].Verdicts)
+
+		// Verdicts are of all users
+		tasks, err = tst.taskService.GetAll(tst.tx, userId+1, schemas.TaskFilter{}, 10, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, tasks[0].Progress)
+		assert.Equal(t, verdicts, tasks[0].Verdicts)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		userId := tst.createUser(t)
+		graphTaskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Shortest paths", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		sortTaskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Sorting 100%", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		graphsId, err := tst.tagr.CreateTag(tst.tx, &models.Tag{Name: "graphs", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		dijkstraId, err := tst.tagr.CreateTag(tst.tx, &models.Tag{Name: "dijkstra", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, tst.tagr.SetTaskTags(tst.tx, graphTaskId, []int64{graphsId, dijkstraId})) ||
+			!assert.NoError(t, tst.tagr.SetTaskTags(tst.tx, sortTaskId, []int64{graphsId})) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, tst.tx.Model(&models.Task{}).Where("id = ?", sortTaskId).Update("difficulty", models.TaskDifficultyEasy).Error) {
+			t.FailNow()
+		}
+
+		taskIds := func(t *testing.T, filter schemas.TaskFilter) []int64 {
+			tasks, err := tst.taskService.GetAll(tst.tx, userId, filter, 10, 0)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			ids := []int64{}
+			for _, task := range tasks {
+				ids = append(ids, task.Id)
+			}
+			return ids
+		}
+		assert.Equal(t, []int64{graphTaskId, sortTaskId}, taskIds(t, schemas.TaskFilter{Tags: []string{"Graphs"}}))
+		assert.Equal(t, []int64{graphTaskId}, taskIds(t, schemas.TaskFilter{Tags: []string{"graphs", "dijkstra"}}))
+		assert.Equal(t, []int64{sortTaskId}, taskIds(t, schemas.TaskFilter{Difficulty: "easy"}))
+		assert.Equal(t, []int64{graphTaskId}, taskIds(t, schemas.TaskFilter{Search: "PATHS"}))
+		assert.Equal(t, []int64{graphTaskId}, taskIds(t, schemas.TaskFilter{Search: "dijk"}))
+		// Wildcards are matched literally
+		assert.Equal(t, []int64{sortTaskId}, taskIds(t, schemas.TaskFilter{Search: "%"}))
+
+		tasks, err := tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{Search: "paths"}, 10, 0)
+		if assert.NoError(t, err) && assert.Len(t, tasks, 1) {
+			assert.Equal(t, []string{"dijkstra", "graphs"}, tasks[0].Tags)
+		}
+
+		_, err = tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{Difficulty: "impossible"}, 10, 0)
+		var validationErrors validator.ValidationErrors
+		assert.ErrorAs(t, err, &validationErrors)
+		tst.rollbackToSavePoint()
+	})
+
 	t.Run("No tasks", func(t *testing.T) {
-		tasks, err := tst.taskService.GetAll(tst.tx, 10, 0)
+		tasks, err := tst.taskService.GetAll(tst.tx, 0, schemas.TaskFilter{}, 10, 0)
 		assert.NoError(t, err)
 		assert.Empty(t, tasks)
 		tst.rollbackToSavePoint()
@@ -166,7 +378,7 @@ func TestGetTask(t *testing.T) {
 			Title:     "Test Task",
 			CreatedBy: userId,
 		}
-		taskId, err := tst.taskService.Create(tst.tx, task)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(task.CreatedBy), task)
 		assert.NoError(t, err)
 		assert.NotEqual(t, 0, taskId)
 		taskResp, err := tst.taskService.GetTask(tst.tx, taskId)
@@ -187,27 +399,920 @@ func TestUpdateTask(t *testing.T) {
 			Title:     "Test Task",
 			CreatedBy: userId,
 		}
-		taskId, err := tst.taskService.Create(tst.tx, task)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(task.CreatedBy), task)
 		assert.NoError(t, err)
 		assert.NotEqual(t, 0, taskId)
 		updatedTask := schemas.UpdateTask{
-			Title: "Updated Task",
+			Title:   "Updated Task",
+			Version: 1,
 		}
-		err = tst.taskService.UpdateTask(tst.tx, taskId, updatedTask)
-		assert.NoError(t, err)
-		taskResp, err := tst.taskService.GetTask(tst.tx, taskId)
+		taskResp, err := tst.taskService.UpdateTask(tst.tx, schemas.User{Id: userId}, taskId, updatedTask)
 		assert.NoError(t, err)
 		assert.Equal(t, updatedTask.Title, taskResp.Title)
 		assert.Equal(t, task.CreatedBy, taskResp.CreatedBy)
+		assert.Equal(t, int64(2), taskResp.Version)
+		tst.rollbackToSavePoint()
+	})
+	t.Run("Difficulty", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		taskResp, err := tst.taskService.UpdateTask(tst.tx, schemas.User{Id: userId}, taskId, schemas.UpdateTask{Difficulty: "hard", Version: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, "hard", taskResp.Difficulty)
+		assert.Equal(t, "Test Task", taskResp.Title)
+		taskResp, err = tst.taskService.UpdateTask(tst.tx, schemas.User{Id: userId}, taskId, schemas.UpdateTask{Difficulty: "none", Version: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, "", taskResp.Difficulty)
+		tst.rollbackToSavePoint()
+	})
+	t.Run("Outdated version", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		_, err = tst.taskService.UpdateTask(tst.tx, schemas.User{Id: userId}, taskId, schemas.UpdateTask{Title: "First edit", Version: 1})
+		assert.NoError(t, err)
+		_, err = tst.taskService.UpdateTask(tst.tx, schemas.User{Id: userId}, taskId, schemas.UpdateTask{Title: "Second edit", Version: 1})
+		assert.ErrorIs(t, err, ErrTaskVersionConflict)
+		taskResp, err := tst.taskService.GetTask(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.Equal(t, "First edit", taskResp.Title)
+		tst.rollbackToSavePoint()
+	})
+	t.Run("Not author", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		_, err = tst.taskService.UpdateTask(tst.tx, schemas.User{Id: userId + 1, Role: string(models.UserRoleStudent)}, taskId, schemas.UpdateTask{Title: "Updated Task", Version: 1})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
 		tst.rollbackToSavePoint()
 	})
 	t.Run("Nonexistent task", func(t *testing.T) {
 		updatedTask := schemas.UpdateTask{
-			Title: "Updated Task",
+			Title:   "Updated Task",
+			Version: 1,
+		}
+		_, err := tst.taskService.UpdateTask(tst.tx, schemas.User{}, 0, updatedTask)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestBookmarkTask(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	t.Run("Success", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		_, err = tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Other Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+
+		err = tst.taskService.BookmarkTask(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+		// Bookmarking twice is not an error
+		err = tst.taskService.BookmarkTask(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+
+		tasks, err := tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{Bookmarked: true}, 10, 0)
+		assert.NoError(t, err)
+		if assert.Len(t, tasks, 1) {
+			assert.Equal(t, taskId, tasks[0].Id)
+			assert.True(t, tasks[0].Bookmarked)
 		}
-		err := tst.taskService.UpdateTask(tst.tx, 0, updatedTask)
+
+		err = tst.taskService.UnbookmarkTask(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+		tasks, err = tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{Bookmarked: true}, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, tasks)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Nonexistent task", func(t *testing.T) {
+		userId := tst.createUser(t)
+		err := tst.taskService.BookmarkTask(tst.tx, 0, userId)
 		assert.ErrorIs(t, err, ErrTaskNotFound)
 		tst.rollbackToSavePoint()
 	})
 	tst.tx.Rollback()
 }
+
+func TestTaskNote(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	t.Run("Success", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+
+		_, err = tst.taskService.PutTaskNote(tst.tx, taskId, userId, schemas.TaskNoteEdit{Content: "first"})
+		assert.NoError(t, err)
+		_, err = tst.taskService.PutTaskNote(tst.tx, taskId, userId, schemas.TaskNoteEdit{Content: "# second"})
+		assert.NoError(t, err)
+
+		note, err := tst.taskService.GetTaskNote(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+		assert.Equal(t, "# second", note.Content)
+
+		tasks, err := tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{}, 10, 0)
+		assert.NoError(t, err)
+		if assert.Len(t, tasks, 1) {
+			assert.True(t, tasks[0].HasNote)
+		}
+
+		err = tst.taskService.DeleteTaskNote(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+		_, err = tst.taskService.GetTaskNote(tst.tx, taskId, userId)
+		assert.ErrorIs(t, err, ErrTaskNoteNotFound)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Too long note", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		note, err := tst.taskService.PutTaskNote(tst.tx, taskId, userId, schemas.TaskNoteEdit{Content: strings.Repeat("a", 10001)})
+		assert.Error(t, err)
+		assert.Nil(t, note)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestTaskDraft(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	t.Run("Per language drafts", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		_, err = tst.taskService.PutTaskDraft(tst.tx, taskId, userId, schemas.TaskDraftEdit{LanguageId: 1, Content: "first"})
+		assert.NoError(t, err)
+		_, err = tst.taskService.PutTaskDraft(tst.tx, taskId, userId, schemas.TaskDraftEdit{LanguageId: 1, Content: "second"})
+		assert.NoError(t, err)
+		_, err = tst.taskService.PutTaskDraft(tst.tx, taskId, userId, schemas.TaskDraftEdit{LanguageId: 2, Content: "other"})
+		assert.NoError(t, err)
+
+		drafts, err := tst.taskService.GetTaskDrafts(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+		if assert.Len(t, drafts, 2) {
+			assert.Equal(t, "second", drafts[0].Content)
+			assert.Equal(t, "other", drafts[1].Content)
+		}
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Expired draft", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		_, err = tst.taskService.PutTaskDraft(tst.tx, taskId, userId, schemas.TaskDraftEdit{LanguageId: 1, Content: "old"})
+		assert.NoError(t, err)
+		err = tst.tx.Model(&models.TaskDraft{}).Where("task_id = ?", taskId).UpdateColumn("updated_at", time.Now().Add(-TaskDraftTTL-time.Hour)).Error
+		assert.NoError(t, err)
+
+		drafts, err := tst.taskService.GetTaskDrafts(tst.tx, taskId, userId)
+		assert.NoError(t, err)
+		assert.Empty(t, drafts)
+		deleted, err := tst.taskService.DeleteExpiredDrafts(tst.tx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Too large draft", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		assert.NoError(t, err)
+		draft, err := tst.taskService.PutTaskDraft(tst.tx, taskId, userId, schemas.TaskDraftEdit{LanguageId: 1, Content: strings.Repeat("a", 65537)})
+		assert.Error(t, err)
+		assert.Nil(t, draft)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestUpdateTaskCoAuthors(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	createTask := func(t *testing.T) (schemas.User, int64, int64) {
+		authorId := tst.createUser(t)
+		coAuthorId, err := tst.ur.CreateUser(tst.tx, &models.User{
+			Name:         "Co",
+			Surname:      "Author",
+			Email:        "coauthor@email.com",
+			Username:     "coauthor",
+			PasswordHash: "password",
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: authorId,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		author := schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}
+		return author, coAuthorId, taskId
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		author, coAuthorId, taskId := createTask(t)
+		coAuthors, err := tst.taskService.UpdateTaskCoAuthors(tst.tx, author, taskId, schemas.TaskCoAuthorsEdit{
+			CoAuthors: []schemas.TaskCoAuthorEdit{{UserId: coAuthorId}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []schemas.TaskCoAuthor{{UserId: coAuthorId, DisplayName: "Co Author"}}, coAuthors)
+
+		_, err = tst.taskService.UpdateTaskCoAuthors(tst.tx, author, taskId, schemas.TaskCoAuthorsEdit{
+			CoAuthors: []schemas.TaskCoAuthorEdit{{UserId: coAuthorId, DisplayName: "Dr. Co Author"}},
+		})
+		assert.NoError(t, err)
+
+		task, err := tst.taskService.GetTask(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.Equal(t, []schemas.TaskCoAuthor{{UserId: coAuthorId, DisplayName: "Dr. Co Author"}}, task.CoAuthors)
+
+		// Renaming a co-author is not a collaborator change
+		notifications, err := tst.ns.GetNotifications(tst.tx, schemas.User{Id: coAuthorId}, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, notifications, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.NotificationTypeCoAuthorAdded), notifications[0].Type)
+		assert.Equal(t, author.Id, notifications[0].ActorId)
+		assert.Equal(t, &taskId, notifications[0].TaskId)
+
+		_, err = tst.taskService.UpdateTaskCoAuthors(tst.tx, author, taskId, schemas.TaskCoAuthorsEdit{})
+		assert.NoError(t, err)
+		notifications, err = tst.ns.GetNotifications(tst.tx, schemas.User{Id: coAuthorId}, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, notifications, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.NotificationTypeCoAuthorRemoved), notifications[0].Type)
+
+		var changes []models.TaskCoAuthorChange
+		err = tst.tx.Where("task_id = ?", taskId).Order("id").Find(&changes).Error
+		if !assert.NoError(t, err) || !assert.Len(t, changes, 2) {
+			t.FailNow()
+		}
+		assert.True(t, changes[0].Added)
+		assert.False(t, changes[1].Added)
+		assert.Equal(t, coAuthorId, changes[1].UserId)
+		assert.Equal(t, author.Id, changes[1].ChangedBy)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Not the author", func(t *testing.T) {
+		_, coAuthorId, taskId := createTask(t)
+		coAuthor := schemas.User{Id: coAuthorId, Role: string(models.UserRoleTeacher)}
+		_, err := tst.taskService.UpdateTaskCoAuthors(tst.tx, coAuthor, taskId, schemas.TaskCoAuthorsEdit{})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Invalid co-author", func(t *testing.T) {
+		author, coAuthorId, taskId := createTask(t)
+		invalid := [][]schemas.TaskCoAuthorEdit{
+			{{UserId: author.Id}},
+			{{UserId: coAuthorId}, {UserId: coAuthorId}},
+			{{UserId: coAuthorId + 100}},
+		}
+		for _, coAuthors := range invalid {
+			_, err := tst.taskService.UpdateTaskCoAuthors(tst.tx, author, taskId, schemas.TaskCoAuthorsEdit{CoAuthors: coAuthors})
+			assert.ErrorIs(t, err, ErrInvalidCoAuthor)
+		}
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestUpdateTestCaseGroups(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	createTask := func(t *testing.T) (schemas.User, int64) {
+		authorId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: authorId,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}, taskId
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		author, taskId := createTask(t)
+		_, err := tst.taskService.UpdateTestCaseGroups(tst.tx, author, taskId, schemas.TestCaseGroupsEdit{
+			Groups: []schemas.TestCaseGroupEdit{{Name: "All", Points: 100, Tests: []int64{1, 2, 3}}},
+		})
+		assert.NoError(t, err)
+
+		groups, err := tst.taskService.UpdateTestCaseGroups(tst.tx, author, taskId, schemas.TestCaseGroupsEdit{
+			Groups: []schemas.TestCaseGroupEdit{
+				{Name: "Small", Points: 40, Tests: []int64{1, 2}},
+				{Name: "Large", Points: 60, Tests: []int64{3}},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, groups, 2)
+
+		groups, err = tst.taskService.GetTestCaseGroups(tst.tx, taskId)
+		if !assert.NoError(t, err) || !assert.Len(t, groups, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, "Small", groups[0].Name)
+		assert.Equal(t, int64(40), groups[0].Points)
+		assert.Equal(t, []int64{1, 2}, groups[0].Tests)
+		assert.Equal(t, "Large", groups[1].Name)
+		assert.Equal(t, []int64{3}, groups[1].Tests)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Not the author", func(t *testing.T) {
+		author, taskId := createTask(t)
+		other := schemas.User{Id: author.Id + 100, Role: string(models.UserRoleTeacher)}
+		_, err := tst.taskService.UpdateTestCaseGroups(tst.tx, other, taskId, schemas.TestCaseGroupsEdit{})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Invalid groups", func(t *testing.T) {
+		author, taskId := createTask(t)
+		invalid := [][]schemas.TestCaseGroupEdit{
+			{{Name: "A", Points: 50, Tests: []int64{1}}, {Name: "A", Points: 50, Tests: []int64{2}}},
+			{{Name: "A", Points: 50, Tests: []int64{1}}, {Name: "B", Points: 50, Tests: []int64{1}}},
+		}
+		for _, groups := range invalid {
+			_, err := tst.taskService.UpdateTestCaseGroups(tst.tx, author, taskId, schemas.TestCaseGroupsEdit{Groups: groups})
+			assert.ErrorIs(t, err, ErrInvalidTestCaseGroups)
+		}
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Task not found", func(t *testing.T) {
+		_, err := tst.taskService.GetTestCaseGroups(tst.tx, 0)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestUpdateTaskEvaluationPolicy(t *testing.T) {
+	tst := newTaskServiceTest(t)
+	defaults := tst.config.Evaluation.Defaults
+
+	createTask := func(t *testing.T) (schemas.User, int64) {
+		authorId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: authorId,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}, taskId
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		_, taskId := createTask(t)
+		policy, err := tst.taskService.GetTaskEvaluationPolicy(tst.tx, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, defaults.OutputLimit, policy.OutputLimit)
+		assert.Equal(t, defaults.StderrLimit, policy.StderrLimit)
+		assert.Equal(t, defaults.ProcessLimit, policy.ProcessLimit)
+		assert.Equal(t, tst.config.Evaluation.Max.OutputLimit, policy.Max.OutputLimit)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		author, taskId := createTask(t)
+		outputLimit := int64(8 << 10)
+		processLimit := int64(4)
+		_, err := tst.taskService.UpdateTaskEvaluationPolicy(tst.tx, author, taskId, schemas.TaskEvaluationPolicyEdit{
+			OutputLimit:  &outputLimit,
+			ProcessLimit: &processLimit,
+		})
+		assert.NoError(t, err)
+
+		policy, err := tst.taskService.GetTaskEvaluationPolicy(tst.tx, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, outputLimit, policy.OutputLimit)
+		assert.Equal(t, defaults.StderrLimit, policy.StderrLimit)
+		assert.Equal(t, processLimit, policy.ProcessLimit)
+
+		_, err = tst.taskService.UpdateTaskEvaluationPolicy(tst.tx, author, taskId, schemas.TaskEvaluationPolicyEdit{})
+		assert.NoError(t, err)
+		policy, err = tst.taskService.GetTaskEvaluationPolicy(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.Equal(t, defaults.OutputLimit, policy.OutputLimit)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Above the platform limit", func(t *testing.T) {
+		author, taskId := createTask(t)
+		processLimit := tst.config.Evaluation.Max.ProcessLimit + 1
+		_, err := tst.taskService.UpdateTaskEvaluationPolicy(tst.tx, author, taskId, schemas.TaskEvaluationPolicyEdit{ProcessLimit: &processLimit})
+		assert.ErrorIs(t, err, ErrEvaluationLimitExceeded)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Not the author", func(t *testing.T) {
+		author, taskId := createTask(t)
+		other := schemas.User{Id: author.Id + 100, Role: string(models.UserRoleTeacher)}
+		_, err := tst.taskService.UpdateTaskEvaluationPolicy(tst.tx, other, taskId, schemas.TaskEvaluationPolicyEdit{})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestTaskSandbox(t *testing.T) {
+	tst := newTaskServiceTest(t)
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+	enabled := true
+	disabled := false
+
+	createTask := func(t *testing.T) int64 {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: userId,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return taskId
+	}
+
+	t.Run("Add and remove", func(t *testing.T) {
+		taskId := createTask(t)
+		_, err := tst.taskService.GetSandboxTask(tst.tx, taskId)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+
+		err = tst.taskService.SetTaskSandbox(tst.tx, admin, taskId, schemas.TaskSandboxEdit{Sandbox: &enabled})
+		assert.NoError(t, err)
+		task, err := tst.taskService.GetSandboxTask(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.True(t, task.Sandbox)
+		tasks, err := tst.taskService.GetSandboxTasks(tst.tx, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 1)
+
+		err = tst.taskService.SetTaskSandbox(tst.tx, admin, taskId, schemas.TaskSandboxEdit{Sandbox: &disabled})
+		assert.NoError(t, err)
+		tasks, err = tst.taskService.GetSandboxTasks(tst.tx, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, tasks)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		taskId := createTask(t)
+		teacher := schemas.User{Id: 1, Role: string(models.UserRoleTeacher)}
+		err := tst.taskService.SetTaskSandbox(tst.tx, teacher, taskId, schemas.TaskSandboxEdit{Sandbox: &enabled})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Task not found", func(t *testing.T) {
+		err := tst.taskService.SetTaskSandbox(tst.tx, admin, 1000, schemas.TaskSandboxEdit{Sandbox: &enabled})
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestSetTaskTestVisibility(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	createTask := func(t *testing.T) (schemas.User, int64) {
+		authorId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{
+			Title:     "Test Task",
+			CreatedBy: authorId,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}, taskId
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		author, taskId := createTask(t)
+		task, err := tst.taskService.GetTask(tst.tx, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "all", task.TestVisibility)
+
+		err = tst.taskService.SetTaskTestVisibility(tst.tx, author, taskId, schemas.TaskTestVisibilityEdit{TestVisibility: "first_failed"})
+		assert.NoError(t, err)
+		task, err = tst.taskService.GetTask(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.Equal(t, "first_failed", task.TestVisibility)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Invalid visibility", func(t *testing.T) {
+		author, taskId := createTask(t)
+		err := tst.taskService.SetTaskTestVisibility(tst.tx, author, taskId, schemas.TaskTestVisibilityEdit{TestVisibility: "some"})
+		var validationErrors validator.ValidationErrors
+		assert.ErrorAs(t, err, &validationErrors)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Not the author", func(t *testing.T) {
+		author, taskId := createTask(t)
+		other := schemas.User{Id: author.Id + 100, Role: string(models.UserRoleTeacher)}
+		err := tst.taskService.SetTaskTestVisibility(tst.tx, other, taskId, schemas.TaskTestVisibilityEdit{TestVisibility: "hidden"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestSetTaskSubmissionRetention(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	authorId := tst.createUser(t)
+	taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{
+		Title:     "Test Task",
+		CreatedBy: authorId,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	author := schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}
+
+	t.Run("Success", func(t *testing.T) {
+		task, err := tst.taskService.GetTask(tst.tx, taskId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "default", task.SubmissionRetention)
+
+		err = tst.taskService.SetTaskSubmissionRetention(tst.tx, author, taskId, schemas.TaskSubmissionRetentionEdit{SubmissionRetention: "best_and_latest"})
+		assert.NoError(t, err)
+		task, err = tst.taskService.GetTask(tst.tx, taskId)
+		assert.NoError(t, err)
+		assert.Equal(t, "best_and_latest", task.SubmissionRetention)
+	})
+
+	t.Run("Not the author", func(t *testing.T) {
+		other := schemas.User{Id: authorId + 100, Role: string(models.UserRoleTeacher)}
+		err := tst.taskService.SetTaskSubmissionRetention(tst.tx, other, taskId, schemas.TaskSubmissionRetentionEdit{SubmissionRetention: "all"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+	tst.tx.Rollback()
+}
+
+func TestGetTaskStats(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	t.Run("Scoped to the term in progress", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Test Task", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: "c", Version: "99"}
+		if !assert.NoError(t, tst.tx.Create(language).Error) {
+			t.FailNow()
+		}
+		// Submitted before any term exists
+		_, err = tst.taskService.CreateSubmission(tst.tx, taskId, userId, language.Id, 1, "")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		term := &models.Term{Name: "Current", StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(time.Hour)}
+		_, err = tst.termr.CreateTerm(tst.tx, term)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		submissionId, err := tst.taskService.CreateSubmission(tst.tx, taskId, userId, language.Id, 2, "")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		result := &models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Message: "accepted", PassedTests: 1, TotalTests: 1, Score: 100}
+		if !assert.NoError(t, tst.tx.Create(result).Error) {
+			t.FailNow()
+		}
+
+		stats, err := tst.taskService.GetTaskStats(tst.tx, userId, taskId, nil, false)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, term.Id, stats.Term.Id)
+		assert.Equal(t, int64(1), stats.Attempts)
+		assert.Equal(t, int64(1), stats.SolvedUsers)
+		assert.Equal(t, float64(100), stats.AcceptanceRate)
+
+		stats, err = tst.taskService.GetTaskStats(tst.tx, userId, taskId, nil, true)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Nil(t, stats.Term)
+		assert.Equal(t, int64(2), stats.Attempts)
+		assert.Equal(t, float64(50), stats.AcceptanceRate)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Hidden by a group of the user", func(t *testing.T) {
+		userId := tst.createUser(t)
+		studentId, err := tst.ur.CreateUser(tst.tx, &models.User{Name: "Student", Surname: "User", Email: "student@email.com", Username: "student", PasswordHash: "password"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Test Task", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: "c", Version: "99"}
+		if !assert.NoError(t, tst.tx.Create(language).Error) {
+			t.FailNow()
+		}
+		for order, submitter := range []int64{userId, studentId} {
+			submissionId, err := tst.taskService.CreateSubmission(tst.tx, taskId, submitter, language.Id, int64(order+1), "")
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.NoError(t, tst.tx.Create(&models.SubmissionResult{SubmissionId: submissionId, Code: "OK", Score: 100}).Error)
+		}
+		group := &models.Group{Name: "Group"}
+		if !assert.NoError(t, tst.tx.Create(group).Error) {
+			t.FailNow()
+		}
+		assert.NoError(t, tst.tx.Create(&models.TaskGroup{TaskId: taskId, GroupId: group.Id, HideStats: true}).Error)
+		assert.NoError(t, tst.tx.Create(&models.UserGroup{UserId: studentId, GroupId: group.Id}).Error)
+
+		stats, err := tst.taskService.GetTaskStats(tst.tx, studentId, taskId, nil, true)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.True(t, stats.OwnOnly)
+		assert.Equal(t, int64(1), stats.Attempts)
+		tasks, err := tst.taskService.GetAll(tst.tx, studentId, schemas.TaskFilter{}, 10, 0)
+		if assert.NoError(t, err) && assert.Len(t, tasks, 1) {
+			assert.Nil(t, tasks[0].Verdicts)
+		}
+
+		stats, err = tst.taskService.GetTaskStats(tst.tx, userId, taskId, nil, true)
+		assert.NoError(t, err)
+		assert.False(t, stats.OwnOnly)
+		assert.Equal(t, int64(2), stats.Attempts)
+		tasks, err = tst.taskService.GetAll(tst.tx, userId, schemas.TaskFilter{}, 10, 0)
+		if assert.NoError(t, err) && assert.Len(t, tasks, 1) {
+			assert.NotNil(t, tasks[0].Verdicts)
+		}
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Term not found", func(t *testing.T) {
+		userId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(userId), &schemas.Task{Title: "Test Task", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		termId := int64(1000)
+		_, err = tst.taskService.GetTaskStats(tst.tx, userId, taskId, &termId, false)
+		assert.ErrorIs(t, err, ErrTermNotFound)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestTaskChanges(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	t.Run("Notifies participants of the term in progress", func(t *testing.T) {
+		authorId := tst.createUser(t)
+		author := schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}
+		studentId, err := tst.ur.CreateUser(tst.tx, &models.User{Name: "Student", Surname: "User", Email: "student@email.com", Username: "student", PasswordHash: "password"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{Title: "Test Task", CreatedBy: authorId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		term := &models.Term{Name: "Current", StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(time.Hour)}
+		_, err = tst.termr.CreateTerm(tst.tx, term)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		language := &models.LanguageConfig{Type: models.LanguageTypeC, Version: "99"}
+		if !assert.NoError(t, tst.tx.Create(language).Error) {
+			t.FailNow()
+		}
+		_, err = tst.taskService.CreateSubmission(tst.tx, taskId, studentId, language.Id, 1, "")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		_, err = tst.taskService.UpdateTask(tst.tx, author, taskId, schemas.UpdateTask{Title: "Renamed Task", Version: 1})
+		assert.NoError(t, err)
+		outputLimit := int64(8 << 10)
+		_, err = tst.taskService.UpdateTaskEvaluationPolicy(tst.tx, author, taskId, schemas.TaskEvaluationPolicyEdit{OutputLimit: &outputLimit})
+		assert.NoError(t, err)
+
+		changes, err := tst.taskService.GetTaskChanges(tst.tx, taskId, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, changes, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, "output_limit", changes[0].Field)
+		assert.Equal(t, "default", changes[0].OldValue)
+		assert.Equal(t, "8192", changes[0].NewValue)
+		assert.Equal(t, "title", changes[1].Field)
+		assert.Equal(t, "Renamed Task", changes[1].NewValue)
+
+		notifications, err := tst.ns.GetNotifications(tst.tx, schemas.User{Id: studentId}, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, notifications, 2) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.NotificationTypeTaskUpdated), notifications[0].Type)
+		assert.Equal(t, &taskId, notifications[0].TaskId)
+
+		tasks, err := tst.taskService.GetAll(tst.tx, studentId, schemas.TaskFilter{}, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, tasks, 1) {
+			t.FailNow()
+		}
+		assert.True(t, tasks[0].Updated)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Unchanged title", func(t *testing.T) {
+		authorId := tst.createUser(t)
+		taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{Title: "Test Task", CreatedBy: authorId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = tst.taskService.UpdateTask(tst.tx, schemas.User{Id: authorId}, taskId, schemas.UpdateTask{Title: "Test Task", Version: 1})
+		assert.NoError(t, err)
+		changes, err := tst.taskService.GetTaskChanges(tst.tx, taskId, 10, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Task not found", func(t *testing.T) {
+		_, err := tst.taskService.GetTaskChanges(tst.tx, 1000, 10, 0)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+		tst.rollbackToSavePoint()
+	})
+	tst.tx.Rollback()
+}
+
+func TestTaskPool(t *testing.T) {
+	tst := newTaskServiceTest(t)
+
+	createPool := func(t *testing.T) (schemas.User, int64, *schemas.TaskPool) {
+		authorId, err := tst.ur.CreateUser(tst.tx, &models.User{
+			Name:         "Test Teacher",
+			Surname:      "Test Surname",
+			Email:        "teacher@email.com",
+			Username:     "testteacher",
+			PasswordHash: "password",
+			Role:         models.UserRoleTeacher,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		studentId := tst.createUser(t)
+		author := schemas.User{Id: authorId, Role: string(models.UserRoleTeacher)}
+		var taskIds []int64
+		for _, title := range []string{"Variant A", "Variant B", "Variant C"} {
+			taskId, err := tst.taskService.Create(tst.tx, tst.teacher(authorId), &schemas.Task{Title: title, CreatedBy: authorId})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			taskIds = append(taskIds, taskId)
+		}
+		pool, err := tst.taskService.CreateTaskPool(tst.tx, author, schemas.TaskPoolCreate{Title: "Homework 1", TaskIds: taskIds})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return author, studentId, pool
+	}
+
+	t.Run("Student is assigned one variant", func(t *testing.T) {
+		author, studentId, pool := createPool(t)
+		assert.Len(t, pool.TaskIds, 3)
+
+		tasks, err := tst.taskService.GetAll(tst.tx, studentId, schemas.TaskFilter{}, 10, 0)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if assert.Len(t, tasks, 1) {
+			student := schemas.User{Id: studentId, Role: string(models.UserRoleStudent)}
+			for _, taskId := range pool.TaskIds {
+				err := tst.taskService.AuthorizeSubmission(tst.tx, student, taskId)
+				if taskId == tasks[0].Id {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, ErrTaskNotAssigned)
+				}
+			}
+		}
+
+		tasks, err = tst.taskService.GetAll(tst.tx, author.Id, schemas.TaskFilter{}, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 3)
+		err = tst.taskService.AuthorizeSubmission(tst.tx, author, pool.TaskIds[0])
+		assert.NoError(t, err)
+
+		err = tst.taskService.DeleteTaskPool(tst.tx, author, pool.Id)
+		assert.NoError(t, err)
+		tasks, err = tst.taskService.GetAll(tst.tx, studentId, schemas.TaskFilter{}, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 3)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Custom role is assigned one variant", func(t *testing.T) {
+		_, userId, pool := createPool(t)
+		rr, err := repository.NewRoleRepository(tst.tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = rr.CreateRole(tst.tx, &models.Role{Name: "assistant", Permissions: []models.RolePermission{{Resource: "submission", Action: "create", Scope: ScopeAll}}})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.NoError(t, tst.ur.UpdateRole(tst.tx, userId, "assistant")) {
+			t.FailNow()
+		}
+
+		assistant := schemas.User{Id: userId, Role: "assistant", Permissions: []schemas.Permission{
+			{Resource: string(ResourceSubmission), Action: string(ActionCreate), Scope: ScopeAll},
+		}}
+		assignedVariants := 0
+		for _, taskId := range pool.TaskIds {
+			err := tst.taskService.AuthorizeSubmission(tst.tx, assistant, taskId)
+			if err == nil {
+				assignedVariants++
+			} else {
+				assert.ErrorIs(t, err, ErrTaskNotAssigned)
+			}
+		}
+		assert.Equal(t, 1, assignedVariants)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Task already in a pool", func(t *testing.T) {
+		author, _, pool := createPool(t)
+		_, err := tst.taskService.CreateTaskPool(tst.tx, author, schemas.TaskPoolCreate{Title: "Homework 2", TaskIds: pool.TaskIds[:2]})
+		assert.ErrorIs(t, err, ErrInvalidTaskPool)
+		tst.rollbackToSavePoint()
+	})
+
+	t.Run("Not authorized", func(t *testing.T) {
+		_, studentId, pool := createPool(t)
+		student := schemas.User{Id: studentId, Role: string(models.UserRoleStudent)}
+		_, err := tst.taskService.CreateTaskPool(tst.tx, student, schemas.TaskPoolCreate{Title: "Homework 2", TaskIds: []int64{1, 2}})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		_, err = tst.taskService.GetTaskPool(tst.tx, student, pool.Id)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		err = tst.taskService.DeleteTaskPool(tst.tx, schemas.User{Id: studentId, Role: string(models.UserRoleTeacher)}, pool.Id)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tst.rollbackToSavePoint()
+	})
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"github.com/mini-maxit/backend/package/repository"
+	"gorm.io/gorm"
+)
+
+// TaskVerdictSummariesMigration is the online migration filling verdict summaries of tasks judged before
+// summaries were kept. Task lists aggregate submissions until it is cut over
+const TaskVerdictSummariesMigration = "task_verdict_summaries"
+
+type taskVerdictSummaryBackfill struct {
+	submissionRepository repository.SubmissionRepository
+	summaryRepository    repository.TaskVerdictSummaryRepository
+	estimateCount        bool
+}
+
+func (b *taskVerdictSummaryBackfill) Name() string {
+	return TaskVerdictSummariesMigration
+}
+
+func (b *taskVerdictSummaryBackfill) Count(tx *gorm.DB) (int64, error) {
+	if b.estimateCount {
+		return b.submissionRepository.EstimateSubmissions(tx)
+	}
+	return b.submissionRepository.CountSubmissions(tx)
+}
+
+func (b *taskVerdictSummaryBackfill) BackfillBatch(tx *gorm.DB, afterId int64, batchSize int) (int64, int64, error) {
+	submissions, err := b.submissionRepository.GetSubmissionsAfter(tx, afterId, batchSize)
+	if err != nil || len(submissions) == 0 {
+		return afterId, 0, err
+	}
+	// Refreshing recomputes summaries of the whole task, so each task is refreshed once per batch
+	refreshed := make(map[int64]bool)
+	for _, submission := range submissions {
+		if refreshed[submission.TaskId] {
+			continue
+		}
+		err = b.summaryRepository.Refresh(tx, submission.TaskId)
+		if err != nil {
+			return afterId, 0, err
+		}
+		refreshed[submission.TaskId] = true
+	}
+	return submissions[len(submissions)-1].Id, int64(len(submissions)), nil
+}
+
+// NewTaskVerdictSummaryBackfill returns the backfill of TaskVerdictSummariesMigration. With estimateCount the progress
+// is measured against an estimate of the number of submissions instead of an exact count
+func NewTaskVerdictSummaryBackfill(submissionRepository repository.SubmissionRepository, summaryRepository repository.TaskVerdictSummaryRepository, estimateCount bool) Backfill {
+	return &taskVerdictSummaryBackfill{
+		submissionRepository: submissionRepository,
+		summaryRepository:    summaryRepository,
+		estimateCount:        estimateCount,
+	}
+}
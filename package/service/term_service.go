@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrTermNotFound = errors.New("term not found")
+var ErrTermOverlaps = errors.New("term overlaps an existing term")
+
+type TermService interface {
+	// CreateTerm creates an academic term. Terms cannot overlap. Only admins can create terms
+	CreateTerm(tx *gorm.DB, currentUser schemas.User, term schemas.TermCreate) (*schemas.Term, error)
+	GetAllTerms(tx *gorm.DB) ([]schemas.Term, error)
+}
+
+type TermServiceImpl struct {
+	termRepository       repository.TermRepository
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ts *TermServiceImpl) CreateTerm(tx *gorm.DB, currentUser schemas.User, term schemas.TermCreate) (*schemas.Term, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTerm, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(term); err != nil {
+		ts.logger.Errorf("Error validating term: %v", err.Error())
+		return nil, err
+	}
+
+	overlaps, err := ts.termRepository.HasOverlappingTerm(tx, term.StartsAt, term.EndsAt)
+	if err != nil {
+		ts.logger.Errorf("Error checking overlapping terms: %v", err.Error())
+		return nil, err
+	}
+	if overlaps {
+		return nil, ErrTermOverlaps
+	}
+
+	model := &models.Term{
+		Name:     term.Name,
+		StartsAt: term.StartsAt,
+		EndsAt:   term.EndsAt,
+	}
+	_, err = ts.termRepository.CreateTerm(tx, model)
+	if err != nil {
+		ts.logger.Errorf("Error creating term: %v", err.Error())
+		return nil, err
+	}
+	return termModelToSchema(model), nil
+}
+
+func (ts *TermServiceImpl) GetAllTerms(tx *gorm.DB) ([]schemas.Term, error) {
+	terms, err := ts.termRepository.GetAllTerms(tx)
+	if err != nil {
+		ts.logger.Errorf("Error getting terms: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.Term, 0, len(terms))
+	for _, term := range terms {
+		result = append(result, *termModelToSchema(&term))
+	}
+	return result, nil
+}
+
+func termModelToSchema(model *models.Term) *schemas.Term {
+	return &schemas.Term{
+		Id:       model.Id,
+		Name:     model.Name,
+		StartsAt: model.StartsAt,
+		EndsAt:   model.EndsAt,
+	}
+}
+
+func NewTermService(termRepository repository.TermRepository, accessControlService AccessControlService) TermService {
+	log := logger.NewNamedLogger("term_service")
+	return &TermServiceImpl{
+		termRepository:       termRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
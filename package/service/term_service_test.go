@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTerm(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	tr, err := repository.NewTermRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ts := NewTermService(tr, newAccessControlServiceTest(t, tx))
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+	startsAt := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	winter := schemas.TermCreate{Name: "Winter 2024/25", StartsAt: startsAt, EndsAt: startsAt.AddDate(0, 4, 0)}
+
+	t.Run("Success", func(t *testing.T) {
+		term, err := ts.CreateTerm(tx, admin, winter)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, winter.Name, term.Name)
+
+		terms, err := ts.GetAllTerms(tx)
+		assert.NoError(t, err)
+		assert.Len(t, terms, 1)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Overlapping term", func(t *testing.T) {
+		_, err := ts.CreateTerm(tx, admin, winter)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ts.CreateTerm(tx, admin, schemas.TermCreate{Name: "Overlapping", StartsAt: startsAt.AddDate(0, 3, 0), EndsAt: startsAt.AddDate(0, 8, 0)})
+		assert.ErrorIs(t, err, ErrTermOverlaps)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Ends before it starts", func(t *testing.T) {
+		_, err := ts.CreateTerm(tx, admin, schemas.TermCreate{Name: "Invalid", StartsAt: startsAt, EndsAt: startsAt.AddDate(0, -1, 0)})
+		var validationErrors validator.ValidationErrors
+		if assert.ErrorAs(t, err, &validationErrors) && assert.Len(t, validationErrors, 1) {
+			assert.Equal(t, "ends_at", validationErrors[0].Field())
+			assert.Equal(t, "gtfield", validationErrors[0].Tag())
+		}
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		_, err := ts.CreateTerm(tx, schemas.User{Id: 1, Role: string(models.UserRoleTeacher)}, winter)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TrustListApiKeyBytes is the number of random bytes of generated API keys
+const TrustListApiKeyBytes = 32
+
+var ErrTrustListEntryNotFound = errors.New("trust list entry not found")
+var ErrInvalidTrustListEntry = errors.New("value must be a CIDR range or IP for ip_range, a user id for user_id and empty for api_key")
+
+type TrustListService interface {
+	GetEntries(tx *gorm.DB, currentUser schemas.User) ([]schemas.TrustListEntry, error)
+	// CreateEntry adds an entry to the trust list. For api_key entries a key is generated and returned once,
+	// only its hash is stored. Only admins can manage the trust list
+	CreateEntry(tx *gorm.DB, currentUser schemas.User, entry schemas.TrustListEntryCreate) (*schemas.TrustListEntry, error)
+	DeleteEntry(tx *gorm.DB, currentUser schemas.User, entryId int64) error
+	// Reload replaces the in-memory trust list used by IsTrusted with the stored entries
+	Reload(tx *gorm.DB) error
+	// IsTrusted reports whether a request from the IP, with the API key or of the user is trusted.
+	// Empty apiKey and non-positive userId never match
+	IsTrusted(ip net.IP, apiKey string, userId int64) bool
+	// HasTrustedUsers reports whether the trust list has user entries, so callers can skip resolving the user
+	HasTrustedUsers() bool
+}
+
+// trustList is an immutable snapshot of the stored entries
+type trustList struct {
+	ipRanges     []*net.IPNet
+	apiKeyHashes map[string]bool
+	userIds      map[int64]bool
+}
+
+type TrustListServiceImpl struct {
+	trustListRepository  repository.TrustListRepository
+	trustList            atomic.Pointer[trustList]
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (ts *TrustListServiceImpl) GetEntries(tx *gorm.DB, currentUser schemas.User) ([]schemas.TrustListEntry, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTrustList, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	entries, err := ts.trustListRepository.GetAllEntries(tx)
+	if err != nil {
+		ts.logger.Errorf("Error getting trust list entries: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.TrustListEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *ts.modelToSchema(&entry))
+	}
+	return result, nil
+}
+
+func (ts *TrustListServiceImpl) CreateEntry(tx *gorm.DB, currentUser schemas.User, entry schemas.TrustListEntryCreate) (*schemas.TrustListEntry, error) {
+	if !ts.accessControlService.Can(currentUser, ResourceTrustList, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+
+	validate := utils.NewValidator()
+	if err := validate.Struct(entry); err != nil {
+		ts.logger.Errorf("Error validating trust list entry: %v", err.Error())
+		return nil, err
+	}
+
+	model := &models.TrustListEntry{
+		Kind:        models.TrustListKind(entry.Kind),
+		Description: entry.Description,
+		CreatedBy:   currentUser.Id,
+	}
+	apiKey := ""
+	switch model.Kind {
+	case models.TrustListKindIPRange:
+		ipRange, err := parseIPRange(entry.Value)
+		if err != nil {
+			return nil, ErrInvalidTrustListEntry
+		}
+		model.Value = ipRange.String()
+	case models.TrustListKindUserId:
+		userId, err := strconv.ParseInt(entry.Value, 10, 64)
+		if err != nil || userId <= 0 {
+			return nil, ErrInvalidTrustListEntry
+		}
+		model.Value = strconv.FormatInt(userId, 10)
+	case models.TrustListKindAPIKey:
+		if entry.Value != "" {
+			return nil, ErrInvalidTrustListEntry
+		}
+		key := make([]byte, TrustListApiKeyBytes)
+		if _, err := rand.Read(key); err != nil {
+			ts.logger.Errorf("Error generating API key: %v", err.Error())
+			return nil, err
+		}
+		apiKey = hex.EncodeToString(key)
+		model.Value = hashApiKey(apiKey)
+	}
+
+	_, err := ts.trustListRepository.CreateEntry(tx, model)
+	if err != nil {
+		ts.logger.Errorf("Error creating trust list entry: %v", err.Error())
+		return nil, err
+	}
+	ts.logger.Infof("Trust list entry %d (%s) created by user %d", model.Id, model.Kind, currentUser.Id)
+
+	result := ts.modelToSchema(model)
+	result.ApiKey = apiKey
+	return result, nil
+}
+
+func (ts *TrustListServiceImpl) DeleteEntry(tx *gorm.DB, currentUser schemas.User, entryId int64) error {
+	if !ts.accessControlService.Can(currentUser, ResourceTrustList, ActionManage) {
+		return ErrNotAuthorized
+	}
+
+	deleted, err := ts.trustListRepository.DeleteEntry(tx, entryId)
+	if err != nil {
+		ts.logger.Errorf("Error deleting trust list entry: %v", err.Error())
+		return err
+	}
+	if deleted == 0 {
+		return ErrTrustListEntryNotFound
+	}
+	ts.logger.Infof("Trust list entry %d deleted by user %d", entryId, currentUser.Id)
+	return nil
+}
+
+func (ts *TrustListServiceImpl) Reload(tx *gorm.DB) error {
+	entries, err := ts.trustListRepository.GetAllEntries(tx)
+	if err != nil {
+		ts.logger.Errorf("Error getting trust list entries: %v", err.Error())
+		return err
+	}
+
+	list := &trustList{
+		apiKeyHashes: make(map[string]bool),
+		userIds:      make(map[int64]bool),
+	}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.TrustListKindIPRange:
+			ipRange, err := parseIPRange(entry.Value)
+			if err != nil {
+				ts.logger.Warnf("Skipping invalid trust list entry %d: %v", entry.Id, err.Error())
+				continue
+			}
+			list.ipRanges = append(list.ipRanges, ipRange)
+		case models.TrustListKindAPIKey:
+			list.apiKeyHashes[entry.Value] = true
+		case models.TrustListKindUserId:
+			userId, err := strconv.ParseInt(entry.Value, 10, 64)
+			if err != nil {
+				ts.logger.Warnf("Skipping invalid trust list entry %d: %v", entry.Id, err.Error())
+				continue
+			}
+			list.userIds[userId] = true
+		}
+	}
+	ts.trustList.Store(list)
+	return nil
+}
+
+func (ts *TrustListServiceImpl) IsTrusted(ip net.IP, apiKey string, userId int64) bool {
+	list := ts.trustList.Load()
+	if list == nil {
+		return false
+	}
+	if ip != nil {
+		for _, ipRange := range list.ipRanges {
+			if ipRange.Contains(ip) {
+				return true
+			}
+		}
+	}
+	if apiKey != "" && list.apiKeyHashes[hashApiKey(apiKey)] {
+		return true
+	}
+	return userId > 0 && list.userIds[userId]
+}
+
+func (ts *TrustListServiceImpl) HasTrustedUsers() bool {
+	list := ts.trustList.Load()
+	return list != nil && len(list.userIds) > 0
+}
+
+func (ts *TrustListServiceImpl) modelToSchema(model *models.TrustListEntry) *schemas.TrustListEntry {
+	value := model.Value
+	if model.Kind == models.TrustListKindAPIKey {
+		value = ""
+	}
+	return &schemas.TrustListEntry{
+		Id:          model.Id,
+		Kind:        string(model.Kind),
+		Value:       value,
+		Description: model.Description,
+		CreatedBy:   model.CreatedBy,
+		CreatedAt:   model.CreatedAt,
+	}
+}
+
+// parseIPRange parses a CIDR range. A single IP is a range of one address
+func parseIPRange(value string) (*net.IPNet, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipRange, err := net.ParseCIDR(value)
+	return ipRange, err
+}
+
+func hashApiKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+func NewTrustListService(trustListRepository repository.TrustListRepository, accessControlService AccessControlService) TrustListService {
+	log := logger.NewNamedLogger("trust_list_service")
+	return &TrustListServiceImpl{
+		trustListRepository:  trustListRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
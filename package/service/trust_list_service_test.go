@@ -0,0 +1,106 @@
+package service
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustList(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tr, err := repository.NewTrustListRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ts := NewTrustListService(tr, newAccessControlServiceTest(t, tx))
+	savePoint := "savepoint"
+	tx.SavePoint(savePoint)
+
+	createAdmin := func(t *testing.T) schemas.User {
+		adminId, err := ur.CreateUser(tx, &models.User{
+			Name:         "Test Admin",
+			Surname:      "Test Surname",
+			Email:        "admin@email.com",
+			Username:     "testadmin",
+			PasswordHash: "password",
+			Role:         models.UserRoleAdmin,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return schemas.User{Id: adminId, Role: string(models.UserRoleAdmin)}
+	}
+
+	t.Run("Trusted requests", func(t *testing.T) {
+		admin := createAdmin(t)
+		ipRange, err := ts.CreateEntry(tx, admin, schemas.TrustListEntryCreate{Kind: "ip_range", Value: "10.1.0.0/16", Description: "Lab NAT"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		apiKey, err := ts.CreateEntry(tx, admin, schemas.TrustListEntryCreate{Kind: "api_key"})
+		if !assert.NoError(t, err) || !assert.NotEmpty(t, apiKey.ApiKey) {
+			t.FailNow()
+		}
+		_, err = ts.CreateEntry(tx, admin, schemas.TrustListEntryCreate{Kind: "user_id", Value: "42"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.False(t, ts.IsTrusted(net.ParseIP("10.1.2.3"), "", 0), "trust list is only applied after reload")
+
+		assert.NoError(t, ts.Reload(tx))
+		assert.True(t, ts.IsTrusted(net.ParseIP("10.1.2.3"), "", 0))
+		assert.True(t, ts.IsTrusted(nil, apiKey.ApiKey, 0))
+		assert.True(t, ts.IsTrusted(nil, "", 42))
+		assert.True(t, ts.HasTrustedUsers())
+		assert.False(t, ts.IsTrusted(net.ParseIP("10.2.0.1"), "wrong", 41))
+
+		entries, err := ts.GetEntries(tx, admin)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 3)
+		for _, entry := range entries {
+			assert.Empty(t, entry.ApiKey)
+		}
+
+		assert.NoError(t, ts.DeleteEntry(tx, admin, ipRange.Id))
+		assert.NoError(t, ts.Reload(tx))
+		assert.False(t, ts.IsTrusted(net.ParseIP("10.1.2.3"), "", 0))
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Invalid entry", func(t *testing.T) {
+		admin := createAdmin(t)
+		invalid := []schemas.TrustListEntryCreate{
+			{Kind: "ip_range", Value: "not an ip"},
+			{Kind: "user_id", Value: "-1"},
+			{Kind: "api_key", Value: "chosen key"},
+		}
+		for _, entry := range invalid {
+			_, err := ts.CreateEntry(tx, admin, entry)
+			assert.ErrorIs(t, err, ErrInvalidTrustListEntry)
+		}
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Entry not found", func(t *testing.T) {
+		admin := createAdmin(t)
+		err := ts.DeleteEntry(tx, admin, 1000)
+		assert.ErrorIs(t, err, ErrTrustListEntryNotFound)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		_, err := ts.CreateEntry(tx, schemas.User{Id: 1, Role: string(models.UserRoleTeacher)}, schemas.TrustListEntryCreate{Kind: "api_key"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+	tx.Rollback()
+}
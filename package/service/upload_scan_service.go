@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mini-maxit/backend/internal/logger"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrFileInfected = errors.New("uploaded file is infected")
+
+type UploadScanService interface {
+	// ScanTaskArchive scans a task archive before it is stored and rewinds it. An infected archive is copied to the
+	// quarantine directory, recorded, and ErrFileInfected is returned. Does nothing when scanning is disabled
+	ScanTaskArchive(tx *gorm.DB, uploadedBy int64, filename string, file io.ReadSeeker) error
+	// ScanSubmission scans a solution like ScanTaskArchive
+	ScanSubmission(tx *gorm.DB, uploadedBy int64, filename string, file io.ReadSeeker) error
+	// GetQuarantinedFiles returns rejected uploads newest first. Only admins can see them
+	GetQuarantinedFiles(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.QuarantinedFile, error)
+}
+
+type UploadScanServiceImpl struct {
+	scanner              FileScanner
+	quarantineDir        string
+	quarantineRepository repository.QuarantineRepository
+	accessControlService AccessControlService
+	logger               *zap.SugaredLogger
+}
+
+func (us *UploadScanServiceImpl) ScanTaskArchive(tx *gorm.DB, uploadedBy int64, filename string, file io.ReadSeeker) error {
+	return us.scanUpload(tx, uploadedBy, models.UploadKindTaskArchive, filename, file)
+}
+
+func (us *UploadScanServiceImpl) ScanSubmission(tx *gorm.DB, uploadedBy int64, filename string, file io.ReadSeeker) error {
+	return us.scanUpload(tx, uploadedBy, models.UploadKindSubmission, filename, file)
+}
+
+func (us *UploadScanServiceImpl) scanUpload(tx *gorm.DB, uploadedBy int64, kind models.UploadKind, filename string, file io.ReadSeeker) error {
+	if us.scanner == nil {
+		return nil
+	}
+	result, err := us.scanner.Scan(file)
+	if err != nil {
+		us.logger.Errorf("Error scanning %s %s: %v", kind, filename, err.Error())
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if !result.Infected {
+		return nil
+	}
+
+	quarantined, err := us.quarantine(file)
+	if err != nil {
+		us.logger.Errorf("Error quarantining %s %s: %v", kind, filename, err.Error())
+		return err
+	}
+	quarantined.Kind = kind
+	quarantined.Filename = filename
+	quarantined.Signature = result.Signature
+	quarantined.UploadedBy = uploadedBy
+	_, err = us.quarantineRepository.CreateFile(tx, quarantined)
+	if err != nil {
+		us.logger.Errorf("Error recording quarantined file: %v", err.Error())
+		return err
+	}
+	us.logger.Warnf("Rejected %s %s of user %d infected with %s, quarantined as %s", kind, filename, uploadedBy, result.Signature, quarantined.Path)
+	return ErrFileInfected
+}
+
+// quarantine copies the file to the quarantine directory, named by its hash so uploads of the same file are kept once.
+// The file is only readable by the backend, so it is not opened by accident
+func (us *UploadScanServiceImpl) quarantine(file io.Reader) (*models.QuarantinedFile, error) {
+	if err := os.MkdirAll(us.quarantineDir, 0o700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(us.quarantineDir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), file)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	path := filepath.Join(us.quarantineDir, sum)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return &models.QuarantinedFile{Path: path, Sha256: sum, Size: size}, nil
+}
+
+func (us *UploadScanServiceImpl) GetQuarantinedFiles(tx *gorm.DB, currentUser schemas.User, limit, offset int64) ([]schemas.QuarantinedFile, error) {
+	if !us.accessControlService.Can(currentUser, ResourceQuarantine, ActionView) {
+		return nil, ErrNotAuthorized
+	}
+
+	files, err := us.quarantineRepository.GetFiles(tx, limit, offset)
+	if err != nil {
+		us.logger.Errorf("Error getting quarantined files: %v", err.Error())
+		return nil, err
+	}
+
+	result := make([]schemas.QuarantinedFile, 0, len(files))
+	for _, file := range files {
+		result = append(result, schemas.QuarantinedFile{
+			Id:         file.Id,
+			Kind:       string(file.Kind),
+			Filename:   file.Filename,
+			Sha256:     file.Sha256,
+			Size:       file.Size,
+			Signature:  file.Signature,
+			UploadedBy: file.UploadedBy,
+			CreatedAt:  file.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+// NewUploadScanService scans uploads with the scanner. A nil scanner disables scanning
+func NewUploadScanService(scanner FileScanner, quarantineDir string, quarantineRepository repository.QuarantineRepository, accessControlService AccessControlService) UploadScanService {
+	log := logger.NewNamedLogger("upload_scan_service")
+	return &UploadScanServiceImpl{
+		scanner:              scanner,
+		quarantineDir:        quarantineDir,
+		quarantineRepository: quarantineRepository,
+		accessControlService: accessControlService,
+		logger:               log,
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mini-maxit/backend/internal/testutils"
+	"github.com/mini-maxit/backend/package/domain/models"
+	"github.com/mini-maxit/backend/package/domain/schemas"
+	"github.com/mini-maxit/backend/package/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// fileScannerStub reports files containing the EICAR marker as infected
+type fileScannerStub struct {
+	err error
+}
+
+func (s *fileScannerStub) Scan(file io.Reader) (*ScanResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(content), "EICAR") {
+		return &ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return &ScanResult{}, nil
+}
+
+func TestScanUpload(t *testing.T) {
+	tx := testutils.NewTestTx(t)
+	ur, err := repository.NewUserRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	qr, err := repository.NewQuarantineRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	scanner := &fileScannerStub{}
+	quarantineDir := t.TempDir()
+	us := NewUploadScanService(scanner, quarantineDir, qr, newAccessControlServiceTest(t, tx))
+	users := []*models.User{
+		{Name: "Admin", Surname: "User", Email: "admin@email.com", Username: "admin", PasswordHash: "password", Role: models.UserRoleAdmin},
+		{Name: "Student", Surname: "User", Email: "student@email.com", Username: "student", PasswordHash: "password"},
+	}
+	if !assert.NoError(t, ur.CreateUsers(tx, users)) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: users[0].Id, Role: string(models.UserRoleAdmin)}
+	student := schemas.User{Id: users[1].Id, Role: string(models.UserRoleStudent)}
+	savePoint := "users"
+	tx.SavePoint(savePoint)
+
+	t.Run("Clean file", func(t *testing.T) {
+		file := strings.NewReader("print('hello')")
+		err := us.ScanSubmission(tx, student.Id, "solution.py", file)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		assert.Equal(t, "print('hello')", string(content))
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Infected file", func(t *testing.T) {
+		err := us.ScanTaskArchive(tx, student.Id, "task.zip", strings.NewReader("EICAR"))
+		assert.ErrorIs(t, err, ErrFileInfected)
+
+		files, err := us.GetQuarantinedFiles(tx, admin, 10, 0)
+		if !assert.NoError(t, err) || !assert.Len(t, files, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.UploadKindTaskArchive), files[0].Kind)
+		assert.Equal(t, "Eicar-Test-Signature", files[0].Signature)
+		assert.Equal(t, student.Id, files[0].UploadedBy)
+		content, err := os.ReadFile(quarantineDir + "/" + files[0].Sha256)
+		assert.NoError(t, err)
+		assert.Equal(t, "EICAR", string(content))
+
+		_, err = us.GetQuarantinedFiles(tx, student, 10, 0)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Scanner unavailable", func(t *testing.T) {
+		scanner.err = ErrScannerUnavailable
+		defer func() { scanner.err = nil }()
+		err := us.ScanSubmission(tx, student.Id, "solution.py", strings.NewReader("print('hello')"))
+		assert.ErrorIs(t, err, ErrScannerUnavailable)
+		tx.RollbackTo(savePoint)
+	})
+
+	t.Run("Scanning disabled", func(t *testing.T) {
+		err := NewUploadScanService(nil, "", qr, newAccessControlServiceTest(t, tx)).ScanSubmission(tx, student.Id, "solution.py", strings.NewReader("EICAR"))
+		assert.NoError(t, err)
+	})
+	tx.Rollback()
+}
@@ -1,31 +1,125 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/mini-maxit/backend/internal/logger"
 	"github.com/mini-maxit/backend/package/domain/models"
 	"github.com/mini-maxit/backend/package/domain/schemas"
 	"github.com/mini-maxit/backend/package/repository"
+	"github.com/mini-maxit/backend/package/utils"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// MaxUserImportRows is the maximum number of users in a single import, each row hashes a password
+const MaxUserImportRows = 1000
+
+// UserImportColumns are the columns required in the header of a user import file
+var UserImportColumns = []string{"name", "surname", "email", "username", "role"}
+
+// Actions reported by GetCapabilities
+const (
+	CapabilitySubmitSolution    = "submit_solution"
+	CapabilityCreateTask        = "create_task"
+	CapabilityManageTaskPools   = "manage_task_pools"
+	CapabilityRejudge           = "rejudge"
+	CapabilityCheckPlagiarism   = "check_plagiarism"
+	CapabilityRedactSubmissions = "redact_submissions"
+	CapabilityImportGrades      = "import_grades"
+	CapabilityBrowseSubmissions = "browse_submissions"
+	CapabilityManageTerms       = "manage_terms"
+	CapabilityManageLanguages   = "manage_languages"
+	CapabilityManageTrustList   = "manage_trust_list"
+	CapabilityManageSandbox     = "manage_sandbox"
+	CapabilityManageIncidents   = "manage_incidents"
+	CapabilityManageMigrations  = "manage_migrations"
+	CapabilityImportUsers       = "import_users"
+	CapabilityRecomputeScores   = "recompute_scores"
+	CapabilityViewJudgeAudits   = "view_judge_audits"
+	CapabilityCheckIntegrity    = "check_integrity"
+	CapabilityManageUsers       = "manage_users"
+	CapabilityManageRoles       = "manage_roles"
+)
+
+type capabilityPermission struct {
+	capability string
+	permission permission
+}
+
+// capabilityPermissions are the permissions of the matrix behind the actions reported by GetCapabilities, in the order they are reported
+var capabilityPermissions = []capabilityPermission{
+	{CapabilitySubmitSolution, permission{ResourceSubmission, ActionCreate}},
+	{CapabilityCreateTask, permission{ResourceTask, ActionCreate}},
+	{CapabilityManageTaskPools, permission{ResourceTaskPool, ActionManage}},
+	{CapabilityRejudge, permission{ResourceTask, ActionRejudge}},
+	{CapabilityCheckPlagiarism, permission{ResourceTask, ActionCheckPlagiarism}},
+	{CapabilityRedactSubmissions, permission{ResourceSubmission, ActionRedact}},
+	{CapabilityImportGrades, permission{ResourceGrade, ActionImport}},
+	{CapabilityBrowseSubmissions, permission{ResourceSubmission, ActionBrowse}},
+	{CapabilityManageTerms, permission{ResourceTerm, ActionManage}},
+	{CapabilityManageLanguages, permission{ResourceLanguage, ActionManage}},
+	{CapabilityManageTrustList, permission{ResourceTrustList, ActionManage}},
+	{CapabilityManageSandbox, permission{ResourceTask, ActionManageSandbox}},
+	{CapabilityManageIncidents, permission{ResourceStatus, ActionManageIncidents}},
+	{CapabilityManageMigrations, permission{ResourceMigration, ActionManage}},
+	{CapabilityImportUsers, permission{ResourceUser, ActionImport}},
+	{CapabilityRecomputeScores, permission{ResourceSubmission, ActionRecompute}},
+	{CapabilityViewJudgeAudits, permission{ResourceJudgeAudit, ActionView}},
+	{CapabilityCheckIntegrity, permission{ResourceIntegrity, ActionCheck}},
+	{CapabilityManageUsers, permission{ResourceUser, ActionManage}},
+	{CapabilityManageRoles, permission{ResourceRole, ActionManage}},
+}
+
 var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrNotAuthorized     = errors.New("not authorized")
+	ErrInvalidUserImport = errors.New("invalid user import file")
+	ErrOwnUserStatus     = errors.New("admins cannot change the status of their own account")
+	ErrCannotImpersonate = errors.New("users cannot impersonate themselves, admins or users with permissions they lack")
 )
 
 type UserService interface {
 	GetUserByEmail(tx *gorm.DB, email string) (*schemas.User, error)
 	GetAllUsers(tx *gorm.DB, limit, offset int64) ([]schemas.User, error)
+	// GetUserById returns the user with the permissions of their custom role, so the user can be authorized
 	GetUserById(tx *gorm.DB, userId int64) (*schemas.User, error)
 	EditUser(tx *gorm.DB, userId int64, updateInfo *schemas.UserEdit) error
+	// ImportUsers creates users from a CSV file with a header naming the name, surname, email, username and role
+	// columns, and optionally password. Invalid rows are reported and skipped, the others are created.
+	// Only admins can import users
+	ImportUsers(tx *gorm.DB, currentUser schemas.User, file io.Reader) (*schemas.UserImportReport, error)
+	// GetCapabilities returns the actions the user may perform based on the permissions of their role, and how many
+	// tasks they created
+	GetCapabilities(tx *gorm.DB, currentUser schemas.User) (*schemas.UserCapabilities, error)
+	// SetUserStatus deactivates, bans or reactivates a user. Deactivated and banned users can no longer log in,
+	// their sessions are rejected and their refresh tokens revoked. Only admins can change the status of other users
+	SetUserStatus(tx *gorm.DB, currentUser schemas.User, userId int64, edit schemas.UserStatusEdit) (*schemas.User, error)
+	// ImpersonateUser opens a short-lived session as an active user who is not an admin, so admins can see what
+	// the user sees. Every impersonation is recorded in the audit log
+	ImpersonateUser(tx *gorm.DB, currentUser schemas.User, userId int64) (*schemas.Session, error)
+	// GetAuditLog returns actions of admins on user accounts, newest first, optionally about a single user. Admins only
+	GetAuditLog(tx *gorm.DB, currentUser schemas.User, targetUserId *int64, limit, offset int64) ([]schemas.AuditLogEntry, error)
 }
 
 type UserServiceImpl struct {
-	userRepository repository.UserRepository
-	logger         *zap.SugaredLogger
+	userRepository         repository.UserRepository
+	taskRepository         repository.TaskRepository
+	refreshTokenRepository repository.RefreshTokenRepository
+	auditLogRepository     repository.AuditLogRepository
+	sessionService         SessionService
+	accessControlService   AccessControlService
+	logger                 *zap.SugaredLogger
 }
 
 func (us *UserServiceImpl) GetUserByEmail(tx *gorm.DB, email string) (*schemas.User, error) {
@@ -78,6 +172,10 @@ func (us *UserServiceImpl) GetUserById(tx *gorm.DB, userId int64) (*schemas.User
 	}
 
 	user := us.modelToSchema(userModel)
+	err = us.accessControlService.ResolvePermissions(tx, user)
+	if err != nil {
+		return nil, err
+	}
 
 	return user, nil
 }
@@ -102,6 +200,174 @@ func (us *UserServiceImpl) EditUser(tx *gorm.DB, userId int64, updateInfo *schem
 	return nil
 }
 
+func (us *UserServiceImpl) ImportUsers(tx *gorm.DB, currentUser schemas.User, file io.Reader) (*schemas.UserImportReport, error) {
+	if !us.accessControlService.Can(currentUser, ResourceUser, ActionImport) {
+		return nil, ErrNotAuthorized
+	}
+
+	rows, err := us.readUserImport(file)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &schemas.UserImportReport{Rows: make([]schemas.UserImportRowResult, len(rows))}
+	validate := utils.NewValidator()
+	emails := make([]string, 0, len(rows))
+	usernames := make([]string, 0, len(rows))
+	for i, row := range rows {
+		report.Rows[i] = schemas.UserImportRowResult{Row: i + 2, Email: row.Email, Username: row.Username}
+		if err := validate.Struct(row); err != nil {
+			validationErrors, ok := err.(validator.ValidationErrors)
+			if !ok {
+				return nil, err
+			}
+			fields := make([]string, 0, len(validationErrors))
+			for _, fe := range validationErrors {
+				fields = append(fields, fe.Field())
+			}
+			report.Rows[i].Error = "invalid " + strings.Join(fields, ", ")
+			continue
+		}
+		emails = append(emails, row.Email)
+		usernames = append(usernames, row.Username)
+	}
+
+	existing, err := us.userRepository.GetUsersByEmailsOrUsernames(tx, emails, usernames)
+	if err != nil {
+		us.logger.Errorf("Error getting existing users: %v", err.Error())
+		return nil, err
+	}
+	takenEmails := make(map[string]bool, len(existing))
+	takenUsernames := make(map[string]bool, len(existing))
+	for _, user := range existing {
+		takenEmails[user.Email] = true
+		takenUsernames[user.Username] = true
+	}
+
+	users := make([]*models.User, 0, len(rows))
+	created := make([]int, 0, len(rows))
+	for i, row := range rows {
+		result := &report.Rows[i]
+		if result.Error != "" {
+			continue
+		}
+		// Taken also marks rows earlier in the file, so duplicates within the file are reported too
+		if takenEmails[row.Email] {
+			result.Error = "email already exists"
+			continue
+		}
+		if takenUsernames[row.Username] {
+			result.Error = "username already exists"
+			continue
+		}
+		takenEmails[row.Email] = true
+		takenUsernames[row.Username] = true
+
+		password := row.Password
+		if password == "" {
+			password, err = generatePassword()
+			if err != nil {
+				us.logger.Errorf("Error generating password: %v", err.Error())
+				return nil, err
+			}
+			result.Password = password
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			us.logger.Errorf("Error generating password hash: %v", err.Error())
+			return nil, err
+		}
+		users = append(users, &models.User{
+			Name:         row.Name,
+			Surname:      row.Surname,
+			Email:        row.Email,
+			Username:     row.Username,
+			PasswordHash: string(hash),
+			Role:         models.UserRole(row.Role),
+		})
+		created = append(created, i)
+	}
+
+	err = us.userRepository.CreateUsers(tx, users)
+	if err != nil {
+		us.logger.Errorf("Error creating users: %v", err.Error())
+		return nil, err
+	}
+	for j, i := range created {
+		report.Rows[i].UserId = users[j].Id
+	}
+	report.Created = len(users)
+	report.Failed = len(rows) - len(users)
+
+	us.logger.Infof("User %d imported %d users, %d rows failed", currentUser.Id, report.Created, report.Failed)
+	return report, nil
+}
+
+// readUserImport parses the rows of a user import file, mapping columns by the header
+func (us *UserServiceImpl) readUserImport(file io.Reader) ([]schemas.UserImportRow, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: file is empty", ErrInvalidUserImport)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUserImport, err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range UserImportColumns {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column, required columns are %s", ErrInvalidUserImport, column, strings.Join(UserImportColumns, ", "))
+		}
+	}
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []schemas.UserImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidUserImport, err.Error())
+		}
+		if len(rows) == MaxUserImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidUserImport, MaxUserImportRows)
+		}
+		rows = append(rows, schemas.UserImportRow{
+			Name:     value(record, "name"),
+			Surname:  value(record, "surname"),
+			Email:    value(record, "email"),
+			Username: value(record, "username"),
+			Role:     strings.ToLower(value(record, "role")),
+			Password: value(record, "password"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no users", ErrInvalidUserImport)
+	}
+	return rows, nil
+}
+
+// generatePassword returns a random password for imported users without one
+func generatePassword() (string, error) {
+	password := make([]byte, 12)
+	if _, err := rand.Read(password); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(password), nil
+}
+
 func (us *UserServiceImpl) modelToSchema(user *models.User) *schemas.User {
 	if user.Role == "" {
 		us.logger.Errorf("")
@@ -113,6 +379,7 @@ func (us *UserServiceImpl) modelToSchema(user *models.User) *schemas.User {
 		Email:    user.Email,
 		Username: user.Username,
 		Role:     string(user.Role),
+		Status:   string(user.Status),
 	}
 }
 
@@ -134,10 +401,164 @@ func (us *UserServiceImpl) updateModel(curretnModel *schemas.User, updateInfo *s
 	}
 }
 
-func NewUserService(userRepository repository.UserRepository) UserService {
+func (us *UserServiceImpl) GetCapabilities(tx *gorm.DB, currentUser schemas.User) (*schemas.UserCapabilities, error) {
+	authoredTasks, err := us.taskRepository.CountCreatedBy(tx, currentUser.Id)
+	if err != nil {
+		us.logger.Errorf("Error counting tasks of user: %v", err.Error())
+		return nil, err
+	}
+
+	capabilities := &schemas.UserCapabilities{
+		Role:          currentUser.Role,
+		Actions:       []string{},
+		AuthoredTasks: authoredTasks,
+		AllTasks:      us.accessControlService.CanAll(currentUser, ResourceTask, ActionEdit),
+	}
+	for _, cp := range capabilityPermissions {
+		if us.accessControlService.Can(currentUser, cp.permission.resource, cp.permission.action) {
+			capabilities.Actions = append(capabilities.Actions, cp.capability)
+		}
+	}
+	return capabilities, nil
+}
+
+func (us *UserServiceImpl) SetUserStatus(tx *gorm.DB, currentUser schemas.User, userId int64, edit schemas.UserStatusEdit) (*schemas.User, error) {
+	if !us.accessControlService.Can(currentUser, ResourceUser, ActionManage) {
+		return nil, ErrNotAuthorized
+	}
+	validate := utils.NewValidator()
+	if err := validate.Struct(edit); err != nil {
+		us.logger.Errorf("Error validating user status: %v", err.Error())
+		return nil, err
+	}
+	if userId == currentUser.Id {
+		return nil, ErrOwnUserStatus
+	}
+	user, err := us.userRepository.GetUser(tx, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		us.logger.Errorf("Error getting user: %v", err.Error())
+		return nil, err
+	}
+
+	status := models.UserStatus(edit.Status)
+	reason := edit.Reason
+	if status == models.UserStatusActive {
+		reason = ""
+	}
+	err = us.userRepository.UpdateStatus(tx, userId, status, reason)
+	if err != nil {
+		us.logger.Errorf("Error updating user status: %v", err.Error())
+		return nil, err
+	}
+	if status != models.UserStatusActive {
+		err = us.refreshTokenRepository.RevokeUserTokens(tx, userId)
+		if err != nil {
+			us.logger.Errorf("Error revoking refresh tokens: %v", err.Error())
+			return nil, err
+		}
+	}
+
+	detail := fmt.Sprintf("%s -> %s", user.Status, status)
+	if reason != "" {
+		detail += ": " + reason
+	}
+	err = us.auditLogRepository.CreateEntry(tx, &models.AuditLogEntry{
+		ActorId:      currentUser.Id,
+		Action:       models.AuditActionUserStatusChanged,
+		TargetUserId: userId,
+		Detail:       detail,
+	})
+	if err != nil {
+		us.logger.Errorf("Error creating audit log entry: %v", err.Error())
+		return nil, err
+	}
+	us.logger.Infof("Status of user %d changed to %s by user %d", userId, status, currentUser.Id)
+
+	user.Status = status
+	user.StatusReason = reason
+	return us.modelToSchema(user), nil
+}
+
+func (us *UserServiceImpl) ImpersonateUser(tx *gorm.DB, currentUser schemas.User, userId int64) (*schemas.Session, error) {
+	if !us.accessControlService.Can(currentUser, ResourceUser, ActionImpersonate) {
+		return nil, ErrNotAuthorized
+	}
+	if userId == currentUser.Id {
+		return nil, ErrCannotImpersonate
+	}
+	user, err := us.userRepository.GetUser(tx, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		us.logger.Errorf("Error getting user: %v", err.Error())
+		return nil, err
+	}
+	if user.Role == models.UserRoleAdmin {
+		return nil, ErrCannotImpersonate
+	}
+	// A session of a user with permissions the actor lacks, such as managing roles, would escalate the actor
+	target := schemas.User{Id: user.Id, Role: string(user.Role)}
+	if err := us.accessControlService.ResolvePermissions(tx, &target); err != nil {
+		return nil, err
+	}
+	if !us.accessControlService.Covers(currentUser, target) {
+		return nil, ErrCannotImpersonate
+	}
+
+	session, err := us.sessionService.CreateImpersonationSession(tx, userId, currentUser.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = us.auditLogRepository.CreateEntry(tx, &models.AuditLogEntry{
+		ActorId:      currentUser.Id,
+		Action:       models.AuditActionUserImpersonated,
+		TargetUserId: userId,
+		Detail:       fmt.Sprintf("session valid until %s", session.ExpiresAt.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		us.logger.Errorf("Error creating audit log entry: %v", err.Error())
+		return nil, err
+	}
+	us.logger.Infof("User %d impersonated by user %d", userId, currentUser.Id)
+	return session, nil
+}
+
+func (us *UserServiceImpl) GetAuditLog(tx *gorm.DB, currentUser schemas.User, targetUserId *int64, limit, offset int64) ([]schemas.AuditLogEntry, error) {
+	if !us.accessControlService.Can(currentUser, ResourceAuditLog, ActionView) {
+		return nil, ErrNotAuthorized
+	}
+	entries, err := us.auditLogRepository.GetEntries(tx, targetUserId, limit, offset)
+	if err != nil {
+		us.logger.Errorf("Error getting audit log: %v", err.Error())
+		return nil, err
+	}
+	result := make([]schemas.AuditLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, schemas.AuditLogEntry{
+			Id:           entry.Id,
+			ActorId:      entry.ActorId,
+			Action:       string(entry.Action),
+			TargetUserId: entry.TargetUserId,
+			Detail:       entry.Detail,
+			CreatedAt:    entry.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+func NewUserService(userRepository repository.UserRepository, taskRepository repository.TaskRepository, refreshTokenRepository repository.RefreshTokenRepository, auditLogRepository repository.AuditLogRepository, sessionService SessionService, accessControlService AccessControlService) UserService {
 	log := logger.NewNamedLogger("user_service")
 	return &UserServiceImpl{
-		userRepository: userRepository,
-		logger:         log,
+		userRepository:         userRepository,
+		taskRepository:         taskRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		auditLogRepository:     auditLogRepository,
+		sessionService:         sessionService,
+		accessControlService:   accessControlService,
+		logger:                 log,
 	}
 }
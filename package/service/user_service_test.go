@@ -1,6 +1,7 @@
 package service
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mini-maxit/backend/internal/config"
@@ -13,11 +14,13 @@ import (
 )
 
 type userServiceTest struct {
-	tx          *gorm.DB
-	config      *config.Config
-	ur          repository.UserRepository
-	userService UserService
-	savePoint   string
+	tx             *gorm.DB
+	config         *config.Config
+	ur             repository.UserRepository
+	tr             repository.TaskRepository
+	sessionService SessionService
+	userService    UserService
+	savePoint      string
 }
 
 func newUserServiceTest(t *testing.T) *userServiceTest {
@@ -27,15 +30,34 @@ func newUserServiceTest(t *testing.T) *userServiceTest {
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	us := NewUserService(ur)
+	tr, err := repository.NewTaskRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sr, err := repository.NewSessionRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	rtr, err := repository.NewRefreshTokenRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	alr, err := repository.NewAuditLogRepository(tx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ss := NewSessionService(sr, ur)
+	us := NewUserService(ur, tr, rtr, alr, ss, newAccessControlServiceTest(t, tx))
 	savePoint := "savepoint"
 	tx.SavePoint(savePoint)
 	return &userServiceTest{
-		tx:          tx,
-		config:      config,
-		ur:          ur,
-		userService: us,
-		savePoint:   savePoint,
+		tx:             tx,
+		config:         config,
+		ur:             ur,
+		tr:             tr,
+		sessionService: ss,
+		userService:    us,
+		savePoint:      savePoint,
 	}
 }
 
@@ -152,3 +174,257 @@ func TestEditUser(t *testing.T) {
 		assert.ErrorIs(t, err, ErrUserNotFound)
 	})
 }
+
+func TestImportUsers(t *testing.T) {
+	ust := newUserServiceTest(t)
+	defer ust.tx.Rollback()
+	admin := schemas.User{Id: 1, Role: string(models.UserRoleAdmin)}
+
+	t.Run("Per row report", func(t *testing.T) {
+		_, err := ust.ur.CreateUser(ust.tx, &models.User{
+			Name:         "Existing",
+			Surname:      "User",
+			Email:        "existing@email.com",
+			Username:     "existing",
+			PasswordHash: "password",
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		file := strings.Join([]string{
+			"Email,Name,Surname,Username,Role",
+			"first@email.com,First,Student,first,student",
+			"existing@email.com,Taken,Email,taken,student",
+			"first@email.com,Duplicate,Row,duplicate,student",
+			"invalid,Bad,Row,bad,student",
+			"teacher@email.com,Some,Teacher,teacher,Teacher",
+		}, "\n")
+
+		report, err := ust.userService.ImportUsers(ust.tx, admin, strings.NewReader(file))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 3, report.Failed)
+		if assert.Len(t, report.Rows, 5) {
+			assert.NotZero(t, report.Rows[0].UserId)
+			assert.NotEmpty(t, report.Rows[0].Password)
+			assert.Equal(t, "email already exists", report.Rows[1].Error)
+			assert.Equal(t, "email already exists", report.Rows[2].Error)
+			assert.Equal(t, "invalid email", report.Rows[3].Error)
+			assert.Equal(t, 6, report.Rows[4].Row)
+		}
+		teacher, err := ust.userService.GetUserByEmail(ust.tx, "teacher@email.com")
+		assert.NoError(t, err)
+		assert.Equal(t, string(models.UserRoleTeacher), teacher.Role)
+		ust.RollbackToSavepoint()
+	})
+
+	t.Run("Missing column", func(t *testing.T) {
+		_, err := ust.userService.ImportUsers(ust.tx, admin, strings.NewReader("email,name,surname\nfirst@email.com,First,Student"))
+		assert.ErrorIs(t, err, ErrInvalidUserImport)
+		ust.RollbackToSavepoint()
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		_, err := ust.userService.ImportUsers(ust.tx, schemas.User{Role: string(models.UserRoleTeacher)}, strings.NewReader(""))
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+}
+
+func TestGetCapabilities(t *testing.T) {
+	ust := newUserServiceTest(t)
+	defer ust.tx.Rollback()
+
+	t.Run("Teacher", func(t *testing.T) {
+		userId, err := ust.ur.CreateUser(ust.tx, &models.User{
+			Name:         "Test User",
+			Surname:      "Test Surname",
+			Email:        "email@email.com",
+			Username:     "testuser",
+			PasswordHash: "password",
+			Role:         models.UserRoleTeacher,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ust.tr.Create(ust.tx, models.Task{Title: "Test Task", CreatedBy: userId})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		capabilities, err := ust.userService.GetCapabilities(ust.tx, schemas.User{Id: userId, Role: string(models.UserRoleTeacher)})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, int64(1), capabilities.AuthoredTasks)
+		assert.False(t, capabilities.AllTasks)
+		assert.Contains(t, capabilities.Actions, CapabilitySubmitSolution)
+		assert.Contains(t, capabilities.Actions, CapabilityCheckPlagiarism)
+		assert.NotContains(t, capabilities.Actions, CapabilityManageTerms)
+		ust.RollbackToSavepoint()
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		capabilities, err := ust.userService.GetCapabilities(ust.tx, schemas.User{Id: 1, Role: string(models.UserRoleAdmin)})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.True(t, capabilities.AllTasks)
+		assert.Contains(t, capabilities.Actions, CapabilityImportUsers)
+		assert.Contains(t, capabilities.Actions, CapabilityRejudge)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		capabilities, err := ust.userService.GetCapabilities(ust.tx, schemas.User{Id: 1, Role: "guest"})
+		assert.NoError(t, err)
+		assert.Empty(t, capabilities.Actions)
+	})
+}
+
+func TestSetUserStatus(t *testing.T) {
+	ust := newUserServiceTest(t)
+	defer ust.tx.Rollback()
+
+	users := []*models.User{
+		{Name: "Admin", Surname: "User", Email: "admin@email.com", Username: "admin", PasswordHash: "password", Role: models.UserRoleAdmin},
+		{Name: "Student", Surname: "User", Email: "student@email.com", Username: "student", PasswordHash: "password"},
+	}
+	if !assert.NoError(t, ust.ur.CreateUsers(ust.tx, users)) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: users[0].Id, Role: string(models.UserRoleAdmin)}
+	ust.tx.SavePoint("users")
+
+	t.Run("Ban and reactivate", func(t *testing.T) {
+		session, err := ust.sessionService.CreateSession(ust.tx, users[1].Id)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		user, err := ust.userService.SetUserStatus(ust.tx, admin, users[1].Id, schemas.UserStatusEdit{Status: "banned", Reason: "Cheating"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, string(models.UserStatusBanned), user.Status)
+		_, err = ust.sessionService.ValidateSession(ust.tx, session.Id)
+		assert.ErrorIs(t, err, ErrUserInactive)
+		_, err = ust.sessionService.CreateSession(ust.tx, users[1].Id)
+		assert.ErrorIs(t, err, ErrUserInactive)
+
+		_, err = ust.userService.SetUserStatus(ust.tx, admin, users[1].Id, schemas.UserStatusEdit{Status: "active"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ust.sessionService.ValidateSession(ust.tx, session.Id)
+		assert.NoError(t, err)
+
+		entries, err := ust.userService.GetAuditLog(ust.tx, admin, &users[1].Id, 10, 0)
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "banned -> active", entries[0].Detail)
+			assert.Equal(t, "active -> banned: Cheating", entries[1].Detail)
+		}
+		ust.tx.RollbackTo("users")
+	})
+
+	t.Run("Own account", func(t *testing.T) {
+		_, err := ust.userService.SetUserStatus(ust.tx, admin, admin.Id, schemas.UserStatusEdit{Status: "deactivated"})
+		assert.ErrorIs(t, err, ErrOwnUserStatus)
+		ust.tx.RollbackTo("users")
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		student := schemas.User{Id: users[1].Id, Role: string(models.UserRoleStudent)}
+		_, err := ust.userService.SetUserStatus(ust.tx, student, admin.Id, schemas.UserStatusEdit{Status: "banned"})
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		ust.tx.RollbackTo("users")
+	})
+}
+
+func TestImpersonateUser(t *testing.T) {
+	ust := newUserServiceTest(t)
+	defer ust.tx.Rollback()
+
+	users := []*models.User{
+		{Name: "Admin", Surname: "User", Email: "admin@email.com", Username: "admin", PasswordHash: "password", Role: models.UserRoleAdmin},
+		{Name: "Other", Surname: "Admin", Email: "other@email.com", Username: "other", PasswordHash: "password", Role: models.UserRoleAdmin},
+		{Name: "Student", Surname: "User", Email: "student@email.com", Username: "student", PasswordHash: "password"},
+	}
+	if !assert.NoError(t, ust.ur.CreateUsers(ust.tx, users)) {
+		t.FailNow()
+	}
+	admin := schemas.User{Id: users[0].Id, Role: string(models.UserRoleAdmin)}
+	ust.tx.SavePoint("users")
+
+	t.Run("Separate session", func(t *testing.T) {
+		own, err := ust.sessionService.CreateSession(ust.tx, users[2].Id)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		session, err := ust.userService.ImpersonateUser(ust.tx, admin, users[2].Id)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NotEqual(t, own.Id, session.Id)
+		assert.Equal(t, &admin.Id, session.ImpersonatedBy)
+		assert.Empty(t, session.RefreshToken)
+
+		validated, err := ust.sessionService.ValidateSession(ust.tx, session.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, users[2].Id, validated.UserId)
+		again, err := ust.sessionService.CreateSession(ust.tx, users[2].Id)
+		assert.NoError(t, err)
+		assert.Equal(t, own.Id, again.Id)
+
+		entries, err := ust.userService.GetAuditLog(ust.tx, admin, nil, 10, 0)
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, string(models.AuditActionUserImpersonated), entries[0].Action)
+		}
+		ust.tx.RollbackTo("users")
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		_, err := ust.userService.ImpersonateUser(ust.tx, admin, users[1].Id)
+		assert.ErrorIs(t, err, ErrCannotImpersonate)
+		ust.tx.RollbackTo("users")
+	})
+
+	t.Run("User with permissions the actor lacks", func(t *testing.T) {
+		rr, err := repository.NewRoleRepository(ust.tx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		roles := []*models.Role{
+			{Name: "support", Permissions: []models.RolePermission{{Resource: "user", Action: "impersonate", Scope: ScopeAll}}},
+			{Name: "role_admin", Permissions: []models.RolePermission{{Resource: "role", Action: "manage", Scope: ScopeAll}}},
+		}
+		for _, role := range roles {
+			if _, err := rr.CreateRole(ust.tx, role); !assert.NoError(t, err) {
+				t.FailNow()
+			}
+		}
+		if !assert.NoError(t, ust.ur.UpdateRole(ust.tx, users[2].Id, "role_admin")) {
+			t.FailNow()
+		}
+		support := schemas.User{Id: users[0].Id, Role: "support", Permissions: []schemas.Permission{{Resource: "user", Action: "impersonate", Scope: ScopeAll}}}
+
+		// Impersonating the role admin would let support assign itself the admin role
+		_, err = ust.userService.ImpersonateUser(ust.tx, support, users[2].Id)
+		assert.ErrorIs(t, err, ErrCannotImpersonate)
+		_, err = ust.userService.ImpersonateUser(ust.tx, admin, users[2].Id)
+		assert.NoError(t, err)
+		ust.tx.RollbackTo("users")
+	})
+
+	t.Run("Banned user", func(t *testing.T) {
+		_, err := ust.userService.SetUserStatus(ust.tx, admin, users[2].Id, schemas.UserStatusEdit{Status: "banned"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = ust.userService.ImpersonateUser(ust.tx, admin, users[2].Id)
+		assert.ErrorIs(t, err, ErrUserInactive)
+		ust.tx.RollbackTo("users")
+	})
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"github.com/mini-maxit/backend/package/repository"
+	"gorm.io/gorm"
+)
+
+// UserTaskSummariesMigration is the online migration filling summaries of users and tasks judged before
+// summaries were kept. Task lists aggregate submissions until it is cut over
+const UserTaskSummariesMigration = "user_task_summaries"
+
+type userTaskSummaryBackfill struct {
+	submissionRepository repository.SubmissionRepository
+	summaryRepository    repository.UserTaskSummaryRepository
+	estimateCount        bool
+}
+
+func (b *userTaskSummaryBackfill) Name() string {
+	return UserTaskSummariesMigration
+}
+
+func (b *userTaskSummaryBackfill) Count(tx *gorm.DB) (int64, error) {
+	if b.estimateCount {
+		return b.submissionRepository.EstimateSubmissions(tx)
+	}
+	return b.submissionRepository.CountSubmissions(tx)
+}
+
+func (b *userTaskSummaryBackfill) BackfillBatch(tx *gorm.DB, afterId int64, batchSize int) (int64, int64, error) {
+	submissions, err := b.submissionRepository.GetSubmissionsAfter(tx, afterId, batchSize)
+	if err != nil || len(submissions) == 0 {
+		return afterId, 0, err
+	}
+	// Refreshing recomputes the whole summary, so each pair is refreshed once per batch
+	refreshed := make(map[[2]int64]bool)
+	for _, submission := range submissions {
+		key := [2]int64{submission.UserId, submission.TaskId}
+		if refreshed[key] {
+			continue
+		}
+		err = b.summaryRepository.Refresh(tx, submission.UserId, submission.TaskId)
+		if err != nil {
+			return afterId, 0, err
+		}
+		refreshed[key] = true
+	}
+	return submissions[len(submissions)-1].Id, int64(len(submissions)), nil
+}
+
+// NewUserTaskSummaryBackfill returns the backfill of UserTaskSummariesMigration. With estimateCount the progress is
+// measured against an estimate of the number of submissions instead of an exact count
+func NewUserTaskSummaryBackfill(submissionRepository repository.SubmissionRepository, summaryRepository repository.UserTaskSummaryRepository, estimateCount bool) Backfill {
+	return &userTaskSummaryBackfill{
+		submissionRepository: submissionRepository,
+		summaryRepository:    summaryRepository,
+		estimateCount:        estimateCount,
+	}
+}
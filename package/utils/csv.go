@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// CSVFlushRows is the number of records buffered before they are written through to the client
+const CSVFlushRows = 100
+
+// utf8BOM makes Excel read the file as UTF-8 instead of the code page of the locale
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var ErrInvalidCSVDelimiter = errors.New("invalid CSV delimiter")
+
+// CSVOptions are the dialect of a CSV export. The zero value is a comma separated file without a BOM
+type CSVOptions struct {
+	// Delimiter separates fields, a comma by default. Excel in European locales expects a semicolon
+	Delimiter rune
+	// BOM starts the file with the UTF-8 byte order mark, so Excel shows non-ASCII characters correctly
+	BOM bool
+}
+
+// CSVWriter writes RFC 4180 records, quoting fields with delimiters, quotes or line breaks and ending
+// lines with CRLF. Records are flushed every CSVFlushRows, also through an http.Flusher, so large
+// exports are streamed instead of being built in memory
+type CSVWriter struct {
+	out     io.Writer
+	writer  *csv.Writer
+	bom     bool
+	started bool
+	pending int
+}
+
+func NewCSVWriter(out io.Writer, options CSVOptions) (*CSVWriter, error) {
+	writer := csv.NewWriter(out)
+	writer.UseCRLF = true
+	if options.Delimiter != 0 {
+		if options.Delimiter == '"' || options.Delimiter == '\r' || options.Delimiter == '\n' {
+			return nil, ErrInvalidCSVDelimiter
+		}
+		writer.Comma = options.Delimiter
+	}
+	return &CSVWriter{out: out, writer: writer, bom: options.BOM}, nil
+}
+
+func (cw *CSVWriter) Write(record []string) error {
+	if !cw.started {
+		cw.started = true
+		if cw.bom {
+			if _, err := cw.out.Write(utf8BOM); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cw.writer.Write(record); err != nil {
+		return err
+	}
+	cw.pending++
+	if cw.pending >= CSVFlushRows {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush writes buffered records to the output and returns the first error of any write
+func (cw *CSVWriter) Flush() error {
+	cw.pending = 0
+	cw.writer.Flush()
+	if err := cw.writer.Error(); err != nil {
+		return err
+	}
+	if flusher, ok := cw.out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
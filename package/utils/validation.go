@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validationAliases are rules shared between schemas, so limits of the platform are defined once
+var validationAliases = map[string]string{
+	"batch_size":  "omitempty,gte=1,lte=10000",
+	"password":    "required,gte=8,lte=50",
+	"person_name": "required,gte=3,lte=50",
+}
+
+// StructRules is implemented by schemas with rules spanning several fields that tags cannot express.
+// Violations are reported with sl.ReportError, so they are returned as field errors like tag rules
+type StructRules interface {
+	StructRules(sl validator.StructLevel)
+}
+
+var (
+	structRulesMu    sync.Mutex
+	structRulesTypes []interface{}
+)
+
+// RegisterStructRules makes validators returned by NewValidator check the struct rules of the given schemas
+func RegisterStructRules(schemas ...StructRules) {
+	structRulesMu.Lock()
+	defer structRulesMu.Unlock()
+	for _, schema := range schemas {
+		structRulesTypes = append(structRulesTypes, schema)
+	}
+}
+
+func structRulesValidator(sl validator.StructLevel) {
+	sl.Current().Interface().(StructRules).StructRules(sl)
+}
+
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+func usernameValidator(fl validator.FieldLevel) bool {
+	return usernameRegexp.MatchString(fl.Field().String())
+}
+
+// jsonFieldName names fields in validation errors as they are named in requests
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func NewValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(jsonFieldName)
+	validate.RegisterValidation("username", usernameValidator)
+	for alias, tags := range validationAliases {
+		validate.RegisterAlias(alias, tags)
+	}
+	structRulesMu.Lock()
+	if len(structRulesTypes) > 0 {
+		validate.RegisterStructValidation(structRulesValidator, structRulesTypes...)
+	}
+	structRulesMu.Unlock()
+	return validate
+}
@@ -5,6 +5,7 @@ POSTGRES_PASSWORD=postgres
 POSTGRES_DB=test-maxit
 POSTGRES_CONTAINER_NAME=maxit-testdb
 BROKER_CONTAINER_NAME=maxit-testbroker
+REDIS_CONTAINER_NAME=maxit-testredis
 COVERAGE_FILE="coverage.out"
 COVERAGE_HTML="coverage.html"
 
@@ -31,6 +32,10 @@ setup_container() {
     -p 5672:5672 \
     -p 15672:15672 \
     rabbitmq:3.13-management
+  docker rm -f $REDIS_CONTAINER_NAME 2>/dev/null || true
+  docker run -d --name $REDIS_CONTAINER_NAME \
+    -p 6379:6379 \
+    redis:7
   echo -e "\033[32mContainer setup complete.\033[0m"
 }
 
@@ -59,6 +64,7 @@ cleanup_container() {
   check_docker
   docker rm -f $POSTGRES_CONTAINER_NAME 2>/dev/null || true
   docker rm -f $BROKER_CONTAINER_NAME 2>/dev/null || true
+  docker rm -f $REDIS_CONTAINER_NAME 2>/dev/null || true
   echo -e "\033[32mContainer cleanup complete.\033[0m"
 }
 